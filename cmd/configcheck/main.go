@@ -0,0 +1,27 @@
+// Configcheck validates an order pipeline server configuration.
+//
+// It takes the server's flags, builds the pipeline, steps, limiters and
+// routes exactly as the server would and prints the effective
+// configuration as JSON, without binding a port, starting sidecar
+// processes or opening output files. It is equivalent to
+// server -validate-config and is meant for CI and pre-deploy checks.
+//
+// Usage:
+//
+//	configcheck [server flags]
+//
+// It exits with status 1 on the first configuration error.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/app"
+)
+
+func main() {
+	if err := app.Validate(os.Args, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
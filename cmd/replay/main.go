@@ -0,0 +1,75 @@
+// Replay re-executes recorded orders against the step simulators.
+//
+// The server writes recordings with -record; each one holds the full
+// request, the server flags in effect and the step timings. Replay
+// rebuilds the pipeline from the recorded flags, runs each order alone
+// and prints the recorded and replayed outcomes side by side, so a
+// production incident can be reproduced on a laptop.
+//
+// Usage:
+//
+//	replay -file recordings.jsonl [-order o-1]
+//
+// It exits with status 1 if an order or step outcome differs.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/recording"
+)
+
+func main() {
+	mismatch, err := run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if mismatch {
+		os.Exit(1)
+	}
+}
+
+// run replays the selected recordings and reports whether any outcome
+// differed from the recording.
+func run() (bool, error) {
+	file := flag.String("file", "", "recording file written by the server's -record flag")
+	orderID := flag.String("order", "", "replay only this order ID; default all")
+	flag.Parse()
+
+	if *file == "" {
+		return false, fmt.Errorf("replay: -file is required")
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	recs, err := recording.Read(f)
+	if err != nil {
+		return false, err
+	}
+
+	mismatch := false
+	replayed := 0
+	for _, rec := range recs {
+		if *orderID != "" && rec.Request.OrderID != *orderID {
+			continue
+		}
+		res, err := replay(rec)
+		if err != nil {
+			return false, fmt.Errorf("replay %s: %w", rec.Request.OrderID, err)
+		}
+		if err := report(os.Stdout, rec, res); err != nil {
+			return false, err
+		}
+		mismatch = mismatch || !res.Match
+		replayed++
+	}
+	if replayed == 0 {
+		return false, fmt.Errorf("replay: no recordings selected")
+	}
+	return mismatch, nil
+}
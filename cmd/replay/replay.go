@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/order"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/courier"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/payment"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/pool"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/tracker"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/vendor"
+)
+
+// The server's default courier pool size and request deadline.
+const (
+	poolSize       = 5
+	requestTimeout = 10 * time.Second
+)
+
+// result is the outcome of replaying one recording.
+type result struct {
+	Status     model.Status
+	DurationMS int64
+	Steps      []model.StepResult
+	Match      bool // order status and every step's status and detail equal the recording
+}
+
+// replay rebuilds the pipeline from rec.Config and runs rec.Request
+// through it. Only flags that change step behavior are applied:
+// fail-at-end, courier-zones and vendor-hedge-delay.
+func replay(rec model.Recording) (result, error) {
+	svc, err := pipeline(rec.Config)
+	if err != nil {
+		return result{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	steps, err := svc.Process(ctx, rec.Request)
+	res := result{
+		DurationMS: time.Since(start).Milliseconds(),
+		Steps:      steps,
+	}
+	res.Status = outcome(steps, err)
+	res.Match = res.Status == rec.Status && len(steps) == len(rec.Steps)
+	for i := 0; res.Match && i < len(steps); i++ {
+		r := rec.Steps[i]
+		res.Match = steps[i].Name == r.Name && steps[i].Status == r.Status && steps[i].Detail == r.Detail
+	}
+	return res, nil
+}
+
+// pipeline builds the order service described by the server flags in
+// config, with the step simulators the server uses.
+func pipeline(config map[string]string) (*order.Service, error) {
+	zoneCfg, err := courier.ParseZones(config["courier-zones"])
+	if err != nil {
+		return nil, err
+	}
+	zones := courier.NewZones(pool.New(poolSize), zoneCfg)
+	tr := &tracker.Tracker{}
+
+	notifyVendor := func(ctx context.Context, req model.OrderRequest) error {
+		return vendor.Notify(ctx, req, tr)
+	}
+	if v := config["vendor-hedge-delay"]; v != "" {
+		delay, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("vendor-hedge-delay: %w", err)
+		}
+		if delay > 0 {
+			notifyVendor = vendor.NewHedger(notifyVendor, func(ctx context.Context, req model.OrderRequest) error {
+				return vendor.NotifySecondary(ctx, req, tr)
+			}, delay).Notify
+		}
+	}
+
+	steps := []order.Step{
+		{Name: "payment", Run: func(ctx context.Context, req model.OrderRequest) error {
+			return payment.Process(ctx, req, tr)
+		}},
+		{Name: "vendor", Run: notifyVendor},
+		{Name: "courier", Run: func(ctx context.Context, req model.OrderRequest) error {
+			return courier.Assign(ctx, req, zones.For(req.Zone), tr)
+		}},
+	}
+
+	var opts []order.Option
+	if v := config["fail-at-end"]; v != "" {
+		failAtEnd, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("fail-at-end: %w", err)
+		}
+		if failAtEnd {
+			opts = append(opts, order.FailAtEnd())
+		}
+	}
+	return order.New(steps, opts...), nil
+}
+
+// outcome returns the order status the server would report for steps
+// and err. Error kinds are compared per step, through StepResult.Detail.
+func outcome(steps []model.StepResult, err error) model.Status {
+	if err != nil {
+		return model.StatusError
+	}
+	for _, s := range steps {
+		if s.Status == model.StatusDeferred {
+			return model.PendingStatus(s.Name)
+		}
+	}
+	return model.StatusOK
+}
+
+// report prints the recorded and replayed outcomes of one order.
+func report(w io.Writer, rec model.Recording, res result) error {
+	verdict := "match"
+	if !res.Match {
+		verdict = "MISMATCH"
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "order %s\trecorded\treplayed\t%s\n", rec.Request.OrderID, verdict)
+	fmt.Fprintf(tw, "  result\t%s\t%s\t\n", describe(rec.Status, rec.ErrorKind, rec.DurationMS), describe(res.Status, "", res.DurationMS))
+	for i := 0; i < max(len(rec.Steps), len(res.Steps)); i++ {
+		var name, was, now string
+		if i < len(rec.Steps) {
+			s := rec.Steps[i]
+			name, was = s.Name, describe(s.Status, s.Detail, s.DurationMS)
+		}
+		if i < len(res.Steps) {
+			s := res.Steps[i]
+			name, now = s.Name, describe(s.Status, s.Detail, s.DurationMS)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t\n", name, was, now)
+	}
+	return tw.Flush()
+}
+
+func describe(status model.Status, detail string, durationMS int64) string {
+	parts := []string{string(status)}
+	if detail != "" {
+		parts = append(parts, "("+detail+")")
+	}
+	return strings.Join(append(parts, strconv.FormatInt(durationMS, 10)+"ms"), " ")
+}
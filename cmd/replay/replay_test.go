@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	fast := map[string]int64{"payment": 1, "vendor": 1, "courier": 1}
+	okSteps := []model.StepResult{
+		{Name: "payment", Status: model.StatusOK},
+		{Name: "vendor", Status: model.StatusOK},
+		{Name: "courier", Status: model.StatusOK},
+	}
+
+	tests := []struct {
+		name  string
+		rec   model.Recording
+		match bool
+	}{
+		{
+			name:  "success",
+			rec:   model.Recording{Request: model.OrderRequest{OrderID: "o-1", Amount: 100, DelayMS: fast}, Status: model.StatusOK, Steps: okSteps},
+			match: true,
+		},
+		{
+			name: "fail at end keeps siblings",
+			rec: model.Recording{
+				Request: model.OrderRequest{OrderID: "o-2", Amount: 100, FailStep: "vendor", DelayMS: fast},
+				Config:  map[string]string{"fail-at-end": "true"},
+				Status:  model.StatusError,
+				Steps: []model.StepResult{
+					{Name: "payment", Status: model.StatusOK},
+					{Name: "vendor", Status: model.StatusError, Detail: "vendor_unavailable"},
+					{Name: "courier", Status: model.StatusOK},
+				},
+			},
+			match: true,
+		},
+		{
+			name: "outcome changed",
+			rec: model.Recording{
+				Request: model.OrderRequest{OrderID: "o-3", Amount: 100, DelayMS: fast},
+				Status:  model.StatusError,
+				Steps:   okSteps,
+			},
+			match: false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := replay(tt.rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Match != tt.match {
+				t.Fatalf("expected match=%v, got %+v", tt.match, res)
+			}
+		})
+	}
+}
+
+func TestReplayInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	for _, config := range []map[string]string{
+		{"fail-at-end": "maybe"},
+		{"vendor-hedge-delay": "soon"},
+		{"courier-zones": "north"},
+	} {
+		if _, err := replay(model.Recording{Config: config}); err == nil {
+			t.Fatalf("expected an error for config %v", config)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	t.Parallel()
+
+	rec := model.Recording{
+		Request:    model.OrderRequest{OrderID: "o-1"},
+		Status:     model.StatusError,
+		ErrorKind:  "vendor_unavailable",
+		DurationMS: 210,
+		Steps:      []model.StepResult{{Name: "vendor", Status: model.StatusError, Detail: "vendor_unavailable", DurationMS: 200}},
+	}
+	res := result{Status: model.StatusOK, DurationMS: 205, Steps: []model.StepResult{{Name: "vendor", Status: model.StatusOK, DurationMS: 200}}}
+
+	var buf bytes.Buffer
+	if err := report(&buf, rec, res); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"order o-1", "MISMATCH", "error (vendor_unavailable) 210ms", "ok 205ms", "vendor"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Scenarios runs declarative load scenarios against a running server.
+//
+// A scenario file is a JSON array of scenarios, each submitting orders
+// with a mix of step failures and asserting the spread of response
+// statuses, that in-flight steps drain, and numbers read from JSON
+// endpoints; see stress.json. Results are printed per scenario.
+//
+// Usage:
+//
+//	scenarios -file cmd/scenarios/stress.json [-url http://localhost:8080] [-run stress]
+//
+// It exits with status 1 if any scenario fails.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/scenario"
+)
+
+func main() {
+	failed, err := run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// run runs the selected scenarios and reports whether any failed.
+func run() (bool, error) {
+	file := flag.String("file", "", "JSON scenario file")
+	url := flag.String("url", "http://localhost:8080", "base URL of the server under test")
+	adminURL := flag.String("admin-url", "http://localhost:8081", "base URL serving /dashboard and /admin/* (the server's -admin-listen); empty uses -url")
+	name := flag.String("run", "", "run only this scenario; default all")
+	token := flag.String("token", "", "bearer token, for servers started with -oidc-issuer")
+	secret := flag.String("replay-secret", "", "signs orders, for servers started with -replay-secret")
+	flag.Parse()
+
+	if *file == "" {
+		return false, fmt.Errorf("scenarios: -file is required")
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	scs, err := scenario.Load(f)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	runner := &scenario.Runner{BaseURL: *url, AdminURL: *adminURL, Token: *token, Secret: []byte(*secret)}
+	failed := false
+	ran := 0
+	for _, sc := range scs {
+		if *name != "" && sc.Name != *name {
+			continue
+		}
+		res, err := runner.Run(ctx, sc)
+		if err != nil {
+			return false, fmt.Errorf("scenario %s: %w", sc.Name, err)
+		}
+		report(os.Stdout, res)
+		failed = failed || !res.Passed()
+		ran++
+	}
+	if ran == 0 {
+		return false, fmt.Errorf("scenarios: no scenarios selected")
+	}
+	return failed, nil
+}
+
+// report prints one scenario's outcome.
+func report(w io.Writer, res scenario.Result) {
+	verdict := "PASS"
+	if !res.Passed() {
+		verdict = "FAIL"
+	}
+	fmt.Fprintf(w, "%s %s: %d orders in %dms", verdict, res.Name, res.Orders, res.DurationMS)
+	if res.DrainMS >= 0 {
+		fmt.Fprintf(w, ", drained in %dms", res.DrainMS)
+	}
+	fmt.Fprintln(w)
+	for _, code := range slices.Sorted(maps.Keys(res.Statuses)) {
+		fmt.Fprintf(w, "  %d\t%d\n", code, res.Statuses[code])
+	}
+	for _, f := range res.Failures {
+		fmt.Fprintf(w, "  failed: %s\n", f)
+	}
+}
@@ -1,91 +1,26 @@
 // Order Pipeline is a concurrent order-processing HTTP server.
 //
-// It acts as the composition root: creates shared infrastructure
-// (pool, tracker), builds pipeline steps as closures, wires the
-// order orchestrator and HTTP handler, then starts the server.
+// The wiring lives in internal/app, the composition root; main only
+// runs it with the command line.
 //
-// No other package imports all concrete service types — only main
-// knows how the pieces fit together.
+// Usage:
+//
+//	server [-listen addr] [-validate-config] [flags]
 package main
 
 import (
-	"context"
-	"errors"
 	"log"
-	"net/http"
-	"time"
+	"os"
 
-	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
-	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/order"
-	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/courier"
-	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/payment"
-	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/pool"
-	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/tracker"
-	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/vendor"
-	httptransport "github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/transport/http"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/app"
 )
 
 // main is the entry point for the order pipeline server.
 //
-// It invokes the run function and if it returns an error,
+// It invokes app.Run and if it returns an error,
 // it writes the error into stderr and exits the program.
 func main() {
-	if err := run(); err != nil {
+	if err := app.Run(os.Args); err != nil {
 		log.Fatal(err)
 	}
 }
-
-// run starts the HTTP server on 127.0.0.1:8080 and wires dependencies.
-//
-// It returns an error if the server fails to start or exits unexpectedly,
-// excluding a graceful close (http.ErrServerClosed).
-func run() error {
-	const requestTimeout = 10 * time.Second
-	const poolSize = 5
-
-	// Create bounded concurrency semaphore
-	p := pool.New(poolSize)
-
-	// Set up goroutine tracker
-	tr := &tracker.Tracker{}
-
-	// Build the pipeline steps
-	steps := []order.Step{
-		{Name: "payment", Run: func(ctx context.Context, req model.OrderRequest) error {
-			return payment.Process(ctx, req, tr)
-		}},
-		{Name: "vendor", Run: func(ctx context.Context, req model.OrderRequest) error {
-			return vendor.Notify(ctx, req, tr)
-		}},
-		{Name: "courier", Run: func(ctx context.Context, req model.OrderRequest) error {
-			return courier.Assign(ctx, req, p, tr)
-		}},
-	}
-
-	// Construct the order service
-	orderSvc := order.New(steps)
-
-	// Construct the HTTP handler
-	h := httptransport.New(orderSvc, requestTimeout)
-
-	// Set up routing
-	mux := http.NewServeMux()
-	mux.HandleFunc("/order", h.HandleOrder)
-
-	// Configure the HTTP server
-	srv := &http.Server{
-		Addr:              "127.0.0.1:8080",
-		Handler:           mux,
-		ReadTimeout:       10 * time.Second,
-		ReadHeaderTimeout: 3 * time.Second,
-		WriteTimeout:      requestTimeout + 5*time.Second,
-		IdleTimeout:       60 * time.Second,
-	}
-
-	log.Printf("listening on %s", srv.Addr)
-
-	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		return err
-	}
-	return nil
-}
@@ -0,0 +1,200 @@
+// Package accesslog provides an HTTP access log, separate from the
+// application logger.
+//
+// Each completed request is written as one line in either JSON or
+// Apache combined format. Lines can be sampled and toggled per route,
+// and written to any io.Writer, including a RotatingFile.
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/statuswriter"
+)
+
+// Format selects the access-log line format.
+type Format string
+
+// Supported formats.
+const (
+	FormatJSON     Format = "json"
+	FormatCombined Format = "combined" // Apache combined log format
+)
+
+// ParseFormat returns the Format named by s.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatJSON, FormatCombined:
+		return f, nil
+	default:
+		return "", fmt.Errorf("accesslog: unknown format %q", s)
+	}
+}
+
+// Config configures a Logger.
+type Config struct {
+	Format Format
+
+	// SampleRate is the fraction of requests logged, in (0, 1].
+	// Zero or out-of-range values log every request.
+	SampleRate float64
+
+	// Routes toggles logging per request path. Paths mapped to false are
+	// never logged; unlisted paths are logged.
+	Routes map[string]bool
+}
+
+// Logger writes access-log lines for HTTP requests.
+type Logger struct {
+	cfg  Config
+	rand func() float64
+	now  func() time.Time
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// New returns a Logger writing to w.
+//
+// An empty Format defaults to FormatCombined. It panics if w is nil.
+func New(w io.Writer, cfg Config) *Logger {
+	if w == nil {
+		panic("accesslog.New: nil writer")
+	}
+	if cfg.Format == "" {
+		cfg.Format = FormatCombined
+	}
+	if cfg.SampleRate <= 0 || cfg.SampleRate > 1 {
+		cfg.SampleRate = 1
+	}
+	return &Logger{cfg: cfg, rand: rand.Float64, now: time.Now, w: w}
+}
+
+// entry is one access-log record.
+type entry struct {
+	Time       time.Time `json:"time"`
+	RemoteAddr string    `json:"remote_addr"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Proto      string    `json:"proto"`
+	Status     int       `json:"status"`
+	ReqBytes   int64     `json:"req_bytes"`
+	RespBytes  int64     `json:"resp_bytes"`
+	DurationMS float64   `json:"duration_ms"`
+	Referer    string    `json:"referer,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+// Middleware wraps next and logs each request after it completes.
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if enabled, ok := l.cfg.Routes[r.URL.Path]; ok && !enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if l.cfg.SampleRate < 1 && l.rand() >= l.cfg.SampleRate {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := l.now()
+		body := &countingReader{r: r.Body}
+		if r.Body != nil {
+			r.Body = body
+		}
+		rw := statuswriter.New(w)
+
+		next.ServeHTTP(rw, r)
+
+		l.write(entry{
+			Time:       start,
+			RemoteAddr: remoteHost(r.RemoteAddr),
+			Method:     r.Method,
+			Path:       r.URL.RequestURI(),
+			Proto:      r.Proto,
+			Status:     rw.Status(),
+			ReqBytes:   body.n,
+			RespBytes:  rw.Bytes(),
+			DurationMS: float64(l.now().Sub(start).Microseconds()) / 1000,
+			Referer:    r.Referer(),
+			UserAgent:  r.UserAgent(),
+		})
+	})
+}
+
+// write formats e and writes it as a single line.
+func (l *Logger) write(e entry) {
+	var buf bytes.Buffer
+	switch l.cfg.Format {
+	case FormatJSON:
+		_ = json.NewEncoder(&buf).Encode(e)
+	default:
+		appendCombined(&buf, e)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.w.Write(buf.Bytes())
+}
+
+// appendCombined writes e in Apache combined log format:
+//
+//	host - - [time] "method path proto" status bytes "referer" "user-agent"
+func appendCombined(buf *bytes.Buffer, e entry) {
+	buf.WriteString(orDash(e.RemoteAddr))
+	buf.WriteString(" - - [")
+	buf.WriteString(e.Time.Format("02/Jan/2006:15:04:05 -0700"))
+	buf.WriteString("] ")
+	buf.WriteString(strconv.Quote(e.Method + " " + e.Path + " " + e.Proto))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.Itoa(e.Status))
+	buf.WriteByte(' ')
+	if e.RespBytes == 0 {
+		buf.WriteByte('-')
+	} else {
+		buf.WriteString(strconv.FormatInt(e.RespBytes, 10))
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.Quote(orDash(e.Referer)))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.Quote(orDash(e.UserAgent)))
+	buf.WriteByte('\n')
+}
+
+// remoteHost strips the port from addr.
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// countingReader counts bytes read from the request body.
+type countingReader struct {
+	r io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) Close() error { return c.r.Close() }
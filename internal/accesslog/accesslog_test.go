@@ -0,0 +1,124 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLogger(buf *bytes.Buffer, cfg Config) *Logger {
+	l := New(buf, cfg)
+	start := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	calls := 0
+	l.now = func() time.Time {
+		calls++
+		if calls%2 == 1 {
+			return start
+		}
+		return start.Add(1500 * time.Microsecond)
+	}
+	return l
+}
+
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.Copy(io.Discard, r.Body)
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte("hello"))
+})
+
+func newTestRequest() *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/order?x=1", strings.NewReader(`{"a":1}`))
+	req.RemoteAddr = "10.0.0.1:5555"
+	req.Header.Set("User-Agent", "curl/8")
+	return req
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"json", "combined"} {
+		if _, err := ParseFormat(s); err != nil {
+			t.Fatalf("ParseFormat(%q): unexpected error: %v", s, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestMiddlewareCombined(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	l := newTestLogger(&buf, Config{Format: FormatCombined})
+
+	l.Middleware(echoHandler).ServeHTTP(httptest.NewRecorder(), newTestRequest())
+
+	want := `10.0.0.1 - - [02/Jan/2026:15:04:05 +0000] "POST /order?x=1 HTTP/1.1" 201 5 "-" "curl/8"` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("expected\n%q\ngot\n%q", want, got)
+	}
+}
+
+func TestMiddlewareJSON(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	l := newTestLogger(&buf, Config{Format: FormatJSON})
+
+	l.Middleware(echoHandler).ServeHTTP(httptest.NewRecorder(), newTestRequest())
+
+	var e entry
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatalf("decode log line: %v", err)
+	}
+	if e.Status != http.StatusCreated || e.ReqBytes != 7 || e.RespBytes != 5 || e.DurationMS != 1.5 {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if e.RemoteAddr != "10.0.0.1" || e.Method != http.MethodPost || e.Path != "/order?x=1" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+}
+
+func TestMiddlewareFiltering(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		cfg       Config
+		roll      float64
+		wantLines int
+	}{
+		{name: "route_disabled", cfg: Config{Routes: map[string]bool{"/order": false}}, wantLines: 0},
+		{name: "route_enabled", cfg: Config{Routes: map[string]bool{"/order": true}}, wantLines: 1},
+		{name: "other_route_disabled", cfg: Config{Routes: map[string]bool{"/capacity": false}}, wantLines: 1},
+		{name: "sampled_in", cfg: Config{SampleRate: 0.5}, roll: 0.2, wantLines: 1},
+		{name: "sampled_out", cfg: Config{SampleRate: 0.5}, roll: 0.7, wantLines: 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			l := newTestLogger(&buf, tt.cfg)
+			l.rand = func() float64 { return tt.roll }
+
+			w := httptest.NewRecorder()
+			l.Middleware(echoHandler).ServeHTTP(w, newTestRequest())
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("expected handler to run, got %d", w.Code)
+			}
+			if got := strings.Count(buf.String(), "\n"); got != tt.wantLines {
+				t.Fatalf("expected %d lines, got %d", tt.wantLines, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package accesslog
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// RotatingFile is an append-only file that rotates once it grows past
+// a size limit, keeping a bounded number of numbered backups
+// (path.1 is the newest).
+type RotatingFile struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// OpenRotatingFile opens or creates path for appending.
+//
+// A non-positive maxBytes defaults to 100 MiB; a negative maxBackups is
+// treated as zero, in which case the file is truncated on rotation.
+func OpenRotatingFile(path string, maxBytes int64, maxBackups int) (*RotatingFile, error) {
+	if maxBytes <= 0 {
+		maxBytes = 100 << 20
+	}
+	if maxBackups < 0 {
+		maxBackups = 0
+	}
+	rf := &RotatingFile{path: path, maxBytes: maxBytes, maxBackups: maxBackups}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// Write appends p, rotating first if p would push the file past maxBytes.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.f == nil {
+		return 0, fs.ErrClosed
+	}
+	if rf.size > 0 && rf.size+int64(len(p)) > rf.maxBytes {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.f.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.f == nil {
+		return nil
+	}
+	err := rf.f.Close()
+	rf.f = nil
+	return err
+}
+
+// open opens rf.path for appending. The caller must hold rf.mu or own rf.
+func (rf *RotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("accesslog: open %s: %w", rf.path, err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("accesslog: stat %s: %w", rf.path, err)
+	}
+	rf.f = f
+	rf.size = fi.Size()
+	return nil
+}
+
+// rotate shifts backups up by one and starts a fresh file.
+// The caller must hold rf.mu.
+func (rf *RotatingFile) rotate() error {
+	if err := rf.f.Close(); err != nil {
+		return fmt.Errorf("accesslog: close %s: %w", rf.path, err)
+	}
+	rf.f = nil
+
+	if rf.maxBackups == 0 {
+		if err := os.Remove(rf.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("accesslog: rotate: %w", err)
+		}
+		return rf.open()
+	}
+
+	for i := rf.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(backupName(rf.path, i), backupName(rf.path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("accesslog: rotate: %w", err)
+		}
+	}
+	if err := os.Rename(rf.path, backupName(rf.path, 1)); err != nil {
+		return fmt.Errorf("accesslog: rotate: %w", err)
+	}
+	return rf.open()
+}
+
+func backupName(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
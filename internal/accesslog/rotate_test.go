@@ -0,0 +1,99 @@
+package accesslog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(b)
+}
+
+func TestRotatingFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "access.log")
+	rf, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer rf.Close()
+
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	if got := readFile(t, path); got != "dddddd\n" {
+		t.Fatalf("current: expected %q, got %q", "dddddd\n", got)
+	}
+	if got := readFile(t, path+".1"); got != "cccccc\n" {
+		t.Fatalf("backup 1: expected %q, got %q", "cccccc\n", got)
+	}
+	if got := readFile(t, path+".2"); got != "bbbbbb\n" {
+		t.Fatalf("backup 2: expected %q, got %q", "bbbbbb\n", got)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected no third backup, got %v", err)
+	}
+}
+
+func TestRotatingFileNoBackups(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "access.log")
+	rf, err := OpenRotatingFile(path, 10, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if got := readFile(t, path); got != "bbbbbb\n" {
+		t.Fatalf("expected %q, got %q", "bbbbbb\n", got)
+	}
+	if _, err := rf.Write([]byte("x")); err == nil {
+		t.Fatal("expected error writing to closed file")
+	}
+}
+
+// Reopening keeps appending and counts the existing size.
+func TestRotatingFileReopen(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(path, []byte("aaaaaaaa\n"), 0o644); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	rf, err := OpenRotatingFile(path, 10, 1)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer rf.Close()
+
+	if _, err := rf.Write([]byte("bb\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got := readFile(t, path+".1"); got != "aaaaaaaa\n" {
+		t.Fatalf("backup: expected %q, got %q", "aaaaaaaa\n", got)
+	}
+	if got := readFile(t, path); got != "bb\n" {
+		t.Fatalf("current: expected %q, got %q", "bb\n", got)
+	}
+}
@@ -0,0 +1,168 @@
+// Package alert notifies operators of operational events through
+// external sinks: a Slack incoming webhook, PagerDuty or email.
+//
+// Components already report events such as a tripped kill switch, a
+// dependency going down, fast error-budget burn, an error kind spike or
+// a failed synthetic order as warnings in the application log. Handler
+// wraps the log's slog.Handler and hands every record at or above its
+// level to a Notifier, so no component needs to know about alerting.
+//
+// A Notifier deduplicates repeated alerts: an alert with the same key as
+// one sent less than the throttle period ago is counted instead of sent,
+// and the count goes with the next alert of that key sent. Delivery runs
+// in the background, so logging never waits on a sink.
+package alert
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alert is one operational event.
+type Alert struct {
+	Key     string // deduplication key: the message and identifying attributes
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Attrs   []slog.Attr
+	Repeats int // alerts of this key suppressed since the last one sent
+}
+
+// Summary returns a one-line description of a, e.g.
+// "kill switch tripped switch=payment reason=dependency down".
+func (a Alert) Summary() string {
+	var b strings.Builder
+	b.WriteString(a.Message)
+	for _, at := range a.Attrs {
+		b.WriteString(" " + at.Key + "=" + at.Value.String())
+	}
+	if a.Repeats > 0 {
+		b.WriteString(" (repeated " + strconv.Itoa(a.Repeats) + " times)")
+	}
+	return b.String()
+}
+
+// Sink delivers alerts to one destination.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, a Alert) error
+}
+
+// Config sets how alerts are deduplicated and delivered.
+type Config struct {
+	Throttle  time.Duration // minimum time between alerts of one key; default 10 minutes
+	Timeout   time.Duration // deadline of one delivery to one sink; default 10 seconds
+	QueueSize int           // alerts waiting for delivery; default 100
+}
+
+// Notifier deduplicates alerts and delivers them to its sinks.
+type Notifier struct {
+	cfg    Config
+	sinks  []Sink
+	logger *slog.Logger
+	now    func() time.Time
+	queue  chan Alert
+
+	mu   sync.Mutex
+	keys map[string]*keyState
+}
+
+type keyState struct {
+	last    time.Time // when an alert of the key was last sent
+	repeats int       // suppressed since
+}
+
+// New returns a Notifier delivering to sinks. Delivery failures are
+// logged through logger, which must not itself alert through the
+// Notifier. Non-positive Config fields take their defaults. It panics
+// if logger is nil or no sinks are given.
+func New(cfg Config, logger *slog.Logger, sinks ...Sink) *Notifier {
+	if logger == nil {
+		panic("alert.New: nil logger")
+	}
+	if len(sinks) == 0 {
+		panic("alert.New: no sinks")
+	}
+	if cfg.Throttle <= 0 {
+		cfg.Throttle = 10 * time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	return &Notifier{
+		cfg:    cfg,
+		sinks:  slices.Clip(sinks),
+		logger: logger,
+		now:    time.Now,
+		queue:  make(chan Alert, cfg.QueueSize),
+		keys:   map[string]*keyState{},
+	}
+}
+
+// Notify queues a for delivery unless an alert with the same key was
+// sent less than the throttle period ago. It never blocks: when the
+// queue is full the alert is dropped and logged.
+func (n *Notifier) Notify(a Alert) {
+	if a.Time.IsZero() {
+		a.Time = n.now()
+	}
+	if a.Key == "" {
+		a.Key = a.Message
+	}
+
+	n.mu.Lock()
+	k := n.keys[a.Key]
+	if k == nil {
+		k = &keyState{}
+		n.keys[a.Key] = k
+	}
+	if !k.last.IsZero() && a.Time.Sub(k.last) < n.cfg.Throttle {
+		k.repeats++
+		n.mu.Unlock()
+		return
+	}
+	a.Repeats, k.repeats, k.last = k.repeats, 0, a.Time
+	n.mu.Unlock()
+
+	select {
+	case n.queue <- a:
+	default:
+		n.logger.Warn("alert queue full", slog.String("alert", a.Message))
+	}
+}
+
+// Run delivers queued alerts to every sink until ctx is done.
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case a := <-n.queue:
+			n.deliver(ctx, a)
+		}
+	}
+}
+
+// deliver sends a to each sink in turn, logging failures.
+func (n *Notifier) deliver(ctx context.Context, a Alert) {
+	for _, s := range n.sinks {
+		sctx, cancel := context.WithTimeout(ctx, n.cfg.Timeout)
+		err := s.Send(sctx, a)
+		cancel()
+		if err != nil {
+			n.logger.Error("alert delivery failed",
+				slog.String("sink", s.Name()),
+				slog.String("alert", a.Message),
+				slog.Any("err", err),
+			)
+		}
+	}
+}
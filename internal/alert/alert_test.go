@@ -0,0 +1,142 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink records the alerts sent to it, failing with err.
+type recordingSink struct {
+	mu   sync.Mutex
+	got  []Alert
+	sent chan struct{}
+	err  error
+}
+
+func newRecordingSink() *recordingSink {
+	return &recordingSink{sent: make(chan struct{}, 16)}
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Send(_ context.Context, a Alert) error {
+	s.mu.Lock()
+	s.got = append(s.got, a)
+	s.mu.Unlock()
+	s.sent <- struct{}{}
+	return s.err
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestNotifier_Throttle(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	sink := newRecordingSink()
+	n := New(Config{Throttle: time.Minute}, discardLogger(), sink)
+
+	tests := []struct {
+		name     string
+		key      string
+		at       time.Duration // after now
+		wantSent bool
+		wantReps int
+	}{
+		{name: "first", key: "a", at: 0, wantSent: true},
+		{name: "repeat", key: "a", at: 10 * time.Second},
+		{name: "other_key", key: "b", at: 20 * time.Second, wantSent: true},
+		{name: "repeat_again", key: "a", at: 59 * time.Second},
+		{name: "after_throttle", key: "a", at: time.Minute, wantSent: true, wantReps: 2},
+		{name: "quiet_repeat", key: "a", at: 3 * time.Minute, wantSent: true},
+	}
+	for _, tt := range tests {
+		n.Notify(Alert{Key: tt.key, Message: tt.key, Time: now.Add(tt.at)})
+		select {
+		case a := <-n.queue:
+			if !tt.wantSent {
+				t.Fatalf("%s: expected the alert suppressed", tt.name)
+			}
+			if a.Repeats != tt.wantReps {
+				t.Fatalf("%s: expected %d repeats, got %d", tt.name, tt.wantReps, a.Repeats)
+			}
+		default:
+			if tt.wantSent {
+				t.Fatalf("%s: expected the alert queued", tt.name)
+			}
+		}
+	}
+}
+
+func TestNotifier_Run(t *testing.T) {
+	t.Parallel()
+
+	failing, ok := newRecordingSink(), newRecordingSink()
+	failing.err = errors.New("webhook down")
+	var logs strings.Builder
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	n := New(Config{QueueSize: 1}, logger, failing, ok)
+
+	n.Notify(Alert{Message: "kill switch tripped"})
+	n.Notify(Alert{Message: "dependency down"}) // queue full
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		n.Run(ctx)
+		close(done)
+	}()
+	<-failing.sent
+	<-ok.sent
+	cancel()
+	<-done
+
+	// A failing sink does not keep the alert from the others.
+	if len(ok.got) != 1 || ok.got[0].Message != "kill switch tripped" || ok.got[0].Time.IsZero() {
+		t.Fatalf("expected the first alert delivered, got %+v", ok.got)
+	}
+	for _, want := range []string{"alert queue full", "alert delivery failed", "webhook down"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Fatalf("expected %q logged, got %s", want, logs.String())
+		}
+	}
+}
+
+func TestAlert_Summary(t *testing.T) {
+	t.Parallel()
+
+	a := Alert{
+		Message: "kill switch tripped",
+		Attrs:   []slog.Attr{slog.String("switch", "payment"), slog.Duration("cooldown", 30*time.Second)},
+		Repeats: 3,
+	}
+	if got, want := a.Summary(), "kill switch tripped switch=payment cooldown=30s (repeated 3 times)"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestNew_InvalidPanics(t *testing.T) {
+	t.Parallel()
+
+	for name, fn := range map[string]func(){
+		"nil_logger": func() { New(Config{}, nil, newRecordingSink()) },
+		"no_sinks":   func() { New(Config{}, discardLogger()) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			fn()
+		})
+	}
+}
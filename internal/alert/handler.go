@@ -0,0 +1,100 @@
+package alert
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// volatileAttrs are left out of alert keys: they differ between
+// occurrences of the same event, such as each failed probe's order ID.
+var volatileAttrs = []string{"err", "error", "order_id"}
+
+// Handler is a slog.Handler that passes records to another handler and
+// turns those at or above its level into alerts.
+type Handler struct {
+	next     slog.Handler
+	notifier *Notifier
+	level    slog.Leveler
+	attrs    []slog.Attr // from WithAttrs, group-qualified
+	group    string      // from WithGroup, dot-terminated
+}
+
+// NewHandler returns a Handler writing records to next and notifying n
+// of those at level or above. A nil level defaults to slog.LevelWarn.
+// It panics if next or n is nil.
+func NewHandler(next slog.Handler, n *Notifier, level slog.Leveler) *Handler {
+	if next == nil {
+		panic("alert.NewHandler: nil handler")
+	}
+	if n == nil {
+		panic("alert.NewHandler: nil notifier")
+	}
+	if level == nil {
+		level = slog.LevelWarn
+	}
+	return &Handler{next: next, notifier: n, level: level}
+}
+
+// Enabled reports whether next logs records at l, or l raises an alert.
+func (h *Handler) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= h.level.Level() || h.next.Enabled(ctx, l)
+}
+
+// Handle notifies of r if it is at the alert level and passes it on if
+// next is enabled for it. The alert's key is its message and its
+// attributes other than errors and order IDs.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= h.level.Level() {
+		attrs := slices.Clone(h.attrs)
+		r.Attrs(func(a slog.Attr) bool {
+			attrs = append(attrs, slog.Attr{Key: h.group + a.Key, Value: a.Value.Resolve()})
+			return true
+		})
+		h.notifier.Notify(Alert{
+			Key:     alertKey(r.Message, attrs),
+			Time:    r.Time,
+			Level:   r.Level,
+			Message: r.Message,
+			Attrs:   attrs,
+		})
+	}
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func alertKey(msg string, attrs []slog.Attr) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, a := range attrs {
+		if a.Value.Kind() == slog.KindString && !slices.Contains(volatileAttrs, a.Key) {
+			b.WriteString("\x00" + a.Key + "=" + a.Value.String())
+		}
+	}
+	return b.String()
+}
+
+// WithAttrs returns a Handler whose alerts and records carry attrs.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := *h
+	out.next = h.next.WithAttrs(attrs)
+	out.attrs = slices.Clip(h.attrs)
+	for _, a := range attrs {
+		out.attrs = append(out.attrs, slog.Attr{Key: h.group + a.Key, Value: a.Value.Resolve()})
+	}
+	return &out
+}
+
+// WithGroup returns a Handler qualifying later attributes with name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	out := *h
+	out.next = h.next.WithGroup(name)
+	out.group = h.group + name + "."
+	return &out
+}
@@ -0,0 +1,51 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	next := slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelError})
+	n := New(Config{}, discardLogger(), newRecordingSink())
+	logger := slog.New(NewHandler(next, n, nil)).With("region", "eu-west")
+
+	logger.Info("dependency recovered", "dependency", "payment")
+	if len(n.queue) != 0 {
+		t.Fatal("expected no alert below the alert level")
+	}
+
+	// Probes fail with varying order IDs and errors; they share a key.
+	logger.Warn("synthetic order failed", "order_id", "synthetic-1", "error", "timeout")
+	logger.Warn("synthetic order failed", "order_id", "synthetic-2", "err", errors.New("declined"))
+	logger.WithGroup("slo").Warn("fast burn", "route", "/order")
+	if len(n.queue) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(n.queue))
+	}
+	probe, burn := <-n.queue, <-n.queue
+	if probe.Key != "synthetic order failed\x00region=eu-west" || probe.Level != slog.LevelWarn {
+		t.Fatalf("unexpected probe alert %+v", probe)
+	}
+	if !strings.Contains(burn.Summary(), "region=eu-west slo.route=/order") || burn.Key == probe.Key {
+		t.Fatalf("unexpected burn alert %+v", burn)
+	}
+
+	// Records are written only where next is enabled.
+	if out.Len() != 0 {
+		t.Fatalf("expected warnings withheld from next, got %s", out.String())
+	}
+	logger.Error("alert delivery failed")
+	if !strings.Contains(out.String(), "alert delivery failed") {
+		t.Fatalf("expected the error written, got %q", out.String())
+	}
+	if !logger.Handler().Enabled(context.Background(), slog.LevelWarn) {
+		t.Fatal("expected warnings enabled for alerting")
+	}
+}
@@ -0,0 +1,160 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Slack posts alerts to a Slack incoming webhook URL.
+type Slack struct {
+	URL    string
+	Client *http.Client // default http.DefaultClient
+}
+
+// Name implements Sink.
+func (s Slack) Name() string { return "slack" }
+
+// Send implements Sink.
+func (s Slack) Send(ctx context.Context, a Alert) error {
+	return postJSON(ctx, s.Client, s.URL, map[string]string{"text": a.Summary()})
+}
+
+// PagerDutyURL is the PagerDuty Events API v2 endpoint.
+const PagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty triggers PagerDuty incidents through the Events API v2.
+// Alerts of one key share a dedup key, so repeats update one incident.
+type PagerDuty struct {
+	RoutingKey string
+	URL        string // default PagerDutyURL
+	Source     string // the alerting host, e.g. the region; default "order-pipeline"
+	Client     *http.Client
+}
+
+// Name implements Sink.
+func (p PagerDuty) Name() string { return "pagerduty" }
+
+// Send implements Sink.
+func (p PagerDuty) Send(ctx context.Context, a Alert) error {
+	url, source := p.URL, p.Source
+	if url == "" {
+		url = PagerDutyURL
+	}
+	if source == "" {
+		source = "order-pipeline"
+	}
+	severity := "warning"
+	if a.Level >= slog.LevelError {
+		severity = "error"
+	}
+	details := make(map[string]string, len(a.Attrs))
+	for _, at := range a.Attrs {
+		details[at.Key] = at.Value.String()
+	}
+	return postJSON(ctx, p.Client, url, map[string]any{
+		"routing_key":  p.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    a.Key,
+		"payload": map[string]any{
+			"summary":        a.Summary(),
+			"source":         source,
+			"severity":       severity,
+			"timestamp":      a.Time.UTC().Format(time.RFC3339),
+			"custom_details": details,
+		},
+	})
+}
+
+// postJSON POSTs body as JSON to url, failing unless the answer is 2xx.
+func postJSON(ctx context.Context, client *http.Client, url string, body any) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("alert: %s answered %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// Email mails alerts through an SMTP relay, without authentication.
+type Email struct {
+	Addr string // relay host:port
+	From string
+	To   []string
+}
+
+// Name implements Sink.
+func (e Email) Name() string { return "email" }
+
+// Send implements Sink. The context bounds connecting to the relay and
+// the whole exchange.
+func (e Email) Send(ctx context.Context, a Alert) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", e.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	host, _, _ := net.SplitHostPort(e.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if err := c.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, e.message(a)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message formats a as an RFC 5322 message.
+func (e Email) message(a Alert) string {
+	var b strings.Builder
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(a.Message)
+	fmt.Fprintf(&b, "From: %s\r\nTo: %s\r\nSubject: [%s] %s\r\n", e.From, strings.Join(e.To, ", "), a.Level, subject)
+	fmt.Fprintf(&b, "Date: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n", a.Time.Format(time.RFC1123Z))
+	b.WriteString(a.Summary() + "\r\n")
+	return b.String()
+}
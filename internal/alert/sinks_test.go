@@ -0,0 +1,175 @@
+package alert
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testAlert() Alert {
+	return Alert{
+		Key:     "kill switch tripped\x00switch=payment",
+		Time:    time.Unix(1_700_000_000, 0),
+		Level:   slog.LevelWarn,
+		Message: "kill switch tripped",
+		Attrs:   []slog.Attr{slog.String("switch", "payment")},
+	}
+}
+
+func TestHTTPSinks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		sink   func(url string) Sink
+		status int
+		check  func(t *testing.T, body map[string]any)
+	}{
+		{
+			name:   "slack",
+			sink:   func(url string) Sink { return Slack{URL: url} },
+			status: http.StatusOK,
+			check: func(t *testing.T, body map[string]any) {
+				if body["text"] != "kill switch tripped switch=payment" {
+					t.Fatalf("unexpected text %v", body["text"])
+				}
+			},
+		},
+		{
+			name:   "pagerduty",
+			sink:   func(url string) Sink { return PagerDuty{RoutingKey: "rk", URL: url, Source: "eu-west"} },
+			status: http.StatusAccepted,
+			check: func(t *testing.T, body map[string]any) {
+				payload, _ := body["payload"].(map[string]any)
+				if body["routing_key"] != "rk" || body["event_action"] != "trigger" || body["dedup_key"] != testAlert().Key {
+					t.Fatalf("unexpected event %v", body)
+				}
+				if payload["severity"] != "warning" || payload["source"] != "eu-west" || payload["timestamp"] != "2023-11-14T22:13:20Z" {
+					t.Fatalf("unexpected payload %v", payload)
+				}
+			},
+		},
+		{
+			name:   "rejected",
+			sink:   func(url string) Sink { return Slack{URL: url} },
+			status: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			bodies := make(chan map[string]any, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]any
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				bodies <- body
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			err := tt.sink(srv.URL).Send(context.Background(), testAlert())
+			if tt.check == nil {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.check(t, <-bodies)
+		})
+	}
+}
+
+// serveSMTP answers one SMTP session on ln and returns the lines it
+// received.
+func serveSMTP(t *testing.T, ln net.Listener) <-chan []string {
+	t.Helper()
+	lines := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			lines <- nil
+			return
+		}
+		defer conn.Close()
+		var got []string
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+		reply("220 test ready")
+		data := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			got = append(got, line)
+			switch {
+			case data && line == ".":
+				data = false
+				reply("250 queued")
+			case data:
+			case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+				reply("250 test")
+			case line == "DATA":
+				data = true
+				reply("354 go ahead")
+			case line == "QUIT":
+				reply("221 bye")
+				lines <- got
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+		lines <- got
+	}()
+	return lines
+}
+
+func TestEmail(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := serveSMTP(t, ln)
+
+	e := Email{Addr: ln.Addr().String(), From: "pipeline@example.com", To: []string{"ops@example.com", "oncall@example.com"}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Send(ctx, testAlert()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := strings.Join(<-lines, "\n")
+	for _, want := range []string{
+		"MAIL FROM:<pipeline@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"RCPT TO:<oncall@example.com>",
+		"Subject: [WARN] kill switch tripped",
+		"kill switch tripped switch=payment",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in the session, got\n%s", want, got)
+		}
+	}
+
+	// An unreachable relay fails within the context.
+	ln.Close()
+	if err := e.Send(ctx, testAlert()); err == nil {
+		t.Fatal("expected an error")
+	}
+}
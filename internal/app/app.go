@@ -0,0 +1,1187 @@
+// Package app is the composition root of the order pipeline server.
+//
+// It creates shared infrastructure (pool, tracker), builds pipeline
+// steps as closures, wires the order orchestrator and HTTP handler,
+// then starts the server. cmd/server serves with it and cmd/configcheck
+// validates a configuration with it.
+//
+// No other package imports all concrete service types — only app
+// knows how the pieces fit together.
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/accesslog"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/alert"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/audit"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/auth"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/config"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/deferred"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/deps"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/killswitch"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/maintenance"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/netacl"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/order"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/policy"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/probe"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/recording"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/redact"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/cost"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/courier"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/geocode"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/loyalty"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/outbound"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/payment"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/pool"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/sidecar"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/tax"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/tracker"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/vendor"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/tracedump"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/traffic"
+	httptransport "github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/transport/http"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/webhook"
+)
+
+// Run wires dependencies from the command line args (args[0] is the
+// program name), layered over the environment and an optional config
+// file as described in package config, and serves HTTP on the listener selected by the -listen
+// flag (default 127.0.0.1:8080). With -validate-config it behaves as
+// Validate, writing to stdout.
+//
+// It returns an error if the server fails to start or exits unexpectedly,
+// excluding a graceful close (http.ErrServerClosed).
+func Run(args []string) error {
+	return run(args, os.Stdout, false)
+}
+
+// Validate builds everything Run would from args — steps, limiters,
+// decorators and routes — and writes the effective configuration to
+// out as JSON, without binding a listener, starting background work or
+// sidecar processes, or opening output files.
+//
+// It returns the first configuration error.
+func Validate(args []string, out io.Writer) error {
+	return run(args, out, true)
+}
+
+// profiles are the presets selectable with -profile. dev is a local
+// playground: verbose logs on stderr and an access log on stdout, the
+// optional steps on their in-process fakes, an audit log in the temp
+// directory, synthetic probes and a batch of sample orders.
+var profiles = map[string]config.Preset{
+	"dev": {
+		"log-level":      "debug",
+		"access-log":     "-",
+		"tax-provider":   "fake",
+		"geocode":        "true",
+		"loyalty":        "true",
+		"audit-log":      filepath.Join(os.TempDir(), "order-pipeline-dev-audit.log"),
+		"probe-interval": "30s",
+		"seed-orders":    "20",
+	},
+}
+
+func run(args []string, out io.Writer, validate bool) error {
+	fs := flag.NewFlagSet(filepath.Base(args[0]), flag.ExitOnError)
+
+	validateConfig := fs.Bool("validate-config", false,
+		"build the configuration, print the effective config as JSON and exit without serving")
+	requestTimeout := fs.Duration("request-timeout", 10*time.Second,
+		"deadline for processing one standard order")
+	slaClasses := fs.String("sla-classes", "standard=0s/5s,express=3s/1s",
+		"comma-separated name=timeout[/target] service classes selected by an order's sla, the first the default; a 0s timeout means -request-timeout")
+	poolSize := fs.Int("pool-size", 5,
+		"max concurrent courier assignments outside scheduled shifts")
+	listenSpec := fs.String("listen", "127.0.0.1:8080",
+		`listen address: "host:port", "unix:<path>" or "systemd"`)
+	adminListenSpec := fs.String("admin-listen", "127.0.0.1:8081",
+		`listen address serving /admin/*: "host:port" or "unix:<path>"; without -oidc-issuer the admin routes are served only here; empty disables it`)
+	accessLogPath := fs.String("access-log", "",
+		`access log destination: file path, "-" for stdout, empty to disable`)
+	accessLogFormat := fs.String("access-log-format", "combined",
+		`access log format: "combined" or "json"`)
+	failAtEnd := fs.Bool("fail-at-end", false,
+		"run every step to completion and report all failures instead of canceling on the first")
+	courierSchedule := fs.String("courier-schedule", "",
+		`courier pool size by time of day (server local time), e.g. "17:00-21:00=20,sat-sun@10:00-14:00=8"`)
+	courierZones := fs.String("courier-zones", "",
+		`per-zone courier pools, e.g. "north=5:center,center=8:north+south,south=3:center"`)
+	vendorHedgeDelay := fs.Duration("vendor-hedge-delay", 0,
+		"hedge vendor notifications to the secondary endpoint after this delay; 0 disables")
+	outboundLimit := fs.Int("outbound-limit", 0,
+		"max concurrent outbound calls across payment, vendor and courier (1-128); 0 disables")
+	outboundDestLimits := fs.String("outbound-dest-limits", "",
+		`per-destination outbound limits within -outbound-limit, e.g. "payment=10,vendor=20"`)
+	auditLogPath := fs.String("audit-log", "",
+		"hash-chained audit log of processed orders: file path, empty to disable")
+	redactSpec := fs.String("redact", "",
+		`redact fields in logs and the audit log, e.g. "order_id=hash" (strategies: drop, hash, mask)`)
+	oidcIssuer := fs.String("oidc-issuer", "",
+		"require JWTs from this OIDC issuer on every route; empty disables authentication")
+	oidcAudience := fs.String("oidc-audience", "order-pipeline",
+		"audience JWTs must be issued for")
+	oidcJWKSURL := fs.String("oidc-jwks-url", "",
+		"issuer key set URL; discovered from -oidc-issuer when empty")
+	probeInterval := fs.Duration("probe-interval", 0,
+		"submit a synthetic order through the pipeline at this interval; 0 disables")
+	stepFallbacks := fs.String("step-fallbacks", "",
+		"behavior of steps disabled by their kill switch, e.g. courier=defer; default fail")
+	recordPath := fs.String("record", "",
+		"append recordings of selected orders to this file for cmd/replay; empty disables")
+	recordOrders := fs.String("record-orders", "*",
+		"comma-separated order IDs to record with -record; * records every order")
+	errorStatuses := fs.String("error-statuses", "",
+		"comma-separated kind=status overrides of the error kind to HTTP status mapping, e.g. canceled=499")
+	timeoutResponse := fs.String("timeout-response", httptransport.TimeoutPartial,
+		"body for orders that time out: partial (step results so far) or minimal")
+	lateStepGrace := fs.Duration("late-step-grace", 0,
+		"let steps running at the order deadline finish for this long in the background and log their outcome; 0 disables")
+	coalesceOrders := fs.Bool("coalesce-orders", false,
+		"process concurrent submissions of the same order_id once and answer them all with the result, flagged shared")
+	replayGuard := fs.Bool("replay-guard", false,
+		"require X-Request-Nonce and X-Request-Timestamp on POST /order and reject replayed or stale submissions")
+	replaySecret := fs.String("replay-secret", "",
+		"with -replay-guard, also require X-Request-Signature, an HMAC-SHA256 of the nonce, timestamp and body under this secret; mirrors to -shadow-url are signed with it too; empty only deduplicates")
+	splitOrders := fs.Bool("split-orders", false,
+		"split orders whose items come from several vendors into a sub-order per vendor, each running the pipeline; some failing answers partially_completed")
+	maxConcurrentSteps := fs.Int("max-concurrent-steps", 0,
+		"max steps of one order running at once; further steps wait for a slot; 0 runs every step at once")
+	stepIdleTimeout := fs.Duration("step-idle-timeout", 0,
+		"stop steps running this long without a heartbeat or returning, failing them with kind step_stalled; 0 disables")
+	taxProvider := fs.String("tax-provider", "",
+		`tax calculation ahead of payment: "fake", an http(s) URL of a tax service, or empty to disable`)
+	taxCritical := fs.Bool("tax-critical", true,
+		"fail orders whose tax cannot be computed; false charges the untaxed amount")
+	geocodeEnabled := fs.Bool("geocode", false,
+		"validate and geocode delivery addresses ahead of courier assignment")
+	zoneCenters := fs.String("zone-centers", "",
+		`delivery zone centers for geocoded orders without a zone, e.g. "north=60.21:24.95,center=60.17:24.94"`)
+	stepMemo := fs.Bool("step-memo", false,
+		"share the result of identical lookups, such as geocoding one address, among the steps of an order")
+	simulate := fs.Bool("simulation", false,
+		"run in simulation mode: steps skip delays of 1ms or less instead of arming timers, for load tests")
+	loyaltyEnabled := fs.Bool("loyalty", false,
+		"accrue loyalty points for successful orders as a tail step")
+	tailSyncWait := fs.Duration("tail-sync-wait", 0,
+		"how long a successful order waits for its tail steps before responding; 0 responds at once")
+	maintenanceOn := fs.Bool("maintenance", false,
+		"start in maintenance mode: reject new orders with 503 and pause background work until turned off via /admin/maintenance")
+	stepCosts := fs.String("step-costs", "",
+		`comma-separated step=provider:region:cost_micros tags and estimated cost per call, e.g. "payment=stripe:eu-west:2500"; empty disables cost attribution`)
+	sidecarSteps := fs.String("sidecar-steps", "",
+		`extra pipeline steps run by sidecar processes over stdin/stdout JSON, e.g. "fraud:500ms=./bin/fraud-check --strict"; empty disables`)
+	orderHooks := fs.String("order-hooks", "",
+		`per-tenant request and response hooks, e.g. "*:normalize,acme:default-sla=express,acme:omit-steps"; empty disables`)
+	policyRules := fs.String("policy-rules", "",
+		`semicolon-separated routing rules setting the SLA class or zone, e.g. "amount>=5000 && zone==north => sla=express"; empty disables`)
+	shadowURL := fs.String("shadow-url", "",
+		"mirror a sample of orders to this POST /order URL after processing and log divergent outcomes; empty disables")
+	shadowRate := fs.Float64("shadow-rate", 0.01,
+		"fraction of orders mirrored with -shadow-url, in (0, 1]")
+	webhooksOn := fs.Bool("webhooks", false,
+		"publish order.completed, order.failed and step.failed events to endpoints registered via /admin/webhooks")
+	webhooksAllowPrivate := fs.Bool("webhooks-allow-private", false,
+		"accept webhook endpoints on loopback, private and link-local addresses")
+	maxConnections := fs.Int("max-connections", 0,
+		"max open client connections; connections over it are answered 503 and closed; 0 disables")
+	orderChannels := fs.String("channels", "",
+		`comma-separated order source channels accepted in "channel", each counted in GET /admin/channels, e.g. web,ios,partner-acme; empty accepts any`)
+	regionName := fs.String("region", "",
+		"region this instance serves, named in X-Served-By and log records; empty disables region pinning")
+	regionPeers := fs.String("region-peers", "",
+		`comma-separated region=URL pairs of peer regions that orders pinned elsewhere are pointed at, e.g. "eu-west=https://eu.example.com"`)
+	tolerantRoutes := fs.String("tolerant-routes", "",
+		"comma-separated routes whose JSON bodies may carry unknown fields, ignored and counted instead of rejected, e.g. /order")
+	allowCIDRs := fs.String("allow-cidrs", "",
+		"comma-separated client networks served, e.g. 10.0.0.0/8,192.168.1.7; empty allows all")
+	denyCIDRs := fs.String("deny-cidrs", "",
+		"comma-separated client networks refused with 403, even when allowed")
+	trustedProxies := fs.String("trusted-proxies", "",
+		"comma-separated proxy networks whose X-Forwarded-For names the client; empty uses the peer address")
+	syntheticFrom := fs.String("synthetic-from", "",
+		"comma-separated client networks whose baggage synthetic=true marker is honored, along with tokens carrying the synthetic role; empty trusts none")
+	dependencyChecks := fs.String("dependency-checks", "",
+		`comma-separated step=URL health endpoints of upstream providers, checked in the background to trip step kill switches and drive GET /readyz, e.g. "payment=http://payments.internal/healthz"; empty disables`)
+	dependencyInterval := fs.Duration("dependency-interval", 10*time.Second,
+		"time between health checks of -dependency-checks endpoints")
+	degradation := fs.String("degradation", deps.DefaultMatrix,
+		"comma-separated step=action pairs applied while a -dependency-checks step is down: reject (refuse orders), defer (vendor, courier) or fail")
+	traceDumpPath := fs.String("trace-dump", "",
+		"append OTLP JSON span trees of requests sent with X-Debug-Trace: 1 to this file; empty disables")
+	alertSlackURL := fs.String("alert-slack-url", "",
+		"Slack incoming webhook URL receiving operational alerts (tripped kill switches, dependencies down, SLO fast burn, error spikes, failed probes); empty disables")
+	alertPagerDutyKey := fs.String("alert-pagerduty-key", "",
+		"PagerDuty Events API v2 routing key receiving operational alerts; empty disables")
+	alertSMTP := fs.String("alert-smtp", "",
+		"host:port of an SMTP relay mailing operational alerts to -alert-email-to; empty disables")
+	alertEmailFrom := fs.String("alert-email-from", "order-pipeline@localhost",
+		"sender address of alert emails")
+	alertEmailTo := fs.String("alert-email-to", "",
+		"comma-separated recipients of alert emails sent through -alert-smtp")
+	alertThrottle := fs.Duration("alert-throttle", 10*time.Minute,
+		"minimum time between alerts of the same event; repeats in between are counted into the next one")
+	logLevelName := fs.String("log-level", "info",
+		"initial application log level: debug, info, warn or error; changeable via /admin/loglevel")
+	seedCount := fs.Int("seed-orders", 0,
+		"process this many sample orders at startup, so dashboards and admin endpoints have data; 0 disables")
+	sources, err := config.LoadProfiles(fs, args[1:], os.Environ(), profiles)
+	if err != nil {
+		return err
+	}
+	validate = validate || *validateConfig
+
+	const readTimeout = 10 * time.Second
+	const readHeaderTimeout = 3 * time.Second
+	const writeTimeoutMargin = 5 * time.Second
+	const idleTimeout = 60 * time.Second
+	const replaySkew = 30 * time.Second
+	const replayWindow = 5 * time.Minute
+	const replayMaxNonces = 100_000
+	const replayMaxPerClient = 10_000
+	const loadThreshold = 0.8
+	const queueThreshold = 1
+	const accessLogSampleRate = 1.0
+	const accessLogMaxBytes = 100 << 20
+	const accessLogBackups = 5
+	const logSampleRate = 0.1
+	const slowRequestThreshold = 1 * time.Second
+	const slowLogSize = 100
+	const batchCancelWait = 5 * time.Second
+	const batchCancelConcurrency = 16
+	const maintenanceRetryAfter = 60 * time.Second
+	const probeSLO = 1 * time.Second
+	const orderAvailability = 0.999
+	const orderLatencyObjective = 2 * time.Second
+	const anomalyFactor = 5.0
+	const killSwitchThreshold = 0.5
+	const killSwitchMinRequests = 20
+	const killSwitchWindow = 30 * time.Second
+	const killSwitchCooldown = 30 * time.Second
+	const deferredRetryInterval = 5 * time.Second
+	const deferredAttemptTimeout = 5 * time.Second
+	const deferredExpiry = 10 * time.Minute
+	const deferredCapacity = 1000
+	const tailBudget = 5 * time.Second
+	const loyaltyUnitsPerPoint = 100
+	const fakeTaxRateBP = 825
+	const geocodeCacheSize = 10000
+	const fakeGeocodeRadius = 0.1
+	const fakeGeocodeDelay = 10 * time.Millisecond
+	const shadowConcurrency = 16
+	const sidecarHealthInterval = 10 * time.Second
+	const sidecarRestartDelay = 1 * time.Second
+	const dependencyCheckTimeout = 2 * time.Second
+	const dependencyDownAfter = 2
+
+	// Mask personal data before it is logged or stored
+	redactor, err := redact.Parse(*redactSpec)
+	if err != nil {
+		return err
+	}
+
+	// Application logger; the level can be changed at runtime
+	logLevel := new(slog.LevelVar)
+	if err := logLevel.UnmarshalText([]byte(*logLevelName)); err != nil {
+		return fmt.Errorf("-log-level: %w", err)
+	}
+	logOpts := &slog.HandlerOptions{Level: logLevel}
+	if redactor.Enabled() {
+		logOpts.ReplaceAttr = redactor.ReplaceAttr
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, logOpts))
+	if *regionName != "" {
+		logger = logger.With("region", *regionName)
+	}
+	slog.SetDefault(logger)
+
+	// Send warnings of the components watching the pipeline to the
+	// alert sinks, if any, deduplicating repeats
+	var alertSinks []alert.Sink
+	if *alertSlackURL != "" {
+		alertSinks = append(alertSinks, alert.Slack{URL: *alertSlackURL})
+	}
+	if *alertPagerDutyKey != "" {
+		alertSinks = append(alertSinks, alert.PagerDuty{RoutingKey: *alertPagerDutyKey, Source: *regionName})
+	}
+	switch recipients := strings.FieldsFunc(*alertEmailTo, func(r rune) bool { return r == ',' || r == ' ' }); {
+	case *alertSMTP != "" && len(recipients) == 0:
+		return errors.New("-alert-smtp needs -alert-email-to")
+	case *alertSMTP == "" && len(recipients) > 0:
+		return errors.New("-alert-email-to needs -alert-smtp")
+	case *alertSMTP != "":
+		alertSinks = append(alertSinks, alert.Email{Addr: *alertSMTP, From: *alertEmailFrom, To: recipients})
+	}
+	alertLogger := logger
+	if len(alertSinks) > 0 {
+		notifier := alert.New(alert.Config{Throttle: *alertThrottle}, logger, alertSinks...)
+		if !validate {
+			go notifier.Run(context.Background())
+		}
+		alertLogger = slog.New(alert.NewHandler(logger.Handler(), notifier, slog.LevelWarn))
+	}
+
+	// Create bounded concurrency semaphore
+	p := pool.New(*poolSize)
+
+	// Log when the courier pool fills up and how long it stays full
+	p.OnSaturation(func(e pool.SaturationEvent) {
+		switch {
+		case e.Full && e.First:
+			logger.Warn("courier pool saturated for the first time", slog.Int("capacity", p.Cap()))
+		case e.Full:
+			logger.Info("courier pool saturated", slog.Int("capacity", p.Cap()))
+		default:
+			logger.Info("courier pool no longer saturated", slog.Int64("duration_ms", e.Duration.Milliseconds()))
+		}
+	})
+
+	// Resize the courier pool by shift; outside every shift it keeps poolSize
+	schedule, err := pool.ParseSchedule(*courierSchedule, *poolSize)
+	if err != nil {
+		return err
+	}
+	scheduler := pool.NewScheduler(p, schedule)
+	if len(schedule.Shifts) > 0 && !validate {
+		go scheduler.Run(context.Background())
+	}
+
+	// Route courier assignments to per-zone pools; unzoned orders use p
+	zoneCfg, err := courier.ParseZones(*courierZones)
+	if err != nil {
+		return err
+	}
+	zones := courier.NewZones(p, zoneCfg)
+
+	// Set up goroutine tracker
+	tr := &tracker.Tracker{}
+
+	// Notify the vendor's primary endpoint, hedging to the secondary if enabled
+	notifyVendor := func(ctx context.Context, req model.OrderRequest) error {
+		return vendor.Notify(ctx, req, tr)
+	}
+	if *vendorHedgeDelay > 0 {
+		hedger := vendor.NewHedger(notifyVendor, func(ctx context.Context, req model.OrderRequest) error {
+			return vendor.NotifySecondary(ctx, req, tr)
+		}, *vendorHedgeDelay)
+		notifyVendor = hedger.Notify
+	}
+
+	// Charge synthetic traffic to the sandbox account
+	pay := func(ctx context.Context, req model.OrderRequest) error {
+		if traffic.FromContext(ctx) == traffic.Synthetic {
+			return payment.ProcessSandbox(ctx, req, tr)
+		}
+		return payment.Process(ctx, req, tr)
+	}
+
+	// Compute tax ahead of payment, which then charges the taxed total
+	if *taxProvider != "" {
+		var provider tax.Provider
+		switch {
+		case *taxProvider == "fake":
+			provider = tax.Fake{RateBP: fakeTaxRateBP}
+		case strings.HasPrefix(*taxProvider, "http://"), strings.HasPrefix(*taxProvider, "https://"):
+			provider = tax.HTTPProvider{URL: *taxProvider}
+		default:
+			return fmt.Errorf("tax provider %q: want fake or an http(s) URL", *taxProvider)
+		}
+		pay = tax.New(provider, *taxCritical, logger).Wrap(pay)
+	}
+
+	// Geocode delivery addresses ahead of courier assignment, picking
+	// the nearest zone for orders that name none
+	assign := func(ctx context.Context, req model.OrderRequest) error {
+		return courier.Assign(ctx, req, zones.For(req.Zone), tr)
+	}
+	areas, err := geocode.ParseAreas(*zoneCenters)
+	if err != nil {
+		return err
+	}
+	if *geocodeEnabled {
+		var center geocode.Location
+		for _, a := range areas {
+			center.Lat += a.Center.Lat / float64(len(areas))
+			center.Lon += a.Center.Lon / float64(len(areas))
+		}
+		var provider geocode.Provider = geocode.NewCache(geocode.Fake{Center: center, Radius: fakeGeocodeRadius, Delay: fakeGeocodeDelay}, geocodeCacheSize)
+		if *stepMemo {
+			provider = geocode.Memoized(provider)
+		}
+		assign = geocode.NewResolver(provider, areas).Wrap(assign)
+	}
+
+	// Build the pipeline steps
+	steps := []order.Step{
+		{Name: "payment", Run: pay},
+		{Name: "vendor", Run: notifyVendor},
+		{Name: "courier", Run: assign},
+	}
+
+	// Add steps implemented by sidecar processes, kept running and
+	// health-checked until the server exits
+	sidecarCfgs, err := sidecar.ParseSpecs(*sidecarSteps)
+	if err != nil {
+		return err
+	}
+	var sidecars sidecar.Set
+	var sidecarWG sync.WaitGroup
+	sidecarCtx, stopSidecars := context.WithCancel(context.Background())
+	defer func() {
+		stopSidecars()
+		sidecarWG.Wait() // the processes are killed before returning
+	}()
+	for _, cfg := range sidecarCfgs {
+		for _, st := range steps {
+			if st.Name == cfg.Name {
+				return fmt.Errorf("sidecar step %q: name taken by a built-in step", cfg.Name)
+			}
+		}
+		sc := sidecar.New(cfg, sidecarHealthInterval, sidecarRestartDelay, logger)
+		if validate {
+			if _, err := exec.LookPath(cfg.Command[0]); err != nil {
+				return fmt.Errorf("sidecar step %q: %w", cfg.Name, err)
+			}
+		} else {
+			sidecarWG.Go(func() { sc.Run(sidecarCtx) })
+		}
+		sidecars = append(sidecars, sc)
+		steps = append(steps, order.Step{Name: cfg.Name, Run: sc.Step})
+	}
+
+	// Attribute the estimated cost of each step call to the caller's tenant
+	rates, err := cost.ParseRates(*stepCosts)
+	if err != nil {
+		return err
+	}
+	var ledger *cost.Ledger
+	if len(rates) > 0 {
+		ledger = cost.NewLedger(func(ctx context.Context) string {
+			claims, _ := auth.FromContext(ctx)
+			return claims.Tenant
+		})
+		for i := range steps {
+			if rate, ok := rates[steps[i].Name]; ok {
+				steps[i].Run = ledger.Wrap(steps[i].Name, rate, steps[i].Run)
+				delete(rates, steps[i].Name)
+			}
+		}
+		for name := range rates {
+			return fmt.Errorf("step costs: unknown step %q", name)
+		}
+	}
+
+	// Cap concurrent downstream calls, globally and per destination
+	destLimits, err := outbound.ParseLimits(*outboundDestLimits)
+	if err != nil {
+		return err
+	}
+	var outboundLim *outbound.Limiter
+	if *outboundLimit > 0 {
+		outboundLim = outbound.New(*outboundLimit, destLimits)
+		for i := range steps {
+			steps[i].Run = outboundLim.Wrap(steps[i].Name, steps[i].Run)
+		}
+	}
+
+	// Maintenance mode rejects new orders and pauses background work
+	maintenanceMode := maintenance.New(maintenanceRetryAfter)
+	maintenanceMode.Set(*maintenanceOn)
+
+	// Disable steps whose error rate trips their kill switch; deferred
+	// steps are retried in the background while the switch recovers
+	switches := killswitch.NewBoard(killswitch.Config{
+		Threshold:   killSwitchThreshold,
+		MinRequests: killSwitchMinRequests,
+		Window:      killSwitchWindow,
+		Cooldown:    killSwitchCooldown,
+	}, alertLogger)
+	fallbacks, err := killswitch.ParseFallbacks(*stepFallbacks)
+	if err != nil {
+		return err
+	}
+	isStep := func(name string) bool {
+		return slices.ContainsFunc(steps, func(s order.Step) bool { return s.Name == name })
+	}
+	dependencies, err := deps.Parse(*dependencyChecks)
+	if err != nil {
+		return err
+	}
+	matrix, err := deps.ParseMatrix(*degradation)
+	if err != nil {
+		return err
+	}
+	for name := range matrix {
+		if !isStep(name) {
+			return fmt.Errorf("degradation %s: no such step", name)
+		}
+	}
+	// A monitored dependency degraded by deferral defers its step
+	// whenever the step is disabled, by the monitor or its error rate
+	for _, d := range dependencies {
+		if !isStep(d.Name) {
+			return fmt.Errorf("dependency check %s: no such step", d.Name)
+		}
+		if matrix[d.Name] != model.DegradeDefer {
+			continue
+		}
+		if f, ok := fallbacks[d.Name]; ok && f != killswitch.FallbackDefer {
+			return fmt.Errorf("step %s: -degradation defers it but -step-fallbacks sets %s", d.Name, f)
+		}
+		fallbacks[d.Name] = killswitch.FallbackDefer
+	}
+	deferredWork := map[string]string{"courier": "assignment", "vendor": "notification"}
+	var deferredQueue *deferred.Queue
+	for i := range steps {
+		name, run := steps[i].Name, steps[i].Run
+		sw := switches.Register(name)
+		var fallback func(context.Context, model.OrderRequest) error
+		if fallbacks[name] == killswitch.FallbackDefer {
+			work, ok := deferredWork[name]
+			if !ok {
+				return fmt.Errorf("step %s cannot be deferred", name)
+			}
+			if deferredQueue == nil {
+				deferredQueue = deferred.New(deferred.Config{
+					Interval:       deferredRetryInterval,
+					AttemptTimeout: deferredAttemptTimeout,
+					Expiry:         deferredExpiry,
+					Capacity:       deferredCapacity,
+					Paused:         maintenanceMode.On,
+				}, logger)
+				if !validate {
+					go deferredQueue.Run(context.Background())
+				}
+			}
+			retry := sw.WrapStep(run, nil) // fails fast while the switch is still disabled
+			fallback = func(_ context.Context, req model.OrderRequest) error {
+				err := deferredQueue.Enqueue(req.OrderID, name, func(ctx context.Context) error {
+					return retry(ctx, req)
+				})
+				if err != nil {
+					return fmt.Errorf("%s: %w", name, killswitch.ErrDisabled)
+				}
+				return fmt.Errorf("%s: %s %w", name, work, order.ErrDeferred)
+			}
+		}
+		steps[i].Run = sw.WrapStep(run, fallback)
+	}
+
+	// Check upstream provider health in the background; while one is
+	// down, its step's kill switch stays tripped and readiness fails
+	var monitor *deps.Monitor
+	if len(dependencies) > 0 {
+		holdFor := *dependencyInterval + dependencyCheckTimeout // until the next check renews it
+		monitor = deps.New(dependencies, deps.Config{
+			Interval:  *dependencyInterval,
+			Timeout:   dependencyCheckTimeout,
+			DownAfter: dependencyDownAfter,
+			Matrix:    matrix,
+		}, alertLogger,
+			deps.PauseWhile(maintenanceMode.On),
+			deps.OnDown(func(name string) { _ = switches.Trip(name, holdFor, "dependency down") }),
+			deps.OnRecover(func(name string) { _ = switches.Recover(name) }),
+		)
+		if !validate {
+			go monitor.Run(context.Background())
+		}
+	}
+
+	// Record step spans of traced requests, if trace dumps are enabled
+	var traces *tracedump.Exporter
+	if *traceDumpPath != "" && !validate {
+		traces, err = tracedump.Open(*traceDumpPath, "order-pipeline", logger)
+		if err != nil {
+			return err
+		}
+		defer traces.Close()
+		for i := range steps {
+			steps[i].Run = tracedump.WrapStep(steps[i].Name, steps[i].Run)
+		}
+	}
+
+	// Construct the order service
+	var orderOpts []order.Option
+	if *failAtEnd {
+		orderOpts = append(orderOpts, order.FailAtEnd())
+	}
+	if *stepMemo {
+		orderOpts = append(orderOpts, order.Memoize())
+	}
+	if *simulate {
+		orderOpts = append(orderOpts, order.Simulate())
+	}
+	if *maxConcurrentSteps > 0 {
+		if *lateStepGrace > 0 {
+			return fmt.Errorf("-max-concurrent-steps cannot be combined with -late-step-grace")
+		}
+		orderOpts = append(orderOpts, order.MaxConcurrentSteps(*maxConcurrentSteps))
+	}
+	if *stepIdleTimeout > 0 {
+		orderOpts = append(orderOpts, order.StepIdleTimeout(*stepIdleTimeout))
+	}
+	if *lateStepGrace > 0 {
+		orderOpts = append(orderOpts, order.FinishLate(*lateStepGrace, func(orderID string, r model.StepResult) {
+			logger.LogAttrs(context.Background(), slog.LevelInfo, "late step finished",
+				slog.String("order_id", orderID),
+				slog.String("step", r.Name),
+				slog.String("status", r.Status.String()),
+				slog.String("detail", r.Detail),
+				slog.Int64("duration_ms", r.DurationMS),
+			)
+		}))
+	}
+	// Run non-critical tail steps after successful orders, off the
+	// response path; their failures are only logged
+	var tailSteps []order.Step
+	var loyaltyProgram *loyalty.Program
+	if *loyaltyEnabled {
+		loyaltyProgram = loyalty.New(loyalty.NewMemoryStore(), loyaltyUnitsPerPoint)
+		tailSteps = append(tailSteps, order.Step{Name: "loyalty", Run: func(ctx context.Context, req model.OrderRequest) error {
+			if traffic.FromContext(ctx) == traffic.Synthetic {
+				return nil // probes earn no points
+			}
+			return loyaltyProgram.Accrue(ctx, req)
+		}})
+	}
+	if len(tailSteps) > 0 {
+		tail := order.NewTail(order.TailConfig{Budget: tailBudget, SyncWait: *tailSyncWait, Tracker: tr}, func(orderID, step string, err error) {
+			logger.LogAttrs(context.Background(), slog.LevelWarn, "tail step failed",
+				slog.String("order_id", orderID),
+				slog.String("step", step),
+				slog.String("error", err.Error()),
+			)
+		}, tailSteps...)
+		orderOpts = append(orderOpts, order.TailSteps(tail))
+	}
+	orderSvc := order.New(steps, orderOpts...)
+
+	// Probe the pipeline with synthetic orders, alerting in the log
+	var prober *probe.Prober
+	if *probeInterval > 0 {
+		prober = probe.New(orderSvc, *probeInterval, probeSLO, alertLogger, probe.PauseWhile(maintenanceMode.On))
+		if !validate {
+			go prober.Run(context.Background())
+		}
+	}
+
+	// Capture diagnostics for slow orders
+	slowLog := httptransport.NewSlowLog(slowRequestThreshold, slowLogSize, p)
+
+	// Apply per-class deadlines and track latency SLA attainment
+	classes, err := httptransport.ParseSLAClasses(*slaClasses)
+	if err != nil {
+		return err
+	}
+	sla := httptransport.NewSLA(classes...)
+
+	// Flag error kinds spiking above their baseline rate
+	anomalies := httptransport.NewAnomalyDetector(alertLogger, anomalyFactor)
+	if !validate {
+		go anomalies.Run(context.Background())
+	}
+
+	// Keep dashboard read models of order outcomes
+	projection := httptransport.NewProjection()
+
+	// Let operators cancel orders in flight in bulk
+	canceller := httptransport.NewCanceller(batchCancelWait, batchCancelConcurrency)
+
+	// Decorate order processing; the audit trail, if enabled, is outermost
+	processor := projection.Wrap(anomalies.Wrap(slowLog.Wrap(sla.Wrap(canceller.Wrap(orderSvc)))))
+
+	// Count outcomes and latency per order source channel, if configured
+	channelNames, err := httptransport.ParseChannels(*orderChannels)
+	if err != nil {
+		return err
+	}
+	var channels *httptransport.Channels
+	if len(channelNames) > 0 {
+		channels = httptransport.NewChannels(channelNames...)
+		processor = channels.Wrap(processor)
+	}
+
+	// Publish order outcomes to subscribed webhook endpoints, if enabled
+	var webhooks *httptransport.Webhooks
+	if *webhooksOn {
+		dispatcher := webhook.New(webhook.Config{AllowPrivate: *webhooksAllowPrivate}, logger)
+		if !validate {
+			go dispatcher.Run(context.Background())
+		}
+		webhooks = httptransport.NewWebhooks(dispatcher)
+		processor = webhooks.Wrap(processor)
+	}
+
+	var trail *httptransport.AuditTrail
+	if *auditLogPath != "" && !validate {
+		auditLog, err := audit.Open(*auditLogPath)
+		if err != nil {
+			return err
+		}
+		defer auditLog.Close()
+		trail = httptransport.NewAuditTrail(auditLog, logger, redactor)
+		processor = trail.Wrap(processor)
+	}
+
+	// Record selected orders with the configuration in effect, for replay
+	if *recordPath != "" && !validate {
+		recordings, err := recording.Open(*recordPath)
+		if err != nil {
+			return err
+		}
+		defer recordings.Close()
+		config := map[string]string{}
+		fs.VisitAll(func(f *flag.Flag) { config[f.Name] = f.Value.String() })
+		recorder := httptransport.NewRecorder(recordings, config, strings.Split(*recordOrders, ","), logger)
+		processor = recorder.Wrap(processor)
+	}
+
+	// Mirror sampled orders to a shadow deployment, comparing outcomes
+	// by mismatch category
+	var shadow *httptransport.Shadow
+	if *shadowURL != "" {
+		shadow = httptransport.NewShadow(httptransport.HTTPShadow{URL: *shadowURL, Secret: []byte(*replaySecret)}, *shadowRate, *requestTimeout, shadowConcurrency, logger)
+		processor = shadow.Wrap(processor)
+	}
+
+	// Pin orders to their region, pointing those of peer regions there
+	peers, err := httptransport.ParseRegionPeers(*regionPeers)
+	if err != nil {
+		return err
+	}
+	var region *httptransport.Region
+	switch {
+	case *regionName != "":
+		if _, ok := peers[*regionName]; ok {
+			return fmt.Errorf("region %s is listed in -region-peers", *regionName)
+		}
+		region = httptransport.NewRegion(*regionName, peers)
+	case len(peers) > 0:
+		return errors.New("-region-peers needs -region")
+	}
+
+	// Construct the HTTP handler, answering failed orders per error kind
+	statuses, err := httptransport.ParseStatusMap(*errorStatuses)
+	if err != nil {
+		return err
+	}
+	timeoutMode, err := httptransport.ParseTimeoutResponse(*timeoutResponse)
+	if err != nil {
+		return err
+	}
+	handlerOpts := []httptransport.Option{
+		httptransport.WithStatusMap(statuses),
+		httptransport.WithTimeoutResponse(timeoutMode),
+	}
+	if loyaltyProgram != nil {
+		handlerOpts = append(handlerOpts, httptransport.WithLoyaltyPoints(loyaltyProgram.Accrued))
+	}
+	if channels != nil {
+		handlerOpts = append(handlerOpts, httptransport.WithChannels(channels))
+	}
+	if region != nil {
+		handlerOpts = append(handlerOpts, httptransport.WithRegion(region))
+	}
+	if monitor != nil {
+		handlerOpts = append(handlerOpts, httptransport.WithDegradations(monitor.Degradations))
+	}
+	if *coalesceOrders {
+		handlerOpts = append(handlerOpts, httptransport.WithCoalescing())
+	}
+	handlerOpts = append(handlerOpts, httptransport.WithSLA(sla))
+	if *splitOrders {
+		handlerOpts = append(handlerOpts, httptransport.WithSubOrders())
+	}
+	// Rewrite orders per tenant, then route them by the policy rules
+	routing, err := policy.Parse(*policyRules)
+	if err != nil {
+		return err
+	}
+	if *orderHooks != "" || len(routing.Rules()) > 0 {
+		tenantOf := func(ctx context.Context) string {
+			claims, _ := auth.FromContext(ctx)
+			return claims.Tenant
+		}
+		hooks := httptransport.NewHooks(tenantOf)
+		if err := httptransport.ParseHooks(*orderHooks, hooks); err != nil {
+			return err
+		}
+		if len(routing.Rules()) > 0 {
+			hooks.OnRequest(httptransport.AllTenants, func(ctx context.Context, req *model.OrderRequest) {
+				*req = routing.Evaluate(tenantOf(ctx), time.Now(), *req).Order
+			})
+		}
+		handlerOpts = append(handlerOpts, httptransport.WithHooks(hooks))
+	}
+	h := httptransport.New(processor, *requestTimeout, handlerOpts...)
+
+	// Let operators tune the server timeouts and the request timeout at
+	// runtime, for new requests and connections
+	timeouts := httptransport.NewTimeouts(readTimeout, *requestTimeout+writeTimeoutMargin, idleTimeout, h)
+
+	// Track client connections, capping how many may be open
+	conns := httptransport.NewConnManager(*maxConnections)
+
+	// Reject replayed order submissions
+	var replay *httptransport.ReplayGuard
+	if *replayGuard {
+		replay = httptransport.NewReplayGuard(replaySkew, replayWindow, replayMaxNonces, replayMaxPerClient, []byte(*replaySecret))
+	}
+
+	// Advertise courier pool load to clients
+	bp := httptransport.NewBackpressure(p, loadThreshold, queueThreshold)
+
+	// Serve a live web dashboard
+	dashboard := httptransport.NewDashboard(projection, bp, tr)
+
+	// Report what this instance runs: build, effective flags, steps,
+	// limits and the optional features enabled
+	features := map[string]bool{
+		"access_log":         *accessLogPath != "",
+		"admin_listener":     *adminListenSpec != "",
+		"alerting":           len(alertSinks) > 0,
+		"audit_log":          *auditLogPath != "",
+		"authentication":     *oidcIssuer != "",
+		"channels":           channels != nil,
+		"connection_limit":   *maxConnections > 0,
+		"cost_attribution":   ledger != nil,
+		"courier_schedule":   len(schedule.Shifts) > 0,
+		"courier_zones":      len(zoneCfg) > 0,
+		"deferred_steps":     deferredQueue != nil,
+		"dependency_monitor": monitor != nil,
+		"fail_at_end":        *failAtEnd,
+		"geocode":            *geocodeEnabled,
+		"ip_acl":             *allowCIDRs != "" || *denyCIDRs != "",
+		"late_step_grace":    *lateStepGrace > 0,
+		"loyalty":            loyaltyProgram != nil,
+		"order_coalescing":   *coalesceOrders,
+		"order_hooks":        *orderHooks != "",
+		"outbound_limits":    outboundLim != nil,
+		"policy_routing":     len(routing.Rules()) > 0,
+		"probe":              *probeInterval > 0,
+		"recording":          *recordPath != "",
+		"redaction":          redactor.Enabled(),
+		"replay_guard":       replay != nil,
+		"region_pinning":     region != nil,
+		"shadow":             shadow != nil,
+		"sidecar_steps":      len(sidecars) > 0,
+		"simulation":         *simulate,
+		"step_idle_timeout":  *stepIdleTimeout > 0,
+		"step_limit":         *maxConcurrentSteps > 0,
+		"step_memo":          *stepMemo,
+		"sub_orders":         *splitOrders,
+		"tax":                *taxProvider != "",
+		"tolerant_reader":    *tolerantRoutes != "",
+		"trace_dump":         *traceDumpPath != "",
+		"vendor_hedging":     *vendorHedgeDelay > 0,
+		"webhooks":           webhooks != nil,
+	}
+	effective := effectiveConfig(fs, sources, steps, tailSteps, *poolSize, zoneCfg, *outboundLimit, destLimits, features)
+	info := model.InstanceInfo{Build: buildInfo(), StartedAt: time.Now().UTC().Format(time.RFC3339), Config: effective}
+
+	// Decode the bodies of tolerant routes ignoring unknown fields
+	tolerated, err := httptransport.ParseTolerantRoutes(*tolerantRoutes)
+	if err != nil {
+		return err
+	}
+	tolerant := httptransport.NewTolerantReader(tolerated, logger)
+
+	// Honor the synthetic traffic marker only from trusted networks or
+	// callers with the synthetic role
+	syntheticNets, err := netacl.ParsePrefixes(*syntheticFrom)
+	if err != nil {
+		return err
+	}
+	syntheticTrusted := func(r *http.Request) bool {
+		if ip, ok := netacl.FromContext(r.Context()); ok && netacl.Contains(syntheticNets, ip) {
+			return true
+		}
+		claims, ok := auth.FromContext(r.Context())
+		return ok && claims.HasRole("synthetic")
+	}
+
+	// Set up routing
+	mux := http.NewServeMux()
+	orderSwitch := switches.Register("/order")
+	intakeFallback := killswitch.Fallback{Status: statuses.Status("disabled"), Message: "order intake is temporarily disabled"}
+	var orderRoute http.Handler = http.HandlerFunc(h.HandleOrder)
+	if replay != nil {
+		orderRoute = replay.Middleware(orderRoute)
+	}
+	orderRoute = orderSwitch.Middleware(intakeFallback, traffic.Middleware(syntheticTrusted, bp.Middleware(orderRoute)))
+	orderRoute = maintenanceMode.Middleware(orderRoute)
+	if traces != nil {
+		orderRoute = traces.Middleware(orderRoute)
+	}
+	mux.Handle("/order", orderRoute)
+	mux.HandleFunc("/capacity", bp.HandleCapacity)
+	mux.HandleFunc("/admin/loglevel", httptransport.HandleLogLevel(logLevel))
+	mux.HandleFunc("/admin/slowlog", slowLog.HandleSlowLog)
+	mux.HandleFunc("/admin/orders:batchCancel", canceller.HandleBatchCancel)
+	mux.HandleFunc("/admin/orders/progress", canceller.HandleProgress)
+	mux.HandleFunc("/admin/pool/schedule", httptransport.HandlePoolSchedule(scheduler))
+	mux.HandleFunc("/admin/pool/telemetry", httptransport.HandlePoolTelemetry(p))
+	mux.HandleFunc("/admin/courier/zones", httptransport.HandleCourierZones(zones))
+	mux.HandleFunc("/admin/sla", sla.HandleSLA)
+	mux.HandleFunc("/admin/anomalies", anomalies.HandleAnomalies)
+	mux.HandleFunc("/dashboard", dashboard.HandlePage)
+	mux.HandleFunc("/dashboard/data", dashboard.HandleData)
+	mux.HandleFunc("/dashboard/static/", dashboard.HandleStatic)
+	mux.HandleFunc("/admin/dashboard/statuses", projection.HandleStatusCounts)
+	mux.HandleFunc("/admin/dashboard/failures", projection.HandleTopFailures)
+	mux.HandleFunc("/admin/killswitches", httptransport.HandleKillSwitches(switches))
+	mux.HandleFunc("/admin/maintenance", httptransport.HandleMaintenance(maintenanceMode, projection))
+	mux.HandleFunc("/admin/info", httptransport.HandleInfo(info, timeouts))
+	mux.HandleFunc("/admin/timeouts", timeouts.HandleTimeouts)
+	mux.HandleFunc("/admin/connections", conns.HandleConnections)
+	mux.HandleFunc("/admin/unknown-fields", tolerant.HandleUnknownFields)
+	if deferredQueue != nil {
+		mux.HandleFunc("/admin/deferred", httptransport.HandleDeferred(deferredQueue))
+	}
+	if outboundLim != nil {
+		mux.HandleFunc("/admin/outbound", httptransport.HandleOutbound(outboundLim))
+	}
+	if *maxConcurrentSteps > 0 {
+		mux.HandleFunc("/admin/steps/limit", httptransport.HandleStepLimit(orderSvc))
+	}
+	if trail != nil {
+		mux.HandleFunc("/admin/audit/verify", trail.HandleVerify)
+	}
+	if prober != nil {
+		mux.HandleFunc("/admin/probe", httptransport.HandleProbe(prober))
+	}
+	if ledger != nil {
+		mux.HandleFunc("/admin/costs", httptransport.HandleCosts(ledger))
+	}
+	if channels != nil {
+		mux.HandleFunc("/admin/channels", channels.HandleChannels)
+	}
+	if webhooks != nil {
+		mux.HandleFunc("/admin/webhooks", webhooks.HandleWebhooks)
+		mux.HandleFunc("/admin/webhooks/deliveries", webhooks.HandleDeliveries)
+	}
+	if region != nil {
+		mux.HandleFunc("/admin/region", region.HandleRegion)
+	}
+	if shadow != nil {
+		mux.HandleFunc("/admin/shadow", shadow.HandleShadow)
+	}
+	if len(routing.Rules()) > 0 {
+		mux.HandleFunc("/admin/policy", httptransport.HandlePolicy(routing))
+	}
+	if len(sidecars) > 0 {
+		mux.HandleFunc("/admin/sidecars", httptransport.HandleSidecars(sidecars))
+	}
+	if monitor != nil {
+		mux.HandleFunc("/admin/dependencies", httptransport.HandleDependencies(monitor))
+		mux.HandleFunc("/readyz", httptransport.HandleReadiness(monitor))
+	}
+
+	// Track availability and latency objectives; alert on fast budget burn
+	slo := httptransport.NewSLO(alertLogger, httptransport.SLOObjective{
+		Route: "/order", Availability: orderAvailability, Latency: orderLatencyObjective,
+	})
+	mux.HandleFunc("/admin/slo", slo.HandleSLO)
+
+	// Authenticate requests with the OIDC issuer's JWTs, if enabled;
+	// admin routes and the dashboard also require the admin role
+	var routes http.Handler = mux
+	if *oidcIssuer != "" {
+		verifier := auth.NewVerifier(auth.Config{
+			Issuer:   *oidcIssuer,
+			Audience: *oidcAudience,
+			JWKSURL:  *oidcJWKSURL,
+			Public:   probePaths,
+		})
+		for _, prefix := range adminPrefixes {
+			routes = auth.RequireRole(prefix, "admin", routes)
+		}
+		routes = verifier.Middleware(routes)
+	}
+	routes = tolerant.Middleware(routes)
+
+	// Resolve client addresses behind trusted proxies and refuse those
+	// outside the allow list or in the deny list
+	allow, err := netacl.ParsePrefixes(*allowCIDRs)
+	if err != nil {
+		return err
+	}
+	deny, err := netacl.ParsePrefixes(*denyCIDRs)
+	if err != nil {
+		return err
+	}
+	proxies, err := netacl.ParsePrefixes(*trustedProxies)
+	if err != nil {
+		return err
+	}
+	acl := netacl.New(netacl.Config{Allow: allow, Deny: deny, TrustedProxies: proxies})
+
+	// Log request outcomes: errors and slow requests in full, successes sampled
+	reqLog := httptransport.NewRequestLogger(logger, logSampleRate, slowRequestThreshold)
+
+	// Open the access log, if enabled
+	var access *accesslog.Logger
+	if *accessLogPath != "" {
+		format, err := accesslog.ParseFormat(*accessLogFormat)
+		if err != nil {
+			return err
+		}
+		var w io.WriteCloser = nopCloser{io.Discard}
+		if !validate {
+			w, err = openAccessLog(*accessLogPath, accessLogMaxBytes, accessLogBackups)
+			if err != nil {
+				return err
+			}
+		}
+		defer w.Close()
+
+		access = accesslog.New(w, accesslog.Config{
+			Format:     format,
+			SampleRate: accessLogSampleRate,
+			Routes:     map[string]bool{"/capacity": false}, // polled by clients
+		})
+	}
+
+	// Configure an HTTP server for routes; its read, write and idle
+	// timeouts are enforced by timeouts so they can be tuned at runtime
+	newServer := func(routes http.Handler) *http.Server {
+		handler := acl.Filter(reqLog.Middleware(slo.Middleware(routes)))
+		if region != nil {
+			handler = region.Middleware(handler)
+		}
+		if access != nil {
+			handler = access.Middleware(handler)
+		}
+		return &http.Server{
+			Handler:           timeouts.Middleware(acl.RealIP(handler)),
+			ReadHeaderTimeout: readHeaderTimeout,
+			ConnState: func(c net.Conn, state http.ConnState) {
+				timeouts.ConnState(c, state)
+				conns.ConnState(c, state)
+			},
+		}
+	}
+
+	// Serve /admin/* and the dashboard to administrators only: on
+	// -listen behind the admin role with -oidc-issuer, and on -admin-listen
+	publicRoutes := routes
+	if *oidcIssuer == "" {
+		publicRoutes = withoutAdmin(routes)
+	}
+	srv := newServer(publicRoutes)
+	var adminSrv *http.Server
+	switch {
+	case *adminListenSpec == "systemd":
+		return errors.New("-admin-listen: want host:port or unix:<path>")
+	case *adminListenSpec != "":
+		adminSrv = newServer(onlyAdmin(routes))
+	}
+
+	// Stop here when only validating, reporting what would be served
+	if validate {
+		return writeEffectiveConfig(out, effective)
+	}
+
+	ln, err := listen(*listenSpec)
+	if err != nil {
+		return err
+	}
+	ln = conns.Listener(ln)
+
+	logger.Info("listening", "network", ln.Addr().Network(), "addr", ln.Addr().String())
+
+	errc := make(chan error, 2)
+	if adminSrv != nil {
+		adminLn, err := listen(*adminListenSpec)
+		if err != nil {
+			return err
+		}
+		adminLn = conns.Listener(adminLn)
+		logger.Info("listening for admin routes", "network", adminLn.Addr().Network(), "addr", adminLn.Addr().String())
+		go func() { errc <- adminSrv.Serve(adminLn) }()
+	}
+
+	// Give a playground something to show
+	if *seedCount > 0 {
+		go seedOrders(context.Background(), processor, sampleOrders(*seedCount), *requestTimeout, logger)
+	}
+
+	go func() { errc <- srv.Serve(ln) }()
+	if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// probePaths are the routes load balancers and orchestrators poll. They
+// are served without a token, so probes keep working with -oidc-issuer.
+var probePaths = []string{"/readyz", "/capacity"}
+
+// adminPrefixes are the path prefixes of the routes served to
+// administrators only: the admin API and the dashboard.
+var adminPrefixes = []string{"/admin/", "/dashboard"}
+
+// isAdminPath reports whether path is under one of adminPrefixes.
+func isAdminPath(path string) bool {
+	for _, prefix := range adminPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutAdmin serves the requests of h outside the admin routes and
+// answers 404 for those inside them.
+func withoutAdmin(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAdminPath(r.URL.Path) {
+			http.NotFound(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// onlyAdmin serves the admin routes of h and answers 404 for the
+// others.
+func onlyAdmin(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAdminPath(r.URL.Path) {
+			http.NotFound(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// openAccessLog returns the access-log destination for path.
+//
+// "-" selects stdout; any other value opens a size-rotated file.
+func openAccessLog(path string, maxBytes int64, backups int) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return accesslog.OpenRotatingFile(path, maxBytes, backups)
+}
+
+// nopCloser adapts a writer that must not be closed, such as stdout.
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
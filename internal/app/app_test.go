@@ -0,0 +1,155 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	auditPath := filepath.Join(dir, "audit.log")
+	regular := filepath.Join(dir, "regular")
+	if err := os.WriteFile(regular, nil, 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	var out bytes.Buffer
+	err := Validate([]string{"configcheck",
+		"-listen", "unix:" + regular, // listening would fail: not a socket
+		"-audit-log", auditPath,
+		"-courier-zones", "north=3",
+		"-outbound-limit", "4", "-outbound-dest-limits", "payment=2",
+		"-loyalty",
+		"-shadow-url", "http://ops:pw@staging/order",
+	}, &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var cfg model.EffectiveConfig
+	if err := json.Unmarshal(out.Bytes(), &cfg); err != nil {
+		t.Fatalf("decode %q: %v", out.String(), err)
+	}
+	if want := []string{"payment", "vendor", "courier"}; !slices.Equal(cfg.Steps, want) {
+		t.Fatalf("expected steps %v, got %v", want, cfg.Steps)
+	}
+	if want := []string{"loyalty"}; !slices.Equal(cfg.TailSteps, want) {
+		t.Fatalf("expected tail steps %v, got %v", want, cfg.TailSteps)
+	}
+	if cfg.Limits["courier_zone:north"] != 3 || cfg.Limits["outbound:payment"] != 2 || cfg.Flags["outbound-limit"] != "4" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if !cfg.Features["loyalty"] || !cfg.Features["audit_log"] || cfg.Features["tax"] {
+		t.Fatalf("unexpected features %v", cfg.Features)
+	}
+	if got := cfg.Flags["shadow-url"]; strings.Contains(got, "pw") {
+		t.Fatalf("expected the shadow password redacted, got %q", got)
+	}
+	if cfg.Sources["outbound-limit"] != "flag" || cfg.Sources["listen"] != "flag" || cfg.Sources["tax-provider"] != "" {
+		t.Fatalf("unexpected sources %v", cfg.Sources)
+	}
+	if _, err := os.Stat(auditPath); !os.IsNotExist(err) {
+		t.Fatalf("expected the audit log not to be opened, got %v", err)
+	}
+}
+
+func TestValidate_DevProfile(t *testing.T) {
+	var out bytes.Buffer
+	if err := Validate([]string{"configcheck", "-profile", "dev", "-seed-orders", "5"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var cfg model.EffectiveConfig
+	if err := json.Unmarshal(out.Bytes(), &cfg); err != nil {
+		t.Fatalf("decode %q: %v", out.String(), err)
+	}
+	if !cfg.Features["tax"] || !cfg.Features["geocode"] || !cfg.Features["loyalty"] || !cfg.Features["audit_log"] {
+		t.Fatalf("expected the dev features on, got %v", cfg.Features)
+	}
+	// Flags override the profile.
+	if cfg.Flags["seed-orders"] != "5" || cfg.Sources["seed-orders"] != "flag" {
+		t.Fatalf("expected the seed-orders flag to win, got %q from %q", cfg.Flags["seed-orders"], cfg.Sources["seed-orders"])
+	}
+	if cfg.Flags["log-level"] != "debug" || cfg.Sources["log-level"] != "profile" {
+		t.Fatalf("expected debug logs from the profile, got %q from %q", cfg.Flags["log-level"], cfg.Sources["log-level"])
+	}
+}
+
+func TestValidate_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "bad_policy", args: []string{"-policy-rules", "zone"}, want: "policy"},
+		{name: "unknown_cost_step", args: []string{"-step-costs", "fraud=acme:eu:10"}, want: "unknown step"},
+		{name: "missing_sidecar", args: []string{"-sidecar-steps", "fraud=/nonexistent/fraud-check"}, want: "fraud"},
+		{name: "taken_sidecar_name", args: []string{"-sidecar-steps", "payment=true"}, want: "built-in"},
+		{name: "unknown_profile", args: []string{"-profile", "staging"}, want: "unknown profile"},
+		{name: "systemd_admin_listener", args: []string{"-admin-listen", "systemd"}, want: "-admin-listen"},
+		{name: "bad_sla_classes", args: []string{"-sla-classes", "express"}, want: "sla class"},
+		{name: "bad_log_level", args: []string{"-log-level", "loud"}, want: "-log-level"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := Validate(append([]string{"configcheck"}, tt.args...), &out)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+			if out.Len() != 0 {
+				t.Fatalf("expected no config output, got %q", out.String())
+			}
+		})
+	}
+}
+
+// Without -oidc-issuer the admin routes and the dashboard are served
+// only on the admin listener, and the admin listener serves nothing else.
+func TestAdminRouting(t *testing.T) {
+	t.Parallel()
+
+	routes := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+	public, admin := withoutAdmin(routes), onlyAdmin(routes)
+
+	tests := []struct {
+		path       string
+		wantPublic int
+		wantAdmin  int
+	}{
+		{path: "/order", wantPublic: http.StatusOK, wantAdmin: http.StatusNotFound},
+		{path: "/admin/webhooks", wantPublic: http.StatusNotFound, wantAdmin: http.StatusOK},
+		{path: "/admin/", wantPublic: http.StatusNotFound, wantAdmin: http.StatusOK},
+		{path: "/administrator", wantPublic: http.StatusOK, wantAdmin: http.StatusNotFound},
+		{path: "/dashboard", wantPublic: http.StatusNotFound, wantAdmin: http.StatusOK},
+		{path: "/dashboard/data", wantPublic: http.StatusNotFound, wantAdmin: http.StatusOK},
+		{path: "/dashboard/static/dashboard.js", wantPublic: http.StatusNotFound, wantAdmin: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+
+			for _, c := range []struct {
+				name string
+				h    http.Handler
+				want int
+			}{{"public", public, tt.wantPublic}, {"admin", admin, tt.wantAdmin}} {
+				w := httptest.NewRecorder()
+				c.h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+				if w.Code != c.want {
+					t.Fatalf("%s: expected %d, got %d", c.name, c.want, w.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"net/url"
+	"runtime/debug"
+	"slices"
+	"strings"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/config"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/order"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/courier"
+)
+
+// Version is the release the binary was built from, set at link time:
+//
+//	go build -ldflags "-X github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/app.Version=v1.4.0" ./cmd/server
+//
+// When empty, the main module version recorded by the go tool is used.
+var Version string
+
+// redacted replaces secret flag values in reports.
+const redacted = "REDACTED"
+
+// secretFlagWords mark flags whose whole value is a secret.
+var secretFlagWords = []string{"secret", "password", "token", "key"}
+
+// secretFlags are the other flags whose whole value is a secret, such
+// as a Slack incoming webhook URL, whose path is its credential.
+var secretFlags = []string{"alert-slack-url"}
+
+// redactFlag returns value as it may be reported for the flag name:
+// flags named like a secret are replaced entirely, and URLs lose the
+// password of their user info.
+func redactFlag(name, value string) string {
+	if value == "" {
+		return value
+	}
+	if slices.Contains(secretFlags, name) {
+		return redacted
+	}
+	for _, w := range secretFlagWords {
+		if strings.Contains(name, w) {
+			return redacted
+		}
+	}
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			return u.String()
+		}
+	}
+	return value
+}
+
+// effectiveConfig reports every flag's value in effect, defaults
+// included and secrets redacted, where the others came from, and what the flags resolved to: the
+// pipeline and tail steps in run order, the concurrency limits and the
+// optional features enabled.
+func effectiveConfig(fs *flag.FlagSet, sources map[string]config.Source, steps, tail []order.Step, poolSize int, zones []courier.Zone, outboundLimit int, destLimits map[string]int, features map[string]bool) model.EffectiveConfig {
+	cfg := model.EffectiveConfig{
+		Flags:     map[string]string{},
+		Sources:   map[string]string{},
+		Steps:     []string{},
+		TailSteps: []string{},
+		Limits:    map[string]int{"courier_pool": poolSize},
+		Features:  features,
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		cfg.Flags[f.Name] = redactFlag(f.Name, f.Value.String())
+		if src := sources[f.Name]; src != config.Default {
+			cfg.Sources[f.Name] = string(src)
+		}
+	})
+	for _, st := range steps {
+		cfg.Steps = append(cfg.Steps, st.Name)
+	}
+	for _, st := range tail {
+		cfg.TailSteps = append(cfg.TailSteps, st.Name)
+	}
+	for _, z := range zones {
+		cfg.Limits["courier_zone:"+z.Name] = z.Size
+	}
+	if outboundLimit > 0 {
+		cfg.Limits["outbound"] = outboundLimit
+		for dest, n := range destLimits {
+			cfg.Limits["outbound:"+dest] = n
+		}
+	}
+	return cfg
+}
+
+// writeEffectiveConfig writes cfg to w as indented JSON.
+func writeEffectiveConfig(w io.Writer, cfg model.EffectiveConfig) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cfg)
+}
+
+// buildInfo reports the binary's version and the VCS state it was
+// built from, as far as the go tool recorded it.
+func buildInfo() model.BuildInfo {
+	b := model.BuildInfo{Version: Version}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		if b.Version == "" {
+			b.Version = "unknown"
+		}
+		return b
+	}
+	if b.Version == "" {
+		b.Version = bi.Main.Version
+	}
+	b.GoVersion = bi.GoVersion
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			b.Commit = s.Value
+		case "vcs.time":
+			b.CommitTime = s.Value
+		case "vcs.modified":
+			b.Modified = s.Value == "true"
+		}
+	}
+	return b
+}
@@ -0,0 +1,47 @@
+package app
+
+import "testing"
+
+func TestRedactFlag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		flag  string
+		value string
+		want  string
+	}{
+		{name: "plain", flag: "listen", value: "127.0.0.1:8080", want: "127.0.0.1:8080"},
+		{name: "secret_name", flag: "webhook-secret", value: "s3cr3t", want: redacted},
+		{name: "secret_flag", flag: "alert-slack-url", value: "https://hooks.slack.com/services/T0/B0/x", want: redacted},
+		{name: "empty_secret", flag: "api-token", value: "", want: ""},
+		{name: "url_password", flag: "shadow-url", value: "https://ops:pw@staging:8080/order", want: "https://ops:" + redacted + "@staging:8080/order"},
+		{name: "url_user_only", flag: "shadow-url", value: "https://ops@staging/order", want: "https://ops@staging/order"},
+		{name: "url_plain", flag: "tax-provider", value: "https://tax.internal/v1", want: "https://tax.internal/v1"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := redactFlag(tt.flag, tt.value); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	old := Version
+	t.Cleanup(func() { Version = old })
+
+	Version = "v1.4.0"
+	if got := buildInfo(); got.Version != "v1.4.0" || got.GoVersion == "" {
+		t.Fatalf("expected the link-time version, got %+v", got)
+	}
+	Version = ""
+	if got := buildInfo(); got.Version == "" {
+		t.Fatalf("expected the module version, got %+v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd
+// socket activation (SD_LISTEN_FDS_START).
+const listenFDsStart = 3
+
+// listen creates the server listener described by spec.
+//
+// Supported forms:
+//   - "host:port"   — TCP listener
+//   - "unix:<path>" — Unix domain socket; a stale socket file is removed
+//   - "systemd"     — first socket inherited via LISTEN_FDS
+func listen(spec string) (net.Listener, error) {
+	switch {
+	case spec == "systemd":
+		return systemdListener()
+	case strings.HasPrefix(spec, "unix:"):
+		return unixListener(strings.TrimPrefix(spec, "unix:"))
+	default:
+		return net.Listen("tcp", spec)
+	}
+}
+
+// unixListener listens on a Unix domain socket at path.
+func unixListener(path string) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("listen: empty unix socket path")
+	}
+	// Remove a socket left behind by a previous run; refuse to touch
+	// anything that is not a socket.
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("listen: %s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("listen: remove stale socket: %w", err)
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// systemdListener returns the first listener passed by systemd.
+//
+// It validates LISTEN_PID against the current process and unsets the
+// activation variables so they are not inherited by child processes.
+func systemdListener() (net.Listener, error) {
+	defer func() {
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, errors.New("listen: no systemd sockets for this process (LISTEN_PID)")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, errors.New("listen: no systemd sockets passed (LISTEN_FDS)")
+	}
+
+	f := os.NewFile(uintptr(listenFDsStart), "LISTEN_FD_3")
+	defer f.Close() // FileListener dups the descriptor
+
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("listen: systemd socket: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListen(t *testing.T) {
+	dir := t.TempDir()
+	sock := filepath.Join(dir, "order.sock")
+	regular := filepath.Join(dir, "regular")
+	if err := os.WriteFile(regular, nil, 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		spec     string
+		wantNet  string
+		wantFail bool
+	}{
+		{name: "tcp", spec: "127.0.0.1:0", wantNet: "tcp"},
+		{name: "unix", spec: "unix:" + sock, wantNet: "unix"},
+		{name: "unix_empty_path", spec: "unix:", wantFail: true},
+		{name: "unix_not_socket", spec: "unix:" + regular, wantFail: true},
+		{name: "systemd_not_activated", spec: "systemd", wantFail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := listen(tt.spec)
+			if tt.wantFail {
+				if err == nil {
+					ln.Close()
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer ln.Close()
+
+			if got := ln.Addr().Network(); got != tt.wantNet {
+				t.Fatalf("expected network %q, got %q", tt.wantNet, got)
+			}
+		})
+	}
+}
+
+// A socket file left by a previous run must not block startup.
+func TestListenUnixStaleSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "order.sock")
+
+	first, err := unixListener(sock)
+	if err != nil {
+		t.Fatalf("first listen: %v", err)
+	}
+	// Simulate a crash: keep the file, drop the listener.
+	first.(interface{ SetUnlinkOnClose(bool) }).SetUnlinkOnClose(false)
+	first.Close()
+
+	second, err := unixListener(sock)
+	if err != nil {
+		t.Fatalf("second listen: %v", err)
+	}
+	second.Close()
+}
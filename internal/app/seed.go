@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// orderProcessor is the part of the decorated order processor the
+// seeder uses.
+type orderProcessor interface {
+	Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error)
+}
+
+// sampleOrders returns n sample orders for a development playground:
+// varied amounts, step delays and service classes, with every fifth
+// order failing at a step in turn. The same n always gives the same
+// orders.
+func sampleOrders(n int) []model.OrderRequest {
+	failSteps := []string{"vendor", "payment", "courier"}
+	out := make([]model.OrderRequest, n)
+	for i := range out {
+		req := model.OrderRequest{
+			OrderID: "sample-" + strconv.Itoa(i+1),
+			Amount:  uint64(500 + 250*(i%7)),
+			DelayMS: map[string]int64{"vendor": int64(20 * (i % 4)), "courier": int64(15 * (i % 3))},
+		}
+		if i%3 == 2 {
+			req.SLA = "express"
+		}
+		if i%5 == 4 {
+			req.FailStep = failSteps[(i/5)%len(failSteps)]
+		}
+		out[i] = req
+	}
+	return out
+}
+
+// seedOrders runs reqs through p one at a time, each bounded by
+// timeout, so dashboards and admin endpoints have data to show, and
+// logs how many failed. Pooled results are released.
+func seedOrders(ctx context.Context, p orderProcessor, reqs []model.OrderRequest, timeout time.Duration, logger *slog.Logger) {
+	failed := 0
+	for _, req := range reqs {
+		octx, cancel := context.WithTimeout(ctx, timeout)
+		steps, err := p.Process(octx, req)
+		cancel()
+		if r, ok := p.(interface{ Release([]model.StepResult) }); ok {
+			r.Release(steps)
+		}
+		if err != nil {
+			failed++
+		}
+		logger.Debug("sample order processed", slog.String("order_id", req.OrderID), slog.Any("err", err))
+		if ctx.Err() != nil {
+			return
+		}
+	}
+	logger.Info("seeded sample orders", slog.Int("orders", len(reqs)), slog.Int("failed", failed))
+}
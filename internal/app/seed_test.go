@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// seedProcessor fails orders with a FailStep and counts releases.
+type seedProcessor struct {
+	seen     []string
+	released int
+}
+
+func (p *seedProcessor) Process(_ context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	p.seen = append(p.seen, req.OrderID)
+	if req.FailStep != "" {
+		return nil, errors.New(req.FailStep + " failed")
+	}
+	return []model.StepResult{{Name: "payment", Status: model.StatusOK}}, nil
+}
+
+func (p *seedProcessor) Release([]model.StepResult) { p.released++ }
+
+func TestSampleOrders(t *testing.T) {
+	t.Parallel()
+
+	orders := sampleOrders(15)
+	if !slices.EqualFunc(orders, sampleOrders(15), func(a, b model.OrderRequest) bool {
+		return a.OrderID == b.OrderID && a.Amount == b.Amount && a.FailStep == b.FailStep && a.SLA == b.SLA
+	}) {
+		t.Fatal("expected the same orders for the same n")
+	}
+	var fails []string
+	for _, o := range orders {
+		if o.FailStep != "" {
+			fails = append(fails, o.FailStep)
+		}
+	}
+	if want := []string{"vendor", "payment", "courier"}; !slices.Equal(fails, want) {
+		t.Fatalf("expected failures %v, got %v", want, fails)
+	}
+	if orders[0].OrderID != "sample-1" || orders[2].SLA != "express" {
+		t.Fatalf("unexpected orders %+v", orders[:3])
+	}
+}
+
+func TestSeedOrders(t *testing.T) {
+	t.Parallel()
+
+	p := &seedProcessor{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	seedOrders(context.Background(), p, sampleOrders(6), time.Second, logger)
+	if len(p.seen) != 6 || p.released != 6 {
+		t.Fatalf("expected 6 orders processed and released, got %d and %d", len(p.seen), p.released)
+	}
+
+	// A cancelled context stops after the order in flight.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p = &seedProcessor{}
+	seedOrders(ctx, p, sampleOrders(6), time.Second, logger)
+	if len(p.seen) != 1 {
+		t.Fatalf("expected seeding to stop, got %v", p.seen)
+	}
+}
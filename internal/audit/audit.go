@@ -0,0 +1,222 @@
+// Package audit provides an append-only, hash-chained event log.
+//
+// Every entry carries the hash of the entry before it, so altering,
+// removing or reordering any entry breaks the chain from that point on.
+// Verify recomputes the chain over a time range.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// maxEntrySize bounds one encoded entry when reading the log back.
+const maxEntrySize = 1 << 20
+
+// Log appends entries to a JSON-lines file.
+type Log struct {
+	path string
+	now  func() time.Time
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64 // bytes of complete entries; Verify reads no further
+	seq  int64
+	prev string
+}
+
+// Open opens or creates the log at path and resumes the chain from its
+// last entry.
+//
+// It returns an error if the file cannot be opened or its last entry
+// cannot be decoded.
+func Open(path string) (*Log, error) {
+	l := &Log{path: path, now: time.Now}
+	if err := l.resume(); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: open %s: %w", path, err)
+	}
+	l.f = f
+	return l, nil
+}
+
+// resume reads the sequence number and hash of the last entry.
+func (l *Log) resume() error {
+	f, err := os.Open(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("audit: open %s: %w", l.path, err)
+	}
+	defer f.Close()
+
+	var last []byte
+	sc := newScanner(f)
+	for sc.Scan() {
+		l.size += int64(len(sc.Bytes())) + 1
+		last = append(last[:0], sc.Bytes()...)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("audit: read %s: %w", l.path, err)
+	}
+	if last == nil {
+		return nil
+	}
+	var e model.AuditEntry
+	if err := json.Unmarshal(last, &e); err != nil {
+		return fmt.Errorf("audit: last entry of %s: %w", l.path, err)
+	}
+	l.seq, l.prev = e.Seq, e.Hash
+	return nil
+}
+
+// Append sets e's Seq, Time, PrevHash and Hash and writes it to the log.
+// Entries are timestamped in append order, so times never decrease
+// along the chain unless the wall clock steps back.
+func (l *Log) Append(e model.AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return fs.ErrClosed
+	}
+	e.Seq, e.PrevHash = l.seq+1, l.prev
+	e.Time = l.now().UTC().Format(time.RFC3339Nano)
+	hash, err := Hash(e)
+	if err != nil {
+		return err
+	}
+	e.Hash = hash
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("audit: encode entry: %w", err)
+	}
+	line = append(line, '\n')
+	if _, err := l.f.Write(line); err != nil {
+		return fmt.Errorf("audit: write: %w", err)
+	}
+	l.seq, l.prev = e.Seq, e.Hash
+	l.size += int64(len(line))
+	return nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// Hash returns the chain hash of e: the hex SHA-256 of its JSON
+// encoding with Hash cleared.
+func Hash(e model.AuditEntry) (string, error) {
+	e.Hash = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", fmt.Errorf("audit: encode entry: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Verify checks the entries written between from and to, inclusive. A
+// zero from or to leaves that end open.
+//
+// Each entry in the range must hash to its recorded Hash and link to
+// the entry before it by sequence number and PrevHash. Verify reports
+// the first broken entry; it returns an error only if the log cannot
+// be read.
+func (l *Log) Verify(from, to time.Time) (model.AuditVerification, error) {
+	l.mu.Lock()
+	size := l.size
+	l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return model.AuditVerification{}, fmt.Errorf("audit: open %s: %w", l.path, err)
+	}
+	defer f.Close()
+	return verify(io.LimitReader(f, size), from, to)
+}
+
+func verify(r io.Reader, from, to time.Time) (model.AuditVerification, error) {
+	out := model.AuditVerification{Valid: true}
+	if !from.IsZero() {
+		out.From = from.UTC().Format(time.RFC3339)
+	}
+	if !to.IsZero() {
+		out.To = to.UTC().Format(time.RFC3339)
+	}
+	broken := func(seq int64, reason string) (model.AuditVerification, error) {
+		out.Valid, out.BrokenSeq, out.Reason = false, seq, reason
+		return out, nil
+	}
+
+	var prevSeq int64
+	var prevHash string
+	sc := newScanner(r)
+	for sc.Scan() {
+		var e model.AuditEntry
+		dec := json.NewDecoder(bytes.NewReader(sc.Bytes()))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&e); err != nil {
+			return broken(prevSeq+1, "malformed entry")
+		}
+		t, err := time.Parse(time.RFC3339Nano, e.Time)
+		if err != nil {
+			return broken(e.Seq, "invalid time")
+		}
+		if !to.IsZero() && t.After(to) {
+			break
+		}
+		if from.IsZero() || !t.Before(from) {
+			if e.Seq != prevSeq+1 {
+				return broken(prevSeq+1, "sequence gap")
+			}
+			if e.PrevHash != prevHash {
+				return broken(e.Seq, "previous hash mismatch")
+			}
+			if h, err := Hash(e); err != nil || h != e.Hash {
+				return broken(e.Seq, "hash mismatch")
+			}
+			if out.Entries == 0 {
+				out.FirstSeq = e.Seq
+			}
+			out.LastSeq = e.Seq
+			out.Entries++
+		}
+		prevSeq, prevHash = e.Seq, e.Hash
+	}
+	if err := sc.Err(); err != nil {
+		return model.AuditVerification{}, fmt.Errorf("audit: read: %w", err)
+	}
+	return out, nil
+}
+
+func newScanner(r io.Reader) *bufio.Scanner {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), maxEntrySize)
+	return sc
+}
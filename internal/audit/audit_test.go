@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+var epoch = time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+
+// writeLog appends n entries one second apart, starting at epoch+1s.
+func writeLog(t *testing.T, path string, n int) *Log {
+	t.Helper()
+
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	tick := epoch.Add(time.Duration(l.seq) * time.Second)
+	l.now = func() time.Time {
+		tick = tick.Add(time.Second)
+		return tick
+	}
+	for i := 0; i < n; i++ {
+		e := model.AuditEntry{Event: "order.processed", OrderID: fmt.Sprintf("o-%d", l.seq+1), Status: model.StatusOK}
+		if err := l.Append(e); err != nil {
+			t.Fatalf("append #%d: %v", i+1, err)
+		}
+	}
+	return l
+}
+
+func TestLogVerify(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := writeLog(t, path, 5)
+	defer l.Close()
+
+	got, err := l.Verify(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !got.Valid || got.Entries != 5 || got.FirstSeq != 1 || got.LastSeq != 5 {
+		t.Fatalf("expected 5 valid entries, got %+v", got)
+	}
+
+	// Range [epoch+2s, epoch+4s] covers entries 2 through 4.
+	got, err = l.Verify(epoch.Add(2*time.Second), epoch.Add(4*time.Second))
+	if err != nil {
+		t.Fatalf("verify range: %v", err)
+	}
+	if !got.Valid || got.Entries != 3 || got.FirstSeq != 2 || got.LastSeq != 4 {
+		t.Fatalf("expected entries 2-4, got %+v", got)
+	}
+	if got.From != "2026-01-02T15:00:02Z" || got.To != "2026-01-02T15:00:04Z" {
+		t.Fatalf("unexpected range echo: %+v", got)
+	}
+}
+
+func TestOpenResumesChain(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := writeLog(t, path, 3).Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := writeLog(t, path, 2)
+	defer l.Close()
+
+	got, err := l.Verify(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !got.Valid || got.Entries != 5 || got.LastSeq != 5 {
+		t.Fatalf("expected an unbroken chain of 5 across reopen, got %+v", got)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		tamper     func(lines [][]byte) [][]byte
+		wantSeq    int64
+		wantReason string
+	}{
+		{
+			name: "edited_field",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[2] = bytes.Replace(lines[2], []byte(`"status":"ok"`), []byte(`"status":"error"`), 1)
+				return lines
+			},
+			wantSeq:    3,
+			wantReason: "hash mismatch",
+		},
+		{
+			name: "rehashed_edit",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[2] = rehashed(t, lines[2], func(e *model.AuditEntry) { e.OrderID = "forged" })
+				return lines
+			},
+			wantSeq:    4,
+			wantReason: "previous hash mismatch",
+		},
+		{
+			name:       "deleted_entry",
+			tamper:     func(lines [][]byte) [][]byte { return append(lines[:1], lines[2:]...) },
+			wantSeq:    2,
+			wantReason: "sequence gap",
+		},
+		{
+			name: "swapped_entries",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			wantSeq:    2,
+			wantReason: "sequence gap",
+		},
+		{
+			name: "added_field",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[0] = bytes.Replace(lines[0], []byte(`{`), []byte(`{"note":"x",`), 1)
+				return lines
+			},
+			wantSeq:    1,
+			wantReason: "malformed entry",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "audit.log")
+			l := writeLog(t, path, 5)
+			defer l.Close()
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := tt.tamper(bytes.Split(bytes.TrimSuffix(raw, []byte("\n")), []byte("\n")))
+			got, err := verify(bytes.NewReader(append(bytes.Join(lines, []byte("\n")), '\n')), time.Time{}, time.Time{})
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if got.Valid || got.BrokenSeq != tt.wantSeq || got.Reason != tt.wantReason {
+				t.Fatalf("expected broken_seq=%d reason=%q, got %+v", tt.wantSeq, tt.wantReason, got)
+			}
+		})
+	}
+}
+
+// rehashed decodes line, applies edit, and re-signs it with a valid hash.
+func rehashed(t *testing.T, line []byte, edit func(*model.AuditEntry)) []byte {
+	t.Helper()
+
+	var e model.AuditEntry
+	if err := json.Unmarshal(line, &e); err != nil {
+		t.Fatal(err)
+	}
+	edit(&e)
+	h, err := Hash(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Hash = h
+	out, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestAppendAfterClose(t *testing.T) {
+	t.Parallel()
+
+	l := writeLog(t, filepath.Join(t.TempDir(), "audit.log"), 0)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Append(model.AuditEntry{Status: model.StatusOK}); err == nil {
+		t.Fatal("expected append to a closed log to fail")
+	}
+}
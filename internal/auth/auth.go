@@ -0,0 +1,328 @@
+// Package auth authenticates requests with JWTs issued by an OIDC
+// provider.
+//
+// A Verifier checks a token's signature against the issuer's JSON Web
+// Key Set (fetched via OIDC discovery and cached), its issuer, audience
+// and validity window, and exposes the subject, tenant and roles claims
+// to handlers through the request context.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrInvalidToken is wrapped by every error caused by the token itself,
+// as opposed to failing to fetch the issuer's keys.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Clock skew tolerated on exp and nbf, and the minimum time between key
+// set refreshes triggered by an unknown key ID.
+const (
+	leeway     = 30 * time.Second
+	minRefresh = time.Minute
+)
+
+// Config configures a Verifier.
+type Config struct {
+	Issuer   string        // required iss claim; also the discovery base URL
+	Audience string        // required aud value
+	JWKSURL  string        // key set location; discovered from Issuer when empty
+	CacheTTL time.Duration // how long a fetched key set is used; default 1 hour
+	Client   *http.Client  // default: a client with a 5-second timeout
+	Public   []string      // exact paths served without a token, such as readiness probes
+}
+
+// Claims are the authenticated identity carried by a token.
+type Claims struct {
+	Subject string
+	Tenant  string
+	Roles   []string
+	Expires time.Time
+}
+
+// HasRole reports whether c includes role.
+func (c Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+// Verifier validates JWTs signed with RS256 or ES256.
+type Verifier struct {
+	cfg   Config
+	now   func() time.Time
+	fetch singleflight.Group
+
+	mu      sync.RWMutex
+	keys    map[string]crypto.PublicKey // by kid
+	fetched time.Time
+}
+
+// NewVerifier returns a Verifier for cfg. Keys are fetched on first use.
+//
+// It panics if cfg.Issuer or cfg.Audience is empty.
+func NewVerifier(cfg Config) *Verifier {
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		panic("auth.NewVerifier: issuer and audience are required")
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = time.Hour
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &Verifier{cfg: cfg, now: time.Now}
+}
+
+// Verify checks token and returns its claims.
+//
+// It returns an error wrapping ErrInvalidToken if the token is malformed,
+// badly signed, expired, not yet valid, or issued by or for someone else.
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, invalid("malformed token")
+	}
+	var hdr struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &hdr); err != nil {
+		return Claims{}, invalid("malformed header")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, invalid("malformed signature")
+	}
+
+	key, err := v.key(ctx, hdr.Kid)
+	if err != nil {
+		return Claims{}, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !verifySignature(hdr.Alg, key, digest[:], sig) {
+		return Claims{}, invalid("bad signature")
+	}
+
+	var raw struct {
+		Iss    string   `json:"iss"`
+		Sub    string   `json:"sub"`
+		Aud    audience `json:"aud"`
+		Exp    *float64 `json:"exp"`
+		Nbf    *float64 `json:"nbf"`
+		Tenant string   `json:"tenant"`
+		Roles  []string `json:"roles"`
+	}
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return Claims{}, invalid("malformed claims")
+	}
+	now := v.now()
+	switch {
+	case raw.Iss != v.cfg.Issuer:
+		return Claims{}, invalid("wrong issuer")
+	case !slices.Contains(raw.Aud, v.cfg.Audience):
+		return Claims{}, invalid("wrong audience")
+	case raw.Exp == nil:
+		return Claims{}, invalid("missing exp")
+	case now.After(unixTime(*raw.Exp).Add(leeway)):
+		return Claims{}, invalid("expired")
+	case raw.Nbf != nil && now.Add(leeway).Before(unixTime(*raw.Nbf)):
+		return Claims{}, invalid("not yet valid")
+	}
+	return Claims{Subject: raw.Sub, Tenant: raw.Tenant, Roles: raw.Roles, Expires: unixTime(*raw.Exp)}, nil
+}
+
+func invalid(reason string) error {
+	return fmt.Errorf("auth: %w: %s", ErrInvalidToken, reason)
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func unixTime(sec float64) time.Time {
+	return time.Unix(0, int64(sec*float64(time.Second)))
+}
+
+// audience decodes the aud claim, which may be a string or an array.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// verifySignature checks sig over digest with key, which must match alg.
+func verifySignature(alg string, key crypto.PublicKey, digest, sig []byte) bool {
+	switch alg {
+	case "RS256":
+		k, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest, sig) == nil
+	case "ES256":
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok || k.Curve != elliptic.P256() || len(sig) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(k, digest, r, s)
+	default:
+		return false // includes "none" and symmetric algorithms
+	}
+}
+
+// key returns the public key for kid, refreshing the key set when it
+// is stale or, at most once per minRefresh, when kid is unknown.
+func (v *Verifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.RLock()
+	k, ok := v.keys[kid]
+	age := v.now().Sub(v.fetched)
+	fetchedOnce := v.keys != nil
+	v.mu.RUnlock()
+
+	if ok && age < v.cfg.CacheTTL {
+		return k, nil
+	}
+	if fetchedOnce && !ok && age < minRefresh {
+		return nil, invalid("unknown key")
+	}
+	if _, err, _ := v.fetch.Do("jwks", func() (any, error) { return nil, v.refresh(ctx) }); err != nil {
+		if ok {
+			return k, nil // keep using a known key while the issuer is unreachable
+		}
+		return nil, err
+	}
+
+	v.mu.RLock()
+	k, ok = v.keys[kid]
+	v.mu.RUnlock()
+	if !ok {
+		return nil, invalid("unknown key")
+	}
+	return k, nil
+}
+
+// refresh fetches and installs the issuer's key set.
+func (v *Verifier) refresh(ctx context.Context) error {
+	url := v.cfg.JWKSURL
+	if url == "" {
+		var disc struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := v.getJSON(ctx, strings.TrimSuffix(v.cfg.Issuer, "/")+"/.well-known/openid-configuration", &disc); err != nil {
+			return err
+		}
+		if disc.Issuer != v.cfg.Issuer || disc.JWKSURI == "" {
+			return fmt.Errorf("auth: discovery document does not match issuer %s", v.cfg.Issuer)
+		}
+		url = disc.JWKSURI
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := v.getJSON(ctx, url, &set); err != nil {
+		return err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, j := range set.Keys {
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+		if k, err := j.publicKey(); err == nil {
+			keys[j.Kid] = k
+		}
+	}
+
+	v.mu.Lock()
+	v.keys, v.fetched = keys, v.now()
+	v.mu.Unlock()
+	return nil
+}
+
+func (v *Verifier) getJSON(ctx context.Context, url string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+	resp, err := v.cfg.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("auth: fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth: fetch %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("auth: decode %s: %w", url, err)
+	}
+	return nil
+}
+
+// jwk is one entry of a JSON Web Key Set.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (j jwk) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if j.Crv != "P-256" {
+			return nil, errors.New("unsupported curve " + j.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(j.X)
+		y, errY := base64.RawURLEncoding.DecodeString(j.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid EC point")
+		}
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+	default:
+		return nil, errors.New("unsupported key type " + j.Kty)
+	}
+}
@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testAudience = "order-pipeline"
+
+var (
+	rsaKeyOnce sync.Once
+	rsaKey     *rsa.PrivateKey
+)
+
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	rsaKeyOnce.Do(func() {
+		var err error
+		if rsaKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			panic(err)
+		}
+	})
+	return rsaKey
+}
+
+// issuer is a fake OIDC provider serving discovery and a key set.
+type issuer struct {
+	srv     *httptest.Server
+	fetches atomic.Int64
+
+	mu   sync.Mutex
+	keys []map[string]string
+}
+
+func newIssuer(t *testing.T) *issuer {
+	t.Helper()
+	is := &issuer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": is.srv.URL, "jwks_uri": is.srv.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		is.fetches.Add(1)
+		is.mu.Lock()
+		defer is.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": is.keys})
+	})
+	is.srv = httptest.NewServer(mux)
+	t.Cleanup(is.srv.Close)
+	return is
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func (is *issuer) addRSA(kid string, k *rsa.PublicKey) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	is.keys = append(is.keys, map[string]string{
+		"kty": "RSA", "kid": kid, "use": "sig",
+		"n": b64(k.N.Bytes()), "e": b64(big.NewInt(int64(k.E)).Bytes()),
+	})
+}
+
+func (is *issuer) addEC(kid string, k *ecdsa.PublicKey) {
+	raw, err := k.Bytes()
+	if err != nil {
+		panic(err)
+	}
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	is.keys = append(is.keys, map[string]string{
+		"kty": "EC", "kid": kid, "crv": "P-256", "x": b64(raw[1:33]), "y": b64(raw[33:]),
+	})
+}
+
+// sign returns a compact JWS of claims with the given header.
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+	hdr, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	body, _ := json.Marshal(claims)
+	input := b64(hdr) + "." + b64(body)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return input + "." + b64(sig)
+}
+
+func validClaims(iss string) map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss": iss, "sub": "user-1", "aud": testAudience,
+		"exp": now.Add(time.Hour).Unix(), "nbf": now.Add(-time.Minute).Unix(),
+		"tenant": "acme", "roles": []string{"admin", "ops"},
+	}
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	is := newIssuer(t)
+	rk := testRSAKey(t)
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	is.addRSA("rsa-1", &rk.PublicKey)
+	is.addEC("ec-1", &ek.PublicKey)
+	v := NewVerifier(Config{Issuer: is.srv.URL, Audience: testAudience})
+
+	with := func(edit func(map[string]any)) map[string]any {
+		c := validClaims(is.srv.URL)
+		edit(c)
+		return c
+	}
+	tampered := func(tok string) string {
+		parts := strings.Split(tok, ".")
+		body, _ := json.Marshal(with(func(c map[string]any) { c["roles"] = []string{"admin", "root"} }))
+		return parts[0] + "." + b64(body) + "." + parts[2]
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "rs256", token: sign(t, "RS256", "rsa-1", rk, validClaims(is.srv.URL))},
+		{name: "es256", token: sign(t, "ES256", "ec-1", ek, validClaims(is.srv.URL))},
+		{name: "audience_array", token: sign(t, "RS256", "rsa-1", rk, with(func(c map[string]any) { c["aud"] = []string{"other", testAudience} }))},
+		{name: "within_leeway", token: sign(t, "RS256", "rsa-1", rk, with(func(c map[string]any) { c["exp"] = time.Now().Add(-10 * time.Second).Unix() }))},
+		{name: "expired", token: sign(t, "RS256", "rsa-1", rk, with(func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() })), wantErr: true},
+		{name: "missing_exp", token: sign(t, "RS256", "rsa-1", rk, with(func(c map[string]any) { delete(c, "exp") })), wantErr: true},
+		{name: "not_yet_valid", token: sign(t, "RS256", "rsa-1", rk, with(func(c map[string]any) { c["nbf"] = time.Now().Add(time.Hour).Unix() })), wantErr: true},
+		{name: "wrong_audience", token: sign(t, "RS256", "rsa-1", rk, with(func(c map[string]any) { c["aud"] = "other" })), wantErr: true},
+		{name: "wrong_issuer", token: sign(t, "RS256", "rsa-1", rk, with(func(c map[string]any) { c["iss"] = "https://evil.example" })), wantErr: true},
+		{name: "tampered_claims", token: tampered(sign(t, "RS256", "rsa-1", rk, validClaims(is.srv.URL))), wantErr: true},
+		{name: "alg_key_mismatch", token: sign(t, "ES256", "rsa-1", ek, validClaims(is.srv.URL)), wantErr: true},
+		{name: "alg_none", token: strings.Join(strings.Split(sign(t, "none", "rsa-1", rk, validClaims(is.srv.URL)), ".")[:2], ".") + ".", wantErr: true},
+		{name: "unknown_kid", token: sign(t, "RS256", "rsa-9", rk, validClaims(is.srv.URL)), wantErr: true},
+		{name: "malformed", token: "not-a-jwt", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := v.Verify(context.Background(), tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("expected %v, got %v", ErrInvalidToken, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.Subject != "user-1" || c.Tenant != "acme" || !c.HasRole("admin") || c.HasRole("root") {
+				t.Fatalf("unexpected claims: %+v", c)
+			}
+		})
+	}
+}
+
+func TestVerify_KeyCacheAndRotation(t *testing.T) {
+	t.Parallel()
+
+	is := newIssuer(t)
+	rk := testRSAKey(t)
+	is.addRSA("rsa-1", &rk.PublicKey)
+	v := NewVerifier(Config{Issuer: is.srv.URL, Audience: testAudience})
+	now := time.Now()
+	v.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := v.Verify(ctx, sign(t, "RS256", "rsa-1", rk, validClaims(is.srv.URL))); err != nil {
+			t.Fatalf("verify #%d: %v", i+1, err)
+		}
+	}
+	if n := is.fetches.Load(); n != 1 {
+		t.Fatalf("expected the key set fetched once, got %d", n)
+	}
+
+	// The issuer rotates in a new key. Within minRefresh an unknown kid
+	// does not trigger a fetch; after it, the new key is picked up.
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	is.addEC("ec-2", &ek.PublicKey)
+	rotated := sign(t, "ES256", "ec-2", ek, validClaims(is.srv.URL))
+	if _, err := v.Verify(ctx, rotated); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected unknown key within minRefresh, got %v", err)
+	}
+	now = now.Add(minRefresh)
+	if _, err := v.Verify(ctx, rotated); err != nil {
+		t.Fatalf("expected rotated key accepted, got %v", err)
+	}
+	if n := is.fetches.Load(); n != 2 {
+		t.Fatalf("expected two fetches, got %d", n)
+	}
+}
+
+func TestVerify_IssuerUnavailable(t *testing.T) {
+	t.Parallel()
+
+	is := newIssuer(t)
+	rk := testRSAKey(t)
+	is.addRSA("rsa-1", &rk.PublicKey)
+	v := NewVerifier(Config{Issuer: is.srv.URL, Audience: testAudience, CacheTTL: time.Minute})
+	now := time.Now()
+	v.now = func() time.Time { return now }
+	tok := sign(t, "RS256", "rsa-1", rk, validClaims(is.srv.URL))
+
+	if _, err := v.Verify(context.Background(), tok); err != nil {
+		t.Fatal(err)
+	}
+	is.srv.Close()
+
+	// A stale but known key keeps working while the issuer is down.
+	now = now.Add(2 * time.Minute)
+	if _, err := v.Verify(context.Background(), tok); err != nil {
+		t.Fatalf("expected cached key to be used, got %v", err)
+	}
+
+	cold := NewVerifier(Config{Issuer: is.srv.URL, Audience: testAudience})
+	_, err := cold.Verify(context.Background(), tok)
+	if err == nil || errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a fetch error, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+type claimsKey struct{}
+
+// NewContext returns a copy of ctx carrying c.
+func NewContext(ctx context.Context, c Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, c)
+}
+
+// FromContext returns the claims carried by ctx and whether the request
+// was authenticated.
+func FromContext(ctx context.Context) (Claims, bool) {
+	c, ok := ctx.Value(claimsKey{}).(Claims)
+	return c, ok
+}
+
+// Middleware wraps next and requires a valid bearer token on every
+// request outside Config.Public, storing its claims in the request
+// context.
+//
+// A missing or invalid token gets a 401 with kind unauthorized. If the
+// issuer's keys cannot be fetched the request gets a 503 with kind
+// auth_unavailable. Requests to a public path are served without
+// claims, whatever their Authorization header.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(v.cfg.Public, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := bearerToken(r.Header.Get("Authorization"))
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "unauthorized", "bearer token required")
+			return
+		}
+		claims, err := v.Verify(r.Context(), token)
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized", "invalid token")
+			return
+		case err != nil:
+			writeError(w, http.StatusServiceUnavailable, "auth_unavailable", "identity provider unavailable")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), claims)))
+	})
+}
+
+// RequireRole wraps next and rejects requests whose path starts with
+// prefix unless their claims include role. It must run inside
+// Verifier.Middleware. Rejections get a 403 with kind forbidden.
+func RequireRole(prefix, role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			if c, _ := FromContext(r.Context()); !c.HasRole(role) {
+				writeError(w, http.StatusForbidden, "forbidden", "role "+role+" required")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func bearerToken(h string) (string, bool) {
+	scheme, token, ok := strings.Cut(h, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+func writeError(w http.ResponseWriter, status int, kind, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(model.OrderResponse{
+		Status: model.StatusError,
+		Error:  &model.ErrorPayload{Kind: kind, Message: msg},
+	})
+}
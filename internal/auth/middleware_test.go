@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	is := newIssuer(t)
+	rk := testRSAKey(t)
+	is.addRSA("rsa-1", &rk.PublicKey)
+	v := NewVerifier(Config{Issuer: is.srv.URL, Audience: testAudience, Public: []string{"/readyz"}})
+
+	admin := sign(t, "RS256", "rsa-1", rk, validClaims(is.srv.URL))
+	viewerClaims := validClaims(is.srv.URL)
+	viewerClaims["roles"] = []string{"viewer"}
+	viewer := sign(t, "RS256", "rsa-1", rk, viewerClaims)
+
+	var gotSubject string
+	h := v.Middleware(RequireRole("/admin/", "admin", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, _ := FromContext(r.Context())
+		gotSubject = c.Subject
+	})))
+
+	tests := []struct {
+		name     string
+		path     string
+		header   string
+		wantCode int
+	}{
+		{name: "no_token", path: "/order", wantCode: http.StatusUnauthorized},
+		{name: "wrong_scheme", path: "/order", header: "Basic " + admin, wantCode: http.StatusUnauthorized},
+		{name: "invalid_token", path: "/order", header: "Bearer x.y.z", wantCode: http.StatusUnauthorized},
+		{name: "viewer_order", path: "/order", header: "Bearer " + viewer, wantCode: http.StatusOK},
+		{name: "viewer_admin", path: "/admin/sla", header: "Bearer " + viewer, wantCode: http.StatusForbidden},
+		{name: "admin_admin", path: "/admin/sla", header: "bearer " + admin, wantCode: http.StatusOK},
+		{name: "probe_no_token", path: "/readyz", wantCode: http.StatusOK},
+		{name: "probe_invalid_token", path: "/readyz", header: "Bearer x.y.z", wantCode: http.StatusOK},
+		{name: "probe_subpath", path: "/readyz/x", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSubject = ""
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, w.Code, w.Body)
+			}
+			wantSubject := "user-1"
+			if tt.path == "/readyz" {
+				wantSubject = "" // public paths are served without claims
+			}
+			if tt.wantCode == http.StatusOK && gotSubject != wantSubject {
+				t.Fatalf("expected subject %q in context, got %q", wantSubject, gotSubject)
+			}
+			if tt.wantCode == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Fatal("expected a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Package config layers the server's configuration sources over its
+// flags.
+//
+// Every setting is a flag; a flag's value comes from the first of
+// these that sets it, highest precedence first:
+//
+//  1. the command line, e.g. -pool-size 20
+//  2. an environment variable named EnvPrefix plus the flag name in
+//     upper case with dashes as underscores, e.g. ORDER_PIPELINE_POOL_SIZE=20
+//  3. the config file named by -config or ORDER_PIPELINE_CONFIG, one
+//     name = value line per flag, e.g. pool-size = 20
+//  4. the profile named by -profile or ORDER_PIPELINE_PROFILE, a preset
+//     of flag values such as one for local development
+//  5. the flag's default
+//
+// Values from every source are parsed by the flag itself, so a
+// duration or integer is typed the same way wherever it is written.
+package config
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// EnvPrefix starts the names of environment variables setting flags.
+const EnvPrefix = "ORDER_PIPELINE_"
+
+// FileFlag is the flag, defined by Load, naming the config file.
+const FileFlag = "config"
+
+// ProfileFlag is the flag, defined by LoadProfiles, naming the profile.
+const ProfileFlag = "profile"
+
+// Preset is the flag values of a profile, by flag name.
+type Preset map[string]string
+
+// Source is where a flag's value came from.
+type Source string
+
+const (
+	Default Source = "default"
+	Profile Source = "profile"
+	File    Source = "file"
+	Env     Source = "env"
+	Flag    Source = "flag"
+)
+
+// EnvName returns the environment variable setting the flag name.
+func EnvName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Load defines the -config flag on fs, parses args and then applies the
+// config file and the environment (as KEY=value entries, like
+// os.Environ) to every flag the command line left unset. It returns the
+// source of each flag's value, by flag name.
+//
+// Unknown names in the file and unknown EnvPrefix variables are errors,
+// so a misspelt setting is not silently ignored.
+func Load(fs *flag.FlagSet, args []string, environ []string) (map[string]Source, error) {
+	return LoadProfiles(fs, args, environ, nil)
+}
+
+// LoadProfiles is Load with profiles: unless profiles is empty, it also
+// defines the -profile flag, and the selected profile's values apply
+// beneath the config file. An unknown profile, or a profile setting an
+// unknown flag, is an error.
+func LoadProfiles(fs *flag.FlagSet, args []string, environ []string, profiles map[string]Preset) (map[string]Source, error) {
+	path := fs.String(FileFlag, "", "config file of name = value lines, overridden by "+EnvPrefix+"* variables and flags")
+	var profile *string
+	if len(profiles) > 0 {
+		profile = fs.String(ProfileFlag, "", "preset of flag values beneath the config file, one of: "+strings.Join(slices.Sorted(maps.Keys(profiles)), ", "))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	sources := map[string]Source{}
+	fs.VisitAll(func(f *flag.Flag) { sources[f.Name] = Default })
+	fs.Visit(func(f *flag.Flag) { sources[f.Name] = Flag })
+
+	env := map[string]string{}
+	for _, kv := range environ {
+		k, v, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(k, EnvPrefix) {
+			env[k] = v
+		}
+	}
+	set := func(name, value string, src Source) error {
+		if sources[name] == Flag {
+			return nil
+		}
+		if err := fs.Set(name, value); err != nil {
+			return err
+		}
+		sources[name] = src
+		return nil
+	}
+
+	// The file path and profile themselves come from the flag or the
+	// environment only
+	if v, ok := env[EnvName(FileFlag)]; ok {
+		if err := set(FileFlag, v, Env); err != nil {
+			return nil, err
+		}
+	}
+	if profile != nil {
+		if v, ok := env[EnvName(ProfileFlag)]; ok {
+			if err := set(ProfileFlag, v, Env); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if profile != nil && *profile != "" {
+		values, ok := profiles[*profile]
+		if !ok {
+			return nil, fmt.Errorf("config: unknown profile %q", *profile)
+		}
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			if name == FileFlag || name == ProfileFlag || fs.Lookup(name) == nil {
+				return nil, fmt.Errorf("config: profile %s: unknown setting %q", *profile, name)
+			}
+			if err := set(name, values[name], Profile); err != nil {
+				return nil, fmt.Errorf("config: profile %s: %s: %w", *profile, name, err)
+			}
+		}
+	}
+	if *path != "" {
+		values, err := readFile(*path)
+		if err != nil {
+			return nil, err
+		}
+		for _, kv := range values {
+			if kv.name == FileFlag || kv.name == ProfileFlag || fs.Lookup(kv.name) == nil {
+				return nil, fmt.Errorf("config: %s:%d: unknown setting %q", *path, kv.line, kv.name)
+			}
+			if err := set(kv.name, kv.value, File); err != nil {
+				return nil, fmt.Errorf("config: %s:%d: %s: %w", *path, kv.line, kv.name, err)
+			}
+		}
+	}
+
+	names := map[string]string{}
+	fs.VisitAll(func(f *flag.Flag) { names[EnvName(f.Name)] = f.Name })
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		v := env[k]
+		name, ok := names[k]
+		if !ok {
+			return nil, fmt.Errorf("config: unknown variable %s", k)
+		}
+		if name == FileFlag || name == ProfileFlag {
+			continue
+		}
+		if err := set(name, v, Env); err != nil {
+			return nil, fmt.Errorf("config: %s: %w", k, err)
+		}
+	}
+	return sources, nil
+}
+
+// setting is one name = value line of a config file.
+type setting struct {
+	line        int
+	name, value string
+}
+
+// readFile reads the settings of a config file. Blank lines and lines
+// starting with # are skipped; values may be quoted with double quotes.
+func readFile(path string) ([]setting, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	defer f.Close()
+
+	var out []setting
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("config: %s:%d: want name = value", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+		out = append(out, setting{line: n, name: strings.TrimSpace(name), value: value})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	return out, nil
+}
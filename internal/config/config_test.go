@@ -0,0 +1,204 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newFlags returns a flag set with one flag of each common type.
+func newFlags() (*flag.FlagSet, *int, *time.Duration, *string) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	size := fs.Int("pool-size", 5, "")
+	timeout := fs.Duration("request-timeout", 10*time.Second, "")
+	listen := fs.String("listen", "127.0.0.1:8080", "")
+	return fs, size, timeout, listen
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "server.conf")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	t.Parallel()
+
+	path := writeFile(t, "# defaults for staging\npool-size = 8\nrequest-timeout = \"4s\"\n\nlisten = 0.0.0.0:8080\n")
+
+	tests := []struct {
+		name        string
+		args        []string
+		environ     []string
+		wantSize    int
+		wantTimeout time.Duration
+		wantListen  string
+		wantSources map[string]Source
+	}{
+		{
+			name:     "defaults",
+			wantSize: 5, wantTimeout: 10 * time.Second, wantListen: "127.0.0.1:8080",
+			wantSources: map[string]Source{"pool-size": Default, "request-timeout": Default, "listen": Default},
+		},
+		{
+			name:     "file_over_defaults",
+			args:     []string{"-config", path},
+			wantSize: 8, wantTimeout: 4 * time.Second, wantListen: "0.0.0.0:8080",
+			wantSources: map[string]Source{"pool-size": File, "request-timeout": File, "config": Flag},
+		},
+		{
+			name:     "env_over_file",
+			environ:  []string{"ORDER_PIPELINE_CONFIG=" + path, "ORDER_PIPELINE_POOL_SIZE=20", "HOME=/root"},
+			wantSize: 20, wantTimeout: 4 * time.Second, wantListen: "0.0.0.0:8080",
+			wantSources: map[string]Source{"pool-size": Env, "request-timeout": File, "config": Env},
+		},
+		{
+			name:     "flags_over_env",
+			args:     []string{"-config", path, "-pool-size", "3", "-request-timeout", "1s"},
+			environ:  []string{"ORDER_PIPELINE_POOL_SIZE=20", "ORDER_PIPELINE_REQUEST_TIMEOUT=2s"},
+			wantSize: 3, wantTimeout: time.Second, wantListen: "0.0.0.0:8080",
+			wantSources: map[string]Source{"pool-size": Flag, "request-timeout": Flag, "listen": File},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs, size, timeout, listen := newFlags()
+			sources, err := Load(fs, tt.args, tt.environ)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *size != tt.wantSize || *timeout != tt.wantTimeout || *listen != tt.wantListen {
+				t.Fatalf("expected %d %v %s, got %d %v %s", tt.wantSize, tt.wantTimeout, tt.wantListen, *size, *timeout, *listen)
+			}
+			for name, want := range tt.wantSources {
+				if sources[name] != want {
+					t.Fatalf("expected %s from %s, got %s", name, want, sources[name])
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		file    string
+		environ []string
+		want    string
+	}{
+		{name: "bad_env_type", environ: []string{"ORDER_PIPELINE_POOL_SIZE=lots"}, want: "ORDER_PIPELINE_POOL_SIZE"},
+		{name: "unknown_env", environ: []string{"ORDER_PIPELINE_POOLSIZE=20"}, want: "unknown variable ORDER_PIPELINE_POOLSIZE"},
+		{name: "bad_file_type", file: "request-timeout = soon\n", want: ":1: request-timeout"},
+		{name: "unknown_file_setting", file: "pool-size = 3\npoolsize = 3\n", want: `:2: unknown setting "poolsize"`},
+		{name: "nested_config", file: "config = other.conf\n", want: "unknown setting"},
+		{name: "malformed_line", file: "pool-size 3\n", want: "want name = value"},
+		{name: "missing_file", environ: []string{"ORDER_PIPELINE_CONFIG=/nonexistent/server.conf"}, want: "no such file"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var args []string
+			if tt.file != "" {
+				args = []string{"-config", writeFile(t, tt.file)}
+			}
+			fs, _, _, _ := newFlags()
+			_, err := Load(fs, args, tt.environ)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	t.Parallel()
+
+	profiles := map[string]Preset{
+		"dev": {"pool-size": "2", "request-timeout": "30s", "listen": "127.0.0.1:9090"},
+		"bad": {"poolsize": "2"},
+	}
+	path := writeFile(t, "request-timeout = 4s\n")
+
+	tests := []struct {
+		name        string
+		args        []string
+		environ     []string
+		wantErr     string
+		wantSize    int
+		wantTimeout time.Duration
+		wantSources map[string]Source
+	}{
+		{
+			name:     "no_profile",
+			wantSize: 5, wantTimeout: 10 * time.Second,
+			wantSources: map[string]Source{"pool-size": Default, "profile": Default},
+		},
+		{
+			name:     "profile_over_defaults",
+			args:     []string{"-profile", "dev"},
+			wantSize: 2, wantTimeout: 30 * time.Second,
+			wantSources: map[string]Source{"pool-size": Profile, "listen": Profile, "profile": Flag},
+		},
+		{
+			name:     "file_and_env_over_profile",
+			args:     []string{"-config", path, "-pool-size", "7"},
+			environ:  []string{"ORDER_PIPELINE_PROFILE=dev", "ORDER_PIPELINE_LISTEN=0.0.0.0:80"},
+			wantSize: 7, wantTimeout: 4 * time.Second,
+			wantSources: map[string]Source{"pool-size": Flag, "request-timeout": File, "listen": Env, "profile": Env},
+		},
+		{name: "unknown_profile", args: []string{"-profile", "prod"}, wantErr: `unknown profile "prod"`},
+		{name: "unknown_setting", args: []string{"-profile", "bad"}, wantErr: `profile bad: unknown setting "poolsize"`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs, size, timeout, _ := newFlags()
+			sources, err := LoadProfiles(fs, tt.args, tt.environ, profiles)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *size != tt.wantSize || *timeout != tt.wantTimeout {
+				t.Fatalf("expected %d %v, got %d %v", tt.wantSize, tt.wantTimeout, *size, *timeout)
+			}
+			for name, want := range tt.wantSources {
+				if sources[name] != want {
+					t.Fatalf("expected %s from %s, got %s", name, want, sources[name])
+				}
+			}
+		})
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	t.Parallel()
+
+	if got := EnvName("request-timeout"); got != "ORDER_PIPELINE_REQUEST_TIMEOUT" {
+		t.Fatalf("expected ORDER_PIPELINE_REQUEST_TIMEOUT, got %s", got)
+	}
+}
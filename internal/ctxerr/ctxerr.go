@@ -0,0 +1,26 @@
+// Package ctxerr reports why a context stopped a wait.
+//
+// The orchestrator, the courier pool and every step's wait return the
+// same error when their context is done. It sits below both the order
+// package and the services, so neither has to import the other's tree
+// for it.
+package ctxerr
+
+import (
+	"context"
+	"fmt"
+)
+
+// Err returns ctx.Err(), annotated with the context's cancellation
+// cause when it has a more specific one.
+//
+// The cause is appended as text (%v, not %w), so errors.Is still sees
+// context.Canceled or context.DeadlineExceeded while an error kind
+// carried by the cause does not leak into the caller's classification.
+func Err(ctx context.Context) error {
+	err := ctx.Err()
+	if cause := context.Cause(ctx); cause != nil && cause != err {
+		return fmt.Errorf("%w: %v", err, cause)
+	}
+	return err
+}
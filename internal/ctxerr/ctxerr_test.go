@@ -0,0 +1,51 @@
+package ctxerr
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type kindError struct{}
+
+func (kindError) Error() string { return "sibling failed" }
+func (kindError) Kind() string  { return "vendor_unavailable" }
+
+func TestErr(t *testing.T) {
+	t.Parallel()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	withCause, cancelCause := context.WithCancelCause(context.Background())
+	cancelCause(kindError{})
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+		wantMsg string
+	}{
+		{name: "live", ctx: context.Background()},
+		{name: "canceled", ctx: canceled, wantErr: context.Canceled, wantMsg: "context canceled"},
+		{name: "cause", ctx: withCause, wantErr: context.Canceled, wantMsg: "context canceled: sibling failed"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := Err(tt.ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if err != nil && err.Error() != tt.wantMsg {
+				t.Fatalf("expected %q, got %q", tt.wantMsg, err)
+			}
+			var k kindError
+			if errors.As(err, &k) {
+				t.Fatalf("expected the cause's kind not to leak through %v", err)
+			}
+		})
+	}
+}
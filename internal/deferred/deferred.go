@@ -0,0 +1,200 @@
+// Package deferred completes postponed step work in the background.
+//
+// A step that falls back by deferring its work (courier assignment while
+// the courier kill switch is engaged) enqueues a task. A Queue retries
+// its tasks on a fixed interval until each succeeds or expires, logging
+// the outcome.
+package deferred
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// ErrFull is returned by Enqueue when the queue holds Capacity tasks.
+var ErrFull = errors.New("deferred: queue full")
+
+// Config sets how tasks are retried.
+type Config struct {
+	Interval       time.Duration // time between retry rounds; default 5 seconds
+	AttemptTimeout time.Duration // deadline of one attempt; default 5 seconds
+	Expiry         time.Duration // how long a task is retried; default 10 minutes
+	Capacity       int           // pending tasks held; default 1000
+	Paused         func() bool   // optional; rounds are skipped while it returns true
+}
+
+// Queue holds deferred tasks and retries them.
+type Queue struct {
+	cfg    Config
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu    sync.Mutex
+	tasks []*task // enqueue order
+
+	completed atomic.Int64
+	expired   atomic.Int64
+	rejected  atomic.Int64
+}
+
+type task struct {
+	orderID  string
+	step     string
+	run      func(context.Context) error
+	enqueued time.Time
+	attempts int
+	lastErr  string
+}
+
+// New returns an empty Queue. Non-positive Config fields take their
+// defaults. It panics if logger is nil.
+func New(cfg Config, logger *slog.Logger) *Queue {
+	if logger == nil {
+		panic("deferred.New: nil logger")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.AttemptTimeout <= 0 {
+		cfg.AttemptTimeout = 5 * time.Second
+	}
+	if cfg.Expiry <= 0 {
+		cfg.Expiry = 10 * time.Minute
+	}
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = 1000
+	}
+	return &Queue{cfg: cfg, logger: logger, now: time.Now}
+}
+
+// Enqueue adds a task completing step for orderID by calling run. The
+// first attempt is made in the next retry round. It returns ErrFull if
+// the queue is at capacity.
+func (q *Queue) Enqueue(orderID, step string, run func(context.Context) error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.tasks) >= q.cfg.Capacity {
+		q.rejected.Add(1)
+		return ErrFull
+	}
+	q.tasks = append(q.tasks, &task{orderID: orderID, step: step, run: run, enqueued: q.now()})
+	return nil
+}
+
+// Run retries pending tasks once per interval until ctx is done. Rounds
+// falling while Config.Paused reports true are skipped; tasks keep
+// aging meanwhile.
+func (q *Queue) Run(ctx context.Context) {
+	t := time.NewTicker(q.cfg.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if q.cfg.Paused != nil && q.cfg.Paused() {
+				continue
+			}
+			q.retry(ctx)
+		}
+	}
+}
+
+// retry attempts every pending task once, in enqueue order, and drops
+// those that succeed or have expired.
+func (q *Queue) retry(ctx context.Context) {
+	q.mu.Lock()
+	pending := append([]*task(nil), q.tasks...)
+	q.mu.Unlock()
+
+	done := make(map[*task]bool)
+	for _, t := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		if q.now().Sub(t.enqueued) >= q.cfg.Expiry {
+			done[t] = true
+			q.expired.Add(1)
+			q.logger.LogAttrs(ctx, slog.LevelError, "deferred step expired",
+				slog.String("order_id", t.orderID),
+				slog.String("step", t.step),
+				slog.Int("attempts", t.attempts),
+				slog.String("last_error", t.lastErr),
+			)
+			continue
+		}
+		if q.attempt(ctx, t) {
+			done[t] = true
+			q.completed.Add(1)
+			q.logger.LogAttrs(ctx, slog.LevelInfo, "deferred step completed",
+				slog.String("order_id", t.orderID),
+				slog.String("step", t.step),
+				slog.Int("attempts", t.attempts),
+			)
+		}
+	}
+	if len(done) == 0 {
+		return
+	}
+
+	q.mu.Lock()
+	kept := q.tasks[:0]
+	for _, t := range q.tasks {
+		if !done[t] {
+			kept = append(kept, t)
+		}
+	}
+	clear(q.tasks[len(kept):])
+	q.tasks = kept
+	q.mu.Unlock()
+}
+
+// errAttemptTimeout is the cancellation cause of an attempt that ran
+// past Config.AttemptTimeout.
+var errAttemptTimeout = errors.New("deferred attempt timeout")
+
+// attempt runs t once and reports whether it succeeded.
+func (q *Queue) attempt(ctx context.Context, t *task) bool {
+	actx, cancel := context.WithTimeoutCause(ctx, q.cfg.AttemptTimeout, errAttemptTimeout)
+	defer cancel()
+	err := t.run(actx)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t.attempts++
+	if err != nil {
+		t.lastErr = err.Error()
+		return false
+	}
+	return true
+}
+
+// Stats reports queue counters and the pending tasks in enqueue order.
+func (q *Queue) Stats() model.DeferredQueue {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	out := model.DeferredQueue{
+		Pending:   len(q.tasks),
+		Completed: q.completed.Load(),
+		Expired:   q.expired.Load(),
+		Rejected:  q.rejected.Load(),
+	}
+	for _, t := range q.tasks {
+		out.Tasks = append(out.Tasks, model.DeferredTask{
+			OrderID:   t.orderID,
+			Step:      t.step,
+			Attempts:  t.attempts,
+			Enqueued:  t.enqueued.UTC().Format(time.RFC3339),
+			LastError: t.lastErr,
+		})
+	}
+	return out
+}
@@ -0,0 +1,165 @@
+package deferred
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testQueue returns a queue whose clock only moves when the returned
+// advance function is called.
+func testQueue(cfg Config, logger *slog.Logger) (*Queue, func(time.Duration)) {
+	q := New(cfg, logger)
+	now := time.Unix(1_700_000_000, 0)
+	q.now = func() time.Time { return now }
+	return q, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestQueueRetriesUntilSuccess(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	q, _ := testQueue(Config{}, slog.New(slog.NewTextHandler(&buf, nil)))
+
+	calls := 0
+	if err := q.Enqueue("o-1", "courier", func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Fatal("expected an attempt deadline")
+		}
+		calls++
+		if calls < 3 {
+			return errors.New("no courier")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pending   int
+		completed int64
+		lastError string
+	}{
+		{pending: 1, lastError: "no courier"},
+		{pending: 1, lastError: "no courier"},
+		{pending: 0, completed: 1},
+	}
+	for i, tt := range tests {
+		q.retry(context.Background())
+		st := q.Stats()
+		if st.Pending != tt.pending || st.Completed != tt.completed {
+			t.Fatalf("round %d: expected pending=%d completed=%d, got %+v", i+1, tt.pending, tt.completed, st)
+		}
+		if tt.pending > 0 && (st.Tasks[0].Attempts != i+1 || st.Tasks[0].LastError != tt.lastError) {
+			t.Fatalf("round %d: unexpected task %+v", i+1, st.Tasks[0])
+		}
+	}
+	if !strings.Contains(buf.String(), "deferred step completed") || !strings.Contains(buf.String(), "attempts=3") {
+		t.Fatalf("expected a completion log line, got %q", buf.String())
+	}
+}
+
+func TestQueueExpiry(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	q, advance := testQueue(Config{Expiry: time.Minute}, slog.New(slog.NewTextHandler(&buf, nil)))
+	_ = q.Enqueue("o-1", "courier", func(context.Context) error { return errors.New("no courier") })
+
+	q.retry(context.Background())
+	advance(time.Minute)
+	q.retry(context.Background())
+
+	st := q.Stats()
+	if st.Pending != 0 || st.Expired != 1 || st.Completed != 0 {
+		t.Fatalf("expected one expired task, got %+v", st)
+	}
+	if !strings.Contains(buf.String(), "deferred step expired") || !strings.Contains(buf.String(), "last_error=\"no courier\"") {
+		t.Fatalf("expected an expiry log line, got %q", buf.String())
+	}
+}
+
+func TestQueueCapacity(t *testing.T) {
+	t.Parallel()
+
+	q, _ := testQueue(Config{Capacity: 1}, slog.New(slog.DiscardHandler))
+	run := func(context.Context) error { return nil }
+	if err := q.Enqueue("o-1", "courier", run); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Enqueue("o-2", "courier", run); !errors.Is(err, ErrFull) {
+		t.Fatalf("expected %v, got %v", ErrFull, err)
+	}
+	if st := q.Stats(); st.Pending != 1 || st.Rejected != 1 || st.Tasks[0].OrderID != "o-1" {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+
+	// A completed task frees its slot.
+	q.retry(context.Background())
+	if err := q.Enqueue("o-2", "courier", run); err != nil {
+		t.Fatalf("expected room after completion, got %v", err)
+	}
+}
+
+func TestQueueRun(t *testing.T) {
+	t.Parallel()
+
+	q := New(Config{Interval: 5 * time.Millisecond}, slog.New(slog.DiscardHandler))
+	var done atomic.Bool
+	_ = q.Enqueue("o-1", "courier", func(context.Context) error {
+		done.Store(true)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(stopped)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for !done.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the task to run")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-stopped
+}
+
+func TestQueueRun_Paused(t *testing.T) {
+	t.Parallel()
+
+	var paused atomic.Bool
+	paused.Store(true)
+	q := New(Config{Interval: 5 * time.Millisecond, Paused: paused.Load}, slog.New(slog.DiscardHandler))
+	var runs atomic.Int64
+	_ = q.Enqueue("o-1", "courier", func(context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+
+	time.Sleep(30 * time.Millisecond)
+	if n := runs.Load(); n != 0 {
+		t.Fatalf("expected no attempts while paused, got %d", n)
+	}
+	paused.Store(false)
+	deadline := time.Now().Add(time.Second)
+	for runs.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the task to run after resuming")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,331 @@
+// Package deps monitors the health endpoints of upstream providers.
+//
+// A Monitor periodically sends GET requests to each provider's health
+// URL and keeps its status: up after a 2xx answer, down after several
+// consecutive failures. Hooks let the caller act on it, such as tripping
+// the kill switch of the step calling a provider that is down, and the
+// list of dependencies that are down drives readiness. This notices an
+// outage before orders fail on it, and the recovery before their kill
+// switch cools down.
+//
+// A degradation matrix names what the pipeline does while each
+// dependency is down (see model.Degradation): refuse orders, accept
+// them with the step's work deferred, or let the step fail fast.
+// Degradations reports the actions in effect, for order responses.
+package deps
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// Dependency statuses.
+const (
+	Unknown = "unknown" // not yet up or down
+	Up      = "up"
+	Down    = "down"
+)
+
+// Dependency is an upstream provider and its health URL.
+type Dependency struct {
+	Name string
+	URL  string
+}
+
+// Parse parses a comma-separated list of name=URL pairs, e.g.
+// "payment=http://payments.internal/healthz". URLs must be absolute
+// http(s) URLs.
+func Parse(spec string) ([]Dependency, error) {
+	var deps []Dependency
+	seen := map[string]bool{}
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, rawURL, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("deps: %q: want name=URL", entry)
+		}
+		u, err := url.Parse(strings.TrimSpace(rawURL))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("deps: %q: want an http(s) URL", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("deps: %q: repeated", name)
+		}
+		seen[name] = true
+		deps = append(deps, Dependency{Name: name, URL: u.String()})
+	}
+	return deps, nil
+}
+
+// DefaultMatrix is the degradation matrix "payment=reject,vendor=defer,courier=defer":
+// without payment no order can be charged, while vendor notification
+// and courier assignment can be caught up once the provider is back.
+const DefaultMatrix = "payment=reject,vendor=defer,courier=defer"
+
+// ParseMatrix parses a degradation matrix, a comma-separated list of
+// dependency=action pairs with actions model.DegradeReject,
+// model.DegradeDefer and model.DegradeFail, e.g. "payment=reject".
+func ParseMatrix(spec string) (map[string]string, error) {
+	matrix := map[string]string{}
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, action, ok := strings.Cut(entry, "=")
+		name, action = strings.TrimSpace(name), strings.TrimSpace(action)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("deps: degradation %q: want dependency=action", entry)
+		}
+		switch action {
+		case model.DegradeReject, model.DegradeDefer, model.DegradeFail:
+		default:
+			return nil, fmt.Errorf("deps: degradation %q: unknown action %q", entry, action)
+		}
+		if _, dup := matrix[name]; dup {
+			return nil, fmt.Errorf("deps: degradation %q: repeated", name)
+		}
+		matrix[name] = action
+	}
+	return matrix, nil
+}
+
+// Config sets how often dependencies are checked, when they are
+// considered down and what is done meanwhile.
+type Config struct {
+	Interval  time.Duration     // between checks; default 10 seconds
+	Timeout   time.Duration     // for one check; default 2 seconds, at most Interval
+	DownAfter int               // consecutive failures before a dependency is down; default 2
+	Client    *http.Client      // nil means http.DefaultClient
+	Matrix    map[string]string // degradation action by dependency; default model.DegradeFail
+}
+
+// Monitor checks a fixed set of dependencies.
+type Monitor struct {
+	cfg       Config
+	logger    *slog.Logger
+	now       func() time.Time
+	paused    func() bool       // optional
+	onDown    func(name string) // optional
+	onRecover func(name string) // optional
+	deps      []*dependency     // configured order
+}
+
+type dependency struct {
+	Dependency
+	action string // taken while down
+
+	mu    sync.Mutex
+	state model.DependencyStatus
+}
+
+// Option configures a Monitor.
+type Option func(*Monitor)
+
+// PauseWhile skips the checks falling while paused returns true, such
+// as during maintenance.
+func PauseWhile(paused func() bool) Option {
+	return func(m *Monitor) { m.paused = paused }
+}
+
+// OnDown calls fn with the name of a dependency after every check that
+// finds it down, not only the first, so that fn may hold a state, such
+// as a kill switch trip, for about one interval at a time.
+func OnDown(fn func(name string)) Option {
+	return func(m *Monitor) { m.onDown = fn }
+}
+
+// OnRecover calls fn with the name of a dependency that was down when a
+// check finds it up again.
+func OnRecover(fn func(name string)) Option {
+	return func(m *Monitor) { m.onRecover = fn }
+}
+
+// New returns a Monitor checking deps as configured by cfg. Zero or
+// out-of-range Config fields take their defaults. It panics if deps is
+// empty or logger is nil.
+func New(deps []Dependency, cfg Config, logger *slog.Logger, opts ...Option) *Monitor {
+	if len(deps) == 0 {
+		panic("deps.New: no dependencies")
+	}
+	if logger == nil {
+		panic("deps.New: nil logger")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	cfg.Timeout = min(cfg.Timeout, cfg.Interval)
+	if cfg.DownAfter <= 0 {
+		cfg.DownAfter = 2
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	m := &Monitor{cfg: cfg, logger: logger, now: time.Now}
+	for _, d := range deps {
+		action := cfg.Matrix[d.Name]
+		if action == "" {
+			action = model.DegradeFail
+		}
+		m.deps = append(m.deps, &dependency{
+			Dependency: d,
+			action:     action,
+			state:      model.DependencyStatus{Name: d.Name, URL: d.URL, Status: Unknown, Degradation: action},
+		})
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Run checks every dependency immediately and then once per interval
+// until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
+	t := time.NewTicker(m.cfg.Interval)
+	defer t.Stop()
+	for {
+		if m.paused == nil || !m.paused() {
+			m.checkAll(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// checkAll checks every dependency concurrently and waits for them.
+func (m *Monitor) checkAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, d := range m.deps {
+		wg.Go(func() { m.check(ctx, d) })
+	}
+	wg.Wait()
+}
+
+// check sends one health request to d and updates its status.
+func (m *Monitor) check(ctx context.Context, d *dependency) {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+
+	start := m.now()
+	err := m.get(ctx, d.URL)
+	took := m.now().Sub(start)
+
+	d.mu.Lock()
+	st := &d.state
+	prev := st.Status
+	st.Checks++
+	st.LastCheck = start.UTC().Format(time.RFC3339)
+	st.LastLatencyMS = took.Milliseconds()
+	st.LastError = ""
+	if err != nil {
+		st.Failures++
+		st.ConsecutiveFailures++
+		st.LastError = err.Error()
+		if st.ConsecutiveFailures >= int64(m.cfg.DownAfter) {
+			st.Status = Down
+		}
+	} else {
+		st.ConsecutiveFailures = 0
+		st.Status = Up
+	}
+	if st.Status != prev {
+		st.Since = st.LastCheck
+	}
+	status := st.Status
+	d.mu.Unlock()
+
+	switch {
+	case status == Down && prev != Down:
+		m.logger.LogAttrs(ctx, slog.LevelWarn, "dependency down",
+			slog.String("dependency", d.Name),
+			slog.String("error", err.Error()),
+		)
+	case status == Up && prev == Down:
+		m.logger.LogAttrs(ctx, slog.LevelInfo, "dependency recovered",
+			slog.String("dependency", d.Name),
+		)
+		if m.onRecover != nil {
+			m.onRecover(d.Name)
+		}
+	}
+	if status == Down && m.onDown != nil {
+		m.onDown(d.Name)
+	}
+}
+
+// get requests rawURL and fails unless the answer is a 2xx.
+func (m *Monitor) get(ctx context.Context, rawURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := m.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // let the connection be reused
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Stats reports every dependency in configured order.
+func (m *Monitor) Stats() []model.DependencyStatus {
+	out := make([]model.DependencyStatus, len(m.deps))
+	for i, d := range m.deps {
+		d.mu.Lock()
+		out[i] = d.state
+		d.mu.Unlock()
+	}
+	return out
+}
+
+// Down returns the names of the dependencies that are down, in
+// configured order. Dependencies not checked yet are not down.
+func (m *Monitor) Down() []string {
+	var down []string
+	for _, d := range m.deps {
+		d.mu.Lock()
+		if d.state.Status == Down {
+			down = append(down, d.Name)
+		}
+		d.mu.Unlock()
+	}
+	return down
+}
+
+// Degradations returns the degradation actions in effect, one for each
+// dependency that is down, in configured order.
+func (m *Monitor) Degradations() []model.Degradation {
+	var out []model.Degradation
+	for _, d := range m.deps {
+		d.mu.Lock()
+		if d.state.Status == Down {
+			out = append(out, model.Degradation{Dependency: d.Name, Action: d.action})
+		}
+		d.mu.Unlock()
+	}
+	return out
+}
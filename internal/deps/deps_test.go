@@ -0,0 +1,214 @@
+package deps
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		spec    string
+		want    []Dependency
+		wantErr bool
+	}{
+		{spec: "", want: nil},
+		{spec: " payment=http://p.internal/healthz , vendor=https://v.example.com/health", want: []Dependency{
+			{Name: "payment", URL: "http://p.internal/healthz"},
+			{Name: "vendor", URL: "https://v.example.com/health"},
+		}},
+		{spec: "payment", wantErr: true},
+		{spec: "=http://p.internal", wantErr: true},
+		{spec: "payment=p.internal/healthz", wantErr: true},
+		{spec: "payment=ftp://p.internal", wantErr: true},
+		{spec: "payment=http://a,payment=http://b", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%q: expected error=%v, got %v", tt.spec, tt.wantErr, err)
+		}
+		if !tt.wantErr && !slices.Equal(got, tt.want) {
+			t.Fatalf("%q: expected %v, got %v", tt.spec, tt.want, got)
+		}
+	}
+}
+
+func TestParseMatrix(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseMatrix(DefaultMatrix)
+	want := map[string]string{"payment": model.DegradeReject, "vendor": model.DegradeDefer, "courier": model.DegradeDefer}
+	if err != nil || !maps.Equal(got, want) {
+		t.Fatalf("expected %v, got %v (%v)", want, got, err)
+	}
+	if got, err := ParseMatrix(" vendor = fail ,"); err != nil || got["vendor"] != model.DegradeFail {
+		t.Fatalf("expected vendor=fail, got %v (%v)", got, err)
+	}
+	for _, spec := range []string{"payment", "=reject", "payment=retry", "payment=reject,payment=fail"} {
+		if _, err := ParseMatrix(spec); err == nil {
+			t.Fatalf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	t.Parallel()
+
+	var failing atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	var downCalls, recoverCalls []string
+	m := New([]Dependency{{Name: "payment", URL: srv.URL}, {Name: "vendor", URL: srv.URL}},
+		Config{Interval: time.Minute, DownAfter: 2, Matrix: map[string]string{"vendor": model.DegradeDefer}},
+		slog.New(slog.NewTextHandler(&logs, nil)),
+		OnDown(func(name string) { downCalls = append(downCalls, name) }),
+		OnRecover(func(name string) { recoverCalls = append(recoverCalls, name) }),
+	)
+	now := time.Unix(1_700_000_000, 0)
+	m.now = func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+	vendor := m.deps[1]
+
+	if st := m.Stats()[1]; st.Status != Unknown || st.Checks != 0 {
+		t.Fatalf("expected unchecked, got %+v", st)
+	}
+
+	m.check(context.Background(), vendor)
+	if st := m.Stats()[1]; st.Status != Up || st.Since == "" || st.LastLatencyMS != 1 {
+		t.Fatalf("expected up, got %+v", st)
+	}
+
+	// One failure is not enough to be down.
+	failing.Store(true)
+	m.check(context.Background(), vendor)
+	if st := m.Stats()[1]; st.Status != Up || st.LastError != "status 503" || st.ConsecutiveFailures != 1 {
+		t.Fatalf("expected still up after one failure, got %+v", st)
+	}
+	m.check(context.Background(), vendor)
+	m.check(context.Background(), vendor)
+	st := m.Stats()[1]
+	if st.Status != Down || st.Checks != 4 || st.Failures != 3 {
+		t.Fatalf("expected down, got %+v", st)
+	}
+	if !slices.Equal(m.Down(), []string{"vendor"}) {
+		t.Fatalf("expected vendor down, got %v", m.Down())
+	}
+	if got := m.Degradations(); !slices.Equal(got, []model.Degradation{{Dependency: "vendor", Action: model.DegradeDefer}}) {
+		t.Fatalf("expected vendor deferred, got %v", got)
+	}
+	if st := m.Stats()[0]; st.Degradation != model.DegradeFail {
+		t.Fatalf("expected payment to fail by default, got %q", st.Degradation)
+	}
+	if !slices.Equal(downCalls, []string{"vendor", "vendor"}) {
+		t.Fatalf("expected OnDown after both checks finding vendor down, got %v", downCalls)
+	}
+	if n := strings.Count(logs.String(), `msg="dependency down"`); n != 1 {
+		t.Fatalf("expected one down log line, got %d:\n%s", n, logs.String())
+	}
+
+	failing.Store(false)
+	m.check(context.Background(), vendor)
+	if st := m.Stats()[1]; st.Status != Up || st.LastError != "" || st.ConsecutiveFailures != 0 {
+		t.Fatalf("expected recovered, got %+v", st)
+	}
+	if !slices.Equal(recoverCalls, []string{"vendor"}) || m.Down() != nil || m.Degradations() != nil {
+		t.Fatalf("expected one recovery, got %v (down %v)", recoverCalls, m.Down())
+	}
+	if !strings.Contains(logs.String(), `msg="dependency recovered" dependency=vendor`) {
+		t.Fatalf("expected a recovery log line, got:\n%s", logs.String())
+	}
+}
+
+func TestMonitorTimeout(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	m := New([]Dependency{{Name: "courier", URL: srv.URL}}, Config{Timeout: 20 * time.Millisecond, DownAfter: 1},
+		slog.New(slog.DiscardHandler))
+	m.checkAll(context.Background())
+	if st := m.Stats()[0]; st.Status != Down || !strings.Contains(st.LastError, "deadline exceeded") {
+		t.Fatalf("expected a hung dependency down, got %+v", st)
+	}
+}
+
+func TestMonitorRun(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits.Add(1) }))
+	defer srv.Close()
+
+	var paused atomic.Bool
+	paused.Store(true)
+	m := New([]Dependency{{Name: "payment", URL: srv.URL}}, Config{Interval: 5 * time.Millisecond},
+		slog.New(slog.DiscardHandler), PauseWhile(paused.Load))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Run(ctx)
+		close(done)
+	}()
+	time.Sleep(30 * time.Millisecond)
+	if n := hits.Load(); n != 0 {
+		t.Fatalf("expected no checks while paused, got %d", n)
+	}
+	paused.Store(false)
+	deadline := time.Now().Add(2 * time.Second)
+	for hits.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if st := m.Stats()[0]; st.Status != Up || st.Checks < 2 {
+		t.Fatalf("expected repeated checks, got %+v", st)
+	}
+}
+
+func TestNewPanics(t *testing.T) {
+	t.Parallel()
+
+	for name, fn := range map[string]func(){
+		"no_dependencies": func() { New(nil, Config{}, slog.New(slog.DiscardHandler)) },
+		"nil_logger":      func() { New([]Dependency{{Name: "payment", URL: "http://p"}}, Config{}, nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: expected a panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
@@ -0,0 +1,336 @@
+// Package killswitch disables failing routes and steps.
+//
+// A Switch watches the error rate of one route or step over a rolling
+// window. When the rate reaches a threshold it trips: the route answers
+// with a fallback response and the step fails fast, without calling the
+// dependency, until a cool-down elapses. Operators can also force a
+// switch on or off.
+package killswitch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/statuswriter"
+)
+
+// Switch modes.
+const (
+	Auto = "auto" // trip on the error rate
+	On   = "on"   // forced enabled; never trips
+	Off  = "off"  // forced disabled
+)
+
+type disabledError struct{}
+
+func (disabledError) Error() string { return "disabled by kill switch" }
+func (disabledError) Kind() string  { return "disabled" }
+
+// ErrDisabled is returned by steps whose switch is disabled.
+var ErrDisabled = disabledError{}
+
+// Config sets when switches trip and for how long.
+type Config struct {
+	Threshold   float64       // failure fraction that trips a switch; default 0.5
+	MinRequests int           // outcomes in the window before a switch can trip; default 20
+	Window      time.Duration // length of the counting window; default 30 seconds
+	Cooldown    time.Duration // how long an automatic trip lasts; default 30 seconds
+}
+
+// Board holds the switches of one process.
+type Board struct {
+	cfg    Config
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu       sync.Mutex
+	byName   map[string]*Switch
+	switches []*Switch // registration order
+}
+
+// NewBoard returns an empty Board. Zero or out-of-range Config fields
+// take their defaults. It panics if logger is nil.
+func NewBoard(cfg Config, logger *slog.Logger) *Board {
+	if logger == nil {
+		panic("killswitch.NewBoard: nil logger")
+	}
+	if cfg.Threshold <= 0 || cfg.Threshold > 1 {
+		cfg.Threshold = 0.5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 20
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 30 * time.Second
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	return &Board{cfg: cfg, logger: logger, now: time.Now, byName: make(map[string]*Switch)}
+}
+
+// Register adds a switch in Auto mode. It panics if name is taken.
+func (b *Board) Register(name string) *Switch {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, dup := b.byName[name]; dup {
+		panic("killswitch.Board.Register: duplicate switch " + name)
+	}
+	s := &Switch{name: name, board: b, mode: Auto}
+	b.byName[name] = s
+	b.switches = append(b.switches, s)
+	return s
+}
+
+// Set changes the mode of the named switch. Setting any mode clears an
+// automatic trip and starts a fresh window.
+func (b *Board) Set(name, mode string) error {
+	s := b.lookup(name)
+	if s == nil {
+		return fmt.Errorf("killswitch: unknown switch %q", name)
+	}
+	if mode != Auto && mode != On && mode != Off {
+		return fmt.Errorf("killswitch: unknown mode %q", mode)
+	}
+	s.mu.Lock()
+	s.mode = mode
+	s.trippedUntil = time.Time{}
+	s.resetLocked(b.now())
+	s.mu.Unlock()
+
+	b.logger.Info("kill switch set", "switch", name, "mode", mode)
+	return nil
+}
+
+// Trip disables the named switch for d as an automatic trip does, for
+// failures seen outside its step, such as a failing health check. Only
+// switches in Auto mode are affected; a trip in progress is extended,
+// never shortened.
+func (b *Board) Trip(name string, d time.Duration, reason string) error {
+	s := b.lookup(name)
+	if s == nil {
+		return fmt.Errorf("killswitch: unknown switch %q", name)
+	}
+	now := b.now()
+	s.mu.Lock()
+	tripped := s.mode == Auto && !now.Before(s.trippedUntil)
+	if s.mode == Auto && now.Add(d).After(s.trippedUntil) {
+		s.trippedUntil = now.Add(d)
+	}
+	if tripped {
+		s.trips++
+		s.resetLocked(now)
+	}
+	s.mu.Unlock()
+
+	if tripped {
+		b.logger.Warn("kill switch tripped", "switch", name, "reason", reason, "cooldown", d)
+	}
+	return nil
+}
+
+// Recover ends an automatic trip of the named switch early.
+func (b *Board) Recover(name string) error {
+	s := b.lookup(name)
+	if s == nil {
+		return fmt.Errorf("killswitch: unknown switch %q", name)
+	}
+	now := b.now()
+	s.mu.Lock()
+	recovered := now.Before(s.trippedUntil)
+	s.trippedUntil = time.Time{}
+	s.resetLocked(now)
+	s.mu.Unlock()
+
+	if recovered {
+		b.logger.Info("kill switch recovered", "switch", name)
+	}
+	return nil
+}
+
+func (b *Board) lookup(name string) *Switch {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.byName[name]
+}
+
+// Stats reports every switch in registration order.
+func (b *Board) Stats() []model.KillSwitch {
+	b.mu.Lock()
+	switches := append([]*Switch(nil), b.switches...)
+	b.mu.Unlock()
+
+	now := b.now()
+	out := make([]model.KillSwitch, len(switches))
+	for i, s := range switches {
+		s.mu.Lock()
+		st := model.KillSwitch{
+			Name:     s.name,
+			Mode:     s.mode,
+			Enabled:  s.allowLocked(now),
+			Requests: s.total,
+			Failures: s.failures,
+			Trips:    s.trips,
+		}
+		if now.Before(s.trippedUntil) {
+			st.TrippedUntil = s.trippedUntil.UTC().Format(time.RFC3339)
+		}
+		s.mu.Unlock()
+		out[i] = st
+	}
+	return out
+}
+
+// Switch guards one route or step.
+type Switch struct {
+	name  string
+	board *Board
+
+	mu           sync.Mutex
+	mode         string
+	windowStart  time.Time
+	total        int64
+	failures     int64
+	trippedUntil time.Time
+	trips        int64
+}
+
+// Allow reports whether the guarded route or step may run.
+func (s *Switch) Allow() bool {
+	now := s.board.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.allowLocked(now)
+}
+
+func (s *Switch) allowLocked(now time.Time) bool {
+	switch s.mode {
+	case On:
+		return true
+	case Off:
+		return false
+	default:
+		return !now.Before(s.trippedUntil)
+	}
+}
+
+// Record counts one outcome and trips the switch if the window's
+// failure rate has reached the threshold.
+func (s *Switch) Record(failed bool) {
+	cfg := s.board.cfg
+	now := s.board.now()
+
+	s.mu.Lock()
+	if now.Sub(s.windowStart) >= cfg.Window {
+		s.resetLocked(now)
+	}
+	s.total++
+	if failed {
+		s.failures++
+	}
+	tripped := s.mode == Auto && !now.Before(s.trippedUntil) &&
+		s.total >= int64(cfg.MinRequests) &&
+		float64(s.failures) >= cfg.Threshold*float64(s.total)
+	var rate float64
+	if tripped {
+		rate = float64(s.failures) / float64(s.total)
+		s.trippedUntil = now.Add(cfg.Cooldown)
+		s.trips++
+		s.resetLocked(now)
+	}
+	s.mu.Unlock()
+
+	if tripped {
+		s.board.logger.Warn("kill switch tripped",
+			"switch", s.name, "failure_rate", rate, "cooldown", cfg.Cooldown)
+	}
+}
+
+func (s *Switch) resetLocked(now time.Time) {
+	s.windowStart, s.total, s.failures = now, 0, 0
+}
+
+// WrapStep returns run guarded by s. While s is disabled, fallback runs
+// instead; with a nil fallback the step fails at once with an error
+// wrapping ErrDisabled. Cancellation caused by a sibling step's failure
+// is not counted against s.
+func (s *Switch) WrapStep(run, fallback func(context.Context, model.OrderRequest) error) func(context.Context, model.OrderRequest) error {
+	return func(ctx context.Context, req model.OrderRequest) error {
+		if !s.Allow() {
+			if fallback != nil {
+				return fallback(ctx, req)
+			}
+			return fmt.Errorf("%s: %w", s.name, ErrDisabled)
+		}
+		err := run(ctx, req)
+		if !errors.Is(err, context.Canceled) {
+			s.Record(err != nil)
+		}
+		return err
+	}
+}
+
+// Step fallbacks accepted by ParseFallbacks.
+const (
+	FallbackFail  = "fail"  // fail with ErrDisabled
+	FallbackDefer = "defer" // postpone the step's work
+)
+
+// ParseFallbacks parses a comma-separated list of step=fallback pairs,
+// for example "courier=defer".
+func ParseFallbacks(spec string) (map[string]string, error) {
+	out := map[string]string{}
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, fb, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || (fb != FallbackFail && fb != FallbackDefer) {
+			return nil, fmt.Errorf("killswitch: fallback %q: want step=fail|defer", entry)
+		}
+		out[name] = fb
+	}
+	return out, nil
+}
+
+// Fallback is the response a disabled route answers with.
+type Fallback struct {
+	Status  int    // default 503
+	Message string // default "temporarily disabled"
+}
+
+// Middleware returns next guarded by s. While s is disabled, requests get
+// fallback with error kind disabled; otherwise 5xx responses count as
+// failures.
+func (s *Switch) Middleware(fallback Fallback, next http.Handler) http.Handler {
+	if fallback.Status == 0 {
+		fallback.Status = http.StatusServiceUnavailable
+	}
+	if fallback.Message == "" {
+		fallback.Message = "temporarily disabled"
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Allow() {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(fallback.Status)
+			_ = json.NewEncoder(w).Encode(model.OrderResponse{
+				Status: model.StatusError,
+				Error:  &model.ErrorPayload{Kind: "disabled", Message: fallback.Message},
+			})
+			return
+		}
+		sw := statuswriter.New(w)
+		next.ServeHTTP(sw, r)
+		s.Record(sw.Status() >= http.StatusInternalServerError)
+	})
+}
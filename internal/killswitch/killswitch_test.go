@@ -0,0 +1,307 @@
+package killswitch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// testBoard returns a board whose clock only moves when the returned
+// advance function is called.
+func testBoard(cfg Config, logger *slog.Logger) (*Board, func(time.Duration)) {
+	b := NewBoard(cfg, logger)
+	now := time.Unix(1_700_000_000, 0)
+	b.now = func() time.Time { return now }
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestSwitchTripsAndRecovers(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	b, advance := testBoard(Config{Threshold: 0.5, MinRequests: 4, Window: time.Minute, Cooldown: 30 * time.Second},
+		slog.New(slog.NewTextHandler(&buf, nil)))
+	s := b.Register("courier")
+
+	calls := 0
+	failing := errors.New("courier down")
+	run := s.WrapStep(func(context.Context, model.OrderRequest) error {
+		calls++
+		return failing
+	}, nil)
+
+	// Below MinRequests the switch stays enabled however bad the rate.
+	for i := 0; i < 3; i++ {
+		if err := run(context.Background(), model.OrderRequest{}); !errors.Is(err, failing) {
+			t.Fatalf("call %d: expected %v, got %v", i+1, failing, err)
+		}
+	}
+	// The fourth failure trips it.
+	_ = run(context.Background(), model.OrderRequest{})
+
+	err := run(context.Background(), model.OrderRequest{})
+	if !errors.Is(err, ErrDisabled) {
+		t.Fatalf("expected %v, got %v", ErrDisabled, err)
+	}
+	var k interface{ Kind() string }
+	if !errors.As(err, &k) || k.Kind() != "disabled" {
+		t.Fatalf("expected kind disabled, got %v", err)
+	}
+	if calls != 4 {
+		t.Fatalf("expected the tripped step not to run, got %d calls", calls)
+	}
+	if !strings.Contains(buf.String(), "kill switch tripped") {
+		t.Fatalf("expected a trip log line, got %q", buf.String())
+	}
+	st := b.Stats()[0]
+	if st.Enabled || st.Trips != 1 || st.TrippedUntil == "" {
+		t.Fatalf("expected a tripped switch, got %+v", st)
+	}
+
+	advance(30 * time.Second)
+	if !s.Allow() {
+		t.Fatal("expected the switch to re-enable after the cool-down")
+	}
+	if st := b.Stats()[0]; !st.Enabled || st.TrippedUntil != "" {
+		t.Fatalf("expected an enabled switch, got %+v", st)
+	}
+}
+
+func TestSwitchWindow(t *testing.T) {
+	t.Parallel()
+
+	b, advance := testBoard(Config{Threshold: 0.5, MinRequests: 4, Window: time.Minute}, slog.New(slog.DiscardHandler))
+	s := b.Register("vendor")
+
+	tests := []struct {
+		name    string
+		outcome []bool // true is a failure
+		advance time.Duration
+		enabled bool
+	}{
+		{name: "under threshold", outcome: []bool{true, false, false, false, false}, enabled: true},
+		{name: "old window expires", outcome: []bool{true, true}, advance: time.Minute, enabled: true},
+		{name: "fresh window trips", outcome: []bool{true, true}, enabled: false},
+	}
+	for _, tt := range tests {
+		advance(tt.advance)
+		for _, failed := range tt.outcome {
+			s.Record(failed)
+		}
+		if got := s.Allow(); got != tt.enabled {
+			t.Fatalf("%s: expected enabled=%v, got %v", tt.name, tt.enabled, got)
+		}
+	}
+}
+
+func TestWrapStepIgnoresCancellation(t *testing.T) {
+	t.Parallel()
+
+	b, _ := testBoard(Config{MinRequests: 1}, slog.New(slog.DiscardHandler))
+	s := b.Register("payment")
+	run := s.WrapStep(func(context.Context, model.OrderRequest) error {
+		return fmt.Errorf("payment: %w", context.Canceled)
+	}, nil)
+	_ = run(context.Background(), model.OrderRequest{})
+	if st := b.Stats()[0]; st.Requests != 0 || !st.Enabled {
+		t.Fatalf("expected sibling cancellation not to count, got %+v", st)
+	}
+}
+
+func TestWrapStepFallback(t *testing.T) {
+	t.Parallel()
+
+	b, _ := testBoard(Config{}, slog.New(slog.DiscardHandler))
+	s := b.Register("courier")
+	deferred := errors.New("deferred")
+	run := s.WrapStep(func(context.Context, model.OrderRequest) error {
+		t.Fatal("expected a disabled step not to run")
+		return nil
+	}, func(context.Context, model.OrderRequest) error {
+		return deferred
+	})
+	if err := b.Set("courier", Off); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(context.Background(), model.OrderRequest{}); !errors.Is(err, deferred) {
+		t.Fatalf("expected %v, got %v", deferred, err)
+	}
+}
+
+func TestParseFallbacks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		spec    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{spec: "", want: map[string]string{}},
+		{spec: "courier=defer, vendor=fail", want: map[string]string{"courier": FallbackDefer, "vendor": FallbackFail}},
+		{spec: "courier", wantErr: true},
+		{spec: "courier=retry", wantErr: true},
+		{spec: "=defer", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFallbacks(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%q: expected error=%v, got %v", tt.spec, tt.wantErr, err)
+		}
+		if !tt.wantErr && !maps.Equal(got, tt.want) {
+			t.Fatalf("%q: expected %v, got %v", tt.spec, tt.want, got)
+		}
+	}
+}
+
+func TestBoardSet(t *testing.T) {
+	t.Parallel()
+
+	b, _ := testBoard(Config{MinRequests: 1}, slog.New(slog.DiscardHandler))
+	s := b.Register("courier")
+
+	tests := []struct {
+		name    string
+		sw      string
+		mode    string
+		wantErr bool
+		enabled bool
+	}{
+		{name: "force off", sw: "courier", mode: Off, enabled: false},
+		{name: "force on", sw: "courier", mode: On, enabled: true},
+		{name: "unknown switch", sw: "nope", mode: Off, wantErr: true, enabled: true},
+		{name: "unknown mode", sw: "courier", mode: "maybe", wantErr: true, enabled: true},
+	}
+	for _, tt := range tests {
+		err := b.Set(tt.sw, tt.mode)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: expected error=%v, got %v", tt.name, tt.wantErr, err)
+		}
+		if got := s.Allow(); got != tt.enabled {
+			t.Fatalf("%s: expected enabled=%v, got %v", tt.name, tt.enabled, got)
+		}
+	}
+
+	// Forced on never trips.
+	s.Record(true)
+	if !s.Allow() {
+		t.Fatal("expected a forced-on switch not to trip")
+	}
+
+	// Back to auto: a failure now trips it, and setting auto again clears the trip.
+	if err := b.Set("courier", Auto); err != nil {
+		t.Fatal(err)
+	}
+	s.Record(true)
+	if s.Allow() {
+		t.Fatal("expected an auto switch to trip")
+	}
+	if err := b.Set("courier", Auto); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Allow() {
+		t.Fatal("expected Set to clear the trip")
+	}
+}
+
+func TestBoardTrip(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	b, advance := testBoard(Config{}, slog.New(slog.NewTextHandler(&buf, nil)))
+	s := b.Register("vendor")
+
+	if err := b.Trip("vendor", 10*time.Second, "health check failed"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Allow() {
+		t.Fatal("expected a tripped switch to be disabled")
+	}
+	// Tripping again extends the trip without counting another.
+	advance(5 * time.Second)
+	_ = b.Trip("vendor", 10*time.Second, "health check failed")
+	advance(8 * time.Second)
+	if s.Allow() {
+		t.Fatal("expected the trip extended")
+	}
+	if st := b.Stats()[0]; st.Trips != 1 {
+		t.Fatalf("expected 1 trip, got %d", st.Trips)
+	}
+	if !strings.Contains(buf.String(), "reason=\"health check failed\"") {
+		t.Fatalf("expected the reason logged, got %s", buf.String())
+	}
+
+	if err := b.Recover("vendor"); err != nil || !s.Allow() {
+		t.Fatalf("expected Recover to end the trip, got enabled=%v (%v)", s.Allow(), err)
+	}
+
+	// Forced modes are left alone.
+	_ = b.Set("vendor", On)
+	_ = b.Trip("vendor", time.Minute, "health check failed")
+	if !s.Allow() {
+		t.Fatal("expected a forced-on switch not to trip")
+	}
+
+	if err := b.Trip("nope", time.Second, ""); err == nil {
+		t.Fatal("expected an error for an unknown switch")
+	}
+	if err := b.Recover("nope"); err == nil {
+		t.Fatal("expected an error for an unknown switch")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	b, _ := testBoard(Config{MinRequests: 2, Threshold: 1}, slog.New(slog.DiscardHandler))
+	s := b.Register("/order")
+
+	status := http.StatusInternalServerError
+	h := s.Middleware(Fallback{Message: "orders paused"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/order", nil))
+		if w.Code != status {
+			t.Fatalf("expected %d, got %d", status, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/order", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+	var resp model.OrderResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Error == nil || resp.Error.Kind != "disabled" || resp.Error.Message != "orders paused" {
+		t.Fatalf("expected the fallback response, got %+v", resp)
+	}
+}
+
+func TestBoardRegisterPanicsOnDuplicate(t *testing.T) {
+	t.Parallel()
+
+	b := NewBoard(Config{}, slog.New(slog.DiscardHandler))
+	b.Register("courier")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for a duplicate switch")
+		}
+	}()
+	b.Register("courier")
+}
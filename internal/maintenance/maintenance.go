@@ -0,0 +1,98 @@
+// Package maintenance implements the process-wide maintenance mode.
+//
+// While maintenance mode is on, the order route rejects new orders with
+// 503 and a Retry-After hint, orders already in flight run to
+// completion, and background workers skip their rounds. Unlike a
+// shutdown, the process keeps serving status and admin reads, and
+// leaving maintenance mode resumes normal service at once.
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// Mode is the maintenance switch. It is safe for concurrent use.
+type Mode struct {
+	retryAfter time.Duration
+	now        func() time.Time
+
+	mu    sync.Mutex
+	since time.Time // zero while off
+}
+
+// New returns a Mode, off, advertising retryAfter to rejected clients.
+// A non-positive retryAfter defaults to 60 seconds.
+func New(retryAfter time.Duration) *Mode {
+	if retryAfter <= 0 {
+		retryAfter = time.Minute
+	}
+	return &Mode{retryAfter: retryAfter, now: time.Now}
+}
+
+// Set turns maintenance mode on or off. Turning it on while it is
+// already on keeps the original start time.
+func (m *Mode) Set(on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case !on:
+		m.since = time.Time{}
+	case m.since.IsZero():
+		m.since = m.now()
+	}
+}
+
+// On reports whether maintenance mode is on. Background workers pass it
+// as their pause condition.
+func (m *Mode) On() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.since.IsZero()
+}
+
+// Status reports the mode. InFlightOrders is left for the caller.
+func (m *Mode) Status() model.Maintenance {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := model.Maintenance{
+		Enabled:     !m.since.IsZero(),
+		RetryAfterS: m.retryAfterSeconds(),
+	}
+	if st.Enabled {
+		st.Since = m.since.UTC().Format(time.RFC3339)
+	}
+	return st
+}
+
+// Middleware returns next guarded by m. While maintenance mode is on,
+// requests get 503 with error kind maintenance and a Retry-After header,
+// without reaching next.
+func (m *Mode) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.On() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.FormatInt(m.retryAfterSeconds(), 10))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(model.OrderResponse{
+			Status: model.StatusError,
+			Error:  &model.ErrorPayload{Kind: "maintenance", Message: "down for maintenance"},
+		})
+	})
+}
+
+// retryAfterSeconds returns the Retry-After hint in whole seconds,
+// rounded up.
+func (m *Mode) retryAfterSeconds() int64 {
+	return int64((m.retryAfter + time.Second - 1) / time.Second)
+}
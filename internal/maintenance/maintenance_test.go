@@ -0,0 +1,75 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestModeSet(t *testing.T) {
+	t.Parallel()
+
+	m := New(1500 * time.Millisecond)
+	now := time.Unix(1_700_000_000, 0)
+	m.now = func() time.Time { return now }
+
+	if m.On() || m.Status().Since != "" {
+		t.Fatalf("expected off, got %+v", m.Status())
+	}
+	m.Set(true)
+	now = now.Add(time.Minute)
+	m.Set(true) // keeps the original start
+	st := m.Status()
+	if !m.On() || st.Since != "2023-11-14T22:13:20Z" || st.RetryAfterS != 2 {
+		t.Fatalf("expected on since the first Set with retry after 2s, got %+v", st)
+	}
+	m.Set(false)
+	if m.On() {
+		t.Fatal("expected off")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	m := New(0)
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		on             bool
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{name: "off_passes_through", wantStatus: http.StatusOK},
+		{name: "on_rejects", on: true, wantStatus: http.StatusServiceUnavailable, wantRetryAfter: "60"},
+	}
+
+	for _, tt := range tests {
+		m.Set(tt.on)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/order", nil))
+		if w.Code != tt.wantStatus {
+			t.Fatalf("%s: expected %d, got %d", tt.name, tt.wantStatus, w.Code)
+		}
+		if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+			t.Fatalf("%s: expected Retry-After %q, got %q", tt.name, tt.wantRetryAfter, got)
+		}
+		if !tt.on {
+			continue
+		}
+		var resp model.OrderResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: decode: %v", tt.name, err)
+		}
+		if resp.Error == nil || resp.Error.Kind != "maintenance" {
+			t.Fatalf("%s: expected kind maintenance, got %+v", tt.name, resp.Error)
+		}
+	}
+}
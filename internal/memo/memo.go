@@ -0,0 +1,108 @@
+// Package memo shares the results of identical calls made while
+// processing one order.
+//
+// The steps of an order run concurrently and may need the same lookup,
+// such as geocoding the same address, from different places. A table
+// attached to the order's context lets the first caller of a key make
+// the call while concurrent and later callers reuse its result instead
+// of calling out again.
+//
+// Results are invalidated by scope and by outcome. A table lives for
+// one order, so a result never reaches another order nor outlives the
+// one that computed it. Within the order only successes are kept: a
+// failed call's error goes to the callers already waiting on it, and
+// the next caller tries again. Forget drops a key early, for a caller
+// that changed what the result depends on.
+package memo
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+type tableKey struct{}
+
+// table holds the calls of one order by key.
+type table struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	done chan struct{} // closed once val and err are set
+	val  any
+	err  error
+}
+
+// NewContext returns a copy of ctx carrying an empty table, for one
+// order.
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tableKey{}, &table{calls: make(map[string]*call)})
+}
+
+// Do returns the result of fn for key within the order ctx belongs to.
+// The first caller of key runs fn; callers arriving while it runs wait
+// for its result, or for their own ctx to be done; later callers get a
+// kept success at once. Without a table in ctx, Do calls fn every time.
+//
+// All callers of a key must expect the same result type, and fn must
+// not call Do with its own key.
+func Do[T any](ctx context.Context, key string, fn func(context.Context) (T, error)) (T, error) {
+	t, _ := ctx.Value(tableKey{}).(*table)
+	if t == nil {
+		return fn(ctx)
+	}
+
+	t.mu.Lock()
+	if c, ok := t.calls[key]; ok {
+		t.mu.Unlock()
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			var zero T
+			return zero, context.Cause(ctx)
+		}
+		if c.err != nil {
+			var zero T
+			return zero, c.err
+		}
+		return c.val.(T), nil
+	}
+	c := &call{done: make(chan struct{})}
+	t.calls[key] = c
+	t.mu.Unlock()
+
+	returned := false
+	defer func() {
+		if !returned {
+			c.err = errPanicked // fn panicked; release the waiters
+		}
+		if c.err != nil {
+			t.mu.Lock()
+			if t.calls[key] == c {
+				delete(t.calls, key)
+			}
+			t.mu.Unlock()
+		}
+		close(c.done)
+	}()
+	val, err := fn(ctx)
+	c.val, c.err, returned = val, err, true
+	return val, err
+}
+
+// errPanicked is the result shared with the waiters of a call that
+// panicked.
+var errPanicked = errors.New("memo: call panicked")
+
+// Forget drops the result kept for key, if any, so that the next Do
+// calls out again. Callers already waiting on a running call still get
+// its result.
+func Forget(ctx context.Context, key string) {
+	if t, _ := ctx.Value(tableKey{}).(*table); t != nil {
+		t.mu.Lock()
+		delete(t.calls, key)
+		t.mu.Unlock()
+	}
+}
@@ -0,0 +1,142 @@
+package memo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int64
+	lookup := func(context.Context) (string, error) {
+		calls.Add(1)
+		return "60.17:24.94", nil
+	}
+
+	ctx := NewContext(context.Background())
+	for range 3 {
+		if got, err := Do(ctx, "geocode:a", lookup); err != nil || got != "60.17:24.94" {
+			t.Fatalf("expected the lookup result, got %q (%v)", got, err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected 1 call within one order, got %d", n)
+	}
+
+	// Another key, another order and no order at all each call again.
+	_, _ = Do(ctx, "geocode:b", lookup)
+	_, _ = Do(NewContext(context.Background()), "geocode:a", lookup)
+	_, _ = Do(context.Background(), "geocode:a", lookup)
+	_, _ = Do(context.Background(), "geocode:a", lookup)
+	if n := calls.Load(); n != 5 {
+		t.Fatalf("expected 5 calls, got %d", n)
+	}
+
+	Forget(ctx, "geocode:a")
+	_, _ = Do(ctx, "geocode:a", lookup)
+	if n := calls.Load(); n != 6 {
+		t.Fatalf("expected Forget to drop the result, got %d calls", n)
+	}
+	Forget(context.Background(), "geocode:a") // no table: nothing to do
+}
+
+func TestDoErrorsNotKept(t *testing.T) {
+	t.Parallel()
+
+	ctx := NewContext(context.Background())
+	failing := errors.New("provider unavailable")
+	calls := 0
+	lookup := func(context.Context) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, failing
+		}
+		return 42, nil
+	}
+	if _, err := Do(ctx, "k", lookup); !errors.Is(err, failing) {
+		t.Fatalf("expected %v, got %v", failing, err)
+	}
+	if got, err := Do(ctx, "k", lookup); err != nil || got != 42 {
+		t.Fatalf("expected a retry after the failure, got %d (%v)", got, err)
+	}
+	if got, _ := Do(ctx, "k", lookup); got != 42 || calls != 2 {
+		t.Fatalf("expected the success kept, got %d after %d calls", got, calls)
+	}
+}
+
+func TestDoConcurrent(t *testing.T) {
+	t.Parallel()
+
+	ctx := NewContext(context.Background())
+	release := make(chan struct{})
+	var calls atomic.Int64
+	lookup := func(context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 7, nil
+	}
+
+	const callers = 8
+	var wg sync.WaitGroup
+	results := make([]int, callers)
+	for i := range callers {
+		wg.Go(func() { results[i], _ = Do(ctx, "k", lookup) })
+	}
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond) // let the others queue behind it
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected concurrent callers to share 1 call, got %d", n)
+	}
+	for i, r := range results {
+		if r != 7 {
+			t.Fatalf("caller %d: expected 7, got %d", i, r)
+		}
+	}
+}
+
+func TestDoWaiterCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx := NewContext(context.Background())
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_, _ = Do(ctx, "k", func(context.Context) (int, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+	}()
+	<-started
+
+	waiterCtx, cancel := context.WithCancelCause(ctx)
+	stop := errors.New("sibling failed")
+	cancel(stop)
+	if _, err := Do(waiterCtx, "k", func(context.Context) (int, error) { return 2, nil }); !errors.Is(err, stop) {
+		t.Fatalf("expected the waiter's cause %v, got %v", stop, err)
+	}
+	close(release)
+}
+
+func TestDoPanic(t *testing.T) {
+	t.Parallel()
+
+	ctx := NewContext(context.Background())
+	func() {
+		defer func() { _ = recover() }()
+		_, _ = Do(ctx, "k", func(context.Context) (int, error) { panic("boom") })
+	}()
+	if got, err := Do(ctx, "k", func(context.Context) (int, error) { return 3, nil }); err != nil || got != 3 {
+		t.Fatalf("expected a call after a panic to run again, got %d (%v)", got, err)
+	}
+}
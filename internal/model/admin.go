@@ -0,0 +1,439 @@
+package model
+
+// LogLevel is the request and response payload of the log-level
+// admin endpoint.
+type LogLevel struct {
+	Level string `json:"level"` // "DEBUG" | "INFO" | "WARN" | "ERROR", optionally with an offset
+}
+
+// SlowRequest is a diagnostic record captured for an order whose
+// processing exceeded the slow-request threshold.
+type SlowRequest struct {
+	Time         string       `json:"time"` // RFC 3339
+	OrderID      string       `json:"order_id"`
+	DurationMS   int64        `json:"duration_ms"`
+	ErrorKind    string       `json:"error_kind,omitempty"`
+	Steps        []StepResult `json:"steps"`
+	Goroutines   int          `json:"goroutines"`
+	PoolCapacity int          `json:"pool_capacity"`
+	PoolInUse    int          `json:"pool_in_use"`
+	PoolWaiting  int64        `json:"pool_waiting"` // callers queued on the pool
+}
+
+// PoolSchedule is the response payload of the courier pool schedule
+// admin endpoint.
+type PoolSchedule struct {
+	ActiveSize  int         `json:"active_size"`
+	DefaultSize int         `json:"default_size"`
+	ActiveShift int         `json:"active_shift"` // index into Shifts, -1 outside every shift
+	Shifts      []PoolShift `json:"shifts"`
+}
+
+// PoolShift is one entry of a PoolSchedule.
+type PoolShift struct {
+	Days  []string `json:"days,omitempty"` // "mon".."sun"; empty means every day
+	Start string   `json:"start"`          // "HH:MM"
+	End   string   `json:"end"`            // "HH:MM"; at or before Start wraps past midnight
+	Size  int      `json:"size"`
+}
+
+// SLAClassStats reports latency objective attainment for one service
+// class.
+type SLAClassStats struct {
+	Class      string  `json:"class"`
+	TimeoutMS  int64   `json:"timeout_ms"`
+	TargetMS   int64   `json:"target_ms"`
+	Requests   int64   `json:"requests"`
+	Met        int64   `json:"met"`        // succeeded within TargetMS
+	Attainment float64 `json:"attainment"` // Met / Requests; 0 before the first request
+}
+
+// ChannelStats reports the outcomes and latency of the orders from one
+// source channel.
+type ChannelStats struct {
+	Channel     string  `json:"channel"`
+	Orders      int64   `json:"orders"`
+	Succeeded   int64   `json:"succeeded"`
+	Failed      int64   `json:"failed"`
+	SuccessRate float64 `json:"success_rate"` // Succeeded / Orders; 0 before the first order
+	AvgMS       int64   `json:"avg_ms"`
+	MaxMS       int64   `json:"max_ms"`
+}
+
+// RegionStats is the response payload of the region admin endpoint.
+type RegionStats struct {
+	Region      string            `json:"region"`
+	Peers       map[string]string `json:"peers"`       // region → base URL
+	Served      int64             `json:"served"`      // orders processed here
+	Misdirected map[string]int64  `json:"misdirected"` // orders refused, by the region they belong to
+}
+
+// ProbeStats is the response payload of the synthetic order probe admin
+// endpoint.
+type ProbeStats struct {
+	IntervalMS     int64  `json:"interval_ms"`
+	SLOMS          int64  `json:"slo_ms"`
+	Runs           int64  `json:"runs"`
+	Failures       int64  `json:"failures"`
+	Slow           int64  `json:"slow"`                // succeeded but exceeded SLOMS
+	LastTime       string `json:"last_time,omitempty"` // RFC 3339
+	LastDurationMS int64  `json:"last_duration_ms"`
+	LastError      string `json:"last_error,omitempty"`
+}
+
+// DependencyStatus reports the health of one upstream provider, from
+// the dependency monitor admin endpoint.
+type DependencyStatus struct {
+	Name                string `json:"name"`
+	URL                 string `json:"url"`
+	Status              string `json:"status"`          // "unknown" (not checked yet) | "up" | "down"
+	Degradation         string `json:"degradation"`     // action while down: DegradeReject | DegradeDefer | DegradeFail
+	Since               string `json:"since,omitempty"` // RFC 3339, when Status last changed
+	Checks              int64  `json:"checks"`
+	Failures            int64  `json:"failures"`
+	ConsecutiveFailures int64  `json:"consecutive_failures"`
+	LastCheck           string `json:"last_check,omitempty"` // RFC 3339
+	LastLatencyMS       int64  `json:"last_latency_ms"`
+	LastError           string `json:"last_error,omitempty"`
+}
+
+// Readiness is the response payload of the readiness endpoint.
+type Readiness struct {
+	Ready bool     `json:"ready"`
+	Down  []string `json:"down,omitempty"` // dependencies failing their health checks
+}
+
+// SLOStats reports availability and latency objective compliance for
+// one route over the rolling windows.
+type SLOStats struct {
+	Route         string  `json:"route"`
+	Availability  float64 `json:"availability"` // objective, e.g. 0.999
+	LatencyMS     int64   `json:"latency_ms"`   // slower requests count as bad; 0 if unset
+	Requests      int64   `json:"requests"`     // in the long window
+	Bad           int64   `json:"bad"`          // in the long window
+	ShortBurnRate float64 `json:"short_burn_rate"`
+	LongBurnRate  float64 `json:"long_burn_rate"`
+	FastBurn      bool    `json:"fast_burn"` // both burn rates at or above the fast-burn threshold
+}
+
+// ErrorKindRate reports the failure rate of one error kind and whether
+// it is spiking above its baseline.
+type ErrorKindRate struct {
+	Kind      string  `json:"kind"`
+	Baseline  float64 `json:"baseline"`  // smoothed fraction of orders failing with Kind
+	Rate      float64 `json:"rate"`      // fraction in the last complete window
+	Count     int64   `json:"count"`     // orders failing with Kind in the last complete window
+	Anomalous bool    `json:"anomalous"` // Rate is a significant spike over Baseline
+}
+
+// KillSwitch reports the state of one route or step kill switch.
+type KillSwitch struct {
+	Name         string `json:"name"`
+	Mode         string `json:"mode"` // "auto" | "on" (forced enabled) | "off" (forced disabled)
+	Enabled      bool   `json:"enabled"`
+	TrippedUntil string `json:"tripped_until,omitempty"` // RFC 3339, while an automatic trip lasts
+	Requests     int64  `json:"requests"`                // in the current window
+	Failures     int64  `json:"failures"`                // in the current window
+	Trips        int64  `json:"trips"`                   // automatic trips so far
+}
+
+// KillSwitchUpdate is the request payload for setting a kill switch mode.
+type KillSwitchUpdate struct {
+	Name string `json:"name"`
+	Mode string `json:"mode"`
+}
+
+// DeferredQueue reports the deferred step completion queue.
+type DeferredQueue struct {
+	Pending   int            `json:"pending"`
+	Completed int64          `json:"completed"`
+	Expired   int64          `json:"expired"`
+	Rejected  int64          `json:"rejected"` // enqueues refused because the queue was full
+	Tasks     []DeferredTask `json:"tasks,omitempty"`
+}
+
+// DeferredTask is one pending deferred step.
+type DeferredTask struct {
+	OrderID   string `json:"order_id"`
+	Step      string `json:"step"`
+	Attempts  int    `json:"attempts"`
+	Enqueued  string `json:"enqueued"` // RFC 3339
+	LastError string `json:"last_error,omitempty"`
+}
+
+// StatusMinute counts orders by final status for one minute.
+type StatusMinute struct {
+	Minute string           `json:"minute"` // RFC 3339, start of the minute
+	Counts map[Status]int64 `json:"counts"`
+}
+
+// StepFailureCount counts step failures of one kind.
+type StepFailureCount struct {
+	Step  string `json:"step"`
+	Kind  string `json:"kind"`
+	Count int64  `json:"count"`
+}
+
+// LatencyMinute summarizes order processing latency for one minute.
+type LatencyMinute struct {
+	Minute string `json:"minute"` // RFC 3339, start of the minute
+	Orders int64  `json:"orders"`
+	AvgMS  int64  `json:"avg_ms"`
+	MaxMS  int64  `json:"max_ms"`
+}
+
+// RecentFailure is one recently failed order.
+type RecentFailure struct {
+	Time    string `json:"time"` // RFC 3339
+	OrderID string `json:"order_id"`
+	Kind    string `json:"kind"`
+}
+
+// DashboardSnapshot is the data behind the web dashboard.
+type DashboardSnapshot struct {
+	Time           string             `json:"time"` // RFC 3339
+	InFlightOrders int64              `json:"in_flight_orders"`
+	InFlightSteps  int64              `json:"in_flight_steps"`
+	Pool           CapacityResponse   `json:"pool"`
+	Latency        []LatencyMinute    `json:"latency"`
+	Statuses       []StatusMinute     `json:"statuses"`
+	TopFailures    []StepFailureCount `json:"top_failures"`
+	RecentFailures []RecentFailure    `json:"recent_failures"`
+}
+
+// Outcomes of one order in a BatchReport.
+const (
+	OutcomeCanceled     = "canceled"      // canceled and stopped
+	OutcomeStillRunning = "still_running" // canceled but not stopped before the report
+	OutcomeNotFound     = "not_found"     // not in flight
+)
+
+// BatchCancelRequest is the request payload of the batch cancel admin
+// endpoint. Exactly one of OrderIDs and Filter is set.
+type BatchCancelRequest struct {
+	OrderIDs []string     `json:"order_ids,omitempty"`
+	Filter   *OrderFilter `json:"filter,omitempty"`
+}
+
+// OrderFilter selects in-flight orders. Set criteria must all match;
+// at least one is required.
+type OrderFilter struct {
+	Zone     string `json:"zone,omitempty"`
+	MinAgeMS int64  `json:"min_age_ms,omitempty"` // in flight at least this long
+}
+
+// BatchReport is the response payload of a batch admin operation.
+type BatchReport struct {
+	Results []BatchOutcome `json:"results"`
+}
+
+// BatchOutcome is the outcome of a batch operation for one order.
+type BatchOutcome struct {
+	OrderID string `json:"order_id"`
+	Outcome string `json:"outcome"` // OutcomeCanceled | OutcomeStillRunning | OutcomeNotFound
+}
+
+// OrderProgress is one in-flight order in the order progress admin
+// endpoint.
+type OrderProgress struct {
+	OrderID   string       `json:"order_id"`
+	Zone      string       `json:"zone,omitempty"`
+	ElapsedMS int64        `json:"elapsed_ms"`
+	Done      []StepResult `json:"done"`    // finished steps, in completion order
+	Running   []string     `json:"running"` // steps not finished yet, in registration order
+	// Heartbeats holds the latest heartbeat of each running step that
+	// has sent one, in registration order.
+	Heartbeats []StepHeartbeat `json:"heartbeats,omitempty"`
+}
+
+// StepHeartbeat is the latest progress report of a running step.
+type StepHeartbeat struct {
+	Step    string `json:"step"`
+	Percent *int   `json:"percent,omitempty"` // 0-100; omitted if the step reported none
+	Message string `json:"message,omitempty"`
+	AgeMS   int64  `json:"age_ms"` // since the heartbeat
+}
+
+// Maintenance is the response payload of the maintenance mode admin
+// endpoint.
+type Maintenance struct {
+	Enabled        bool   `json:"enabled"`
+	Since          string `json:"since,omitempty"` // RFC 3339, while enabled
+	RetryAfterS    int64  `json:"retry_after_s"`   // Retry-After sent to rejected orders
+	InFlightOrders int64  `json:"in_flight_orders"`
+}
+
+// MaintenanceUpdate is the request payload for turning maintenance mode
+// on or off.
+type MaintenanceUpdate struct {
+	Enabled bool `json:"enabled"`
+}
+
+// CostReport is the response payload of the cost attribution admin
+// endpoint.
+type CostReport struct {
+	TotalMicros int64      `json:"total_micros"`
+	Lines       []CostLine `json:"lines"`
+}
+
+// CostLine is the accumulated spend of one step on one resource for one
+// tenant.
+type CostLine struct {
+	Tenant     string `json:"tenant"`
+	Step       string `json:"step"`
+	Provider   string `json:"provider"`
+	Region     string `json:"region"`
+	Calls      int64  `json:"calls"`
+	CostMicros int64  `json:"cost_micros"` // millionths of the currency unit
+}
+
+// SidecarStatus reports one sidecar step for the sidecar admin endpoint.
+type SidecarStatus struct {
+	Step      string `json:"step"`
+	Command   string `json:"command"`
+	TimeoutMS int64  `json:"timeout_ms"`
+	Running   bool   `json:"running"`
+	Healthy   bool   `json:"healthy"` // answered the latest health check
+	PID       int    `json:"pid,omitempty"`
+	Calls     int64  `json:"calls"`
+	Failures  int64  `json:"failures"`
+	Restarts  int64  `json:"restarts"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// PolicyRules is the response payload listing the policy rules, in
+// evaluation order.
+type PolicyRules struct {
+	Rules []string `json:"rules"`
+}
+
+// PolicyEvaluation is the request payload of the policy dry-run admin
+// endpoint: the order to route, on behalf of Tenant, as if received at
+// Time (RFC 3339; now when empty).
+type PolicyEvaluation struct {
+	Order  OrderRequest `json:"order"`
+	Tenant string       `json:"tenant,omitempty"`
+	Time   string       `json:"time,omitempty"`
+}
+
+// PolicyDecision is the outcome of routing one order by the policy
+// rules.
+type PolicyDecision struct {
+	Matched  bool         `json:"matched"`
+	Rule     int          `json:"rule"`                // index of the matching rule; -1 if none
+	RuleText string       `json:"rule_text,omitempty"` // as configured
+	Order    OrderRequest `json:"order"`               // with the rule's settings applied
+}
+
+// Categories of differences between a primary and a shadow outcome.
+const (
+	MismatchStatus      = "status"       // order status
+	MismatchErrorKind   = "error_kind"   // kind of the primary error
+	MismatchErrors      = "errors"       // set of step failures, in fail-at-end mode
+	MismatchStepStatus  = "step_status"  // a step's status
+	MismatchStepDetail  = "step_detail"  // a step's detail, such as its error kind
+	MismatchStepMissing = "step_missing" // a primary step the shadow did not run
+	MismatchStepExtra   = "step_extra"   // a shadow step the primary did not run
+)
+
+// ShadowMismatch is one difference between a primary and a shadow
+// outcome.
+type ShadowMismatch struct {
+	Category string `json:"category"` // Mismatch*
+	Detail   string `json:"detail"`   // e.g. `step vendor: ok != error`
+}
+
+// ShadowReport is the response payload of the shadow traffic admin
+// endpoint.
+type ShadowReport struct {
+	Rate       float64          `json:"rate"`     // fraction of orders mirrored
+	Mirrored   int64            `json:"mirrored"` // mirrors sent
+	Skipped    int64            `json:"skipped"`  // sampled, but too many mirrors in flight
+	Failed     int64            `json:"failed"`   // no outcome from the shadow
+	Matched    int64            `json:"matched"`
+	Diverged   int64            `json:"diverged"`
+	Categories []ShadowCategory `json:"categories"` // by count, highest first
+}
+
+// ShadowCategory counts the divergent shadow outcomes with at least one
+// mismatch of Category, with the latest example.
+type ShadowCategory struct {
+	Category    string `json:"category"`
+	Count       int64  `json:"count"`
+	LastOrderID string `json:"last_order_id"`
+	LastDetail  string `json:"last_detail"`
+}
+
+// EffectiveConfig is the configuration a server resolved from its
+// flags at startup. Secrets in flag values are redacted.
+type EffectiveConfig struct {
+	Flags     map[string]string `json:"flags"`      // every flag, defaults included
+	Sources   map[string]string `json:"sources"`    // "profile", "file", "env" or "flag" for flags not at their default
+	Steps     []string          `json:"steps"`      // pipeline steps in run order
+	TailSteps []string          `json:"tail_steps"` // run after successful orders
+	Limits    map[string]int    `json:"limits"`     // courier_pool, courier_zone:<name>, outbound, outbound:<dest>
+	Features  map[string]bool   `json:"features"`   // optional features and whether they are enabled
+}
+
+// InstanceInfo is the response payload of the instance info admin
+// endpoint.
+type InstanceInfo struct {
+	Build     BuildInfo       `json:"build"`
+	StartedAt string          `json:"started_at"` // RFC 3339
+	Config    EffectiveConfig `json:"config"`
+	Timeouts  ServerTimeouts  `json:"timeouts"` // in effect now; Config has the startup flags
+}
+
+// ServerTimeouts is the request and response payload of the server
+// timeouts admin endpoint. In a request, zero fields are left
+// unchanged.
+type ServerTimeouts struct {
+	ReadMS    int64 `json:"read_ms"`    // reading a request body; 0 disables
+	WriteMS   int64 `json:"write_ms"`   // writing a response; 0 disables
+	IdleMS    int64 `json:"idle_ms"`    // keep-alive between requests; 0 disables
+	RequestMS int64 `json:"request_ms"` // processing deadline of an order
+}
+
+// ConnectionStats is the response payload of the server connections
+// admin endpoint.
+type ConnectionStats struct {
+	Max           int     `json:"max"`    // open connection limit; 0 means unlimited
+	Open          int     `json:"open"`   // new, active and idle
+	Active        int     `json:"active"` // serving a request
+	Idle          int     `json:"idle"`   // keep-alive, between requests
+	Peak          int     `json:"peak"`
+	Accepted      int64   `json:"accepted"`
+	Rejected      int64   `json:"rejected"` // answered 503 over the limit
+	Closed        int64   `json:"closed"`
+	Reused        int64   `json:"reused"`          // requests on kept-alive connections
+	AvgLifetimeMS int64   `json:"avg_lifetime_ms"` // of closed connections
+	AvgRequests   float64 `json:"avg_requests"`    // per closed connection
+}
+
+// UnknownField counts the requests to a tolerant route whose body had
+// a field the server did not know, decoded without it.
+type UnknownField struct {
+	Route    string `json:"route"`
+	Field    string `json:"field"`
+	Count    int64  `json:"count"`
+	LastSeen string `json:"last_seen"` // RFC 3339
+}
+
+// BuildInfo identifies the binary a server runs.
+type BuildInfo struct {
+	Version    string `json:"version"`               // set at link time, else the module version
+	Commit     string `json:"commit,omitempty"`      // VCS revision
+	CommitTime string `json:"commit_time,omitempty"` // RFC 3339
+	Modified   bool   `json:"modified"`              // built from a tree with uncommitted changes
+	GoVersion  string `json:"go_version"`
+}
+
+// StepLimit reports the orchestrator's limit on the steps of one order
+// running at once.
+type StepLimit struct {
+	Limit    int   `json:"limit"`
+	Started  int64 `json:"started"`   // steps started under the limit
+	Queued   int64 `json:"queued"`    // of which waited for a slot
+	Waiting  int64 `json:"waiting"`   // steps waiting for a slot now
+	QueuedMS int64 `json:"queued_ms"` // total time steps waited
+}
@@ -0,0 +1,30 @@
+package model
+
+// AuditEntry is one record of the hash-chained audit log.
+//
+// Hash is the hex SHA-256 of the entry's JSON encoding with Hash empty;
+// PrevHash is the Hash of the preceding entry, empty for the first.
+type AuditEntry struct {
+	Seq       int64        `json:"seq"`  // 1-based position in the log
+	Time      string       `json:"time"` // RFC 3339 with nanoseconds, set on append
+	Event     string       `json:"event"`
+	OrderID   string       `json:"order_id"`
+	Status    Status       `json:"status"`
+	ErrorKind string       `json:"error_kind,omitempty"`
+	Steps     []StepResult `json:"steps,omitempty"`
+	PrevHash  string       `json:"prev_hash"`
+	Hash      string       `json:"hash"`
+}
+
+// AuditVerification is the response payload of the audit chain
+// verification endpoint.
+type AuditVerification struct {
+	From      string `json:"from,omitempty"` // RFC 3339; empty means the start of the log
+	To        string `json:"to,omitempty"`   // RFC 3339; empty means now
+	Entries   int64  `json:"entries"`        // entries checked in the range
+	FirstSeq  int64  `json:"first_seq,omitempty"`
+	LastSeq   int64  `json:"last_seq,omitempty"`
+	Valid     bool   `json:"valid"`
+	BrokenSeq int64  `json:"broken_seq,omitempty"` // first entry failing verification
+	Reason    string `json:"reason,omitempty"`
+}
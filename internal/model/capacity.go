@@ -0,0 +1,52 @@
+package model
+
+// CapacityResponse is the output payload of the capacity endpoint.
+//
+// It advertises current headroom so clients can self-throttle.
+type CapacityResponse struct {
+	Capacity             int     `json:"capacity"`
+	InUse                int     `json:"in_use"`
+	Waiting              int64   `json:"waiting"`
+	Headroom             int     `json:"headroom"`
+	Load                 float64 `json:"load"` // (in_use + waiting) / capacity
+	SuggestedConcurrency int     `json:"suggested_concurrency"`
+}
+
+// CourierZone reports one delivery zone's courier pool.
+type CourierZone struct {
+	Name     string `json:"name"`
+	Capacity int    `json:"capacity"`
+	InUse    int    `json:"in_use"`
+	Waiting  int64  `json:"waiting"`
+	Assigned int64  `json:"assigned"` // slots taken for the zone's own orders
+	Lent     int64  `json:"lent"`     // slots taken for adjacent zones' orders
+}
+
+// OutboundLimit reports one outbound concurrency limit.
+type OutboundLimit struct {
+	Destination string `json:"destination"` // "*" for the global limit
+	Limit       int    `json:"limit"`
+	InUse       int    `json:"in_use"`
+	Waiting     int64  `json:"waiting"`
+}
+
+// PoolTelemetry reports how long callers waited for the courier pool
+// and how often, and for how long, it was full.
+type PoolTelemetry struct {
+	Capacity           int          `json:"capacity"`
+	Acquired           int64        `json:"acquired"`
+	Abandoned          int64        `json:"abandoned"` // waits given up when the order's context ended
+	WaitBuckets        []WaitBucket `json:"wait_buckets"`
+	Saturated          bool         `json:"saturated"`
+	SaturatedSince     string       `json:"saturated_since,omitempty"` // RFC 3339, while saturated
+	Saturations        int64        `json:"saturations"`               // full periods begun
+	SaturatedMS        int64        `json:"saturated_ms"`              // total time full
+	LongestSaturatedMS int64        `json:"longest_saturated_ms"`      // longest ended full period
+}
+
+// WaitBucket counts acquisitions that waited at most LE, and longer than
+// the previous bucket's bound.
+type WaitBucket struct {
+	LE    string `json:"le"` // e.g. "10ms"; "+Inf" for the last bucket
+	Count int64  `json:"count"`
+}
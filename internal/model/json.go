@@ -0,0 +1,286 @@
+package model
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Hand-written JSON encoders for the /order hot path.
+//
+// Each AppendJSON method appends exactly the bytes json.Marshal would
+// produce for the value (including HTML escaping and omitempty rules),
+// without reflection. Types without an AppendJSON method are encoded by
+// encoding/json. Status values are written as-is; the validation in
+// Status.MarshalText is not repeated on the hot path.
+
+// AppendJSON appends the JSON encoding of r to b.
+func (r OrderRequest) AppendJSON(b []byte) []byte {
+	b = append(b, `{"order_id":`...)
+	b = appendString(b, r.OrderID)
+	b = append(b, `,"amount":`...)
+	b = strconv.AppendUint(b, r.Amount, 10)
+	if r.FailStep != "" {
+		b = append(b, `,"fail_step":`...)
+		b = appendString(b, r.FailStep)
+	}
+	if len(r.DelayMS) > 0 {
+		b = append(b, `,"delay_ms":{`...)
+		for i, k := range slices.Sorted(maps.Keys(r.DelayMS)) {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendString(b, k)
+			b = append(b, ':')
+			b = strconv.AppendInt(b, r.DelayMS[k], 10)
+		}
+		b = append(b, '}')
+	}
+	if r.Address != "" {
+		b = append(b, `,"address":`...)
+		b = appendString(b, r.Address)
+	}
+	if r.Zone != "" {
+		b = append(b, `,"zone":`...)
+		b = appendString(b, r.Zone)
+	}
+	if r.SLA != "" {
+		b = append(b, `,"sla":`...)
+		b = appendString(b, r.SLA)
+	}
+	if r.Channel != "" {
+		b = append(b, `,"channel":`...)
+		b = appendString(b, r.Channel)
+	}
+	if r.Region != "" {
+		b = append(b, `,"region":`...)
+		b = appendString(b, r.Region)
+	}
+	if len(r.Items) > 0 {
+		b = append(b, `,"items":[`...)
+		for i, it := range r.Items {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, `{"vendor":`...)
+			b = appendString(b, it.Vendor)
+			b = append(b, `,"amount":`...)
+			b = strconv.AppendUint(b, it.Amount, 10)
+			b = append(b, '}')
+		}
+		b = append(b, ']')
+	}
+	return append(b, '}')
+}
+
+// AppendJSON appends the JSON encoding of r to b.
+func (r OrderResponse) AppendJSON(b []byte) []byte {
+	b = append(b, `{"status":`...)
+	b = appendString(b, string(r.Status))
+	b = append(b, `,"order_id":`...)
+	b = appendString(b, r.OrderID)
+	if len(r.Steps) > 0 {
+		b = append(b, `,"steps":[`...)
+		for i, s := range r.Steps {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = s.AppendJSON(b)
+		}
+		b = append(b, ']')
+	}
+	if r.Error != nil {
+		b = append(b, `,"error":`...)
+		b = r.Error.AppendJSON(b)
+	}
+	if len(r.Errors) > 0 {
+		b = append(b, `,"errors":[`...)
+		for i, e := range r.Errors {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = e.AppendJSON(b)
+		}
+		b = append(b, ']')
+	}
+	if c := r.CancellationCause; c != nil {
+		b = append(b, `,"cancellation_cause":{"reason":`...)
+		b = appendString(b, c.Reason)
+		if c.Step != "" {
+			b = append(b, `,"step":`...)
+			b = appendString(b, c.Step)
+		}
+		if c.Kind != "" {
+			b = append(b, `,"kind":`...)
+			b = appendString(b, c.Kind)
+		}
+		b = append(b, '}')
+	}
+	if r.LoyaltyPoints != 0 {
+		b = append(b, `,"loyalty_points":`...)
+		b = strconv.AppendInt(b, r.LoyaltyPoints, 10)
+	}
+	if len(r.Degradations) > 0 {
+		b = append(b, `,"degradations":[`...)
+		for i, d := range r.Degradations {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, `{"dependency":`...)
+			b = appendString(b, d.Dependency)
+			b = append(b, `,"action":`...)
+			b = appendString(b, d.Action)
+			b = append(b, '}')
+		}
+		b = append(b, ']')
+	}
+	if r.Shared {
+		b = append(b, `,"shared":true`...)
+	}
+	if len(r.SubOrders) > 0 {
+		b = append(b, `,"sub_orders":[`...)
+		for i, so := range r.SubOrders {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = so.AppendJSON(b)
+		}
+		b = append(b, ']')
+	}
+	return append(b, '}')
+}
+
+// AppendJSON appends the JSON encoding of s to b.
+func (s SubOrder) AppendJSON(b []byte) []byte {
+	b = append(b, `{"order_id":`...)
+	b = appendString(b, s.OrderID)
+	b = append(b, `,"vendor":`...)
+	b = appendString(b, s.Vendor)
+	b = append(b, `,"amount":`...)
+	b = strconv.AppendUint(b, s.Amount, 10)
+	b = append(b, `,"status":`...)
+	b = appendString(b, string(s.Status))
+	if len(s.Steps) > 0 {
+		b = append(b, `,"steps":[`...)
+		for i, st := range s.Steps {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = st.AppendJSON(b)
+		}
+		b = append(b, ']')
+	}
+	if s.Error != nil {
+		b = append(b, `,"error":`...)
+		b = s.Error.AppendJSON(b)
+	}
+	return append(b, '}')
+}
+
+// AppendJSON appends the JSON encoding of s to b.
+func (s StepResult) AppendJSON(b []byte) []byte {
+	b = append(b, `{"name":`...)
+	b = appendString(b, s.Name)
+	b = append(b, `,"status":`...)
+	b = appendString(b, string(s.Status))
+	b = append(b, `,"duration_ms":`...)
+	b = strconv.AppendInt(b, s.DurationMS, 10)
+	if s.Detail != "" {
+		b = append(b, `,"detail":`...)
+		b = appendString(b, s.Detail)
+	}
+	if s.Variant != "" {
+		b = append(b, `,"variant":`...)
+		b = appendString(b, s.Variant)
+	}
+	return append(b, '}')
+}
+
+// AppendJSON appends the JSON encoding of e to b.
+func (e ErrorPayload) AppendJSON(b []byte) []byte {
+	b = append(b, `{"kind":`...)
+	b = appendString(b, e.Kind)
+	if e.Message != "" {
+		b = append(b, `,"message":`...)
+		b = appendString(b, e.Message)
+	}
+	if e.Step != "" {
+		b = append(b, `,"step":`...)
+		b = appendString(b, e.Step)
+	}
+	if len(e.Fields) > 0 {
+		b = append(b, `,"fields":[`...)
+		for i, f := range e.Fields {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, `{"field":`...)
+			b = appendString(b, f.Field)
+			b = append(b, `,"message":`...)
+			b = appendString(b, f.Message)
+			b = append(b, '}')
+		}
+		b = append(b, ']')
+	}
+	return append(b, '}')
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendString appends s as a JSON string, escaping it the way
+// encoding/json does by default: control characters, quotes,
+// backslashes, <, > and &, U+2028 and U+2029. Invalid UTF-8 is
+// replaced with a literal U+FFFD (older toolchains write the equivalent
+// \ufffd escape instead).
+func appendString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
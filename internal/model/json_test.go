@@ -0,0 +1,144 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+// sameJSON reports whether got matches json.Marshal output want.
+//
+// Bytes must be identical, except that encoding/json's spelling of the
+// U+FFFD replacement for invalid UTF-8 differs across Go versions; for
+// such inputs the decoded values are compared instead.
+func sameJSON(t *testing.T, got, want []byte, validUTF8 bool) bool {
+	t.Helper()
+
+	if string(got) == string(want) {
+		return true
+	}
+	if validUTF8 {
+		return false
+	}
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("unmarshal %s: %v", got, err)
+	}
+	if err := json.Unmarshal(want, &w); err != nil {
+		t.Fatalf("unmarshal %s: %v", want, err)
+	}
+	return reflect.DeepEqual(g, w)
+}
+
+var jsonStrings = []string{
+	"",
+	"o-1",
+	`quote " and backslash \`,
+	"html <script>&amp;</script>",
+	"control \b\f\n\r\t \x00 \x1f \x7f",
+	"unicode caf\u00e9 \u65e5\u672c \U0001f69a",
+	"separators \u2028 \u2029",
+	"invalid \xff\xfe utf-8",
+}
+
+func TestAppendJSONMatchesEncodingJSON(t *testing.T) {
+	t.Parallel()
+
+	type jsonCase struct {
+		v     any
+		valid bool
+	}
+	var cases []jsonCase
+	for _, s := range jsonStrings {
+		valid := utf8.ValidString(s)
+		cases = append(cases,
+			jsonCase{OrderRequest{OrderID: s, Amount: 1200, FailStep: s, DelayMS: map[string]int64{s: 5, "courier": -1, "payment": 150}, Address: s, Zone: s, SLA: s, Channel: s, Region: s, Items: []OrderItem{{Vendor: s, Amount: 500}, {}}}, valid},
+			jsonCase{OrderResponse{Status: "error", OrderID: s, Error: &ErrorPayload{Kind: s, Message: s}}, valid},
+			jsonCase{OrderResponse{Status: "error", OrderID: s, Errors: []ErrorPayload{{Kind: s, Message: s, Step: s}, {Kind: "timeout"}}}, valid},
+			jsonCase{OrderResponse{Status: "error", Error: &ErrorPayload{Kind: s, Fields: []FieldError{{Field: s, Message: s}, {}}}}, valid},
+			jsonCase{OrderResponse{Status: "error", OrderID: s, CancellationCause: &CancellationCause{Reason: s, Step: s, Kind: s}}, valid},
+			jsonCase{OrderResponse{Status: "degraded", OrderID: s, Degradations: []Degradation{{Dependency: s, Action: s}, {}}}, valid},
+			jsonCase{StepResult{Name: s, Status: "ok", DurationMS: 42, Detail: s, Variant: s}, valid},
+			jsonCase{OrderResponse{Status: "partially_completed", OrderID: s, SubOrders: []SubOrder{
+				{OrderID: s, Vendor: s, Amount: 700, Status: "ok", Steps: []StepResult{{Name: s, Status: "ok"}}},
+				{OrderID: s, Vendor: s, Status: "error", Error: &ErrorPayload{Kind: s}},
+			}}, valid},
+		)
+	}
+	for _, v := range []any{
+		OrderRequest{},
+		OrderResponse{Status: StatusError}, // the zero Status does not marshal
+		OrderResponse{Status: "ok", OrderID: "o-1", Steps: []StepResult{
+			{Name: "payment", Status: "ok", DurationMS: 150},
+			{Name: "vendor", Status: "canceled", DurationMS: -1, Detail: "operation not completed"},
+		}},
+		OrderResponse{Status: "ok", Steps: []StepResult{}},
+		ErrorPayload{Kind: "timeout"},
+		OrderResponse{Status: "error", Errors: []ErrorPayload{}},
+		ErrorPayload{Kind: "invalid_order", Fields: []FieldError{}},
+		OrderResponse{Status: "error", CancellationCause: &CancellationCause{Reason: CauseServerTimeout}},
+		OrderResponse{Status: "ok", OrderID: "o-1", LoyaltyPoints: 12},
+		OrderResponse{Status: "ok", Degradations: []Degradation{}},
+		OrderResponse{Status: "ok", OrderID: "o-1", Shared: true},
+		OrderRequest{Items: []OrderItem{}},
+		OrderResponse{Status: "ok", SubOrders: []SubOrder{}},
+	} {
+		cases = append(cases, jsonCase{v, true})
+	}
+
+	for _, c := range cases {
+		want, err := json.Marshal(c.v)
+		if err != nil {
+			t.Fatalf("marshal %#v: %v", c.v, err)
+		}
+		got := c.v.(interface{ AppendJSON([]byte) []byte }).AppendJSON(nil)
+		if !sameJSON(t, got, want, c.valid) {
+			t.Errorf("mismatch for %#v\nwant %s\ngot  %s", c.v, want, got)
+		}
+	}
+}
+
+func FuzzAppendString(f *testing.F) {
+	for _, s := range jsonStrings {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		want, err := json.Marshal(s)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		if got := appendString(nil, s); !sameJSON(t, got, want, utf8.ValidString(s)) {
+			t.Fatalf("mismatch for %q\nwant %s\ngot  %s", s, want, got)
+		}
+	})
+}
+
+var benchResponse = OrderResponse{
+	Status:  "error",
+	OrderID: "o-123",
+	Steps: []StepResult{
+		{Name: "payment", Status: "error", DurationMS: 105, Detail: "payment_declined"},
+		{Name: "vendor", Status: "canceled", DurationMS: 105},
+		{Name: "courier", Status: "canceled", DurationMS: 106},
+	},
+	Error: &ErrorPayload{Kind: "payment_declined", Message: "order failed"},
+}
+
+func BenchmarkEncodeOrderResponse(b *testing.B) {
+	b.Run("encoding_json", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := json.Marshal(benchResponse); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("append_json", func(b *testing.B) {
+		b.ReportAllocs()
+		buf := make([]byte, 0, 512)
+		for b.Loop() {
+			buf = benchResponse.AppendJSON(buf[:0])
+		}
+	})
+}
@@ -1,32 +1,149 @@
 // Package model defines the request and response payloads for the order API.
 package model
 
+import "time"
+
 // OrderRequest is the input payload for processing an order.
 type OrderRequest struct {
 	OrderID  string           `json:"order_id"`
 	Amount   uint64           `json:"amount"`
 	FailStep string           `json:"fail_step,omitempty"` // "payment" | "vendor" | "courier"
 	DelayMS  map[string]int64 `json:"delay_ms,omitempty"`  // per-step delay override in ms
+	Address  string           `json:"address,omitempty"`   // delivery address, geocoded to pick the zone
+	Zone     string           `json:"zone,omitempty"`      // delivery zone selecting the courier pool
+	SLA      string           `json:"sla,omitempty"`       // service class, e.g. "express" | "standard"
+	Channel  string           `json:"channel,omitempty"`   // order source, e.g. "web" | "ios" | "partner-acme"
+	Region   string           `json:"region,omitempty"`    // region the order must be processed in
+	Items    []OrderItem      `json:"items,omitempty"`     // basket lines; several vendors split the order into sub-orders
+}
+
+// StepDelay returns the delay for step: the positive override in
+// DelayMS (milliseconds) if there is one, or else def.
+func (r OrderRequest) StepDelay(step string, def time.Duration) time.Duration {
+	if ms, ok := r.DelayMS[step]; ok && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return def
+}
+
+// OrderItem is one basket line of an order.
+type OrderItem struct {
+	Vendor string `json:"vendor"`
+	Amount uint64 `json:"amount"`
 }
 
 // OrderResponse is the output payload returned after order processing.
 type OrderResponse struct {
-	Status  string        `json:"status"` // "ok" | "error"
+	Status  Status        `json:"status"` // StatusOK | StatusError | PendingStatus of a deferred step
+	OrderID string        `json:"order_id"`
+	Steps   []StepResult  `json:"steps,omitempty"`
+	Error   *ErrorPayload `json:"error,omitempty"`
+
+	// Errors lists every step failure when more than one step failed
+	// (fail-at-end mode). Error then holds the most severe of them.
+	Errors []ErrorPayload `json:"errors,omitempty"`
+
+	// CancellationCause explains why steps were canceled, if any were.
+	CancellationCause *CancellationCause `json:"cancellation_cause,omitempty"`
+
+	// LoyaltyPoints are the points the order earned, when the loyalty
+	// step finished before the response was sent.
+	LoyaltyPoints int64 `json:"loyalty_points,omitempty"`
+
+	// Degradations lists the dependencies that were down when the order
+	// arrived and how the pipeline worked around them.
+	Degradations []Degradation `json:"degradations,omitempty"`
+
+	// Shared reports that the order was processed once for several
+	// concurrent submissions of its order_id, all answered with the
+	// same outcome.
+	Shared bool `json:"shared,omitempty"`
+
+	// SubOrders holds the outcome of each vendor's share of an order
+	// split by vendor, in the order the vendors first appear in its
+	// items. Steps is then empty.
+	SubOrders []SubOrder `json:"sub_orders,omitempty"`
+}
+
+// SubOrder is the outcome of one vendor's share of a split order.
+type SubOrder struct {
 	OrderID string        `json:"order_id"`
+	Vendor  string        `json:"vendor"`
+	Amount  uint64        `json:"amount"`
+	Status  Status        `json:"status"`
 	Steps   []StepResult  `json:"steps,omitempty"`
 	Error   *ErrorPayload `json:"error,omitempty"`
 }
 
+// Degradation actions taken while a dependency is down.
+const (
+	DegradeReject = "reject" // new orders are refused
+	DegradeDefer  = "defer"  // orders are accepted and the step's work postponed
+	DegradeFail   = "fail"   // the step fails fast, failing the order
+)
+
+// Degradation is an action in effect because a dependency is down.
+type Degradation struct {
+	Dependency string `json:"dependency"`
+	Action     string `json:"action"` // DegradeReject | DegradeDefer | DegradeFail
+}
+
+// Reasons a pipeline's steps were canceled.
+const (
+	CauseStepFailed         = "step_failed"         // a sibling step failed
+	CauseClientDisconnected = "client_disconnected" // the client went away
+	CauseServerTimeout      = "server_timeout"      // the request or SLA class deadline fired
+)
+
+// CancellationCause describes why an order's pipeline was canceled.
+type CancellationCause struct {
+	Reason string `json:"reason"`         // CauseStepFailed | CauseClientDisconnected | CauseServerTimeout
+	Step   string `json:"step,omitempty"` // failing step, for CauseStepFailed
+	Kind   string `json:"kind,omitempty"` // its error kind, for CauseStepFailed
+}
+
 // StepResult captures the outcome of a single processing step.
 type StepResult struct {
 	Name       string `json:"name"`
-	Status     string `json:"status"` // "ok" | "error" | "canceled"
+	Status     Status `json:"status"` // StatusOK | StatusError | StatusCanceled | StatusDeferred
 	DurationMS int64  `json:"duration_ms"`
 	Detail     string `json:"detail,omitempty"`
+	Variant    string `json:"variant,omitempty"` // experiment variant the step ran under
 }
 
 // ErrorPayload describes an error in the response.
 type ErrorPayload struct {
-	Kind    string `json:"kind"` // "payment_declined", "timeout", etc.
-	Message string `json:"message,omitempty"`
+	Kind    string       `json:"kind"` // "payment_declined", "timeout", etc.
+	Message string       `json:"message,omitempty"`
+	Step    string       `json:"step,omitempty"`   // failing step, in Errors entries
+	Fields  []FieldError `json:"fields,omitempty"` // invalid request fields, for kind "invalid_order"
+}
+
+// FieldError describes why one request field is invalid.
+type FieldError struct {
+	Field   string `json:"field"` // JSON name, e.g. "amount"
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem details body, returned instead of an
+// OrderResponse for failed requests that accept application/problem+json.
+// Fields after Instance are extension members.
+type Problem struct {
+	Type     string `json:"type"`  // URI identifying the error kind
+	Title    string `json:"title"` // HTTP status text
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"` // request URI
+
+	Kind    string         `json:"kind"`
+	Step    string         `json:"step,omitempty"` // step that produced the error, if any
+	OrderID string         `json:"order_id,omitempty"`
+	Steps   []StepResult   `json:"steps,omitempty"`
+	Errors  []ErrorPayload `json:"errors,omitempty"` // as in OrderResponse.Errors
+	Fields  []FieldError   `json:"fields,omitempty"` // as in ErrorPayload.Fields
+
+	CancellationCause *CancellationCause `json:"cancellation_cause,omitempty"`
+	Degradations      []Degradation      `json:"degradations,omitempty"`
+	Shared            bool               `json:"shared,omitempty"` // as in OrderResponse.Shared
+	SubOrders         []SubOrder         `json:"sub_orders,omitempty"`
 }
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrderRequest_StepDelay(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		delayMS map[string]int64
+		want    time.Duration
+	}{
+		{name: "nil_map", delayMS: nil, want: 150 * time.Millisecond},
+		{name: "other_step", delayMS: map[string]int64{"vendor": 10}, want: 150 * time.Millisecond},
+		{name: "override", delayMS: map[string]int64{"payment": 10}, want: 10 * time.Millisecond},
+		{name: "zero", delayMS: map[string]int64{"payment": 0}, want: 150 * time.Millisecond},
+		{name: "negative", delayMS: map[string]int64{"payment": -5}, want: 150 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := (OrderRequest{DelayMS: tt.delayMS}).StepDelay("payment", 150*time.Millisecond); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
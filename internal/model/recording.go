@@ -0,0 +1,13 @@
+package model
+
+// Recording captures one processed order for offline replay: the full
+// request, the server configuration in effect and the step timings.
+type Recording struct {
+	Time       string            `json:"time"` // RFC 3339 with nanoseconds, set when written
+	Request    OrderRequest      `json:"request"`
+	Config     map[string]string `json:"config"` // server flag values by name
+	Status     Status            `json:"status"`
+	ErrorKind  string            `json:"error_kind,omitempty"`
+	DurationMS int64             `json:"duration_ms"`
+	Steps      []StepResult      `json:"steps"`
+}
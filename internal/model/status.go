@@ -0,0 +1,99 @@
+package model
+
+import "fmt"
+
+// Status is the machine-readable outcome of an order or a step.
+//
+// It marshals as a JSON string and rejects unknown spellings in both
+// directions, so every transport and client sees the same values.
+type Status string
+
+// Statuses reported in OrderResponse and StepResult.
+const (
+	StatusOK       Status = "ok"       // completed successfully
+	StatusError    Status = "error"    // failed with a domain or internal error
+	StatusCanceled Status = "canceled" // stopped by cancellation or deadline before completing
+	StatusDegraded Status = "degraded" // completed, but a non-critical part failed
+	StatusDeferred Status = "deferred" // step work postponed by a fallback, to complete later
+
+	// StatusClientDisconnected is an order abandoned because the client
+	// went away before it completed.
+	StatusClientDisconnected Status = "client_disconnected"
+
+	// StatusAcceptedPendingCourier is an accepted order whose courier
+	// assignment was deferred.
+	StatusAcceptedPendingCourier Status = "accepted_pending_courier"
+
+	// StatusPartiallyCompleted is a split order some of whose
+	// sub-orders completed and some failed.
+	StatusPartiallyCompleted Status = "partially_completed"
+)
+
+// Statuses returns every defined Status, in declaration order.
+func Statuses() []Status {
+	return []Status{StatusOK, StatusError, StatusCanceled, StatusDegraded, StatusDeferred, StatusAcceptedPendingCourier, StatusClientDisconnected, StatusPartiallyCompleted}
+}
+
+// ParseStatus returns the Status spelled s.
+func ParseStatus(s string) (Status, error) {
+	if st := Status(s); st.Valid() {
+		return st, nil
+	}
+	return "", fmt.Errorf("model: unknown status %q", s)
+}
+
+// Valid reports whether s is one of the defined statuses.
+func (s Status) Valid() bool {
+	switch s {
+	case StatusOK, StatusError, StatusCanceled, StatusDegraded, StatusDeferred, StatusAcceptedPendingCourier, StatusClientDisconnected, StatusPartiallyCompleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// Failed reports whether s means the work did not complete.
+//
+// It panics on an undefined status, so a status added without updating
+// this switch is caught by tests rather than misreported.
+func (s Status) Failed() bool {
+	switch s {
+	case StatusOK, StatusDegraded, StatusDeferred, StatusAcceptedPendingCourier, StatusPartiallyCompleted:
+		return false
+	case StatusError, StatusCanceled, StatusClientDisconnected:
+		return true
+	default:
+		panic(fmt.Sprintf("model.Status.Failed: unknown status %q", string(s)))
+	}
+}
+
+// PendingStatus returns the order status for an order accepted with the
+// named step's work deferred: StatusAcceptedPendingCourier for the
+// courier step and StatusDegraded for any other.
+func PendingStatus(step string) Status {
+	if step == "courier" {
+		return StatusAcceptedPendingCourier
+	}
+	return StatusDegraded
+}
+
+func (s Status) String() string { return string(s) }
+
+// MarshalText implements encoding.TextMarshaler. It fails for undefined
+// statuses, including the zero value.
+func (s Status) MarshalText() ([]byte, error) {
+	if !s.Valid() {
+		return nil, fmt.Errorf("model: unknown status %q", string(s))
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Status) UnmarshalText(text []byte) error {
+	st, err := ParseStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*s = st
+	return nil
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    Status
+		wantErr bool
+	}{
+		{in: "ok", want: StatusOK},
+		{in: "error", want: StatusError},
+		{in: "canceled", want: StatusCanceled},
+		{in: "degraded", want: StatusDegraded},
+		{in: "deferred", want: StatusDeferred},
+		{in: "accepted_pending_courier", want: StatusAcceptedPendingCourier},
+		{in: "client_disconnected", want: StatusClientDisconnected},
+		{in: "", wantErr: true},
+		{in: "OK", wantErr: true},
+		{in: "cancelled", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseStatus(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// Every defined status is valid, round-trips through JSON and is
+// handled by the exhaustive switches.
+func TestStatuses(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range Statuses() {
+		if !s.Valid() {
+			t.Fatalf("expected %q to be valid", s)
+		}
+		_ = s.Failed() // panics if the switch is not exhaustive
+
+		b, err := json.Marshal(s)
+		if err != nil {
+			t.Fatalf("marshal %q: %v", s, err)
+		}
+		if want := `"` + string(s) + `"`; string(b) != want {
+			t.Fatalf("expected %s, got %s", want, b)
+		}
+		var got Status
+		if err := json.Unmarshal(b, &got); err != nil || got != s {
+			t.Fatalf("expected %q, got %q (err %v)", s, got, err)
+		}
+	}
+}
+
+func TestStatusFailed(t *testing.T) {
+	t.Parallel()
+
+	want := map[Status]bool{
+		StatusOK:       false,
+		StatusDegraded: false,
+		StatusError:    true,
+		StatusCanceled: true,
+	}
+	for s, failed := range want {
+		if got := s.Failed(); got != failed {
+			t.Fatalf("%q: expected %v, got %v", s, failed, got)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected panic for unknown status")
+		}
+	}()
+	Status("bogus").Failed()
+}
+
+func TestStatusJSONRejectsUnknown(t *testing.T) {
+	t.Parallel()
+
+	if _, err := json.Marshal(StepResult{Name: "payment"}); err == nil {
+		t.Fatal("expected marshal error for zero status")
+	}
+
+	var r StepResult
+	if err := json.Unmarshal([]byte(`{"name":"payment","status":"cancelled"}`), &r); err == nil {
+		t.Fatalf("expected unmarshal error, got %+v", r)
+	}
+}
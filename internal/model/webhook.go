@@ -0,0 +1,55 @@
+package model
+
+// Webhook event types.
+const (
+	EventOrderCompleted = "order.completed" // the order succeeded (or was accepted with deferred work)
+	EventOrderFailed    = "order.failed"    // the order failed
+	EventStepFailed     = "step.failed"     // one step failed; sent once per failed step
+)
+
+// WebhookEvent is the body POSTed to subscribed endpoints.
+type WebhookEvent struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"` // EventOrderCompleted | EventOrderFailed | EventStepFailed
+	Time      string `json:"time"` // RFC 3339
+	OrderID   string `json:"order_id"`
+	Status    Status `json:"status"`               // the order's status, or the step's for EventStepFailed
+	Step      string `json:"step,omitempty"`       // failed step, for EventStepFailed
+	ErrorKind string `json:"error_kind,omitempty"` // why the order or step failed
+}
+
+// WebhookSubscriptionRequest is the input payload registering an endpoint.
+type WebhookSubscriptionRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"` // signs every delivery; never returned
+}
+
+// WebhookSubscription is a registered endpoint.
+type WebhookSubscription struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	CreatedAt string   `json:"created_at"` // RFC 3339
+	Delivered int64    `json:"delivered"`
+	Failed    int64    `json:"failed"` // deliveries given up after every attempt
+}
+
+// Webhook delivery outcomes.
+const (
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery records one event's delivery to one subscription.
+type WebhookDelivery struct {
+	Subscription   string `json:"subscription"`
+	EventID        string `json:"event_id"`
+	Event          string `json:"event"`
+	OrderID        string `json:"order_id"`
+	Outcome        string `json:"outcome"` // DeliveryDelivered | DeliveryFailed
+	Attempts       int    `json:"attempts"`
+	ResponseStatus int    `json:"response_status,omitempty"` // of the last attempt
+	LastError      string `json:"last_error,omitempty"`
+	Time           string `json:"time"` // RFC 3339, when the outcome was settled
+}
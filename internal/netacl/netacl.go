@@ -0,0 +1,194 @@
+// Package netacl resolves the client address of a request and filters
+// requests by it.
+//
+// Behind a load balancer or reverse proxy, RemoteAddr is the proxy's
+// address. When the peer is a trusted proxy, the client is instead taken
+// from X-Forwarded-For: the header is walked from the nearest hop
+// outwards, skipping trusted proxies, and the first untrusted address is
+// the client. Entries further out were written by the client itself and
+// are ignored, so a client cannot spoof its address by sending the
+// header.
+package netacl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// Config lists the networks of an ACL.
+type Config struct {
+	Allow          []netip.Prefix // if non-empty, only clients in these networks are served
+	Deny           []netip.Prefix // clients refused, even when allowed
+	TrustedProxies []netip.Prefix // peers whose X-Forwarded-For is believed
+}
+
+// ACL resolves client addresses and enforces the allow and deny lists.
+// It is safe for concurrent use.
+type ACL struct {
+	cfg    Config
+	denied atomic.Int64
+}
+
+// New returns an ACL for cfg.
+func New(cfg Config) *ACL {
+	return &ACL{cfg: cfg}
+}
+
+// ParsePrefixes parses a comma-separated list of CIDR networks and
+// single addresses, e.g. "10.0.0.0/8, 192.168.1.7, ::1". An empty spec
+// yields no prefixes.
+func ParsePrefixes(spec string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for item := range strings.SplitSeq(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			p, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("netacl: %w", err)
+			}
+			out = append(out, p.Masked())
+			continue
+		}
+		a, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("netacl: %w", err)
+		}
+		a = a.Unmap()
+		out = append(out, netip.PrefixFrom(a, a.BitLen()))
+	}
+	return out, nil
+}
+
+type clientKey struct{}
+
+// NewContext returns a copy of ctx carrying the client address a.
+func NewContext(ctx context.Context, a netip.Addr) context.Context {
+	return context.WithValue(ctx, clientKey{}, a)
+}
+
+// FromContext returns the client address carried by ctx, if any.
+func FromContext(ctx context.Context) (netip.Addr, bool) {
+	a, ok := ctx.Value(clientKey{}).(netip.Addr)
+	return a, ok
+}
+
+// ClientIP returns the client address of r. It is the peer address
+// unless the peer is a trusted proxy, in which case it comes from
+// X-Forwarded-For as described in the package documentation; when every
+// hop is trusted, the outermost is the client. An unparsable entry ends
+// the walk at the hop before it. ok is false if RemoteAddr holds no
+// address.
+func (a *ACL) ClientIP(r *http.Request) (ip netip.Addr, ok bool) {
+	ip, ok = parseAddr(r.RemoteAddr)
+	if !ok || !a.trusted(ip) {
+		return ip, ok
+	}
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		ip = hop.Unmap()
+		if !a.trusted(ip) {
+			break
+		}
+	}
+	return ip, true
+}
+
+// Allowed reports whether ip may be served: it is in no denied network
+// and, if there is an allow list, in an allowed one.
+func (a *ACL) Allowed(ip netip.Addr) bool {
+	if Contains(a.cfg.Deny, ip) {
+		return false
+	}
+	return len(a.cfg.Allow) == 0 || Contains(a.cfg.Allow, ip)
+}
+
+// Denied returns how many requests Filter has refused.
+func (a *ACL) Denied() int64 { return a.denied.Load() }
+
+// RealIP wraps next so that it sees the client address: it is stored in
+// the request context and, when it differs from the peer, replaces
+// RemoteAddr, without a port, for the access log and anything else
+// reading it. It must be the outermost middleware that reads RemoteAddr.
+func (a *ACL) RealIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, ok := a.ClientIP(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r = r.WithContext(NewContext(r.Context(), ip))
+		if peer, _ := parseAddr(r.RemoteAddr); peer != ip {
+			r.RemoteAddr = ip.String()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Filter wraps next so that requests from clients not Allowed get 403
+// with error kind forbidden_address, without reaching next. It takes
+// the client address stored by RealIP, falling back to ClientIP. Peers
+// without an IP address, such as Unix socket clients, are served unless
+// there is an allow list.
+func (a *ACL) Filter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, ok := FromContext(r.Context())
+		if !ok {
+			ip, ok = a.ClientIP(r)
+		}
+		if ok && a.Allowed(ip) || !ok && len(a.cfg.Allow) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		a.denied.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(model.OrderResponse{
+			Status: model.StatusError,
+			Error:  &model.ErrorPayload{Kind: "forbidden_address", Message: "client address not allowed"},
+		})
+	})
+}
+
+func (a *ACL) trusted(ip netip.Addr) bool {
+	return Contains(a.cfg.TrustedProxies, ip)
+}
+
+// parseAddr parses the host of a host:port or bare address.
+func parseAddr(addr string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.Unmap(), true
+}
+
+// Contains reports whether ip is in any of prefixes.
+func Contains(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
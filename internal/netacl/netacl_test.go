@@ -0,0 +1,197 @@
+package netacl
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func mustPrefixes(t *testing.T, spec string) []netip.Prefix {
+	t.Helper()
+	p, err := ParsePrefixes(spec)
+	if err != nil {
+		t.Fatalf("ParsePrefixes(%q): %v", spec, err)
+	}
+	return p
+}
+
+func TestParsePrefixes(t *testing.T) {
+	t.Parallel()
+
+	got := mustPrefixes(t, " 10.1.2.3/8, 192.168.1.7,::1 ,::ffff:10.0.0.1")
+	want := []string{"10.0.0.0/8", "192.168.1.7/32", "::1/128", "10.0.0.1/32"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if p := mustPrefixes(t, ""); len(p) != 0 {
+		t.Fatalf("expected no prefixes, got %v", p)
+	}
+	for _, spec := range []string{"10.0.0.0/33", "example.com", "10.0.0.1/8/2"} {
+		if _, err := ParsePrefixes(spec); err == nil {
+			t.Fatalf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	t.Parallel()
+
+	acl := New(Config{TrustedProxies: mustPrefixes(t, "10.0.0.0/8")})
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{name: "direct", remote: "203.0.113.5:4000", want: "203.0.113.5"},
+		{name: "untrusted_peer_ignores_header", remote: "203.0.113.5:4000", xff: []string{"198.51.100.1"}, want: "203.0.113.5"},
+		{name: "trusted_peer_no_header", remote: "10.0.0.2:4000", want: "10.0.0.2"},
+		{name: "one_proxy", remote: "10.0.0.2:4000", xff: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "proxy_chain", remote: "10.0.0.2:4000", xff: []string{"198.51.100.1, 10.0.0.9"}, want: "198.51.100.1"},
+		{name: "spoofed_prefix", remote: "10.0.0.2:4000", xff: []string{"1.2.3.4, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "split_headers", remote: "10.0.0.2:4000", xff: []string{"198.51.100.1", "10.0.0.9"}, want: "198.51.100.1"},
+		{name: "all_trusted", remote: "10.0.0.2:4000", xff: []string{"10.0.0.7, 10.0.0.9"}, want: "10.0.0.7"},
+		{name: "garbage_stops_walk", remote: "10.0.0.2:4000", xff: []string{"198.51.100.1, bogus, 10.0.0.9"}, want: "10.0.0.9"},
+		{name: "mapped_v4", remote: "[::ffff:10.0.0.2]:4000", xff: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "ipv6", remote: "[2001:db8::1]:4000", want: "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			got, ok := acl.ClientIP(r)
+			if !ok || got.String() != tt.want {
+				t.Fatalf("expected %s, got %v (ok %v)", tt.want, got, ok)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		allow, deny string
+		ip          string
+		want        bool
+	}{
+		{name: "no_lists", ip: "203.0.113.5", want: true},
+		{name: "in_allow", allow: "203.0.113.0/24", ip: "203.0.113.5", want: true},
+		{name: "outside_allow", allow: "203.0.113.0/24", ip: "198.51.100.1", want: false},
+		{name: "denied", deny: "203.0.113.5", ip: "203.0.113.5", want: false},
+		{name: "deny_beats_allow", allow: "203.0.113.0/24", deny: "203.0.113.5", ip: "203.0.113.5", want: false},
+		{name: "allowed_next_to_denied", allow: "203.0.113.0/24", deny: "203.0.113.5", ip: "203.0.113.6", want: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			acl := New(Config{Allow: mustPrefixes(t, tt.allow), Deny: mustPrefixes(t, tt.deny)})
+			if got := acl.Allowed(netip.MustParseAddr(tt.ip)); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	acl := New(Config{
+		Deny:           mustPrefixes(t, "198.51.100.0/24"),
+		TrustedProxies: mustPrefixes(t, "10.0.0.0/8"),
+	})
+	var seen string
+	var seenCtx netip.Addr
+	h := acl.RealIP(acl.Filter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.RemoteAddr
+		seenCtx, _ = FromContext(r.Context())
+	})))
+
+	// A proxied client: RemoteAddr is rewritten to it.
+	r := httptest.NewRequest(http.MethodGet, "/order", nil)
+	r.RemoteAddr = "10.0.0.2:4000"
+	r.Header.Set("X-Forwarded-For", "203.0.113.5")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || seen != "203.0.113.5" || seenCtx.String() != "203.0.113.5" {
+		t.Fatalf("expected the client address, got %d %q %v", w.Code, seen, seenCtx)
+	}
+
+	// A direct client keeps its RemoteAddr.
+	r = httptest.NewRequest(http.MethodGet, "/order", nil)
+	r.RemoteAddr = "203.0.113.5:4000"
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if seen != "203.0.113.5:4000" {
+		t.Fatalf("expected RemoteAddr unchanged, got %q", seen)
+	}
+
+	// A denied client behind the proxy.
+	r = httptest.NewRequest(http.MethodGet, "/order", nil)
+	r.RemoteAddr = "10.0.0.2:4000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	var resp model.OrderResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if w.Code != http.StatusForbidden || resp.Error == nil || resp.Error.Kind != "forbidden_address" {
+		t.Fatalf("expected 403 forbidden_address, got %d %+v", w.Code, resp.Error)
+	}
+	if acl.Denied() != 1 {
+		t.Fatalf("expected 1 denied, got %d", acl.Denied())
+	}
+}
+
+// Unix socket peers have no IP address: they are served unless an allow
+// list is configured.
+func TestFilter_NoPeerAddress(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		wantCode int
+	}{
+		{name: "no_acl", wantCode: http.StatusOK},
+		{name: "deny_list", cfg: Config{Deny: mustPrefixes(t, "198.51.100.0/24")}, wantCode: http.StatusOK},
+		{name: "allow_list", cfg: Config{Allow: mustPrefixes(t, "10.0.0.0/8")}, wantCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			acl := New(tt.cfg)
+			h := acl.RealIP(acl.Filter(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})))
+			for _, peer := range []string{"", "@"} {
+				r := httptest.NewRequest(http.MethodGet, "/order", nil)
+				r.RemoteAddr = peer
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				if w.Code != tt.wantCode {
+					t.Fatalf("peer %q: expected %d, got %d", peer, tt.wantCode, w.Code)
+				}
+			}
+		})
+	}
+}
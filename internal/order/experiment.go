@@ -0,0 +1,74 @@
+package order
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// Experiment splits orders across variants of one step's behavior.
+//
+// Each order is assigned a variant by hashing the step name with the
+// order ID, so assignment is stable per order and independent across
+// experiments. Variants are equally weighted.
+type Experiment struct {
+	Step     string   // name of the step being varied
+	Variants []string // e.g. "nearest", "least_loaded"
+}
+
+// assign returns the variant for orderID.
+func (e Experiment) assign(orderID string) string {
+	h := fnv.New32a()
+	h.Write([]byte(e.Step + "/" + orderID))
+	return e.Variants[h.Sum32()%uint32(len(e.Variants))]
+}
+
+// Experiments runs the given experiments. Steps read their variant with
+// Variant, and the variant is recorded in StepResult.Variant.
+//
+// New panics if an experiment names an unknown step, has no variants,
+// or shares its step with another experiment.
+func Experiments(exps ...Experiment) Option {
+	return func(s *Service) { s.experiments = append(s.experiments, exps...) }
+}
+
+type variantKey struct{}
+
+// Variant returns the experiment variant the calling step runs under,
+// or "" if its step has no experiment.
+func Variant(ctx context.Context) string {
+	v, _ := ctx.Value(variantKey{}).(string)
+	return v
+}
+
+// resolveExperiments indexes s.experiments by step position.
+func (s *Service) resolveExperiments() {
+	if len(s.experiments) == 0 {
+		return
+	}
+	index := make(map[string]int, len(s.steps))
+	for i, step := range s.steps {
+		index[step.Name] = i
+	}
+	s.byStep = make([]*Experiment, len(s.steps))
+	for i := range s.experiments {
+		e := &s.experiments[i]
+		pos, ok := index[e.Step]
+		switch {
+		case !ok:
+			panic("order.New: experiment for unknown step " + e.Step)
+		case len(e.Variants) == 0:
+			panic("order.New: experiment without variants for step " + e.Step)
+		case s.byStep[pos] != nil:
+			panic("order.New: duplicate experiment for step " + e.Step)
+		}
+		s.byStep[pos] = e
+	}
+}
+
+// variant returns the variant of step i for orderID, or "".
+func (s *Service) variant(i int, orderID string) string {
+	if s.byStep == nil || s.byStep[i] == nil {
+		return ""
+	}
+	return s.byStep[i].assign(orderID)
+}
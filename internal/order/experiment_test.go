@@ -0,0 +1,88 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestExperiments_InvalidPanics(t *testing.T) {
+	t.Parallel()
+
+	steps := []Step{{Name: "courier", Run: func(context.Context, model.OrderRequest) error { return nil }}}
+	tests := []struct {
+		name string
+		exps []Experiment
+	}{
+		{name: "unknown_step", exps: []Experiment{{Step: "vendor", Variants: []string{"a"}}}},
+		{name: "no_variants", exps: []Experiment{{Step: "courier"}}},
+		{name: "duplicate", exps: []Experiment{
+			{Step: "courier", Variants: []string{"a"}},
+			{Step: "courier", Variants: []string{"b"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			New(steps, Experiments(tt.exps...))
+		})
+	}
+}
+
+// Steps see their assigned variant, the variant is recorded in the
+// result, and assignment is stable per order and roughly even.
+func TestExperiments_Assignment(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	seen := map[string]string{} // order ID -> variant seen by the step
+	steps := []Step{
+		{Name: "payment", Run: func(ctx context.Context, _ model.OrderRequest) error {
+			if v := Variant(ctx); v != "" {
+				return fmt.Errorf("unexpected variant %q", v)
+			}
+			return nil
+		}},
+		{Name: "courier", Run: func(ctx context.Context, req model.OrderRequest) error {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[req.OrderID] = Variant(ctx)
+			return nil
+		}},
+	}
+	svc := New(steps, Experiments(Experiment{Step: "courier", Variants: []string{"nearest", "least_loaded"}}))
+
+	counts := map[string]int{}
+	for i := range 2000 {
+		id := fmt.Sprintf("o-%d", i)
+		results, err := svc.Process(context.Background(), model.OrderRequest{OrderID: id})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if results[0].Variant != "" {
+			t.Fatalf("expected no variant on payment, got %q", results[0].Variant)
+		}
+		v := results[1].Variant
+		if v != seen[id] {
+			t.Fatalf("order %s: result variant %q, step saw %q", id, v, seen[id])
+		}
+		again, _ := svc.Process(context.Background(), model.OrderRequest{OrderID: id})
+		if again[1].Variant != v {
+			t.Fatalf("order %s: variant changed from %q to %q", id, v, again[1].Variant)
+		}
+		counts[v]++
+	}
+	if len(counts) != 2 || counts["nearest"] < 800 || counts["least_loaded"] < 800 {
+		t.Fatalf("expected an even split, got %v", counts)
+	}
+}
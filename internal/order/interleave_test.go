@@ -0,0 +1,84 @@
+//go:build syncpoint
+
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/pool"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/syncpoint"
+)
+
+// courierStep takes a slot of p for the step's duration and reports the
+// context's state right after getting it.
+func courierStep(p *pool.Pool, sawErr chan<- error) Step {
+	return Step{Name: "courier", Run: func(ctx context.Context, _ model.OrderRequest) error {
+		if err := p.Acquire(ctx); err != nil {
+			return err
+		}
+		defer p.Release()
+		sawErr <- ctx.Err()
+		return ctx.Err()
+	}}
+}
+
+var declined = Step{Name: "payment", Run: func(context.Context, model.OrderRequest) error {
+	return testKindErr{kind: "payment_declined"}
+}}
+
+// The courier gets its slot, then payment fails before the courier goes
+// on: the courier must see the cancellation and give the slot back.
+func TestProcess_CourierAcquiresAsPaymentFails(t *testing.T) {
+	t.Cleanup(syncpoint.Reset)
+
+	p := pool.New(1)
+	acquired := syncpoint.PauseAt(syncpoint.PoolAcquired, "")
+	syncpoint.Set(syncpoint.OrderStepStart, "payment", func() { <-acquired.Reached() })
+	syncpoint.Set(syncpoint.OrderStepFailed, "payment", acquired.Release)
+
+	saw := make(chan error, 1)
+	svc := New([]Step{declined, courierStep(p, saw)})
+	out, err := svc.Process(context.Background(), model.OrderRequest{OrderID: "o-1"})
+
+	var se *StepError
+	if !errors.As(err, &se) || se.Step != "payment" {
+		t.Fatalf("expected the payment error, got %v", err)
+	}
+	if got := <-saw; !errors.Is(got, context.Canceled) {
+		t.Fatalf("expected the courier to see the cancellation after acquiring, got %v", got)
+	}
+	if out[1].Status != model.StatusCanceled || p.InUse() != 0 {
+		t.Fatalf("expected the courier canceled with its slot released, got %+v, in_use=%d", out[1], p.InUse())
+	}
+}
+
+// Payment fails while the courier waits for a full pool: the wait is
+// abandoned and the courier never runs with a slot.
+func TestProcess_PaymentFailsWhileCourierWaits(t *testing.T) {
+	t.Cleanup(syncpoint.Reset)
+
+	p := pool.New(1)
+	if err := p.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Release()
+	waiting := syncpoint.Signal(syncpoint.PoolAcquireWait, "")
+	syncpoint.Set(syncpoint.OrderStepStart, "payment", func() { <-waiting })
+
+	saw := make(chan error, 1)
+	svc := New([]Step{declined, courierStep(p, saw)})
+	out, err := svc.Process(context.Background(), model.OrderRequest{OrderID: "o-1"})
+
+	if err == nil || out[0].Status != model.StatusError || out[1].Status != model.StatusCanceled {
+		t.Fatalf("expected payment error and courier canceled, got %v, %+v", err, out)
+	}
+	if len(saw) != 0 {
+		t.Fatal("expected the courier never to get a slot")
+	}
+	if tel := p.Telemetry(); tel.Abandoned != 1 {
+		t.Fatalf("expected 1 abandoned wait, got %+v", tel)
+	}
+}
@@ -6,6 +6,14 @@
 // Steps are executed in parallel. If any step returns a non-nil error,
 // the shared context is canceled and remaining steps are expected to
 // stop promptly. The first non-nil error is returned to the caller.
+// In fail-at-end mode every step runs to completion instead and all
+// step errors are returned together.
+//
+// A step that returns ErrDeferred has postponed its work: it is reported
+// as model.StatusDeferred and does not fail the order.
+//
+// Long-running steps can send heartbeats with progress.Heartbeat. Under
+// StepIdleTimeout, a step going that long without one is stopped.
 //
 // The result slice always preserves step registration order.
 package order
@@ -13,11 +21,18 @@ package order
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/ctxerr"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/memo"
 	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/progress"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/simulation"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/syncpoint"
 )
 
 // Steps contract for the order pipeline.
@@ -28,19 +43,147 @@ type Step struct {
 
 // Service orchestrates the order workflow.
 type Service struct {
-	steps []Step
+	steps     []Step
+	failAtEnd bool
+	results   sync.Pool // *[]model.StepResult, len(steps) each
+
+	lateGrace  time.Duration // > 0 lets steps outlive the order deadline
+	lateReport func(orderID string, result model.StepResult)
+
+	tail *Tail // started after successful orders; nil without tail steps
+
+	memoize  bool // each order gets a memo table; see Memoize
+	simulate bool // each order is marked for simulation; see Simulate
+
+	stepLimit int // > 0 caps the steps running at once per order
+	limit     stepLimitStats
+
+	stepIdle time.Duration // > 0 stops steps going this long without a heartbeat
+
+	experiments []Experiment
+	byStep      []*Experiment // indexed like steps; nil without experiments
+}
+
+// Option configures a Service.
+type Option func(*Service)
+
+// FailAtEnd makes Process run every step to completion instead of
+// canceling siblings on the first error. All step errors are returned,
+// joined in registration order, each wrapped in a *StepError.
+func FailAtEnd() Option {
+	return func(s *Service) { s.failAtEnd = true }
+}
+
+// FinishLate lets steps still running when the order's deadline fires
+// finish in the background, for up to grace longer. Process returns at
+// the deadline with those steps reported as canceled, and report is
+// called with each one's real outcome once it finishes. Cancellation
+// for any other reason, such as a failing sibling or a client
+// disconnect, still stops the steps at once.
+//
+// A non-positive grace or nil report leaves FinishLate off.
+func FinishLate(grace time.Duration, report func(orderID string, result model.StepResult)) Option {
+	return func(s *Service) {
+		if grace > 0 && report != nil {
+			s.lateGrace, s.lateReport = grace, report
+		}
+	}
+}
+
+// Memoize gives each order its own memo table (see package memo), so
+// that its steps, tail steps included, share the result of identical
+// keyed calls such as memoized geocoding.
+func Memoize() Option {
+	return func(s *Service) { s.memoize = true }
+}
+
+// Simulate marks each order's context for simulation (see package
+// simulation), so that its steps skip delays of a millisecond or less
+// instead of arming a timer for them.
+func Simulate() Option {
+	return func(s *Service) { s.simulate = true }
+}
+
+// MaxConcurrentSteps caps the steps of one order running at once at n,
+// for pipelines with many steps, such as fanning out to many vendors,
+// so that an order does not start a goroutine per step at once. Steps
+// beyond the limit wait for a slot in registration order. A waiting step
+// whose context ends before it gets a slot does not run and is reported
+// canceled. StepLimit reports the counters.
+//
+// A limit cannot be combined with FinishLate, since steps queued at the
+// deadline would hold Process past it; New panics if both are set. A
+// non-positive n leaves the limit off.
+func MaxConcurrentSteps(n int) Option {
+	return func(s *Service) { s.stepLimit = max(n, 0) }
+}
+
+// StepIdleTimeout stops a step that runs for d without sending a
+// heartbeat (see progress.Heartbeat) or returning: its context is
+// canceled and it fails with ErrStepStalled. Each heartbeat restarts the
+// timeout, so a step reporting progress may run as long as the order's
+// deadline allows. A non-positive d leaves the timeout off.
+func StepIdleTimeout(d time.Duration) Option {
+	return func(s *Service) { s.stepIdle = max(d, 0) }
+}
+
+// stepLimitStats counts the steps started under MaxConcurrentSteps.
+type stepLimitStats struct {
+	started atomic.Int64
+	queued  atomic.Int64 // started after waiting for a slot
+	waiting atomic.Int64 // waiting for a slot now
+	waitNS  atomic.Int64 // total time waited
 }
 
 // New returns a Service that executes the provided steps concurrently.
 //
 // It panics if no steps are provided.
-func New(steps []Step) *Service {
+func New(steps []Step, opts ...Option) *Service {
 	if len(steps) == 0 {
 		panic("order.New: no steps") // caught a programmer error
 	}
-	return &Service{steps: steps}
+	s := &Service{steps: steps}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.stepLimit > 0 && s.lateGrace > 0 {
+		panic("order.New: MaxConcurrentSteps cannot be combined with FinishLate")
+	}
+	s.resolveExperiments()
+	return s
 }
 
+// StepError records which step produced an error.
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string { return e.Step + ": " + e.Err.Error() }
+
+// Unwrap returns the step's error.
+func (e *StepError) Unwrap() error { return e.Err }
+
+// StepName returns the name of the failing step.
+func (e *StepError) StepName() string { return e.Step }
+
+type deferredError struct{}
+
+func (deferredError) Error() string { return "deferred" }
+func (deferredError) Kind() string  { return "deferred" }
+
+// ErrDeferred is returned, possibly wrapped, by a step whose work was
+// postponed by a fallback, to be completed later.
+var ErrDeferred = deferredError{}
+
+type stalledError struct{}
+
+func (stalledError) Error() string { return "step stalled without a heartbeat" }
+func (stalledError) Kind() string  { return "step_stalled" }
+
+// ErrStepStalled is the error of a step stopped by StepIdleTimeout.
+var ErrStepStalled = stalledError{}
+
 // kinder is satisfied by errors that carry a classification kind.
 type kinder interface {
 	Kind() string
@@ -50,31 +193,101 @@ type kinder interface {
 //
 // Each step receives the same context. If any step returns a non-nil error,
 // the shared context is canceled and remaining steps are expected to abort
-// promptly. The first non-nil error is returned.
+// promptly. The first step's error is returned wrapped in a *StepError,
+// which is also the context's cancellation cause (see context.Cause).
+// In fail-at-end mode siblings are not canceled and the step errors are
+// joined instead. Under FinishLate, steps running at the deadline are
+// reported as canceled while they finish in the background. A successful
+// order starts the steps of TailSteps, if any, and waits for them for up
+// to their SyncWait.
 //
 // The returned slice contains one StepResult per registered step,
-// in registration order.
+// in registration order. It may come from an internal pool; callers
+// that are done with it can hand it back with Release.
 func (s *Service) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
-	g, ctx := errgroup.WithContext(ctx)
+	if s.memoize {
+		ctx = memo.NewContext(ctx)
+	}
+	if s.simulate {
+		ctx = simulation.NewContext(ctx)
+	}
+	out, err := s.process(ctx, req)
+	if err == nil && s.tail != nil {
+		s.tail.waitSync(ctx, s.tail.Start(ctx, req))
+	}
+	return out, err
+}
+
+func (s *Service) process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	var late *lateSteps
+	if s.lateGrace > 0 {
+		late = s.detachDeadline(ctx, len(s.steps))
+		ctx = late.ctx
+	}
+
+	var g errgroup.Group
+	if s.stepLimit > 0 {
+		g.SetLimit(s.stepLimit)
+	}
+	var errs []error
+	cancel := func(error) {}
+	switch {
+	case s.failAtEnd:
+		errs = make([]error, len(s.steps))
+	case late != nil:
+		cancel = late.cancel // released once the last step returns
+	default:
+		// The first failing step cancels its siblings, with a
+		// *StepError naming it as the cause.
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+	}
 
-	out := make([]model.StepResult, len(s.steps))
+	// Steps report through a collector, which a caller watching the
+	// order may have attached to ctx.
+	out := s.newResults()
 	for i, step := range s.steps {
-		out[i] = model.StepResult{Name: step.Name, Status: "canceled", Detail: "operation not completed"} // pre-fill with default value
+		out[i] = model.StepResult{Name: step.Name, Status: model.StatusCanceled, Detail: "operation not completed", Variant: s.variant(i, req.OrderID)} // pre-fill with default value
+	}
+	results := progress.FromContext(ctx)
+	if results == nil || !results.Attach(out) {
+		results = new(progress.Collector)
+		results.Attach(out)
+	}
+	defer results.Finish()
+
+	for i, step := range s.steps {
+		variant := out[i].Variant
+		stepCtx := ctx
+		if variant != "" {
+			stepCtx = context.WithValue(ctx, variantKey{}, variant)
+		}
 
 		// Call the steps concurrently
 		i, step := i, step
-		g.Go(func() error {
+		run := func(queued bool) error {
 			start := time.Now()
-			err := step.Run(ctx, req) // execute the step function
+			var err error
+			if queued && stepCtx.Err() != nil {
+				err = ctxerr.Err(stepCtx) // ended while waiting for a slot
+			} else {
+				runCtx, stop := s.stepContext(stepCtx, results, i)
+				syncpoint.Hit(syncpoint.OrderStepStart, step.Name)
+				err = stop(step.Run(runCtx, req)) // execute the step function
+				syncpoint.Hit(syncpoint.OrderStepEnd, step.Name)
+			}
 			durationMS := time.Since(start).Milliseconds()
 
-			status := "ok" // default value
+			status := model.StatusOK // default value
 			detail := ""
+			if errors.Is(err, ErrDeferred) {
+				status, detail, err = model.StatusDeferred, "deferred", nil
+			}
 			if err != nil {
 				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-					status = "canceled"
+					status = model.StatusCanceled
 				} else {
-					status = "error"
+					status = model.StatusError
 					var k kinder
 					if errors.As(err, &k) {
 						detail = k.Kind()
@@ -82,16 +295,273 @@ func (s *Service) Process(ctx context.Context, req model.OrderRequest) ([]model.
 				}
 			}
 
-			out[i] = model.StepResult{
+			result := model.StepResult{
 				Name:       step.Name,
 				Status:     status,
 				DurationMS: durationMS,
 				Detail:     detail,
+				Variant:    variant,
+			}
+			if late != nil {
+				late.mu.Lock()
+				defer late.mu.Unlock()
+				if late.detached {
+					s.lateReport(req.OrderID, result)
+					return nil
+				}
+				late.finished[i] = true
+			}
+			results.Set(i, result)
+			if err == nil {
+				return nil
 			}
+			if s.failAtEnd {
+				errs[i] = &StepError{Step: step.Name, Err: withCause(ctx, err)}
+				syncpoint.Hit(syncpoint.OrderStepFailed, step.Name)
+				return nil
+			}
+			if ctx.Err() == nil {
+				cancel(&StepError{Step: step.Name, Err: err})
+			}
+			syncpoint.Hit(syncpoint.OrderStepFailed, step.Name)
 			return err
+		}
+		if s.stepLimit == 0 {
+			g.Go(func() error { return run(false) })
+			continue
+		}
+		s.limit.started.Add(1)
+		if g.TryGo(func() error { return run(false) }) {
+			continue
+		}
+		// At the limit: wait for a running step to return.
+		s.limit.queued.Add(1)
+		s.limit.waiting.Add(1)
+		queuedAt := time.Now()
+		g.Go(func() error {
+			s.limit.waiting.Add(-1)
+			s.limit.waitNS.Add(int64(time.Since(queuedAt)))
+			return run(true)
 		})
 	}
 
-	err := g.Wait()
-	return out, err
+	var err error
+	if late == nil {
+		err = g.Wait()
+	} else {
+		var timedOut bool
+		if err, timedOut = late.wait(&g); timedOut {
+			return out, late.timeoutError(s.steps, errs)
+		}
+	}
+	if s.failAtEnd {
+		return out, errors.Join(errs...)
+	}
+	// Report the step that canceled the rest, rather than whichever
+	// canceled sibling returned first.
+	var cause *StepError
+	if err != nil && errors.As(context.Cause(ctx), &cause) {
+		return out, cause
+	}
+	return out, withCause(ctx, err)
+}
+
+// defaultKeepAlive is how often, without StepIdleTimeout, waits under
+// progress.KeepAlive report that they are alive. Under the timeout they
+// beat three times within it.
+const defaultKeepAlive = time.Second
+
+// stepContext returns the context step i runs under, whose heartbeats
+// go to results. Under StepIdleTimeout the context is canceled once the
+// step goes that long without one. The step's error must be passed to
+// stop, which releases the context and returns ErrStepStalled in place
+// of the error of a step the timeout stopped.
+func (s *Service) stepContext(ctx context.Context, results *progress.Collector, i int) (_ context.Context, stop func(error) error) {
+	if s.stepIdle == 0 {
+		return progress.WithHeartbeat(ctx, defaultKeepAlive, func(percent int, message string) {
+			results.Beat(i, percent, message)
+		}), func(err error) error { return err }
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancelCause(ctx)
+	idle := time.AfterFunc(s.stepIdle, func() { cancel(ErrStepStalled) })
+	ctx = progress.WithHeartbeat(ctx, s.stepIdle/3, func(percent int, message string) {
+		idle.Reset(s.stepIdle)
+		results.Beat(i, percent, message)
+	})
+	return ctx, func(err error) error {
+		idle.Stop()
+		if err != nil && parent.Err() == nil && errors.Is(context.Cause(ctx), ErrStepStalled) {
+			err = ErrStepStalled
+		}
+		cancel(nil)
+		return err
+	}
+}
+
+// StepLimit reports the MaxConcurrentSteps limit and how many steps
+// waited behind it.
+func (s *Service) StepLimit() model.StepLimit {
+	return model.StepLimit{
+		Limit:    s.stepLimit,
+		Started:  s.limit.started.Load(),
+		Queued:   s.limit.queued.Load(),
+		Waiting:  s.limit.waiting.Load(),
+		QueuedMS: time.Duration(s.limit.waitNS.Load()).Milliseconds(),
+	}
+}
+
+// lateSteps tracks one Process call under FinishLate.
+type lateSteps struct {
+	ctx      context.Context // the steps' context, free of the order deadline
+	cancel   context.CancelCauseFunc
+	parent   context.Context
+	deadline chan struct{} // closed when the order deadline fires
+	stop     func() bool   // stops the deadline watch
+
+	mu       sync.Mutex
+	grace    *time.Timer
+	detached bool   // Process has returned; finishing steps report late
+	finished []bool // indexed like steps; set before detachment
+}
+
+// errLateGrace stops steps still running a grace period past the order
+// deadline.
+var errLateGrace = errors.New("late step grace period exceeded")
+
+// detachDeadline returns the context for n steps under ctx: canceled
+// with ctx's cause, except when ctx's deadline fires, in which case the
+// steps get s.lateGrace longer.
+func (s *Service) detachDeadline(ctx context.Context, n int) *lateSteps {
+	stepCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	l := &lateSteps{
+		ctx:      stepCtx,
+		cancel:   cancel,
+		parent:   ctx,
+		deadline: make(chan struct{}),
+		finished: make([]bool, n),
+	}
+	l.stop = context.AfterFunc(ctx, func() {
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			cancel(context.Cause(ctx))
+			return
+		}
+		l.mu.Lock()
+		l.grace = time.AfterFunc(s.lateGrace, func() { cancel(errLateGrace) })
+		l.mu.Unlock()
+		close(l.deadline)
+	})
+	return l
+}
+
+// wait waits for g, or for the order deadline. On the deadline it marks
+// the steps detached and reports timedOut; the steps' context is
+// released once the stragglers finish.
+func (l *lateSteps) wait(g *errgroup.Group) (err error, timedOut bool) {
+	done := make(chan error, 1)
+	go func() {
+		done <- g.Wait()
+	}()
+	select {
+	case err = <-done:
+		l.release()
+		return err, false
+	case <-l.deadline:
+		l.mu.Lock()
+		l.detached = true
+		l.mu.Unlock()
+		go func() {
+			<-done
+			l.release()
+		}()
+		return nil, true
+	}
+}
+
+func (l *lateSteps) release() {
+	l.stop()
+	l.mu.Lock()
+	if l.grace != nil {
+		l.grace.Stop()
+	}
+	l.mu.Unlock()
+	l.cancel(nil)
+}
+
+// timeoutError returns the order's error when Process returned at the
+// deadline: the deadline error, joined in fail-at-end mode with the
+// errors of the steps that had finished and naming each unfinished one.
+// l.detached must be set, so no step writes errs concurrently.
+func (l *lateSteps) timeoutError(steps []Step, errs []error) error {
+	deadline := ctxerr.Err(l.parent)
+	if errs == nil {
+		return deadline
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, done := range l.finished {
+		if !done {
+			errs[i] = &StepError{Step: steps[i].Name, Err: deadline}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// causeError is a context error whose context was canceled with a
+// classified cause.
+//
+// It is one failure, not a joined error: Unwrap returns only the
+// context error, so callers splitting joined errors see a single entry.
+// Is and As reach the cause first, so the order is classified by it.
+type causeError struct {
+	err   error // the context error
+	cause error
+}
+
+func (e *causeError) Error() string { return e.err.Error() + ": " + e.cause.Error() }
+
+// Unwrap returns the context error.
+func (e *causeError) Unwrap() error { return e.err }
+
+// Is reports whether the cause matches target.
+func (e *causeError) Is(target error) bool { return errors.Is(e.cause, target) }
+
+// As finds the first error in the cause's chain that matches target.
+func (e *causeError) As(target any) bool { return errors.As(e.cause, target) }
+
+// withCause returns err, a context error from ctx, joined with ctx's
+// cancellation cause if the cause carries a kind, so the order is
+// classified by why it was canceled (for example a client disconnect)
+// rather than as plainly canceled. Other errors are returned unchanged.
+func withCause(ctx context.Context, err error) error {
+	if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	cause := context.Cause(ctx)
+	var k kinder
+	if cause == nil || errors.Is(err, cause) || !errors.As(cause, &k) {
+		return err
+	}
+	return &causeError{err: err, cause: cause}
+}
+
+// newResults returns a results slice of len(s.steps), reused if possible.
+// Every element is overwritten by Process before it is read.
+func (s *Service) newResults() []model.StepResult {
+	if p, ok := s.results.Get().(*[]model.StepResult); ok {
+		return *p
+	}
+	return make([]model.StepResult, len(s.steps))
+}
+
+// Release returns a slice obtained from Process for reuse.
+//
+// The caller must not use results, or any slice sharing its backing
+// array, after calling Release. Slices of the wrong length are ignored.
+func (s *Service) Release(results []model.StepResult) {
+	if len(results) != len(s.steps) || cap(results) != len(s.steps) {
+		return
+	}
+	clear(results) // drop string references before pooling
+	s.results.Put(&results)
 }
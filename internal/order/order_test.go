@@ -3,10 +3,16 @@ package order
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/memo"
 	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/progress"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/pool"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/simulation"
 )
 
 type testKindErr struct {
@@ -86,6 +92,68 @@ func TestProcess_DomainErrorCancelsSiblings(t *testing.T) {
 	}
 }
 
+// The failing step, not a canceled sibling that returns first, is
+// reported as the error and as the cancellation cause seen by siblings.
+func TestProcess_ReportsCancelingStep(t *testing.T) {
+	t.Parallel()
+
+	domainErr := testKindErr{kind: "vendor_unavailable"}
+	causes := make(chan error, 1)
+	steps := []Step{
+		{Name: "waits", Run: func(ctx context.Context, _ model.OrderRequest) error {
+			<-ctx.Done()
+			causes <- context.Cause(ctx)
+			return ctx.Err()
+		}},
+		{Name: "vendor", Run: func(context.Context, model.OrderRequest) error {
+			return domainErr
+		}},
+	}
+	svc := New(steps)
+
+	_, err := svc.Process(context.Background(), model.OrderRequest{OrderID: "o-2"})
+	var se *StepError
+	if !errors.As(err, &se) || se.Step != "vendor" || !errors.Is(err, domainErr) {
+		t.Fatalf("expected vendor *StepError, got %v", err)
+	}
+	if cause := <-causes; !errors.As(cause, &se) || se.Step != "vendor" {
+		t.Fatalf("expected vendor as cancellation cause, got %v", cause)
+	}
+}
+
+// A parent cancellation with a classified cause is reported under the
+// cause's kind, while still matching the context error.
+func TestProcess_ClassifiedParentCause(t *testing.T) {
+	t.Parallel()
+
+	cause := testKindErr{kind: "client_disconnected"}
+	wait := func(ctx context.Context, _ model.OrderRequest) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	steps := []Step{{Name: "a", Run: wait}, {Name: "b", Run: wait}}
+
+	for _, svc := range []*Service{New(steps), New(steps, FailAtEnd())} {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(cause)
+		results, err := svc.Process(ctx, model.OrderRequest{OrderID: "o-3"})
+		var k kinder
+		if !errors.Is(err, context.Canceled) || !errors.As(err, &k) || k.Kind() != "client_disconnected" {
+			t.Fatalf("expected canceled error of kind client_disconnected, got %v", err)
+		}
+		if results[0].Status != model.StatusCanceled {
+			t.Fatalf("expected step canceled, got %+v", results[0])
+		}
+	}
+
+	// Causes without a kind leave the error unchanged.
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errors.New("shutdown"))
+	if _, err := New(steps).Process(ctx, model.OrderRequest{OrderID: "o-4"}); err != context.Canceled {
+		t.Fatalf("expected plain %v, got %v", context.Canceled, err)
+	}
+}
+
 func TestProcess_ContextAlreadyCanceled(t *testing.T) {
 	t.Parallel()
 
@@ -184,3 +252,537 @@ func TestProcess_ResultOrder(t *testing.T) {
 		t.Fatalf("expected [slow, fast], got [%s, %s]", results[0].Name, results[1].Name)
 	}
 }
+
+// A released slice is reused, fully overwritten, by the next Process call.
+func TestProcess_ReleaseReuse(t *testing.T) {
+	t.Parallel()
+
+	steps := []Step{
+		{Name: "a", Run: func(context.Context, model.OrderRequest) error { return nil }},
+		{Name: "b", Run: func(_ context.Context, req model.OrderRequest) error {
+			if req.FailStep == "b" {
+				return testKindErr{kind: "b_failed"}
+			}
+			return nil
+		}},
+	}
+	svc := New(steps)
+
+	first, err := svc.Process(context.Background(), model.OrderRequest{OrderID: "o-1", FailStep: "b"})
+	if !errors.As(err, new(testKindErr)) {
+		t.Fatalf("expected kind error, got %v", err)
+	}
+	svc.Release(first)
+	for i, r := range first {
+		if r != (model.StepResult{}) {
+			t.Fatalf("released result %d not cleared: %+v", i, r)
+		}
+	}
+
+	second, err := svc.Process(context.Background(), model.OrderRequest{OrderID: "o-2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, r := range second {
+		if r.Status != "ok" || r.Detail != "" {
+			t.Fatalf("stale result after reuse: %+v", r)
+		}
+	}
+
+	// Foreign slices are not pooled.
+	svc.Release(make([]model.StepResult, 1))
+	svc.Release(nil)
+}
+
+func BenchmarkProcess(b *testing.B) {
+	noop := func(context.Context, model.OrderRequest) error { return nil }
+	svc := New([]Step{{Name: "payment", Run: noop}, {Name: "vendor", Run: noop}, {Name: "courier", Run: noop}})
+	req := model.OrderRequest{OrderID: "o-1", Amount: 100}
+
+	b.Run("no_release", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := svc.Process(context.Background(), req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("release", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			out, err := svc.Process(context.Background(), req)
+			if err != nil {
+				b.Fatal(err)
+			}
+			svc.Release(out)
+		}
+	})
+}
+
+// A collector in the context sees each result as its step finishes, and
+// only the first Process call under the context collects into it.
+func TestProcess_Progress(t *testing.T) {
+	t.Parallel()
+
+	paid := make(chan struct{})
+	release := make(chan struct{})
+	svc := New([]Step{
+		{Name: "payment", Run: func(context.Context, model.OrderRequest) error { return nil }},
+		{Name: "vendor", Run: func(context.Context, model.OrderRequest) error {
+			<-release
+			return nil
+		}},
+	})
+	c := new(progress.Collector)
+	c.Watch(func(r model.StepResult) {
+		if r.Name == "payment" {
+			close(paid)
+		}
+	})
+	ctx := progress.NewContext(context.Background(), c)
+
+	type result struct {
+		out []model.StepResult
+		err error
+	}
+	res := make(chan result, 1)
+	go func() {
+		out, err := svc.Process(ctx, model.OrderRequest{OrderID: "o-1"})
+		res <- result{out, err}
+	}()
+	<-paid
+	done, running := c.Snapshot()
+	if len(done) != 1 || done[0].Status != model.StatusOK || len(running) != 1 || running[0] != "vendor" {
+		t.Fatalf("expected payment done and vendor running, got %+v %v", done, running)
+	}
+	close(release)
+	r := <-res
+	if r.err != nil || !c.Finished() {
+		t.Fatalf("expected a finished success, got %v", r.err)
+	}
+	svc.Release(r.out) // the collector keeps its own copy
+	if done, _ := c.Snapshot(); len(done) != 2 || done[1].Name != "vendor" {
+		t.Fatalf("expected both results kept, got %+v", done)
+	}
+
+	// A second run under the same context uses a private collector.
+	if _, err := svc.Process(ctx, model.OrderRequest{OrderID: "o-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if done, _ := c.Snapshot(); len(done) != 2 {
+		t.Fatalf("expected the first run's results only, got %+v", done)
+	}
+}
+
+// A step going quiet for the idle timeout is stopped as stalled, while
+// one sending heartbeats may outlive it.
+func TestProcess_StepIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	const idle = 40 * time.Millisecond
+	quiet := func(ctx context.Context, _ model.OrderRequest) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	beating := func(ctx context.Context, _ model.OrderRequest) error {
+		for pct := 0; pct <= 100; pct += 20 {
+			progress.Heartbeat(ctx, pct, "")
+			select {
+			case <-time.After(idle / 4):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+
+	// A courier-style wait for a full pool, freed well after the timeout.
+	queued := func(ctx context.Context, _ model.OrderRequest) error {
+		p := pool.New(1)
+		if err := p.Acquire(ctx); err != nil {
+			return err
+		}
+		time.AfterFunc(3*idle, p.Release)
+		if err := p.Acquire(ctx); err != nil {
+			return err
+		}
+		p.Release()
+		return nil
+	}
+
+	tests := []struct {
+		name       string
+		run        func(context.Context, model.OrderRequest) error
+		deadline   time.Duration // 0: none
+		wantStatus model.Status
+		wantErr    error
+	}{
+		{name: "stalled", run: quiet, wantStatus: model.StatusError, wantErr: ErrStepStalled},
+		{name: "heartbeats", run: beating, wantStatus: model.StatusOK},
+		{name: "pool_wait", run: queued, wantStatus: model.StatusOK},
+		{name: "deadline_first", run: quiet, deadline: idle / 4, wantStatus: model.StatusCanceled, wantErr: context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			svc := New([]Step{
+				{Name: "payment", Run: func(context.Context, model.OrderRequest) error { return nil }},
+				{Name: "vendor", Run: tt.run},
+			}, StepIdleTimeout(idle))
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+			out, err := svc.Process(ctx, model.OrderRequest{OrderID: "o-1"})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if out[1].Status != tt.wantStatus {
+				t.Fatalf("expected vendor %s, got %+v", tt.wantStatus, out[1])
+			}
+			if tt.wantErr == ErrStepStalled && out[1].Detail != "step_stalled" {
+				t.Fatalf("expected detail step_stalled, got %+v", out[1])
+			}
+		})
+	}
+}
+
+// Heartbeats reach the collector watching the order.
+func TestProcess_Heartbeat(t *testing.T) {
+	t.Parallel()
+
+	beat := make(chan struct{})
+	release := make(chan struct{})
+	svc := New([]Step{{Name: "vendor", Run: func(ctx context.Context, _ model.OrderRequest) error {
+		progress.Heartbeat(ctx, 30, "menu synced")
+		close(beat)
+		<-release
+		return nil
+	}}})
+	c := new(progress.Collector)
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.Process(progress.NewContext(context.Background(), c), model.OrderRequest{OrderID: "o-1"})
+		done <- err
+	}()
+	<-beat
+	hs := c.Heartbeats(time.Now())
+	if len(hs) != 1 || hs[0].Step != "vendor" || hs[0].Percent == nil || *hs[0].Percent != 30 || hs[0].Message != "menu synced" {
+		t.Fatalf("expected vendor's heartbeat, got %+v", hs)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// In fail-at-end mode a failing step does not cancel its siblings and
+// every step error is returned.
+func TestProcess_FailAtEnd(t *testing.T) {
+	t.Parallel()
+
+	declined := testKindErr{kind: "payment_declined"}
+	unavailable := testKindErr{kind: "vendor_unavailable"}
+
+	steps := []Step{
+		{Name: "payment", Run: func(context.Context, model.OrderRequest) error { return declined }},
+		{Name: "slow_ok", Run: func(ctx context.Context, _ model.OrderRequest) error {
+			select {
+			case <-time.After(20 * time.Millisecond):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}},
+		{Name: "vendor", Run: func(context.Context, model.OrderRequest) error { return unavailable }},
+	}
+	svc := New(steps, FailAtEnd())
+
+	results, err := svc.Process(context.Background(), model.OrderRequest{OrderID: "o-7"})
+	if !errors.Is(err, declined) || !errors.Is(err, unavailable) {
+		t.Fatalf("expected both step errors, got %v", err)
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected joined error, got %T", err)
+	}
+	var names []string
+	for _, e := range joined.Unwrap() {
+		var se *StepError
+		if !errors.As(e, &se) {
+			t.Fatalf("expected *StepError, got %T", e)
+		}
+		names = append(names, se.StepName())
+	}
+	if len(names) != 2 || names[0] != "payment" || names[1] != "vendor" {
+		t.Fatalf("expected [payment vendor], got %v", names)
+	}
+
+	if results[1].Status != "ok" {
+		t.Fatalf("expected slow_ok to finish, got %+v", results[1])
+	}
+	if results[0].Detail != "payment_declined" || results[2].Detail != "vendor_unavailable" {
+		t.Fatalf("unexpected failing results: %+v", results)
+	}
+}
+
+func TestProcess_DeferredStep(t *testing.T) {
+	t.Parallel()
+
+	siblingDone := make(chan struct{})
+	svc := New([]Step{
+		{Name: "payment", Run: func(ctx context.Context, _ model.OrderRequest) error {
+			select {
+			case <-time.After(20 * time.Millisecond):
+				close(siblingDone)
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}},
+		{Name: "courier", Run: func(context.Context, model.OrderRequest) error {
+			return fmt.Errorf("courier: assignment %w", ErrDeferred)
+		}},
+	})
+
+	results, err := svc.Process(context.Background(), model.OrderRequest{OrderID: "o-9"})
+	if err != nil {
+		t.Fatalf("expected a deferred step not to fail the order, got %v", err)
+	}
+	select {
+	case <-siblingDone:
+	default:
+		t.Fatal("expected the sibling step to run to completion")
+	}
+	if results[0].Status != model.StatusOK {
+		t.Fatalf("expected payment ok, got %+v", results[0])
+	}
+	if results[1].Status != model.StatusDeferred || results[1].Detail != "deferred" {
+		t.Fatalf("expected courier deferred, got %+v", results[1])
+	}
+}
+
+func TestProcess_FailAtEndAllSuccess(t *testing.T) {
+	t.Parallel()
+
+	svc := New([]Step{
+		{Name: "a", Run: func(context.Context, model.OrderRequest) error { return nil }},
+	}, FailAtEnd())
+
+	if _, err := svc.Process(context.Background(), model.OrderRequest{OrderID: "o-8"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Under Memoize, steps of one order share keyed calls; orders do not.
+func TestProcess_Memoize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantCalls int64
+	}{
+		{name: "memoized", opts: []Option{Memoize()}, wantCalls: 2}, // one per order
+		{name: "plain", wantCalls: 6},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var calls atomic.Int64
+			lookup := func(ctx context.Context, req model.OrderRequest) error {
+				_, err := memo.Do(ctx, "address:"+req.OrderID, func(context.Context) (string, error) {
+					calls.Add(1)
+					return "north", nil
+				})
+				return err
+			}
+			svc := New([]Step{{Name: "a", Run: lookup}, {Name: "b", Run: lookup}, {Name: "c", Run: lookup}}, tt.opts...)
+			for _, id := range []string{"o-1", "o-1"} {
+				if _, err := svc.Process(context.Background(), model.OrderRequest{OrderID: id}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Fatalf("expected %d calls, got %d", tt.wantCalls, got)
+			}
+		})
+	}
+}
+
+func TestProcess_Simulate(t *testing.T) {
+	t.Parallel()
+
+	for _, on := range []bool{false, true} {
+		var opts []Option
+		if on {
+			opts = append(opts, Simulate())
+		}
+		var got atomic.Bool
+		svc := New([]Step{{Name: "a", Run: func(ctx context.Context, _ model.OrderRequest) error {
+			got.Store(simulation.Active(ctx))
+			return nil
+		}}}, opts...)
+		if _, err := svc.Process(context.Background(), model.OrderRequest{OrderID: "o-1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Load() != on {
+			t.Fatalf("expected simulation %v, got %v", on, got.Load())
+		}
+	}
+}
+
+func TestProcess_MaxConcurrentSteps(t *testing.T) {
+	t.Parallel()
+
+	var running, peak atomic.Int64
+	step := func(ctx context.Context, _ model.OrderRequest) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}
+	var steps []Step
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		steps = append(steps, Step{Name: name, Run: step})
+	}
+	svc := New(steps, MaxConcurrentSteps(2))
+	results, err := svc.Process(context.Background(), model.OrderRequest{OrderID: "o-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, r := range results {
+		if r.Status != model.StatusOK {
+			t.Fatalf("expected every step ok, got %+v", results)
+		}
+	}
+	if got := peak.Load(); got != 2 {
+		t.Fatalf("expected at most 2 steps at once, got %d", got)
+	}
+	// c waits for a or b and e for c or d; d may find a slot already free.
+	if got := svc.StepLimit(); got.Limit != 2 || got.Started != 6 || got.Queued < 2 || got.Queued > 4 || got.Waiting != 0 || got.QueuedMS < 5 {
+		t.Fatalf("expected 6 steps started, 2 to 4 of them queued, got %+v", got)
+	}
+
+	// Steps still queued when a sibling fails do not run.
+	var ran atomic.Int64
+	svc = New([]Step{
+		{Name: "a", Run: func(context.Context, model.OrderRequest) error { return errors.New("a failed") }},
+		{Name: "b", Run: func(context.Context, model.OrderRequest) error { ran.Add(1); return nil }},
+		{Name: "c", Run: func(context.Context, model.OrderRequest) error { ran.Add(1); return nil }},
+	}, MaxConcurrentSteps(1))
+	results, err = svc.Process(context.Background(), model.OrderRequest{OrderID: "o-2"})
+	if err == nil || ran.Load() != 0 {
+		t.Fatalf("expected the queued steps skipped, got %d run (%v)", ran.Load(), err)
+	}
+	if results[1].Status != model.StatusCanceled || results[2].Status != model.StatusCanceled {
+		t.Fatalf("expected the queued steps canceled, got %+v", results)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected panic for a limit under FinishLate")
+		}
+	}()
+	New(steps, MaxConcurrentSteps(2), FinishLate(time.Second, func(string, model.StepResult) {}))
+}
+
+// sleepStep returns a step that takes d unless its context ends first.
+func sleepStep(name string, d time.Duration) Step {
+	return Step{Name: name, Run: func(ctx context.Context, _ model.OrderRequest) error {
+		select {
+		case <-time.After(d):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}}
+}
+
+func TestProcess_FinishLate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		grace      time.Duration
+		opts       []Option
+		wantStatus model.Status // of the late report
+	}{
+		{name: "finishes", grace: time.Second, wantStatus: model.StatusOK},
+		{name: "finishes_fail_at_end", grace: time.Second, opts: []Option{FailAtEnd()}, wantStatus: model.StatusOK},
+		{name: "grace_exceeded", grace: 10 * time.Millisecond, wantStatus: model.StatusCanceled},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			reports := make(chan model.StepResult, 1)
+			opts := append(tt.opts, FinishLate(tt.grace, func(orderID string, r model.StepResult) {
+				if orderID != "o-1" {
+					t.Errorf("expected order o-1, got %q", orderID)
+				}
+				reports <- r
+			}))
+			svc := New([]Step{sleepStep("payment", 0), sleepStep("courier", 100*time.Millisecond)}, opts...)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			results, err := svc.Process(ctx, model.OrderRequest{OrderID: "o-1"})
+			if d := time.Since(start); d > 80*time.Millisecond {
+				t.Fatalf("expected Process to return at the deadline, took %v", d)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+			}
+			var se *StepError
+			if len(tt.opts) > 0 && (!errors.As(err, &se) || se.Step != "courier") {
+				t.Fatalf("expected courier named in fail-at-end error, got %v", err)
+			}
+			if results[0].Status != model.StatusOK || results[1].Status != model.StatusCanceled {
+				t.Fatalf("expected payment ok and courier canceled, got %+v", results)
+			}
+
+			select {
+			case r := <-reports:
+				if r.Name != "courier" || r.Status != tt.wantStatus {
+					t.Fatalf("expected late courier %s, got %+v", tt.wantStatus, r)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("expected a late report")
+			}
+		})
+	}
+}
+
+// Cancellation other than the deadline still stops steps at once.
+func TestProcess_FinishLateCanceled(t *testing.T) {
+	t.Parallel()
+
+	reported := make(chan model.StepResult, 1)
+	svc := New([]Step{sleepStep("courier", time.Minute)},
+		FinishLate(time.Minute, func(_ string, r model.StepResult) { reported <- r }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	results, err := svc.Process(ctx, model.OrderRequest{OrderID: "o-2"})
+	if !errors.Is(err, context.Canceled) || results[0].Status != model.StatusCanceled {
+		t.Fatalf("expected canceled courier, got %v %+v", err, results)
+	}
+	select {
+	case r := <-reported:
+		t.Fatalf("expected no late report, got %+v", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
@@ -0,0 +1,114 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// Tracker counts in-flight goroutines. *tracker.Tracker satisfies it.
+type Tracker interface {
+	Inc()
+	Dec()
+}
+
+// Tail runs non-critical steps, such as analytics or loyalty points,
+// after an order has succeeded.
+//
+// Tail steps run concurrently in the background, detached from the
+// request's cancellation but sharing one budget per order. They never
+// change the order's result: a failure is handed to the error callback.
+type Tail struct {
+	cfg     TailConfig
+	steps   []Step
+	onError func(orderID, step string, err error)
+	wg      sync.WaitGroup
+}
+
+// TailConfig sets how tail steps run.
+type TailConfig struct {
+	Budget   time.Duration // time all tail steps of one order may take
+	SyncWait time.Duration // how long Process waits for them; 0 returns at once
+	Tracker  Tracker       // counts running tail steps; may be nil
+}
+
+// errTailBudget is the cancellation cause of tail steps still running
+// when their budget runs out.
+var errTailBudget = errors.New("tail step budget exceeded")
+
+// NewTail returns a Tail running steps as configured by cfg. onError
+// receives each failure.
+//
+// It panics if no steps are given, cfg.Budget is not positive or
+// onError is nil.
+func NewTail(cfg TailConfig, onError func(orderID, step string, err error), steps ...Step) *Tail {
+	switch {
+	case len(steps) == 0:
+		panic("order.NewTail: no steps")
+	case cfg.Budget <= 0:
+		panic("order.NewTail: non-positive budget")
+	case onError == nil:
+		panic("order.NewTail: nil error callback")
+	}
+	return &Tail{cfg: cfg, steps: steps, onError: onError}
+}
+
+// TailSteps makes Process start t's steps for every order that
+// succeeds, just before returning. Process waits for them for up to
+// the configured SyncWait, so only that much is added to the response
+// time; steps still running then finish in the background.
+func TailSteps(t *Tail) Option {
+	return func(s *Service) { s.tail = t }
+}
+
+// Start runs the tail steps for req in the background. They see ctx's
+// values but not its cancellation or deadline. The returned channel is
+// closed once all of them have returned.
+func (t *Tail) Start(ctx context.Context, req model.OrderRequest) <-chan struct{} {
+	ctx, cancel := context.WithTimeoutCause(context.WithoutCancel(ctx), t.cfg.Budget, errTailBudget)
+	var wg sync.WaitGroup
+	for _, step := range t.steps {
+		if t.cfg.Tracker != nil {
+			t.cfg.Tracker.Inc()
+		}
+		t.wg.Add(1)
+		wg.Go(func() {
+			defer t.wg.Done()
+			if t.cfg.Tracker != nil {
+				defer t.cfg.Tracker.Dec()
+			}
+			if err := step.Run(ctx, req); err != nil {
+				t.onError(req.OrderID, step.Name, err)
+			}
+		})
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		cancel()
+		close(done)
+	}()
+	return done
+}
+
+// waitSync waits for done for up to SyncWait, or until ctx is done.
+func (t *Tail) waitSync(ctx context.Context, done <-chan struct{}) {
+	if t.cfg.SyncWait <= 0 {
+		return
+	}
+	timer := time.NewTimer(t.cfg.SyncWait)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// Wait blocks until every tail step started so far has returned.
+func (t *Tail) Wait() {
+	t.wg.Wait()
+}
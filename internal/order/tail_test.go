@@ -0,0 +1,196 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+type countTracker struct {
+	running atomic.Int64
+}
+
+func (c *countTracker) Inc() { c.running.Add(1) }
+func (c *countTracker) Dec() { c.running.Add(-1) }
+
+type tailFailure struct {
+	orderID, step string
+	err           error
+}
+
+func TestProcess_TailSteps(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		stepErr  error
+		wantRuns int32
+	}{
+		{name: "success_starts_tail", wantRuns: 2},
+		{name: "failure_skips_tail", stepErr: errors.New("boom"), wantRuns: 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var runs atomic.Int32
+			release := make(chan struct{})
+			tailStep := func(ctx context.Context, _ model.OrderRequest) error {
+				runs.Add(1)
+				<-release
+				return ctx.Err()
+			}
+			tr := &countTracker{}
+			var mu sync.Mutex
+			var failures []tailFailure
+			tail := NewTail(TailConfig{Budget: time.Second, Tracker: tr}, func(orderID, step string, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				failures = append(failures, tailFailure{orderID, step, err})
+			}, Step{Name: "analytics", Run: tailStep}, Step{Name: "loyalty", Run: tailStep})
+
+			svc := New([]Step{
+				{Name: "payment", Run: func(context.Context, model.OrderRequest) error { return tt.stepErr }},
+			}, TailSteps(tail))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			_, err := svc.Process(ctx, model.OrderRequest{OrderID: "o-1"})
+			if !errors.Is(err, tt.stepErr) {
+				t.Fatalf("expected %v, got %v", tt.stepErr, err)
+			}
+			// The request going away must not stop the tail steps.
+			cancel()
+			if got := tr.running.Load(); got != int64(tt.wantRuns) {
+				t.Fatalf("expected %d tail steps tracked, got %d", tt.wantRuns, got)
+			}
+			close(release)
+			tail.Wait()
+
+			if got := runs.Load(); got != tt.wantRuns {
+				t.Fatalf("expected %d tail runs, got %d", tt.wantRuns, got)
+			}
+			if got := tr.running.Load(); got != 0 {
+				t.Fatalf("expected 0 tail steps tracked after Wait, got %d", got)
+			}
+			if len(failures) != 0 {
+				t.Fatalf("expected no failures, got %v", failures)
+			}
+		})
+	}
+}
+
+func TestProcess_TailSyncWait(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		tailDelay time.Duration
+		wantDone  bool
+	}{
+		{name: "tail_finishes_in_time", tailDelay: time.Millisecond, wantDone: true},
+		{name: "tail_outlasts_wait", tailDelay: 200 * time.Millisecond, wantDone: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var done atomic.Bool
+			tail := NewTail(TailConfig{Budget: time.Second, SyncWait: 50 * time.Millisecond}, func(string, string, error) {},
+				Step{Name: "loyalty", Run: func(context.Context, model.OrderRequest) error {
+					time.Sleep(tt.tailDelay)
+					done.Store(true)
+					return nil
+				}})
+			svc := New([]Step{
+				{Name: "payment", Run: func(context.Context, model.OrderRequest) error { return nil }},
+			}, TailSteps(tail))
+
+			if _, err := svc.Process(context.Background(), model.OrderRequest{OrderID: "o-3"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := done.Load(); got != tt.wantDone {
+				t.Fatalf("expected tail done=%v on return, got %v", tt.wantDone, got)
+			}
+			tail.Wait()
+		})
+	}
+}
+
+func TestTail_ReportsFailures(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var failures []tailFailure
+	tail := NewTail(TailConfig{Budget: 20 * time.Millisecond}, func(orderID, step string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failures = append(failures, tailFailure{orderID, step, err})
+	},
+		Step{Name: "analytics", Run: func(context.Context, model.OrderRequest) error {
+			return testKindErr{kind: "analytics_unavailable"}
+		}},
+		Step{Name: "loyalty", Run: func(ctx context.Context, _ model.OrderRequest) error {
+			<-ctx.Done()
+			return context.Cause(ctx)
+		}},
+	)
+
+	tail.Start(context.Background(), model.OrderRequest{OrderID: "o-2"})
+	tail.Wait()
+
+	if len(failures) != 2 {
+		t.Fatalf("expected 2 failures, got %v", failures)
+	}
+	byStep := map[string]tailFailure{}
+	for _, f := range failures {
+		if f.orderID != "o-2" {
+			t.Fatalf("expected order o-2, got %q", f.orderID)
+		}
+		byStep[f.step] = f
+	}
+	var k kinder
+	if !errors.As(byStep["analytics"].err, &k) || k.Kind() != "analytics_unavailable" {
+		t.Fatalf("expected analytics_unavailable, got %v", byStep["analytics"].err)
+	}
+	if !errors.Is(byStep["loyalty"].err, errTailBudget) {
+		t.Fatalf("expected %v, got %v", errTailBudget, byStep["loyalty"].err)
+	}
+}
+
+func TestNewTail_Panics(t *testing.T) {
+	t.Parallel()
+
+	step := Step{Name: "analytics", Run: func(context.Context, model.OrderRequest) error { return nil }}
+	onError := func(string, string, error) {}
+	tests := []struct {
+		name string
+		new  func()
+	}{
+		{name: "no_steps", new: func() { NewTail(TailConfig{Budget: time.Second}, onError) }},
+		{name: "zero_budget", new: func() { NewTail(TailConfig{}, onError, step) }},
+		{name: "nil_callback", new: func() { NewTail(TailConfig{Budget: time.Second}, nil, step) }},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			tt.new()
+		})
+	}
+}
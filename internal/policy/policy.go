@@ -0,0 +1,257 @@
+// Package policy routes orders by rules over the request.
+//
+// A rule is a conjunction of comparisons over the order's amount, zone
+// and SLA class, the caller's tenant and the hour of day, and the
+// settings applied to orders it matches:
+//
+//	amount>=5000 && zone==north => sla=express
+//	tenant==acme && hour>=22 => sla=standard, zone=center
+//
+// Rules are compiled once, when parsed, and the first matching rule
+// wins.
+//
+// The grammar is a deliberate, fixed subset of what CEL would accept:
+// conditions are joined with && only, with no || and no parentheses
+// (write one rule per alternative), and string fields compare with ==
+// and != against a plain name, with no other string operators, quoting
+// or functions. Parse rejects anything outside the subset, so a rule
+// never means something other than it reads.
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// field identifies what a condition compares.
+type field int
+
+const (
+	fieldAmount field = iota
+	fieldHour
+	fieldTenant
+	fieldZone
+	fieldSLA
+)
+
+var fields = map[string]field{
+	"amount": fieldAmount,
+	"hour":   fieldHour,
+	"tenant": fieldTenant,
+	"zone":   fieldZone,
+	"sla":    fieldSLA,
+}
+
+// numeric reports whether f compares numbers rather than strings.
+func (f field) numeric() bool { return f == fieldAmount || f == fieldHour }
+
+// ops lists the comparison operators, longest first so that "<=" is
+// not read as "<".
+var ops = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// cond is one compiled comparison.
+type cond struct {
+	field field
+	op    string
+	str   string
+	num   int64
+}
+
+// subject is what conditions are evaluated against.
+type subject struct {
+	req    model.OrderRequest
+	tenant string
+	hour   int
+}
+
+func (c cond) match(s subject) bool {
+	if c.field.numeric() {
+		v := int64(s.hour)
+		if c.field == fieldAmount {
+			v = int64(min(s.req.Amount, 1<<63-1))
+		}
+		switch c.op {
+		case "==":
+			return v == c.num
+		case "!=":
+			return v != c.num
+		case "<":
+			return v < c.num
+		case "<=":
+			return v <= c.num
+		case ">":
+			return v > c.num
+		default:
+			return v >= c.num
+		}
+	}
+	var v string
+	switch c.field {
+	case fieldTenant:
+		v = s.tenant
+	case fieldZone:
+		v = s.req.Zone
+	default:
+		v = s.req.SLA
+	}
+	// Parse admits no string operator other than == and !=.
+	if c.op == "==" {
+		return v == c.str
+	}
+	return v != c.str
+}
+
+// Rule is one compiled routing rule.
+type Rule struct {
+	text  string
+	conds []cond
+	sla   string // "" leaves the class unchanged
+	zone  string // "" leaves the zone unchanged
+}
+
+// Engine evaluates an ordered list of rules. It is immutable and safe
+// for concurrent use.
+type Engine struct {
+	rules []Rule
+}
+
+// Parse compiles a semicolon-separated list of rules of the form
+//
+//	cond [&& cond...] => setting [, setting...]
+//
+// where a cond is field op value with field one of amount, hour,
+// tenant, zone and sla, op one of == != < <= > >= (only == and != for
+// strings, whose value is a plain name), and a setting is sla=class or
+// zone=name.
+func Parse(spec string) (*Engine, error) {
+	e := &Engine{}
+	for text := range strings.SplitSeq(spec, ";") {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		r, err := parseRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("policy: rule %q: %w", text, err)
+		}
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+func parseRule(text string) (Rule, error) {
+	when, then, ok := strings.Cut(text, "=>")
+	if !ok {
+		return Rule{}, fmt.Errorf("want conditions => settings")
+	}
+	switch {
+	case strings.Contains(when, "||"):
+		return Rule{}, fmt.Errorf("|| is not supported; write one rule per alternative")
+	case strings.ContainsAny(when, "()"):
+		return Rule{}, fmt.Errorf("parentheses are not supported")
+	}
+	r := Rule{text: text}
+	for part := range strings.SplitSeq(when, "&&") {
+		c, err := parseCond(strings.TrimSpace(part))
+		if err != nil {
+			return Rule{}, err
+		}
+		r.conds = append(r.conds, c)
+	}
+	for part := range strings.SplitSeq(then, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("setting %q: want sla=class or zone=name", part)
+		}
+		switch strings.TrimSpace(key) {
+		case "sla":
+			r.sla = value
+		case "zone":
+			r.zone = value
+		default:
+			return Rule{}, fmt.Errorf("setting %q: want sla=class or zone=name", part)
+		}
+	}
+	return r, nil
+}
+
+func parseCond(s string) (cond, error) {
+	for _, op := range ops {
+		name, value, ok := strings.Cut(s, op)
+		if !ok {
+			continue
+		}
+		f, known := fields[strings.TrimSpace(name)]
+		if !known {
+			return cond{}, fmt.Errorf("condition %q: unknown field %q", s, strings.TrimSpace(name))
+		}
+		c := cond{field: f, op: op, str: strings.TrimSpace(value)}
+		if !f.numeric() {
+			if op != "==" && op != "!=" || !plainName(c.str) {
+				return cond{}, fmt.Errorf("condition %q: %s compares with == or != against a plain name only", s, strings.TrimSpace(name))
+			}
+			return c, nil
+		}
+		n, err := strconv.ParseInt(c.str, 10, 64)
+		if err != nil {
+			return cond{}, fmt.Errorf("condition %q: want an integer", s)
+		}
+		c.num = n
+		return c, nil
+	}
+	return cond{}, fmt.Errorf("condition %q: want field op value", s)
+}
+
+// plainName reports whether s is made of letters, digits, '_', '-' and
+// '.', so that a string condition cannot hide another operator, a quote
+// or a function call in its value.
+func plainName(s string) bool {
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' && c != '-' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// Rules returns the rule texts in evaluation order.
+func (e *Engine) Rules() []string {
+	out := make([]string, len(e.rules))
+	for i, r := range e.rules {
+		out[i] = r.text
+	}
+	return out
+}
+
+// Evaluate finds the first rule matching req from tenant at the given
+// time, server local hour, and returns req with its settings applied.
+func (e *Engine) Evaluate(tenant string, at time.Time, req model.OrderRequest) model.PolicyDecision {
+	s := subject{req: req, tenant: tenant, hour: at.Hour()}
+	for i, r := range e.rules {
+		if !r.matches(s) {
+			continue
+		}
+		if r.sla != "" {
+			req.SLA = r.sla
+		}
+		if r.zone != "" {
+			req.Zone = r.zone
+		}
+		return model.PolicyDecision{Matched: true, Rule: i, RuleText: r.text, Order: req}
+	}
+	return model.PolicyDecision{Rule: -1, Order: req}
+}
+
+func (r Rule) matches(s subject) bool {
+	for _, c := range r.conds {
+		if !c.match(s) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package policy
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	e, err := Parse("amount>=5000 && zone==north => sla=express; tenant!=acme=>zone=center, sla=standard;")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"amount>=5000 && zone==north => sla=express", "tenant!=acme=>zone=center, sla=standard"}
+	if got := e.Rules(); !slices.Equal(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	for _, spec := range []string{
+		"amount>=5000",
+		"amount>=5000 =>",
+		"amount>=5000 => priority=high",
+		"weight>1 => sla=express",
+		"amount>=lots => sla=express",
+		"zone>north => sla=express",
+		"zone => sla=express",
+		"zone==north => sla=",
+		"zone==north || zone==south => sla=express",
+		"(zone==north) => sla=express",
+		"amount>=5000 && (zone==north) => sla=express",
+		"zone==north!=south => sla=express",
+		"zone<=north => sla=express",
+		`tenant=="acme" => sla=express`,
+		"tenant==acme corp => sla=express",
+		"tenant.startsWith(ac) => sla=express",
+		"zone in north => sla=express",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+
+	e, err := Parse("amount>=5000 && zone==north => sla=express; tenant==acme && hour>=22 => sla=standard, zone=center; hour<6 => zone=center")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	noon := time.Date(2026, 1, 5, 12, 0, 0, 0, time.Local)
+	late := time.Date(2026, 1, 5, 23, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		tenant   string
+		at       time.Time
+		req      model.OrderRequest
+		wantRule int
+		wantSLA  string
+		wantZone string
+	}{
+		{name: "large_north_order", at: noon, req: model.OrderRequest{Amount: 5000, Zone: "north"}, wantRule: 0, wantSLA: "express", wantZone: "north"},
+		{name: "first_match_wins", tenant: "acme", at: late, req: model.OrderRequest{Amount: 9000, Zone: "north"}, wantRule: 0, wantSLA: "express", wantZone: "north"},
+		{name: "tenant_at_night", tenant: "acme", at: late, req: model.OrderRequest{Amount: 100, SLA: "express"}, wantRule: 1, wantSLA: "standard", wantZone: "center"},
+		{name: "no_match", tenant: "globex", at: noon, req: model.OrderRequest{Amount: 100, Zone: "south"}, wantRule: -1, wantZone: "south"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := e.Evaluate(tt.tenant, tt.at, tt.req)
+			if got.Rule != tt.wantRule || got.Matched != (tt.wantRule >= 0) {
+				t.Fatalf("expected rule %d, got %+v", tt.wantRule, got)
+			}
+			if got.Order.SLA != tt.wantSLA || got.Order.Zone != tt.wantZone || got.Order.Amount != tt.req.Amount {
+				t.Fatalf("expected sla %q zone %q, got %+v", tt.wantSLA, tt.wantZone, got.Order)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Package probe runs synthetic canary orders through the pipeline.
+//
+// A Prober periodically submits a small order marked as synthetic
+// traffic, so steps use their sandbox integrations, and logs an alert
+// when the order fails or misses its latency objective. This catches a
+// broken dependency even when no real traffic is arriving.
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/traffic"
+)
+
+type orderProcessor interface {
+	Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error)
+}
+
+// resultReleaser is implemented by processors that pool result slices.
+type resultReleaser interface {
+	Release(results []model.StepResult)
+}
+
+// Prober submits synthetic orders on a fixed interval.
+type Prober struct {
+	proc     orderProcessor
+	interval time.Duration
+	slo      time.Duration
+	logger   *slog.Logger
+	now      func() time.Time
+	paused   func() bool // optional
+
+	seq      atomic.Int64
+	runs     atomic.Int64
+	failures atomic.Int64
+	slow     atomic.Int64
+
+	mu   sync.Mutex
+	last model.ProbeStats // Last* fields only
+}
+
+// Option configures a Prober.
+type Option func(*Prober)
+
+// PauseWhile skips the probes falling while paused returns true, such as
+// during maintenance, so they neither run nor count as failures.
+func PauseWhile(paused func() bool) Option {
+	return func(p *Prober) { p.paused = paused }
+}
+
+// New returns a Prober sending one order to proc per interval and
+// alerting through logger when an order fails or takes longer than slo.
+//
+// A non-positive interval defaults to 1 minute and a non-positive slo
+// to 1 second. It panics if proc or logger is nil.
+func New(proc orderProcessor, interval, slo time.Duration, logger *slog.Logger, opts ...Option) *Prober {
+	if proc == nil {
+		panic("probe.New: nil order processor")
+	}
+	if logger == nil {
+		panic("probe.New: nil logger")
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if slo <= 0 {
+		slo = time.Second
+	}
+	p := &Prober{proc: proc, interval: interval, slo: slo, logger: logger, now: time.Now}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Run sends a probe immediately and then once per interval until ctx
+// is done.
+func (p *Prober) Run(ctx context.Context) {
+	t := time.NewTicker(p.interval)
+	defer t.Stop()
+	for {
+		if p.paused == nil || !p.paused() {
+			p.probe(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// errProbeTimeout is the cancellation cause of a probe that ran past
+// the probe interval.
+var errProbeTimeout = errors.New("probe interval exceeded")
+
+// probe sends one synthetic order. It is bounded by the interval so a
+// hung pipeline cannot stack up probes.
+func (p *Prober) probe(ctx context.Context) {
+	ctx, cancel := context.WithTimeoutCause(traffic.NewContext(ctx, traffic.Synthetic), p.interval, errProbeTimeout)
+	defer cancel()
+
+	req := model.OrderRequest{
+		OrderID: fmt.Sprintf("synthetic-%d", p.seq.Add(1)),
+		Amount:  1,
+	}
+
+	start := p.now()
+	results, err := p.proc.Process(ctx, req)
+	d := p.now().Sub(start)
+	if r, ok := p.proc.(resultReleaser); ok {
+		r.Release(results)
+	}
+
+	p.runs.Add(1)
+	last := model.ProbeStats{
+		LastTime:       start.UTC().Format(time.RFC3339),
+		LastDurationMS: d.Milliseconds(),
+	}
+	switch {
+	case err != nil:
+		p.failures.Add(1)
+		last.LastError = err.Error()
+		p.logger.LogAttrs(ctx, slog.LevelError, "synthetic order failed",
+			slog.String("order_id", req.OrderID),
+			slog.Duration("duration", d),
+			slog.String("error", err.Error()),
+		)
+	case d > p.slo:
+		p.slow.Add(1)
+		p.logger.LogAttrs(ctx, slog.LevelWarn, "synthetic order exceeded SLO",
+			slog.String("order_id", req.OrderID),
+			slog.Duration("duration", d),
+			slog.Duration("slo", p.slo),
+		)
+	}
+
+	p.mu.Lock()
+	p.last = last
+	p.mu.Unlock()
+}
+
+// Stats reports probe counts and the outcome of the latest probe.
+func (p *Prober) Stats() model.ProbeStats {
+	p.mu.Lock()
+	st := p.last
+	p.mu.Unlock()
+
+	st.IntervalMS = p.interval.Milliseconds()
+	st.SLOMS = p.slo.Milliseconds()
+	st.Runs = p.runs.Load()
+	st.Failures = p.failures.Load()
+	st.Slow = p.slow.Load()
+	return st
+}
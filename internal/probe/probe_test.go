@@ -0,0 +1,141 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/traffic"
+)
+
+// fakeClock advances by step on every call.
+func fakeClock(step time.Duration) func() time.Time {
+	now := time.Unix(1_700_000_000, 0)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+type stubProcessor struct {
+	err      error
+	class    traffic.Class
+	req      model.OrderRequest
+	released int
+}
+
+func (s *stubProcessor) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	s.class = traffic.FromContext(ctx)
+	s.req = req
+	return []model.StepResult{{Name: "payment"}}, s.err
+}
+
+func (s *stubProcessor) Release([]model.StepResult) { s.released++ }
+
+func TestProbe(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		err          error
+		took         time.Duration
+		wantFailures int64
+		wantSlow     int64
+		wantLog      string
+	}{
+		{name: "ok", took: 100 * time.Millisecond},
+		{name: "at_slo", took: time.Second},
+		{name: "slow", took: 2 * time.Second, wantSlow: 1, wantLog: "level=WARN msg=\"synthetic order exceeded SLO\""},
+		{name: "failed", err: errors.New("vendor down"), took: time.Millisecond, wantFailures: 1, wantLog: "level=ERROR msg=\"synthetic order failed\""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			stub := &stubProcessor{err: tt.err}
+			p := New(stub, time.Minute, time.Second, slog.New(slog.NewTextHandler(&buf, nil)))
+			p.now = fakeClock(tt.took) // start and end are one step apart
+
+			p.probe(context.Background())
+
+			if stub.class != traffic.Synthetic {
+				t.Fatalf("expected %v traffic, got %v", traffic.Synthetic, stub.class)
+			}
+			if stub.req.OrderID != "synthetic-1" || stub.req.Amount == 0 {
+				t.Fatalf("unexpected probe request: %+v", stub.req)
+			}
+			if stub.released != 1 {
+				t.Fatalf("expected results released once, got %d", stub.released)
+			}
+
+			st := p.Stats()
+			if st.Runs != 1 || st.Failures != tt.wantFailures || st.Slow != tt.wantSlow {
+				t.Fatalf("unexpected stats: %+v", st)
+			}
+			if st.LastDurationMS != tt.took.Milliseconds() {
+				t.Fatalf("expected last_duration_ms=%d, got %d", tt.took.Milliseconds(), st.LastDurationMS)
+			}
+			if tt.err != nil && st.LastError != tt.err.Error() {
+				t.Fatalf("expected last_error %q, got %q", tt.err, st.LastError)
+			}
+
+			log := buf.String()
+			if tt.wantLog == "" && log != "" {
+				t.Fatalf("expected no alert, got %q", log)
+			}
+			if !strings.Contains(log, tt.wantLog) {
+				t.Fatalf("expected log containing %q, got %q", tt.wantLog, log)
+			}
+		})
+	}
+}
+
+func TestProbeRun(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubProcessor{}
+	p := New(stub, 10*time.Millisecond, time.Second, slog.New(slog.DiscardHandler))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+	p.Run(ctx)
+
+	if runs := p.Stats().Runs; runs < 3 {
+		t.Fatalf("expected at least 3 runs, got %d", runs)
+	}
+	if stub.req.OrderID == "synthetic-1" {
+		t.Fatalf("expected distinct order IDs per probe, got %q", stub.req.OrderID)
+	}
+}
+
+func TestProbeRun_Paused(t *testing.T) {
+	t.Parallel()
+
+	p := New(&stubProcessor{}, 5*time.Millisecond, time.Second, slog.New(slog.DiscardHandler),
+		PauseWhile(func() bool { return true }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	p.Run(ctx)
+
+	if runs := p.Stats().Runs; runs != 0 {
+		t.Fatalf("expected no runs while paused, got %d", runs)
+	}
+}
+
+func TestNewDefaults(t *testing.T) {
+	t.Parallel()
+
+	p := New(&stubProcessor{}, 0, 0, slog.New(slog.DiscardHandler))
+	if st := p.Stats(); st.IntervalMS != time.Minute.Milliseconds() || st.SLOMS != 1000 {
+		t.Fatalf("expected interval_ms=60000 slo_ms=1000, got %+v", st)
+	}
+}
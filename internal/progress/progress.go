@@ -0,0 +1,264 @@
+// Package progress collects the step results of one order while its
+// steps run, so that callers can watch the order in flight.
+//
+// The orchestrator writes every step result through a Collector rather
+// than into its result slice directly. The Collector serializes the
+// writes with readers: Snapshot can be taken at any time from any
+// goroutine, and watchers registered with Watch see each result as it
+// lands, in completion order. A caller that wants to observe an order
+// attaches a Collector to the order's context with NewContext.
+//
+// A long-running step reports that it is still making progress with
+// Heartbeat, or with KeepAlive around a long wait, through the callback
+// the orchestrator puts on the step's context with WithHeartbeat.
+package progress
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// Collector holds one order's step results as its steps finish.
+//
+// The orchestrator calls Attach with its pre-filled result slice, Set as
+// each step finishes and Finish before handing the slice to its caller.
+// After Finish the Collector keeps its own copy, so a slice returned to
+// a pool is never read through it.
+type Collector struct {
+	mu       sync.Mutex
+	results  []model.StepResult
+	done     []int // indexes of set results, in completion order
+	attached bool
+	finished bool
+	watchers []func(model.StepResult)
+	beats    []beat // indexed like results; nil before the first Beat
+}
+
+// beat is a step's latest heartbeat.
+type beat struct {
+	percent int // < 0 if none was reported
+	message string
+	at      time.Time
+}
+
+// Attach makes c collect into results, whose entries are the defaults
+// reported for steps that never finish. It reports false, leaving c
+// unchanged, if c already collects for another Process call, as when a
+// decorator runs the order twice under one context.
+func (c *Collector) Attach(results []model.StepResult) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.attached {
+		return false
+	}
+	c.results, c.attached = results, true
+	return true
+}
+
+// Set records the result of step i and passes it to the watchers. Set
+// after Finish is ignored.
+func (c *Collector) Set(i int, r model.StepResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.finished {
+		return
+	}
+	c.results[i] = r
+	c.done = append(c.done, i)
+	for _, fn := range c.watchers {
+		fn(r)
+	}
+}
+
+// Beat records a heartbeat from step i, replacing its previous one.
+// Beat after step i is set, or after Finish, is ignored.
+func (c *Collector) Beat(i, percent int, message string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.finished || slices.Contains(c.done, i) {
+		return
+	}
+	if c.beats == nil {
+		c.beats = make([]beat, len(c.results))
+	}
+	if percent > 100 {
+		percent = -1
+	}
+	c.beats[i] = beat{percent: percent, message: message, at: time.Now()}
+}
+
+// Finish ends collection and detaches c from the slice passed to
+// Attach. Steps never set keep their default result and count as done.
+func (c *Collector) Finish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.finished || !c.attached {
+		return
+	}
+	c.results = append([]model.StepResult(nil), c.results...)
+	set := c.setIndexes()
+	for i := range c.results {
+		if !set[i] {
+			c.done = append(c.done, i)
+		}
+	}
+	c.finished = true
+}
+
+// setIndexes marks the indexes in c.done.
+func (c *Collector) setIndexes() []bool {
+	set := make([]bool, len(c.results))
+	for _, i := range c.done {
+		set[i] = true
+	}
+	return set
+}
+
+// Watch registers fn to be called with each step result as it is set,
+// after the results already set. fn runs with c locked, on the step's
+// goroutine: it must be quick and must not call back into c.
+func (c *Collector) Watch(fn func(model.StepResult)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, i := range c.done {
+		fn(c.results[i])
+	}
+	c.watchers = append(c.watchers, fn)
+}
+
+// Snapshot returns copies of the finished steps' results, in completion
+// order, and the names of the steps still running, in registration
+// order. Before Attach both are empty.
+func (c *Collector) Snapshot() (done []model.StepResult, running []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	done = make([]model.StepResult, 0, len(c.done))
+	for _, i := range c.done {
+		done = append(done, c.results[i])
+	}
+	running = []string{}
+	for i, set := range c.setIndexes() {
+		if !set {
+			running = append(running, c.results[i].Name)
+		}
+	}
+	return done, running
+}
+
+// Heartbeats returns the latest heartbeat of each running step that has
+// sent one, in registration order, aged as of now.
+func (c *Collector) Heartbeats(now time.Time) []model.StepHeartbeat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.beats == nil {
+		return nil
+	}
+	var out []model.StepHeartbeat
+	for i, set := range c.setIndexes() {
+		b := c.beats[i]
+		if set || b.at.IsZero() {
+			continue
+		}
+		h := model.StepHeartbeat{Step: c.results[i].Name, Message: b.message, AgeMS: now.Sub(b.at).Milliseconds()}
+		if b.percent >= 0 {
+			h.Percent = &b.percent
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+// Finished reports whether the order's Process call has returned.
+func (c *Collector) Finished() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.finished
+}
+
+type collectorKey struct{}
+
+// NewContext returns a copy of ctx carrying c, for one order.
+func NewContext(ctx context.Context, c *Collector) context.Context {
+	return context.WithValue(ctx, collectorKey{}, c)
+}
+
+// FromContext returns the Collector in ctx, or nil.
+func FromContext(ctx context.Context) *Collector {
+	c, _ := ctx.Value(collectorKey{}).(*Collector)
+	return c
+}
+
+type heartbeatKey struct{}
+
+// heartbeat is the callback a step's Heartbeat calls.
+type heartbeat struct {
+	every time.Duration // how often KeepAlive beats
+	fn    func(percent int, message string)
+}
+
+// WithHeartbeat returns a copy of ctx, for one step, whose Heartbeat
+// calls fn. Waits under KeepAlive send a heartbeat every every.
+func WithHeartbeat(ctx context.Context, every time.Duration, fn func(percent int, message string)) context.Context {
+	return context.WithValue(ctx, heartbeatKey{}, &heartbeat{every: every, fn: fn})
+}
+
+// Heartbeat reports that the step running under ctx is still making
+// progress, with how far it has got as a percentage and a message,
+// either of which may be left out: a percent outside [0, 100], such as
+// -1, reports none. Heartbeats show in the order's progress and keep the
+// order's step idle timeout, if any, from stopping the step. Outside a
+// step it does nothing.
+func Heartbeat(ctx context.Context, percent int, message string) {
+	if hb, ok := ctx.Value(heartbeatKey{}).(*heartbeat); ok {
+		hb.fn(percent, message)
+	}
+}
+
+// KeepAlive sends a heartbeat with message, and no percentage, at the
+// interval of ctx's step until stop is called. A step calls it around a
+// wait that is slow but alive, such as queueing for a pool slot or an
+// upstream call, which the step idle timeout must not take for a hang.
+// Outside a step it does nothing.
+func KeepAlive(ctx context.Context, message string) (stop func()) {
+	hb, ok := ctx.Value(heartbeatKey{}).(*heartbeat)
+	if !ok || hb.every <= 0 {
+		return func() {}
+	}
+	k := &keepAlive{hb: hb, message: message}
+	k.mu.Lock()
+	k.timer = time.AfterFunc(hb.every, k.beat)
+	k.mu.Unlock()
+	return k.stop
+}
+
+// keepAlive is one KeepAlive call. Its timer is re-armed after each
+// beat rather than ticking, so a wait that ends before the first beat
+// costs a timer and no goroutine.
+type keepAlive struct {
+	hb      *heartbeat
+	message string
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	stopped bool
+}
+
+func (k *keepAlive) beat() {
+	k.hb.fn(-1, k.message)
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !k.stopped {
+		k.timer.Reset(k.hb.every)
+	}
+}
+
+func (k *keepAlive) stop() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.stopped = true
+	k.timer.Stop()
+}
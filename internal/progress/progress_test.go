@@ -0,0 +1,176 @@
+package progress
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func defaults(names ...string) []model.StepResult {
+	out := make([]model.StepResult, len(names))
+	for i, n := range names {
+		out[i] = model.StepResult{Name: n, Status: model.StatusCanceled}
+	}
+	return out
+}
+
+func names(rs []model.StepResult) []string {
+	var out []string
+	for _, r := range rs {
+		out = append(out, r.Name+"="+string(r.Status))
+	}
+	return out
+}
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	var c Collector
+	if done, running := c.Snapshot(); len(done) != 0 || len(running) != 0 {
+		t.Fatalf("expected an empty snapshot before Attach, got %v %v", done, running)
+	}
+	out := defaults("payment", "vendor", "courier")
+	if !c.Attach(out) || c.Attach(defaults("payment")) {
+		t.Fatal("expected only the first Attach to take")
+	}
+
+	c.Set(1, model.StepResult{Name: "vendor", Status: model.StatusOK})
+	var watched []string
+	c.Watch(func(r model.StepResult) { watched = append(watched, r.Name) }) // replays vendor
+	c.Set(0, model.StepResult{Name: "payment", Status: model.StatusError})
+
+	done, running := c.Snapshot()
+	if got, want := names(done), []string{"vendor=ok", "payment=error"}; !slices.Equal(got, want) {
+		t.Fatalf("expected done %v, got %v", want, got)
+	}
+	if !slices.Equal(running, []string{"courier"}) || !slices.Equal(watched, []string{"vendor", "payment"}) {
+		t.Fatalf("unexpected running %v or watched %v", running, watched)
+	}
+	if out[1].Status != model.StatusOK {
+		t.Fatalf("expected results written through, got %+v", out)
+	}
+
+	// After Finish the unset step counts as done with its default, and
+	// the caller's slice is no longer read or written.
+	c.Finish()
+	clear(out)
+	c.Set(2, model.StepResult{Name: "courier", Status: model.StatusOK})
+	done, running = c.Snapshot()
+	if got, want := names(done), []string{"vendor=ok", "payment=error", "courier=canceled"}; !slices.Equal(got, want) || len(running) != 0 {
+		t.Fatalf("expected done %v and nothing running, got %v %v", want, got, running)
+	}
+	if !c.Finished() || len(watched) != 2 {
+		t.Fatalf("expected finished with no further watch calls, got %v", watched)
+	}
+}
+
+// Steps setting results while readers snapshot; run with -race.
+func TestCollector_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	const steps = 64
+	var c Collector
+	c.Attach(make([]model.StepResult, steps))
+	var wg sync.WaitGroup
+	for i := range steps {
+		wg.Go(func() { c.Set(i, model.StepResult{Name: "s", Status: model.StatusOK}) })
+		wg.Go(func() { c.Snapshot() })
+	}
+	wg.Wait()
+	c.Finish()
+	if done, _ := c.Snapshot(); len(done) != steps {
+		t.Fatalf("expected %d results, got %d", steps, len(done))
+	}
+}
+
+func TestContext(t *testing.T) {
+	t.Parallel()
+
+	if FromContext(context.Background()) != nil {
+		t.Fatal("expected no collector")
+	}
+	c := new(Collector)
+	if FromContext(NewContext(context.Background(), c)) != c {
+		t.Fatal("expected the attached collector")
+	}
+}
+
+func TestCollector_Heartbeats(t *testing.T) {
+	t.Parallel()
+
+	var c Collector
+	c.Attach(defaults("payment", "vendor", "courier"))
+	now := time.Now()
+	if hs := c.Heartbeats(now); hs != nil {
+		t.Fatalf("expected no heartbeats, got %+v", hs)
+	}
+
+	c.Beat(1, 10, "")
+	c.Beat(1, 40, "menu synced")
+	c.Beat(2, -1, "waiting for a courier")
+	c.Beat(0, 50, "")
+	c.Set(0, model.StepResult{Name: "payment", Status: model.StatusOK}) // drops its heartbeat
+	c.Beat(0, 60, "")                                                   // ignored once set
+
+	hs := c.Heartbeats(now.Add(time.Second))
+	if len(hs) != 2 || hs[0].Step != "vendor" || hs[1].Step != "courier" {
+		t.Fatalf("expected vendor and courier heartbeats, got %+v", hs)
+	}
+	if hs[0].Percent == nil || *hs[0].Percent != 40 || hs[0].Message != "menu synced" || hs[0].AgeMS < 900 {
+		t.Fatalf("expected vendor's latest heartbeat, got %+v", hs[0])
+	}
+	if hs[1].Percent != nil || hs[1].Message != "waiting for a courier" {
+		t.Fatalf("expected a message without a percent, got %+v", hs[1])
+	}
+
+	c.Finish()
+	c.Beat(1, 90, "")
+	if hs := c.Heartbeats(now); len(hs) != 0 {
+		t.Fatalf("expected no heartbeats after Finish, got %+v", hs)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	Heartbeat(context.Background(), 50, "outside a step") // no callback: nothing happens
+	var got []string
+	ctx := WithHeartbeat(context.Background(), 0, func(percent int, message string) {
+		got = append(got, fmt.Sprint(percent, message))
+	})
+	Heartbeat(ctx, 50, "half")
+	if !slices.Equal(got, []string{"50half"}) {
+		t.Fatalf("unexpected heartbeats %q", got)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	t.Parallel()
+
+	KeepAlive(context.Background(), "outside a step")() // no callback: nothing happens
+
+	beats := make(chan string, 16)
+	ctx := WithHeartbeat(context.Background(), 5*time.Millisecond, func(percent int, message string) {
+		beats <- fmt.Sprint(percent, message)
+	})
+	stop := KeepAlive(ctx, "waiting")
+	for range 3 {
+		if got := <-beats; got != "-1waiting" {
+			t.Fatalf("unexpected heartbeat %q", got)
+		}
+	}
+	stop()
+	time.Sleep(20 * time.Millisecond)
+	for len(beats) > 0 { // at most one beat raced stop
+		<-beats
+	}
+	time.Sleep(20 * time.Millisecond)
+	if len(beats) != 0 {
+		t.Fatalf("expected no heartbeats after stop, got %d", len(beats))
+	}
+}
@@ -0,0 +1,82 @@
+// Package recording stores processed orders for offline replay.
+//
+// A Writer appends model.Recording values to a JSON-lines file; Read
+// loads them back for cmd/replay.
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// maxRecordingSize bounds one encoded recording when reading a file.
+const maxRecordingSize = 1 << 20
+
+// Writer appends recordings to a JSON-lines file.
+type Writer struct {
+	now func() time.Time
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+// Open opens or creates the recording file at path for appending.
+func Open(path string) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("recording: open %s: %w", path, err)
+	}
+	return &Writer{now: time.Now, f: f}, nil
+}
+
+// Record sets rec.Time and appends rec as one line.
+func (w *Writer) Record(rec model.Recording) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	rec.Time = w.now().UTC().Format(time.RFC3339Nano)
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("recording: encode: %w", err)
+	}
+	if _, err := w.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("recording: write: %w", err)
+	}
+	return nil
+}
+
+// Close closes the file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.f.Close()
+}
+
+// Read decodes every recording in r, one per line.
+func Read(r io.Reader) ([]model.Recording, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), maxRecordingSize)
+
+	var out []model.Recording
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var rec model.Recording
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("recording: line %d: %w", line, err)
+		}
+		out = append(out, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("recording: read: %w", err)
+	}
+	return out, nil
+}
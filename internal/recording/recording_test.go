@@ -0,0 +1,86 @@
+package recording
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "recordings.jsonl")
+	recs := []model.Recording{
+		{
+			Request:    model.OrderRequest{OrderID: "o-1", Amount: 100, DelayMS: map[string]int64{"courier": 5}},
+			Config:     map[string]string{"fail-at-end": "false"},
+			Status:     model.StatusOK,
+			DurationMS: 310,
+			Steps:      []model.StepResult{{Name: "payment", Status: model.StatusOK, DurationMS: 150}},
+		},
+		{
+			Request:   model.OrderRequest{OrderID: "o-2", Amount: 100, FailStep: "vendor"},
+			Status:    model.StatusError,
+			ErrorKind: "vendor_unavailable",
+			Steps:     []model.StepResult{{Name: "vendor", Status: model.StatusError, Detail: "vendor_unavailable"}},
+		},
+	}
+
+	// Two writers append to the same file, as across restarts.
+	for i, rec := range recs {
+		w, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		at := time.Unix(1_700_000_000+int64(i), 0)
+		w.now = func() time.Time { return at }
+		if err := w.Record(rec); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		recs[i].Time = at.UTC().Format(time.RFC3339Nano)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := Read(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, recs) {
+		t.Fatalf("expected %+v, got %+v", recs, got)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "malformed line", in: "{\"request\":{}}\n{not json\n", want: "line 2"},
+		{name: "unknown status", in: "{\"status\":\"maybe\"}\n", want: "line 1"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := Read(strings.NewReader(tt.in))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected an error mentioning %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
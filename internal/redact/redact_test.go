@@ -0,0 +1,102 @@
+package redact
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		spec    string
+		wantErr bool
+		want    map[string]Strategy
+	}{
+		{spec: "", want: map[string]Strategy{}},
+		{spec: "name=drop, phone=mask,order_id=hash", want: map[string]Strategy{"name": Drop, "phone": Mask, "order_id": Hash}},
+		{spec: "name", wantErr: true},
+		{spec: "=drop", wantErr: true},
+		{spec: "name=shred", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.spec, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := Parse(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %q", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(r.fields) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, r.fields)
+			}
+			for k, v := range tt.want {
+				if r.fields[k] != v {
+					t.Fatalf("expected %v, got %v", tt.want, r.fields)
+				}
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	t.Parallel()
+
+	r := New(map[string]Strategy{"name": Drop, "phone": Mask, "order_id": Hash})
+	tests := []struct {
+		field, value, want string
+	}{
+		{field: "name", value: "Ada Lovelace", want: ""},
+		{field: "phone", value: "+358401234567", want: "*********4567"},
+		{field: "phone", value: "123", want: "***"},
+		{field: "phone", value: "ääääöö", want: "**ääöö"},
+		{field: "order_id", value: "o-1", want: "sha256:5cbdcb742069a582"},
+		{field: "zone", value: "north", want: "north"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.field+"/"+tt.value, func(t *testing.T) {
+			t.Parallel()
+
+			if got := r.String(tt.field, tt.value); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	var nilR *Redactor
+	if got := nilR.String("name", "Ada"); got != "Ada" || nilR.Enabled() {
+		t.Fatalf("expected a nil Redactor to be a no-op, got %q", got)
+	}
+}
+
+func TestReplaceAttr(t *testing.T) {
+	t.Parallel()
+
+	r := New(map[string]Strategy{"name": Drop, "phone": Mask, "order_id": Hash})
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: r.ReplaceAttr}))
+
+	logger.Info("order", "order_id", "o-1", "name", "Ada", slog.Group("contact", "phone", "0401234567"), "zone", "north")
+
+	out := buf.String()
+	for _, want := range []string{"order_id=sha256:5cbdcb742069a582", "contact.phone=******4567", "zone=north"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in %q", want, out)
+		}
+	}
+	if strings.Contains(out, "Ada") || strings.Contains(out, "name=") {
+		t.Fatalf("expected name dropped, got %q", out)
+	}
+}
@@ -0,0 +1,258 @@
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	httptransport "github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/transport/http"
+)
+
+// drainPoll is how often the runner polls in-flight steps while waiting
+// for them to drain.
+const drainPoll = 50 * time.Millisecond
+
+// Runner runs scenarios against the server at BaseURL.
+type Runner struct {
+	BaseURL  string       // e.g. "http://localhost:8080"
+	AdminURL string       // serves /dashboard and /admin/*, e.g. "http://localhost:8081"; empty uses BaseURL
+	Token    string       // bearer token for -oidc-issuer servers; empty sends none
+	Secret   []byte       // signs orders for -replay-secret servers; empty sends no signature
+	Client   *http.Client // default: a client keeping a connection per concurrent order
+}
+
+// Result is the outcome of one scenario.
+type Result struct {
+	Name       string
+	Orders     int
+	Statuses   map[int]int // HTTP status code → responses
+	Errors     int         // requests with no response
+	DurationMS int64       // submitting every order
+	DrainMS    int64       // last response to zero in-flight steps; -1 if not checked
+	Failures   []string    // failed assertions
+}
+
+// Passed reports whether every request got a response and every
+// assertion held.
+func (r Result) Passed() bool { return r.Errors == 0 && len(r.Failures) == 0 }
+
+// Run submits s's orders and checks its expectations. The error reports
+// a run that could not be carried out, such as a canceled context;
+// failed assertions are in the Result.
+func (rn *Runner) Run(ctx context.Context, s Scenario) (Result, error) {
+	if err := s.Validate(); err != nil {
+		return Result{}, err
+	}
+	if rn.Client == nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.MaxIdleConnsPerHost = s.Concurrency
+		defer t.CloseIdleConnections()
+		withClient := *rn
+		withClient.Client = &http.Client{Transport: t}
+		rn = &withClient
+	}
+	prefix := s.Name + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	reqs := s.requests(prefix)
+	res := Result{Name: s.Name, Orders: len(reqs), Statuses: make(map[int]int), DrainMS: -1}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		next = make(chan int)
+	)
+	start := time.Now()
+	for range s.Concurrency {
+		wg.Go(func() {
+			for i := range next {
+				code, err := rn.submit(ctx, reqs[i])
+				mu.Lock()
+				if err != nil {
+					res.Errors++
+				} else {
+					res.Statuses[code]++
+				}
+				mu.Unlock()
+			}
+		})
+	}
+	for i := range reqs {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(next)
+	wg.Wait()
+	res.DurationMS = time.Since(start).Milliseconds()
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	if res.Errors > 0 {
+		res.Failures = append(res.Failures, fmt.Sprintf("%d of %d requests failed without a response", res.Errors, len(reqs)))
+	}
+
+	codes := make([]string, 0, len(s.Expect.Statuses))
+	for code := range s.Expect.Statuses {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		want := s.Expect.Statuses[code]
+		n, _ := strconv.Atoi(code)
+		if got := float64(res.Statuses[n]) / float64(len(reqs)); !want.contains(got) {
+			res.Failures = append(res.Failures, fmt.Sprintf("status %s: fraction %.3f outside %s", code, got, want))
+		}
+	}
+
+	if s.Expect.DrainWithin != "" {
+		within, _ := time.ParseDuration(s.Expect.DrainWithin)
+		drained, err := rn.drain(ctx, within)
+		if err != nil {
+			res.Failures = append(res.Failures, err.Error())
+		} else {
+			res.DrainMS = drained.Milliseconds()
+		}
+	}
+
+	for _, m := range s.Expect.Metrics {
+		v, err := rn.metric(ctx, m.Path, m.Field)
+		switch {
+		case err != nil:
+			res.Failures = append(res.Failures, fmt.Sprintf("metric %s %s: %v", m.Path, m.Field, err))
+		case !m.contains(v):
+			res.Failures = append(res.Failures, fmt.Sprintf("metric %s %s: %v outside %s", m.Path, m.Field, v, m.Range))
+		}
+	}
+	return res, ctx.Err()
+}
+
+// submit posts req to /order with fresh replay-protection headers and
+// returns the response status code.
+func (rn *Runner) submit(ctx context.Context, req model.OrderRequest) (int, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, rn.BaseURL+"/order", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	n, ts := nonce(), strconv.FormatInt(time.Now().Unix(), 10)
+	r.Header.Set(httptransport.HeaderNonce, n)
+	r.Header.Set(httptransport.HeaderTimestamp, ts)
+	if len(rn.Secret) > 0 {
+		r.Header.Set(httptransport.HeaderSignature, httptransport.SignRequest(rn.Secret, n, ts, body))
+	}
+	resp, err := rn.do(r)
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// drain polls /dashboard/data until in-flight steps reach zero and
+// returns how long that took, failing after within.
+func (rn *Runner) drain(ctx context.Context, within time.Duration) (time.Duration, error) {
+	start := time.Now()
+	for {
+		v, err := rn.metric(ctx, "/dashboard/data", "in_flight_steps")
+		if err != nil {
+			return 0, fmt.Errorf("drain: %w", err)
+		}
+		if v == 0 {
+			return time.Since(start), nil
+		}
+		if time.Since(start) >= within {
+			return 0, fmt.Errorf("drain: %v steps still in flight after %s", v, within)
+		}
+		select {
+		case <-time.After(drainPoll):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// metric fetches path and returns the number at the dot-separated field.
+func (rn *Runner) metric(ctx context.Context, path, field string) (float64, error) {
+	base := rn.BaseURL
+	if rn.AdminURL != "" && (strings.HasPrefix(path, "/dashboard") || strings.HasPrefix(path, "/admin/")) {
+		base = rn.AdminURL
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, base+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := rn.do(r)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("answered %d", resp.StatusCode)
+	}
+	var doc any
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return 0, err
+	}
+	return lookup(doc, field)
+}
+
+// lookup walks the dot-separated field through decoded JSON; array
+// elements are selected by index.
+func lookup(doc any, field string) (float64, error) {
+	v := doc
+	for _, key := range strings.Split(field, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = node[key]; !ok {
+				return 0, fmt.Errorf("no field %q", key)
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return 0, fmt.Errorf("no element %q", key)
+			}
+			v = node[i]
+		default:
+			return 0, fmt.Errorf("%q is not an object or array", key)
+		}
+	}
+	n, ok := v.(float64)
+	if !ok {
+		return 0, fmt.Errorf("%v is not a number", v)
+	}
+	return n, nil
+}
+
+func (rn *Runner) do(r *http.Request) (*http.Response, error) {
+	if rn.Token != "" {
+		r.Header.Set("Authorization", "Bearer "+rn.Token)
+	}
+	return rn.Client.Do(r)
+}
+
+// nonceBase and nonceSeq make replay-protection nonces unique to this
+// process run.
+var (
+	nonceBase = strconv.FormatInt(time.Now().UnixNano(), 36)
+	nonceSeq  atomic.Uint64
+)
+
+func nonce() string {
+	return nonceBase + "-" + strconv.FormatUint(nonceSeq.Add(1), 36)
+}
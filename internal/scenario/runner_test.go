@@ -0,0 +1,191 @@
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	httptransport "github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/transport/http"
+)
+
+// fakeServer answers /order by the failing step, as the pipeline does,
+// and reports in-flight steps that drain after inFlightPolls polls.
+func fakeServer(t *testing.T, inFlightPolls int64) *httptest.Server {
+	t.Helper()
+	var polls atomic.Int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/order", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(httptransport.HeaderNonce) == "" || r.Header.Get(httptransport.HeaderTimestamp) == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req model.OrderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch req.FailStep {
+		case "payment":
+			w.WriteHeader(http.StatusBadRequest)
+		case "vendor", "courier":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	mux.HandleFunc("/dashboard/data", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"in_flight_steps": max(inFlightPolls-polls.Add(1), 0),
+			"pool":            map[string]any{"in_use": 0},
+			"statuses":        []any{map[string]any{"ok": 3}},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func ptr(v float64) *float64 { return &v }
+
+func TestRunner_Run(t *testing.T) {
+	t.Parallel()
+
+	mixed := Scenario{
+		Name:        "mixed",
+		Orders:      200,
+		Concurrency: 8,
+		Failures:    map[string]float64{"payment": 0.2, "vendor": 0.1, "courier": 0.1},
+	}
+
+	tests := []struct {
+		name         string
+		expect       Expect
+		polls        int64
+		adminURL     bool // serve /dashboard/data only on a separate admin server
+		wantFailures []string
+	}{
+		{
+			name: "passes",
+			expect: Expect{
+				Statuses:    map[string]Range{"200": {Min: ptr(0.6), Max: ptr(0.6)}, "400": {Min: ptr(0.2)}, "503": {Max: ptr(0.2)}},
+				DrainWithin: "2s",
+				Metrics: []Metric{
+					{Path: "/dashboard/data", Field: "pool.in_use", Range: Range{Max: ptr(0)}},
+					{Path: "/dashboard/data", Field: "statuses.0.ok", Range: Range{Min: ptr(3)}},
+				},
+			},
+			polls: 3,
+		},
+		{
+			name: "admin_url",
+			expect: Expect{
+				DrainWithin: "2s",
+				Metrics: []Metric{
+					{Path: "/dashboard/data", Field: "pool.in_use", Range: Range{Max: ptr(0)}},
+				},
+			},
+			polls:    3,
+			adminURL: true,
+		},
+		{
+			name: "assertions_fail",
+			expect: Expect{
+				Statuses:    map[string]Range{"200": {Min: ptr(0.9)}},
+				DrainWithin: "1ms",
+				Metrics: []Metric{
+					{Path: "/dashboard/data", Field: "pool.in_use", Range: Range{Min: ptr(1)}},
+					{Path: "/dashboard/data", Field: "pool.missing"},
+					{Path: "/capacity", Field: "in_use"},
+				},
+			},
+			polls: 1000,
+			wantFailures: []string{
+				"status 200: fraction 0.600 outside [0.9, +inf]",
+				"drain:",
+				"pool.in_use: 0 outside [1, +inf]",
+				`no field "missing"`,
+				"answered 404",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := fakeServer(t, tt.polls)
+			rn := &Runner{BaseURL: srv.URL}
+			if tt.adminURL {
+				public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if strings.HasPrefix(r.URL.Path, "/dashboard") {
+						http.NotFound(w, r)
+						return
+					}
+					srv.Config.Handler.ServeHTTP(w, r)
+				}))
+				t.Cleanup(public.Close)
+				rn = &Runner{BaseURL: public.URL, AdminURL: srv.URL}
+			}
+			s := mixed
+			s.Expect = tt.expect
+			res, err := rn.Run(context.Background(), s)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.Statuses[200] != 120 || res.Statuses[400] != 40 || res.Statuses[503] != 40 || res.Errors != 0 {
+				t.Fatalf("unexpected statuses %v, %d errors", res.Statuses, res.Errors)
+			}
+			if res.Passed() != (len(tt.wantFailures) == 0) || len(res.Failures) != len(tt.wantFailures) {
+				t.Fatalf("expected failures %q, got %q", tt.wantFailures, res.Failures)
+			}
+			for i, want := range tt.wantFailures {
+				if !strings.Contains(res.Failures[i], want) {
+					t.Fatalf("failure %d: expected %q, got %q", i, want, res.Failures[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRunner_Unreachable(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	res, err := (&Runner{BaseURL: srv.URL}).Run(context.Background(), Scenario{Name: "down", Orders: 5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Errors != 5 || res.Passed() {
+		t.Fatalf("expected every request to fail, got %+v", res)
+	}
+}
+
+func TestRunner_Secret(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("s3cret")
+	guard := httptransport.NewReplayGuard(0, 0, 0, 0, secret)
+	srv := httptest.NewServer(guard.Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		name   string
+		secret []byte
+		want   int
+	}{
+		{name: "signed", secret: secret, want: http.StatusOK},
+		{name: "unsigned", want: http.StatusUnauthorized},
+	} {
+		res, err := (&Runner{BaseURL: srv.URL, Secret: tt.secret}).Run(context.Background(), Scenario{Name: tt.name, Orders: 3})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if res.Statuses[tt.want] != 3 {
+			t.Fatalf("%s: expected 3 responses with %d, got %v", tt.name, tt.want, res.Statuses)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Package scenario runs declarative load scenarios against a running
+// server and checks the outcome.
+//
+// A Scenario submits a number of orders at a given concurrency, with a
+// given fraction failing at each step, then asserts the spread of HTTP
+// statuses, that in-flight steps drain to zero, and numeric fields of
+// JSON endpoints such as /dashboard/data. Scenarios are read from a JSON
+// file, so a stress run is data rather than test code.
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// ErrInvalid is wrapped by errors for malformed scenarios.
+var ErrInvalid = errors.New("invalid scenario")
+
+// Scenario describes one run.
+type Scenario struct {
+	Name        string             `json:"name"`
+	Orders      int                `json:"orders"`
+	Concurrency int                `json:"concurrency"` // default 10
+	Amount      uint64             `json:"amount"`      // default 1200
+	DelayMS     map[string]int64   `json:"delay_ms"`    // simulated step latencies, as in POST /order
+	Failures    map[string]float64 `json:"failures"`    // step → fraction of orders failing there
+	Seed        uint64             `json:"seed"`        // shuffles which orders fail
+	Expect      Expect             `json:"expect"`
+}
+
+// Expect holds a scenario's assertions.
+type Expect struct {
+	// Statuses bounds the fraction of responses with each HTTP status
+	// code, e.g. "503": {"min": 0.1, "max": 0.2}.
+	Statuses map[string]Range `json:"statuses"`
+	// DrainWithin is how long in-flight steps, as reported by
+	// /dashboard/data, may take to reach zero after the last response,
+	// e.g. "5s"; empty skips the check.
+	DrainWithin string `json:"drain_within"`
+	// Metrics are checked after the drain.
+	Metrics []Metric `json:"metrics"`
+}
+
+// Range bounds a value; a nil bound is open.
+type Range struct {
+	Min *float64 `json:"min"`
+	Max *float64 `json:"max"`
+}
+
+// contains reports whether v is within r.
+func (r Range) contains(v float64) bool {
+	return (r.Min == nil || v >= *r.Min) && (r.Max == nil || v <= *r.Max)
+}
+
+func (r Range) String() string {
+	bound := func(p *float64, open string) string {
+		if p == nil {
+			return open
+		}
+		return fmt.Sprint(*p)
+	}
+	return "[" + bound(r.Min, "-inf") + ", " + bound(r.Max, "+inf") + "]"
+}
+
+// Metric asserts a number in a JSON endpoint's answer.
+type Metric struct {
+	Path  string `json:"path"`  // e.g. "/dashboard/data"
+	Field string `json:"field"` // dot path, array indexes as numbers, e.g. "pool.in_use"
+	Range
+}
+
+// Load reads a JSON array of scenarios from r and validates each.
+func Load(r io.Reader) ([]Scenario, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var scs []Scenario
+	if err := dec.Decode(&scs); err != nil {
+		return nil, fmt.Errorf("scenario: %w", err)
+	}
+	for i := range scs {
+		if err := scs[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return scs, nil
+}
+
+// Validate checks s and fills in its defaults.
+func (s *Scenario) Validate() error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("scenario %q: %w: %s", s.Name, ErrInvalid, fmt.Sprintf(format, args...))
+	}
+	if s.Name == "" {
+		return invalid("name is required")
+	}
+	if s.Orders <= 0 {
+		return invalid("orders must be > 0")
+	}
+	if s.Concurrency < 0 {
+		return invalid("concurrency must be >= 0")
+	}
+	if s.Concurrency == 0 {
+		s.Concurrency = 10
+	}
+	if s.Amount == 0 {
+		s.Amount = 1200
+	}
+	total := 0.0
+	for step, f := range s.Failures {
+		if f < 0 || f > 1 {
+			return invalid("failure fraction for %s must be within [0, 1]", step)
+		}
+		total += f
+	}
+	if total > 1 {
+		return invalid("failure fractions add up to more than 1")
+	}
+	for code := range s.Expect.Statuses {
+		if len(code) != 3 || strings.Trim(code, "0123456789") != "" {
+			return invalid("status %q is not an HTTP status code", code)
+		}
+	}
+	if d := s.Expect.DrainWithin; d != "" {
+		if _, err := time.ParseDuration(d); err != nil {
+			return invalid("drain_within: %v", err)
+		}
+	}
+	for _, m := range s.Expect.Metrics {
+		if !strings.HasPrefix(m.Path, "/") || m.Field == "" {
+			return invalid("metric needs an absolute path and a field")
+		}
+	}
+	return nil
+}
+
+// requests returns the orders s submits, prefixing their IDs with
+// prefix. Each step fails on round(fraction × orders) of them, spread by
+// a shuffle seeded with s.Seed.
+func (s *Scenario) requests(prefix string) []model.OrderRequest {
+	fail := make([]string, 0, s.Orders)
+	steps := make([]string, 0, len(s.Failures))
+	for step := range s.Failures {
+		steps = append(steps, step)
+	}
+	slices.Sort(steps)
+	for _, step := range steps {
+		n := int(math.Round(s.Failures[step] * float64(s.Orders)))
+		for i := 0; i < n && len(fail) < s.Orders; i++ {
+			fail = append(fail, step)
+		}
+	}
+	fail = fail[:s.Orders] // the rest succeed
+	rand.New(rand.NewPCG(s.Seed, s.Seed)).Shuffle(len(fail), func(i, j int) {
+		fail[i], fail[j] = fail[j], fail[i]
+	})
+
+	reqs := make([]model.OrderRequest, s.Orders)
+	for i := range reqs {
+		reqs[i] = model.OrderRequest{
+			OrderID:  fmt.Sprintf("%s-%d", prefix, i),
+			Amount:   s.Amount,
+			DelayMS:  s.DelayMS,
+			FailStep: fail[i],
+		}
+	}
+	return reqs
+}
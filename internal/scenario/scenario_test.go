@@ -0,0 +1,78 @@
+package scenario
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{name: "valid", file: `[{"name":"mixed","orders":10,"failures":{"payment":0.5},"expect":{"statuses":{"200":{"min":0.5}},"drain_within":"1s"}}]`},
+		{name: "unknown_field", file: `[{"name":"mixed","orders":10,"workers":4}]`, wantErr: "unknown field"},
+		{name: "no_name", file: `[{"orders":10}]`, wantErr: "name is required"},
+		{name: "no_orders", file: `[{"name":"mixed"}]`, wantErr: "orders must be > 0"},
+		{name: "fraction_range", file: `[{"name":"mixed","orders":10,"failures":{"payment":1.5}}]`, wantErr: "within [0, 1]"},
+		{name: "fraction_sum", file: `[{"name":"mixed","orders":10,"failures":{"payment":0.6,"vendor":0.6}}]`, wantErr: "more than 1"},
+		{name: "bad_status", file: `[{"name":"mixed","orders":10,"expect":{"statuses":{"ok":{}}}}]`, wantErr: "not an HTTP status code"},
+		{name: "bad_drain", file: `[{"name":"mixed","orders":10,"expect":{"drain_within":"soon"}}]`, wantErr: "drain_within"},
+		{name: "relative_metric", file: `[{"name":"mixed","orders":10,"expect":{"metrics":[{"path":"capacity","field":"in_use"}]}}]`, wantErr: "absolute path"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			scs, err := Load(strings.NewReader(tt.file))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if scs[0].Concurrency != 10 || scs[0].Amount != 1200 {
+					t.Fatalf("expected defaults filled in, got %+v", scs[0])
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if tt.name != "unknown_field" && !errors.Is(err, ErrInvalid) {
+				t.Fatalf("expected ErrInvalid, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRequests(t *testing.T) {
+	t.Parallel()
+
+	s := Scenario{Name: "mixed", Orders: 100, Failures: map[string]float64{"payment": 0.2, "courier": 0.05}, Seed: 7}
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	reqs := s.requests("run")
+	counts := map[string]int{}
+	for _, r := range reqs {
+		counts[r.FailStep]++
+	}
+	if counts["payment"] != 20 || counts["courier"] != 5 || counts[""] != 75 {
+		t.Fatalf("unexpected failure mix %v", counts)
+	}
+	if reqs[0].OrderID != "run-0" || reqs[99].OrderID != "run-99" || reqs[0].Amount != 1200 {
+		t.Fatalf("unexpected orders %+v, %+v", reqs[0], reqs[99])
+	}
+
+	// The same seed fails the same orders.
+	again := s.requests("run")
+	for i := range reqs {
+		if reqs[i].FailStep != again[i].FailStep {
+			t.Fatalf("order %d: expected %q, got %q", i, reqs[i].FailStep, again[i].FailStep)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Package cost attributes the estimated spend of step executions.
+//
+// Each step is tagged with the resource it calls (provider and region)
+// and an estimated cost per call. A Ledger wraps the steps and adds up
+// calls and cost per tenant, step and resource, so spend can be
+// reported per tenant.
+package cost
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// Unattributed is the tenant of calls made without a known tenant.
+const Unattributed = "unattributed"
+
+// Rate tags a step with the resource it calls and its estimated cost.
+type Rate struct {
+	Provider   string
+	Region     string
+	CostMicros int64 // estimated cost of one call, in millionths of the currency unit
+}
+
+// ParseRates parses a comma-separated list of step costs of the form
+//
+//	step=provider:region:cost_micros
+//
+// for example "payment=stripe:eu-west:2500,courier=fleet:eu-west:1200".
+func ParseRates(spec string) (map[string]Rate, error) {
+	out := map[string]Rate{}
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		step, tags, ok := strings.Cut(entry, "=")
+		parts := strings.Split(tags, ":")
+		if !ok || step == "" || len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("cost: rate %q: want step=provider:region:cost_micros", entry)
+		}
+		micros, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil || micros < 0 {
+			return nil, fmt.Errorf("cost: rate %q: cost must be a non-negative integer", entry)
+		}
+		out[step] = Rate{Provider: parts[0], Region: parts[1], CostMicros: micros}
+	}
+	return out, nil
+}
+
+// lineKey identifies one line of the cost report.
+type lineKey struct {
+	tenant, step, provider, region string
+}
+
+// Ledger accumulates step calls and their estimated cost. It is safe for
+// concurrent use.
+type Ledger struct {
+	tenant func(context.Context) string
+
+	mu    sync.Mutex
+	lines map[lineKey]*model.CostLine
+}
+
+// NewLedger returns an empty Ledger attributing calls to the tenant
+// returned by tenant for the step's context. Calls whose tenant is ""
+// are attributed to Unattributed.
+//
+// It panics if tenant is nil.
+func NewLedger(tenant func(context.Context) string) *Ledger {
+	if tenant == nil {
+		panic("cost.NewLedger: nil tenant func")
+	}
+	return &Ledger{tenant: tenant, lines: make(map[lineKey]*model.CostLine)}
+}
+
+// Wrap returns run charging one call at rate to the ledger each time it
+// executes, whatever its outcome: providers bill for failed calls too.
+func (l *Ledger) Wrap(step string, rate Rate, run func(context.Context, model.OrderRequest) error) func(context.Context, model.OrderRequest) error {
+	return func(ctx context.Context, req model.OrderRequest) error {
+		err := run(ctx, req)
+		l.charge(ctx, step, rate)
+		return err
+	}
+}
+
+func (l *Ledger) charge(ctx context.Context, step string, rate Rate) {
+	tenant := l.tenant(ctx)
+	if tenant == "" {
+		tenant = Unattributed
+	}
+	k := lineKey{tenant: tenant, step: step, provider: rate.Provider, region: rate.Region}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	line := l.lines[k]
+	if line == nil {
+		line = &model.CostLine{Tenant: tenant, Step: step, Provider: rate.Provider, Region: rate.Region}
+		l.lines[k] = line
+	}
+	line.Calls++
+	line.CostMicros += rate.CostMicros
+}
+
+// Report returns the accumulated lines for tenant, or for every tenant
+// if tenant is "", ordered by tenant, step, provider and region.
+func (l *Ledger) Report(tenant string) model.CostReport {
+	l.mu.Lock()
+	out := model.CostReport{Lines: []model.CostLine{}}
+	for _, line := range l.lines {
+		if tenant == "" || line.Tenant == tenant {
+			out.Lines = append(out.Lines, *line)
+			out.TotalMicros += line.CostMicros
+		}
+	}
+	l.mu.Unlock()
+
+	slices.SortFunc(out.Lines, func(a, b model.CostLine) int {
+		return cmp.Or(
+			cmp.Compare(a.Tenant, b.Tenant),
+			cmp.Compare(a.Step, b.Step),
+			cmp.Compare(a.Provider, b.Provider),
+			cmp.Compare(a.Region, b.Region),
+		)
+	})
+	return out
+}
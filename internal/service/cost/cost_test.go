@@ -0,0 +1,111 @@
+package cost
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestParseRates(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseRates("payment=stripe:eu-west:2500, courier=fleet:eu-west:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (Rate{Provider: "stripe", Region: "eu-west", CostMicros: 2500}); got["payment"] != want {
+		t.Fatalf("expected %+v, got %+v", want, got["payment"])
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 rates, got %+v", got)
+	}
+
+	for _, spec := range []string{"payment", "=stripe:eu:1", "payment=stripe:eu", "payment=:eu:1", "payment=stripe::1", "payment=stripe:eu:x", "payment=stripe:eu:-1"} {
+		if _, err := ParseRates(spec); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
+
+type tenantKey struct{}
+
+func TestLedger(t *testing.T) {
+	t.Parallel()
+
+	l := NewLedger(func(ctx context.Context) string {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		return tenant
+	})
+	stripe := Rate{Provider: "stripe", Region: "eu-west", CostMicros: 2500}
+	fleet := Rate{Provider: "fleet", Region: "eu-west", CostMicros: 1200}
+	errDeclined := errors.New("declined")
+	pay := l.Wrap("payment", stripe, func(context.Context, model.OrderRequest) error { return errDeclined })
+	assign := l.Wrap("courier", fleet, func(context.Context, model.OrderRequest) error { return nil })
+
+	acme := context.WithValue(context.Background(), tenantKey{}, "acme")
+	if err := pay(acme, model.OrderRequest{}); err != errDeclined {
+		t.Fatalf("expected %v, got %v", errDeclined, err)
+	}
+	_ = pay(acme, model.OrderRequest{})
+	_ = assign(acme, model.OrderRequest{})
+	_ = assign(context.Background(), model.OrderRequest{})
+
+	tests := []struct {
+		name      string
+		tenant    string
+		wantLines []model.CostLine
+		wantTotal int64
+	}{
+		{
+			name:   "all_tenants",
+			tenant: "",
+			wantLines: []model.CostLine{
+				{Tenant: "acme", Step: "courier", Provider: "fleet", Region: "eu-west", Calls: 1, CostMicros: 1200},
+				{Tenant: "acme", Step: "payment", Provider: "stripe", Region: "eu-west", Calls: 2, CostMicros: 5000},
+				{Tenant: Unattributed, Step: "courier", Provider: "fleet", Region: "eu-west", Calls: 1, CostMicros: 1200},
+			},
+			wantTotal: 7400,
+		},
+		{
+			name:      "one_tenant",
+			tenant:    Unattributed,
+			wantLines: []model.CostLine{{Tenant: Unattributed, Step: "courier", Provider: "fleet", Region: "eu-west", Calls: 1, CostMicros: 1200}},
+			wantTotal: 1200,
+		},
+		{
+			name:      "unknown_tenant",
+			tenant:    "globex",
+			wantLines: []model.CostLine{},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := l.Report(tt.tenant)
+			if got.TotalMicros != tt.wantTotal || len(got.Lines) != len(tt.wantLines) {
+				t.Fatalf("expected total %d over %d lines, got %+v", tt.wantTotal, len(tt.wantLines), got)
+			}
+			for i, line := range got.Lines {
+				if line != tt.wantLines[i] {
+					t.Fatalf("line %d: expected %+v, got %+v", i, tt.wantLines[i], line)
+				}
+			}
+		})
+	}
+}
+
+func TestNewLedger_NilTenantPanics(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected panic for nil tenant func")
+		}
+	}()
+	NewLedger(nil)
+}
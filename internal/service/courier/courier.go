@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/shared"
 	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/tracker"
 )
 
@@ -43,7 +44,7 @@ func Assign(ctx context.Context, req model.OrderRequest, l limiter, tr *tracker.
 	const stepName = "courier"
 
 	// Assign provided delay time or use default value
-	delay := resolveStepDelay(req.DelayMS, stepName, 100*time.Millisecond)
+	delay := req.StepDelay(stepName, 100*time.Millisecond)
 
 	if err := l.Acquire(ctx); err != nil {
 		return err
@@ -51,7 +52,7 @@ func Assign(ctx context.Context, req model.OrderRequest, l limiter, tr *tracker.
 	defer l.Release()
 
 	// Block step until the delay elapses or the context is done
-	if err := waitOrCancel(ctx, delay); err != nil {
+	if err := shared.SleepOrDone(ctx, delay); err != nil {
 		return err
 	}
 
@@ -62,36 +63,3 @@ func Assign(ctx context.Context, req model.OrderRequest, l limiter, tr *tracker.
 
 	return nil
 }
-
-// resolveStepDelay returns the effective delay for a step.
-//
-// If delayMS contains a positive value for the given step (in milliseconds),
-// that value is used. Otherwise, defaultDelay is returned.
-func resolveStepDelay(delayMS map[string]int64, step string, defaultDelay time.Duration) time.Duration {
-	if delayMS == nil {
-		return defaultDelay
-	}
-	if ms, ok := delayMS[step]; ok && ms > 0 {
-		return time.Duration(ms) * time.Millisecond
-	}
-	return defaultDelay
-}
-
-// waitOrCancel blocks for d or until ctx is canceled.
-//
-// It returns nil if the duration elapses, or ctx.Err() if the context
-// is done first. If d <= 0, it returns immediately.
-func waitOrCancel(ctx context.Context, d time.Duration) error {
-	if d <= 0 {
-		return nil
-	}
-	t := time.NewTimer(d)
-	defer t.Stop()
-
-	select {
-	case <-t.C:
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
-	}
-}
@@ -0,0 +1,170 @@
+package courier
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/pool"
+)
+
+// DefaultZone names the pool used for orders without a known zone.
+const DefaultZone = "default"
+
+// Zone configures one delivery zone's courier pool.
+type Zone struct {
+	Name     string
+	Size     int
+	Adjacent []string // zones to borrow couriers from when saturated, in order
+}
+
+// ParseZones parses a comma-separated list of zones of the form
+//
+//	name=size[:adjacent+adjacent...]
+//
+// for example "north=5:center,center=8:north+south,south=3:center".
+func ParseZones(spec string) ([]Zone, error) {
+	var zones []Zone
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, rest, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("courier: zone %q: want name=size", entry)
+		}
+		size, adj, _ := strings.Cut(rest, ":")
+		n, err := strconv.Atoi(size)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("courier: zone %q: invalid size %q", entry, size)
+		}
+		z := Zone{Name: name, Size: n}
+		if adj != "" {
+			z.Adjacent = strings.Split(adj, "+")
+		}
+		zones = append(zones, z)
+	}
+	return zones, nil
+}
+
+// Zones routes courier assignments to per-zone pools.
+//
+// An order is served by its zone's pool. When that pool is saturated, a
+// free slot in an adjacent zone is borrowed instead; if none is free the
+// order waits on its own zone. Orders with no or an unknown zone use
+// the default pool.
+type Zones struct {
+	byName map[string]*zone
+	order  []*zone // default first, then configuration order
+}
+
+type zone struct {
+	name     string
+	pool     *pool.Pool
+	adjacent []*zone
+	assigned atomic.Int64 // slots taken in this zone for its own orders
+	lent     atomic.Int64 // slots taken in this zone for other zones' orders
+}
+
+// NewZones returns Zones with fallback as the default pool and one new
+// pool per configured zone.
+//
+// It panics if fallback is nil, a zone is named DefaultZone or declared
+// twice, or an adjacent zone is unknown.
+func NewZones(fallback *pool.Pool, zones []Zone) *Zones {
+	if fallback == nil {
+		panic("courier.NewZones: nil default pool")
+	}
+	def := &zone{name: DefaultZone, pool: fallback}
+	z := &Zones{byName: map[string]*zone{DefaultZone: def}, order: []*zone{def}}
+	for _, cfg := range zones {
+		if _, dup := z.byName[cfg.Name]; dup {
+			panic("courier.NewZones: duplicate zone " + cfg.Name)
+		}
+		zn := &zone{name: cfg.Name, pool: pool.New(cfg.Size)}
+		z.byName[cfg.Name] = zn
+		z.order = append(z.order, zn)
+	}
+	for _, cfg := range zones {
+		zn := z.byName[cfg.Name]
+		for _, name := range cfg.Adjacent {
+			adj, ok := z.byName[name]
+			if !ok || adj == zn {
+				panic("courier.NewZones: invalid adjacent zone " + name + " for " + cfg.Name)
+			}
+			zn.adjacent = append(zn.adjacent, adj)
+		}
+	}
+	return z
+}
+
+// For returns a limiter for one assignment in the named zone.
+func (z *Zones) For(name string) *ZoneLease {
+	home, ok := z.byName[name]
+	if !ok {
+		home = z.byName[DefaultZone]
+	}
+	return &ZoneLease{home: home}
+}
+
+// Stats reports capacity and assignment counts per zone.
+func (z *Zones) Stats() []model.CourierZone {
+	out := make([]model.CourierZone, len(z.order))
+	for i, zn := range z.order {
+		out[i] = model.CourierZone{
+			Name:     zn.name,
+			Capacity: zn.pool.Cap(),
+			InUse:    zn.pool.InUse(),
+			Waiting:  zn.pool.Waiting(),
+			Assigned: zn.assigned.Load(),
+			Lent:     zn.lent.Load(),
+		}
+	}
+	return out
+}
+
+// ZoneLease acquires a courier slot for one order and remembers which
+// zone's pool it came from. It is not safe for concurrent use.
+type ZoneLease struct {
+	home *zone
+	held *zone
+}
+
+// Acquire takes a slot in the home zone, borrows one from the first
+// adjacent zone with a free slot, or waits for the home zone.
+func (l *ZoneLease) Acquire(ctx context.Context) error {
+	if l.home.pool.TryAcquire() {
+		l.hold(l.home)
+		return nil
+	}
+	for _, adj := range l.home.adjacent {
+		if adj.pool.TryAcquire() {
+			l.hold(adj)
+			return nil
+		}
+	}
+	if err := l.home.pool.Acquire(ctx); err != nil {
+		return err
+	}
+	l.hold(l.home)
+	return nil
+}
+
+func (l *ZoneLease) hold(zn *zone) {
+	l.held = zn
+	if zn == l.home {
+		zn.assigned.Add(1)
+	} else {
+		zn.lent.Add(1)
+	}
+}
+
+// Release frees the slot taken by Acquire.
+func (l *ZoneLease) Release() {
+	l.held.pool.Release()
+	l.held = nil
+}
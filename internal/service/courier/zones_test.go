@@ -0,0 +1,137 @@
+package courier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/pool"
+)
+
+func TestParseZones(t *testing.T) {
+	t.Parallel()
+
+	zones, err := ParseZones("north=5:center, center=8:north+south,south=3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(zones) != 3 {
+		t.Fatalf("expected 3 zones, got %+v", zones)
+	}
+	if zones[1].Name != "center" || zones[1].Size != 8 || len(zones[1].Adjacent) != 2 || zones[1].Adjacent[1] != "south" {
+		t.Fatalf("unexpected center zone: %+v", zones[1])
+	}
+	if zones[2].Adjacent != nil {
+		t.Fatalf("expected no adjacent zones for south, got %v", zones[2].Adjacent)
+	}
+
+	for _, spec := range []string{"north", "=5", "north=0", "north=x:center"} {
+		if _, err := ParseZones(spec); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestNewZones_InvalidPanics(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		zones []Zone
+	}{
+		{name: "default_name", zones: []Zone{{Name: DefaultZone, Size: 1}}},
+		{name: "duplicate", zones: []Zone{{Name: "a", Size: 1}, {Name: "a", Size: 2}}},
+		{name: "unknown_adjacent", zones: []Zone{{Name: "a", Size: 1, Adjacent: []string{"b"}}}},
+		{name: "self_adjacent", zones: []Zone{{Name: "a", Size: 1, Adjacent: []string{"a"}}}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			NewZones(pool.New(1), tt.zones)
+		})
+	}
+}
+
+// A saturated zone borrows from its first free neighbor, and waits on
+// its own pool once every neighbor is full.
+func TestZones_FallbackToAdjacent(t *testing.T) {
+	t.Parallel()
+
+	z := NewZones(pool.New(1), []Zone{
+		{Name: "north", Size: 1, Adjacent: []string{"center"}},
+		{Name: "center", Size: 1},
+	})
+	ctx := context.Background()
+
+	own := z.For("north")
+	if err := own.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	borrowed := z.For("north")
+	if err := borrowed.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := z.Stats()
+	north, center := stats[1], stats[2]
+	if north.Assigned != 1 || north.InUse != 1 || center.Lent != 1 || center.InUse != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	// Both pools are full: the third order waits on north.
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := z.For("north").Acquire(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	borrowed.Release()
+	own.Release()
+	for _, s := range z.Stats() {
+		if s.InUse != 0 {
+			t.Fatalf("zone %s: expected in_use=0, got %d", s.Name, s.InUse)
+		}
+	}
+}
+
+func TestZones_UnknownZoneUsesDefault(t *testing.T) {
+	t.Parallel()
+
+	z := NewZones(pool.New(2), []Zone{{Name: "north", Size: 1}})
+
+	for _, name := range []string{"", "atlantis"} {
+		l := z.For(name)
+		if err := l.Acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		defer l.Release()
+	}
+
+	def := z.Stats()[0]
+	if def.Name != DefaultZone || def.InUse != 2 || def.Assigned != 2 {
+		t.Fatalf("unexpected default zone stats: %+v", def)
+	}
+}
+
+func TestAssign_Zone(t *testing.T) {
+	t.Parallel()
+
+	z := NewZones(pool.New(1), []Zone{{Name: "south", Size: 2}})
+	req := model.OrderRequest{OrderID: "o-9", Amount: 800, Zone: "south", DelayMS: map[string]int64{"courier": 1}}
+
+	if err := Assign(context.Background(), req, z.For(req.Zone), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if south := z.Stats()[1]; south.Assigned != 1 || south.InUse != 0 {
+		t.Fatalf("unexpected south stats: %+v", south)
+	}
+}
@@ -0,0 +1,263 @@
+// Package geocode validates delivery addresses and resolves them to
+// coordinates.
+//
+// A Resolver geocodes an order's address ahead of courier assignment and,
+// for orders without a zone, picks the delivery zone whose center is
+// nearest. Providers are pluggable; Fake resolves addresses in process,
+// Cache remembers the answers for repeated addresses, and Memoized
+// shares one lookup among the steps of an order.
+package geocode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/memo"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/shared"
+)
+
+type badAddressError struct{}
+
+func (badAddressError) Error() string { return "bad address" }
+func (badAddressError) Kind() string  { return "bad_address" }
+
+// ErrBadAddress is returned when an address cannot be resolved to a
+// location.
+var ErrBadAddress = badAddressError{}
+
+// Location is a point in decimal degrees.
+type Location struct {
+	Lat, Lon float64
+}
+
+// Provider resolves an address to its location.
+type Provider interface {
+	Geocode(ctx context.Context, address string) (Location, error)
+}
+
+// Fake is an in-process Provider. It accepts addresses with a house
+// number and a street name and places each one at a stable,
+// hash-derived point within Radius degrees of Center.
+//
+// It simulates a lookup taking Delay and respects context cancellation.
+type Fake struct {
+	Center Location
+	Radius float64
+	Delay  time.Duration
+}
+
+// Geocode implements Provider.
+func (f Fake) Geocode(ctx context.Context, address string) (Location, error) {
+	if err := shared.SleepOrDone(ctx, f.Delay); err != nil {
+		return Location{}, err
+	}
+	var letter, digit bool
+	for _, r := range address {
+		letter = letter || unicode.IsLetter(r)
+		digit = digit || unicode.IsDigit(r)
+	}
+	if !letter || !digit || len(address) > 200 {
+		return Location{}, fmt.Errorf("geocode: %w", ErrBadAddress)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(normalize(address)))
+	sum := h.Sum64()
+	return Location{
+		Lat: f.Center.Lat + f.Radius*(float64(sum>>32)/(1<<32)*2-1),
+		Lon: f.Center.Lon + f.Radius*(float64(uint32(sum))/(1<<32)*2-1),
+	}, nil
+}
+
+// normalize folds case and whitespace so spellings of one address
+// share a cache entry.
+func normalize(address string) string {
+	return strings.ToLower(strings.Join(strings.Fields(address), " "))
+}
+
+// Cache is a Provider remembering the locations, and the rejections, of
+// up to a fixed number of addresses, oldest evicted first. It is safe
+// for concurrent use.
+type Cache struct {
+	next Provider
+	size int
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	order   []string // insertion order, for eviction
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type cacheEntry struct {
+	loc Location
+	err error // ErrBadAddress, wrapped, or nil
+}
+
+// NewCache returns a Cache in front of p holding up to size addresses.
+// A non-positive size holds 1000.
+//
+// It panics if p is nil.
+func NewCache(p Provider, size int) *Cache {
+	if p == nil {
+		panic("geocode.NewCache: nil provider")
+	}
+	if size <= 0 {
+		size = 1000
+	}
+	return &Cache{next: p, size: size, entries: make(map[string]cacheEntry, size)}
+}
+
+// Geocode implements Provider. Only locations and ErrBadAddress results
+// are cached; other errors, such as a canceled context, are retried on
+// the next call.
+func (c *Cache) Geocode(ctx context.Context, address string) (Location, error) {
+	key := normalize(address)
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		c.hits.Add(1)
+		return e.loc, e.err
+	}
+	c.misses.Add(1)
+
+	loc, err := c.next.Geocode(ctx, address)
+	if err != nil && !errors.Is(err, ErrBadAddress) {
+		return loc, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		if len(c.order) == c.size {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = cacheEntry{loc: loc, err: err}
+	return loc, err
+}
+
+// Hits returns the number of lookups answered from the cache.
+func (c *Cache) Hits() int64 { return c.hits.Load() }
+
+// Misses returns the number of lookups passed to the provider.
+func (c *Cache) Misses() int64 { return c.misses.Load() }
+
+// Memoized returns a Provider making each address lookup once per order
+// (see package memo), keyed by the normalized address, and passing it
+// to p. Rejections are not kept, so a second step asking about a
+// rejected address asks p again, which a Cache answers.
+//
+// It panics if p is nil.
+func Memoized(p Provider) Provider {
+	if p == nil {
+		panic("geocode.Memoized: nil provider")
+	}
+	return memoized{p}
+}
+
+type memoized struct{ next Provider }
+
+func (m memoized) Geocode(ctx context.Context, address string) (Location, error) {
+	return memo.Do(ctx, "geocode:"+normalize(address), func(ctx context.Context) (Location, error) {
+		return m.next.Geocode(ctx, address)
+	})
+}
+
+// Area names a delivery zone by its center.
+type Area struct {
+	Name   string
+	Center Location
+}
+
+// ParseAreas parses a comma-separated list of zone centers of the form
+//
+//	name=lat:lon
+//
+// for example "north=60.21:24.95,center=60.17:24.94".
+func ParseAreas(spec string) ([]Area, error) {
+	var areas []Area
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, point, ok := strings.Cut(entry, "=")
+		lat, lon, ok2 := strings.Cut(point, ":")
+		if !ok || !ok2 || name == "" {
+			return nil, fmt.Errorf("geocode: area %q: want name=lat:lon", entry)
+		}
+		la, err1 := strconv.ParseFloat(lat, 64)
+		lo, err2 := strconv.ParseFloat(lon, 64)
+		if err1 != nil || err2 != nil || la < -90 || la > 90 || lo < -180 || lo > 180 {
+			return nil, fmt.Errorf("geocode: area %q: invalid coordinates", entry)
+		}
+		areas = append(areas, Area{Name: name, Center: Location{Lat: la, Lon: lo}})
+	}
+	return areas, nil
+}
+
+// Nearest returns the name of the area whose center is closest to loc,
+// or "" if there are no areas. Distances are compared on a flat
+// projection, which is accurate at city scale.
+func Nearest(areas []Area, loc Location) string {
+	best, bestDist := "", 0.0
+	for _, a := range areas {
+		dLat := a.Center.Lat - loc.Lat
+		dLon := a.Center.Lon - loc.Lon
+		if d := dLat*dLat + dLon*dLon; best == "" || d < bestDist {
+			best, bestDist = a.Name, d
+		}
+	}
+	return best
+}
+
+// Resolver geocodes order addresses ahead of courier assignment.
+type Resolver struct {
+	provider Provider
+	areas    []Area
+}
+
+// NewResolver returns a Resolver using p and assigning zones from areas.
+//
+// It panics if p is nil.
+func NewResolver(p Provider, areas []Area) *Resolver {
+	if p == nil {
+		panic("geocode.NewResolver: nil provider")
+	}
+	return &Resolver{provider: p, areas: areas}
+}
+
+// Wrap returns run preceded by geocoding the order's address. An order
+// without a zone gets the nearest area's zone; a zone sent by the client
+// is kept. Orders without an address, or with fail_step "geocode"
+// simulating a rejected address, skip or fail the lookup respectively.
+func (r *Resolver) Wrap(run func(context.Context, model.OrderRequest) error) func(context.Context, model.OrderRequest) error {
+	return func(ctx context.Context, req model.OrderRequest) error {
+		if req.FailStep == "geocode" {
+			return fmt.Errorf("geocode: %w", ErrBadAddress)
+		}
+		if req.Address == "" {
+			return run(ctx, req)
+		}
+		loc, err := r.provider.Geocode(ctx, req.Address)
+		if err != nil {
+			return err
+		}
+		if req.Zone == "" {
+			req.Zone = Nearest(r.areas, loc)
+		}
+		return run(ctx, req)
+	}
+}
@@ -0,0 +1,233 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/memo"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+var helsinki = Location{Lat: 60.17, Lon: 24.94}
+
+func TestFakeGeocode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		address string
+		wantErr error
+	}{
+		{name: "street_and_number", address: "Mannerheimintie 12"},
+		{name: "no_number", address: "Mannerheimintie", wantErr: ErrBadAddress},
+		{name: "no_street", address: "12", wantErr: ErrBadAddress},
+		{name: "empty", address: "", wantErr: ErrBadAddress},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := Fake{Center: helsinki, Radius: 0.1}
+			loc, err := f.Geocode(context.Background(), tt.address)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if d := loc.Lat - helsinki.Lat; d < -0.1 || d > 0.1 {
+				t.Fatalf("expected latitude within radius, got %v", loc)
+			}
+			again, _ := f.Geocode(context.Background(), "  mannerheimintie   12 ")
+			if again != loc {
+				t.Fatalf("expected a stable location, got %v and %v", loc, again)
+			}
+		})
+	}
+}
+
+func TestFakeGeocode_ContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Fake{Delay: time.Second}.Geocode(ctx, "Mannerheimintie 12")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+type countingProvider struct {
+	calls atomic.Int64
+	err   error
+}
+
+func (p *countingProvider) Geocode(_ context.Context, address string) (Location, error) {
+	p.calls.Add(1)
+	if p.err != nil {
+		return Location{}, p.err
+	}
+	return Location{Lat: float64(len(address))}, nil
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	p := &countingProvider{}
+	c := NewCache(p, 2)
+	ctx := context.Background()
+
+	first, _ := c.Geocode(ctx, "Main St 1")
+	if again, _ := c.Geocode(ctx, "main st  1"); again != first {
+		t.Fatalf("expected cached %v, got %v", first, again)
+	}
+	if p.calls.Load() != 1 || c.Hits() != 1 || c.Misses() != 1 {
+		t.Fatalf("expected 1 call, 1 hit, 1 miss; got %d, %d, %d", p.calls.Load(), c.Hits(), c.Misses())
+	}
+
+	// Two more addresses evict the oldest entry.
+	c.Geocode(ctx, "Main St 2")
+	c.Geocode(ctx, "Main St 3")
+	c.Geocode(ctx, "Main St 1")
+	if got := p.calls.Load(); got != 4 {
+		t.Fatalf("expected the oldest entry to be evicted, got %d calls", got)
+	}
+}
+
+func TestCache_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int64
+	}{
+		{name: "bad_address_cached", err: ErrBadAddress, wantCalls: 1},
+		{name: "context_error_not_cached", err: context.DeadlineExceeded, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &countingProvider{err: tt.err}
+			c := NewCache(p, 0)
+			for i := 0; i < 2; i++ {
+				if _, err := c.Geocode(context.Background(), "nowhere"); !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+			}
+			if got := p.calls.Load(); got != tt.wantCalls {
+				t.Fatalf("expected %d calls, got %d", tt.wantCalls, got)
+			}
+		})
+	}
+}
+
+func TestMemoized(t *testing.T) {
+	t.Parallel()
+
+	p := &countingProvider{}
+	m := Memoized(p)
+	orderCtx := memo.NewContext(context.Background())
+
+	first, _ := m.Geocode(orderCtx, "Main St 1")
+	if again, _ := m.Geocode(orderCtx, " main st 1"); again != first || p.calls.Load() != 1 {
+		t.Fatalf("expected one lookup per order, got %d calls (%v, %v)", p.calls.Load(), first, again)
+	}
+	m.Geocode(memo.NewContext(context.Background()), "Main St 1")
+	m.Geocode(context.Background(), "Main St 1")
+	if got := p.calls.Load(); got != 3 {
+		t.Fatalf("expected other orders to look up again, got %d calls", got)
+	}
+
+	p.err = ErrBadAddress
+	for i := 0; i < 2; i++ {
+		if _, err := m.Geocode(orderCtx, "nowhere"); !errors.Is(err, ErrBadAddress) {
+			t.Fatalf("expected %v, got %v", ErrBadAddress, err)
+		}
+	}
+	if got := p.calls.Load(); got != 5 {
+		t.Fatalf("expected rejections not kept, got %d calls", got)
+	}
+}
+
+func TestParseAreas(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseAreas("north=60.21:24.95, center=60.17:24.94")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "north" || got[1].Center != helsinki {
+		t.Fatalf("unexpected areas: %+v", got)
+	}
+
+	for _, spec := range []string{"north", "=60:24", "north=60", "north=x:24", "north=91:24", "north=60:181"} {
+		if _, err := ParseAreas(spec); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestNearest(t *testing.T) {
+	t.Parallel()
+
+	areas := []Area{
+		{Name: "north", Center: Location{Lat: 60.25, Lon: 24.95}},
+		{Name: "center", Center: helsinki},
+	}
+	if got := Nearest(areas, Location{Lat: 60.18, Lon: 24.93}); got != "center" {
+		t.Fatalf("expected center, got %q", got)
+	}
+	if got := Nearest(areas, Location{Lat: 60.3, Lon: 25.0}); got != "north" {
+		t.Fatalf("expected north, got %q", got)
+	}
+	if got := Nearest(nil, helsinki); got != "" {
+		t.Fatalf("expected no zone, got %q", got)
+	}
+}
+
+func TestResolverWrap(t *testing.T) {
+	t.Parallel()
+
+	areas := []Area{{Name: "north", Center: Location{Lat: 18}}, {Name: "south", Center: Location{Lat: 0}}}
+	tests := []struct {
+		name     string
+		req      model.OrderRequest
+		wantZone string
+		wantErr  error
+		wantRun  bool
+	}{
+		{name: "zone_from_address", req: model.OrderRequest{Address: "Northern Road 100"}, wantZone: "north", wantRun: true},
+		{name: "client_zone_kept", req: model.OrderRequest{Address: "Northern Road 100", Zone: "south"}, wantZone: "south", wantRun: true},
+		{name: "no_address", req: model.OrderRequest{}, wantRun: true},
+		{name: "fail_step", req: model.OrderRequest{Address: "Northern Road 100", FailStep: "geocode"}, wantErr: ErrBadAddress},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var ran bool
+			var zone string
+			run := NewResolver(&countingProvider{}, areas).Wrap(func(_ context.Context, req model.OrderRequest) error {
+				ran, zone = true, req.Zone
+				return nil
+			})
+			if err := run(context.Background(), tt.req); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if ran != tt.wantRun || zone != tt.wantZone {
+				t.Fatalf("expected run=%v zone=%q, got run=%v zone=%q", tt.wantRun, tt.wantZone, ran, zone)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Package loyalty provides the points-accrual step used by the order
+// pipeline.
+//
+// A Program awards points for paid orders and records them in a Store,
+// at most once per order ID, so retried or replayed orders never earn
+// twice. It is meant to run as a tail step after a successful order.
+package loyalty
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/shared"
+)
+
+type unavailableError struct{}
+
+func (unavailableError) Error() string { return "loyalty unavailable" }
+func (unavailableError) Kind() string  { return "loyalty_unavailable" }
+
+// ErrUnavailable is returned when points cannot be recorded.
+var ErrUnavailable = unavailableError{}
+
+// Store records the points accrued per order.
+type Store interface {
+	// Accrue records points for orderID unless it already has an
+	// accrual, and returns the points on record for it.
+	Accrue(ctx context.Context, orderID string, points int64) (int64, error)
+
+	// Points returns the points recorded for orderID, if any.
+	Points(ctx context.Context, orderID string) (int64, bool, error)
+}
+
+// MemoryStore is an in-process Store. It is safe for concurrent use.
+type MemoryStore struct {
+	mu      sync.Mutex
+	byOrder map[string]int64
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{byOrder: map[string]int64{}}
+}
+
+// Accrue implements Store.
+func (m *MemoryStore) Accrue(_ context.Context, orderID string, points int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.byOrder[orderID]; ok {
+		return p, nil
+	}
+	m.byOrder[orderID] = points
+	return points, nil
+}
+
+// Points implements Store.
+func (m *MemoryStore) Points(_ context.Context, orderID string) (int64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.byOrder[orderID]
+	return p, ok, nil
+}
+
+// Program awards one point per unitsPerPoint of the order amount.
+type Program struct {
+	store         Store
+	unitsPerPoint uint64
+}
+
+// New returns a Program recording points in store. A zero unitsPerPoint
+// awards one point per 100 units.
+//
+// It panics if store is nil.
+func New(store Store, unitsPerPoint uint64) *Program {
+	if store == nil {
+		panic("loyalty.New: nil store")
+	}
+	if unitsPerPoint == 0 {
+		unitsPerPoint = 100
+	}
+	return &Program{store: store, unitsPerPoint: unitsPerPoint}
+}
+
+// PointsFor returns the points earned by an order of amount.
+func (p *Program) PointsFor(amount uint64) int64 {
+	return int64(amount / p.unitsPerPoint)
+}
+
+// Accrue executes the loyalty step for a paid order.
+//
+// It simulates latency using the "loyalty" delay override and respects
+// context cancellation. An order that already earned points keeps them
+// unchanged. If the step is configured to fail or the store fails, it
+// returns an error wrapping ErrUnavailable.
+func (p *Program) Accrue(ctx context.Context, req model.OrderRequest) error {
+	const stepName = "loyalty"
+	delay := req.StepDelay(stepName, 20*time.Millisecond)
+
+	// Block step until the delay elapses or the context is done
+	if err := shared.SleepOrDone(ctx, delay); err != nil {
+		return err
+	}
+
+	if req.FailStep == stepName {
+		return fmt.Errorf("loyalty: %w", ErrUnavailable)
+	}
+	if _, err := p.store.Accrue(ctx, req.OrderID, p.PointsFor(req.Amount)); err != nil {
+		return fmt.Errorf("loyalty: %w: %v", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// Accrued returns the points recorded for orderID, or false if none are
+// recorded yet or the store cannot be read.
+func (p *Program) Accrued(orderID string) (int64, bool) {
+	points, ok, err := p.store.Points(context.Background(), orderID)
+	if err != nil {
+		return 0, false
+	}
+	return points, ok
+}
@@ -0,0 +1,116 @@
+package loyalty
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+type failingStore struct{ MemoryStore }
+
+func (*failingStore) Accrue(context.Context, string, int64) (int64, error) {
+	return 0, errors.New("store down")
+}
+
+func TestAccrue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		store      Store
+		req        model.OrderRequest
+		wantErr    error
+		wantPoints int64
+		wantOK     bool
+	}{
+		{
+			name:       "success",
+			store:      NewMemoryStore(),
+			req:        model.OrderRequest{OrderID: "o-1", Amount: 1250, DelayMS: map[string]int64{"loyalty": 1}},
+			wantPoints: 12,
+			wantOK:     true,
+		},
+		{
+			name:   "below_one_point",
+			store:  NewMemoryStore(),
+			req:    model.OrderRequest{OrderID: "o-2", Amount: 99, DelayMS: map[string]int64{"loyalty": 1}},
+			wantOK: true,
+		},
+		{
+			name:    "fail_step",
+			store:   NewMemoryStore(),
+			req:     model.OrderRequest{OrderID: "o-3", Amount: 500, FailStep: "loyalty", DelayMS: map[string]int64{"loyalty": 1}},
+			wantErr: ErrUnavailable,
+		},
+		{
+			name:    "store_failure",
+			store:   &failingStore{},
+			req:     model.OrderRequest{OrderID: "o-4", Amount: 500, DelayMS: map[string]int64{"loyalty": 1}},
+			wantErr: ErrUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := New(tt.store, 0)
+			err := p.Accrue(context.Background(), tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			points, ok := p.Accrued(tt.req.OrderID)
+			if ok != tt.wantOK || points != tt.wantPoints {
+				t.Fatalf("expected points=%d ok=%v, got points=%d ok=%v", tt.wantPoints, tt.wantOK, points, ok)
+			}
+		})
+	}
+}
+
+func TestAccrue_IdempotentPerOrder(t *testing.T) {
+	t.Parallel()
+
+	p := New(NewMemoryStore(), 10)
+	req := model.OrderRequest{OrderID: "o-1", Amount: 100, DelayMS: map[string]int64{"loyalty": 1}}
+	if err := p.Accrue(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	req.Amount = 5000 // a retry with a changed amount earns nothing more
+	if err := p.Accrue(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if points, _ := p.Accrued("o-1"); points != 10 {
+		t.Fatalf("expected 10, got %d", points)
+	}
+}
+
+func TestAccrue_ContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	p := New(NewMemoryStore(), 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := p.Accrue(ctx, model.OrderRequest{OrderID: "o-1", Amount: 500, DelayMS: map[string]int64{"loyalty": 1000}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if _, ok := p.Accrued("o-1"); ok {
+		t.Fatal("expected no accrual after cancel")
+	}
+}
+
+func TestNew_NilStorePanics(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected panic for nil store")
+		}
+	}()
+	New(nil, 0)
+}
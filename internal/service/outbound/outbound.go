@@ -0,0 +1,114 @@
+// Package outbound caps concurrent calls to downstream integrations.
+//
+// A Limiter bounds the total number of in-flight outbound calls across
+// all destinations (payment, vendor, courier), with optional tighter
+// limits per destination, so a traffic spike cannot overwhelm the
+// downstreams.
+package outbound
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/pool"
+)
+
+// Limiter gates outbound calls on a global pool and per-destination pools.
+type Limiter struct {
+	global *pool.Pool
+	dests  map[string]*pool.Pool
+}
+
+// New returns a Limiter allowing up to global concurrent calls in total
+// and up to perDest[name] calls to each listed destination. Sizes are
+// clamped like pool.New; unlisted destinations share only the global limit.
+func New(global int, perDest map[string]int) *Limiter {
+	l := &Limiter{global: pool.New(global), dests: make(map[string]*pool.Pool, len(perDest))}
+	for name, n := range perDest {
+		l.dests[name] = pool.New(n)
+	}
+	return l
+}
+
+// ParseLimits parses a comma-separated list of name=limit pairs,
+// for example "payment=10,vendor=20".
+func ParseLimits(spec string) (map[string]int, error) {
+	out := map[string]int{}
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, n, ok := strings.Cut(entry, "=")
+		limit, err := strconv.Atoi(n)
+		if !ok || name == "" || err != nil || limit <= 0 {
+			return nil, fmt.Errorf("outbound: limit %q: want name=positive integer", entry)
+		}
+		out[name] = limit
+	}
+	return out, nil
+}
+
+// Acquire reserves a slot for one call to dest, waiting first on the
+// destination limit and then on the global limit. It returns ctx.Err()
+// if the wait is aborted.
+func (l *Limiter) Acquire(ctx context.Context, dest string) error {
+	d := l.dests[dest]
+	if d != nil {
+		if err := d.Acquire(ctx); err != nil {
+			return err
+		}
+	}
+	if err := l.global.Acquire(ctx); err != nil {
+		if d != nil {
+			d.Release()
+		}
+		return err
+	}
+	return nil
+}
+
+// Release frees the slot taken by Acquire for dest.
+func (l *Limiter) Release(dest string) {
+	l.global.Release()
+	if d := l.dests[dest]; d != nil {
+		d.Release()
+	}
+}
+
+// Wrap returns run gated by the limiter for dest. The wait counts
+// against run's context, so a step queued past its deadline fails with
+// the context error like any other step.
+func (l *Limiter) Wrap(dest string, run func(context.Context, model.OrderRequest) error) func(context.Context, model.OrderRequest) error {
+	return func(ctx context.Context, req model.OrderRequest) error {
+		if err := l.Acquire(ctx, dest); err != nil {
+			return err
+		}
+		defer l.Release(dest)
+		return run(ctx, req)
+	}
+}
+
+// Stats reports occupancy and queue depth, global limit first and then
+// destinations by name.
+func (l *Limiter) Stats() []model.OutboundLimit {
+	out := []model.OutboundLimit{limitStats("*", l.global)}
+	for _, name := range slices.Sorted(maps.Keys(l.dests)) {
+		out = append(out, limitStats(name, l.dests[name]))
+	}
+	return out
+}
+
+func limitStats(name string, p *pool.Pool) model.OutboundLimit {
+	return model.OutboundLimit{
+		Destination: name,
+		Limit:       p.Cap(),
+		InUse:       p.InUse(),
+		Waiting:     p.Waiting(),
+	}
+}
@@ -0,0 +1,134 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestParseLimits(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseLimits("payment=10, vendor=20")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got["payment"] != 10 || got["vendor"] != 20 {
+		t.Fatalf("unexpected limits: %v", got)
+	}
+
+	for _, spec := range []string{"payment", "=3", "payment=0", "payment=x"} {
+		if _, err := ParseLimits(spec); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
+
+// blocked reports whether acquiring dest waits past a short deadline.
+func blocked(l *Limiter, dest string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := l.Acquire(ctx, dest)
+	if err == nil {
+		l.Release(dest)
+		return false
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+func TestLimiter_GlobalAndDestinationLimits(t *testing.T) {
+	t.Parallel()
+
+	l := New(3, map[string]int{"payment": 1})
+	ctx := context.Background()
+
+	if err := l.Acquire(ctx, "payment"); err != nil {
+		t.Fatal(err)
+	}
+	if !blocked(l, "payment") {
+		t.Fatal("expected payment to be capped at 1")
+	}
+	if blocked(l, "vendor") {
+		t.Fatal("expected vendor to share only the global limit")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := l.Acquire(ctx, "vendor"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !blocked(l, "courier") {
+		t.Fatal("expected the global limit of 3 to be reached")
+	}
+
+	stats := l.Stats()
+	want := []model.OutboundLimit{
+		{Destination: "*", Limit: 3, InUse: 3},
+		{Destination: "payment", Limit: 1, InUse: 1},
+	}
+	if len(stats) != len(want) || stats[0] != want[0] || stats[1] != want[1] {
+		t.Fatalf("expected %+v, got %+v", want, stats)
+	}
+
+	l.Release("payment")
+	l.Release("vendor")
+	l.Release("vendor")
+	for _, s := range l.Stats() {
+		if s.InUse != 0 {
+			t.Fatalf("%s: expected in_use=0, got %d", s.Destination, s.InUse)
+		}
+	}
+}
+
+// A call that times out waiting on the global limit must give back its
+// destination slot.
+func TestLimiter_GlobalTimeoutReleasesDestination(t *testing.T) {
+	t.Parallel()
+
+	l := New(1, map[string]int{"payment": 1})
+	if err := l.Acquire(context.Background(), "vendor"); err != nil {
+		t.Fatal(err)
+	}
+	if !blocked(l, "payment") {
+		t.Fatal("expected payment to wait on the global limit")
+	}
+	if got := l.Stats()[1].InUse; got != 0 {
+		t.Fatalf("expected payment in_use=0 after timeout, got %d", got)
+	}
+	l.Release("vendor")
+}
+
+func TestLimiter_Wrap(t *testing.T) {
+	t.Parallel()
+
+	l := New(1, nil)
+	ran := make(chan struct{})
+	release := make(chan struct{})
+	run := l.Wrap("vendor", func(context.Context, model.OrderRequest) error {
+		close(ran)
+		<-release
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- run(context.Background(), model.OrderRequest{OrderID: "o-1"}) }()
+	<-ran
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	queued := l.Wrap("payment", func(context.Context, model.OrderRequest) error { return nil })
+	if err := queued(ctx, model.OrderRequest{OrderID: "o-2"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := l.Stats()[0].InUse; got != 0 {
+		t.Fatalf("expected in_use=0, got %d", got)
+	}
+}
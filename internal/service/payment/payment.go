@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/shared"
 	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/tracker"
 )
 
@@ -27,6 +28,21 @@ var ErrDeclined = declinedError{}
 // context cancellation. If payment fails validation or is declined,
 // it returns an error wrapping ErrDeclined.
 func Process(ctx context.Context, req model.OrderRequest, tr *tracker.Tracker) error {
+	return process(ctx, req, tr, "payment")
+}
+
+// ProcessSandbox executes the payment step with sandbox credentials,
+// for synthetic traffic that must not charge real accounts.
+//
+// It behaves like Process, but its latency override is read from the
+// "payment_sandbox" delay_ms key.
+func ProcessSandbox(ctx context.Context, req model.OrderRequest, tr *tracker.Tracker) error {
+	return process(ctx, req, tr, "payment_sandbox")
+}
+
+// process simulates one payment account whose latency override is read
+// from delayKey.
+func process(ctx context.Context, req model.OrderRequest, tr *tracker.Tracker, delayKey string) error {
 	// Track the running step
 	if tr != nil {
 		tr.Inc()
@@ -34,10 +50,10 @@ func Process(ctx context.Context, req model.OrderRequest, tr *tracker.Tracker) e
 	}
 
 	const stepName = "payment"
-	delay := resolveStepDelay(req.DelayMS, stepName, 150*time.Millisecond)
+	delay := req.StepDelay(delayKey, 150*time.Millisecond)
 
 	// Block step until the delay elapses or the context is done
-	if err := waitOrCancel(ctx, delay); err != nil {
+	if err := shared.SleepOrDone(ctx, delay); err != nil {
 		return err
 	}
 
@@ -52,36 +68,3 @@ func Process(ctx context.Context, req model.OrderRequest, tr *tracker.Tracker) e
 
 	return nil
 }
-
-// resolveStepDelay returns the effective delay for a step.
-//
-// If delayMS contains a positive value for the given step (in milliseconds),
-// that value is used. Otherwise, defaultDelay is returned.
-func resolveStepDelay(delayMS map[string]int64, step string, defaultDelay time.Duration) time.Duration {
-	if delayMS == nil {
-		return defaultDelay
-	}
-	if ms, ok := delayMS[step]; ok && ms > 0 {
-		return time.Duration(ms) * time.Millisecond
-	}
-	return defaultDelay
-}
-
-// waitOrCancel blocks for d or until ctx is canceled.
-//
-// It returns nil if the duration elapses, or ctx.Err() if the context
-// is done first. If d <= 0, it returns immediately.
-func waitOrCancel(ctx context.Context, d time.Duration) error {
-	if d <= 0 {
-		return nil
-	}
-	t := time.NewTimer(d)
-	defer t.Stop()
-
-	select {
-	case <-t.C:
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
-	}
-}
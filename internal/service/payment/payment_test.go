@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
 	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/tracker"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/simulation"
 )
 
 func TestProcess(t *testing.T) {
@@ -94,3 +96,47 @@ func TestProcess_ContextCanceled(t *testing.T) {
 		t.Fatalf("expected context.Canceled, got %v", err)
 	}
 }
+
+func TestProcess_Simulation(t *testing.T) {
+	t.Parallel()
+
+	req := model.OrderRequest{OrderID: "o-7", Amount: 100, DelayMS: map[string]int64{"payment": 1}}
+	ctx := simulation.NewContext(context.Background())
+	if err := Process(ctx, req, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start := time.Now()
+	if err := Process(ctx, model.OrderRequest{OrderID: "o-8", Amount: 100, DelayMS: map[string]int64{"payment": 5}}, nil); err != nil || time.Since(start) < 5*time.Millisecond {
+		t.Fatalf("expected a longer delay still waited, got %v after %v", err, time.Since(start))
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := Process(canceled, req, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestProcessSandbox_DelayKey(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	req := model.OrderRequest{
+		OrderID: "o-7",
+		Amount:  100,
+		DelayMS: map[string]int64{"payment": 1000, "payment_sandbox": 1},
+	}
+	if err := ProcessSandbox(ctx, req, nil); err != nil {
+		t.Fatalf("unexpected sandbox error: %v", err)
+	}
+	if err := Process(ctx, req, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	req.FailStep = "payment"
+	if err := ProcessSandbox(context.Background(), req, nil); !errors.Is(err, ErrDeclined) {
+		t.Fatalf("expected %v, got %v", ErrDeclined, err)
+	}
+}
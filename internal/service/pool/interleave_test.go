@@ -0,0 +1,75 @@
+//go:build syncpoint
+
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/syncpoint"
+)
+
+// A shrink below the slots in use retires the next released slot even
+// while a caller waits for it; the caller gets the one after.
+func TestPoolShrinkWithWaiter(t *testing.T) {
+	t.Cleanup(syncpoint.Reset)
+	ctx := context.Background()
+
+	p := New(2)
+	for range 2 {
+		if err := p.Acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waiting := syncpoint.Signal(syncpoint.PoolAcquireWait, "")
+	done := make(chan error, 1)
+	go func() { done <- p.Acquire(ctx) }()
+	<-waiting
+	if p.Waiting() != 1 {
+		t.Fatalf("expected 1 waiter at the wait point, got %d", p.Waiting())
+	}
+
+	p.Resize(1)
+	retired := syncpoint.Signal(syncpoint.PoolReleased, "")
+	p.Release()
+	<-retired
+	if p.Waiting() != 1 || p.InUse() != 1 {
+		t.Fatalf("expected the released slot retired, got waiting=%d in_use=%d", p.Waiting(), p.InUse())
+	}
+
+	acquired := syncpoint.Signal(syncpoint.PoolAcquired, "")
+	p.Release()
+	<-acquired
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected acquire error: %v", err)
+	}
+	if p.InUse() != 1 {
+		t.Fatalf("expected the waiter to hold the last slot, got in_use=%d", p.InUse())
+	}
+}
+
+// A caller counted as waiting when the pool grows takes a new slot.
+func TestPoolGrowWithWaiter(t *testing.T) {
+	t.Cleanup(syncpoint.Reset)
+	ctx := context.Background()
+
+	p := New(1)
+	if err := p.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	waiting := syncpoint.Signal(syncpoint.PoolAcquireWait, "")
+	done := make(chan error, 1)
+	go func() { done <- p.Acquire(ctx) }()
+	<-waiting
+
+	p.Resize(2)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected acquire error: %v", err)
+	}
+	if p.InUse() != 2 {
+		t.Fatalf("expected in_use=2, got %d", p.InUse())
+	}
+	if tel := p.Telemetry(); tel.Acquired != 2 || tel.Abandoned != 0 {
+		t.Fatalf("unexpected telemetry %+v", tel)
+	}
+}
@@ -1,40 +1,284 @@
 // Package pool provides a bounded concurrency semaphore.
+//
+// A Pool also keeps telemetry for sizing it from real traffic: a
+// histogram of acquisition wait times and the periods it spent full.
 package pool
 
-import "context"
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/ctxerr"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/progress"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/syncpoint"
+)
+
+// maxSize is the largest supported pool size.
+const maxSize = 128
 
 // Pool limits concurrent resource assignments.
+//
+// The semaphore channel always has maxSize slots; the ones beyond the
+// current size are held as reserved tokens so the pool can be resized
+// without replacing the channel.
 type Pool struct {
-	sem chan struct{}
+	sem     chan struct{}
+	waiting atomic.Int64
+	now     func() time.Time
+
+	waits     [len(waitBounds) + 1]atomic.Int64 // acquisitions per wait bucket
+	abandoned atomic.Int64                      // waits ended by the context
+
+	fullSince   atomic.Int64 // unix nanos the current full period began; 0 when not full
+	saturations atomic.Int64 // full periods begun
+	fullNanos   atomic.Int64 // total duration of ended full periods
+	longest     atomic.Int64 // longest ended full period, in nanos
+	observer    atomic.Pointer[func(SaturationEvent)]
+
+	mu       sync.Mutex // serializes Resize
+	size     atomic.Int64
+	reserved atomic.Int64 // tokens in sem held by the pool itself
+	debt     atomic.Int64 // reserved tokens still owed after a shrink
 }
 
 // New creates a pool with at least one slot
 // and at most 128 slots.
 // Slots are used to limit the number of concurrent requests to the order processor.
 func New(size int) *Pool {
-	if size <= 0 {
-		size = 1
+	size = clampSize(size)
+	p := &Pool{sem: make(chan struct{}, maxSize), now: time.Now}
+	for range maxSize - size {
+		p.sem <- struct{}{}
+	}
+	p.reserved.Store(int64(maxSize - size))
+	p.size.Store(int64(size))
+	return p
+}
+
+func clampSize(size int) int {
+	return min(max(size, 1), maxSize)
+}
+
+// Resize changes the number of slots, clamped like New.
+//
+// Growing takes effect immediately. When shrinking below the number of
+// slots in use, acquired slots are retired as they are released, so
+// in-flight work is never interrupted.
+func (p *Pool) Resize(size int) {
+	size = clampSize(size)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delta := size - int(p.size.Load())
+	p.size.Store(int64(size))
+
+	for ; delta > 0; delta-- {
+		if p.payDebt() {
+			continue // cancel a pending retirement instead
+		}
+		<-p.sem // free a reserved token; reserved > 0 here
+		p.reserved.Add(-1)
 	}
-	if size > 128 {
-		size = 128
+	for ; delta < 0; delta++ {
+		select {
+		case p.sem <- struct{}{}:
+			p.reserved.Add(1)
+		default:
+			p.debt.Add(1) // retire the next released slot
+		}
+	}
+	p.noteLevel()
+}
+
+// payDebt consumes one unit of debt if any is outstanding.
+func (p *Pool) payDebt() bool {
+	for {
+		d := p.debt.Load()
+		if d == 0 {
+			return false
+		}
+		if p.debt.CompareAndSwap(d, d-1) {
+			return true
+		}
 	}
-	return &Pool{sem: make(chan struct{}, size)}
 }
 
 // Acquire reserves one slot in the pool.
 // If the pool is full, it blocks until a slot becomes available
 // or the context is canceled.
-// It returns ctx.Err() if acquisition is aborted due to cancellation.
+// It returns ctx.Err() if acquisition is aborted due to cancellation,
+// annotated with the context's cancellation cause if it has one.
+// A step waiting for a slot sends heartbeats (see progress.KeepAlive).
 func (p *Pool) Acquire(ctx context.Context) error {
+	// Fast path: a free slot is taken without counting as a waiter.
+	if p.TryAcquire() {
+		return nil
+	}
+
+	p.waiting.Add(1)
+	defer p.waiting.Add(-1)
+	defer progress.KeepAlive(ctx, "waiting for a pool slot")()
+	syncpoint.Hit(syncpoint.PoolAcquireWait, "")
+
+	start := p.now()
 	select {
 	case p.sem <- struct{}{}:
+		p.waits[waitBucket(p.now().Sub(start))].Add(1)
+		p.noteLevel()
+		syncpoint.Hit(syncpoint.PoolAcquired, "")
 		return nil
 	case <-ctx.Done():
-		return ctx.Err()
+		p.abandoned.Add(1)
+		return ctxerr.Err(ctx)
+	}
+}
+
+// TryAcquire reserves one slot if one is free, without blocking. A
+// successful call counts as an acquisition without wait.
+func (p *Pool) TryAcquire() bool {
+	select {
+	case p.sem <- struct{}{}:
+		p.waits[0].Add(1) // no wait
+		p.noteLevel()
+		syncpoint.Hit(syncpoint.PoolAcquired, "")
+		return true
+	default:
+		return false
 	}
 }
 
 // Release frees a previously acquired slot.
+//
+// After a shrink, the slot is retired (kept as a reserved token) instead.
 func (p *Pool) Release() {
-	<-p.sem
+	if p.debt.Load() > 0 && p.payDebt() {
+		p.reserved.Add(1)
+	} else {
+		<-p.sem
+	}
+	p.noteLevel()
+	syncpoint.Hit(syncpoint.PoolReleased, "")
+}
+
+// Cap returns the number of slots in the pool.
+func (p *Pool) Cap() int { return int(p.size.Load()) }
+
+// InUse returns the number of currently acquired slots.
+//
+// After a shrink it may exceed Cap until enough slots are released.
+func (p *Pool) InUse() int {
+	return max(len(p.sem)-int(p.reserved.Load()), 0)
+}
+
+// Waiting returns the number of callers blocked in Acquire.
+func (p *Pool) Waiting() int64 { return p.waiting.Load() }
+
+// waitBounds are the upper bounds of the wait-time histogram buckets; a
+// final bucket counts longer waits. Acquisitions that did not block
+// land in the first bucket.
+var waitBounds = [...]time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// waitBucket returns the histogram bucket of a wait of d.
+func waitBucket(d time.Duration) int {
+	for i, b := range waitBounds {
+		if d <= b {
+			return i
+		}
+	}
+	return len(waitBounds)
+}
+
+// SaturationEvent reports the pool becoming full or ceasing to be full.
+type SaturationEvent struct {
+	Full     bool          // the pool became full; false when a full period ended
+	First    bool          // the first time this pool became full
+	Duration time.Duration // length of the full period that ended; 0 when Full
+}
+
+// OnSaturation registers fn to be called, synchronously, whenever the
+// pool becomes full and whenever a full period ends. fn replaces any
+// earlier observer; nil removes it.
+func (p *Pool) OnSaturation(fn func(SaturationEvent)) {
+	if fn == nil {
+		p.observer.Store(nil)
+		return
+	}
+	p.observer.Store(&fn)
+}
+
+// noteLevel starts or ends a full period when the pool's occupancy
+// crosses its size. Under concurrent changes the boundaries are
+// approximate, which is enough for telemetry.
+func (p *Pool) noteLevel() {
+	full := p.InUse() >= p.Cap()
+	since := p.fullSince.Load()
+	switch {
+	case full && since == 0:
+		if !p.fullSince.CompareAndSwap(0, p.now().UnixNano()) {
+			return
+		}
+		n := p.saturations.Add(1)
+		p.notify(SaturationEvent{Full: true, First: n == 1})
+	case !full && since != 0:
+		if !p.fullSince.CompareAndSwap(since, 0) {
+			return
+		}
+		d := p.now().UnixNano() - since
+		p.fullNanos.Add(d)
+		for {
+			l := p.longest.Load()
+			if d <= l || p.longest.CompareAndSwap(l, d) {
+				break
+			}
+		}
+		p.notify(SaturationEvent{Duration: time.Duration(d)})
+	}
+}
+
+func (p *Pool) notify(e SaturationEvent) {
+	if fn := p.observer.Load(); fn != nil {
+		(*fn)(e)
+	}
+}
+
+// Telemetry reports the wait-time histogram and saturation counters.
+// The ongoing full period, if any, counts toward SaturatedMS.
+func (p *Pool) Telemetry() model.PoolTelemetry {
+	t := model.PoolTelemetry{
+		Capacity:           p.Cap(),
+		Abandoned:          p.abandoned.Load(),
+		Saturations:        p.saturations.Load(),
+		SaturatedMS:        time.Duration(p.fullNanos.Load()).Milliseconds(),
+		LongestSaturatedMS: time.Duration(p.longest.Load()).Milliseconds(),
+		WaitBuckets:        make([]model.WaitBucket, len(p.waits)),
+	}
+	for i := range p.waits {
+		le := "+Inf"
+		if i < len(waitBounds) {
+			le = waitBounds[i].String()
+		}
+		n := p.waits[i].Load()
+		t.WaitBuckets[i] = model.WaitBucket{LE: le, Count: n}
+		t.Acquired += n
+	}
+	if since := p.fullSince.Load(); since != 0 {
+		start := time.Unix(0, since)
+		t.Saturated = true
+		t.SaturatedSince = start.UTC().Format(time.RFC3339Nano)
+		t.SaturatedMS += p.now().Sub(start).Milliseconds()
+	}
+	return t
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 )
@@ -54,7 +55,7 @@ func TestNewPoolSize(t *testing.T) {
 			t.Parallel()
 
 			p := New(tt.in)
-			if got := cap(p.sem); got != tt.out {
+			if got := p.Cap(); got != tt.out {
 				t.Errorf("New(%d): got %d, want %d", tt.in, got, tt.out)
 			}
 		})
@@ -83,7 +84,7 @@ func TestPoolAcquireRelease(t *testing.T) {
 		tt := tt
 		t.Run(fmt.Sprintf("size=%d", tt.size), func(t *testing.T) {
 			pool := New(tt.size)
-			slots := cap(pool.sem)
+			slots := pool.Cap()
 
 			acquired := 0
 			defer func() {
@@ -142,7 +143,7 @@ func TestPoolAcquireContextTimeout(t *testing.T) {
 		tt := tt
 		t.Run(fmt.Sprintf("size=%d", tt.size), func(t *testing.T) {
 			pool := New(tt.size)
-			slots := cap(pool.sem)
+			slots := pool.Cap()
 
 			acquired := 0
 			defer func() {
@@ -171,3 +172,250 @@ func TestPoolAcquireContextTimeout(t *testing.T) {
 		})
 	}
 }
+
+func TestPoolAcquireReportsCause(t *testing.T) {
+	t.Parallel()
+
+	p := New(1)
+	if err := p.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Release()
+
+	cause := errors.New("vendor: vendor unavailable")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(cause)
+	err := p.Acquire(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	if want := "context canceled: vendor: vendor unavailable"; err.Error() != want {
+		t.Fatalf("expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestPoolStats(t *testing.T) {
+	t.Parallel()
+
+	p := New(2)
+	if p.Cap() != 2 || p.InUse() != 0 || p.Waiting() != 0 {
+		t.Fatalf("expected cap=2 in_use=0 waiting=0, got cap=%d in_use=%d waiting=%d", p.Cap(), p.InUse(), p.Waiting())
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := p.Acquire(context.Background()); err != nil {
+			t.Fatalf("acquire #%d failed: %v", i+1, err)
+		}
+	}
+	if got := p.InUse(); got != 2 {
+		t.Fatalf("expected in_use=2, got %d", got)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- p.Acquire(context.Background()) }()
+
+	deadline := time.Now().Add(time.Second)
+	for p.Waiting() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected waiting=1, got %d", p.Waiting())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	p.Release()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected acquire error: %v", err)
+	}
+	if got := p.Waiting(); got != 0 {
+		t.Fatalf("expected waiting=0, got %d", got)
+	}
+	p.Release()
+	p.Release()
+	if got := p.InUse(); got != 0 {
+		t.Fatalf("expected in_use=0, got %d", got)
+	}
+}
+
+func TestPoolResize(t *testing.T) {
+	t.Parallel()
+
+	p := New(2)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := p.Acquire(ctx); err != nil {
+			t.Fatalf("acquire #%d failed: %v", i+1, err)
+		}
+	}
+
+	// Growing frees slots immediately.
+	p.Resize(4)
+	if p.Cap() != 4 {
+		t.Fatalf("expected cap=4, got %d", p.Cap())
+	}
+	for i := 0; i < 2; i++ {
+		if err := p.Acquire(ctx); err != nil {
+			t.Fatalf("acquire after grow #%d failed: %v", i+1, err)
+		}
+	}
+
+	// Shrinking below in-use retires slots as they are released.
+	p.Resize(1)
+	if p.Cap() != 1 || p.InUse() != 4 {
+		t.Fatalf("expected cap=1 in_use=4, got cap=%d in_use=%d", p.Cap(), p.InUse())
+	}
+	for i := 0; i < 3; i++ {
+		p.Release()
+	}
+	if got := p.InUse(); got != 1 {
+		t.Fatalf("expected in_use=1, got %d", got)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := p.Acquire(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected acquire to block at cap=1, got %v", err)
+	}
+
+	p.Release()
+	if err := p.Acquire(ctx); err != nil {
+		t.Fatalf("acquire after release failed: %v", err)
+	}
+	p.Release()
+
+	// Growing while a shrink is pending cancels the pending retirement.
+	p.Resize(0) // clamped to 1
+	if p.Cap() != 1 {
+		t.Fatalf("expected cap=1, got %d", p.Cap())
+	}
+	p.Resize(200) // clamped to 128
+	for i := 0; i < 128; i++ {
+		if err := p.Acquire(ctx); err != nil {
+			t.Fatalf("acquire #%d at cap=128 failed: %v", i+1, err)
+		}
+	}
+	if p.InUse() != 128 {
+		t.Fatalf("expected in_use=128, got %d", p.InUse())
+	}
+}
+
+func TestPoolResizeDebtCanceledByGrow(t *testing.T) {
+	t.Parallel()
+
+	p := New(3)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := p.Acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.Resize(1) // two slots owed
+	p.Resize(3) // debt canceled, nothing to free
+	for i := 0; i < 3; i++ {
+		p.Release()
+	}
+	for i := 0; i < 3; i++ {
+		if err := p.Acquire(ctx); err != nil {
+			t.Fatalf("acquire #%d failed: %v", i+1, err)
+		}
+	}
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := p.Acquire(short); err == nil {
+		t.Fatal("expected fourth acquire to block at cap=3")
+	}
+}
+
+func TestPoolTryAcquire(t *testing.T) {
+	t.Parallel()
+
+	p := New(1)
+	if !p.TryAcquire() {
+		t.Fatal("expected first TryAcquire to succeed")
+	}
+	if p.TryAcquire() {
+		t.Fatal("expected TryAcquire to fail on a full pool")
+	}
+	if p.Waiting() != 0 {
+		t.Fatalf("expected waiting=0, got %d", p.Waiting())
+	}
+	p.Release()
+	if !p.TryAcquire() {
+		t.Fatal("expected TryAcquire to succeed after release")
+	}
+}
+
+func TestWaitBucket(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{d: 0, want: 0},
+		{d: time.Millisecond, want: 0},
+		{d: 2 * time.Millisecond, want: 1},
+		{d: time.Second, want: 6},
+		{d: time.Minute, want: len(waitBounds)},
+	}
+	for _, tt := range tests {
+		if got := waitBucket(tt.d); got != tt.want {
+			t.Fatalf("%v: expected bucket %d, got %d", tt.d, tt.want, got)
+		}
+	}
+}
+
+func TestPoolTelemetry(t *testing.T) {
+	t.Parallel()
+
+	p := New(1)
+	now := time.Unix(1_700_000_000, 0)
+	var mu sync.Mutex
+	p.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(20 * time.Millisecond)
+		return now
+	}
+	var events []SaturationEvent
+	p.OnSaturation(func(e SaturationEvent) { events = append(events, e) })
+
+	// Fill the pool, queue one waiter behind it, and let one give up.
+	if err := p.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Acquire(ctx); err == nil {
+		t.Fatal("expected the canceled wait to fail")
+	}
+	done := make(chan error, 1)
+	go func() { done <- p.Acquire(context.Background()) }()
+	for p.Waiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	p.Release() // hands the slot to the waiter; the pool stays full
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	p.Release()
+
+	tel := p.Telemetry()
+	if tel.Acquired != 2 || tel.Abandoned != 1 || tel.WaitBuckets[0].Count != 1 || tel.WaitBuckets[3].Count != 1 {
+		t.Fatalf("unexpected wait histogram %+v", tel)
+	}
+	if tel.WaitBuckets[3].LE != "50ms" || tel.WaitBuckets[len(tel.WaitBuckets)-1].LE != "+Inf" {
+		t.Fatalf("unexpected bucket bounds %+v", tel.WaitBuckets)
+	}
+	if tel.Saturated || tel.Saturations < 1 || tel.SaturatedMS == 0 || tel.LongestSaturatedMS == 0 {
+		t.Fatalf("unexpected saturation counters %+v", tel)
+	}
+	if len(events) < 2 || !events[0].Full || !events[0].First || events[len(events)-1].Full || events[len(events)-1].Duration == 0 {
+		t.Fatalf("unexpected saturation events %+v", events)
+	}
+
+	p.Resize(2)
+	_ = p.Acquire(context.Background())
+	if tel := p.Telemetry(); tel.Saturated {
+		t.Fatalf("expected headroom after growing, got %+v", tel)
+	}
+}
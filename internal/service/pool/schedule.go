@@ -0,0 +1,209 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// Shift sets the pool size for a daily time range.
+type Shift struct {
+	Days  []time.Weekday // days the shift starts on; empty means every day
+	Start time.Duration  // offset from midnight
+	End   time.Duration  // offset from midnight; End <= Start wraps past midnight
+	Size  int
+}
+
+// Schedule maps time of day and week to a pool size.
+type Schedule struct {
+	Default int     // size outside every shift
+	Shifts  []Shift // the first matching shift wins
+}
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ParseSchedule parses a comma-separated list of shifts of the form
+//
+//	[days@]HH:MM-HH:MM=size
+//
+// where days is a weekday ("sat") or an inclusive range ("mon-fri").
+// For example "17:00-21:00=20,23:00-06:00=5,sat-sun@10:00-14:00=8".
+func ParseSchedule(spec string, defaultSize int) (Schedule, error) {
+	s := Schedule{Default: defaultSize}
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		sh, err := parseShift(entry)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("pool: shift %q: %w", entry, err)
+		}
+		s.Shifts = append(s.Shifts, sh)
+	}
+	return s, nil
+}
+
+func parseShift(entry string) (Shift, error) {
+	var sh Shift
+	if days, rest, ok := strings.Cut(entry, "@"); ok {
+		d, err := parseDays(days)
+		if err != nil {
+			return Shift{}, err
+		}
+		sh.Days, entry = d, rest
+	}
+
+	span, size, ok := strings.Cut(entry, "=")
+	if !ok {
+		return Shift{}, fmt.Errorf("missing =size")
+	}
+	n, err := strconv.Atoi(size)
+	if err != nil || n <= 0 {
+		return Shift{}, fmt.Errorf("invalid size %q", size)
+	}
+	sh.Size = n
+
+	start, end, ok := strings.Cut(span, "-")
+	if !ok {
+		return Shift{}, fmt.Errorf("missing start-end range")
+	}
+	if sh.Start, err = parseClock(start); err != nil {
+		return Shift{}, err
+	}
+	if sh.End, err = parseClock(end); err != nil {
+		return Shift{}, err
+	}
+	return sh, nil
+}
+
+func parseDays(s string) ([]time.Weekday, error) {
+	from, to, isRange := strings.Cut(s, "-")
+	if !isRange {
+		to = from
+	}
+	f := slices.Index(weekdays, from)
+	t := slices.Index(weekdays, to)
+	if f < 0 || t < 0 {
+		return nil, fmt.Errorf("invalid days %q", s)
+	}
+	var days []time.Weekday
+	for d := f; ; d = (d + 1) % 7 {
+		days = append(days, time.Weekday(d))
+		if d == t {
+			return days, nil
+		}
+	}
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// SizeAt returns the pool size in effect at t, in t's location.
+func (s Schedule) SizeAt(t time.Time) int {
+	if i := s.shiftAt(t); i >= 0 {
+		return s.Shifts[i].Size
+	}
+	return s.Default
+}
+
+// shiftAt returns the index of the shift in effect at t, or -1.
+func (s Schedule) shiftAt(t time.Time) int {
+	y, m, d := t.Date()
+	offset := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+	for i, sh := range s.Shifts {
+		if sh.covers(t.Weekday(), offset) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (sh Shift) covers(day time.Weekday, offset time.Duration) bool {
+	if sh.Start < sh.End {
+		return offset >= sh.Start && offset < sh.End && sh.onDay(day)
+	}
+	// Wraps past midnight: the early-morning part belongs to the
+	// previous day's shift.
+	if offset >= sh.Start {
+		return sh.onDay(day)
+	}
+	return offset < sh.End && sh.onDay((day+6)%7)
+}
+
+func (sh Shift) onDay(day time.Weekday) bool {
+	return len(sh.Days) == 0 || slices.Contains(sh.Days, day)
+}
+
+// Scheduler resizes a Pool as its Schedule moves between shifts.
+type Scheduler struct {
+	pool     *Pool
+	schedule Schedule
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewScheduler returns a Scheduler applying s to p.
+//
+// It panics if p is nil.
+func NewScheduler(p *Pool, s Schedule) *Scheduler {
+	if p == nil {
+		panic("pool.NewScheduler: nil pool")
+	}
+	return &Scheduler{pool: p, schedule: s, interval: time.Minute, now: time.Now}
+}
+
+// Run applies the schedule immediately and then once per minute until
+// ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		s.apply()
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Scheduler) apply() {
+	s.pool.Resize(s.schedule.SizeAt(s.now()))
+}
+
+// Snapshot describes the schedule and the pool size in effect.
+func (s *Scheduler) Snapshot() model.PoolSchedule {
+	out := model.PoolSchedule{
+		ActiveSize:  s.pool.Cap(),
+		DefaultSize: s.schedule.Default,
+		ActiveShift: s.schedule.shiftAt(s.now()),
+		Shifts:      make([]model.PoolShift, len(s.schedule.Shifts)),
+	}
+	for i, sh := range s.schedule.Shifts {
+		ps := model.PoolShift{
+			Start: formatClock(sh.Start),
+			End:   formatClock(sh.End),
+			Size:  sh.Size,
+		}
+		for _, d := range sh.Days {
+			ps.Days = append(ps.Days, weekdays[d])
+		}
+		out.Shifts[i] = ps
+	}
+	return out
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
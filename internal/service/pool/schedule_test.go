@@ -0,0 +1,150 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	t.Parallel()
+
+	s, err := ParseSchedule("17:00-21:00=20, 23:00-06:00=5,sat-mon@10:00-14:00=8,wed@09:30-10:00=2", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Default != 10 || len(s.Shifts) != 4 {
+		t.Fatalf("unexpected schedule: %+v", s)
+	}
+	weekend := s.Shifts[2].Days
+	if len(weekend) != 3 || weekend[0] != time.Saturday || weekend[2] != time.Monday {
+		t.Fatalf("expected sat,sun,mon, got %v", weekend)
+	}
+	if s.Shifts[3].Start != 9*time.Hour+30*time.Minute {
+		t.Fatalf("expected 9h30m, got %v", s.Shifts[3].Start)
+	}
+
+	empty, err := ParseSchedule("", 5)
+	if err != nil || len(empty.Shifts) != 0 {
+		t.Fatalf("expected empty schedule, got %+v, %v", empty, err)
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, spec := range []string{
+		"17:00-21:00",       // no size
+		"17:00-21:00=0",     // non-positive size
+		"17:00=20",          // no range
+		"25:00-21:00=20",    // bad clock
+		"fun@10:00-11:00=2", // bad day
+	} {
+		spec := spec
+		t.Run(spec, func(t *testing.T) {
+			t.Parallel()
+			if _, err := ParseSchedule(spec, 5); err == nil {
+				t.Fatalf("expected error for %q", spec)
+			}
+		})
+	}
+}
+
+func TestScheduleSizeAt(t *testing.T) {
+	t.Parallel()
+
+	s, err := ParseSchedule("fri@23:00-02:00=3,17:00-21:00=20,23:00-06:00=5", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2026-10-12 is a Monday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, 12+day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want int
+	}{
+		{name: "default_morning", t: at(0, 9, 0), want: 10},
+		{name: "dinner_start", t: at(0, 17, 0), want: 20},
+		{name: "dinner_end_exclusive", t: at(0, 21, 0), want: 10},
+		{name: "overnight_before_midnight", t: at(0, 23, 30), want: 5},
+		{name: "overnight_after_midnight", t: at(1, 5, 59), want: 5},
+		{name: "friday_late_first_match", t: at(4, 23, 30), want: 3},
+		{name: "friday_shift_into_saturday", t: at(5, 1, 0), want: 3},
+		{name: "saturday_after_friday_shift", t: at(5, 3, 0), want: 5},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := s.SizeAt(tt.t); got != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSchedulerAppliesShifts(t *testing.T) {
+	t.Parallel()
+
+	s, err := ParseSchedule("17:00-21:00=20", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New(5)
+	sc := NewScheduler(p, s)
+
+	now := time.Date(2026, 10, 12, 16, 59, 0, 0, time.UTC)
+	sc.now = func() time.Time { return now }
+
+	sc.apply()
+	if p.Cap() != 5 {
+		t.Fatalf("expected cap=5 before the shift, got %d", p.Cap())
+	}
+	snap := sc.Snapshot()
+	if snap.ActiveShift != -1 || snap.ActiveSize != 5 {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+
+	now = now.Add(time.Minute)
+	sc.apply()
+	if p.Cap() != 20 {
+		t.Fatalf("expected cap=20 during the shift, got %d", p.Cap())
+	}
+	snap = sc.Snapshot()
+	if snap.ActiveShift != 0 || snap.ActiveSize != 20 || snap.Shifts[0].Start != "17:00" || snap.Shifts[0].End != "21:00" {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+}
+
+func TestSchedulerRunStopsOnCancel(t *testing.T) {
+	t.Parallel()
+
+	p := New(2)
+	sc := NewScheduler(p, Schedule{Default: 7})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sc.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for p.Cap() != 7 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected cap=7, got %d", p.Cap())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
@@ -0,0 +1,141 @@
+// Package shared holds the wait every simulated service step makes for
+// its delay.
+//
+// SleepOrDone used to be copied into each service as waitOrCancel. It
+// moved here once it grew a timer pool and a fake clock: at high rates
+// most steps wait about a millisecond, and a fresh runtime timer per
+// wait was the largest source of allocations on the /order path.
+package shared
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/ctxerr"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/simulation"
+)
+
+// timers holds stopped timers for reuse. Since Go 1.23 a stopped or
+// reset timer never delivers a stale value, so a pooled timer needs no
+// draining.
+var timers sync.Pool // *time.Timer
+
+// SleepOrDone blocks for d or until ctx is done.
+//
+// It returns nil if the duration elapses, or ctxerr.Err(ctx) if the
+// context is done first. If d <= 0, or d is short enough for
+// simulation.Skip, it returns immediately without arming a timer. The
+// timer comes from the Clock carried by ctx (see WithClock), or else
+// from a pool of runtime timers.
+func SleepOrDone(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	if simulation.Skip(ctx, d) {
+		if ctx.Err() != nil {
+			return ctxerr.Err(ctx)
+		}
+		return nil
+	}
+	if c, ok := ctx.Value(clockKey{}).(Clock); ok {
+		ch, stop := c.Timer(d)
+		defer stop()
+		return waitOn(ctx, ch)
+	}
+
+	t, ok := timers.Get().(*time.Timer)
+	if ok {
+		t.Reset(d)
+	} else {
+		t = time.NewTimer(d)
+	}
+	defer func() {
+		t.Stop()
+		timers.Put(t)
+	}()
+	return waitOn(ctx, t.C)
+}
+
+// waitOn blocks until ch receives or ctx is done.
+func waitOn(ctx context.Context, ch <-chan time.Time) error {
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctxerr.Err(ctx)
+	}
+}
+
+// Clock makes the timers SleepOrDone waits on.
+type Clock interface {
+	// Timer returns a channel receiving once d has elapsed, and a func
+	// stopping the timer, which SleepOrDone calls when it stops waiting.
+	Timer(d time.Duration) (<-chan time.Time, func())
+}
+
+type clockKey struct{}
+
+// WithClock returns a copy of ctx whose waits use c instead of runtime
+// timers, for tests driving steps with a FakeClock.
+func WithClock(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, c)
+}
+
+// FakeClock is a Clock whose time moves only with Advance.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers map[*fakeTimer]struct{}
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time // buffered; receives once
+}
+
+// NewFakeClock returns a FakeClock reading now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, timers: map[*fakeTimer]struct{}{}}
+}
+
+// Now returns the clock's time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Timer implements Clock.
+func (c *FakeClock) Timer(d time.Duration) (<-chan time.Time, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{at: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.timers[t] = struct{}{}
+	return t.c, func() {
+		c.mu.Lock()
+		delete(c.timers, t)
+		c.mu.Unlock()
+	}
+}
+
+// Advance moves the clock forward by d and fires the timers due by then.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for t := range c.timers {
+		if !t.at.After(c.now) {
+			t.c <- c.now
+			delete(c.timers, t)
+		}
+	}
+}
+
+// Waiting returns the number of timers not yet fired or stopped, so
+// that a test can advance the clock once its steps are all waiting.
+func (c *FakeClock) Waiting() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
@@ -0,0 +1,139 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/simulation"
+)
+
+func TestSleepOrDone(t *testing.T) {
+	t.Parallel()
+
+	canceled, cancel := context.WithCancelCause(context.Background())
+	cancel(errors.New("sibling failed"))
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		d       time.Duration
+		wantErr error
+	}{
+		{name: "elapses", ctx: context.Background(), d: time.Millisecond},
+		{name: "zero", ctx: canceled, d: 0},
+		{name: "canceled", ctx: canceled, d: time.Hour, wantErr: context.Canceled},
+		{name: "simulated", ctx: simulation.NewContext(context.Background()), d: time.Millisecond},
+		{name: "simulated_canceled", ctx: simulation.NewContext(canceled), d: time.Millisecond, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// Twice, so that the second wait reuses a pooled timer.
+			for range 2 {
+				err := SleepOrDone(tt.ctx, tt.d)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				if err != nil && !strings.Contains(err.Error(), "sibling failed") {
+					t.Fatalf("expected the cause in %q", err)
+				}
+			}
+		})
+	}
+}
+
+func TestSleepOrDone_FakeClock(t *testing.T) {
+	t.Parallel()
+
+	clock := NewFakeClock(time.Unix(1_700_000_000, 0))
+	ctx := WithClock(context.Background(), clock)
+
+	var woke atomic.Int32
+	done := make(chan error, 2)
+	for _, d := range []time.Duration{time.Second, time.Minute} {
+		go func() {
+			err := SleepOrDone(ctx, d)
+			woke.Add(1)
+			done <- err
+		}()
+	}
+	for clock.Waiting() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Advance(999 * time.Millisecond)
+	if clock.Waiting() != 2 || woke.Load() != 0 {
+		t.Fatalf("expected both waits pending, got %d woken", woke.Load())
+	}
+	clock.Advance(time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if clock.Waiting() != 1 {
+		t.Fatalf("expected one wait pending, got %d", clock.Waiting())
+	}
+	clock.Advance(time.Minute)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A canceled wait stops its timer.
+	cctx, cancel := context.WithCancel(ctx)
+	go func() {
+		for clock.Waiting() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	if err := SleepOrDone(cctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if clock.Waiting() != 0 {
+		t.Fatalf("expected no waits pending, got %d", clock.Waiting())
+	}
+}
+
+// BenchmarkSleepOrDone runs the stress test's step workload, concurrent
+// 1ms waits with every fifth one cut short by a canceled context, with
+// pooled timers, with a new timer per wait as every service did before,
+// and under simulation.
+func BenchmarkSleepOrDone(b *testing.B) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	newTimer := func(ctx context.Context, d time.Duration) error {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		return waitOn(ctx, t.C)
+	}
+
+	benchmarks := []struct {
+		name  string
+		ctx   context.Context
+		sleep func(context.Context, time.Duration) error
+	}{
+		{name: "pooled", ctx: context.Background(), sleep: SleepOrDone},
+		{name: "new_timer", ctx: context.Background(), sleep: newTimer},
+		{name: "simulation", ctx: simulation.NewContext(context.Background()), sleep: SleepOrDone},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetParallelism(8)
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					ctx := bm.ctx
+					if i%5 == 0 {
+						ctx = canceled
+					}
+					_ = bm.sleep(ctx, time.Millisecond)
+				}
+			})
+		})
+	}
+}
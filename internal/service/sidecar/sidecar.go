@@ -0,0 +1,427 @@
+// Package sidecar runs pipeline steps implemented by external processes.
+//
+// A sidecar is a long-running child process speaking newline-delimited
+// JSON over its stdin and stdout, so a step can be written in any
+// language without rebuilding the server. Each request carries an ID
+// and the sidecar answers it with the same ID, in any order, so one
+// process serves concurrent orders:
+//
+//	→ {"id":1,"type":"run","order":{"order_id":"o-1","amount":1200,...}}
+//	← {"id":1}
+//	→ {"id":2,"type":"health"}
+//	← {"id":2,"error":"model not loaded"}
+//
+// A response without "error" is success. A failed run may name the
+// error kind the order fails with in "kind". Anything the sidecar
+// writes to stderr is logged.
+//
+// A Sidecar supervises its process: it restarts the process when it
+// exits, probes it with health requests and restarts it when they fail,
+// and bounds each call by the sidecar's timeout.
+package sidecar
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/progress"
+)
+
+type unavailableError struct{}
+
+func (unavailableError) Error() string { return "sidecar unavailable" }
+func (unavailableError) Kind() string  { return "sidecar_unavailable" }
+
+// ErrUnavailable is returned by steps whose sidecar is not running or
+// not healthy, or exits while a call is outstanding.
+var ErrUnavailable = unavailableError{}
+
+// stepError is a failure reported by the sidecar itself.
+type stepError struct {
+	kind, msg string
+}
+
+func (e stepError) Error() string { return e.msg }
+func (e stepError) Kind() string  { return e.kind }
+
+// defaultTimeout bounds calls of sidecars configured without a timeout.
+const defaultTimeout = 2 * time.Second
+
+// Config describes one sidecar step.
+type Config struct {
+	Name    string        // step name
+	Command []string      // program and arguments
+	Timeout time.Duration // per call; non-positive defaults to 2 seconds
+}
+
+// ParseSpecs parses a comma-separated list of sidecar steps of the form
+//
+//	name[:timeout]=command [args...]
+//
+// for example "fraud:500ms=./bin/fraud-check --strict,kyc=python3 kyc.py".
+// Commands are split on whitespace and run without a shell.
+func ParseSpecs(spec string) ([]Config, error) {
+	var out []Config
+	seen := map[string]bool{}
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		head, command, ok := strings.Cut(entry, "=")
+		name, timeout, hasTimeout := strings.Cut(head, ":")
+		cfg := Config{Name: strings.TrimSpace(name), Command: strings.Fields(command)}
+		if !ok || cfg.Name == "" || len(cfg.Command) == 0 {
+			return nil, fmt.Errorf("sidecar: step %q: want name[:timeout]=command", entry)
+		}
+		if hasTimeout {
+			d, err := time.ParseDuration(timeout)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("sidecar: step %q: timeout must be a positive duration", entry)
+			}
+			cfg.Timeout = d
+		}
+		if seen[cfg.Name] {
+			return nil, fmt.Errorf("sidecar: step %q configured twice", cfg.Name)
+		}
+		seen[cfg.Name] = true
+		out = append(out, cfg)
+	}
+	return out, nil
+}
+
+// request is one line written to the sidecar.
+type request struct {
+	ID    uint64              `json:"id"`
+	Type  string              `json:"type"` // "run" or "health"
+	Order *model.OrderRequest `json:"order,omitempty"`
+}
+
+// response is one line read from the sidecar.
+type response struct {
+	ID    uint64 `json:"id"`
+	Error string `json:"error,omitempty"`
+	Kind  string `json:"kind,omitempty"`
+}
+
+// process is one running instance of the sidecar's command.
+type process struct {
+	cmd  *exec.Cmd
+	done chan struct{} // closed once the process has exited
+
+	wmu   sync.Mutex
+	stdin io.WriteCloser
+
+	mu      sync.Mutex
+	pending map[uint64]chan response
+}
+
+// Sidecar runs and supervises one sidecar step. It is safe for
+// concurrent use.
+type Sidecar struct {
+	cfg            Config
+	healthInterval time.Duration
+	restartDelay   time.Duration
+	logger         *slog.Logger
+
+	seq      atomic.Uint64
+	calls    atomic.Int64
+	failures atomic.Int64
+	restarts atomic.Int64
+
+	mu      sync.Mutex
+	proc    *process // nil while not running
+	healthy bool
+	lastErr string
+}
+
+// New returns a Sidecar for cfg, health-checked every healthInterval
+// and restarted restartDelay after its process exits. Nothing runs
+// until Run is called.
+//
+// A non-positive healthInterval defaults to 10 seconds and a
+// non-positive restartDelay to 1 second. It panics if cfg has no
+// command or logger is nil.
+func New(cfg Config, healthInterval, restartDelay time.Duration, logger *slog.Logger) *Sidecar {
+	if len(cfg.Command) == 0 {
+		panic("sidecar.New: no command")
+	}
+	if logger == nil {
+		panic("sidecar.New: nil logger")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if healthInterval <= 0 {
+		healthInterval = 10 * time.Second
+	}
+	if restartDelay <= 0 {
+		restartDelay = time.Second
+	}
+	return &Sidecar{cfg: cfg, healthInterval: healthInterval, restartDelay: restartDelay, logger: logger}
+}
+
+// Name returns the sidecar's step name.
+func (s *Sidecar) Name() string { return s.cfg.Name }
+
+// Run starts the sidecar's process and keeps it running until ctx is
+// done, then kills it. Each process must answer a health request before
+// it takes steps.
+func (s *Sidecar) Run(ctx context.Context) {
+	for {
+		p, err := s.start()
+		if err != nil {
+			s.setState(nil, false, err.Error())
+			s.logger.LogAttrs(ctx, slog.LevelError, "sidecar failed to start",
+				slog.String("step", s.cfg.Name),
+				slog.String("error", err.Error()),
+			)
+		} else {
+			s.supervise(ctx, p)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		s.restarts.Add(1)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.restartDelay):
+		}
+	}
+}
+
+// start launches a new process and begins reading its responses.
+func (s *Sidecar) start() (*process, error) {
+	cmd := exec.Command(s.cfg.Command[0], s.cfg.Command[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &process{cmd: cmd, done: make(chan struct{}), stdin: stdin, pending: make(map[uint64]chan response)}
+	logged := make(chan struct{})
+	go func() {
+		s.logStderr(stderr)
+		close(logged)
+	}()
+	go func() {
+		s.readResponses(p, stdout)
+		<-logged
+		_ = cmd.Wait() // after both pipes are drained
+		p.mu.Lock()
+		p.pending = nil // later calls see the process gone
+		p.mu.Unlock()
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// supervise health-checks p until it exits, fails a health check or ctx
+// is done, and then makes sure it is gone.
+func (s *Sidecar) supervise(ctx context.Context, p *process) {
+	defer func() {
+		_ = p.cmd.Process.Kill()
+		<-p.done
+		s.setState(nil, false, "")
+	}()
+
+	t := time.NewTicker(s.healthInterval)
+	defer t.Stop()
+	for {
+		if err := s.check(ctx, p); err != nil {
+			if ctx.Err() == nil {
+				s.logger.LogAttrs(ctx, slog.LevelWarn, "sidecar health check failed; restarting",
+					slog.String("step", s.cfg.Name),
+					slog.String("error", err.Error()),
+				)
+			}
+			s.setState(p, false, err.Error())
+			return
+		}
+		s.setState(p, true, "")
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.done:
+			s.logger.LogAttrs(ctx, slog.LevelWarn, "sidecar exited; restarting",
+				slog.String("step", s.cfg.Name),
+			)
+			s.setState(p, false, "process exited")
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// check sends one health request to p.
+func (s *Sidecar) check(ctx context.Context, p *process) error {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	return s.call(ctx, p, request{Type: "health"})
+}
+
+// Step runs the sidecar step for req. It implements the order.Step Run
+// signature.
+func (s *Sidecar) Step(ctx context.Context, req model.OrderRequest) error {
+	s.calls.Add(1)
+	err := s.step(ctx, req)
+	if err != nil {
+		s.failures.Add(1)
+	}
+	return err
+}
+
+func (s *Sidecar) step(ctx context.Context, req model.OrderRequest) error {
+	s.mu.Lock()
+	p, healthy := s.proc, s.healthy
+	s.mu.Unlock()
+	if p == nil || !healthy {
+		return fmt.Errorf("%s: %w", s.cfg.Name, ErrUnavailable)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	defer progress.KeepAlive(ctx, "waiting for the sidecar")()
+	if err := s.call(ctx, p, request{Type: "run", Order: &req}); err != nil {
+		return fmt.Errorf("%s: %w", s.cfg.Name, err)
+	}
+	return nil
+}
+
+// call sends r to p and waits for its response.
+func (s *Sidecar) call(ctx context.Context, p *process, r request) error {
+	r.ID = s.seq.Add(1)
+	ch := make(chan response, 1)
+	p.mu.Lock()
+	if p.pending == nil {
+		p.mu.Unlock()
+		return ErrUnavailable
+	}
+	p.pending[r.ID] = ch
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, r.ID)
+		p.mu.Unlock()
+	}()
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	p.wmu.Lock()
+	_, err = p.stdin.Write(append(line, '\n'))
+	p.wmu.Unlock()
+	if err != nil {
+		return ErrUnavailable
+	}
+
+	select {
+	case resp := <-ch:
+		switch {
+		case resp.Error == "":
+			return nil
+		case resp.Kind == "":
+			return errors.New(resp.Error)
+		default:
+			return stepError{kind: resp.Kind, msg: resp.Error}
+		}
+	case <-p.done:
+		return ErrUnavailable
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// readResponses delivers each response line to its waiting call until
+// stdout closes. Lines that are not responses to a pending call are
+// logged and dropped.
+func (s *Sidecar) readResponses(p *process, stdout io.Reader) {
+	sc := bufio.NewScanner(stdout)
+	for sc.Scan() {
+		var resp response
+		if err := json.Unmarshal(sc.Bytes(), &resp); err != nil {
+			s.logger.Warn("sidecar wrote an invalid response", slog.String("step", s.cfg.Name), slog.String("error", err.Error()))
+			continue
+		}
+		p.mu.Lock()
+		ch, ok := p.pending[resp.ID]
+		p.mu.Unlock()
+		if ok {
+			ch <- resp // buffered; each ID is answered once
+		}
+	}
+}
+
+// logStderr logs each line the process writes to stderr.
+func (s *Sidecar) logStderr(stderr io.Reader) {
+	sc := bufio.NewScanner(stderr)
+	for sc.Scan() {
+		s.logger.Info("sidecar output", slog.String("step", s.cfg.Name), slog.String("line", sc.Text()))
+	}
+}
+
+func (s *Sidecar) setState(p *process, healthy bool, lastErr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.proc, s.healthy = p, healthy
+	if lastErr != "" {
+		s.lastErr = lastErr
+	}
+}
+
+// Status reports the sidecar's health and counters.
+func (s *Sidecar) Status() model.SidecarStatus {
+	s.mu.Lock()
+	st := model.SidecarStatus{
+		Step:      s.cfg.Name,
+		Command:   strings.Join(s.cfg.Command, " "),
+		TimeoutMS: s.cfg.Timeout.Milliseconds(),
+		Running:   s.proc != nil,
+		Healthy:   s.healthy,
+		LastError: s.lastErr,
+	}
+	if s.proc != nil {
+		st.PID = s.proc.cmd.Process.Pid
+	}
+	s.mu.Unlock()
+
+	st.Calls = s.calls.Load()
+	st.Failures = s.failures.Load()
+	st.Restarts = s.restarts.Load()
+	return st
+}
+
+// Set is the configured sidecar steps.
+type Set []*Sidecar
+
+// Status reports every sidecar in the set, in configuration order.
+func (set Set) Status() []model.SidecarStatus {
+	out := make([]model.SidecarStatus, 0, len(set))
+	for _, s := range set {
+		out = append(out, s.Status())
+	}
+	return out
+}
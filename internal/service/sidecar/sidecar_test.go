@@ -0,0 +1,166 @@
+package sidecar
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// TestHelperProcess is the sidecar run by the tests: the test binary
+// re-executed with SIDECAR_HELPER set to the behavior to simulate. It
+// declines order "o-flagged", never answers order "o-hang", and
+// otherwise accepts orders.
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv("SIDECAR_HELPER")
+	if mode == "" {
+		return
+	}
+	sc := bufio.NewScanner(os.Stdin)
+	enc := json.NewEncoder(os.Stdout)
+	for sc.Scan() {
+		var req request
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+			os.Exit(2)
+		}
+		resp := response{ID: req.ID}
+		switch {
+		case req.Type == "health" && mode == "unhealthy":
+			resp.Error = "model not loaded"
+		case req.Type == "health":
+		case mode == "crash":
+			os.Exit(1)
+		case req.Order.OrderID == "o-hang":
+			continue
+		case req.Order.OrderID == "o-flagged":
+			resp.Error, resp.Kind = "order flagged", "fraud_suspected"
+		}
+		_ = enc.Encode(resp)
+	}
+	os.Exit(0)
+}
+
+// startHelper runs a Sidecar over the helper process in mode until the
+// test ends.
+func startHelper(t *testing.T, mode string) *Sidecar {
+	t.Helper()
+	t.Setenv("SIDECAR_HELPER", mode)
+	s := New(Config{
+		Name:    "fraud",
+		Command: []string{os.Args[0], "-test.run=^TestHelperProcess$"},
+		Timeout: 200 * time.Millisecond,
+	}, 20*time.Millisecond, 10*time.Millisecond, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return s
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestParseSpecs(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseSpecs("fraud:500ms=./bin/fraud-check --strict, kyc=python3 kyc.py")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "fraud" || got[0].Timeout != 500*time.Millisecond ||
+		fmt.Sprint(got[0].Command) != "[./bin/fraud-check --strict]" || got[1].Name != "kyc" || got[1].Timeout != 0 {
+		t.Fatalf("unexpected configs %+v", got)
+	}
+
+	for _, spec := range []string{"fraud", "=./check", "fraud=", "fraud:x=./check", "fraud:-1s=./check", "fraud=./a,fraud=./b"} {
+		if _, err := ParseSpecs(spec); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestSidecarStep(t *testing.T) {
+	s := startHelper(t, "ok")
+	waitFor(t, "healthy sidecar", func() bool { return s.Status().Healthy })
+
+	tests := []struct {
+		name     string
+		orderID  string
+		wantKind string
+		wantErr  error
+	}{
+		{name: "accepted", orderID: "o-1"},
+		{name: "declined", orderID: "o-flagged", wantKind: "fraud_suspected"},
+		{name: "timeout", orderID: "o-hang", wantErr: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		err := s.Step(context.Background(), model.OrderRequest{OrderID: tt.orderID, Amount: 1200})
+		var k interface{ Kind() string }
+		switch {
+		case tt.wantKind != "":
+			if !errors.As(err, &k) || k.Kind() != tt.wantKind {
+				t.Fatalf("%s: expected kind %s, got %v", tt.name, tt.wantKind, err)
+			}
+		case !errors.Is(err, tt.wantErr):
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+	if st := s.Status(); st.Calls != 3 || st.Failures != 2 || st.PID == 0 {
+		t.Fatalf("expected 3 calls and 2 failures of a running process, got %+v", st)
+	}
+}
+
+func TestSidecarRestartsAfterExit(t *testing.T) {
+	s := startHelper(t, "crash")
+	waitFor(t, "healthy sidecar", func() bool { return s.Status().Healthy })
+
+	if err := s.Step(context.Background(), model.OrderRequest{OrderID: "o-1", Amount: 1}); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected %v, got %v", ErrUnavailable, err)
+	}
+	waitFor(t, "restarted sidecar", func() bool {
+		st := s.Status()
+		return st.Restarts > 0 && st.Healthy
+	})
+}
+
+func TestSidecarUnhealthy(t *testing.T) {
+	s := startHelper(t, "unhealthy")
+	waitFor(t, "failed health check", func() bool { return s.Status().LastError == "model not loaded" })
+
+	if err := s.Step(context.Background(), model.OrderRequest{OrderID: "o-1", Amount: 1}); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected %v, got %v", ErrUnavailable, err)
+	}
+}
+
+func TestSidecarStep_NotRunning(t *testing.T) {
+	t.Parallel()
+
+	s := New(Config{Name: "fraud", Command: []string{"true"}}, 0, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := s.Step(context.Background(), model.OrderRequest{}); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected %v, got %v", ErrUnavailable, err)
+	}
+}
@@ -0,0 +1,167 @@
+// Package tax provides the tax calculation run ahead of payment.
+//
+// A Calculator asks a Provider for the tax owed on an order and hands
+// the payment step the order with its amount raised to the taxed total.
+// Providers are pluggable: Fake applies a flat rate in process, and
+// HTTPProvider calls an external tax service.
+package tax
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/ctxerr"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/progress"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/shared"
+)
+
+type unavailableError struct{}
+
+func (unavailableError) Error() string { return "tax unavailable" }
+func (unavailableError) Kind() string  { return "tax_unavailable" }
+
+// ErrUnavailable is returned when the tax on an order cannot be
+// computed.
+var ErrUnavailable = unavailableError{}
+
+// Provider computes the tax owed on an order, in the units of its
+// amount.
+type Provider interface {
+	Tax(ctx context.Context, req model.OrderRequest) (uint64, error)
+}
+
+// Fake is an in-process Provider charging a flat rate.
+//
+// It simulates latency using the "tax" delay override and respects
+// context cancellation. An order with fail_step "tax" fails with
+// ErrUnavailable.
+type Fake struct {
+	RateBP uint64 // rate in basis points; 825 is 8.25%
+}
+
+// Tax implements Provider. The tax is rounded down.
+func (f Fake) Tax(ctx context.Context, req model.OrderRequest) (uint64, error) {
+	const stepName = "tax"
+	delay := req.StepDelay(stepName, 20*time.Millisecond)
+
+	// Block step until the delay elapses or the context is done
+	if err := shared.SleepOrDone(ctx, delay); err != nil {
+		return 0, err
+	}
+
+	if req.FailStep == stepName {
+		return 0, fmt.Errorf("tax: %w", ErrUnavailable)
+	}
+	return req.Amount * f.RateBP / 10000, nil
+}
+
+// HTTPProvider asks an external tax service. It POSTs
+//
+//	{"order_id": "...", "amount": 1200, "zone": "..."}
+//
+// to URL and expects a 200 response of the form {"tax": 99}.
+type HTTPProvider struct {
+	URL    string
+	Client *http.Client // nil means a client with a 5-second timeout
+}
+
+var defaultClient = &http.Client{Timeout: 5 * time.Second}
+
+type taxRequest struct {
+	OrderID string `json:"order_id"`
+	Amount  uint64 `json:"amount"`
+	Zone    string `json:"zone,omitempty"`
+}
+
+type taxResponse struct {
+	Tax *uint64 `json:"tax"`
+}
+
+// Tax implements Provider. Transport, status and decoding failures wrap
+// ErrUnavailable; a done ctx returns its context error.
+func (p HTTPProvider) Tax(ctx context.Context, req model.OrderRequest) (uint64, error) {
+	body, err := json.Marshal(taxRequest{OrderID: req.OrderID, Amount: req.Amount, Zone: req.Zone})
+	if err != nil {
+		return 0, fmt.Errorf("tax: %w: %v", ErrUnavailable, err)
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("tax: %w: %v", ErrUnavailable, err)
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+
+	client := p.Client
+	if client == nil {
+		client = defaultClient
+	}
+	defer progress.KeepAlive(ctx, "waiting for the tax provider")()
+	resp, err := client.Do(hreq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, ctxerr.Err(ctx)
+		}
+		return 0, fmt.Errorf("tax: %w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("tax: %w: %s", ErrUnavailable, resp.Status)
+	}
+	var out taxResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || out.Tax == nil {
+		return 0, fmt.Errorf("tax: %w: invalid response", ErrUnavailable)
+	}
+	return *out.Tax, nil
+}
+
+// Calculator runs a Provider ahead of the payment step.
+type Calculator struct {
+	provider Provider
+	critical bool
+	logger   *slog.Logger
+}
+
+// New returns a Calculator using p. If critical is false, an order whose
+// tax cannot be computed is charged its untaxed amount and the failure
+// is logged; otherwise the order fails with the provider's error.
+//
+// It panics if p or logger is nil.
+func New(p Provider, critical bool, logger *slog.Logger) *Calculator {
+	if p == nil {
+		panic("tax.New: nil provider")
+	}
+	if logger == nil {
+		panic("tax.New: nil logger")
+	}
+	return &Calculator{provider: p, critical: critical, logger: logger}
+}
+
+// Wrap returns run preceded by the tax calculation: run receives the
+// order with Amount set to the taxed total. Context errors always stop
+// the order, whatever the criticality.
+func (c *Calculator) Wrap(run func(context.Context, model.OrderRequest) error) func(context.Context, model.OrderRequest) error {
+	return func(ctx context.Context, req model.OrderRequest) error {
+		t, err := c.provider.Tax(ctx, req)
+		switch {
+		case err == nil:
+			if req.Amount+t < req.Amount {
+				return fmt.Errorf("tax: %w: total overflows", ErrUnavailable)
+			}
+			req.Amount += t
+		case c.critical || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+			return err
+		default:
+			c.logger.LogAttrs(ctx, slog.LevelWarn, "tax skipped",
+				slog.String("order_id", req.OrderID),
+				slog.String("error", err.Error()),
+			)
+		}
+		return run(ctx, req)
+	}
+}
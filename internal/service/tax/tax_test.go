@@ -0,0 +1,180 @@
+package tax
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestFakeTax(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		req     model.OrderRequest
+		want    uint64
+		wantErr error
+	}{
+		{name: "flat_rate", req: model.OrderRequest{Amount: 1200, DelayMS: map[string]int64{"tax": 1}}, want: 99},
+		{name: "rounds_down", req: model.OrderRequest{Amount: 10, DelayMS: map[string]int64{"tax": 1}}, want: 0},
+		{name: "fail_step", req: model.OrderRequest{Amount: 1200, FailStep: "tax", DelayMS: map[string]int64{"tax": 1}}, wantErr: ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Fake{RateBP: 825}.Tax(context.Background(), tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHTTPProviderTax(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    uint64
+		wantErr error
+	}{
+		{name: "ok", status: http.StatusOK, body: `{"tax": 99}`, want: 99},
+		{name: "zero_tax", status: http.StatusOK, body: `{"tax": 0}`, want: 0},
+		{name: "server_error", status: http.StatusBadGateway, body: `{}`, wantErr: ErrUnavailable},
+		{name: "missing_tax", status: http.StatusOK, body: `{}`, wantErr: ErrUnavailable},
+		{name: "malformed", status: http.StatusOK, body: `{"tax":`, wantErr: ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var in taxRequest
+				if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.OrderID != "o-1" || in.Amount != 1200 || in.Zone != "north" {
+					t.Errorf("unexpected request %+v, err=%v", in, err)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			p := HTTPProvider{URL: srv.URL, Client: srv.Client()}
+			got, err := p.Tax(context.Background(), model.OrderRequest{OrderID: "o-1", Amount: 1200, Zone: "north"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHTTPProviderTax_ContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := HTTPProvider{URL: srv.URL, Client: srv.Client()}.Tax(ctx, model.OrderRequest{OrderID: "o-1", Amount: 1200})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+type stubProvider struct {
+	tax uint64
+	err error
+}
+
+func (s stubProvider) Tax(context.Context, model.OrderRequest) (uint64, error) { return s.tax, s.err }
+
+func TestCalculatorWrap(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		provider   stubProvider
+		critical   bool
+		wantErr    error
+		wantAmount uint64 // amount seen by the payment step; 0 if it did not run
+		wantLog    bool
+	}{
+		{name: "taxed_total", provider: stubProvider{tax: 99}, critical: true, wantAmount: 1299},
+		{name: "critical_failure", provider: stubProvider{err: ErrUnavailable}, critical: true, wantErr: ErrUnavailable},
+		{name: "non_critical_failure", provider: stubProvider{err: ErrUnavailable}, wantAmount: 1200, wantLog: true},
+		{name: "non_critical_timeout", provider: stubProvider{err: context.DeadlineExceeded}, wantErr: context.DeadlineExceeded},
+		{name: "overflow", provider: stubProvider{tax: ^uint64(0)}, wantErr: ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			c := New(tt.provider, tt.critical, slog.New(slog.NewTextHandler(&buf, nil)))
+			var charged uint64
+			run := c.Wrap(func(_ context.Context, req model.OrderRequest) error {
+				charged = req.Amount
+				return nil
+			})
+
+			err := run(context.Background(), model.OrderRequest{OrderID: "o-1", Amount: 1200})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if charged != tt.wantAmount {
+				t.Fatalf("expected payment of %d, got %d", tt.wantAmount, charged)
+			}
+			if got := strings.Contains(buf.String(), "tax skipped"); got != tt.wantLog {
+				t.Fatalf("expected log=%v, got %q", tt.wantLog, buf.String())
+			}
+		})
+	}
+}
+
+func TestNew_Panics(t *testing.T) {
+	t.Parallel()
+
+	for name, fn := range map[string]func(){
+		"nil_provider": func() { New(nil, true, slog.New(slog.DiscardHandler)) },
+		"nil_logger":   func() { New(Fake{}, true, nil) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			fn()
+		})
+	}
+}
@@ -0,0 +1,37 @@
+// Package simulation marks orders processed in simulation mode, where
+// the steps' delays only stand in for upstream latency.
+//
+// Load tests drive the pipeline with delay_ms of 0 or 1 at high rates,
+// and arming a runtime timer per step for a millisecond or less then
+// costs more CPU than the rest of the order. A step waiting for a delay
+// asks Skip first: under simulation, waits of at most Threshold return
+// at once, without a timer. Longer waits, and every wait outside
+// simulation, are unchanged.
+package simulation
+
+import (
+	"context"
+	"time"
+)
+
+// Threshold is the longest delay Skip elides.
+const Threshold = time.Millisecond
+
+type activeKey struct{}
+
+// NewContext returns a copy of ctx marked for simulation.
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, activeKey{}, true)
+}
+
+// Active reports whether ctx is marked for simulation.
+func Active(ctx context.Context) bool {
+	on, _ := ctx.Value(activeKey{}).(bool)
+	return on
+}
+
+// Skip reports whether a wait of d may return without blocking: d is at
+// most Threshold and ctx is marked for simulation.
+func Skip(ctx context.Context, d time.Duration) bool {
+	return d <= Threshold && Active(ctx) // compare first; Value walks the context chain
+}
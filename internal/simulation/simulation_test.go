@@ -0,0 +1,38 @@
+package simulation
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSkip(t *testing.T) {
+	t.Parallel()
+
+	sim := NewContext(context.Background())
+	tests := []struct {
+		name string
+		ctx  context.Context
+		d    time.Duration
+		want bool
+	}{
+		{name: "simulated_short", ctx: sim, d: time.Millisecond, want: true},
+		{name: "simulated_sub_ms", ctx: sim, d: time.Microsecond, want: true},
+		{name: "simulated_long", ctx: sim, d: 2 * time.Millisecond},
+		{name: "live_short", ctx: context.Background(), d: time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := Skip(tt.ctx, tt.d); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if !Active(sim) || Active(context.Background()) {
+		t.Fatal("expected only the marked context active")
+	}
+}
@@ -0,0 +1,55 @@
+// Package statuswriter records what a handler wrote to an
+// http.ResponseWriter, for the middlewares that log, count or trace
+// responses once next has returned.
+package statuswriter
+
+import "net/http"
+
+// Writer wraps an http.ResponseWriter and records the status code and
+// the number of body bytes written. Unwrap lets http.ResponseController
+// reach the underlying writer, so deadlines and flushing keep working
+// through it.
+type Writer struct {
+	http.ResponseWriter
+	status int
+	n      int64
+}
+
+// New returns a Writer wrapping w.
+func New(w http.ResponseWriter) *Writer {
+	return &Writer{ResponseWriter: w}
+}
+
+// WriteHeader records the first status code and forwards it.
+func (w *Writer) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write forwards p, counting the bytes written; a write before
+// WriteHeader implies 200.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying writer.
+func (w *Writer) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Status returns the status code written, or 200 if the handler wrote
+// nothing.
+func (w *Writer) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Bytes returns the number of body bytes written.
+func (w *Writer) Bytes() int64 { return w.n }
@@ -0,0 +1,87 @@
+package statuswriter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBytes  int64
+	}{
+		{
+			name:       "nothing_written",
+			handler:    func(http.ResponseWriter, *http.Request) {},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "implicit_ok",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("hello"))
+			},
+			wantStatus: http.StatusOK,
+			wantBytes:  5,
+		},
+		{
+			name: "first_status_kept",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte("down"))
+				_, _ = w.Write([]byte("!"))
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBytes:  5,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			w := New(rec)
+			tt.handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if w.Status() != tt.wantStatus || w.Bytes() != tt.wantBytes {
+				t.Fatalf("expected status=%d bytes=%d, got status=%d bytes=%d",
+					tt.wantStatus, tt.wantBytes, w.Status(), w.Bytes())
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected %d forwarded, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+// http.ResponseController reaches the connection through Unwrap.
+func TestWriter_ResponseController(t *testing.T) {
+	t.Parallel()
+
+	errs := make(chan error, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		rc := http.NewResponseController(New(w))
+		errs <- rc.SetWriteDeadline(time.Now().Add(time.Second))
+		errs <- rc.Flush()
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	for range 2 {
+		if err := <-errs; err != nil {
+			t.Fatalf("expected the controller to reach the connection, got %v", err)
+		}
+	}
+}
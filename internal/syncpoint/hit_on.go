@@ -0,0 +1,84 @@
+//go:build syncpoint
+
+package syncpoint
+
+import "sync"
+
+// Enabled reports whether sync points are compiled in.
+const Enabled = true
+
+type hookKey struct{ point, key string }
+
+var (
+	mu    sync.RWMutex
+	hooks = map[hookKey]func(){}
+)
+
+// Hit runs the hook registered for point and key, or else the one for
+// point and any key. The hook runs on the calling goroutine, so a hook
+// that blocks holds the instrumented code at the point.
+func Hit(point, key string) {
+	mu.RLock()
+	fn, ok := hooks[hookKey{point, key}]
+	if !ok {
+		fn = hooks[hookKey{point, ""}]
+	}
+	mu.RUnlock()
+	if fn != nil {
+		fn()
+	}
+}
+
+// Set registers fn to run whenever point is hit with key; an empty key
+// matches any key not registered on its own. It replaces an earlier hook
+// for the same point and key. A nil fn removes the hook.
+func Set(point, key string, fn func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	if fn == nil {
+		delete(hooks, hookKey{point, key})
+		return
+	}
+	hooks[hookKey{point, key}] = fn
+}
+
+// Reset removes every hook.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	clear(hooks)
+}
+
+// Signal returns a channel closed the first time point is hit with key.
+// It does not hold the goroutine hitting it.
+func Signal(point, key string) <-chan struct{} {
+	ch := make(chan struct{})
+	var once sync.Once
+	Set(point, key, func() { once.Do(func() { close(ch) }) })
+	return ch
+}
+
+// Pause holds every goroutine hitting a point until released.
+type Pause struct {
+	reached     chan struct{}
+	release     chan struct{}
+	reachOnce   sync.Once
+	releaseOnce sync.Once
+}
+
+// PauseAt holds goroutines hitting point with key until Release.
+func PauseAt(point, key string) *Pause {
+	p := &Pause{reached: make(chan struct{}), release: make(chan struct{})}
+	Set(point, key, func() {
+		p.reachOnce.Do(func() { close(p.reached) })
+		<-p.release
+	})
+	return p
+}
+
+// Reached returns a channel closed once a goroutine is held at the
+// point.
+func (p *Pause) Reached() <-chan struct{} { return p.reached }
+
+// Release lets held goroutines continue, and later ones pass through.
+func (p *Pause) Release() { p.releaseOnce.Do(func() { close(p.release) }) }
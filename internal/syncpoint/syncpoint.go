@@ -0,0 +1,36 @@
+// Package syncpoint marks named points in concurrent code where tests
+// can hold goroutines, to force an interleaving deterministically
+// instead of hoping a sleep produces it.
+//
+// Instrumented code calls Hit at each point. In normal builds Hit is an
+// empty function and compiles away. Built with the syncpoint tag
+// (go test -tags syncpoint), Hit runs the hook a test registered for the
+// point with Set, PauseAt or Signal. A point is hit with a key, such as
+// the step name, so a test can hold one step without holding the
+// others.
+//
+// Hooks are global. Tests that register them must not run in parallel
+// and should call Reset when done (t.Cleanup(syncpoint.Reset)); top-level
+// parallel tests only start once the sequential ones have finished.
+package syncpoint
+
+// The instrumented points and their keys.
+const (
+	// PoolAcquireWait is hit by pool.Acquire when no slot is free, after
+	// the caller is counted as waiting and before it blocks. Key "".
+	PoolAcquireWait = "pool.acquire.wait"
+	// PoolAcquired is hit once a slot is taken, by Acquire and
+	// TryAcquire. Key "".
+	PoolAcquired = "pool.acquired"
+	// PoolReleased is hit once a slot is freed or retired. Key "".
+	PoolReleased = "pool.released"
+
+	// OrderStepStart is hit just before a step runs. Key: step name.
+	OrderStepStart = "order.step.start"
+	// OrderStepEnd is hit when a step returns, before its result is
+	// recorded. Key: step name.
+	OrderStepEnd = "order.step.end"
+	// OrderStepFailed is hit after a failing step's error is recorded
+	// and, unless fail-at-end, its siblings are canceled. Key: step name.
+	OrderStepFailed = "order.step.failed"
+)
@@ -0,0 +1,65 @@
+//go:build syncpoint
+
+package syncpoint
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSet(t *testing.T) {
+	t.Cleanup(Reset)
+
+	var got []string
+	Set("p", "", func() { got = append(got, "any") })
+	Set("p", "a", func() { got = append(got, "a") })
+	Hit("p", "a")
+	Hit("p", "b")
+	Hit("q", "a")
+	Set("p", "a", nil)
+	Hit("p", "a")
+	if want := []string{"a", "any", "any"}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("expected hooks %v, got %v", want, got)
+	}
+
+	Reset()
+	Hit("p", "b")
+	if len(got) != 3 {
+		t.Fatalf("expected no hooks after Reset, got %v", got)
+	}
+}
+
+func TestPauseAt(t *testing.T) {
+	t.Cleanup(Reset)
+
+	p := PauseAt("p", "")
+	passed := make(chan struct{})
+	go func() {
+		Hit("p", "a")
+		close(passed)
+	}()
+	<-p.Reached()
+	select {
+	case <-passed:
+		t.Fatal("expected the goroutine held at the point")
+	case <-time.After(10 * time.Millisecond):
+	}
+	p.Release()
+	<-passed
+	Hit("p", "b") // released pauses let later hits through
+}
+
+func TestSignal(t *testing.T) {
+	t.Cleanup(Reset)
+
+	ch := Signal("p", "a")
+	Hit("p", "b")
+	select {
+	case <-ch:
+		t.Fatal("expected no signal for another key")
+	default:
+	}
+	Hit("p", "a")
+	Hit("p", "a")
+	<-ch
+}
@@ -0,0 +1,210 @@
+package tracedump
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/statuswriter"
+)
+
+// HeaderDebug turns on tracing for one request when set to "1" or
+// "true". HeaderTraceID returns the trace ID of a traced request.
+const (
+	HeaderDebug   = "X-Debug-Trace"
+	HeaderTraceID = "X-Debug-Trace-Id"
+)
+
+// Exporter appends traces to a file in OTLP JSON.
+type Exporter struct {
+	service string
+	logger  *slog.Logger
+	now     func() time.Time
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+// Open opens or creates the trace file at path for appending. Spans are
+// attributed to service. Write failures are logged through logger.
+//
+// It panics if logger is nil.
+func Open(path, service string, logger *slog.Logger) (*Exporter, error) {
+	if logger == nil {
+		panic("tracedump.Open: nil logger")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("tracedump: open %s: %w", path, err)
+	}
+	return &Exporter{service: service, logger: logger, now: time.Now, f: f}, nil
+}
+
+// Close closes the file.
+func (e *Exporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.f.Close()
+}
+
+// Middleware traces requests carrying HeaderDebug: it puts a Trace in
+// the request context, sets HeaderTraceID on the response, records a
+// server span for the request and exports the trace when next returns.
+func (e *Exporter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get(HeaderDebug); v != "1" && v != "true" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		t := newTrace(e.now)
+		root := &span{
+			id:    t.root,
+			name:  r.Method + " " + r.URL.Path,
+			kind:  kindServer,
+			start: t.now(),
+		}
+		w.Header().Set(HeaderTraceID, t.ID())
+		sw := statuswriter.New(w)
+		next.ServeHTTP(sw, r.WithContext(NewContext(r.Context(), t)))
+
+		root.end = t.now()
+		root.attrs = []attr{
+			{"http.request.method", r.Method},
+			{"url.path", r.URL.Path},
+			{"http.response.status_code", int64(sw.Status())},
+		}
+		if sw.Status() >= http.StatusInternalServerError {
+			root.status = statusError
+		}
+		t.add(root)
+
+		if err := e.export(t); err != nil {
+			e.logger.LogAttrs(r.Context(), slog.LevelError, "trace export failed",
+				slog.String("trace_id", t.ID()),
+				slog.String("error", err.Error()),
+			)
+		}
+	})
+}
+
+// export appends t to the file as one line.
+func (e *Exporter) export(t *Trace) error {
+	t.mu.Lock()
+	b, err := json.Marshal(e.encode(t))
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.f.Write(append(b, '\n'))
+	return err
+}
+
+// The OTLP JSON encoding of an ExportTraceServiceRequest. IDs are hex
+// and 64-bit integers are decimal strings, as the protocol requires.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttr `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID      string     `json:"traceId"`
+		SpanID       string     `json:"spanId"`
+		ParentSpanID string     `json:"parentSpanId,omitempty"`
+		Name         string     `json:"name"`
+		Kind         int        `json:"kind"`
+		Start        string     `json:"startTimeUnixNano"`
+		End          string     `json:"endTimeUnixNano"`
+		Attributes   []otlpAttr `json:"attributes,omitempty"`
+		Status       otlpStatus `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttr struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		String *string `json:"stringValue,omitempty"`
+		Int    *string `json:"intValue,omitempty"`
+	}
+)
+
+// encode converts t, root span first. t.mu must be held.
+func (e *Exporter) encode(t *Trace) otlpRequest {
+	spans := make([]otlpSpan, 0, len(t.spans))
+	// The root span is added last; emit it first so readers see the
+	// tree top-down.
+	for i := len(t.spans) - 1; i >= 0; i-- {
+		s := t.spans[i]
+		if s.id != t.root {
+			continue
+		}
+		spans = append(spans, encodeSpan(t.id, s))
+	}
+	for _, s := range t.spans {
+		if s.id != t.root {
+			spans = append(spans, encodeSpan(t.id, s))
+		}
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttr{encodeAttr(attr{"service.name", e.service})}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "tracedump"},
+			Spans: spans,
+		}},
+	}}}
+}
+
+func encodeSpan(traceID string, s *span) otlpSpan {
+	out := otlpSpan{
+		TraceID:      traceID,
+		SpanID:       s.id,
+		ParentSpanID: s.parent,
+		Name:         s.name,
+		Kind:         s.kind,
+		Start:        strconv.FormatInt(s.start.UnixNano(), 10),
+		End:          strconv.FormatInt(s.end.UnixNano(), 10),
+		Status:       otlpStatus{Code: s.status, Message: s.errorMsg},
+	}
+	for _, a := range s.attrs {
+		out.Attributes = append(out.Attributes, encodeAttr(a))
+	}
+	return out
+}
+
+func encodeAttr(a attr) otlpAttr {
+	var v otlpValue
+	switch x := a.value.(type) {
+	case int64:
+		s := strconv.FormatInt(x, 10)
+		v.Int = &s
+	default:
+		s := fmt.Sprint(x)
+		v.String = &s
+	}
+	return otlpAttr{Key: a.key, Value: v}
+}
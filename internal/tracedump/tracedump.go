@@ -0,0 +1,128 @@
+// Package tracedump writes per-order span trees to a local file in
+// OTLP JSON, without a tracing collector.
+//
+// A request carrying the debug header gets a Trace in its context. The
+// Middleware opens a server span for the request, WrapStep adds a child
+// span per pipeline step, and when the request finishes the tree is
+// appended to the Exporter's file, one OTLP ExportTraceServiceRequest
+// per line. Such files load into Jaeger and other OTLP-aware tools.
+package tracedump
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// Span kinds and status codes from the OTLP trace protocol.
+const (
+	kindInternal = 1
+	kindServer   = 2
+
+	statusUnset = 0
+	statusError = 2
+)
+
+// span is one finished or open span.
+type span struct {
+	id       string
+	parent   string
+	name     string
+	kind     int
+	start    time.Time
+	end      time.Time
+	attrs    []attr
+	status   int
+	errorMsg string
+}
+
+type attr struct {
+	key   string
+	value any // string or int64
+}
+
+// Trace collects the spans of one request. It is safe for concurrent
+// use by the request's steps.
+type Trace struct {
+	id   string
+	root string
+	now  func() time.Time
+
+	mu    sync.Mutex
+	spans []*span
+}
+
+func newTrace(now func() time.Time) *Trace {
+	return &Trace{id: randomID(16), root: randomID(8), now: now}
+}
+
+// ID returns the trace ID as 32 hex digits.
+func (t *Trace) ID() string { return t.id }
+
+// add appends a finished span.
+func (t *Trace) add(s *span) {
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+}
+
+type traceKey struct{}
+
+// NewContext returns a copy of ctx carrying t.
+func NewContext(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// FromContext returns the Trace in ctx, or nil if the request is not
+// being traced.
+func FromContext(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// kinder is satisfied by errors that carry a classification kind.
+type kinder interface {
+	Kind() string
+}
+
+// WrapStep returns run recording a child span named after the step for
+// traced requests. Untraced requests call run directly.
+func WrapStep(name string, run func(context.Context, model.OrderRequest) error) func(context.Context, model.OrderRequest) error {
+	return func(ctx context.Context, req model.OrderRequest) error {
+		t := FromContext(ctx)
+		if t == nil {
+			return run(ctx, req)
+		}
+		s := &span{
+			id:     randomID(8),
+			parent: t.root,
+			name:   name,
+			kind:   kindInternal,
+			start:  t.now(),
+			attrs:  []attr{{"order.id", req.OrderID}, {"order.step", name}},
+		}
+		err := run(ctx, req)
+		s.end = t.now()
+		if err != nil {
+			s.status, s.errorMsg = statusError, err.Error()
+			var k kinder
+			if errors.As(err, &k) {
+				s.attrs = append(s.attrs, attr{"error.type", k.Kind()})
+			}
+		}
+		t.add(s)
+		return err
+	}
+}
+
+// randomID returns n random bytes as hex.
+func randomID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b) // never fails
+	return hex.EncodeToString(b)
+}
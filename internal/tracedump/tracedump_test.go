@@ -0,0 +1,124 @@
+package tracedump
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+type kindError string
+
+func (e kindError) Error() string { return string(e) }
+func (e kindError) Kind() string  { return string(e) }
+
+// tickClock returns a clock advancing by one millisecond per call.
+func tickClock() func() time.Time {
+	t := time.Unix(1_700_000_000, 0)
+	return func() time.Time {
+		t = t.Add(time.Millisecond)
+		return t
+	}
+}
+
+func TestMiddlewareExportsSpanTree(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	e, err := Open(path, "order-pipeline", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.now = tickClock()
+
+	payment := WrapStep("payment", func(context.Context, model.OrderRequest) error { return nil })
+	vendor := WrapStep("vendor", func(context.Context, model.OrderRequest) error {
+		return kindError("vendor_unavailable")
+	})
+	h := e.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := model.OrderRequest{OrderID: "o-1"}
+		_ = payment(r.Context(), req)
+		_ = vendor(r.Context(), req)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	// Untraced requests are not exported.
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/order", nil))
+
+	r := httptest.NewRequest(http.MethodPost, "/order", nil)
+	r.Header.Set(HeaderDebug, "1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines []otlpRequest
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var req otlpRequest
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, req)
+	}
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 exported trace, got %d", len(lines))
+	}
+
+	spans := lines[0].ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	root := spans[0]
+	if root.Name != "POST /order" || root.Kind != kindServer || root.ParentSpanID != "" || root.Status.Code != statusError {
+		t.Fatalf("unexpected root span %+v", root)
+	}
+	if got := rec.Header().Get(HeaderTraceID); got != root.TraceID || len(got) != 32 {
+		t.Fatalf("expected trace ID header %q, got %q", root.TraceID, got)
+	}
+	for i, name := range []string{"payment", "vendor"} {
+		s := spans[i+1]
+		if s.Name != name || s.ParentSpanID != root.SpanID || s.TraceID != root.TraceID {
+			t.Fatalf("expected %s span under root, got %+v", name, s)
+		}
+		if s.Start <= root.Start || s.End >= root.End {
+			t.Fatalf("expected %s span inside root, got %s-%s in %s-%s", name, s.Start, s.End, root.Start, root.End)
+		}
+	}
+	if spans[1].Status.Code != statusUnset {
+		t.Fatalf("expected payment status unset, got %+v", spans[1].Status)
+	}
+	vs := spans[2]
+	if vs.Status.Code != statusError || vs.Status.Message != "vendor_unavailable" {
+		t.Fatalf("expected vendor error status, got %+v", vs.Status)
+	}
+	if a := vs.Attributes[len(vs.Attributes)-1]; a.Key != "error.type" || *a.Value.String != "vendor_unavailable" {
+		t.Fatalf("expected error.type attribute, got %+v", a)
+	}
+}
+
+func TestWrapStepUntraced(t *testing.T) {
+	t.Parallel()
+
+	want := errors.New("boom")
+	run := WrapStep("payment", func(context.Context, model.OrderRequest) error { return want })
+	if err := run(context.Background(), model.OrderRequest{}); !errors.Is(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+}
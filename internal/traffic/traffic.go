@@ -0,0 +1,123 @@
+// Package traffic classifies requests by origin so that steps can pick
+// their configuration per class, for example sandbox credentials for
+// synthetic monitoring orders.
+//
+// The class is read from the W3C baggage header at the edge and carried
+// to the steps in the request context. Synthetic orders are charged to
+// a sandbox account, so the marker is honored only from callers the
+// server trusts; anyone else's is removed.
+package traffic
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Class is the traffic class of a request.
+type Class uint8
+
+const (
+	Live      Class = iota // real customer traffic; the default
+	Synthetic              // monitoring or test traffic
+)
+
+// String returns "live" or "synthetic".
+func (c Class) String() string {
+	if c == Synthetic {
+		return "synthetic"
+	}
+	return "live"
+}
+
+// BaggageKey is the baggage member marking synthetic traffic, as in
+//
+//	baggage: synthetic=true
+const BaggageKey = "synthetic"
+
+type classKey struct{}
+
+// NewContext returns a copy of ctx carrying c.
+func NewContext(ctx context.Context, c Class) context.Context {
+	return context.WithValue(ctx, classKey{}, c)
+}
+
+// FromContext returns the class carried by ctx, or Live.
+func FromContext(ctx context.Context) Class {
+	c, _ := ctx.Value(classKey{}).(Class)
+	return c
+}
+
+// FromHeader classifies a request by its baggage headers. A request is
+// Synthetic if a BaggageKey member is "true" or "1"; anything else,
+// including malformed baggage, is Live.
+func FromHeader(h http.Header) Class {
+	for _, v := range h.Values("Baggage") {
+		for member := range strings.SplitSeq(v, ",") {
+			kv, _, _ := strings.Cut(member, ";") // drop member properties
+			key, val, ok := strings.Cut(kv, "=")
+			if !ok || strings.TrimSpace(key) != BaggageKey {
+				continue
+			}
+			val, err := url.PathUnescape(strings.TrimSpace(val))
+			if err == nil && (val == "true" || val == "1") {
+				return Synthetic
+			}
+		}
+	}
+	return Live
+}
+
+// SetHeader marks h as Synthetic by adding a BaggageKey member to its
+// baggage.
+func SetHeader(h http.Header) {
+	h.Add("Baggage", BaggageKey+"=true")
+}
+
+// StripHeader removes every BaggageKey member from h's baggage
+// headers, dropping headers left empty. Other members are kept.
+func StripHeader(h http.Header) {
+	values := h.Values("Baggage")
+	if len(values) == 0 {
+		return
+	}
+	var kept []string
+	for _, v := range values {
+		var members []string
+		for member := range strings.SplitSeq(v, ",") {
+			kv, _, _ := strings.Cut(member, ";")
+			key, _, _ := strings.Cut(kv, "=")
+			if strings.TrimSpace(key) != BaggageKey && strings.TrimSpace(member) != "" {
+				members = append(members, strings.TrimSpace(member))
+			}
+		}
+		if len(members) > 0 {
+			kept = append(kept, strings.Join(members, ","))
+		}
+	}
+	h.Del("Baggage")
+	for _, v := range kept {
+		h.Add("Baggage", v)
+	}
+}
+
+// Middleware classifies each request and stores the class in its context.
+//
+// A Synthetic marker is honored only if trusted reports true for the
+// request; otherwise the request is Live and the marker is stripped
+// from its headers, so nothing downstream sees it either. A nil trusted
+// trusts no one.
+func Middleware(trusted func(*http.Request) bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c := FromHeader(r.Header); c != Live {
+			if trusted != nil && trusted(r) {
+				r = r.WithContext(NewContext(r.Context(), c))
+			} else {
+				r.Header = r.Header.Clone()
+				StripHeader(r.Header)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,137 @@
+package traffic
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestFromHeader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		baggage []string
+		want    Class
+	}{
+		{name: "none", want: Live},
+		{name: "true", baggage: []string{"synthetic=true"}, want: Synthetic},
+		{name: "one", baggage: []string{"synthetic=1"}, want: Synthetic},
+		{name: "false", baggage: []string{"synthetic=false"}, want: Live},
+		{name: "among_members", baggage: []string{"user=42, synthetic = true ;ttl=60, env=prod"}, want: Synthetic},
+		{name: "second_header", baggage: []string{"user=42", "synthetic=true"}, want: Synthetic},
+		{name: "percent_encoded", baggage: []string{"synthetic=%74rue"}, want: Synthetic},
+		{name: "other_key", baggage: []string{"not_synthetic=true"}, want: Live},
+		{name: "malformed", baggage: []string{"synthetic"}, want: Live},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := http.Header{}
+			for _, v := range tt.baggage {
+				h.Add("Baggage", v)
+			}
+			if got := FromHeader(h); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSetHeader(t *testing.T) {
+	t.Parallel()
+
+	h := http.Header{}
+	h.Set("Baggage", "user=42")
+	SetHeader(h)
+	if got := FromHeader(h); got != Synthetic {
+		t.Fatalf("expected %v, got %v", Synthetic, got)
+	}
+	StripHeader(h)
+	if got := h.Values("Baggage"); !slices.Equal(got, []string{"user=42"}) {
+		t.Fatalf("expected the other members back, got %q", got)
+	}
+}
+
+func TestStripHeader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		baggage []string
+		want    []string
+	}{
+		{name: "none"},
+		{name: "only_marker", baggage: []string{"synthetic=true"}},
+		{name: "among_members", baggage: []string{"user=42, synthetic = true ;ttl=60, env=prod"}, want: []string{"user=42,env=prod"}},
+		{name: "second_header", baggage: []string{"user=42", "synthetic=1"}, want: []string{"user=42"}},
+		{name: "other_key", baggage: []string{"not_synthetic=true"}, want: []string{"not_synthetic=true"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := http.Header{}
+			for _, v := range tt.baggage {
+				h.Add("Baggage", v)
+			}
+			StripHeader(h)
+			if got := h.Values("Baggage"); !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		baggage     string
+		trusted     func(*http.Request) bool
+		want        Class
+		wantBaggage string
+	}{
+		{name: "trusted", baggage: "synthetic=true", trusted: func(*http.Request) bool { return true }, want: Synthetic, wantBaggage: "synthetic=true"},
+		{name: "untrusted", baggage: "synthetic=true, user=42", trusted: func(*http.Request) bool { return false }, want: Live, wantBaggage: "user=42"},
+		{name: "nil_trust", baggage: "synthetic=true", want: Live},
+		{name: "unmarked", baggage: "user=42", want: Live, wantBaggage: "user=42"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got Class
+			var gotBaggage string
+			h := Middleware(tt.trusted, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = FromContext(r.Context())
+				gotBaggage = r.Header.Get("Baggage")
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/order", nil)
+			req.Header.Set("Baggage", tt.baggage)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want || gotBaggage != tt.wantBaggage {
+				t.Fatalf("expected %v with baggage %q, got %v with %q", tt.want, tt.wantBaggage, got, gotBaggage)
+			}
+		})
+	}
+}
+
+func TestFromContextDefault(t *testing.T) {
+	t.Parallel()
+
+	if got := FromContext(context.Background()); got != Live {
+		t.Fatalf("expected %v, got %v", Live, got)
+	}
+}
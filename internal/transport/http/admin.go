@@ -0,0 +1,409 @@
+package httptransport
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// HandleLogLevel returns a handler for reading and changing the
+// application log level at runtime.
+//
+// GET reports the current level. PUT accepts {"level": "debug"} (any
+// value understood by slog.Level, e.g. "info", "warn", "error+2") and
+// applies it immediately. It panics if lv is nil.
+func HandleLogLevel(lv *slog.LevelVar) http.HandlerFunc {
+	if lv == nil {
+		panic("httptransport.HandleLogLevel: nil level")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req model.LogLevel
+			if err := decodeStrictJSON(w, r, &req); err != nil {
+				badRequest(w, "invalid JSON")
+				return
+			}
+			var level slog.Level
+			if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+				badRequest(w, "unknown log level")
+				return
+			}
+			lv.Set(level)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, model.LogLevel{Level: lv.Level().String()})
+	}
+}
+
+// scheduleSource reports the courier pool schedule.
+type scheduleSource interface {
+	Snapshot() model.PoolSchedule
+}
+
+// HandlePoolSchedule returns a GET handler reporting the courier pool
+// schedule and the size currently in effect. It panics if src is nil.
+func HandlePoolSchedule(src scheduleSource) http.HandlerFunc {
+	if src == nil {
+		panic("httptransport.HandlePoolSchedule: nil source")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, src.Snapshot())
+	}
+}
+
+// telemetrySource reports courier pool wait times and saturation.
+type telemetrySource interface {
+	Telemetry() model.PoolTelemetry
+}
+
+// HandlePoolTelemetry returns a GET handler reporting the courier pool's
+// acquisition wait-time histogram and saturation counters. It panics if
+// src is nil.
+func HandlePoolTelemetry(src telemetrySource) http.HandlerFunc {
+	if src == nil {
+		panic("httptransport.HandlePoolTelemetry: nil source")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, src.Telemetry())
+	}
+}
+
+// zoneSource reports per-zone courier pools.
+type zoneSource interface {
+	Stats() []model.CourierZone
+}
+
+// HandleCourierZones returns a GET handler reporting capacity and
+// assignment counts for each courier zone. It panics if src is nil.
+func HandleCourierZones(src zoneSource) http.HandlerFunc {
+	if src == nil {
+		panic("httptransport.HandleCourierZones: nil source")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, src.Stats())
+	}
+}
+
+// outboundSource reports outbound concurrency limits.
+type outboundSource interface {
+	Stats() []model.OutboundLimit
+}
+
+// HandleOutbound returns a GET handler reporting occupancy and queue
+// depth for each outbound limit. It panics if src is nil.
+func HandleOutbound(src outboundSource) http.HandlerFunc {
+	if src == nil {
+		panic("httptransport.HandleOutbound: nil source")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, src.Stats())
+	}
+}
+
+// stepLimitSource reports the orchestrator's step concurrency limit.
+type stepLimitSource interface {
+	StepLimit() model.StepLimit
+}
+
+// HandleStepLimit returns a GET handler reporting the limit on the steps
+// of one order running at once and how many steps queued behind it. It
+// panics if src is nil.
+func HandleStepLimit(src stepLimitSource) http.HandlerFunc {
+	if src == nil {
+		panic("httptransport.HandleStepLimit: nil source")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, src.StepLimit())
+	}
+}
+
+// probeSource reports synthetic order probe results.
+type probeSource interface {
+	Stats() model.ProbeStats
+}
+
+// HandleProbe returns a GET handler reporting synthetic order probe
+// counts and the latest outcome. It panics if src is nil.
+func HandleProbe(src probeSource) http.HandlerFunc {
+	if src == nil {
+		panic("httptransport.HandleProbe: nil source")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, src.Stats())
+	}
+}
+
+// dependencySource reports upstream dependency health.
+type dependencySource interface {
+	Stats() []model.DependencyStatus
+	Down() []string
+}
+
+// HandleDependencies returns a GET handler reporting the status and
+// latest health check of each upstream dependency. It panics if src is
+// nil.
+func HandleDependencies(src dependencySource) http.HandlerFunc {
+	if src == nil {
+		panic("httptransport.HandleDependencies: nil source")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, src.Stats())
+	}
+}
+
+// HandleReadiness returns a GET handler answering 200 while no upstream
+// dependency is down and 503 naming the ones that are, so that a load
+// balancer can route orders away from an instance that would fail them.
+// It panics if src is nil.
+func HandleReadiness(src dependencySource) http.HandlerFunc {
+	if src == nil {
+		panic("httptransport.HandleReadiness: nil source")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		down := src.Down()
+		status := http.StatusOK
+		if len(down) > 0 {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, model.Readiness{Ready: len(down) == 0, Down: down})
+	}
+}
+
+// killSwitchBoard reports and sets route and step kill switches.
+type killSwitchBoard interface {
+	Stats() []model.KillSwitch
+	Set(name, mode string) error
+}
+
+// HandleKillSwitches returns a handler reporting kill switches on GET and
+// setting one switch's mode ("auto", "on" or "off") on PUT. Either way,
+// it responds with every switch. It panics if board is nil.
+func HandleKillSwitches(board killSwitchBoard) http.HandlerFunc {
+	if board == nil {
+		panic("httptransport.HandleKillSwitches: nil board")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req model.KillSwitchUpdate
+			if err := decodeStrictJSON(w, r, &req); err != nil {
+				badRequest(w, "invalid JSON")
+				return
+			}
+			if err := board.Set(req.Name, req.Mode); err != nil {
+				badRequest(w, err.Error())
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, board.Stats())
+	}
+}
+
+// deferredSource reports the deferred step completion queue.
+type deferredSource interface {
+	Stats() model.DeferredQueue
+}
+
+// HandleDeferred returns a GET handler reporting deferred step counters
+// and pending tasks. It panics if src is nil.
+func HandleDeferred(src deferredSource) http.HandlerFunc {
+	if src == nil {
+		panic("httptransport.HandleDeferred: nil source")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, src.Stats())
+	}
+}
+
+// maintenanceMode is the process maintenance switch.
+type maintenanceMode interface {
+	Status() model.Maintenance
+	Set(on bool)
+}
+
+// inFlightSource reports the number of orders being processed.
+type inFlightSource interface {
+	InFlight() int64
+}
+
+// HandleMaintenance returns a handler reporting maintenance mode on GET
+// and turning it on or off on PUT ({"enabled": true}). Either way, it
+// responds with the mode and the orders still in flight, so operators
+// can watch them drain. It panics if mode or orders is nil.
+func HandleMaintenance(mode maintenanceMode, orders inFlightSource) http.HandlerFunc {
+	if mode == nil {
+		panic("httptransport.HandleMaintenance: nil mode")
+	}
+	if orders == nil {
+		panic("httptransport.HandleMaintenance: nil in-flight source")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req model.MaintenanceUpdate
+			if err := decodeStrictJSON(w, r, &req); err != nil {
+				badRequest(w, "invalid JSON")
+				return
+			}
+			mode.Set(req.Enabled)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		st := mode.Status()
+		st.InFlightOrders = orders.InFlight()
+		writeJSON(w, http.StatusOK, st)
+	}
+}
+
+// costSource reports estimated step spend.
+type costSource interface {
+	Report(tenant string) model.CostReport
+}
+
+// HandleCosts returns a GET handler reporting estimated step spend per
+// tenant, step and resource. The tenant query parameter narrows the
+// report to one tenant. It panics if src is nil.
+func HandleCosts(src costSource) http.HandlerFunc {
+	if src == nil {
+		panic("httptransport.HandleCosts: nil source")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, src.Report(r.URL.Query().Get("tenant")))
+	}
+}
+
+// sidecarSource reports the sidecar steps.
+type sidecarSource interface {
+	Status() []model.SidecarStatus
+}
+
+// HandleSidecars returns a GET handler reporting the health and
+// counters of each sidecar step. It panics if src is nil.
+func HandleSidecars(src sidecarSource) http.HandlerFunc {
+	if src == nil {
+		panic("httptransport.HandleSidecars: nil source")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, src.Status())
+	}
+}
+
+// policyEngine routes orders by rules.
+type policyEngine interface {
+	Rules() []string
+	Evaluate(tenant string, at time.Time, req model.OrderRequest) model.PolicyDecision
+}
+
+// HandlePolicy returns a handler listing the routing rules on GET and
+// dry-running them on POST: the body is a model.PolicyEvaluation and the
+// response the decision for it, without processing the order. It panics
+// if engine is nil.
+func HandlePolicy(engine policyEngine) http.HandlerFunc {
+	if engine == nil {
+		panic("httptransport.HandlePolicy: nil engine")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, model.PolicyRules{Rules: engine.Rules()})
+		case http.MethodPost:
+			var req model.PolicyEvaluation
+			if err := decodeStrictJSON(w, r, &req); err != nil {
+				badRequest(w, "invalid JSON")
+				return
+			}
+			at := time.Now()
+			if req.Time != "" {
+				t, err := time.Parse(time.RFC3339, req.Time)
+				if err != nil {
+					badRequest(w, "time must be RFC 3339")
+					return
+				}
+				at = t.Local()
+			}
+			writeJSON(w, http.StatusOK, engine.Evaluate(req.Tenant, at, req.Order))
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// timeoutSource reports the server timeouts in effect.
+type timeoutSource interface {
+	Snapshot() model.ServerTimeouts
+}
+
+// HandleInfo returns a GET handler reporting what the instance runs:
+// its build, the effective configuration and the features enabled,
+// with the server timeouts currently in effect from timeouts. It panics
+// if timeouts is nil.
+func HandleInfo(info model.InstanceInfo, timeouts timeoutSource) http.HandlerFunc {
+	if timeouts == nil {
+		panic("httptransport.HandleInfo: nil timeouts")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		info := info
+		info.Timeouts = timeouts.Snapshot()
+		writeJSON(w, http.StatusOK, info)
+	}
+}
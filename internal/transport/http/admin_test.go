@@ -0,0 +1,620 @@
+package httptransport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestHandleLogLevel(t *testing.T) {
+	t.Parallel()
+
+	lv := new(slog.LevelVar)
+	h := HandleLogLevel(lv)
+
+	// Steps share lv, so they run sequentially.
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantLevel  slog.Level
+	}{
+		{name: "get_default", method: http.MethodGet, wantStatus: http.StatusOK, wantLevel: slog.LevelInfo},
+		{name: "put_debug", method: http.MethodPut, body: `{"level":"debug"}`, wantStatus: http.StatusOK, wantLevel: slog.LevelDebug},
+		{name: "put_offset", method: http.MethodPut, body: `{"level":"warn+2"}`, wantStatus: http.StatusOK, wantLevel: slog.LevelWarn + 2},
+		{name: "put_unknown", method: http.MethodPut, body: `{"level":"loud"}`, wantStatus: http.StatusBadRequest, wantLevel: slog.LevelWarn + 2},
+		{name: "put_invalid_json", method: http.MethodPut, body: `{"level":`, wantStatus: http.StatusBadRequest, wantLevel: slog.LevelWarn + 2},
+		{name: "post_not_allowed", method: http.MethodPost, body: `{"level":"error"}`, wantStatus: http.StatusMethodNotAllowed, wantLevel: slog.LevelWarn + 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/admin/loglevel", bytes.NewReader([]byte(tt.body)))
+			w := httptest.NewRecorder()
+
+			h(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := lv.Level(); got != tt.wantLevel {
+				t.Fatalf("expected level %v, got %v", tt.wantLevel, got)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var out model.LogLevel
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if out.Level != tt.wantLevel.String() {
+				t.Fatalf("expected %q, got %q", tt.wantLevel.String(), out.Level)
+			}
+		})
+	}
+}
+
+type stubSchedule struct{ s model.PoolSchedule }
+
+func (s stubSchedule) Snapshot() model.PoolSchedule { return s.s }
+
+type stubTelemetry struct{ model.PoolTelemetry }
+
+func (s stubTelemetry) Telemetry() model.PoolTelemetry { return s.PoolTelemetry }
+
+func TestHandlePoolTelemetry(t *testing.T) {
+	t.Parallel()
+
+	want := model.PoolTelemetry{
+		Capacity:    5,
+		Acquired:    3,
+		WaitBuckets: []model.WaitBucket{{LE: "1ms", Count: 2}, {LE: "+Inf", Count: 1}},
+		Saturations: 1,
+		SaturatedMS: 40,
+	}
+	h := HandlePoolTelemetry(stubTelemetry{want})
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/pool/telemetry", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var out model.PoolTelemetry
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Fatalf("expected %+v, got %+v", want, out)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/admin/pool/telemetry", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandlePoolSchedule(t *testing.T) {
+	t.Parallel()
+
+	want := model.PoolSchedule{
+		ActiveSize:  20,
+		DefaultSize: 5,
+		ActiveShift: 0,
+		Shifts:      []model.PoolShift{{Start: "17:00", End: "21:00", Size: 20}},
+	}
+	h := HandlePoolSchedule(stubSchedule{want})
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/pool/schedule", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var out model.PoolSchedule
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Fatalf("expected %+v, got %+v", want, out)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/admin/pool/schedule", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
+type stubZones []model.CourierZone
+
+func (s stubZones) Stats() []model.CourierZone { return s }
+
+func TestHandleCourierZones(t *testing.T) {
+	t.Parallel()
+
+	want := stubZones{
+		{Name: "default", Capacity: 5, InUse: 1},
+		{Name: "north", Capacity: 3, InUse: 3, Waiting: 2, Assigned: 10, Lent: 4},
+	}
+	h := HandleCourierZones(want)
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/courier/zones", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var out []model.CourierZone
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(out, []model.CourierZone(want)) {
+		t.Fatalf("expected %+v, got %+v", want, out)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPut, "/admin/courier/zones", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
+type stubOutbound []model.OutboundLimit
+
+func (s stubOutbound) Stats() []model.OutboundLimit { return s }
+
+func TestHandleOutbound(t *testing.T) {
+	t.Parallel()
+
+	want := stubOutbound{{Destination: "*", Limit: 50, InUse: 50, Waiting: 7}, {Destination: "payment", Limit: 10, InUse: 10}}
+	h := HandleOutbound(want)
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/outbound", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var out []model.OutboundLimit
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(out, []model.OutboundLimit(want)) {
+		t.Fatalf("expected %+v, got %+v", want, out)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodDelete, "/admin/outbound", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
+type stubStepLimit model.StepLimit
+
+func (s stubStepLimit) StepLimit() model.StepLimit { return model.StepLimit(s) }
+
+func TestHandleStepLimit(t *testing.T) {
+	t.Parallel()
+
+	want := stubStepLimit{Limit: 4, Started: 120, Queued: 30, Waiting: 2, QueuedMS: 85}
+	h := HandleStepLimit(want)
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/steps/limit", nil))
+	var out model.StepLimit
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%v)", w.Code, err)
+	}
+	if out != model.StepLimit(want) {
+		t.Fatalf("expected %+v, got %+v", want, out)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/admin/steps/limit", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
+type stubProbe model.ProbeStats
+
+func (s stubProbe) Stats() model.ProbeStats { return model.ProbeStats(s) }
+
+func TestHandleProbe(t *testing.T) {
+	t.Parallel()
+
+	want := stubProbe{IntervalMS: 60000, SLOMS: 1000, Runs: 12, Failures: 1, LastTime: "2026-01-02T15:04:05Z", LastDurationMS: 204}
+	h := HandleProbe(want)
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/probe", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var out model.ProbeStats
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out != model.ProbeStats(want) {
+		t.Fatalf("expected %+v, got %+v", want, out)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/admin/probe", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
+type stubDependencies []model.DependencyStatus
+
+func (s stubDependencies) Stats() []model.DependencyStatus { return s }
+
+func (s stubDependencies) Down() []string {
+	var down []string
+	for _, d := range s {
+		if d.Status == "down" {
+			down = append(down, d.Name)
+		}
+	}
+	return down
+}
+
+func TestHandleDependencies(t *testing.T) {
+	t.Parallel()
+
+	deps := stubDependencies{
+		{Name: "payment", URL: "http://p/healthz", Status: "up", Checks: 3, LastLatencyMS: 4},
+		{Name: "vendor", URL: "http://v/healthz", Status: "down", Checks: 3, Failures: 2, ConsecutiveFailures: 2, LastError: "status 503"},
+	}
+	h := HandleDependencies(deps)
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/dependencies", nil))
+	var out []model.DependencyStatus
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%v)", w.Code, err)
+	}
+	if !slices.Equal(out, deps) {
+		t.Fatalf("expected %+v, got %+v", deps, out)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/admin/dependencies", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleReadiness(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		deps       stubDependencies
+		wantStatus int
+		want       model.Readiness
+	}{
+		{name: "all_up", deps: stubDependencies{{Name: "payment", Status: "up"}, {Name: "vendor", Status: "unknown"}},
+			wantStatus: http.StatusOK, want: model.Readiness{Ready: true}},
+		{name: "one_down", deps: stubDependencies{{Name: "payment", Status: "up"}, {Name: "vendor", Status: "down"}},
+			wantStatus: http.StatusServiceUnavailable, want: model.Readiness{Down: []string{"vendor"}}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := httptest.NewRecorder()
+			HandleReadiness(tt.deps)(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			var out model.Readiness
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil || w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d (%v)", tt.wantStatus, w.Code, err)
+			}
+			if out.Ready != tt.want.Ready || !slices.Equal(out.Down, tt.want.Down) {
+				t.Fatalf("expected %+v, got %+v", tt.want, out)
+			}
+		})
+	}
+}
+
+type stubKillSwitches struct {
+	switches []model.KillSwitch
+}
+
+func (s *stubKillSwitches) Stats() []model.KillSwitch { return s.switches }
+
+func (s *stubKillSwitches) Set(name, mode string) error {
+	for i := range s.switches {
+		if s.switches[i].Name == name {
+			s.switches[i].Mode = mode
+			s.switches[i].Enabled = mode != "off"
+			return nil
+		}
+	}
+	return errors.New("unknown switch")
+}
+
+func TestHandleKillSwitches(t *testing.T) {
+	t.Parallel()
+
+	board := &stubKillSwitches{switches: []model.KillSwitch{{Name: "courier", Mode: "auto", Enabled: true}}}
+	h := HandleKillSwitches(board)
+
+	tests := []struct {
+		name    string
+		method  string
+		body    string
+		code    int
+		enabled bool
+	}{
+		{name: "get", method: http.MethodGet, code: http.StatusOK, enabled: true},
+		{name: "force off", method: http.MethodPut, body: `{"name":"courier","mode":"off"}`, code: http.StatusOK, enabled: false},
+		{name: "unknown switch", method: http.MethodPut, body: `{"name":"nope","mode":"on"}`, code: http.StatusBadRequest},
+		{name: "invalid JSON", method: http.MethodPut, body: `{"name":`, code: http.StatusBadRequest},
+		{name: "wrong method", method: http.MethodPost, code: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(tt.method, "/admin/killswitches", bytes.NewBufferString(tt.body)))
+		if w.Code != tt.code {
+			t.Fatalf("%s: expected %d, got %d", tt.name, tt.code, w.Code)
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		var out []model.KillSwitch
+		if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+			t.Fatalf("%s: decode: %v", tt.name, err)
+		}
+		if len(out) != 1 || out[0].Enabled != tt.enabled {
+			t.Fatalf("%s: expected enabled=%v, got %+v", tt.name, tt.enabled, out)
+		}
+	}
+}
+
+type stubDeferred model.DeferredQueue
+
+func (s stubDeferred) Stats() model.DeferredQueue { return model.DeferredQueue(s) }
+
+func TestHandleDeferred(t *testing.T) {
+	t.Parallel()
+
+	want := model.DeferredQueue{Pending: 1, Completed: 4, Tasks: []model.DeferredTask{
+		{OrderID: "o-1", Step: "courier", Attempts: 2, Enqueued: "2026-01-02T15:04:05Z", LastError: "no courier available"},
+	}}
+	h := HandleDeferred(stubDeferred(want))
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/deferred", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var out model.DeferredQueue
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Fatalf("expected %+v, got %+v", want, out)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/admin/deferred", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
+type stubMaintenance struct{ on bool }
+
+func (s *stubMaintenance) Status() model.Maintenance {
+	return model.Maintenance{Enabled: s.on, RetryAfterS: 60}
+}
+func (s *stubMaintenance) Set(on bool) { s.on = on }
+
+type stubInFlight int64
+
+func (s stubInFlight) InFlight() int64 { return int64(s) }
+
+func TestHandleMaintenance(t *testing.T) {
+	t.Parallel()
+
+	h := HandleMaintenance(&stubMaintenance{}, stubInFlight(3))
+
+	tests := []struct {
+		name    string
+		method  string
+		body    string
+		code    int
+		enabled bool
+	}{
+		{name: "get", method: http.MethodGet, code: http.StatusOK},
+		{name: "enable", method: http.MethodPut, body: `{"enabled":true}`, code: http.StatusOK, enabled: true},
+		{name: "still enabled", method: http.MethodGet, code: http.StatusOK, enabled: true},
+		{name: "disable", method: http.MethodPut, body: `{"enabled":false}`, code: http.StatusOK},
+		{name: "invalid JSON", method: http.MethodPut, body: `{"enabled":`, code: http.StatusBadRequest},
+		{name: "wrong method", method: http.MethodPost, code: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(tt.method, "/admin/maintenance", bytes.NewBufferString(tt.body)))
+		if w.Code != tt.code {
+			t.Fatalf("%s: expected %d, got %d", tt.name, tt.code, w.Code)
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		var out model.Maintenance
+		if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+			t.Fatalf("%s: decode: %v", tt.name, err)
+		}
+		if out.Enabled != tt.enabled || out.InFlightOrders != 3 {
+			t.Fatalf("%s: expected enabled=%v in_flight_orders=3, got %+v", tt.name, tt.enabled, out)
+		}
+	}
+}
+
+type stubCosts struct{ tenant string }
+
+func (s *stubCosts) Report(tenant string) model.CostReport {
+	s.tenant = tenant
+	return model.CostReport{TotalMicros: 2500, Lines: []model.CostLine{{Tenant: "acme", Step: "payment", Calls: 1, CostMicros: 2500}}}
+}
+
+func TestHandleCosts(t *testing.T) {
+	t.Parallel()
+
+	src := &stubCosts{}
+	h := HandleCosts(src)
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/costs?tenant=acme", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var out model.CostReport
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if src.tenant != "acme" || out.TotalMicros != 2500 || len(out.Lines) != 1 {
+		t.Fatalf("expected the acme report, got tenant=%q %+v", src.tenant, out)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/admin/costs", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
+type stubSidecars []model.SidecarStatus
+
+func (s stubSidecars) Status() []model.SidecarStatus { return s }
+
+func TestHandleSidecars(t *testing.T) {
+	t.Parallel()
+
+	want := stubSidecars{{Step: "fraud", Command: "./fraud-check", TimeoutMS: 500, Running: true, Healthy: true, PID: 4242, Calls: 9, Failures: 1}}
+	h := HandleSidecars(want)
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/sidecars", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var out []model.SidecarStatus
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(out, []model.SidecarStatus(want)) {
+		t.Fatalf("expected %+v, got %+v", want, out)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/admin/sidecars", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
+type stubPolicy struct {
+	tenant string
+	at     time.Time
+}
+
+func (s *stubPolicy) Rules() []string { return []string{"zone==north => sla=express"} }
+
+func (s *stubPolicy) Evaluate(tenant string, at time.Time, req model.OrderRequest) model.PolicyDecision {
+	s.tenant, s.at = tenant, at
+	req.SLA = "express"
+	return model.PolicyDecision{Matched: true, RuleText: "zone==north => sla=express", Order: req}
+}
+
+func TestHandlePolicy(t *testing.T) {
+	t.Parallel()
+
+	src := &stubPolicy{}
+	h := HandlePolicy(src)
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/policy", nil))
+	var rules model.PolicyRules
+	if err := json.NewDecoder(w.Body).Decode(&rules); err != nil || w.Code != http.StatusOK || len(rules.Rules) != 1 {
+		t.Fatalf("expected one rule, got %d %+v (%v)", w.Code, rules, err)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/admin/policy",
+		bytes.NewBufferString(`{"order":{"order_id":"o-1","zone":"north"},"tenant":"acme","time":"2026-01-05T23:00:00Z"}`)))
+	var d model.PolicyDecision
+	if err := json.NewDecoder(w.Body).Decode(&d); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected a decision, got %d (%v)", w.Code, err)
+	}
+	if !d.Matched || d.Order.SLA != "express" || d.Order.OrderID != "o-1" || src.tenant != "acme" ||
+		!src.at.Equal(time.Date(2026, 1, 5, 23, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected decision %+v for tenant %q at %v", d, src.tenant, src.at)
+	}
+
+	for _, tt := range []struct {
+		method, body string
+		code         int
+	}{
+		{http.MethodPost, `{"order":`, http.StatusBadRequest},
+		{http.MethodPost, `{"order":{},"time":"tonight"}`, http.StatusBadRequest},
+		{http.MethodDelete, "", http.StatusMethodNotAllowed},
+	} {
+		w = httptest.NewRecorder()
+		h(w, httptest.NewRequest(tt.method, "/admin/policy", bytes.NewBufferString(tt.body)))
+		if w.Code != tt.code {
+			t.Fatalf("%s %s: expected %d, got %d", tt.method, tt.body, tt.code, w.Code)
+		}
+	}
+}
+
+func TestHandleInfo(t *testing.T) {
+	t.Parallel()
+
+	want := model.InstanceInfo{
+		Build:     model.BuildInfo{Version: "v1.4.0", Commit: "abc123", GoVersion: "go1.25.0"},
+		StartedAt: "2026-01-05T12:00:00Z",
+		Config: model.EffectiveConfig{
+			Flags:     map[string]string{"listen": "127.0.0.1:8080"},
+			Steps:     []string{"payment", "vendor", "courier"},
+			TailSteps: []string{},
+			Limits:    map[string]int{"courier_pool": 5},
+			Features:  map[string]bool{"loyalty": false},
+		},
+	}
+	timeouts := NewTimeouts(10*time.Second, 15*time.Second, time.Minute, New(&stubProcessor{}, 10*time.Second))
+	h := HandleInfo(want, timeouts)
+	want.Timeouts = model.ServerTimeouts{ReadMS: 10000, WriteMS: 15000, IdleMS: 60000, RequestMS: 10000}
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/info", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var out model.InstanceInfo
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Fatalf("expected %+v, got %+v", want, out)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPut, "/admin/info", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
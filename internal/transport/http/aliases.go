@@ -0,0 +1,133 @@
+package httptransport
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Request bodies use snake_case field names, but partner systems often
+// cannot match them exactly. When a body has fields the destination does
+// not know, its top-level keys are matched to the destination's fields
+// ignoring case, '_' and '-', so that orderId, OrderId and order-id all
+// set order_id, and the body is decoded again. Bodies using only the
+// canonical names, or names differing from them only in case, which
+// encoding/json matches itself, are decoded once.
+//
+// When several aliases name the same field, the canonical spelling wins
+// wherever it appears; without it, the first alias in the body wins and
+// the others are dropped. Keys matching no field are left for the
+// decoder to reject (or ignore, on tolerant routes). Nested objects are
+// not rewritten, so map keys such as delay_ms step names keep their
+// spelling.
+
+// fieldNames caches, per struct type, the canonical JSON name of each
+// field by its folded name.
+var fieldNames sync.Map // reflect.Type → map[string]string
+
+// foldName folds a JSON key for alias matching.
+func foldName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(s))
+}
+
+// jsonFields returns the canonical names of the fields of struct type t
+// by folded name.
+func jsonFields(t reflect.Type) map[string]string {
+	if m, ok := fieldNames.Load(t); ok {
+		return m.(map[string]string)
+	}
+	m := make(map[string]string, t.NumField())
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		m[foldName(name)] = name
+	}
+	fieldNames.Store(t, m)
+	return m
+}
+
+// canonicalizeKeys returns body with its top-level keys renamed to the
+// canonical field names of dst, a pointer to a struct, and whether any
+// key changed. Anything else, and bodies that are not a well-formed
+// object, are returned unchanged for the decoder to report.
+func canonicalizeKeys(body []byte, dst any) ([]byte, bool) {
+	t := reflect.TypeOf(dst)
+	if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return body, false
+	}
+	fields := jsonFields(t.Elem())
+
+	type member struct {
+		key   string
+		value json.RawMessage
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return body, false
+	}
+	var members []member
+	winner := map[string]int{} // canonical name → index in members
+	changed := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return body, false
+		}
+		key := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return body, false
+		}
+		name, ok := fields[foldName(key)]
+		if !ok {
+			members = append(members, member{key, value})
+			continue
+		}
+		i, seen := winner[name]
+		switch {
+		case !seen:
+			winner[name] = len(members)
+			members = append(members, member{name, value})
+		case key == name:
+			members[i].value = value // the canonical spelling wins
+		}
+		changed = changed || key != name
+	}
+	if _, err := dec.Token(); err != nil {
+		return body, false
+	}
+	if !changed {
+		return body, false
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(m.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(m.value)
+	}
+	buf.WriteByte('}')
+	buf.Write(body[dec.InputOffset():]) // left for the single-value check
+	return buf.Bytes(), true
+}
@@ -0,0 +1,85 @@
+package httptransport
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestCanonicalizeKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "canonical_unchanged", body: `{"order_id":"o-1", "amount":10}`, want: `{"order_id":"o-1", "amount":10}`},
+		{name: "camel_case", body: `{"orderId":"o-1","failStep":"vendor","delayMs":{"payment":5}}`, want: `{"order_id":"o-1","fail_step":"vendor","delay_ms":{"payment":5}}`},
+		{name: "pascal_and_kebab", body: `{"OrderID":"o-1","fail-step":"vendor"}`, want: `{"order_id":"o-1","fail_step":"vendor"}`},
+		{name: "canonical_wins_before", body: `{"order_id":"a","orderId":"b"}`, want: `{"order_id":"a"}`},
+		{name: "canonical_wins_after", body: `{"orderId":"b","amount":1,"order_id":"a"}`, want: `{"order_id":"a","amount":1}`},
+		{name: "first_alias_wins", body: `{"orderId":"b","OrderId":"c"}`, want: `{"order_id":"b"}`},
+		{name: "unknown_kept", body: `{"orderId":"o-1","giftWrap":true}`, want: `{"order_id":"o-1","giftWrap":true}`},
+		{name: "nested_keys_kept", body: `{"delayMs":{"Payment_Step":5}}`, want: `{"delay_ms":{"Payment_Step":5}}`},
+		{name: "trailing_value_kept", body: `{"orderId":"o-1"} {"x":1}`, want: `{"order_id":"o-1"} {"x":1}`},
+		{name: "not_an_object", body: `["orderId"]`, want: `["orderId"]`},
+		{name: "malformed", body: `{"orderId":`, want: `{"orderId":`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, changed := canonicalizeKeys([]byte(tt.body), &model.OrderRequest{})
+			if string(got) != tt.want || changed != (tt.body != tt.want) {
+				t.Fatalf("expected %s, got %s (changed %v)", tt.want, got, changed)
+			}
+		})
+	}
+
+	if got, changed := canonicalizeKeys([]byte(`{"orderId":1}`), map[string]any{}); changed || string(got) != `{"orderId":1}` {
+		t.Fatalf("expected a non-struct destination left alone, got %s", got)
+	}
+}
+
+func TestHandleOrder_FieldAliases(t *testing.T) {
+	t.Parallel()
+
+	proc := &capturingProcessor{}
+	h := New(proc, time.Second)
+
+	w := httptest.NewRecorder()
+	h.HandleOrder(w, httptest.NewRequest(http.MethodPost, "/order",
+		strings.NewReader(`{"orderId":"o-1","Amount":10,"order-id":"ignored","delayMs":{"vendor":5}}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if proc.got.OrderID != "o-1" || proc.got.Amount != 10 || proc.got.DelayMS["vendor"] != 5 {
+		t.Fatalf("expected the aliased fields decoded, got %+v", proc.got)
+	}
+
+	w = httptest.NewRecorder()
+	h.HandleOrder(w, httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"orderId":"o-1","amount":10,"orderRef":"x"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a field matching nothing, got %d", w.Code)
+	}
+
+	// On a tolerant route, aliases are still matched.
+	tr := NewTolerantReader([]string{"/order"}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	w = httptest.NewRecorder()
+	tr.Middleware(http.HandlerFunc(h.HandleOrder)).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/order",
+		strings.NewReader(`{"orderId":"o-2","amount":10,"orderRef":"x"}`)))
+	if w.Code != http.StatusOK || proc.got.OrderID != "o-2" {
+		t.Fatalf("expected the aliased order accepted, got %d %+v", w.Code, proc.got)
+	}
+	if r := tr.Report(); len(r) != 1 || r[0].Field != "orderRef" {
+		t.Fatalf("expected only orderRef ignored, got %+v", r)
+	}
+}
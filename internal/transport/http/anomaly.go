@@ -0,0 +1,255 @@
+package httptransport
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// Anomaly detection parameters. Rates are measured over one-minute
+// windows and folded into an exponentially weighted baseline.
+const (
+	anomalyWarmup      = 5     // windows before spikes are flagged
+	anomalyAlpha       = 0.1   // baseline weight of each new window
+	anomalyMinCount    = 5     // failures in a window needed to flag a spike
+	anomalyMinBaseline = 0.001 // floor for kinds that are normally absent
+)
+
+// AnomalyDetector tracks the fraction of orders failing with each error
+// kind and logs an alert when a kind spikes well above its baseline,
+// e.g. payment_declined at five times its usual rate.
+//
+// A window is flagged when the kind's rate is at least factor times
+// its baseline and it failed at least a handful of orders, so a single
+// failure at low traffic is not reported. A sustained new level is
+// gradually absorbed into the baseline and the alert then clears.
+//
+// Windows close at minute boundaries while Run is running, and
+// otherwise when the next order arrives. A window without orders leaves
+// the baselines alone and clears any alert.
+type AnomalyDetector struct {
+	logger *slog.Logger
+	factor float64
+	now    func() time.Time
+
+	mu      sync.Mutex
+	window  int64            // unix minute of the open window
+	total   int64            // orders in the open window
+	counts  map[string]int64 // failures per kind in the open window
+	windows int              // complete windows seen
+	kinds   map[string]*kindRate
+}
+
+type kindRate struct {
+	baseline  float64
+	rate      float64
+	count     int64
+	anomalous bool
+}
+
+// anomalyEvent is an alert raised or cleared when a window closes.
+type anomalyEvent struct {
+	kind    string
+	raised  bool
+	current kindRate
+}
+
+// NewAnomalyDetector returns an AnomalyDetector alerting through logger
+// when a kind's rate reaches factor times its baseline.
+//
+// A factor of 1 or less defaults to 5. It panics if logger is nil.
+func NewAnomalyDetector(logger *slog.Logger, factor float64) *AnomalyDetector {
+	if logger == nil {
+		panic("httptransport.NewAnomalyDetector: nil logger")
+	}
+	if factor <= 1 {
+		factor = 5
+	}
+	return &AnomalyDetector{
+		logger: logger,
+		factor: factor,
+		now:    time.Now,
+		counts: make(map[string]int64),
+		kinds:  make(map[string]*kindRate),
+	}
+}
+
+// Wrap returns an orderProcessor that delegates to p and records the
+// error kinds of each order.
+func (d *AnomalyDetector) Wrap(p orderProcessor) orderProcessor {
+	if p == nil {
+		panic("httptransport.AnomalyDetector.Wrap: nil order processor")
+	}
+	return &anomalyProcessor{detector: d, wrapped: wrapped{p}}
+}
+
+// anomalyProcessor is the orderProcessor returned by AnomalyDetector.Wrap.
+type anomalyProcessor struct {
+	wrapped
+	detector *AnomalyDetector
+}
+
+func (ap *anomalyProcessor) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	steps, err := ap.next.Process(ctx, req)
+	ap.detector.record(ctx, err)
+	return steps, err
+}
+
+// Run closes the open window at every minute boundary until ctx is
+// done, so a spike is reported when its minute ends, even if no order
+// follows it.
+func (d *AnomalyDetector) Run(ctx context.Context) {
+	for {
+		next := d.now().Truncate(time.Minute).Add(time.Minute)
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		d.rotate(ctx)
+	}
+}
+
+// rotate closes the open window if its minute has passed and logs the
+// resulting alerts.
+func (d *AnomalyDetector) rotate(ctx context.Context) {
+	d.mu.Lock()
+	events := d.rotateLocked()
+	d.mu.Unlock()
+	d.log(ctx, events)
+}
+
+// rotateLocked closes the open window if its minute has passed and
+// opens the current one, returning state changes. d.mu must be held.
+func (d *AnomalyDetector) rotateLocked() []anomalyEvent {
+	minute := d.now().Unix() / 60
+	if minute == d.window {
+		return nil
+	}
+	events := d.closeLocked()
+	d.window = minute
+	return events
+}
+
+// record counts one order, once per distinct error kind it failed with.
+func (d *AnomalyDetector) record(ctx context.Context, err error) {
+	d.mu.Lock()
+	events := d.rotateLocked()
+	d.total++
+	var seen []string
+	for _, e := range splitErrors(err) {
+		if k := errorKind(e); !slices.Contains(seen, k) {
+			seen = append(seen, k)
+			d.counts[k]++
+		}
+	}
+	d.mu.Unlock()
+
+	d.log(ctx, events)
+}
+
+// log reports raised and cleared alerts.
+func (d *AnomalyDetector) log(ctx context.Context, events []anomalyEvent) {
+	for _, ev := range events {
+		attrs := []slog.Attr{
+			slog.String("kind", ev.kind),
+			slog.Float64("rate", ev.current.rate),
+			slog.Float64("baseline", ev.current.baseline),
+			slog.Int64("count", ev.current.count),
+		}
+		if ev.raised {
+			d.logger.LogAttrs(ctx, slog.LevelWarn, "error kind spike", attrs...)
+		} else {
+			d.logger.LogAttrs(ctx, slog.LevelInfo, "error kind spike cleared", attrs...)
+		}
+	}
+}
+
+// closeLocked folds the open window into the baselines and returns
+// state changes. d.mu must be held.
+//
+// A window without orders has no rates to fold in: it zeroes the
+// reported rates and clears alerts, since nothing is failing, but
+// leaves the baselines and the warmup count as they were.
+func (d *AnomalyDetector) closeLocked() []anomalyEvent {
+	if d.total == 0 {
+		var events []anomalyEvent
+		for _, k := range slices.Sorted(maps.Keys(d.kinds)) {
+			kr := d.kinds[k]
+			kr.rate, kr.count = 0, 0
+			if kr.anomalous {
+				kr.anomalous = false
+				events = append(events, anomalyEvent{kind: k, current: *kr})
+			}
+		}
+		return events
+	}
+	for k := range d.counts {
+		if d.kinds[k] == nil {
+			d.kinds[k] = &kindRate{}
+		}
+	}
+
+	// Average the first windows evenly so the baseline is not biased
+	// toward zero during warmup.
+	alpha := max(anomalyAlpha, 1/float64(d.windows+1))
+
+	var events []anomalyEvent
+	for _, k := range slices.Sorted(maps.Keys(d.kinds)) {
+		kr := d.kinds[k]
+		kr.count = d.counts[k]
+		kr.rate = float64(kr.count) / float64(d.total)
+
+		spike := d.windows >= anomalyWarmup &&
+			kr.count >= anomalyMinCount &&
+			kr.rate >= d.factor*max(kr.baseline, anomalyMinBaseline)
+		if spike != kr.anomalous {
+			kr.anomalous = spike
+			events = append(events, anomalyEvent{kind: k, raised: spike, current: *kr})
+		}
+		kr.baseline += alpha * (kr.rate - kr.baseline)
+	}
+
+	d.windows++
+	d.total = 0
+	clear(d.counts)
+	return events
+}
+
+// Stats reports rates per error kind seen so far, sorted by kind.
+func (d *AnomalyDetector) Stats() []model.ErrorKindRate {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	out := make([]model.ErrorKindRate, 0, len(d.kinds))
+	for _, k := range slices.Sorted(maps.Keys(d.kinds)) {
+		kr := d.kinds[k]
+		out = append(out, model.ErrorKindRate{
+			Kind:      k,
+			Baseline:  kr.baseline,
+			Rate:      kr.rate,
+			Count:     kr.count,
+			Anomalous: kr.anomalous,
+		})
+	}
+	return out
+}
+
+// HandleAnomalies serves per-kind error rates and spike flags.
+//
+// The request must be a GET.
+func (d *AnomalyDetector) HandleAnomalies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, d.Stats())
+}
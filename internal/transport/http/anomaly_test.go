@@ -0,0 +1,245 @@
+package httptransport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// anomalyClock drives an AnomalyDetector one window at a time.
+type anomalyClock struct {
+	d   *AnomalyDetector
+	now time.Time
+}
+
+func newAnomalyClock(d *AnomalyDetector) *anomalyClock {
+	c := &anomalyClock{d: d, now: time.Unix(1_700_000_000, 0).Truncate(time.Minute)}
+	d.now = func() time.Time { return c.now }
+	return c
+}
+
+// window records orders orders, failing[kind] of them with kind, and
+// moves the clock to the next minute.
+func (c *anomalyClock) window(orders int, failing map[string]int) {
+	for kind, n := range failing {
+		for i := 0; i < n; i++ {
+			c.d.record(context.Background(), testAppErr{kind: kind})
+			orders--
+		}
+	}
+	for ; orders > 0; orders-- {
+		c.d.record(context.Background(), nil)
+	}
+	c.now = c.now.Add(time.Minute)
+}
+
+// flush closes the open window, as Run does at a minute boundary.
+func (c *anomalyClock) flush() { c.d.rotate(context.Background()) }
+
+func kindStats(t *testing.T, d *AnomalyDetector, kind string) model.ErrorKindRate {
+	t.Helper()
+	for _, st := range d.Stats() {
+		if st.Kind == kind {
+			return st
+		}
+	}
+	t.Fatalf("no stats for kind %q in %+v", kind, d.Stats())
+	return model.ErrorKindRate{}
+}
+
+func TestAnomalyDetector_Spike(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	d := NewAnomalyDetector(slog.New(slog.NewTextHandler(&buf, nil)), 5)
+	c := newAnomalyClock(d)
+
+	for i := 0; i < 10; i++ {
+		c.window(100, map[string]int{"payment_declined": 2})
+	}
+	if st := kindStats(t, d, "payment_declined"); st.Anomalous || st.Baseline < 0.019 || st.Baseline > 0.021 {
+		t.Fatalf("expected a steady 2%% baseline, got %+v", st)
+	}
+
+	c.window(100, map[string]int{"payment_declined": 15})
+	c.flush()
+	st := kindStats(t, d, "payment_declined")
+	if !st.Anomalous || st.Count != 15 || st.Rate != 0.15 {
+		t.Fatalf("expected a flagged 15%% window, got %+v", st)
+	}
+	if !strings.Contains(buf.String(), `level=WARN msg="error kind spike" kind=payment_declined`) {
+		t.Fatalf("expected spike alert, got %q", buf.String())
+	}
+
+	c.window(100, map[string]int{"payment_declined": 2})
+	c.flush()
+	if kindStats(t, d, "payment_declined").Anomalous {
+		t.Fatal("expected spike to clear")
+	}
+	if !strings.Contains(buf.String(), `msg="error kind spike cleared" kind=payment_declined`) {
+		t.Fatalf("expected cleared alert, got %q", buf.String())
+	}
+}
+
+// A spike is reported when its minute ends without waiting for another
+// order, and an idle minute clears it without moving the baseline.
+func TestAnomalyDetector_IdleMinutes(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	d := NewAnomalyDetector(slog.New(slog.NewTextHandler(&buf, nil)), 5)
+	c := newAnomalyClock(d)
+	for i := 0; i < 10; i++ {
+		c.window(100, map[string]int{"payment_declined": 2})
+	}
+	c.window(100, map[string]int{"payment_declined": 15})
+	c.flush()
+	if !strings.Contains(buf.String(), `msg="error kind spike" kind=payment_declined`) {
+		t.Fatalf("expected the spike reported at the minute boundary, got %q", buf.String())
+	}
+	baseline := kindStats(t, d, "payment_declined").Baseline
+
+	for i := 0; i < 3; i++ {
+		c.now = c.now.Add(time.Minute)
+		c.flush()
+	}
+	st := kindStats(t, d, "payment_declined")
+	if st.Anomalous || st.Count != 0 || st.Rate != 0 || st.Baseline != baseline {
+		t.Fatalf("expected idle minutes to clear the spike and keep baseline %v, got %+v", baseline, st)
+	}
+	if n := strings.Count(buf.String(), `msg="error kind spike cleared"`); n != 1 {
+		t.Fatalf("expected one cleared alert, got %d in %q", n, buf.String())
+	}
+}
+
+func TestAnomalyDetector_Run(t *testing.T) {
+	t.Parallel()
+
+	d := NewAnomalyDetector(slog.New(slog.DiscardHandler), 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to return once its context is done")
+	}
+}
+
+func TestAnomalyDetector_NotFlagged(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		warmup  int // normal windows before the spike
+		failing map[string]int
+	}{
+		{name: "during_warmup", warmup: 2, failing: map[string]int{"payment_declined": 50}},
+		{name: "too_few_failures", warmup: 10, failing: map[string]int{"no_courier": anomalyMinCount - 1}},
+		{name: "below_factor", warmup: 10, failing: map[string]int{"payment_declined": 8}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			d := NewAnomalyDetector(slog.New(slog.NewTextHandler(&buf, nil)), 5)
+			c := newAnomalyClock(d)
+			for i := 0; i < tt.warmup; i++ {
+				c.window(100, map[string]int{"payment_declined": 2})
+			}
+			c.window(100, tt.failing)
+			c.flush()
+
+			for _, st := range d.Stats() {
+				if st.Anomalous {
+					t.Fatalf("expected no anomaly, got %+v", st)
+				}
+			}
+			if buf.Len() != 0 {
+				t.Fatalf("expected no alert, got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestAnomalyDetector_NewKind(t *testing.T) {
+	t.Parallel()
+
+	d := NewAnomalyDetector(slog.New(slog.DiscardHandler), 5)
+	c := newAnomalyClock(d)
+	for i := 0; i < 10; i++ {
+		c.window(100, nil)
+	}
+	c.window(100, map[string]int{"vendor_unavailable": 20})
+	c.flush()
+	if st := kindStats(t, d, "vendor_unavailable"); !st.Anomalous {
+		t.Fatalf("expected a normally absent kind to be flagged, got %+v", st)
+	}
+}
+
+func TestAnomalyDetector_JoinedErrorsCountOncePerKind(t *testing.T) {
+	t.Parallel()
+
+	d := NewAnomalyDetector(slog.New(slog.DiscardHandler), 0)
+	c := newAnomalyClock(d)
+	p := d.Wrap(&stubProcessor{err: errors.Join(
+		testAppErr{kind: "vendor_unavailable"},
+		testAppErr{kind: "vendor_unavailable"},
+		testAppErr{kind: "no_courier"},
+	)})
+	for i := 0; i < 4; i++ {
+		_, _ = p.Process(context.Background(), model.OrderRequest{OrderID: "o-1"})
+	}
+	c.now = c.now.Add(time.Minute)
+	c.flush()
+
+	for _, kind := range []string{"no_courier", "vendor_unavailable"} {
+		if st := kindStats(t, d, kind); st.Count != 4 || st.Rate != 1 {
+			t.Fatalf("expected %s count=4 rate=1, got %+v", kind, st)
+		}
+	}
+}
+
+func TestHandleAnomalies(t *testing.T) {
+	t.Parallel()
+
+	d := NewAnomalyDetector(slog.New(slog.DiscardHandler), 5)
+	c := newAnomalyClock(d)
+	c.window(10, map[string]int{"timeout": 1})
+	c.flush()
+
+	w := httptest.NewRecorder()
+	d.HandleAnomalies(w, httptest.NewRequest(http.MethodGet, "/admin/anomalies", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var out []model.ErrorKindRate
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out) != 1 || out[0].Kind != "timeout" || out[0].Count != 1 || out[0].Rate != 0.1 {
+		t.Fatalf("unexpected stats: %+v", out)
+	}
+
+	w = httptest.NewRecorder()
+	d.HandleAnomalies(w, httptest.NewRequest(http.MethodPut, "/admin/anomalies", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,155 @@
+package httptransport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+type stubAuditLog struct {
+	entries   []model.AuditEntry
+	appendErr error
+	from, to  time.Time
+	result    model.AuditVerification
+	verifyErr error
+}
+
+func (s *stubAuditLog) Append(e model.AuditEntry) error {
+	if s.appendErr != nil {
+		return s.appendErr
+	}
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *stubAuditLog) Verify(from, to time.Time) (model.AuditVerification, error) {
+	s.from, s.to = from, to
+	return s.result, s.verifyErr
+}
+
+func TestAuditTrailWrap(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus model.Status
+		wantKind   string
+	}{
+		{name: "ok", wantStatus: model.StatusOK},
+		{name: "failed", err: testAppErr{kind: "payment_declined"}, wantStatus: model.StatusError, wantKind: "payment_declined"},
+		{name: "joined", err: errors.Join(testAppErr{kind: "payment_declined"}, context.DeadlineExceeded), wantStatus: model.StatusError, wantKind: "timeout"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			log := &stubAuditLog{}
+			steps := []model.StepResult{{Name: "payment", Status: model.StatusOK}}
+			p := NewAuditTrail(log, slog.New(slog.DiscardHandler), nil).Wrap(&stubProcessor{steps: steps, err: tt.err})
+
+			_, err := p.Process(context.Background(), model.OrderRequest{OrderID: "o-1"})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if len(log.entries) != 1 {
+				t.Fatalf("expected one entry, got %d", len(log.entries))
+			}
+			e := log.entries[0]
+			if e.Event != "order.processed" || e.OrderID != "o-1" || e.Status != tt.wantStatus || e.ErrorKind != tt.wantKind || len(e.Steps) != 1 {
+				t.Fatalf("unexpected entry: %+v", e)
+			}
+		})
+	}
+}
+
+type upperRedactor struct{}
+
+func (upperRedactor) String(field, value string) string { return field + ":" + strings.ToUpper(value) }
+
+func TestAuditTrailWrap_Redacted(t *testing.T) {
+	t.Parallel()
+
+	log := &stubAuditLog{}
+	p := NewAuditTrail(log, slog.New(slog.DiscardHandler), upperRedactor{}).Wrap(&stubProcessor{})
+	if _, err := p.Process(context.Background(), model.OrderRequest{OrderID: "o-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := log.entries[0].OrderID; got != "order_id:O-1" {
+		t.Fatalf("expected redacted order_id, got %q", got)
+	}
+}
+
+func TestAuditTrailWrap_AppendFailureLogged(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	log := &stubAuditLog{appendErr: errors.New("disk full")}
+	p := NewAuditTrail(log, slog.New(slog.NewTextHandler(&buf, nil)), nil).Wrap(&stubProcessor{})
+
+	if _, err := p.Process(context.Background(), model.OrderRequest{OrderID: "o-1"}); err != nil {
+		t.Fatalf("expected the order to succeed, got %v", err)
+	}
+	if !strings.Contains(buf.String(), `level=ERROR msg="audit append failed" order_id=o-1 error="disk full"`) {
+		t.Fatalf("expected append failure logged, got %q", buf.String())
+	}
+}
+
+func TestAuditTrailHandleVerify(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		method    string
+		query     string
+		verifyErr error
+		wantCode  int
+		wantFrom  time.Time
+	}{
+		{name: "all", method: http.MethodGet, wantCode: http.StatusOK},
+		{name: "range", method: http.MethodGet, query: "?from=2026-01-02T15:00:00Z&to=2026-01-02T16:00:00Z", wantCode: http.StatusOK, wantFrom: time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)},
+		{name: "bad_from", method: http.MethodGet, query: "?from=yesterday", wantCode: http.StatusBadRequest},
+		{name: "unreadable", method: http.MethodGet, verifyErr: errors.New("io"), wantCode: http.StatusInternalServerError},
+		{name: "method", method: http.MethodPost, wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			log := &stubAuditLog{result: model.AuditVerification{Valid: true, Entries: 3}, verifyErr: tt.verifyErr}
+			a := NewAuditTrail(log, slog.New(slog.DiscardHandler), nil)
+
+			w := httptest.NewRecorder()
+			a.HandleVerify(w, httptest.NewRequest(tt.method, "/admin/audit/verify"+tt.query, nil))
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d", tt.wantCode, w.Code)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			if !log.from.Equal(tt.wantFrom) {
+				t.Fatalf("expected from %v, got %v", tt.wantFrom, log.from)
+			}
+			var out model.AuditVerification
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if out != log.result {
+				t.Fatalf("expected %+v, got %+v", log.result, out)
+			}
+		})
+	}
+}
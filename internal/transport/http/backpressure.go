@@ -0,0 +1,89 @@
+package httptransport
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// Header names used to signal backpressure to clients.
+const (
+	HeaderServerLoad           = "X-Server-Load"
+	HeaderSuggestedConcurrency = "X-Suggested-Concurrency"
+)
+
+// capacitySource reports occupancy of a bounded resource.
+type capacitySource interface {
+	Cap() int
+	InUse() int
+	Waiting() int64
+}
+
+// Backpressure advertises server load so clients can self-throttle.
+type Backpressure struct {
+	src            capacitySource
+	loadThreshold  float64
+	queueThreshold int64
+}
+
+// NewBackpressure returns a Backpressure reporting on src.
+//
+// Load headers are attached once utilization reaches loadThreshold
+// (0..1, default 0.8) or the number of waiters reaches queueThreshold
+// (default 1). It panics if src is nil.
+func NewBackpressure(src capacitySource, loadThreshold float64, queueThreshold int64) *Backpressure {
+	if src == nil {
+		panic("httptransport.NewBackpressure: nil capacity source")
+	}
+	if loadThreshold <= 0 || loadThreshold > 1 {
+		loadThreshold = 0.8
+	}
+	if queueThreshold <= 0 {
+		queueThreshold = 1
+	}
+	return &Backpressure{
+		src:            src,
+		loadThreshold:  loadThreshold,
+		queueThreshold: queueThreshold,
+	}
+}
+
+// snapshot reads the current capacity figures.
+func (b *Backpressure) snapshot() model.CapacityResponse {
+	c := model.CapacityResponse{
+		Capacity: b.src.Cap(),
+		InUse:    b.src.InUse(),
+		Waiting:  b.src.Waiting(),
+	}
+	c.Headroom = max(c.Capacity-c.InUse-int(c.Waiting), 0)
+	if c.Capacity > 0 {
+		c.Load = float64(c.InUse+int(c.Waiting)) / float64(c.Capacity)
+	}
+	c.SuggestedConcurrency = max(c.Headroom, 1)
+	return c
+}
+
+// Middleware wraps next and attaches X-Server-Load and
+// X-Suggested-Concurrency headers when load crosses a threshold.
+func (b *Backpressure) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := b.snapshot()
+		if c.Load >= b.loadThreshold || c.Waiting >= b.queueThreshold {
+			w.Header().Set(HeaderServerLoad, strconv.FormatFloat(c.Load, 'f', 2, 64))
+			w.Header().Set(HeaderSuggestedConcurrency, strconv.Itoa(c.SuggestedConcurrency))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HandleCapacity reports current headroom as a CapacityResponse.
+//
+// The request must be a GET.
+func (b *Backpressure) HandleCapacity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, b.snapshot())
+}
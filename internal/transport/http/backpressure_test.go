@@ -0,0 +1,87 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+type stubCapacity struct {
+	cap     int
+	inUse   int
+	waiting int64
+}
+
+func (s stubCapacity) Cap() int       { return s.cap }
+func (s stubCapacity) InUse() int     { return s.inUse }
+func (s stubCapacity) Waiting() int64 { return s.waiting }
+
+func TestBackpressureMiddleware(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		src           stubCapacity
+		wantLoad      string
+		wantSuggested string
+	}{
+		{name: "idle", src: stubCapacity{cap: 5}},
+		{name: "below_threshold", src: stubCapacity{cap: 5, inUse: 3}},
+		{name: "at_threshold", src: stubCapacity{cap: 5, inUse: 4}, wantLoad: "0.80", wantSuggested: "1"},
+		{name: "queued", src: stubCapacity{cap: 5, inUse: 1, waiting: 1}, wantLoad: "0.40", wantSuggested: "3"},
+		{name: "saturated", src: stubCapacity{cap: 5, inUse: 5, waiting: 4}, wantLoad: "1.80", wantSuggested: "1"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := NewBackpressure(tt.src, 0.8, 1)
+			h := b.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/order", nil))
+
+			if got := w.Header().Get(HeaderServerLoad); got != tt.wantLoad {
+				t.Fatalf("expected %s=%q, got %q", HeaderServerLoad, tt.wantLoad, got)
+			}
+			if got := w.Header().Get(HeaderSuggestedConcurrency); got != tt.wantSuggested {
+				t.Fatalf("expected %s=%q, got %q", HeaderSuggestedConcurrency, tt.wantSuggested, got)
+			}
+		})
+	}
+}
+
+func TestHandleCapacity(t *testing.T) {
+	t.Parallel()
+
+	b := NewBackpressure(stubCapacity{cap: 5, inUse: 2, waiting: 0}, 0.8, 1)
+
+	w := httptest.NewRecorder()
+	b.HandleCapacity(w, httptest.NewRequest(http.MethodGet, "/capacity", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var out model.CapacityResponse
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := model.CapacityResponse{Capacity: 5, InUse: 2, Headroom: 3, Load: 0.4, SuggestedConcurrency: 3}
+	if out != want {
+		t.Fatalf("expected %+v, got %+v", want, out)
+	}
+
+	w = httptest.NewRecorder()
+	b.HandleCapacity(w, httptest.NewRequest(http.MethodPost, "/capacity", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
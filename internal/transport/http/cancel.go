@@ -0,0 +1,252 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/progress"
+)
+
+// maxBatchOrders bounds the order IDs accepted by one batch request.
+const maxBatchOrders = 1000
+
+// errCanceledByOperator is the cancellation cause of orders canceled
+// through the batch cancel endpoint.
+var errCanceledByOperator = errors.New("canceled by operator")
+
+// Canceller tracks the orders in flight so operators can cancel them in
+// bulk, by ID or by filter.
+type Canceller struct {
+	wait        time.Duration
+	concurrency int
+	now         func() time.Time
+
+	mu       sync.Mutex
+	inFlight map[*inFlightOrder]struct{}
+}
+
+// inFlightOrder is one order being processed through a Canceller.
+type inFlightOrder struct {
+	orderID string
+	zone    string
+	start   time.Time
+	cancel  context.CancelCauseFunc
+	done    chan struct{} // closed when Process returns
+	steps   progress.Collector
+}
+
+// NewCanceller returns a Canceller whose batch requests wait up to wait
+// for canceled orders to stop, canceling up to concurrency orders at a
+// time. A non-positive wait defaults to 5 seconds and a non-positive
+// concurrency to 16.
+func NewCanceller(wait time.Duration, concurrency int) *Canceller {
+	if wait <= 0 {
+		wait = 5 * time.Second
+	}
+	if concurrency <= 0 {
+		concurrency = 16
+	}
+	return &Canceller{
+		wait:        wait,
+		concurrency: concurrency,
+		now:         time.Now,
+		inFlight:    make(map[*inFlightOrder]struct{}),
+	}
+}
+
+// Wrap returns an orderProcessor that runs p under a context the
+// Canceller can cancel.
+func (c *Canceller) Wrap(p orderProcessor) orderProcessor {
+	if p == nil {
+		panic("httptransport.Canceller.Wrap: nil order processor")
+	}
+	return &cancelProcessor{canceller: c, wrapped: wrapped{p}}
+}
+
+// cancelProcessor is the orderProcessor returned by Canceller.Wrap.
+type cancelProcessor struct {
+	wrapped
+	canceller *Canceller
+}
+
+func (cp *cancelProcessor) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	o := &inFlightOrder{
+		orderID: req.OrderID,
+		zone:    req.Zone,
+		start:   cp.canceller.now(),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	c := cp.canceller
+	c.mu.Lock()
+	c.inFlight[o] = struct{}{}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.inFlight, o)
+		c.mu.Unlock()
+		close(o.done)
+		cancel(nil)
+	}()
+	return cp.next.Process(progress.NewContext(ctx, &o.steps), req)
+}
+
+// Cancel cancels the orders selected by req and reports, per order,
+// whether it stopped before ctx is done or the Canceller's wait elapses.
+// Requested IDs not in flight are reported as model.OutcomeNotFound;
+// an ID in flight more than once is reported for each order.
+func (c *Canceller) Cancel(ctx context.Context, req model.BatchCancelRequest) model.BatchReport {
+	var targets []*inFlightOrder
+	var report model.BatchReport
+	c.mu.Lock()
+	if req.Filter != nil {
+		for o := range c.inFlight {
+			if matchesFilter(req.Filter, o, c.now()) {
+				targets = append(targets, o)
+			}
+		}
+	} else {
+		byID := make(map[string][]*inFlightOrder, len(c.inFlight))
+		for o := range c.inFlight {
+			byID[o.orderID] = append(byID[o.orderID], o)
+		}
+		seen := make(map[string]bool, len(req.OrderIDs))
+		for _, id := range req.OrderIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if len(byID[id]) == 0 {
+				report.Results = append(report.Results, model.BatchOutcome{OrderID: id, Outcome: model.OutcomeNotFound})
+			}
+			targets = append(targets, byID[id]...)
+		}
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.wait)
+	defer cancel()
+
+	outcomes := make([]model.BatchOutcome, len(targets))
+	var g errgroup.Group
+	g.SetLimit(c.concurrency)
+	for i, o := range targets {
+		g.Go(func() error {
+			o.cancel(errCanceledByOperator)
+			outcome := model.OutcomeCanceled
+			select {
+			case <-o.done:
+			case <-ctx.Done():
+				outcome = model.OutcomeStillRunning
+			}
+			outcomes[i] = model.BatchOutcome{OrderID: o.orderID, Outcome: outcome}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	report.Results = append(report.Results, outcomes...)
+	if report.Results == nil {
+		report.Results = []model.BatchOutcome{}
+	}
+	return report
+}
+
+// Progress reports the orders in flight, oldest first, with the steps
+// each has finished and the ones still running. A non-empty orderID
+// selects that order; it may be in flight more than once. The steps come
+// from the order's progress.Collector, so an order whose processor does
+// not collect into the context, such as one coalesced onto another
+// order's run, reports every step as running.
+func (c *Canceller) Progress(orderID string) []model.OrderProgress {
+	c.mu.Lock()
+	orders := make([]*inFlightOrder, 0, len(c.inFlight))
+	for o := range c.inFlight {
+		if orderID == "" || o.orderID == orderID {
+			orders = append(orders, o)
+		}
+	}
+	c.mu.Unlock()
+	slices.SortFunc(orders, func(a, b *inFlightOrder) int { return a.start.Compare(b.start) })
+
+	now := c.now()
+	out := make([]model.OrderProgress, len(orders))
+	for i, o := range orders {
+		done, running := o.steps.Snapshot()
+		out[i] = model.OrderProgress{
+			OrderID:    o.orderID,
+			Zone:       o.zone,
+			ElapsedMS:  now.Sub(o.start).Milliseconds(),
+			Done:       done,
+			Running:    running,
+			Heartbeats: o.steps.Heartbeats(now),
+		}
+	}
+	return out
+}
+
+// HandleProgress responds with the model.OrderProgress of the orders in
+// flight; the id query parameter selects one order and answers 404 when
+// it is not in flight. The request must be a GET.
+func (c *Canceller) HandleProgress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.URL.Query().Get("id")
+	orders := c.Progress(id)
+	if id != "" && len(orders) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, orders)
+}
+
+// matchesFilter reports whether o meets every criterion set in f.
+func matchesFilter(f *model.OrderFilter, o *inFlightOrder, now time.Time) bool {
+	if f.Zone != "" && o.zone != f.Zone {
+		return false
+	}
+	return f.MinAgeMS <= 0 || now.Sub(o.start) >= time.Duration(f.MinAgeMS)*time.Millisecond
+}
+
+// validateBatchCancel reports what is wrong with req, or returns nil.
+func validateBatchCancel(req model.BatchCancelRequest) error {
+	switch {
+	case (len(req.OrderIDs) > 0) == (req.Filter != nil):
+		return errors.New("exactly one of order_ids and filter is required")
+	case len(req.OrderIDs) > maxBatchOrders:
+		return fmt.Errorf("at most %d order_ids", maxBatchOrders)
+	case req.Filter != nil && req.Filter.Zone == "" && req.Filter.MinAgeMS <= 0:
+		return errors.New("filter needs zone or min_age_ms")
+	}
+	return nil
+}
+
+// HandleBatchCancel cancels in-flight orders and responds with a
+// model.BatchReport. The request must be a POST with a
+// model.BatchCancelRequest body.
+func (c *Canceller) HandleBatchCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req model.BatchCancelRequest
+	if err := decodeStrictJSON(w, r, &req); err != nil {
+		badRequest(w, "invalid JSON")
+		return
+	}
+	if err := validateBatchCancel(req); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, c.Cancel(r.Context(), req))
+}
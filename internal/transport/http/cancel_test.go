@@ -0,0 +1,245 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/order"
+)
+
+// blockingProcessor runs until its context is done, or forever if
+// ignoreCancel is set, and records the cause it was canceled with.
+type blockingProcessor struct {
+	started      chan string
+	ignoreCancel bool
+	release      chan struct{}
+	causes       chan error
+}
+
+func newBlockingProcessor(ignoreCancel bool) *blockingProcessor {
+	return &blockingProcessor{
+		started:      make(chan string, 10),
+		ignoreCancel: ignoreCancel,
+		release:      make(chan struct{}),
+		causes:       make(chan error, 10),
+	}
+}
+
+func (b *blockingProcessor) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	b.started <- req.OrderID
+	if b.ignoreCancel {
+		<-b.release
+		return nil, nil
+	}
+	<-ctx.Done()
+	b.causes <- context.Cause(ctx)
+	return nil, ctx.Err()
+}
+
+// startOrders runs one order per request through c and waits until all
+// of them are in flight.
+func startOrders(t *testing.T, c *Canceller, b *blockingProcessor, reqs ...model.OrderRequest) {
+	t.Helper()
+	p := c.Wrap(b)
+	for _, req := range reqs {
+		go p.Process(t.Context(), req)
+	}
+	for range reqs {
+		<-b.started
+	}
+}
+
+func outcomes(r model.BatchReport) []string {
+	var out []string
+	for _, o := range r.Results {
+		out = append(out, o.OrderID+"="+o.Outcome)
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestCancellerCancel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		req  model.BatchCancelRequest
+		want []string
+	}{
+		{
+			name: "by_id",
+			req:  model.BatchCancelRequest{OrderIDs: []string{"o-1", "o-9", "o-1"}},
+			want: []string{"o-1=canceled", "o-9=not_found"},
+		},
+		{
+			name: "by_zone",
+			req:  model.BatchCancelRequest{Filter: &model.OrderFilter{Zone: "north"}},
+			want: []string{"o-1=canceled", "o-3=canceled"},
+		},
+		{
+			name: "by_age",
+			req:  model.BatchCancelRequest{Filter: &model.OrderFilter{MinAgeMS: 60_000}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := NewCanceller(time.Second, 2)
+			b := newBlockingProcessor(false)
+			startOrders(t, c, b,
+				model.OrderRequest{OrderID: "o-1", Zone: "north"},
+				model.OrderRequest{OrderID: "o-2", Zone: "south"},
+				model.OrderRequest{OrderID: "o-3", Zone: "north"},
+			)
+
+			got := outcomes(c.Cancel(context.Background(), tt.req))
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for _, o := range got {
+				if !strings.HasSuffix(o, "="+model.OutcomeCanceled) {
+					continue
+				}
+				if cause := <-b.causes; !errors.Is(cause, errCanceledByOperator) {
+					t.Fatalf("expected cause %v, got %v", errCanceledByOperator, cause)
+				}
+			}
+		})
+	}
+}
+
+func TestCancellerCancel_StillRunning(t *testing.T) {
+	t.Parallel()
+
+	c := NewCanceller(20*time.Millisecond, 0)
+	b := newBlockingProcessor(true)
+	defer close(b.release)
+	startOrders(t, c, b, model.OrderRequest{OrderID: "o-1"})
+
+	got := outcomes(c.Cancel(context.Background(), model.BatchCancelRequest{OrderIDs: []string{"o-1"}}))
+	if want := []string{"o-1=still_running"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestHandleBatchCancel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{name: "ok", method: http.MethodPost, body: `{"order_ids":["o-1"]}`, wantStatus: http.StatusOK},
+		{name: "method_not_allowed", method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed},
+		{name: "invalid_json", method: http.MethodPost, body: `{"order_ids":`, wantStatus: http.StatusBadRequest},
+		{name: "nothing_selected", method: http.MethodPost, body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "ids_and_filter", method: http.MethodPost, body: `{"order_ids":["o-1"],"filter":{"zone":"north"}}`, wantStatus: http.StatusBadRequest},
+		{name: "empty_filter", method: http.MethodPost, body: `{"filter":{}}`, wantStatus: http.StatusBadRequest},
+		{name: "too_many_ids", method: http.MethodPost, body: `{"order_ids":[` + strings.Repeat(`"o",`, maxBatchOrders) + `"o"]}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := NewCanceller(time.Second, 0)
+			w := httptest.NewRecorder()
+			c.HandleBatchCancel(w, httptest.NewRequest(tt.method, "/admin/orders:batchCancel", strings.NewReader(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var out model.BatchReport
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got := outcomes(out); !slices.Equal(got, []string{"o-1=not_found"}) {
+				t.Fatalf("unexpected report %v", got)
+			}
+		})
+	}
+}
+
+func TestCancellerProgress(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	svc := order.New([]order.Step{
+		{Name: "payment", Run: func(context.Context, model.OrderRequest) error { return nil }},
+		{Name: "courier", Run: func(context.Context, model.OrderRequest) error {
+			<-release
+			return nil
+		}},
+	})
+	c := NewCanceller(time.Second, 0)
+	p := c.Wrap(svc)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		_, _ = p.Process(t.Context(), model.OrderRequest{OrderID: "o-1", Zone: "north"})
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	var got []model.OrderProgress
+	for {
+		if got = c.Progress("o-1"); len(got) == 1 && len(got[0].Done) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected payment to finish, got %+v", got)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got[0].Zone != "north" || got[0].Done[0].Name != "payment" || !slices.Equal(got[0].Running, []string{"courier"}) {
+		t.Fatalf("unexpected progress %+v", got[0])
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantOrders int
+	}{
+		{name: "all", method: http.MethodGet, target: "/admin/orders/progress", wantStatus: http.StatusOK, wantOrders: 1},
+		{name: "by_id", method: http.MethodGet, target: "/admin/orders/progress?id=o-1", wantStatus: http.StatusOK, wantOrders: 1},
+		{name: "not_in_flight", method: http.MethodGet, target: "/admin/orders/progress?id=o-2", wantStatus: http.StatusNotFound},
+		{name: "method_not_allowed", method: http.MethodPost, target: "/admin/orders/progress", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c.HandleProgress(w, httptest.NewRequest(tt.method, tt.target, nil))
+		if w.Code != tt.wantStatus {
+			t.Fatalf("%s: expected %d, got %d", tt.name, tt.wantStatus, w.Code)
+		}
+		if tt.wantStatus != http.StatusOK {
+			continue
+		}
+		var orders []model.OrderProgress
+		if err := json.NewDecoder(w.Body).Decode(&orders); err != nil || len(orders) != tt.wantOrders {
+			t.Fatalf("%s: expected %d orders, got %v (%v)", tt.name, tt.wantOrders, orders, err)
+		}
+	}
+
+	close(release)
+	<-finished
+	if got := c.Progress(""); len(got) != 0 {
+		t.Fatalf("expected no orders in flight, got %+v", got)
+	}
+}
@@ -0,0 +1,158 @@
+package httptransport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// Unattributed is the channel reported for orders that name none.
+const Unattributed = "unattributed"
+
+// Channels is the set of order sources (web, ios, a partner) accepted in
+// OrderRequest.Channel, with per-channel outcome and latency counters.
+//
+// Only configured channels are counted, so a client cannot grow the
+// report by inventing names; the handler rejects unknown ones (see
+// WithChannels).
+type Channels struct {
+	byName map[string]*channelStats
+	names  []string // configured order, Unattributed last
+	now    func() time.Time
+}
+
+type channelStats struct {
+	mu        sync.Mutex
+	orders    int64
+	succeeded int64
+	total     time.Duration
+	max       time.Duration
+}
+
+// NewChannels returns Channels accepting the given names. It panics if
+// no names are given, a name is repeated or a name is Unattributed.
+func NewChannels(names ...string) *Channels {
+	if len(names) == 0 {
+		panic("httptransport.NewChannels: no channels")
+	}
+	c := &Channels{byName: make(map[string]*channelStats, len(names)+1), now: time.Now}
+	for _, n := range append(slices.Clip(names), Unattributed) {
+		if _, dup := c.byName[n]; dup {
+			panic("httptransport.NewChannels: duplicate channel " + n)
+		}
+		c.byName[n] = &channelStats{}
+		c.names = append(c.names, n)
+	}
+	return c
+}
+
+// ParseChannels parses a comma-separated list of channel names, e.g.
+// "web,ios,partner-acme". Names are lowercase letters, digits, '-' and
+// '_'.
+func ParseChannels(spec string) ([]string, error) {
+	var names []string
+	for n := range strings.SplitSeq(spec, ",") {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		if strings.TrimLeft(n, "abcdefghijklmnopqrstuvwxyz0123456789-_") != "" {
+			return nil, fmt.Errorf("httptransport: channel %q: want lowercase letters, digits, - and _", n)
+		}
+		names = append(names, n)
+	}
+	return names, nil
+}
+
+// Known reports whether an order may name channel; the empty channel is
+// always accepted, as Unattributed.
+func (c *Channels) Known(channel string) bool {
+	if channel == "" {
+		return true
+	}
+	_, ok := c.byName[channel]
+	return ok && channel != Unattributed
+}
+
+// list returns the configured channel names, for error messages.
+func (c *Channels) list() string {
+	return strings.Join(c.names[:len(c.names)-1], ", ")
+}
+
+// Wrap returns an orderProcessor that runs p and counts each order's
+// outcome and latency under its channel.
+func (c *Channels) Wrap(p orderProcessor) orderProcessor {
+	if p == nil {
+		panic("httptransport.Channels.Wrap: nil order processor")
+	}
+	return &channelProcessor{channels: c, wrapped: wrapped{p}}
+}
+
+// channelProcessor is the orderProcessor returned by Channels.Wrap.
+type channelProcessor struct {
+	wrapped
+	channels *Channels
+}
+
+func (cp *channelProcessor) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	start := cp.channels.now()
+	steps, err := cp.next.Process(ctx, req)
+	elapsed := cp.channels.now().Sub(start)
+
+	name := req.Channel
+	if name == "" {
+		name = Unattributed
+	}
+	if s, ok := cp.channels.byName[name]; ok {
+		s.mu.Lock()
+		s.orders++
+		if err == nil {
+			s.succeeded++
+		}
+		s.total += elapsed
+		s.max = max(s.max, elapsed)
+		s.mu.Unlock()
+	}
+	return steps, err
+}
+
+// Stats reports the counters per channel, in configured order with
+// Unattributed last.
+func (c *Channels) Stats() []model.ChannelStats {
+	out := make([]model.ChannelStats, len(c.names))
+	for i, n := range c.names {
+		s := c.byName[n]
+		s.mu.Lock()
+		st := model.ChannelStats{
+			Channel:   n,
+			Orders:    s.orders,
+			Succeeded: s.succeeded,
+			Failed:    s.orders - s.succeeded,
+			MaxMS:     s.max.Milliseconds(),
+		}
+		if s.orders > 0 {
+			st.SuccessRate = float64(s.succeeded) / float64(s.orders)
+			st.AvgMS = (s.total / time.Duration(s.orders)).Milliseconds()
+		}
+		s.mu.Unlock()
+		out[i] = st
+	}
+	return out
+}
+
+// HandleChannels serves the per-channel counters.
+//
+// The request must be a GET.
+func (c *Channels) HandleChannels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, c.Stats())
+}
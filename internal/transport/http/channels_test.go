@@ -0,0 +1,136 @@
+package httptransport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// clockedProcessor advances a fake clock by each order's delay_ms
+// "total" and fails orders with a fail_step.
+type clockedProcessor struct{ now *time.Time }
+
+func (p clockedProcessor) Process(_ context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	*p.now = p.now.Add(time.Duration(req.DelayMS["total"]) * time.Millisecond)
+	if req.FailStep != "" {
+		return nil, errors.New("failed")
+	}
+	return nil, nil
+}
+
+func TestParseChannels(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseChannels(" web, ios,,partner-acme ")
+	if err != nil || !slices.Equal(got, []string{"web", "ios", "partner-acme"}) {
+		t.Fatalf("expected three channels, got %v (%v)", got, err)
+	}
+	for _, spec := range []string{"Web", "web,partner acme", "ios/2"} {
+		if _, err := ParseChannels(spec); err == nil {
+			t.Fatalf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestNewChannels_InvalidPanics(t *testing.T) {
+	t.Parallel()
+
+	for name, names := range map[string][]string{
+		"none":         nil,
+		"duplicate":    {"web", "web"},
+		"unattributed": {"web", Unattributed},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			NewChannels(names...)
+		})
+	}
+}
+
+func TestChannelsWrap(t *testing.T) {
+	t.Parallel()
+
+	c := NewChannels("web", "ios")
+	now := time.Unix(1_700_000_000, 0)
+	c.now = func() time.Time { return now }
+	p := c.Wrap(clockedProcessor{now: &now})
+
+	for _, req := range []model.OrderRequest{
+		{Channel: "web", DelayMS: map[string]int64{"total": 100}},
+		{Channel: "web", DelayMS: map[string]int64{"total": 300}, FailStep: "vendor"},
+		{Channel: "web", DelayMS: map[string]int64{"total": 200}},
+		{DelayMS: map[string]int64{"total": 50}},
+		{Channel: "android"}, // not configured; not counted
+	} {
+		_, _ = p.Process(context.Background(), req)
+	}
+
+	want := []model.ChannelStats{
+		{Channel: "web", Orders: 3, Succeeded: 2, Failed: 1, SuccessRate: 2.0 / 3, AvgMS: 200, MaxMS: 300},
+		{Channel: "ios"},
+		{Channel: Unattributed, Orders: 1, Succeeded: 1, SuccessRate: 1, AvgMS: 50, MaxMS: 50},
+	}
+	if got := c.Stats(); !slices.Equal(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	w := httptest.NewRecorder()
+	c.HandleChannels(w, httptest.NewRequest(http.MethodPost, "/admin/channels", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleOrder_Channels(t *testing.T) {
+	t.Parallel()
+
+	h := New(&stubProcessor{}, time.Second, WithChannels(NewChannels("web", "ios")))
+
+	tests := []struct {
+		name       string
+		channel    string
+		wantStatus int
+	}{
+		{name: "known", channel: "ios", wantStatus: http.StatusOK},
+		{name: "none", wantStatus: http.StatusOK},
+		{name: "unknown", channel: "android", wantStatus: http.StatusUnprocessableEntity},
+		{name: "reserved", channel: Unattributed, wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			body, _ := json.Marshal(model.OrderRequest{OrderID: "o-1", Amount: 10, Channel: tt.channel})
+			w := httptest.NewRecorder()
+			h.HandleOrder(w, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus == http.StatusOK {
+				return
+			}
+			var out model.OrderResponse
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			want := []model.FieldError{{Field: "channel", Message: "must be one of web, ios"}}
+			if out.Error == nil || !slices.Equal(out.Error.Fields, want) {
+				t.Fatalf("expected %+v, got %+v", want, out.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package httptransport
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// Clients retrying a slow submission, or a double-clicked checkout, send
+// the same order again while the first is still processing. With
+// WithCoalescing, concurrent orders with the same order_id share one run
+// of the pipeline: the first starts it, the others wait for it, and all
+// are answered with its outcome and flagged Shared. Only the order_id is
+// compared, so the body of the first submission wins. Orders arriving
+// after the run has finished start a new one.
+//
+// The run is detached from the client that started it: it keeps the
+// first request's context values and deadline, and is canceled only
+// when every waiting client has disconnected, with the last one's
+// cause. A client that disconnects earlier stops waiting on its own.
+
+// coalescer shares one pipeline run among concurrent orders with the
+// same ID.
+type coalescer struct {
+	group singleflight.Group
+
+	mu      sync.Mutex
+	flights map[string]*flight // by order ID; in step with group's calls
+}
+
+// flight is the context of one shared run.
+type flight struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	stop    context.CancelFunc // releases the deadline
+	waiters int                // guarded by coalescer.mu
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{flights: map[string]*flight{}}
+}
+
+// process runs req through p, or joins the run in flight for its order
+// ID, and reports whether the results were shared. ctx is the order's
+// processing context and client the request's own. The returned slice
+// belongs to the caller and is never released to p.
+func (c *coalescer) process(client, ctx context.Context, p orderProcessor, req model.OrderRequest) ([]model.StepResult, bool, error) {
+	c.mu.Lock()
+	f, ok := c.flights[req.OrderID]
+	if !ok {
+		f = newFlight(ctx)
+		c.flights[req.OrderID] = f
+	}
+	f.waiters++
+	ch := c.group.DoChan(req.OrderID, func() (any, error) {
+		defer c.finish(req.OrderID, f)
+		steps, err := p.Process(f.ctx, req)
+		out := slices.Clone(steps)
+		if r, ok := p.(resultReleaser); ok {
+			r.Release(steps)
+		}
+		return out, err
+	})
+	c.mu.Unlock()
+
+	select {
+	case res := <-ch:
+		return flightResult(res)
+	case <-client.Done():
+	}
+	c.mu.Lock()
+	f.waiters--
+	last := f.waiters == 0
+	c.mu.Unlock()
+	if !last {
+		return nil, false, errClientDisconnected
+	}
+	f.cancel(errClientDisconnected)
+	return flightResult(<-ch)
+}
+
+// newFlight returns the context of a run started by an order processed
+// under ctx: its values and deadline, but not its cancellation.
+func newFlight(ctx context.Context) *flight {
+	f := &flight{stop: func() {}}
+	f.ctx, f.cancel = context.WithCancelCause(context.WithoutCancel(ctx))
+	if deadline, ok := ctx.Deadline(); ok {
+		f.ctx, f.stop = context.WithDeadlineCause(f.ctx, deadline, errRequestTimeout)
+	}
+	return f
+}
+
+// finish forgets the run for id together with its flight, so that no
+// order joins a finished run, or a flight without its run.
+func (c *coalescer) finish(id string, f *flight) {
+	c.mu.Lock()
+	c.group.Forget(id)
+	delete(c.flights, id)
+	c.mu.Unlock()
+	f.stop()
+	f.cancel(nil)
+}
+
+// flightResult unpacks res, copying the steps for each sharing
+// caller.
+func flightResult(res singleflight.Result) ([]model.StepResult, bool, error) {
+	steps, _ := res.Val.([]model.StepResult)
+	if res.Shared {
+		steps = slices.Clone(steps)
+	}
+	return steps, res.Shared, res.Err
+}
@@ -0,0 +1,148 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// gatedProcessor blocks each order until its gate is closed, and pools
+// its results like order.Service.
+type gatedProcessor struct {
+	gate     chan struct{}
+	calls    atomic.Int64
+	released atomic.Int64
+	canceled chan error // receives the cause of runs whose context ended
+}
+
+func (g *gatedProcessor) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	g.calls.Add(1)
+	select {
+	case <-g.gate:
+		return []model.StepResult{{Name: "payment", Status: model.StatusOK, Detail: req.Zone}}, nil
+	case <-ctx.Done():
+		g.canceled <- context.Cause(ctx)
+		return []model.StepResult{{Name: "payment", Status: model.StatusCanceled}}, ctx.Err()
+	}
+}
+
+func (g *gatedProcessor) Release([]model.StepResult) { g.released.Add(1) }
+
+// waitForWaiters waits until n orders wait on the run for id.
+func waitForWaiters(t *testing.T, h *Handler, id string, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		h.coalescer.mu.Lock()
+		f := h.coalescer.flights[id]
+		got := 0
+		if f != nil {
+			got = f.waiters
+		}
+		h.coalescer.mu.Unlock()
+		if got == n {
+			return
+		}
+	}
+	t.Fatalf("expected %d orders waiting on %s", n, id)
+}
+
+func TestHandleOrder_Coalescing(t *testing.T) {
+	t.Parallel()
+
+	proc := &gatedProcessor{gate: make(chan struct{}), canceled: make(chan error, 1)}
+	h := New(proc, time.Second, WithCoalescing())
+	post := func(ctx context.Context, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.HandleOrder(w, httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body)).WithContext(ctx))
+		return w
+	}
+
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 3)
+	for i, zone := range []string{"first", "second", "third"} {
+		wg.Go(func() {
+			responses[i] = post(context.Background(), `{"order_id":"o-1","amount":10,"zone":"`+zone+`"}`)
+		})
+		waitForWaiters(t, h, "o-1", i+1) // in order, so the first body wins
+	}
+	close(proc.gate)
+	wg.Wait()
+
+	for _, w := range responses {
+		var resp model.OrderResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v)", w.Code, err)
+		}
+		if !resp.Shared || len(resp.Steps) != 1 || resp.Steps[0].Detail != "first" {
+			t.Fatalf("expected the first order's shared result, got %+v", resp)
+		}
+	}
+	if proc.calls.Load() != 1 || proc.released.Load() != 1 {
+		t.Fatalf("expected one run released once, got %d runs, %d releases", proc.calls.Load(), proc.released.Load())
+	}
+
+	// Later orders, and other order IDs, run on their own.
+	for _, id := range []string{"o-1", "o-2"} {
+		w := post(context.Background(), `{"order_id":"`+id+`","amount":10}`)
+		if w.Code != http.StatusOK || strings.Contains(w.Body.String(), `"shared"`) {
+			t.Fatalf("expected an unshared 200 for %s, got %d %s", id, w.Code, w.Body)
+		}
+	}
+	if proc.calls.Load() != 3 {
+		t.Fatalf("expected 3 runs, got %d", proc.calls.Load())
+	}
+}
+
+func TestHandleOrder_CoalescingDisconnect(t *testing.T) {
+	t.Parallel()
+
+	proc := &gatedProcessor{gate: make(chan struct{}), canceled: make(chan error, 1)}
+	h := New(proc, time.Second, WithCoalescing())
+	post := func(ctx context.Context) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.HandleOrder(w, httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"order_id":"o-1","amount":10}`)).WithContext(ctx))
+		return w
+	}
+
+	// The client that started the run leaves; the other still gets it.
+	first, leave := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	var firstResp, secondResp *httptest.ResponseRecorder
+	wg.Go(func() { firstResp = post(first) })
+	waitForWaiters(t, h, "o-1", 1)
+	wg.Go(func() { secondResp = post(context.Background()) })
+	waitForWaiters(t, h, "o-1", 2)
+	leave()
+	waitForWaiters(t, h, "o-1", 1)
+	close(proc.gate)
+	wg.Wait()
+	if !strings.Contains(firstResp.Body.String(), kindClientDisconnected) {
+		t.Fatalf("expected the first client disconnected, got %s", firstResp.Body)
+	}
+	if secondResp.Code != http.StatusOK || !strings.Contains(secondResp.Body.String(), `"shared":true`) {
+		t.Fatalf("expected the shared result, got %d %s", secondResp.Code, secondResp.Body)
+	}
+
+	// When the last client leaves, the run is canceled with its cause.
+	proc.gate = make(chan struct{})
+	only, leave := context.WithCancel(context.Background())
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post(only) }()
+	waitForWaiters(t, h, "o-1", 1)
+	leave()
+	if cause := <-proc.canceled; !errors.Is(cause, errClientDisconnected) {
+		t.Fatalf("expected the run canceled as disconnected, got %v", cause)
+	}
+	if w := <-done; !strings.Contains(w.Body.String(), `"cancellation_cause":{"reason":"client_disconnected"}`) {
+		t.Fatalf("expected a disconnected order, got %s", w.Body)
+	}
+}
@@ -0,0 +1,228 @@
+package httptransport
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// connRejectWait bounds how long a rejected connection is kept open to
+// deliver its 503 before it is closed.
+const connRejectWait = time.Second
+
+// maxRejecting caps the connections being rejected at once; beyond it,
+// overflow connections are closed without a response.
+const maxRejecting = 64
+
+// ConnManager tracks the server's connections through their states and
+// caps how many may be open at once.
+//
+// Listener enforces the cap: a connection accepted while max are open
+// gets a 503 for its first request and closed before the server sees it.
+// ConnState follows each served connection from new through active and
+// idle to closed, for the open, keep-alive and churn counters Stats
+// reports.
+type ConnManager struct {
+	max       int
+	now       func() time.Time
+	rejecting chan struct{}
+
+	mu       sync.Mutex
+	conns    map[net.Conn]*trackedConn
+	active   int
+	idle     int
+	peak     int
+	accepted int64
+	rejected int64
+	closed   int64
+	reused   int64
+	requests int64 // on closed connections
+	lifetime time.Duration
+}
+
+// trackedConn is what ConnManager knows about one open connection.
+type trackedConn struct {
+	opened   time.Time
+	state    http.ConnState
+	requests int64
+}
+
+// NewConnManager returns a ConnManager allowing at most max open
+// connections; a non-positive max only tracks them.
+func NewConnManager(max int) *ConnManager {
+	return &ConnManager{
+		max:       max,
+		now:       time.Now,
+		rejecting: make(chan struct{}, maxRejecting),
+		conns:     make(map[net.Conn]*trackedConn),
+	}
+}
+
+// Listener returns ln limited to the manager's maximum of open
+// connections. The server using it must also use ConnState, which
+// counts the connections it serves.
+func (m *ConnManager) Listener(ln net.Listener) net.Listener {
+	if ln == nil {
+		panic("httptransport.ConnManager.Listener: nil listener")
+	}
+	return &limitListener{Listener: ln, m: m}
+}
+
+// limitListener is the net.Listener returned by ConnManager.Listener.
+type limitListener struct {
+	net.Listener
+	m *ConnManager
+}
+
+// Accept returns the next connection within the limit, rejecting the
+// ones over it. The server calls ConnState(StateNew) for a connection
+// before accepting the next, so the open count is current.
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if !l.m.full() {
+			return c, nil
+		}
+		select {
+		case l.m.rejecting <- struct{}{}:
+			go func() {
+				defer func() { <-l.m.rejecting }()
+				rejectConn(c)
+			}()
+		default:
+			_ = c.Close()
+		}
+	}
+}
+
+// full reports whether a new connection would exceed the limit, and
+// counts it as rejected if so.
+func (m *ConnManager) full() bool {
+	if m.max <= 0 {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.conns) < m.max {
+		return false
+	}
+	m.rejected++
+	return true
+}
+
+// connOverflowBody is the body of the 503 sent to rejected connections.
+var connOverflowBody, _ = json.Marshal(model.OrderResponse{
+	Status: model.StatusError,
+	Error:  &model.ErrorPayload{Kind: "too_many_connections", Message: "server connection limit reached"},
+})
+
+// rejectConn answers c's first request with a 503 and closes it. The
+// response waits for the request's head, since clients treat a response
+// arriving before their request as unsolicited, and whatever the client
+// sends afterwards is discarded, so closing does not reset the
+// connection before the client has read the response.
+func rejectConn(c net.Conn) {
+	defer c.Close()
+	_ = c.SetDeadline(time.Now().Add(connRejectWait))
+	br := bufio.NewReader(c)
+	if _, err := http.ReadRequest(br); err != nil {
+		return
+	}
+	_, err := io.WriteString(c, "HTTP/1.1 503 Service Unavailable\r\n"+
+		"Content-Type: application/json\r\n"+
+		"Retry-After: 1\r\n"+
+		"Connection: close\r\n"+
+		"Content-Length: "+strconv.Itoa(len(connOverflowBody)+1)+"\r\n\r\n"+
+		string(connOverflowBody)+"\n")
+	if err != nil {
+		return
+	}
+	if cw, ok := c.(interface{ CloseWrite() error }); ok {
+		_ = cw.CloseWrite()
+	}
+	_, _ = io.Copy(io.Discard, br)
+}
+
+// ConnState is an http.Server ConnState hook tracking each
+// connection's state.
+func (m *ConnManager) ConnState(c net.Conn, state http.ConnState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tc := m.conns[c]
+	if tc == nil {
+		if state != http.StateNew {
+			return // not seen as new; nothing to update
+		}
+		m.conns[c] = &trackedConn{opened: m.now(), state: state}
+		m.accepted++
+		m.peak = max(m.peak, len(m.conns))
+		return
+	}
+
+	switch tc.state {
+	case http.StateActive:
+		m.active--
+	case http.StateIdle:
+		m.idle--
+	}
+	switch state {
+	case http.StateActive:
+		if tc.state == http.StateIdle {
+			m.reused++
+		}
+		tc.requests++
+		m.active++
+	case http.StateIdle:
+		m.idle++
+	case http.StateClosed, http.StateHijacked:
+		delete(m.conns, c)
+		m.closed++
+		m.requests += tc.requests
+		m.lifetime += m.now().Sub(tc.opened)
+		return
+	}
+	tc.state = state
+}
+
+// Stats reports the connection counters.
+func (m *ConnManager) Stats() model.ConnectionStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := model.ConnectionStats{
+		Max:      m.max,
+		Open:     len(m.conns),
+		Active:   m.active,
+		Idle:     m.idle,
+		Peak:     m.peak,
+		Accepted: m.accepted,
+		Rejected: m.rejected,
+		Closed:   m.closed,
+		Reused:   m.reused,
+	}
+	if m.closed > 0 {
+		s.AvgLifetimeMS = m.lifetime.Milliseconds() / m.closed
+		s.AvgRequests = float64(m.requests) / float64(m.closed)
+	}
+	return s
+}
+
+// HandleConnections serves the connection counters.
+//
+// The request must be a GET.
+func (m *ConnManager) HandleConnections(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, m.Stats())
+}
@@ -0,0 +1,144 @@
+package httptransport
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// newConnServer serves handler through m's listener and state hook.
+func newConnServer(t *testing.T, m *ConnManager) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	srv.Listener = m.Listener(srv.Listener)
+	srv.Config.ConnState = m.ConnState
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// get sends one keep-alive request on conn and reads the response.
+func get(t *testing.T, conn net.Conn, br *bufio.Reader) *http.Response {
+	t.Helper()
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\n\r\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp
+}
+
+// waitStats polls m until cond holds.
+func waitStats(t *testing.T, m *ConnManager, cond func(model.ConnectionStats) bool) model.ConnectionStats {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s := m.Stats()
+		if cond(s) {
+			return s
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for connection stats, last %+v", s)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConnManager_Limit(t *testing.T) {
+	t.Parallel()
+
+	m := NewConnManager(1)
+	srv := newConnServer(t, m)
+	addr := srv.Listener.Addr().String()
+
+	first, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	br := bufio.NewReader(first)
+	for range 2 {
+		if resp := get(t, first, br); resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+	}
+	waitStats(t, m, func(s model.ConnectionStats) bool { return s.Idle == 1 })
+
+	// A second connection is over the limit.
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("expected a 503 response, got %v", err)
+	}
+	var body model.OrderResponse
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" || body.Error == nil || body.Error.Kind != "too_many_connections" {
+		t.Fatalf("expected 503 too_many_connections, got %d %+v", resp.StatusCode, body)
+	}
+
+	first.Close()
+	waitStats(t, m, func(s model.ConnectionStats) bool { return s.Open == 0 })
+	second, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer second.Close()
+	if resp := get(t, second, bufio.NewReader(second)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 once a slot is free, got %d", resp.StatusCode)
+	}
+
+	s := waitStats(t, m, func(s model.ConnectionStats) bool { return s.Idle == 1 })
+	if s.Max != 1 || s.Open != 1 || s.Peak != 1 || s.Accepted != 2 || s.Rejected != 1 || s.Closed != 1 || s.Reused != 1 || s.AvgRequests != 2 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestConnManager_States(t *testing.T) {
+	t.Parallel()
+
+	m := NewConnManager(0)
+	clock := time.Unix(1000, 0)
+	m.now = func() time.Time { return clock }
+	a, b := &net.TCPConn{}, &net.UnixConn{}
+
+	m.ConnState(a, http.StateNew)
+	m.ConnState(b, http.StateNew)
+	m.ConnState(a, http.StateActive)
+	m.ConnState(b, http.StateActive)
+	m.ConnState(a, http.StateIdle)
+	if s := m.Stats(); s.Open != 2 || s.Active != 1 || s.Idle != 1 {
+		t.Fatalf("expected one active and one idle, got %+v", s)
+	}
+
+	m.ConnState(a, http.StateActive)
+	m.ConnState(b, http.StateHijacked)
+	clock = clock.Add(3 * time.Second)
+	m.ConnState(a, http.StateClosed)
+	m.ConnState(a, http.StateClosed) // repeated; ignored
+
+	want := model.ConnectionStats{Peak: 2, Accepted: 2, Closed: 2, Reused: 1, AvgLifetimeMS: 1500, AvgRequests: 1.5}
+	if s := m.Stats(); s != want {
+		t.Fatalf("expected %+v, got %+v", want, s)
+	}
+	if got := m.Listener(&net.TCPListener{}); got == nil {
+		t.Fatal("expected a listener")
+	}
+
+	w := httptest.NewRecorder()
+	m.HandleConnections(w, httptest.NewRequest(http.MethodPost, "/admin/connections", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,96 @@
+package httptransport
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+var dashboardPage = template.Must(template.ParseFS(dashboardFiles, "dashboard/index.html"))
+
+// runningSource reports the number of steps in flight.
+type runningSource interface {
+	Running() int64
+}
+
+// Dashboard serves a small web dashboard of live pipeline state. The
+// page renders a snapshot and its script refreshes it from
+// HandleData every few seconds.
+type Dashboard struct {
+	proj   *Projection
+	bp     *Backpressure
+	steps  runningSource
+	static http.Handler
+	now    func() time.Time
+}
+
+// NewDashboard returns a Dashboard reading order outcomes from proj,
+// courier pool load from bp and in-flight steps from steps.
+//
+// It panics if any source is nil.
+func NewDashboard(proj *Projection, bp *Backpressure, steps runningSource) *Dashboard {
+	if proj == nil || bp == nil || steps == nil {
+		panic("httptransport.NewDashboard: nil source")
+	}
+	static, err := fs.Sub(dashboardFiles, "dashboard/static")
+	if err != nil {
+		panic("httptransport.NewDashboard: " + err.Error())
+	}
+	return &Dashboard{
+		proj:   proj,
+		bp:     bp,
+		steps:  steps,
+		static: http.StripPrefix("/dashboard/static/", http.FileServerFS(static)),
+		now:    time.Now,
+	}
+}
+
+// Snapshot returns the current dashboard data.
+func (d *Dashboard) Snapshot() model.DashboardSnapshot {
+	return model.DashboardSnapshot{
+		Time:           d.now().UTC().Format(time.RFC3339),
+		InFlightOrders: d.proj.InFlight(),
+		InFlightSteps:  d.steps.Running(),
+		Pool:           d.bp.snapshot(),
+		Latency:        d.proj.Latencies(),
+		Statuses:       d.proj.StatusCounts(),
+		TopFailures:    d.proj.TopFailures(),
+		RecentFailures: d.proj.RecentFailures(),
+	}
+}
+
+// HandlePage serves the dashboard page.
+//
+// The request must be a GET.
+func (d *Dashboard) HandlePage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = dashboardPage.Execute(w, d.Snapshot())
+}
+
+// HandleData serves the dashboard snapshot as JSON.
+//
+// The request must be a GET.
+func (d *Dashboard) HandleData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, d.Snapshot())
+}
+
+// HandleStatic serves the dashboard's embedded script and stylesheet
+// under /dashboard/static/.
+func (d *Dashboard) HandleStatic(w http.ResponseWriter, r *http.Request) {
+	d.static.ServeHTTP(w, r)
+}
@@ -0,0 +1,72 @@
+// Refreshes the dashboard from /dashboard/data every few seconds.
+(function () {
+  "use strict";
+
+  const refreshMS = 3000;
+
+  function text(id, value) {
+    document.getElementById(id).textContent = value;
+  }
+
+  function rows(id, items, cells) {
+    const body = document.getElementById(id);
+    body.replaceChildren();
+    for (const item of items) {
+      const tr = document.createElement("tr");
+      for (const value of cells(item)) {
+        const td = document.createElement("td");
+        td.textContent = value;
+        tr.appendChild(td);
+      }
+      body.appendChild(tr);
+    }
+  }
+
+  function line(points, cls, peak, svg) {
+    if (points.length === 0) return;
+    const w = 600, h = 160;
+    const step = points.length > 1 ? w / (points.length - 1) : 0;
+    const d = points
+      .map((v, i) => (i ? "L" : "M") + (i * step).toFixed(1) + "," + (h - (v / peak) * (h - 10)).toFixed(1))
+      .join(" ");
+    const path = document.createElementNS("http://www.w3.org/2000/svg", "path");
+    path.setAttribute("d", d);
+    path.setAttribute("class", cls);
+    svg.appendChild(path);
+  }
+
+  function chart(latency) {
+    const svg = document.getElementById("latency");
+    svg.replaceChildren();
+    const peak = Math.max(1, ...latency.map((m) => m.max_ms));
+    line(latency.map((m) => m.avg_ms), "avg", peak, svg);
+    line(latency.map((m) => m.max_ms), "max", peak, svg);
+  }
+
+  function render(s) {
+    text("time", s.time);
+    text("in-flight-orders", s.in_flight_orders);
+    text("in-flight-steps", s.in_flight_steps);
+    text("pool-use", s.pool.in_use + "/" + s.pool.capacity);
+    text("pool-waiting", s.pool.waiting);
+    chart(s.latency);
+    rows("statuses", s.statuses, (m) => [
+      m.minute,
+      Object.entries(m.counts).map(([k, v]) => k + "=" + v).join(" "),
+    ]);
+    rows("top-failures", s.top_failures, (f) => [f.step, f.kind, f.count]);
+    rows("recent-failures", s.recent_failures, (f) => [f.time, f.order_id, f.kind]);
+  }
+
+  async function refresh() {
+    try {
+      const resp = await fetch("/dashboard/data");
+      if (resp.ok) render(await resp.json());
+    } catch (e) {
+      // Keep the last snapshot on screen until the server answers again.
+    }
+  }
+
+  refresh();
+  setInterval(refresh, refreshMS);
+})();
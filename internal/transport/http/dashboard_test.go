@@ -0,0 +1,93 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+type stubRunning int64
+
+func (s stubRunning) Running() int64 { return int64(s) }
+
+func newTestDashboard() *Dashboard {
+	proj := NewProjection()
+	proj.record("o-<1>", []model.StepResult{{Name: "courier", Status: model.StatusError, Detail: "no_courier"}},
+		model.StatusError, "no_courier", 0)
+	return NewDashboard(proj, NewBackpressure(stubCapacity{cap: 5, inUse: 2, waiting: 1}, 0.8, 1), stubRunning(3))
+}
+
+func TestDashboardPage(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDashboard()
+	w := httptest.NewRecorder()
+	d.HandlePage(w, httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("expected an HTML page, got %q", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`<span id="pool-use">2/5</span>`,
+		`<span id="in-flight-steps">3</span>`,
+		"<td>no_courier</td>",
+		"o-&lt;1&gt;", // order IDs are escaped
+		`src="/dashboard/static/dashboard.js"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected page to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestDashboardData(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDashboard()
+	w := httptest.NewRecorder()
+	d.HandleData(w, httptest.NewRequest(http.MethodGet, "/dashboard/data", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var out model.DashboardSnapshot
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.InFlightSteps != 3 || out.Pool.InUse != 2 || out.Pool.Waiting != 1 ||
+		len(out.RecentFailures) != 1 || len(out.TopFailures) != 1 || len(out.Statuses) != 1 {
+		t.Fatalf("unexpected snapshot %+v", out)
+	}
+}
+
+func TestDashboardStaticAndMethods(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDashboard()
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		path    string
+		code    int
+	}{
+		{name: "script", handler: d.HandleStatic, method: http.MethodGet, path: "/dashboard/static/dashboard.js", code: http.StatusOK},
+		{name: "stylesheet", handler: d.HandleStatic, method: http.MethodGet, path: "/dashboard/static/dashboard.css", code: http.StatusOK},
+		{name: "missing asset", handler: d.HandleStatic, method: http.MethodGet, path: "/dashboard/static/nope.js", code: http.StatusNotFound},
+		{name: "page wrong method", handler: d.HandlePage, method: http.MethodPost, path: "/dashboard", code: http.StatusMethodNotAllowed},
+		{name: "data wrong method", handler: d.HandleData, method: http.MethodPost, path: "/dashboard/data", code: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.handler(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code {
+			t.Fatalf("%s: expected %d, got %d", tt.name, tt.code, w.Code)
+		}
+	}
+}
@@ -3,22 +3,62 @@ package httptransport
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type kinder interface {
 	Kind() string
 }
 
-// kindToStatus maps error classification kinds
-// to HTTP status codes.
-var kindToStatus = map[string]int{
-	"payment_declined":   http.StatusBadRequest,
-	"vendor_unavailable": http.StatusServiceUnavailable,
-	"no_courier":         http.StatusServiceUnavailable,
-	"timeout":            http.StatusGatewayTimeout,
-	"canceled":           http.StatusRequestTimeout,
-	"internal":           http.StatusInternalServerError,
+// StatusMap maps error classification kinds to HTTP status codes.
+// Unmapped kinds get 500.
+type StatusMap map[string]int
+
+// defaultStatuses is the built-in StatusMap.
+var defaultStatuses = StatusMap{
+	"payment_declined":    http.StatusBadRequest,
+	"bad_address":         http.StatusUnprocessableEntity,
+	"invalid_order":       http.StatusUnprocessableEntity, // failed request validation
+	"wrong_region":        http.StatusMisdirectedRequest,  // pinned to a peer region
+	"vendor_unavailable":  http.StatusServiceUnavailable,
+	"tax_unavailable":     http.StatusServiceUnavailable,
+	"no_courier":          http.StatusServiceUnavailable,
+	"sidecar_unavailable": http.StatusServiceUnavailable,
+	"disabled":            http.StatusServiceUnavailable,
+	"dependency_down":     http.StatusServiceUnavailable, // refused by the degradation matrix
+	"timeout":             http.StatusGatewayTimeout,
+	"step_stalled":        http.StatusGatewayTimeout, // stopped by the step idle timeout
+	"canceled":            http.StatusRequestTimeout,
+	"client_disconnected": 499, // nginx's "client closed request"; never seen by the client
+	"internal":            http.StatusInternalServerError,
+}
+
+// kindPriority ranks error kinds by severity, highest first, for
+// picking the representative error when several steps fail. Server-side
+// faults outrank dependency outages, which outrank client-side outcomes.
+// Unlisted kinds rank lowest.
+var kindPriority = map[string]int{
+	"internal":            60,
+	"timeout":             50,
+	"step_stalled":        50,
+	"vendor_unavailable":  40,
+	"tax_unavailable":     40,
+	"no_courier":          40,
+	"sidecar_unavailable": 40,
+	"disabled":            40,
+	"client_disconnected": 35,
+	"canceled":            30,
+	"payment_declined":    20,
+	"bad_address":         20,
+}
+
+// stepNamer is implemented by errors that record the failing step.
+type stepNamer interface {
+	StepName() string
 }
 
 // errorKind returns the kind of an error.
@@ -40,13 +80,90 @@ func errorKind(err error) string {
 	}
 }
 
-func httpStatus(err error) int {
-	if err == nil {
-		return http.StatusOK
+// DefaultStatusMap returns a copy of the built-in mapping.
+func DefaultStatusMap() StatusMap {
+	return maps.Clone(defaultStatuses)
+}
+
+// ParseStatusMap returns the built-in mapping overridden by spec, a
+// comma-separated list of kind=status pairs, for example
+// "canceled=499,fraud_suspected=403". Kinds are lower-case letters,
+// digits and underscores; statuses must be 4xx or 5xx.
+func ParseStatusMap(spec string) (StatusMap, error) {
+	m := DefaultStatusMap()
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kind, code, ok := strings.Cut(entry, "=")
+		status, err := strconv.Atoi(code)
+		if !ok || !validKind(kind) || err != nil || status < 400 || status > 599 {
+			return nil, fmt.Errorf("httptransport: error status %q: want kind=4xx or 5xx status", entry)
+		}
+		m[kind] = status
+	}
+	return m, nil
+}
+
+func validKind(kind string) bool {
+	if kind == "" {
+		return false
 	}
-	kind := errorKind(err)
-	if s, ok := kindToStatus[kind]; ok {
+	for _, c := range kind {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// Status returns the status code for kind.
+func (m StatusMap) Status(kind string) int {
+	if s, ok := m[kind]; ok {
 		return s
 	}
 	return http.StatusInternalServerError
 }
+
+// httpStatus returns the status code for err, 200 if it is nil.
+func (m StatusMap) httpStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return m.Status(errorKind(err))
+}
+
+// splitErrors returns the individual errors joined in err, or err
+// itself if it is not a joined error.
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return j.Unwrap()
+	}
+	return []error{err}
+}
+
+// mostSevere returns the error in errs whose kind has the highest
+// priority. Ties go to the earliest error.
+func mostSevere(errs []error) error {
+	var top error
+	best := -1
+	for _, err := range errs {
+		if p := kindPriority[errorKind(err)]; p > best {
+			top, best = err, p
+		}
+	}
+	return top
+}
+
+// stepName returns the step recorded in err, if any.
+func stepName(err error) string {
+	var s stepNamer
+	if errors.As(err, &s) {
+		return s.StepName()
+	}
+	return ""
+}
@@ -3,11 +3,18 @@
 package httptransport
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
@@ -17,10 +24,118 @@ type orderProcessor interface {
 	Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error)
 }
 
+// resultReleaser is implemented by processors that pool result slices.
+// The handler releases the slice once the response has been written.
+type resultReleaser interface {
+	Release(results []model.StepResult)
+}
+
+// wrapped is embedded by the processors that Wrap methods return. It
+// holds the processor they delegate to, as next, and forwards Release to
+// it, so that pooled results reach the processor that pooled them
+// through any number of wrappers.
+type wrapped struct {
+	next orderProcessor
+}
+
+// Release releases results to next, if it pools them.
+func (w wrapped) Release(results []model.StepResult) {
+	if r, ok := w.next.(resultReleaser); ok {
+		r.Release(results)
+	}
+}
+
 // Handler handles HTTP requests to order orchestration.
 type Handler struct {
 	orderProcessor orderProcessor
-	requestTimeout time.Duration
+	requestTimeout atomic.Int64 // nanoseconds; see SetRequestTimeout
+	statuses       StatusMap
+	minimalTimeout bool
+	loyaltyPoints  func(orderID string) (int64, bool) // nil without a loyalty program
+	hooks          *Hooks                             // nil without request or response hooks
+	channels       *Channels                          // nil accepts any channel
+	region         *Region                            // nil processes orders of any region
+	sla            *SLA                               // nil accepts any service class
+	degradations   func() []model.Degradation         // nil without a dependency monitor
+	coalescer      *coalescer                         // nil processes every order on its own
+	splitOrders    bool                               // split multi-vendor orders; see WithSubOrders
+}
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithStatusMap makes the handler answer failed orders with the status
+// codes in m instead of the built-in mapping.
+func WithStatusMap(m StatusMap) Option {
+	return func(h *Handler) { h.statuses = m }
+}
+
+// Responses to orders that ran out of time, selected by
+// WithTimeoutResponse.
+const (
+	TimeoutPartial = "partial" // the step results collected so far, with 504
+	TimeoutMinimal = "minimal" // only the status and error, with 504
+)
+
+// ParseTimeoutResponse validates a timeout response name.
+func ParseTimeoutResponse(s string) (string, error) {
+	switch s {
+	case TimeoutPartial, TimeoutMinimal:
+		return s, nil
+	default:
+		return "", fmt.Errorf("httptransport: timeout response %q: want %s or %s", s, TimeoutPartial, TimeoutMinimal)
+	}
+}
+
+// WithTimeoutResponse selects the body of responses to orders that
+// failed with kind timeout. The default is TimeoutPartial.
+func WithTimeoutResponse(mode string) Option {
+	return func(h *Handler) { h.minimalTimeout = mode == TimeoutMinimal }
+}
+
+// WithLoyaltyPoints makes successful responses carry the points that
+// lookup reports for the order. Points accrued after the response is
+// built are not shown.
+func WithLoyaltyPoints(lookup func(orderID string) (int64, bool)) Option {
+	return func(h *Handler) { h.loyaltyPoints = lookup }
+}
+
+// WithChannels makes the handler reject orders naming a channel c does
+// not know, as invalid orders.
+func WithChannels(c *Channels) Option {
+	return func(h *Handler) { h.channels = c }
+}
+
+// WithSLA makes the handler reject orders naming a service class s does
+// not know, as invalid orders. Classes of s with no timeout then take
+// the handler's current request timeout as theirs.
+func WithSLA(s *SLA) Option {
+	return func(h *Handler) {
+		h.sla = s
+		s.requestTimeout = h.RequestTimeout
+	}
+}
+
+// WithRegion makes the handler refuse orders pinned to a peer of r
+// with 421 and a Location hint, and reject those naming an unknown
+// region as invalid orders.
+func WithRegion(r *Region) Option {
+	return func(h *Handler) { h.region = r }
+}
+
+// WithDegradations makes the handler consult active for the
+// degradations in effect when an order arrives: orders are refused with
+// kind dependency_down while any of them is model.DegradeReject, and
+// otherwise processed and answered listing them in Degradations.
+func WithDegradations(active func() []model.Degradation) Option {
+	return func(h *Handler) { h.degradations = active }
+}
+
+// WithCoalescing makes concurrent orders with the same order_id share
+// one run of the pipeline, all answered with its outcome and flagged
+// Shared.
+func WithCoalescing() Option {
+	return func(h *Handler) { h.coalescer = newCoalescer() }
 }
 
 // New returns a Handler configured with the given orderProcessor
@@ -28,98 +143,468 @@ type Handler struct {
 //
 // It panics if orderProcessor is nil. If requestTimeout is non-positive,
 // a default timeout is applied.
-func New(orderProcessor orderProcessor, requestTimeout time.Duration) *Handler {
+func New(orderProcessor orderProcessor, requestTimeout time.Duration, opts ...Option) *Handler {
 	if orderProcessor == nil {
 		panic("handler.New: nil order processor")
 	}
 	if requestTimeout <= 0 {
 		requestTimeout = 2 * time.Second
 	}
-	return &Handler{
+	h := &Handler{
 		orderProcessor: orderProcessor,
-		requestTimeout: requestTimeout,
+		statuses:       defaultStatuses,
+	}
+	h.requestTimeout.Store(int64(requestTimeout))
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// RequestTimeout returns the processing deadline of new requests.
+func (h *Handler) RequestTimeout() time.Duration {
+	return time.Duration(h.requestTimeout.Load())
+}
+
+// SetRequestTimeout changes the processing deadline of requests
+// received from now on; requests in flight keep theirs. Non-positive
+// values are ignored.
+func (h *Handler) SetRequestTimeout(d time.Duration) {
+	if d > 0 {
+		h.requestTimeout.Store(int64(d))
 	}
 }
 
 // HandleOrder processes an order request.
 //
-// The request must be a POST with a valid JSON body.
+// The request must be a POST with a valid JSON body of at most
+// maxBodyBytes; malformed JSON is answered with 400, a larger body with
+// 413 and a well-formed but invalid order with 422 listing each invalid
+// field. Request hooks run before validation and response
+// hooks once the order has been processed.
 // Processing is executed with a per-request timeout.
-// The response always contains a structured OrderResponse.
+// The response always contains a structured OrderResponse, or for
+// failures a model.Problem if the client accepts application/problem+json.
+// When the processor reports several failures, the most severe one
+// determines the status code and error kind, and all of them are
+// listed in Errors. Under WithSubOrders a multi-vendor order is answered
+// with its sub-orders' outcomes in SubOrders instead of Steps.
 func (h *Handler) HandleOrder(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
+	problem := wantsProblem(r)
+	reject := func(status int, kind, msg string) {
+		if problem {
+			writeProblem(w, newProblem(r, status, kind, msg))
+			return
+		}
+		writeJSON(w, status, model.OrderResponse{
+			Status: model.StatusError,
+			Error:  &model.ErrorPayload{Kind: kind, Message: msg},
+		})
+	}
+
 	var req model.OrderRequest
-	if err := decodeStrictJSON(r, &req); err != nil {
-		badRequest(w, "invalid JSON")
+	if err := decodeStrictJSON(w, r, &req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			reject(http.StatusRequestEntityTooLarge, "request_too_large", "body exceeds "+strconv.Itoa(maxBodyBytes)+" bytes")
+			return
+		}
+		reject(http.StatusBadRequest, "bad_request", "invalid JSON")
 		return
 	}
+	if h.hooks != nil {
+		h.hooks.rewrite(r.Context(), &req)
+	}
 
-	if req.Amount == 0 {
-		badRequest(w, "order_amount should be > 0")
+	fields := validateOrder(req)
+	if h.channels != nil && !h.channels.Known(req.Channel) {
+		fields = append(fields, model.FieldError{Field: "channel", Message: "must be one of " + h.channels.list()})
+	}
+	if h.sla != nil && !h.sla.Known(req.SLA) {
+		fields = append(fields, model.FieldError{Field: "sla", Message: "must be one of " + h.sla.list()})
+	}
+	if h.region != nil && !h.region.Known(req.Region) {
+		fields = append(fields, model.FieldError{Field: "region", Message: "must be one of " + h.region.known()})
+	}
+	if len(fields) > 0 {
+		status := h.statuses.Status(kindInvalidOrder)
+		if problem {
+			p := newProblem(r, status, kindInvalidOrder, "order has invalid fields")
+			p.OrderID, p.Fields = req.OrderID, fields
+			writeProblem(w, p)
+			return
+		}
+		writeJSON(w, status, model.OrderResponse{
+			Status:  model.StatusError,
+			OrderID: req.OrderID,
+			Error:   &model.ErrorPayload{Kind: kindInvalidOrder, Message: "invalid order", Fields: fields},
+		})
 		return
 	}
 
-	if req.OrderID == "" {
-		badRequest(w, "order_id is required")
-		return
+	if h.region != nil {
+		if peerURL := h.region.redirect(req.Region); peerURL != "" {
+			h.writeWrongRegion(w, r, problem, req, peerURL)
+			return
+		}
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), h.requestTimeout)
+	var degraded []model.Degradation
+	if h.degradations != nil {
+		degraded = h.degradations()
+		for _, d := range degraded {
+			if d.Action == model.DegradeReject {
+				h.writeDependencyDown(w, r, problem, req, d.Dependency, degraded)
+				return
+			}
+		}
+	}
+
+	// Processing is detached from r.Context() so that a client
+	// disconnect cancels it with its own cause, right away.
+	ctx, disconnect := context.WithCancelCause(context.WithoutCancel(r.Context()))
+	defer disconnect(nil)
+	stop := context.AfterFunc(r.Context(), func() { disconnect(errClientDisconnected) })
+	defer stop()
+	ctx, cancel := context.WithTimeoutCause(ctx, h.RequestTimeout(), errRequestTimeout)
 	defer cancel()
 
-	steps, err := h.orderProcessor.Process(ctx, req)
+	var steps []model.StepResult
+	var subs []model.SubOrder
+	var shared bool
+	var err error
+	var parts []model.OrderRequest
+	if h.splitOrders {
+		parts = splitOrder(req)
+	}
+	switch {
+	case parts != nil:
+		subs, err = h.processSplit(ctx, parts)
+	case h.coalescer != nil:
+		steps, shared, err = h.coalescer.process(r.Context(), ctx, h.orderProcessor, req)
+	default:
+		steps, err = h.orderProcessor.Process(ctx, req)
+	}
 
 	resp := model.OrderResponse{
-		Status:  "ok",
-		OrderID: req.OrderID,
-		Steps:   steps,
+		OrderID:      req.OrderID,
+		Steps:        steps,
+		Degradations: degraded,
+		Shared:       shared,
+		SubOrders:    subs,
 	}
-	if err != nil {
-		resp.Status = "error"
+	errs := splitErrors(err)
+	primary := mostSevere(errs)
+	resp.Status = orderStatus(steps, primary)
+	if subs != nil && primary == nil {
+		resp.Status = splitStatus(subs)
+	}
+	if primary != nil {
 		resp.Error = &model.ErrorPayload{
-			Kind:    errorKind(err),
+			Kind:    errorKind(primary),
 			Message: "order failed",
 		}
 	}
+	resp.CancellationCause = cancellationCause(r.Context(), ctx, steps, primary)
+	if resp.CancellationCause != nil {
+		noteCancellation(r.Context(), resp.CancellationCause)
+	}
+	if len(errs) > 1 {
+		resp.Errors = make([]model.ErrorPayload, len(errs))
+		for i, e := range errs {
+			resp.Errors[i] = model.ErrorPayload{
+				Kind:    errorKind(e),
+				Message: e.Error(),
+				Step:    stepName(e),
+			}
+		}
+	}
+
+	if primary == nil && subs == nil && h.loyaltyPoints != nil {
+		if points, ok := h.loyaltyPoints(req.OrderID); ok {
+			resp.LoyaltyPoints = points
+		}
+	}
 
-	writeJSON(w, httpStatus(err), resp)
+	if h.minimalTimeout && resp.Error != nil && resp.Error.Kind == "timeout" {
+		resp.Steps, resp.Errors, resp.CancellationCause = nil, nil, nil
+	}
+	if h.hooks != nil {
+		h.hooks.reshape(r.Context(), &resp)
+	}
+
+	status := h.statuses.httpStatus(primary)
+	if problem && primary != nil {
+		p := newProblem(r, status, resp.Error.Kind, "order failed")
+		if p.Step = failedStep(steps, primary); p.Step != "" {
+			p.Detail = "order failed at step " + p.Step
+		}
+		p.OrderID, p.Steps, p.Errors, p.CancellationCause = resp.OrderID, resp.Steps, resp.Errors, resp.CancellationCause
+		p.Degradations, p.Shared, p.SubOrders = resp.Degradations, resp.Shared, resp.SubOrders
+		writeProblem(w, p)
+	} else {
+		writeJSON(w, status, resp)
+	}
+
+	if r, ok := h.orderProcessor.(resultReleaser); ok && h.coalescer == nil && subs == nil {
+		r.Release(steps)
+	}
 }
 
-// decodeStrictJSON decodes the JSON request body into the given destination.
-// It disallows unknown fields and enforces a single JSON value in the body.
-func decodeStrictJSON(r *http.Request, dst any) error {
-	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
+// clientDisconnectedError is the cancellation cause of orders whose
+// client went away. Its kind classifies such orders as
+// StatusClientDisconnected.
+type clientDisconnectedError struct{}
+
+func (clientDisconnectedError) Error() string { return "client disconnected" }
+func (clientDisconnectedError) Kind() string  { return kindClientDisconnected }
 
-	if err := dec.Decode(dst); err != nil {
+const kindClientDisconnected = "client_disconnected"
+
+var errClientDisconnected error = clientDisconnectedError{}
+
+// errRequestTimeout is the cancellation cause of the handler's request
+// deadline.
+var errRequestTimeout = errors.New("server request deadline exceeded")
+
+// cancellationCause explains why steps were canceled, or returns nil if
+// none were. client is the request's own context and ctx the one the
+// order was processed under.
+func cancellationCause(client, ctx context.Context, steps []model.StepResult, primary error) *model.CancellationCause {
+	canceled := false
+	for _, s := range steps {
+		canceled = canceled || s.Status == model.StatusCanceled
+	}
+	switch {
+	case !canceled:
+		return nil
+	case client.Err() != nil, errorKind(primary) == kindClientDisconnected:
+		return &model.CancellationCause{Reason: model.CauseClientDisconnected}
+	case context.Cause(ctx) == errRequestTimeout, errorKind(primary) == "timeout":
+		return &model.CancellationCause{Reason: model.CauseServerTimeout}
+	default:
+		return &model.CancellationCause{
+			Reason: model.CauseStepFailed,
+			Step:   failedStep(steps, primary),
+			Kind:   errorKind(primary),
+		}
+	}
+}
+
+// orderStatus returns the order status for steps and the most severe
+// error primary: StatusClientDisconnected if the client went away,
+// StatusError on other failures, the pending status of the first
+// deferred step, or StatusOK.
+func orderStatus(steps []model.StepResult, primary error) model.Status {
+	if primary != nil {
+		if errorKind(primary) == kindClientDisconnected {
+			return model.StatusClientDisconnected
+		}
+		return model.StatusError
+	}
+	for _, s := range steps {
+		if s.Status == model.StatusDeferred {
+			return model.PendingStatus(s.Name)
+		}
+	}
+	return model.StatusOK
+}
+
+// writeWrongRegion answers an order pinned to another region, pointing
+// the client at that region's order endpoint.
+func (h *Handler) writeWrongRegion(w http.ResponseWriter, r *http.Request, problem bool, req model.OrderRequest, peerURL string) {
+	status := h.statuses.Status(kindWrongRegion)
+	msg := "order belongs to region " + req.Region
+	w.Header().Set("Location", peerURL+r.URL.Path)
+	if problem {
+		p := newProblem(r, status, kindWrongRegion, msg)
+		p.OrderID = req.OrderID
+		writeProblem(w, p)
+		return
+	}
+	writeJSON(w, status, model.OrderResponse{
+		Status:  model.StatusError,
+		OrderID: req.OrderID,
+		Error:   &model.ErrorPayload{Kind: kindWrongRegion, Message: msg},
+	})
+}
+
+// kindDependencyDown classifies orders refused because a dependency
+// they cannot do without is down.
+const kindDependencyDown = "dependency_down"
+
+// writeDependencyDown answers an order refused because dependency is
+// down, listing the degradations in effect.
+func (h *Handler) writeDependencyDown(w http.ResponseWriter, r *http.Request, problem bool, req model.OrderRequest, dependency string, degraded []model.Degradation) {
+	status := h.statuses.Status(kindDependencyDown)
+	msg := dependency + " is unavailable"
+	if problem {
+		p := newProblem(r, status, kindDependencyDown, msg)
+		p.OrderID, p.Degradations = req.OrderID, degraded
+		writeProblem(w, p)
+		return
+	}
+	writeJSON(w, status, model.OrderResponse{
+		Status:       model.StatusError,
+		OrderID:      req.OrderID,
+		Error:        &model.ErrorPayload{Kind: kindDependencyDown, Message: msg},
+		Degradations: degraded,
+	})
+}
+
+// maxBodyBytes caps the request bodies decodeStrictJSON reads.
+const maxBodyBytes = 1 << 20
+
+// decodeStrictJSON decodes the JSON request body into the given destination.
+// It disallows unknown fields, unless they are aliases of dst's fields
+// (see canonicalizeKeys) or a TolerantReader marked the request, and
+// enforces a single JSON value in the body. A body longer than
+// maxBodyBytes fails with *http.MaxBytesError, and w closes the
+// connection after the response.
+func decodeStrictJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
 		return err
 	}
+	var dec *json.Decoder
+	decode := func(strict bool) error {
+		dec = json.NewDecoder(bytes.NewReader(body))
+		if strict {
+			dec.DisallowUnknownFields()
+		}
+		return dec.Decode(dst)
+	}
+
+	var tolerant *TolerantReader
+	var ignored string
+	if err := decode(true); err != nil {
+		field, ok := unknownField(err)
+		if ok {
+			// The unknown field may be an alias; decode again with
+			// canonical keys.
+			if canonical, changed := canonicalizeKeys(body, dst); changed {
+				body = canonical
+				reflect.ValueOf(dst).Elem().SetZero()
+				if err = decode(true); err != nil {
+					field, ok = unknownField(err)
+				}
+			}
+		}
+		if err != nil {
+			if tolerant = tolerantReaderFrom(r.Context()); !ok || tolerant == nil {
+				return err
+			}
+			// Decode again, this time ignoring unknown fields.
+			if err := decode(false); err != nil {
+				return err
+			}
+			ignored = field
+		}
+	}
 
 	// Enforce a single JSON value in the body.
 	if err := dec.Decode(&struct{}{}); err != io.EOF {
 		return errors.New("multiple JSON values")
 	}
 
+	if ignored != "" {
+		tolerant.ignored(r.Context(), r.URL.Path, ignored)
+	}
 	return nil
 }
 
+// kindInvalidOrder classifies well-formed orders whose fields fail
+// validation.
+const kindInvalidOrder = "invalid_order"
+
+// validateOrder returns every semantic problem with req, in field
+// order, or nil. Malformed JSON is rejected before this, with 400.
+func validateOrder(req model.OrderRequest) []model.FieldError {
+	var fields []model.FieldError
+	if req.OrderID == "" {
+		fields = append(fields, model.FieldError{Field: "order_id", Message: "is required"})
+	}
+	if req.Amount == 0 {
+		fields = append(fields, model.FieldError{Field: "amount", Message: "must be > 0"})
+	}
+	if req.Address != "" && strings.TrimSpace(req.Address) == "" {
+		fields = append(fields, model.FieldError{Field: "address", Message: "must not be blank"})
+	}
+	var total uint64
+	for i, it := range req.Items {
+		field := "items[" + strconv.Itoa(i) + "]"
+		if it.Vendor == "" {
+			fields = append(fields, model.FieldError{Field: field + ".vendor", Message: "is required"})
+		}
+		if it.Amount == 0 {
+			fields = append(fields, model.FieldError{Field: field + ".amount", Message: "must be > 0"})
+		}
+		total += it.Amount
+	}
+	if len(req.Items) > 0 && req.Amount != 0 && total != req.Amount {
+		fields = append(fields, model.FieldError{Field: "amount", Message: "must equal the total of items"})
+	}
+	return fields
+}
+
 // badRequest writes a JSON response with a 400 status code and a bad_request error.
 // It is used to respond to requests with invalid JSON.
 func badRequest(w http.ResponseWriter, msg string) {
 	writeJSON(w, http.StatusBadRequest, model.OrderResponse{
-		Status: "error",
+		Status: model.StatusError,
 		Error:  &model.ErrorPayload{Kind: "bad_request", Message: msg},
 	})
 }
 
+// jsonAppender is satisfied by payloads with a hand-written encoder.
+type jsonAppender interface {
+	AppendJSON(b []byte) []byte
+}
+
 // writeJSON writes v as a JSON response with the given status code.
 // The Content-Type is set to application/json.
+//
+// Payloads implementing jsonAppender (the /order hot path) skip
+// reflection; everything else falls back to encoding/json. Both paths
+// produce the same bytes, including the trailing newline.
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
+	if a, ok := v.(jsonAppender); ok {
+		bp := getBuffer()
+		*bp = append(a.AppendJSON(*bp), '\n')
+		_, _ = w.Write(*bp)
+		putBuffer(bp)
+		return
+	}
 	_ = json.NewEncoder(w).Encode(v)
 }
+
+// maxPooledBuffer caps the size of buffers returned to bufferPool so an
+// unusually large response does not stay pinned in memory.
+const maxPooledBuffer = 64 << 10
+
+// bufferPool holds response encoding buffers (*[]byte).
+var bufferPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 512)
+		return &b
+	},
+}
+
+func getBuffer() *[]byte { return bufferPool.Get().(*[]byte) }
+
+// putBuffer resets bp and returns it to the pool. The caller must not use
+// the buffer afterwards; http.ResponseWriter.Write does not retain it.
+func putBuffer(bp *[]byte) {
+	if cap(*bp) > maxPooledBuffer {
+		return
+	}
+	*bp = (*bp)[:0]
+	bufferPool.Put(bp)
+}
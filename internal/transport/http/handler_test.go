@@ -6,8 +6,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -48,6 +52,7 @@ func TestHandleOrderValidation(t *testing.T) {
 		body       []byte
 		wantStatus int
 		wantKind   string
+		wantFields []model.FieldError
 	}{
 		{
 			name:       "method_not_allowed",
@@ -66,8 +71,41 @@ func TestHandleOrderValidation(t *testing.T) {
 			name:       "missing_order_id",
 			method:     http.MethodPost,
 			body:       []byte(`{"amount":10}`),
-			wantStatus: http.StatusBadRequest,
-			wantKind:   "bad_request",
+			wantStatus: http.StatusUnprocessableEntity,
+			wantKind:   "invalid_order",
+			wantFields: []model.FieldError{{Field: "order_id", Message: "is required"}},
+		},
+		{
+			name:       "zero_amount",
+			method:     http.MethodPost,
+			body:       []byte(`{"order_id":"o-1","amount":0}`),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantKind:   "invalid_order",
+			wantFields: []model.FieldError{{Field: "amount", Message: "must be > 0"}},
+		},
+		{
+			name:       "every_invalid_field",
+			method:     http.MethodPost,
+			body:       []byte(`{"address":"   "}`),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantKind:   "invalid_order",
+			wantFields: []model.FieldError{
+				{Field: "order_id", Message: "is required"},
+				{Field: "amount", Message: "must be > 0"},
+				{Field: "address", Message: "must not be blank"},
+			},
+		},
+		{
+			name:       "invalid_items",
+			method:     http.MethodPost,
+			body:       []byte(`{"order_id":"o-1","amount":10,"items":[{"vendor":"pizza","amount":5},{"amount":0}]}`),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantKind:   "invalid_order",
+			wantFields: []model.FieldError{
+				{Field: "items[1].vendor", Message: "is required"},
+				{Field: "items[1].amount", Message: "must be > 0"},
+				{Field: "amount", Message: "must equal the total of items"},
+			},
 		},
 		{
 			name:       "unknown_fields",
@@ -76,6 +114,13 @@ func TestHandleOrderValidation(t *testing.T) {
 			wantStatus: http.StatusBadRequest,
 			wantKind:   "bad_request",
 		},
+		{
+			name:       "body_too_large",
+			method:     http.MethodPost,
+			body:       []byte(`{"order_id":"o-1","address":"` + strings.Repeat("x", maxBodyBytes) + `"}`),
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantKind:   "request_too_large",
+		},
 		{
 			name:       "multiple_json_values",
 			method:     http.MethodPost,
@@ -111,6 +156,9 @@ func TestHandleOrderValidation(t *testing.T) {
 			if out.Error == nil || out.Error.Kind != tt.wantKind {
 				t.Fatalf("expected error.kind=%s, got %+v", tt.wantKind, out.Error)
 			}
+			if !reflect.DeepEqual(out.Error.Fields, tt.wantFields) {
+				t.Fatalf("expected fields %v, got %v", tt.wantFields, out.Error.Fields)
+			}
 		})
 	}
 }
@@ -150,6 +198,48 @@ func TestHandleOrder_Success(t *testing.T) {
 	}
 }
 
+func TestHandleOrder_DeferredStep(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		deferred string
+		want     model.Status
+	}{
+		{name: "courier", deferred: "courier", want: model.StatusAcceptedPendingCourier},
+		{name: "other step", deferred: "vendor", want: model.StatusDegraded},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			stub := &stubProcessor{
+				steps: []model.StepResult{
+					{Name: "payment", Status: model.StatusOK},
+					{Name: tt.deferred, Status: model.StatusDeferred, Detail: "deferred"},
+				},
+			}
+			h := New(stub, 2*time.Second)
+
+			body, _ := json.Marshal(model.OrderRequest{OrderID: "o-1", Amount: 100})
+			w := httptest.NewRecorder()
+			h.HandleOrder(w, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d", w.Code)
+			}
+			var out model.OrderResponse
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if out.Status != tt.want || out.Error != nil {
+				t.Fatalf("expected status=%q without error, got %+v", tt.want, out)
+			}
+		})
+	}
+}
+
 func TestHandleOrder_AppError(t *testing.T) {
 	t.Parallel()
 
@@ -250,13 +340,474 @@ func TestHTTPStatus(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			if got := httpStatus(tt.err); got != tt.want {
+			if got := DefaultStatusMap().httpStatus(tt.err); got != tt.want {
 				t.Fatalf("expected %d, got %d", tt.want, got)
 			}
 		})
 	}
 }
 
+// A client disconnect aborts the pipeline at once and records the order
+// as client_disconnected rather than canceled, as one failure.
+func TestHandleOrder_ClientDisconnected(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	svc := order.New([]order.Step{{Name: "courier", Run: func(ctx context.Context, _ model.OrderRequest) error {
+		close(started)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	}}})
+	h := New(svc, 10*time.Second)
+
+	ctx, disconnect := context.WithCancel(context.Background())
+	body, _ := json.Marshal(model.OrderRequest{OrderID: "o-1", Amount: 100})
+	req := httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)).WithContext(ctx)
+	w := httptest.NewRecorder()
+	go func() {
+		<-started
+		disconnect()
+	}()
+
+	start := time.Now()
+	h.HandleOrder(w, req)
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("expected prompt abort, took %v", d)
+	}
+
+	if w.Code != 499 {
+		t.Fatalf("expected 499, got %d", w.Code)
+	}
+	var out model.OrderResponse
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Status != model.StatusClientDisconnected || out.Error == nil || out.Error.Kind != "client_disconnected" {
+		t.Fatalf("expected client_disconnected, got %+v", out)
+	}
+	if len(out.Errors) != 0 {
+		t.Fatalf("expected a single error, got %+v", out.Errors)
+	}
+	if out.CancellationCause == nil || out.CancellationCause.Reason != model.CauseClientDisconnected {
+		t.Fatalf("expected client_disconnected cause, got %+v", out.CancellationCause)
+	}
+	if out.Steps[0].Status != model.StatusCanceled {
+		t.Fatalf("expected courier canceled, got %+v", out.Steps[0])
+	}
+}
+
+func TestHandleOrder_TimeoutResponse(t *testing.T) {
+	t.Parallel()
+
+	steps := []model.StepResult{
+		{Name: "payment", Status: model.StatusOK},
+		{Name: "courier", Status: model.StatusCanceled, Detail: "operation not completed"},
+	}
+	tests := []struct {
+		mode      string
+		err       error
+		wantSteps int
+	}{
+		{mode: TimeoutPartial, err: context.DeadlineExceeded, wantSteps: 2},
+		{mode: TimeoutMinimal, err: context.DeadlineExceeded, wantSteps: 0},
+		{mode: TimeoutMinimal, err: vendor.ErrUnavailable, wantSteps: 2}, // only timeouts are trimmed
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.mode+"/"+errorKind(tt.err), func(t *testing.T) {
+			t.Parallel()
+			h := New(&stubProcessor{steps: steps, err: tt.err}, 2*time.Second, WithTimeoutResponse(tt.mode))
+
+			body, _ := json.Marshal(model.OrderRequest{OrderID: "o-1", Amount: 100})
+			w := httptest.NewRecorder()
+			h.HandleOrder(w, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+
+			var out model.OrderResponse
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(out.Steps) != tt.wantSteps || out.Error == nil || out.OrderID != "o-1" {
+				t.Fatalf("expected %d steps and an error, got %+v", tt.wantSteps, out)
+			}
+		})
+	}
+
+	if _, err := ParseTimeoutResponse("full"); err == nil {
+		t.Fatal("expected error for an unknown timeout response")
+	}
+}
+
+func TestHandleOrder_LoyaltyPoints(t *testing.T) {
+	t.Parallel()
+
+	accrued := map[string]int64{"o-1": 12}
+	lookup := func(orderID string) (int64, bool) {
+		p, ok := accrued[orderID]
+		return p, ok
+	}
+	tests := []struct {
+		name    string
+		orderID string
+		err     error
+		want    int64
+	}{
+		{name: "accrued", orderID: "o-1", want: 12},
+		{name: "not_yet_accrued", orderID: "o-2"},
+		{name: "failed_order", orderID: "o-1", err: vendor.ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			h := New(&stubProcessor{err: tt.err}, 2*time.Second, WithLoyaltyPoints(lookup))
+
+			body, _ := json.Marshal(model.OrderRequest{OrderID: tt.orderID, Amount: 100})
+			w := httptest.NewRecorder()
+			h.HandleOrder(w, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+
+			var out model.OrderResponse
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if out.LoyaltyPoints != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, out.LoyaltyPoints)
+			}
+		})
+	}
+}
+
+func TestHandleOrder_Degradations(t *testing.T) {
+	t.Parallel()
+
+	vendorDeferred := model.Degradation{Dependency: "vendor", Action: model.DegradeDefer}
+	paymentRejected := model.Degradation{Dependency: "payment", Action: model.DegradeReject}
+	tests := []struct {
+		name          string
+		active        []model.Degradation
+		problem       bool
+		wantStatus    int
+		wantProcessed bool
+		wantKind      string
+	}{
+		{name: "none", wantStatus: http.StatusOK, wantProcessed: true},
+		{name: "deferred", active: []model.Degradation{vendorDeferred}, wantStatus: http.StatusOK, wantProcessed: true},
+		{name: "rejected", active: []model.Degradation{paymentRejected, vendorDeferred}, wantStatus: http.StatusServiceUnavailable, wantKind: "dependency_down"},
+		{name: "rejected_problem", active: []model.Degradation{paymentRejected}, problem: true, wantStatus: http.StatusServiceUnavailable, wantKind: "dependency_down"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			proc := &capturingProcessor{stubProcessor: stubProcessor{steps: []model.StepResult{{Name: "vendor", Status: model.StatusDeferred}}}}
+			h := New(proc, 2*time.Second, WithDegradations(func() []model.Degradation { return tt.active }))
+
+			req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"order_id":"o-1","amount":100}`))
+			if tt.problem {
+				req.Header.Set("Accept", "application/problem+json")
+			}
+			w := httptest.NewRecorder()
+			h.HandleOrder(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body)
+			}
+			if processed := proc.got.OrderID != ""; processed != tt.wantProcessed {
+				t.Fatalf("expected processed=%v, got %v", tt.wantProcessed, processed)
+			}
+
+			var out struct {
+				Kind         string              `json:"kind"` // problem
+				Error        *model.ErrorPayload `json:"error"`
+				Degradations []model.Degradation `json:"degradations"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			kind := out.Kind
+			if out.Error != nil {
+				kind = out.Error.Kind
+			}
+			if kind != tt.wantKind {
+				t.Fatalf("expected kind %q, got %q", tt.wantKind, kind)
+			}
+			if !slices.Equal(out.Degradations, tt.active) {
+				t.Fatalf("expected degradations %v, got %v", tt.active, out.Degradations)
+			}
+		})
+	}
+}
+
+func TestParseStatusMap(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseStatusMap("canceled=499, fraud_suspected=403")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Status("canceled") != 499 || got.Status("fraud_suspected") != 403 {
+		t.Fatalf("unexpected overrides: %v", got)
+	}
+	if got.Status("timeout") != http.StatusGatewayTimeout || got.Status("unmapped") != http.StatusInternalServerError {
+		t.Fatalf("expected defaults to remain, got %v", got)
+	}
+	if DefaultStatusMap().Status("canceled") != http.StatusRequestTimeout {
+		t.Fatal("expected parsing not to change the built-in mapping")
+	}
+
+	for _, spec := range []string{"canceled", "=499", "canceled=x", "canceled=200", "canceled=600", "Canceled=499", "no-courier=503"} {
+		if _, err := ParseStatusMap(spec); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestHandleOrder_StatusMap(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubProcessor{err: context.Canceled}
+	h := New(stub, 2*time.Second, WithStatusMap(StatusMap{"canceled": 499}))
+
+	body, _ := json.Marshal(model.OrderRequest{OrderID: "o-1", Amount: 100})
+	w := httptest.NewRecorder()
+	h.HandleOrder(w, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+
+	if w.Code != 499 {
+		t.Fatalf("expected 499, got %d", w.Code)
+	}
+}
+
+func TestCancellationCause(t *testing.T) {
+	t.Parallel()
+
+	canceled := []model.StepResult{
+		{Name: "payment", Status: model.StatusError, Detail: "payment_declined"},
+		{Name: "courier", Status: model.StatusCanceled},
+	}
+	gone, cancel := context.WithCancel(context.Background())
+	cancel()
+	timedOut, cancelTimeout := context.WithTimeoutCause(context.Background(), 0, errRequestTimeout)
+	defer cancelTimeout()
+	live := context.Background()
+
+	tests := []struct {
+		name    string
+		client  context.Context
+		ctx     context.Context
+		steps   []model.StepResult
+		primary error
+		want    *model.CancellationCause
+	}{
+		{name: "none canceled", client: live, ctx: live, steps: canceled[:1], primary: payment.ErrDeclined, want: nil},
+		{name: "client", client: gone, ctx: gone, steps: canceled, primary: context.Canceled,
+			want: &model.CancellationCause{Reason: model.CauseClientDisconnected}},
+		{name: "request deadline", client: live, ctx: timedOut, steps: canceled, primary: context.DeadlineExceeded,
+			want: &model.CancellationCause{Reason: model.CauseServerTimeout}},
+		{name: "sla deadline", client: live, ctx: live, steps: canceled, primary: context.DeadlineExceeded,
+			want: &model.CancellationCause{Reason: model.CauseServerTimeout}},
+		{name: "step", client: live, ctx: live, steps: canceled,
+			primary: &order.StepError{Step: "payment", Err: payment.ErrDeclined},
+			want:    &model.CancellationCause{Reason: model.CauseStepFailed, Step: "payment", Kind: "payment_declined"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := cancellationCause(tt.client, tt.ctx, tt.steps, tt.primary)
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMostSevere(t *testing.T) {
+	t.Parallel()
+
+	internal := errors.New("boom")
+	tests := []struct {
+		name string
+		errs []error
+		want error
+	}{
+		{name: "empty", errs: nil, want: nil},
+		{name: "single", errs: []error{payment.ErrDeclined}, want: payment.ErrDeclined},
+		{name: "outage_over_declined", errs: []error{payment.ErrDeclined, vendor.ErrUnavailable}, want: vendor.ErrUnavailable},
+		{name: "tie_keeps_first", errs: []error{courier.ErrNoCourierAvailable, vendor.ErrUnavailable}, want: courier.ErrNoCourierAvailable},
+		{name: "timeout_over_outage", errs: []error{vendor.ErrUnavailable, context.DeadlineExceeded}, want: context.DeadlineExceeded},
+		{name: "internal_highest", errs: []error{context.DeadlineExceeded, internal, payment.ErrDeclined}, want: internal},
+		{name: "unknown_kind_lowest", errs: []error{testAppErr{kind: "other"}, payment.ErrDeclined}, want: payment.ErrDeclined},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := mostSevere(tt.errs); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// With several step failures the response reports the most severe one
+// as the primary error and lists all of them.
+func TestHandleOrder_MultipleErrors(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubProcessor{
+		steps: []model.StepResult{
+			{Name: "payment", Status: "error", Detail: "payment_declined"},
+			{Name: "vendor", Status: "error", Detail: "vendor_unavailable"},
+			{Name: "courier", Status: "ok"},
+		},
+		err: errors.Join(
+			&order.StepError{Step: "payment", Err: payment.ErrDeclined},
+			&order.StepError{Step: "vendor", Err: vendor.ErrUnavailable},
+		),
+	}
+	h := New(stub, 2*time.Second)
+
+	body, _ := json.Marshal(model.OrderRequest{OrderID: "o-1", Amount: 100})
+	req := httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.HandleOrder(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+
+	var out model.OrderResponse
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Error == nil || out.Error.Kind != "vendor_unavailable" {
+		t.Fatalf("expected error.kind=vendor_unavailable, got %+v", out.Error)
+	}
+	want := []model.ErrorPayload{
+		{Kind: "payment_declined", Message: "payment: " + payment.ErrDeclined.Error(), Step: "payment"},
+		{Kind: "vendor_unavailable", Message: "vendor: " + vendor.ErrUnavailable.Error(), Step: "vendor"},
+	}
+	if len(out.Errors) != len(want) {
+		t.Fatalf("expected %d errors, got %+v", len(want), out.Errors)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(out.Errors[i], want[i]) {
+			t.Fatalf("errors[%d]: expected %+v, got %+v", i, want[i], out.Errors[i])
+		}
+	}
+}
+
+// releasingProcessor records slices handed back through Release.
+type releasingProcessor struct {
+	stubProcessor
+	mu       sync.Mutex
+	released [][]model.StepResult
+}
+
+func (p *releasingProcessor) Release(results []model.StepResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.released = append(p.released, results)
+}
+
+// Results are released only after the response body has been written,
+// including through the slow-log decorator.
+func TestHandleOrder_ReleasesResults(t *testing.T) {
+	t.Parallel()
+
+	proc := &releasingProcessor{stubProcessor: stubProcessor{
+		steps: []model.StepResult{{Name: "payment", Status: "ok", DurationMS: 1}},
+	}}
+
+	for _, p := range []orderProcessor{proc, NewSlowLog(time.Second, 1, nil).Wrap(proc)} {
+		h := New(p, 2*time.Second)
+
+		body, _ := json.Marshal(model.OrderRequest{OrderID: "o-1", Amount: 100})
+		w := httptest.NewRecorder()
+		h.HandleOrder(w, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+
+		var out model.OrderResponse
+		if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(out.Steps) != 1 || out.Steps[0].Name != "payment" {
+			t.Fatalf("unexpected steps: %+v", out.Steps)
+		}
+	}
+
+	if len(proc.released) != 2 {
+		t.Fatalf("expected 2 releases, got %d", len(proc.released))
+	}
+}
+
+// Every decorator forwards Release to the processor it wraps, however
+// deeply the processor is wrapped, as the composition root stacks them.
+func TestWrapped_Release(t *testing.T) {
+	t.Parallel()
+
+	proc := &releasingProcessor{}
+	sla := NewSLA(SLAClass{Name: "standard", Timeout: time.Second})
+	p := NewProjection().Wrap(NewAnomalyDetector(slog.New(slog.DiscardHandler), 5).Wrap(
+		NewSlowLog(time.Second, 1, nil).Wrap(sla.Wrap(NewCanceller(time.Second, 1).Wrap(proc)))))
+
+	results := []model.StepResult{{Name: "payment", Status: "ok"}}
+	p.(resultReleaser).Release(results)
+	if len(proc.released) != 1 || &proc.released[0][0] != &results[0] {
+		t.Fatalf("expected the slice released once to the wrapped processor, got %v", proc.released)
+	}
+
+	// A processor that does not pool its results is left alone.
+	sla.Wrap(&stubProcessor{}).(resultReleaser).Release(results)
+}
+
+// The hand-written and reflective paths must write identical bodies.
+func TestWriteJSONMatchesEncoder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{name: "appender", v: model.OrderResponse{
+			Status:  "error",
+			OrderID: "o-<1>",
+			Steps:   []model.StepResult{{Name: "payment", Status: "error", DurationMS: 3, Detail: "payment_declined"}},
+			Error:   &model.ErrorPayload{Kind: "payment_declined", Message: "order failed"},
+		}},
+		{name: "fallback", v: model.CapacityResponse{Capacity: 5, InUse: 2, Headroom: 3, Load: 0.4}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var want bytes.Buffer
+			if err := json.NewEncoder(&want).Encode(tt.v); err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			writeJSON(w, http.StatusOK, tt.v)
+
+			if got := w.Body.String(); got != want.String() {
+				t.Fatalf("expected %q, got %q", want.String(), got)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Fatalf("expected application/json, got %q", ct)
+			}
+		})
+	}
+}
+
 // Ensures the handler never panics on arbitrary input.
 func FuzzHandleOrder(f *testing.F) {
 	f.Add([]byte(`{"order_id":"o-1","amount":100}`))
@@ -282,7 +833,7 @@ func FuzzHandleOrder(f *testing.F) {
 		h.HandleOrder(w, req)
 
 		code := w.Code
-		if code != http.StatusOK && code != http.StatusBadRequest && code != http.StatusMethodNotAllowed {
+		if code != http.StatusOK && code != http.StatusBadRequest && code != http.StatusUnprocessableEntity && code != http.StatusMethodNotAllowed {
 			t.Errorf("unexpected status %d for body %q", code, body)
 		}
 	})
@@ -465,3 +1016,43 @@ func TestHandler_Stress(t *testing.T) {
 
 	waitRunningZero(t, tr)
 }
+
+// rewindBody is a request body that can be reset without allocating.
+type rewindBody struct{ *bytes.Reader }
+
+func (rewindBody) Close() error { return nil }
+
+// discardWriter is a ResponseWriter that drops the body and reuses
+// its header map.
+type discardWriter struct{ h http.Header }
+
+func (d *discardWriter) Header() http.Header         { return d.h }
+func (d *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (d *discardWriter) WriteHeader(int)             {}
+
+// Allocation profile of HandleOrder under parallel load with zero-delay
+// steps, so encoding and bookkeeping dominate.
+func BenchmarkHandleOrderParallel(b *testing.B) {
+	noop := func(context.Context, model.OrderRequest) error { return nil }
+	svc := order.New([]order.Step{
+		{Name: "payment", Run: noop},
+		{Name: "vendor", Run: noop},
+		{Name: "courier", Run: noop},
+	})
+	h := New(svc, 2*time.Second)
+	payload := []byte(`{"order_id":"o-1","amount":1200}`)
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		body := rewindBody{bytes.NewReader(payload)}
+		req := httptest.NewRequest(http.MethodPost, "/order", nil)
+		req.Body = body
+		w := &discardWriter{h: http.Header{}}
+
+		for pb.Next() {
+			body.Reset(payload)
+			clear(w.h)
+			h.HandleOrder(w, req)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package httptransport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// RequestHook rewrites an incoming order before it is validated, for
+// example to fill in defaults or normalize fields.
+type RequestHook func(ctx context.Context, req *model.OrderRequest)
+
+// ResponseHook reshapes the response to a processed order before it is
+// written.
+type ResponseHook func(ctx context.Context, resp *model.OrderResponse)
+
+// AllTenants registers a hook for every tenant, including requests
+// without one.
+const AllTenants = "*"
+
+// Hooks holds the request and response hooks of each tenant. Hooks are
+// registered at startup, before serving; running them is safe for
+// concurrent use.
+type Hooks struct {
+	tenant func(context.Context) string
+	pre    map[string][]RequestHook
+	post   map[string][]ResponseHook
+}
+
+// NewHooks returns an empty Hooks that selects a request's hooks by the
+// tenant func returns for the request context.
+//
+// It panics if tenant is nil.
+func NewHooks(tenant func(context.Context) string) *Hooks {
+	if tenant == nil {
+		panic("httptransport.NewHooks: nil tenant func")
+	}
+	return &Hooks{tenant: tenant, pre: map[string][]RequestHook{}, post: map[string][]ResponseHook{}}
+}
+
+// OnRequest registers hook for tenant's requests. Hooks for AllTenants
+// run first, then the tenant's own, each in registration order.
+func (h *Hooks) OnRequest(tenant string, hook RequestHook) {
+	h.pre[tenant] = append(h.pre[tenant], hook)
+}
+
+// OnResponse registers hook for responses to tenant's orders, ordered
+// like OnRequest.
+func (h *Hooks) OnResponse(tenant string, hook ResponseHook) {
+	h.post[tenant] = append(h.post[tenant], hook)
+}
+
+func (h *Hooks) rewrite(ctx context.Context, req *model.OrderRequest) {
+	for _, hook := range h.pre[AllTenants] {
+		hook(ctx, req)
+	}
+	if t := h.tenant(ctx); t != "" && t != AllTenants {
+		for _, hook := range h.pre[t] {
+			hook(ctx, req)
+		}
+	}
+}
+
+func (h *Hooks) reshape(ctx context.Context, resp *model.OrderResponse) {
+	for _, hook := range h.post[AllTenants] {
+		hook(ctx, resp)
+	}
+	if t := h.tenant(ctx); t != "" && t != AllTenants {
+		for _, hook := range h.post[t] {
+			hook(ctx, resp)
+		}
+	}
+}
+
+// WithHooks makes the handler run h's request hooks on each decoded
+// order and its response hooks on each response to a processed order.
+func WithHooks(h *Hooks) Option {
+	return func(hd *Handler) { hd.hooks = h }
+}
+
+// ParseHooks registers the built-in hooks named by spec, a
+// comma-separated list of tenant:hook[=arg] entries where tenant may be
+// AllTenants, for example "*:normalize,acme:default-sla=express". The
+// built-in hooks are:
+//
+//	normalize          trim the order ID, lower-case zone and SLA class,
+//	                   collapse whitespace in the address
+//	default-zone=Z     set the zone of orders without one (and without an address)
+//	default-sla=S      set the SLA class of orders without one
+//	omit-steps         drop the step list from successful responses
+func ParseHooks(spec string, h *Hooks) error {
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tenant, hook, ok := strings.Cut(entry, ":")
+		name, arg, hasArg := strings.Cut(hook, "=")
+		if !ok || tenant == "" || name == "" {
+			return fmt.Errorf("httptransport: hook %q: want tenant:hook[=arg]", entry)
+		}
+		needsArg := name == "default-zone" || name == "default-sla"
+		switch {
+		case needsArg && arg == "":
+			return fmt.Errorf("httptransport: hook %q: %s takes a value", entry, name)
+		case !needsArg && hasArg:
+			return fmt.Errorf("httptransport: hook %q: %s takes no value", entry, name)
+		}
+		switch name {
+		case "normalize":
+			h.OnRequest(tenant, normalizeOrder)
+		case "default-zone":
+			h.OnRequest(tenant, func(_ context.Context, req *model.OrderRequest) {
+				if req.Zone == "" && req.Address == "" {
+					req.Zone = arg
+				}
+			})
+		case "default-sla":
+			h.OnRequest(tenant, func(_ context.Context, req *model.OrderRequest) {
+				if req.SLA == "" {
+					req.SLA = arg
+				}
+			})
+		case "omit-steps":
+			h.OnResponse(tenant, func(_ context.Context, resp *model.OrderResponse) {
+				if resp.Error == nil {
+					resp.Steps = nil
+				}
+			})
+		default:
+			return fmt.Errorf("httptransport: hook %q: unknown hook %s", entry, name)
+		}
+	}
+	return nil
+}
+
+// normalizeOrder is the normalize request hook.
+func normalizeOrder(_ context.Context, req *model.OrderRequest) {
+	req.OrderID = strings.TrimSpace(req.OrderID)
+	req.Zone = strings.ToLower(strings.TrimSpace(req.Zone))
+	req.SLA = strings.ToLower(strings.TrimSpace(req.SLA))
+	req.Channel = strings.ToLower(strings.TrimSpace(req.Channel))
+	req.Address = strings.Join(strings.Fields(req.Address), " ")
+}
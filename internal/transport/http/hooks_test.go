@@ -0,0 +1,131 @@
+package httptransport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/vendor"
+)
+
+type hookTenantKey struct{}
+
+func hookTenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(hookTenantKey{}).(string)
+	return tenant
+}
+
+// capturingProcessor records the order it was given.
+type capturingProcessor struct {
+	stubProcessor
+	got model.OrderRequest
+}
+
+func (c *capturingProcessor) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	c.got = req
+	return c.stubProcessor.Process(ctx, req)
+}
+
+func TestParseHooks(t *testing.T) {
+	t.Parallel()
+
+	h := NewHooks(hookTenant)
+	if err := ParseHooks("*:normalize, acme:default-sla=express,acme:default-zone=center,acme:omit-steps", h); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(h.pre[AllTenants]) != 1 || len(h.pre["acme"]) != 2 || len(h.post["acme"]) != 1 {
+		t.Fatalf("unexpected hooks pre=%v post=%v", h.pre, h.post)
+	}
+
+	for _, spec := range []string{"normalize", ":normalize", "acme:", "acme:uppercase", "acme:default-zone", "acme:default-zone=", "acme:normalize=yes"} {
+		if err := ParseHooks(spec, NewHooks(hookTenant)); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestHandleOrder_Hooks(t *testing.T) {
+	t.Parallel()
+
+	hooks := NewHooks(hookTenant)
+	if err := ParseHooks("*:normalize,acme:default-zone=center,acme:default-sla=express,acme:omit-steps", hooks); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		tenant    string
+		req       model.OrderRequest
+		err       error
+		wantReq   model.OrderRequest
+		wantSteps int
+	}{
+		{
+			name:      "normalized_for_everyone",
+			req:       model.OrderRequest{OrderID: " o-1 ", Amount: 100, Zone: " North", Address: "1  Main\tSt"},
+			wantReq:   model.OrderRequest{OrderID: "o-1", Amount: 100, Zone: "north", Address: "1 Main St"},
+			wantSteps: 1,
+		},
+		{
+			name:    "tenant_defaults_and_omitted_steps",
+			tenant:  "acme",
+			req:     model.OrderRequest{OrderID: "o-2", Amount: 100},
+			wantReq: model.OrderRequest{OrderID: "o-2", Amount: 100, Zone: "center", SLA: "express"},
+		},
+		{
+			name:      "defaults_keep_given_values",
+			tenant:    "acme",
+			req:       model.OrderRequest{OrderID: "o-3", Amount: 100, Zone: "south", SLA: "standard"},
+			err:       vendor.ErrUnavailable,
+			wantReq:   model.OrderRequest{OrderID: "o-3", Amount: 100, Zone: "south", SLA: "standard"},
+			wantSteps: 1, // failed responses keep their steps
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			proc := &capturingProcessor{stubProcessor: stubProcessor{
+				steps: []model.StepResult{{Name: "payment", Status: model.StatusOK}},
+				err:   tt.err,
+			}}
+			h := New(proc, 2*time.Second, WithHooks(hooks))
+
+			body, _ := json.Marshal(tt.req)
+			r := httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body))
+			r = r.WithContext(context.WithValue(r.Context(), hookTenantKey{}, tt.tenant))
+			w := httptest.NewRecorder()
+			h.HandleOrder(w, r)
+
+			var out model.OrderResponse
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if proc.got.OrderID != tt.wantReq.OrderID || proc.got.Zone != tt.wantReq.Zone ||
+				proc.got.SLA != tt.wantReq.SLA || proc.got.Address != tt.wantReq.Address {
+				t.Fatalf("expected processed order %+v, got %+v", tt.wantReq, proc.got)
+			}
+			if len(out.Steps) != tt.wantSteps {
+				t.Fatalf("expected %d steps, got %+v", tt.wantSteps, out.Steps)
+			}
+		})
+	}
+}
+
+func TestNewHooks_NilTenantPanics(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected panic for nil tenant func")
+		}
+	}()
+	NewHooks(nil)
+}
@@ -0,0 +1,113 @@
+package httptransport
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Page size bounds shared by every list endpoint.
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// cursorVersion prefixes every encoded cursor so its layout can change
+// without misreading cursors handed out by an older build.
+const cursorVersion = 1
+
+// pageQuery is a parsed page request: at most limit items, starting
+// after the item whose key is after when hasAfter is set.
+//
+// List endpoints order their items by a stable, strictly descending
+// uint64 key (newest first), so a cursor naming the last item served
+// stays valid while new items arrive or old ones are evicted.
+type pageQuery struct {
+	limit    int
+	after    uint64
+	hasAfter bool
+}
+
+var (
+	errPageLimit  = errors.New("limit must be a positive integer")
+	errPageCursor = errors.New("invalid cursor")
+)
+
+// parsePage reads the limit and cursor query parameters. A missing limit
+// is defaultPageLimit and a larger one is clamped to maxPageLimit.
+func parsePage(q url.Values) (pageQuery, error) {
+	p := pageQuery{limit: defaultPageLimit}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return pageQuery{}, errPageLimit
+		}
+		p.limit = min(n, maxPageLimit)
+	}
+	if s := q.Get("cursor"); s != "" {
+		key, err := decodeCursor(s)
+		if err != nil {
+			return pageQuery{}, err
+		}
+		p.after, p.hasAfter = key, true
+	}
+	return p, nil
+}
+
+// encodeCursor returns the opaque cursor for key.
+func encodeCursor(key uint64) string {
+	var b [9]byte
+	b[0] = cursorVersion
+	binary.BigEndian.PutUint64(b[1:], key)
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// decodeCursor returns the key encoded by encodeCursor.
+func decodeCursor(s string) (uint64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) != 9 || b[0] != cursorVersion {
+		return 0, errPageCursor
+	}
+	return binary.BigEndian.Uint64(b[1:]), nil
+}
+
+// paginate returns the window of items selected by p and the cursor of
+// the next page, or "" on the last page. items must be ordered by key,
+// strictly descending.
+func paginate[T any](items []T, key func(T) uint64, p pageQuery) ([]T, string) {
+	start := 0
+	if p.hasAfter {
+		start = len(items)
+		for i, it := range items {
+			if key(it) < p.after {
+				start = i
+				break
+			}
+		}
+	}
+	end := min(start+p.limit, len(items))
+	if end == len(items) {
+		return items[start:end], ""
+	}
+	return items[start:end], encodeCursor(key(items[end-1]))
+}
+
+// writePage writes items as a JSON array. If there is a next page, its
+// URL is advertised in a Link header (RFC 8288) with rel="next", so the
+// body keeps the shape of an unpaginated list.
+func writePage[T any](w http.ResponseWriter, r *http.Request, items []T, next string, limit int) {
+	if next != "" {
+		q := r.URL.Query()
+		q.Set("limit", strconv.Itoa(limit))
+		q.Set("cursor", next)
+		w.Header().Set("Link", fmt.Sprintf("<%s?%s>; rel=\"next\"", r.URL.Path, q.Encode()))
+	}
+	if items == nil {
+		items = []T{}
+	}
+	writeJSON(w, http.StatusOK, items)
+}
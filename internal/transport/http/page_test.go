@@ -0,0 +1,128 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestParsePage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		query     string
+		wantLimit int
+		wantAfter uint64
+		wantErr   error
+	}{
+		{name: "defaults", query: "", wantLimit: defaultPageLimit},
+		{name: "limit", query: "limit=5", wantLimit: 5},
+		{name: "limit_clamped", query: "limit=5000", wantLimit: maxPageLimit},
+		{name: "cursor", query: "cursor=" + encodeCursor(42), wantLimit: defaultPageLimit, wantAfter: 42},
+		{name: "zero_limit", query: "limit=0", wantErr: errPageLimit},
+		{name: "bad_limit", query: "limit=ten", wantErr: errPageLimit},
+		{name: "bad_cursor", query: "cursor=!!", wantErr: errPageCursor},
+		{name: "short_cursor", query: "cursor=AQ", wantErr: errPageCursor},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			q, _ := url.ParseQuery(tt.query)
+			got, err := parsePage(q)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if got.limit != tt.wantLimit || got.after != tt.wantAfter {
+				t.Fatalf("expected limit=%d after=%d, got %+v", tt.wantLimit, tt.wantAfter, got)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	t.Parallel()
+
+	items := []uint64{9, 7, 5, 3, 1}
+	key := func(k uint64) uint64 { return k }
+
+	page, next := paginate(items, key, pageQuery{limit: 2})
+	if fmt.Sprint(page) != "[9 7]" || next != encodeCursor(7) {
+		t.Fatalf("unexpected first page %v, next %q", page, next)
+	}
+
+	// The cursor still resumes after 7 once 7 itself has been evicted
+	// and newer items have arrived.
+	items = []uint64{12, 11, 9, 5, 3, 1}
+	page, next = paginate(items, key, pageQuery{limit: 2, after: 7, hasAfter: true})
+	if fmt.Sprint(page) != "[5 3]" || next != encodeCursor(3) {
+		t.Fatalf("unexpected second page %v, next %q", page, next)
+	}
+
+	page, next = paginate(items, key, pageQuery{limit: 2, after: 3, hasAfter: true})
+	if fmt.Sprint(page) != "[1]" || next != "" {
+		t.Fatalf("unexpected last page %v, next %q", page, next)
+	}
+
+	page, next = paginate(items, key, pageQuery{limit: 2, after: 1, hasAfter: true})
+	if len(page) != 0 || next != "" {
+		t.Fatalf("expected an empty page, got %v, next %q", page, next)
+	}
+}
+
+// Following the Link header walks the slow log one page at a time.
+func TestHandleSlowLog_Pages(t *testing.T) {
+	t.Parallel()
+
+	s := NewSlowLog(time.Second, 10, nil)
+	s.now = fakeClock(time.Second)
+	p := s.Wrap(&stubProcessor{})
+	for i := 1; i <= 5; i++ {
+		_, _ = p.Process(context.Background(), model.OrderRequest{OrderID: fmt.Sprintf("o-%d", i)})
+	}
+
+	var got []string
+	target := "/admin/slowlog?limit=2"
+	for pages := 0; target != ""; pages++ {
+		if pages == 5 {
+			t.Fatal("expected the last page to have no Link header")
+		}
+		w := httptest.NewRecorder()
+		s.HandleSlowLog(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		var out []model.SlowRequest
+		if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		for _, rec := range out {
+			got = append(got, rec.OrderID)
+		}
+		target = ""
+		if link := w.Header().Get("Link"); link != "" {
+			target, _, _ = strings.Cut(strings.TrimPrefix(link, "<"), ">")
+		}
+	}
+	if want := "[o-5 o-4 o-3 o-2 o-1]"; fmt.Sprint(got) != want {
+		t.Fatalf("expected %s, got %v", want, got)
+	}
+
+	w := httptest.NewRecorder()
+	s.HandleSlowLog(w, httptest.NewRequest(http.MethodGet, "/admin/slowlog?cursor=bogus", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,79 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// problemMediaType is the RFC 7807 JSON media type.
+const problemMediaType = "application/problem+json"
+
+// problemTypeBase prefixes the error kind in Problem.Type.
+const problemTypeBase = "urn:order-pipeline:error:"
+
+// wantsProblem reports whether r's Accept header prefers problem
+// details over plain JSON for error responses: application/problem+json
+// must be listed explicitly, with a quality at least that of
+// application/json and the wildcards matching it.
+func wantsProblem(r *http.Request) bool {
+	var problem, plain float64
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch mt {
+		case problemMediaType:
+			problem = max(problem, q)
+		case "application/json", "application/*", "*/*":
+			plain = max(plain, q)
+		}
+	}
+	return problem > 0 && problem >= plain
+}
+
+// newProblem returns the problem details for an error of the given kind
+// answered with status.
+func newProblem(r *http.Request, status int, kind, detail string) model.Problem {
+	return model.Problem{
+		Type:     problemTypeBase + kind,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.RequestURI(),
+		Kind:     kind,
+	}
+}
+
+// failedStep returns the step that produced err: the one recorded in
+// err, or else the first failed step whose detail is err's kind.
+func failedStep(steps []model.StepResult, err error) string {
+	if name := stepName(err); name != "" {
+		return name
+	}
+	kind := errorKind(err)
+	for _, s := range steps {
+		if s.Status == model.StatusError && s.Detail == kind {
+			return s.Name
+		}
+	}
+	return ""
+}
+
+// writeProblem writes p as an application/problem+json response.
+func writeProblem(w http.ResponseWriter, p model.Problem) {
+	w.Header().Set("Content-Type", problemMediaType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
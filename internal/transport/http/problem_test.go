@@ -0,0 +1,145 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/order"
+)
+
+func TestWantsProblem(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{accept: "", want: false},
+		{accept: "*/*", want: false},
+		{accept: "application/json", want: false},
+		{accept: "application/problem+json", want: true},
+		{accept: "application/problem+json, application/json", want: true},
+		{accept: "application/json, application/problem+json;q=0.5", want: false},
+		{accept: "application/problem+json;q=0.9, */*;q=0.1", want: true},
+		{accept: "application/problem+json;q=0", want: false},
+		{accept: "application/problem+json;q=x", want: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.accept, func(t *testing.T) {
+			t.Parallel()
+			r := httptest.NewRequest(http.MethodPost, "/order", nil)
+			r.Header.Set("Accept", tt.accept)
+			if got := wantsProblem(r); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// serveProblem posts body to h accepting problem details.
+func serveProblem(h *Handler, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body))
+	r.Header.Set("Accept", problemMediaType)
+	w := httptest.NewRecorder()
+	h.HandleOrder(w, r)
+	return w
+}
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) model.Problem {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != problemMediaType {
+		t.Fatalf("expected Content-Type %s, got %q", problemMediaType, ct)
+	}
+	var p model.Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if p.Status != w.Code {
+		t.Fatalf("expected status member %d, got %d", w.Code, p.Status)
+	}
+	return p
+}
+
+func TestHandleOrder_Problem(t *testing.T) {
+	t.Parallel()
+
+	steps := []model.StepResult{
+		{Name: "payment", Status: model.StatusOK},
+		{Name: "vendor", Status: model.StatusError, Detail: "vendor_unavailable"},
+	}
+	h := New(&stubProcessor{steps: steps, err: testAppErr{kind: "vendor_unavailable"}}, 2*time.Second)
+
+	w := serveProblem(h, `{"order_id":"o-1","amount":100}`)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+	p := decodeProblem(t, w)
+	want := model.Problem{
+		Type:     "urn:order-pipeline:error:vendor_unavailable",
+		Title:    "Service Unavailable",
+		Status:   http.StatusServiceUnavailable,
+		Detail:   "order failed at step vendor",
+		Instance: "/order",
+		Kind:     "vendor_unavailable",
+		Step:     "vendor",
+		OrderID:  "o-1",
+		Steps:    steps,
+	}
+	if p.Type != want.Type || p.Title != want.Title || p.Detail != want.Detail || p.Instance != want.Instance ||
+		p.Kind != want.Kind || p.Step != want.Step || p.OrderID != want.OrderID || len(p.Steps) != 2 {
+		t.Fatalf("expected %+v, got %+v", want, p)
+	}
+}
+
+func TestHandleOrder_ProblemMultipleErrors(t *testing.T) {
+	t.Parallel()
+
+	err := errors.Join(
+		&order.StepError{Step: "payment", Err: testAppErr{kind: "payment_declined"}},
+		&order.StepError{Step: "courier", Err: context.DeadlineExceeded},
+	)
+	h := New(&stubProcessor{err: err}, 2*time.Second)
+
+	p := decodeProblem(t, serveProblem(h, `{"order_id":"o-1","amount":100}`))
+	if p.Kind != "timeout" || p.Step != "courier" || len(p.Errors) != 2 {
+		t.Fatalf("expected timeout at courier with 2 errors, got %+v", p)
+	}
+}
+
+func TestHandleOrder_ProblemValidation(t *testing.T) {
+	t.Parallel()
+
+	h := New(&stubProcessor{}, 2*time.Second)
+	w := serveProblem(h, `{"order_id":"o-1"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", w.Code)
+	}
+	p := decodeProblem(t, w)
+	want := []model.FieldError{{Field: "amount", Message: "must be > 0"}}
+	if p.Kind != "invalid_order" || p.Detail != "order has invalid fields" || !reflect.DeepEqual(p.Fields, want) {
+		t.Fatalf("unexpected problem %+v", p)
+	}
+}
+
+func TestHandleOrder_ProblemSuccessIsPlainJSON(t *testing.T) {
+	t.Parallel()
+
+	h := New(&stubProcessor{steps: []model.StepResult{{Name: "payment", Status: model.StatusOK}}}, 2*time.Second)
+	w := serveProblem(h, `{"order_id":"o-1","amount":100}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected application/json, got %q", ct)
+	}
+}
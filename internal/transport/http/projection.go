@@ -0,0 +1,240 @@
+package httptransport
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// The projection keeps one hour of one-minute buckets, ranks the ten
+// most frequent step failures and remembers the last twenty failed
+// orders.
+const (
+	projectionBuckets = 60
+	projectionTopN    = 10
+	projectionRecent  = 20
+)
+
+// Projection maintains dashboard read models from processed orders:
+// orders in flight, order counts per final status and latency per
+// minute, the step failures seen most often in the last hour, and the
+// most recent failed orders. Reads never reach the pipeline.
+type Projection struct {
+	now      func() time.Time
+	inFlight atomic.Int64
+
+	mu      sync.Mutex
+	buckets [projectionBuckets]projectionBucket
+	recent  [projectionRecent]model.RecentFailure // ring
+	next    int                                   // next ring slot
+	failed  int                                   // ring entries in use
+}
+
+// projectionBucket holds the counts for one minute.
+type projectionBucket struct {
+	minute   int64 // unix minutes
+	statuses map[model.Status]int64
+	failures map[stepFailure]int64
+	orders   int64
+	total    time.Duration
+	max      time.Duration
+}
+
+type stepFailure struct {
+	step string
+	kind string
+}
+
+// NewProjection returns an empty Projection.
+func NewProjection() *Projection {
+	return &Projection{now: time.Now}
+}
+
+// Wrap returns an orderProcessor that runs p and folds each order into
+// the read models.
+func (pr *Projection) Wrap(p orderProcessor) orderProcessor {
+	if p == nil {
+		panic("httptransport.Projection.Wrap: nil order processor")
+	}
+	return &projectionProcessor{proj: pr, wrapped: wrapped{p}}
+}
+
+// projectionProcessor is the orderProcessor returned by Projection.Wrap.
+type projectionProcessor struct {
+	wrapped
+	proj *Projection
+}
+
+func (pp *projectionProcessor) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	pp.proj.inFlight.Add(1)
+	start := pp.proj.now()
+	steps, err := pp.next.Process(ctx, req)
+	pp.proj.inFlight.Add(-1)
+
+	primary := mostSevere(splitErrors(err))
+	pp.proj.record(req.OrderID, steps, orderStatus(steps, primary), errorKind(primary), pp.proj.now().Sub(start))
+	return steps, err
+}
+
+// record counts one order with its final status, error kind (empty on
+// success), failed steps and processing time.
+func (pr *Projection) record(orderID string, steps []model.StepResult, status model.Status, kind string, elapsed time.Duration) {
+	now := pr.now()
+	minute := now.Unix() / 60
+
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	b := &pr.buckets[minute%projectionBuckets]
+	if b.minute != minute || b.statuses == nil {
+		*b = projectionBucket{
+			minute:   minute,
+			statuses: make(map[model.Status]int64),
+			failures: make(map[stepFailure]int64),
+		}
+	}
+	b.statuses[status]++
+	b.orders++
+	b.total += elapsed
+	b.max = max(b.max, elapsed)
+	for _, s := range steps {
+		if s.Status == model.StatusError {
+			b.failures[stepFailure{step: s.Name, kind: s.Detail}]++
+		}
+	}
+
+	if status == model.StatusError {
+		pr.recent[pr.next] = model.RecentFailure{
+			Time:    now.UTC().Format(time.RFC3339),
+			OrderID: orderID,
+			Kind:    kind,
+		}
+		pr.next = (pr.next + 1) % projectionRecent
+		pr.failed = min(pr.failed+1, projectionRecent)
+	}
+}
+
+// InFlight returns the number of orders being processed.
+func (pr *Projection) InFlight() int64 { return pr.inFlight.Load() }
+
+// liveLocked returns the buckets of the last hour, oldest first. pr.mu must
+// be held.
+func (pr *Projection) liveLocked(minute int64) []*projectionBucket {
+	var out []*projectionBucket
+	for i := range pr.buckets {
+		b := &pr.buckets[i]
+		if age := minute - b.minute; b.statuses != nil && age >= 0 && age < projectionBuckets {
+			out = append(out, b)
+		}
+	}
+	slices.SortFunc(out, func(a, b *projectionBucket) int { return cmp.Compare(a.minute, b.minute) })
+	return out
+}
+
+// StatusCounts reports order counts per status for each minute of the
+// last hour that saw orders, oldest first.
+func (pr *Projection) StatusCounts() []model.StatusMinute {
+	minute := pr.now().Unix() / 60
+
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	live := pr.liveLocked(minute)
+	out := make([]model.StatusMinute, len(live))
+	for i, b := range live {
+		out[i] = model.StatusMinute{
+			Minute: time.Unix(b.minute*60, 0).UTC().Format(time.RFC3339),
+			Counts: maps.Clone(b.statuses),
+		}
+	}
+	return out
+}
+
+// Latencies reports order processing latency for each minute of the
+// last hour that saw orders, oldest first.
+func (pr *Projection) Latencies() []model.LatencyMinute {
+	minute := pr.now().Unix() / 60
+
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	live := pr.liveLocked(minute)
+	out := make([]model.LatencyMinute, len(live))
+	for i, b := range live {
+		out[i] = model.LatencyMinute{
+			Minute: time.Unix(b.minute*60, 0).UTC().Format(time.RFC3339),
+			Orders: b.orders,
+			AvgMS:  (b.total / time.Duration(b.orders)).Milliseconds(),
+			MaxMS:  b.max.Milliseconds(),
+		}
+	}
+	return out
+}
+
+// RecentFailures reports the most recent failed orders, newest first.
+func (pr *Projection) RecentFailures() []model.RecentFailure {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	out := make([]model.RecentFailure, pr.failed)
+	for i := range out {
+		out[i] = pr.recent[(pr.next-1-i+projectionRecent)%projectionRecent]
+	}
+	return out
+}
+
+// TopFailures reports the most frequent step failures of the last hour,
+// by count and then by step and kind.
+func (pr *Projection) TopFailures() []model.StepFailureCount {
+	minute := pr.now().Unix() / 60
+
+	pr.mu.Lock()
+	totals := make(map[stepFailure]int64)
+	for _, b := range pr.liveLocked(minute) {
+		for f, n := range b.failures {
+			totals[f] += n
+		}
+	}
+	pr.mu.Unlock()
+
+	out := make([]model.StepFailureCount, 0, len(totals))
+	for f, n := range totals {
+		out = append(out, model.StepFailureCount{Step: f.step, Kind: f.kind, Count: n})
+	}
+	slices.SortFunc(out, func(a, b model.StepFailureCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Step, b.Step), cmp.Compare(a.Kind, b.Kind))
+	})
+	if len(out) > projectionTopN {
+		out = out[:projectionTopN]
+	}
+	return out
+}
+
+// HandleStatusCounts serves order counts per status per minute.
+//
+// The request must be a GET.
+func (pr *Projection) HandleStatusCounts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, pr.StatusCounts())
+}
+
+// HandleTopFailures serves the most frequent step failures.
+//
+// The request must be a GET.
+func (pr *Projection) HandleTopFailures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, pr.TopFailures())
+}
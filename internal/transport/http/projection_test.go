@@ -0,0 +1,168 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestProjection(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0).Truncate(time.Minute)
+	pr := NewProjection()
+	pr.now = func() time.Time { return now }
+
+	ok := &stubProcessor{steps: []model.StepResult{{Name: "payment", Status: model.StatusOK}}}
+	declined := &stubProcessor{
+		steps: []model.StepResult{{Name: "payment", Status: model.StatusError, Detail: "payment_declined"}},
+		err:   testAppErr{kind: "payment_declined"},
+	}
+	noCourier := &stubProcessor{
+		steps: []model.StepResult{
+			{Name: "vendor", Status: model.StatusError, Detail: "vendor_unavailable"},
+			{Name: "courier", Status: model.StatusError, Detail: "no_courier"},
+		},
+		err: testAppErr{kind: "no_courier"},
+	}
+	deferred := &stubProcessor{steps: []model.StepResult{{Name: "courier", Status: model.StatusDeferred}}}
+
+	run := func(p *stubProcessor, n int) {
+		for i := 0; i < n; i++ {
+			_, _ = pr.Wrap(p).Process(context.Background(), model.OrderRequest{})
+		}
+	}
+
+	// Minute 0: stale after the hour; minutes 59 and 60 are live.
+	run(declined, 5)
+	now = now.Add(59 * time.Minute)
+	run(ok, 3)
+	run(declined, 1)
+	now = now.Add(time.Minute)
+	run(noCourier, 2)
+	run(deferred, 1)
+
+	wantStatuses := []model.StatusMinute{
+		{Minute: now.Add(-time.Minute).UTC().Format(time.RFC3339), Counts: map[model.Status]int64{model.StatusOK: 3, model.StatusError: 1}},
+		{Minute: now.UTC().Format(time.RFC3339), Counts: map[model.Status]int64{model.StatusError: 2, model.StatusAcceptedPendingCourier: 1}},
+	}
+	if got := pr.StatusCounts(); !reflect.DeepEqual(got, wantStatuses) {
+		t.Fatalf("expected %+v, got %+v", wantStatuses, got)
+	}
+
+	wantFailures := []model.StepFailureCount{
+		{Step: "courier", Kind: "no_courier", Count: 2},
+		{Step: "vendor", Kind: "vendor_unavailable", Count: 2},
+		{Step: "payment", Kind: "payment_declined", Count: 1},
+	}
+	if got := pr.TopFailures(); !reflect.DeepEqual(got, wantFailures) {
+		t.Fatalf("expected %+v, got %+v", wantFailures, got)
+	}
+}
+
+// funcProcessor calls fn from Process.
+type funcProcessor func(req model.OrderRequest) ([]model.StepResult, error)
+
+func (f funcProcessor) Process(_ context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	return f(req)
+}
+
+func TestProjectionLatencyAndRecentFailures(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0).Truncate(time.Minute)
+	pr := NewProjection()
+	pr.now = func() time.Time { return now }
+
+	p := pr.Wrap(funcProcessor(func(req model.OrderRequest) ([]model.StepResult, error) {
+		if got := pr.InFlight(); got != 1 {
+			t.Fatalf("expected 1 order in flight, got %d", got)
+		}
+		now = now.Add(time.Duration(req.Amount) * time.Millisecond)
+		if req.FailStep != "" {
+			return nil, testAppErr{kind: req.FailStep}
+		}
+		return nil, nil
+	}))
+
+	orders := []model.OrderRequest{
+		{OrderID: "o-1", Amount: 100},
+		{OrderID: "o-2", Amount: 300, FailStep: "no_courier"},
+		{OrderID: "o-3", Amount: 200, FailStep: "payment_declined"},
+	}
+	for _, req := range orders {
+		_, _ = p.Process(context.Background(), req)
+	}
+	if got := pr.InFlight(); got != 0 {
+		t.Fatalf("expected 0 orders in flight, got %d", got)
+	}
+
+	wantLatency := []model.LatencyMinute{{Minute: now.Truncate(time.Minute).UTC().Format(time.RFC3339), Orders: 3, AvgMS: 200, MaxMS: 300}}
+	if got := pr.Latencies(); !reflect.DeepEqual(got, wantLatency) {
+		t.Fatalf("expected %+v, got %+v", wantLatency, got)
+	}
+
+	got := pr.RecentFailures()
+	if len(got) != 2 || got[0].OrderID != "o-3" || got[0].Kind != "payment_declined" || got[1].OrderID != "o-2" {
+		t.Fatalf("expected o-3 then o-2, got %+v", got)
+	}
+
+	// The ring keeps only the newest failures.
+	for i := 0; i < projectionRecent; i++ {
+		pr.record("o-new", nil, model.StatusError, "internal", 0)
+	}
+	if got := pr.RecentFailures(); len(got) != projectionRecent || got[projectionRecent-1].OrderID != "o-new" {
+		t.Fatalf("expected %d newest failures, got %+v", projectionRecent, got)
+	}
+}
+
+func TestProjectionTopN(t *testing.T) {
+	t.Parallel()
+
+	pr := NewProjection()
+	for i := 0; i < projectionTopN+5; i++ {
+		// Kind i fails i+1 times, so the lowest kinds drop off.
+		for j := 0; j <= i; j++ {
+			pr.record("o-1", []model.StepResult{{Name: "vendor", Status: model.StatusError, Detail: string(rune('a' + i))}}, model.StatusError, "internal", 0)
+		}
+	}
+	got := pr.TopFailures()
+	if len(got) != projectionTopN || got[0].Count != projectionTopN+5 || got[len(got)-1].Count != 6 {
+		t.Fatalf("expected the top %d failures, got %+v", projectionTopN, got)
+	}
+}
+
+func TestProjectionHandlers(t *testing.T) {
+	t.Parallel()
+
+	pr := NewProjection()
+	pr.record("o-1", []model.StepResult{{Name: "courier", Status: model.StatusError, Detail: "no_courier"}}, model.StatusError, "no_courier", 0)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		code    int
+	}{
+		{name: "statuses", handler: pr.HandleStatusCounts, method: http.MethodGet, code: http.StatusOK},
+		{name: "failures", handler: pr.HandleTopFailures, method: http.MethodGet, code: http.StatusOK},
+		{name: "statuses wrong method", handler: pr.HandleStatusCounts, method: http.MethodPost, code: http.StatusMethodNotAllowed},
+		{name: "failures wrong method", handler: pr.HandleTopFailures, method: http.MethodPost, code: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.handler(w, httptest.NewRequest(tt.method, "/admin/dashboard", nil))
+		if w.Code != tt.code {
+			t.Fatalf("%s: expected %d, got %d", tt.name, tt.code, w.Code)
+		}
+		if tt.code == http.StatusOK && !json.Valid(w.Body.Bytes()) {
+			t.Fatalf("%s: invalid JSON %q", tt.name, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package httptransport
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// recordWriter persists recordings.
+type recordWriter interface {
+	Record(rec model.Recording) error
+}
+
+// Recorder captures selected orders for offline replay: the request,
+// a snapshot of the server configuration, the outcome and the step
+// timings.
+type Recorder struct {
+	w      recordWriter
+	config map[string]string
+	all    bool
+	orders map[string]bool
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// NewRecorder returns a Recorder writing to w the orders whose IDs are
+// listed in orders, or every order if orders contains "*". config is
+// stored with each recording. Write failures are logged through logger
+// and do not fail the order.
+//
+// It panics if w or logger is nil.
+func NewRecorder(w recordWriter, config map[string]string, orders []string, logger *slog.Logger) *Recorder {
+	if w == nil {
+		panic("httptransport.NewRecorder: nil writer")
+	}
+	if logger == nil {
+		panic("httptransport.NewRecorder: nil logger")
+	}
+	r := &Recorder{w: w, config: config, orders: make(map[string]bool, len(orders)), logger: logger, now: time.Now}
+	for _, id := range orders {
+		if id == "*" {
+			r.all = true
+		}
+		r.orders[id] = true
+	}
+	return r
+}
+
+// Wrap returns an orderProcessor that runs p and records selected
+// orders.
+func (rc *Recorder) Wrap(p orderProcessor) orderProcessor {
+	if p == nil {
+		panic("httptransport.Recorder.Wrap: nil order processor")
+	}
+	return &recordingProcessor{rec: rc, wrapped: wrapped{p}}
+}
+
+// recordingProcessor is the orderProcessor returned by Recorder.Wrap.
+type recordingProcessor struct {
+	wrapped
+	rec *Recorder
+}
+
+func (rp *recordingProcessor) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	rc := rp.rec
+	if !rc.all && !rc.orders[req.OrderID] {
+		return rp.next.Process(ctx, req)
+	}
+
+	start := rc.now()
+	steps, err := rp.next.Process(ctx, req)
+	primary := mostSevere(splitErrors(err))
+	rec := model.Recording{
+		Request:    req,
+		Config:     rc.config,
+		Status:     orderStatus(steps, primary),
+		ErrorKind:  errorKind(primary),
+		DurationMS: rc.now().Sub(start).Milliseconds(),
+		Steps:      steps,
+	}
+	if werr := rc.w.Record(rec); werr != nil {
+		rc.logger.LogAttrs(ctx, slog.LevelError, "order recording failed",
+			slog.String("order_id", req.OrderID),
+			slog.String("error", werr.Error()),
+		)
+	}
+	return steps, err
+}
@@ -0,0 +1,81 @@
+package httptransport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+type stubRecordWriter struct {
+	recs []model.Recording
+	err  error
+}
+
+func (s *stubRecordWriter) Record(rec model.Recording) error {
+	s.recs = append(s.recs, rec)
+	return s.err
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	steps := []model.StepResult{{Name: "vendor", Status: model.StatusError, Detail: "vendor_unavailable", DurationMS: 200}}
+	stub := &stubProcessor{steps: steps, err: testAppErr{kind: "vendor_unavailable"}}
+	config := map[string]string{"fail-at-end": "false"}
+
+	tests := []struct {
+		name     string
+		orders   []string
+		recorded []string
+	}{
+		{name: "selected", orders: []string{"o-2"}, recorded: []string{"o-2"}},
+		{name: "all", orders: []string{"*"}, recorded: []string{"o-1", "o-2"}},
+		{name: "none", orders: nil, recorded: nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := &stubRecordWriter{}
+			rc := NewRecorder(w, config, tt.orders, slog.New(slog.DiscardHandler))
+			rc.now = fakeClock(300 * time.Millisecond)
+			p := rc.Wrap(stub)
+			for _, id := range []string{"o-1", "o-2"} {
+				if _, err := p.Process(context.Background(), model.OrderRequest{OrderID: id, Amount: 100}); err == nil {
+					t.Fatal("expected the processor's error to pass through")
+				}
+			}
+
+			if len(w.recs) != len(tt.recorded) {
+				t.Fatalf("expected %d recordings, got %+v", len(tt.recorded), w.recs)
+			}
+			for i, rec := range w.recs {
+				if rec.Request.OrderID != tt.recorded[i] || rec.Status != model.StatusError ||
+					rec.ErrorKind != "vendor_unavailable" || rec.DurationMS != 300 ||
+					rec.Config["fail-at-end"] != "false" || len(rec.Steps) != 1 || rec.Steps[0].DurationMS != 200 {
+					t.Fatalf("unexpected recording %+v", rec)
+				}
+			}
+		})
+	}
+}
+
+func TestRecorderWriteFailureIsLogged(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	rc := NewRecorder(&stubRecordWriter{err: errors.New("disk full")}, nil, []string{"*"}, slog.New(slog.NewTextHandler(&buf, nil)))
+	if _, err := rc.Wrap(&stubProcessor{}).Process(context.Background(), model.OrderRequest{OrderID: "o-1"}); err != nil {
+		t.Fatalf("expected the order to succeed, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "order recording failed") || !strings.Contains(buf.String(), "disk full") {
+		t.Fatalf("expected a logged write failure, got %q", buf.String())
+	}
+}
@@ -0,0 +1,143 @@
+package httptransport
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// HeaderServedBy names the region that answered a request.
+const HeaderServedBy = "X-Served-By"
+
+// kindWrongRegion classifies orders pinned to another region.
+const kindWrongRegion = "wrong_region"
+
+// Region is the deployment region of this instance and the base URLs of
+// its peer regions, for global deployments where an order must be
+// processed in the region holding its data.
+//
+// Every response names the region in X-Served-By. Orders naming another
+// known region in OrderRequest.Region are refused with 421 and a
+// Location header pointing at that region's /order endpoint; orders
+// naming an unknown region are invalid (see WithRegion). Orders naming
+// no region are processed here.
+type Region struct {
+	name        string
+	peers       map[string]string // region → base URL
+	served      atomic.Int64
+	misdirected map[string]*atomic.Int64 // by peer region
+}
+
+// NewRegion returns the Region name with the given peers, by region
+// name. It panics if name is empty or is one of the peers.
+func NewRegion(name string, peers map[string]string) *Region {
+	if name == "" {
+		panic("httptransport.NewRegion: empty region")
+	}
+	if _, ok := peers[name]; ok {
+		panic("httptransport.NewRegion: region " + name + " is its own peer")
+	}
+	r := &Region{name: name, peers: maps.Clone(peers), misdirected: make(map[string]*atomic.Int64, len(peers))}
+	for p := range peers {
+		r.misdirected[p] = new(atomic.Int64)
+	}
+	return r
+}
+
+// ParseRegionPeers parses a comma-separated list of region=URL pairs,
+// e.g. "eu-west=https://eu.example.com,us-east=https://us.example.com".
+// URLs must be absolute http(s) URLs.
+func ParseRegionPeers(spec string) (map[string]string, error) {
+	peers := map[string]string{}
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, rawURL, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("httptransport: region peer %q: want region=URL", entry)
+		}
+		u, err := url.Parse(strings.TrimSpace(rawURL))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("httptransport: region peer %q: want an http(s) URL", entry)
+		}
+		if _, dup := peers[name]; dup {
+			return nil, fmt.Errorf("httptransport: region peer %q: repeated", name)
+		}
+		peers[name] = strings.TrimSuffix(u.String(), "/")
+	}
+	return peers, nil
+}
+
+// Name returns the region of this instance.
+func (r *Region) Name() string { return r.name }
+
+// Middleware names the region in the X-Served-By header of every
+// response.
+func (r *Region) Middleware(next http.Handler) http.Handler {
+	if next == nil {
+		panic("httptransport.Region.Middleware: nil handler")
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set(HeaderServedBy, r.name)
+		next.ServeHTTP(w, req)
+	})
+}
+
+// Known reports whether an order may be pinned to region: this region,
+// a peer or none.
+func (r *Region) Known(region string) bool {
+	_, ok := r.peers[region]
+	return ok || region == "" || region == r.name
+}
+
+// redirect returns the base URL of the peer region a known region
+// belongs to, or "" if the order is processed here, and counts it.
+func (r *Region) redirect(region string) string {
+	peerURL, ok := r.peers[region]
+	if !ok {
+		r.served.Add(1)
+		return ""
+	}
+	r.misdirected[region].Add(1)
+	return peerURL
+}
+
+// known lists this region and its peers, for error messages.
+func (r *Region) known() string {
+	return strings.Join(append([]string{r.name}, slices.Sorted(maps.Keys(r.peers))...), ", ")
+}
+
+// Stats reports the region, its peers, and the orders served here and
+// refused per peer region.
+func (r *Region) Stats() model.RegionStats {
+	s := model.RegionStats{
+		Region:      r.name,
+		Peers:       maps.Clone(r.peers),
+		Served:      r.served.Load(),
+		Misdirected: make(map[string]int64, len(r.misdirected)),
+	}
+	for p, n := range r.misdirected {
+		s.Misdirected[p] = n.Load()
+	}
+	return s
+}
+
+// HandleRegion serves the region counters.
+//
+// The request must be a GET.
+func (r *Region) HandleRegion(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, r.Stats())
+}
@@ -0,0 +1,119 @@
+package httptransport
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestParseRegionPeers(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseRegionPeers(" eu-west=https://eu.example.com/ , ap=http://10.0.0.1:8080")
+	want := map[string]string{"eu-west": "https://eu.example.com", "ap": "http://10.0.0.1:8080"}
+	if err != nil || !maps.Equal(got, want) {
+		t.Fatalf("expected %v, got %v (%v)", want, got, err)
+	}
+	for _, spec := range []string{"eu-west", "=https://eu.example.com", "eu=ftp://eu.example.com", "eu=/order", "eu=http://a,eu=http://b"} {
+		if _, err := ParseRegionPeers(spec); err == nil {
+			t.Fatalf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestNewRegion_InvalidPanics(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		region string
+		peers  map[string]string
+	}{
+		"empty":    {},
+		"own_peer": {region: "us", peers: map[string]string{"us": "http://us"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			NewRegion(tc.region, tc.peers)
+		})
+	}
+}
+
+func TestHandleOrder_Region(t *testing.T) {
+	t.Parallel()
+
+	region := NewRegion("us-east", map[string]string{"eu-west": "https://eu.example.com"})
+	h := region.Middleware(http.HandlerFunc(New(&stubProcessor{}, time.Second, WithRegion(region)).HandleOrder))
+
+	tests := []struct {
+		name         string
+		region       string
+		problem      bool
+		wantStatus   int
+		wantKind     string
+		wantLocation string
+	}{
+		{name: "here", region: "us-east", wantStatus: http.StatusOK},
+		{name: "unpinned", wantStatus: http.StatusOK},
+		{name: "peer", region: "eu-west", wantStatus: http.StatusMisdirectedRequest, wantKind: "wrong_region", wantLocation: "https://eu.example.com/order"},
+		{name: "peer_problem", region: "eu-west", problem: true, wantStatus: http.StatusMisdirectedRequest, wantKind: "wrong_region", wantLocation: "https://eu.example.com/order"},
+		{name: "unknown", region: "mars", wantStatus: http.StatusUnprocessableEntity, wantKind: "invalid_order"},
+	}
+
+	for _, tt := range tests {
+		body, _ := json.Marshal(model.OrderRequest{OrderID: "o-1", Amount: 10, Region: tt.region})
+		r := httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body))
+		if tt.problem {
+			r.Header.Set("Accept", "application/problem+json")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.wantStatus || w.Header().Get(HeaderServedBy) != "us-east" || w.Header().Get("Location") != tt.wantLocation {
+			t.Fatalf("%s: expected %d from us-east to %q, got %d %v", tt.name, tt.wantStatus, tt.wantLocation, w.Code, w.Header())
+		}
+		if tt.wantKind == "" {
+			continue
+		}
+		var out struct {
+			Kind  string              `json:"kind"`
+			Error *model.ErrorPayload `json:"error"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+			t.Fatalf("%s: decode: %v", tt.name, err)
+		}
+		kind := out.Kind // problem details
+		if out.Error != nil {
+			kind = out.Error.Kind
+		}
+		if kind != tt.wantKind {
+			t.Fatalf("%s: expected kind %s, got %q", tt.name, tt.wantKind, kind)
+		}
+	}
+
+	want := model.RegionStats{
+		Region:      "us-east",
+		Peers:       map[string]string{"eu-west": "https://eu.example.com"},
+		Served:      2,
+		Misdirected: map[string]int64{"eu-west": 2},
+	}
+	got := region.Stats()
+	if got.Region != want.Region || got.Served != want.Served || !maps.Equal(got.Peers, want.Peers) || !maps.Equal(got.Misdirected, want.Misdirected) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	w := httptest.NewRecorder()
+	region.HandleRegion(w, httptest.NewRequest(http.MethodPost, "/admin/region", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
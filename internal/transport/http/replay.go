@@ -0,0 +1,240 @@
+package httptransport
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/netacl"
+)
+
+// Header names carrying the replay-protection values.
+const (
+	HeaderNonce     = "X-Request-Nonce"
+	HeaderTimestamp = "X-Request-Timestamp" // unix seconds
+	HeaderSignature = "X-Request-Signature" // with a secret; see SignRequest
+)
+
+// seenNonce is an entry of the sliding-window nonce cache.
+type seenNonce struct {
+	nonce   string
+	client  string
+	expires time.Time
+}
+
+// ReplayGuard blocks replayed requests by validating a nonce and a
+// timestamp header against a sliding-window nonce cache.
+//
+// A request is accepted only if its timestamp is within skew of the
+// server clock and its nonce has not been seen within the window.
+// Rejections are counted and exposed via Rejected.
+//
+// Without a secret the guard only deduplicates: anyone who sees a
+// request can resend its body under a fresh nonce and timestamp. With
+// a secret, requests must also carry a signature binding the nonce and
+// timestamp to the body (see SignRequest), so only holders of the
+// secret can mint new ones.
+//
+// The cache holds at most maxNonces nonces, and at most maxPerClient
+// from one client address. Forgetting one early would let it be
+// replayed, so a client over its share is refused with 429 until its
+// oldest nonces expire, and while the whole cache is full every new
+// request is refused with 503.
+type ReplayGuard struct {
+	skew         time.Duration
+	window       time.Duration
+	maxNonces    int
+	maxPerClient int
+	secret       []byte
+	now          func() time.Time
+
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	clients map[string]int // cached nonces per client address
+	queue   []seenNonce    // insertion order; expiries are monotonic
+
+	rejected atomic.Int64
+}
+
+// NewReplayGuard returns a ReplayGuard with the given clock skew, nonce
+// window, nonce cache size, per-client share of the cache and
+// signature secret.
+//
+// A non-positive skew defaults to 30 seconds, a non-positive maxNonces
+// to 100,000 and a maxPerClient outside (0, maxNonces] to a tenth of
+// maxNonces. The window is raised to at least 2*skew so that a nonce is
+// remembered for as long as its timestamp can still pass the skew
+// check. An empty secret accepts unsigned requests.
+func NewReplayGuard(skew, window time.Duration, maxNonces, maxPerClient int, secret []byte) *ReplayGuard {
+	if skew <= 0 {
+		skew = 30 * time.Second
+	}
+	if window < 2*skew {
+		window = 2 * skew
+	}
+	if maxNonces <= 0 {
+		maxNonces = 100_000
+	}
+	if maxPerClient <= 0 || maxPerClient > maxNonces {
+		maxPerClient = max(maxNonces/10, 1)
+	}
+	return &ReplayGuard{
+		skew:         skew,
+		window:       window,
+		maxNonces:    maxNonces,
+		maxPerClient: maxPerClient,
+		secret:       secret,
+		now:          time.Now,
+		seen:         make(map[string]time.Time),
+		clients:      make(map[string]int),
+	}
+}
+
+// SignRequest returns the HeaderSignature value of a request under
+// secret: "sha256=" followed by the hex HMAC-SHA256 of the nonce, the
+// timestamp and the raw body, each of the first two followed by a
+// newline.
+func SignRequest(secret []byte, nonce, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(nonce + "\n" + timestamp + "\n"))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Rejected returns the number of requests rejected as replays or
+// for missing/invalid replay headers.
+func (g *ReplayGuard) Rejected() int64 { return g.rejected.Load() }
+
+// Rejection reasons answered with something other than 401.
+const (
+	msgCacheFull  = "replay cache full"
+	msgClientFull = "too many recent nonces from this client"
+)
+
+// Middleware wraps next and rejects requests that fail replay validation
+// with a 401 and a replay_rejected error kind, with a 429 while the
+// client's share of the nonce cache is used up, or with a 503 while the
+// whole cache is full. With a secret the body is read, up to
+// maxBodyBytes, to check the signature and handed on to next.
+func (g *ReplayGuard) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		if len(g.secret) > 0 {
+			var err error
+			body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+			if err != nil {
+				status, kind, msg := http.StatusBadRequest, "bad_request", "unreadable body"
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					status, kind, msg = http.StatusRequestEntityTooLarge, "request_too_large", "body exceeds "+strconv.Itoa(maxBodyBytes)+" bytes"
+				}
+				writeJSON(w, status, model.OrderResponse{
+					Status: model.StatusError,
+					Error:  &model.ErrorPayload{Kind: kind, Message: msg},
+				})
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		if msg := g.check(clientAddr(r), r.Header.Get(HeaderNonce), r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body); msg != "" {
+			g.rejected.Add(1)
+			status := http.StatusUnauthorized
+			switch msg {
+			case msgClientFull:
+				status = http.StatusTooManyRequests
+				w.Header().Set("Retry-After", "1")
+			case msgCacheFull:
+				status = http.StatusServiceUnavailable
+				w.Header().Set("Retry-After", "1")
+			}
+			writeJSON(w, status, model.OrderResponse{
+				Status: model.StatusError,
+				Error:  &model.ErrorPayload{Kind: "replay_rejected", Message: msg},
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// check validates the nonce, timestamp and, with a secret, the
+// signature over body, and records the nonce against client. It returns
+// an empty string on success, or a rejection reason.
+func (g *ReplayGuard) check(client, nonce, timestamp, signature string, body []byte) string {
+	if nonce == "" {
+		return "missing " + HeaderNonce
+	}
+	if len(nonce) > 128 {
+		return "nonce too long"
+	}
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "invalid " + HeaderTimestamp
+	}
+
+	now := g.now()
+	ts := time.Unix(sec, 0)
+	if ts.Before(now.Add(-g.skew)) || ts.After(now.Add(g.skew)) {
+		return "timestamp outside allowed skew"
+	}
+	if len(g.secret) > 0 && !hmac.Equal([]byte(signature), []byte(SignRequest(g.secret, nonce, timestamp, body))) {
+		return "invalid " + HeaderSignature
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.evict(now)
+	if _, ok := g.seen[nonce]; ok {
+		return "nonce already used"
+	}
+	if g.clients[client] >= g.maxPerClient {
+		return msgClientFull
+	}
+	if len(g.seen) >= g.maxNonces {
+		return msgCacheFull
+	}
+	expires := now.Add(g.window)
+	g.seen[nonce] = expires
+	g.clients[client]++
+	g.queue = append(g.queue, seenNonce{nonce: nonce, client: client, expires: expires})
+	return ""
+}
+
+// evict drops nonces whose window has elapsed. The caller must hold g.mu.
+func (g *ReplayGuard) evict(now time.Time) {
+	n := 0
+	for n < len(g.queue) && !g.queue[n].expires.After(now) {
+		e := g.queue[n]
+		delete(g.seen, e.nonce)
+		if g.clients[e.client]--; g.clients[e.client] <= 0 {
+			delete(g.clients, e.client)
+		}
+		n++
+	}
+	if n > 0 {
+		g.queue = append(g.queue[:0], g.queue[n:]...)
+	}
+}
+
+// clientAddr returns the client address netacl resolved for r, or the
+// host of its peer address.
+func clientAddr(r *http.Request) string {
+	if ip, ok := netacl.FromContext(r.Context()); ok {
+		return ip.String()
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,269 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestNewReplayGuardWindow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		skew       time.Duration
+		window     time.Duration
+		wantSkew   time.Duration
+		wantWindow time.Duration
+	}{
+		{name: "defaults", skew: 0, window: 0, wantSkew: 30 * time.Second, wantWindow: time.Minute},
+		{name: "window_raised", skew: 10 * time.Second, window: 5 * time.Second, wantSkew: 10 * time.Second, wantWindow: 20 * time.Second},
+		{name: "window_kept", skew: 10 * time.Second, window: time.Minute, wantSkew: 10 * time.Second, wantWindow: time.Minute},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			g := NewReplayGuard(tt.skew, tt.window, 0, 0, nil)
+			if g.skew != tt.wantSkew || g.window != tt.wantWindow {
+				t.Fatalf("expected skew=%v window=%v, got skew=%v window=%v",
+					tt.wantSkew, tt.wantWindow, g.skew, g.window)
+			}
+		})
+	}
+}
+
+func TestReplayGuardMiddleware(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	tests := []struct {
+		name       string
+		nonce      string
+		timestamp  string
+		wantStatus int
+	}{
+		{name: "accepted", nonce: "n-1", timestamp: ts, wantStatus: http.StatusOK},
+		{name: "replayed", nonce: "n-1", timestamp: ts, wantStatus: http.StatusUnauthorized},
+		{name: "missing_nonce", nonce: "", timestamp: ts, wantStatus: http.StatusUnauthorized},
+		{name: "invalid_timestamp", nonce: "n-2", timestamp: "soon", wantStatus: http.StatusUnauthorized},
+		{name: "stale_timestamp", nonce: "n-3", timestamp: strconv.FormatInt(now.Add(-time.Minute).Unix(), 10), wantStatus: http.StatusUnauthorized},
+		{name: "future_timestamp", nonce: "n-4", timestamp: strconv.FormatInt(now.Add(time.Minute).Unix(), 10), wantStatus: http.StatusUnauthorized},
+		{name: "within_skew", nonce: "n-5", timestamp: strconv.FormatInt(now.Add(-5*time.Second).Unix(), 10), wantStatus: http.StatusOK},
+	}
+
+	g := NewReplayGuard(10*time.Second, 0, 0, 0, nil)
+	g.now = func() time.Time { return now }
+	h := g.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Subtests share the guard's nonce cache, so they run sequentially.
+	wantRejected := int64(0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/order", nil)
+			req.Header.Set(HeaderNonce, tt.nonce)
+			req.Header.Set(HeaderTimestamp, tt.timestamp)
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus == http.StatusOK {
+				return
+			}
+			wantRejected++
+
+			var out model.OrderResponse
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if out.Error == nil || out.Error.Kind != "replay_rejected" {
+				t.Fatalf("expected error.kind=replay_rejected, got %+v", out.Error)
+			}
+		})
+	}
+
+	if got := g.Rejected(); got != wantRejected {
+		t.Fatalf("expected %d rejected, got %d", wantRejected, got)
+	}
+}
+
+// A nonce is forgotten once the window slides past it.
+func TestReplayGuardEviction(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	g := NewReplayGuard(10*time.Second, 20*time.Second, 0, 0, nil)
+	g.now = func() time.Time { return now }
+
+	if msg := g.check("c-1", "n-1", strconv.FormatInt(now.Unix(), 10), "", nil); msg != "" {
+		t.Fatalf("unexpected rejection: %s", msg)
+	}
+
+	now = now.Add(21 * time.Second)
+	if msg := g.check("c-1", "n-2", strconv.FormatInt(now.Unix(), 10), "", nil); msg != "" {
+		t.Fatalf("unexpected rejection: %s", msg)
+	}
+	if _, ok := g.seen["n-1"]; ok {
+		t.Fatal("expected n-1 to be evicted")
+	}
+	if len(g.queue) != 1 {
+		t.Fatalf("expected 1 queued nonce, got %d", len(g.queue))
+	}
+}
+
+// A full cache refuses new nonces from every client instead of
+// forgetting old ones.
+func TestReplayGuardCacheFull(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	g := NewReplayGuard(10*time.Second, 20*time.Second, 2, 2, nil)
+	g.now = func() time.Time { return now }
+
+	for _, nonce := range []string{"n-1", "n-2"} {
+		if msg := g.check("c-"+nonce, nonce, ts, "", nil); msg != "" {
+			t.Fatalf("unexpected rejection of %s: %s", nonce, msg)
+		}
+	}
+	if msg := g.check("c-2", "n-1", ts, "", nil); msg != "nonce already used" {
+		t.Fatalf("expected n-1 to be rejected as replayed, got %q", msg)
+	}
+	if msg := g.check("c-3", "n-3", ts, "", nil); msg != msgCacheFull {
+		t.Fatalf("expected a full cache, got %q", msg)
+	}
+
+	h := g.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	req := httptest.NewRequest(http.MethodPost, "/order", nil)
+	req.Header.Set(HeaderNonce, "n-3")
+	req.Header.Set(HeaderTimestamp, ts)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 503 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+
+	now = now.Add(21 * time.Second)
+	ts = strconv.FormatInt(now.Unix(), 10)
+	if msg := g.check("c-3", "n-3", ts, "", nil); msg != "" {
+		t.Fatalf("expected room once nonces expire, got %q", msg)
+	}
+}
+
+// A client over its share of the cache is refused without affecting
+// other clients, until its nonces expire.
+func TestReplayGuardClientShare(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	g := NewReplayGuard(10*time.Second, 20*time.Second, 10, 2, nil)
+	g.now = func() time.Time { return now }
+	h := g.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func(addr, nonce string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/order", nil)
+		req.RemoteAddr = addr
+		req.Header.Set(HeaderNonce, nonce)
+		req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, nonce := range []string{"a-1", "a-2"} {
+		if w := send("192.0.2.1:1000", nonce); w.Code != http.StatusOK {
+			t.Fatalf("expected %s accepted, got %d", nonce, w.Code)
+		}
+	}
+	if w := send("192.0.2.1:1001", "a-3"); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+	if w := send("192.0.2.2:1000", "b-1"); w.Code != http.StatusOK {
+		t.Fatalf("expected another client accepted, got %d", w.Code)
+	}
+
+	now = now.Add(21 * time.Second)
+	if w := send("192.0.2.1:1000", "a-3"); w.Code != http.StatusOK {
+		t.Fatalf("expected room once the client's nonces expire, got %d", w.Code)
+	}
+	if got := g.clients["192.0.2.1"]; got != 1 {
+		t.Fatalf("expected 1 cached nonce for the client, got %d", got)
+	}
+	if _, ok := g.clients["192.0.2.2"]; ok {
+		t.Fatal("expected the idle client to be forgotten")
+	}
+}
+
+// With a secret, the signature binds the nonce and timestamp to the
+// body, and requests failing it are not remembered.
+func TestReplayGuardSignature(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	secret := []byte("s3cret")
+	body := `{"order_id":"o-1","amount":10}`
+
+	tests := []struct {
+		name       string
+		nonce      string
+		signature  string
+		body       string
+		wantStatus int
+	}{
+		{name: "missing", nonce: "n-1", body: body, wantStatus: http.StatusUnauthorized},
+		{name: "other_body", nonce: "n-1", signature: SignRequest(secret, "n-1", ts, []byte(`{"order_id":"o-1","amount":1}`)), body: body, wantStatus: http.StatusUnauthorized},
+		{name: "other_secret", nonce: "n-1", signature: SignRequest([]byte("guess"), "n-1", ts, []byte(body)), body: body, wantStatus: http.StatusUnauthorized},
+		{name: "other_nonce", nonce: "n-1", signature: SignRequest(secret, "n-2", ts, []byte(body)), body: body, wantStatus: http.StatusUnauthorized},
+		{name: "signed", nonce: "n-1", signature: SignRequest(secret, "n-1", ts, []byte(body)), body: body, wantStatus: http.StatusOK},
+		{name: "replayed", nonce: "n-1", signature: SignRequest(secret, "n-1", ts, []byte(body)), body: body, wantStatus: http.StatusUnauthorized},
+	}
+
+	g := NewReplayGuard(10*time.Second, 0, 0, 0, secret)
+	g.now = func() time.Time { return now }
+	var got string
+	h := g.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Subtests share the guard's nonce cache, so they run sequentially.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(tt.body))
+			req.Header.Set(HeaderNonce, tt.nonce)
+			req.Header.Set(HeaderTimestamp, ts)
+			if tt.signature != "" {
+				req.Header.Set(HeaderSignature, tt.signature)
+			}
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus == http.StatusOK && got != tt.body {
+				t.Fatalf("expected the handler to read %q, got %q", tt.body, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package httptransport
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/statuswriter"
+)
+
+// RequestLogger writes one application log record per request.
+//
+// Failed requests (status >= 400) and slow requests (duration at or above
+// the slow threshold) are always logged. Successful fast requests are
+// sampled so that high QPS does not flood the log.
+type RequestLogger struct {
+	logger     *slog.Logger
+	sampleRate float64
+	slow       time.Duration
+	rand       func() float64
+	now        func() time.Time
+}
+
+// NewRequestLogger returns a RequestLogger writing to logger.
+//
+// sampleRate is the fraction of successful requests logged; values
+// outside (0, 1] log every request. A non-positive slow threshold
+// defaults to 1 second. It panics if logger is nil.
+func NewRequestLogger(logger *slog.Logger, sampleRate float64, slow time.Duration) *RequestLogger {
+	if logger == nil {
+		panic("httptransport.NewRequestLogger: nil logger")
+	}
+	if sampleRate <= 0 || sampleRate > 1 {
+		sampleRate = 1
+	}
+	if slow <= 0 {
+		slow = time.Second
+	}
+	return &RequestLogger{
+		logger:     logger,
+		sampleRate: sampleRate,
+		slow:       slow,
+		rand:       rand.Float64,
+		now:        time.Now,
+	}
+}
+
+// Middleware wraps next and logs the outcome of each request.
+func (l *RequestLogger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := l.now()
+		sw := statuswriter.New(w)
+		note := new(requestNote)
+
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), requestNoteKey{}, note)))
+
+		d := l.now().Sub(start)
+		status := sw.Status()
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest, d >= l.slow:
+			level = slog.LevelWarn
+		case l.sampleRate < 1 && l.rand() >= l.sampleRate:
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("duration", d),
+			slog.Bool("slow", d >= l.slow),
+		}
+		if c := note.cancellation; c != nil {
+			cause := []any{slog.String("reason", c.Reason)}
+			if c.Step != "" {
+				cause = append(cause, slog.String("step", c.Step), slog.String("kind", c.Kind))
+			}
+			attrs = append(attrs, slog.Group("cancellation_cause", cause...))
+		}
+		l.logger.LogAttrs(r.Context(), level, "request", attrs...)
+	})
+}
+
+// requestNote carries outcome details from the handler to the request
+// log record.
+type requestNote struct {
+	cancellation *model.CancellationCause
+}
+
+type requestNoteKey struct{}
+
+// noteCancellation records c for the request log, if r is logged.
+func noteCancellation(ctx context.Context, c *model.CancellationCause) {
+	if n, ok := ctx.Value(requestNoteKey{}).(*requestNote); ok {
+		n.cancellation = c
+	}
+}
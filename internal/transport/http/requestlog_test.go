@@ -0,0 +1,89 @@
+package httptransport
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestRequestLoggerMiddleware(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		status    int
+		duration  time.Duration
+		roll      float64
+		wantLevel string // empty means not logged
+	}{
+		{name: "success_sampled_in", status: http.StatusOK, duration: time.Millisecond, roll: 0.05, wantLevel: "INFO"},
+		{name: "success_sampled_out", status: http.StatusOK, duration: time.Millisecond, roll: 0.5},
+		{name: "slow_success", status: http.StatusOK, duration: 2 * time.Second, roll: 0.5, wantLevel: "WARN"},
+		{name: "client_error", status: http.StatusBadRequest, duration: time.Millisecond, roll: 0.5, wantLevel: "WARN"},
+		{name: "server_error", status: http.StatusServiceUnavailable, duration: time.Millisecond, roll: 0.5, wantLevel: "ERROR"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, nil))
+			l := NewRequestLogger(logger, 0.1, time.Second)
+			l.rand = func() float64 { return tt.roll }
+
+			start := time.Unix(1_700_000_000, 0)
+			calls := 0
+			l.now = func() time.Time {
+				calls++
+				if calls == 1 {
+					return start
+				}
+				return start.Add(tt.duration)
+			}
+
+			h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/order", nil))
+
+			out := buf.String()
+			if tt.wantLevel == "" {
+				if out != "" {
+					t.Fatalf("expected no log line, got %q", out)
+				}
+				return
+			}
+			if !strings.Contains(out, "level="+tt.wantLevel) {
+				t.Fatalf("expected level=%s, got %q", tt.wantLevel, out)
+			}
+			if !strings.Contains(out, "path=/order") {
+				t.Fatalf("expected path attribute, got %q", out)
+			}
+		})
+	}
+}
+
+func TestRequestLoggerCancellationCause(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	l := NewRequestLogger(slog.New(slog.NewTextHandler(&buf, nil)), 1, time.Second)
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		noteCancellation(r.Context(), &model.CancellationCause{Reason: model.CauseStepFailed, Step: "vendor", Kind: "vendor_unavailable"})
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/order", nil))
+
+	want := "cancellation_cause.reason=step_failed cancellation_cause.step=vendor cancellation_cause.kind=vendor_unavailable"
+	if out := buf.String(); !strings.Contains(out, want) {
+		t.Fatalf("expected %q in %q", want, out)
+	}
+}
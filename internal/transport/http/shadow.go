@@ -0,0 +1,250 @@
+package httptransport
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/traffic"
+)
+
+// shadowTarget processes a mirrored order and reports its outcome.
+type shadowTarget interface {
+	Shadow(ctx context.Context, req model.OrderRequest) (model.OrderResponse, error)
+}
+
+// HTTPShadow mirrors orders to the POST /order endpoint of another
+// deployment, such as a staging environment or a new pipeline version.
+// Each request carries a fresh nonce and timestamp so the target's
+// replay guard accepts it, and is marked synthetic so the target
+// charges it to the sandbox account; the target must trust the marker
+// from this deployment (-synthetic-from).
+type HTTPShadow struct {
+	URL    string
+	Secret []byte       // signs mirrors for a target with a replay secret; empty sends none
+	Client *http.Client // nil means http.DefaultClient, bounded by the Shadow timeout
+}
+
+// Shadow implements shadowTarget. Any response whose body decodes as an
+// OrderResponse is an outcome, whatever its status code.
+func (s HTTPShadow) Shadow(ctx context.Context, req model.OrderRequest) (model.OrderResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return model.OrderResponse{}, err
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return model.OrderResponse{}, err
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+	nonce := strconv.FormatUint(rand.Uint64(), 16) + strconv.FormatUint(rand.Uint64(), 16)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	hreq.Header.Set(HeaderNonce, nonce)
+	hreq.Header.Set(HeaderTimestamp, ts)
+	if len(s.Secret) > 0 {
+		hreq.Header.Set(HeaderSignature, SignRequest(s.Secret, nonce, ts, body))
+	}
+	traffic.SetHeader(hreq.Header)
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(hreq)
+	if err != nil {
+		return model.OrderResponse{}, err
+	}
+	defer resp.Body.Close()
+	var out model.OrderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || out.Status == "" {
+		return model.OrderResponse{}, fmt.Errorf("shadow: %s: no order response", resp.Status)
+	}
+	return out, nil
+}
+
+// Shadow mirrors a sample of orders to a shadow target after they have
+// been processed, off the response path, and compares the shadow
+// outcome with the primary one, logging and counting divergences by
+// mismatch category. Mirrored orders never affect
+// the client's response: when the target is slow, mirrors beyond the
+// concurrency limit are skipped.
+type Shadow struct {
+	target  shadowTarget
+	rate    float64
+	timeout time.Duration
+	slots   chan struct{}
+	logger  *slog.Logger
+	rand    func() float64
+
+	mirrored, skipped, failed atomic.Int64
+	matched, diverged         atomic.Int64
+
+	mu         sync.Mutex
+	categories map[string]*model.ShadowCategory
+}
+
+// NewShadow returns a Shadow sending the fraction rate of orders to
+// target, each bounded by timeout, with at most concurrency mirrors in
+// flight.
+//
+// A rate outside (0, 1] is treated as 1, a non-positive timeout
+// defaults to 10 seconds and a non-positive concurrency to 16. It
+// panics if target or logger is nil.
+func NewShadow(target shadowTarget, rate float64, timeout time.Duration, concurrency int, logger *slog.Logger) *Shadow {
+	if target == nil {
+		panic("httptransport.NewShadow: nil target")
+	}
+	if logger == nil {
+		panic("httptransport.NewShadow: nil logger")
+	}
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if concurrency <= 0 {
+		concurrency = 16
+	}
+	return &Shadow{
+		target:     target,
+		rate:       rate,
+		timeout:    timeout,
+		slots:      make(chan struct{}, concurrency),
+		logger:     logger,
+		rand:       rand.Float64,
+		categories: make(map[string]*model.ShadowCategory),
+	}
+}
+
+// Wrap returns an orderProcessor that runs p and mirrors sampled orders
+// once p has returned.
+func (s *Shadow) Wrap(p orderProcessor) orderProcessor {
+	if p == nil {
+		panic("httptransport.Shadow.Wrap: nil order processor")
+	}
+	return &shadowProcessor{shadow: s, wrapped: wrapped{p}}
+}
+
+// shadowProcessor is the orderProcessor returned by Shadow.Wrap.
+type shadowProcessor struct {
+	wrapped
+	shadow *Shadow
+}
+
+func (sp *shadowProcessor) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	steps, err := sp.next.Process(ctx, req)
+	s := sp.shadow
+	if s.rand() >= s.rate {
+		return steps, err
+	}
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		s.skipped.Add(1) // the target is falling behind
+		return steps, err
+	}
+
+	want := primaryOutcome(req, steps, err)
+	go func() {
+		defer func() { <-s.slots }()
+		s.mirror(context.WithoutCancel(ctx), req, want)
+	}()
+	return steps, err
+}
+
+// mirror sends req to the target and logs how its outcome differs from
+// want.
+func (s *Shadow) mirror(ctx context.Context, req model.OrderRequest, want model.OrderResponse) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	s.mirrored.Add(1)
+	got, err := s.target.Shadow(ctx, req)
+	if err != nil {
+		s.failed.Add(1)
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "shadow order failed",
+			slog.String("order_id", req.OrderID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	diffs := compareOutcomes(want, got)
+	if len(diffs) == 0 {
+		s.matched.Add(1)
+		return
+	}
+	s.diverged.Add(1)
+	s.note(req.OrderID, diffs)
+	details := make([]string, len(diffs))
+	for i, d := range diffs {
+		details[i] = d.Category + ": " + d.Detail
+	}
+	s.logger.LogAttrs(ctx, slog.LevelWarn, "shadow order diverged",
+		slog.String("order_id", req.OrderID),
+		slog.Any("diffs", details),
+	)
+}
+
+// note counts a divergent outcome once in each of its categories.
+func (s *Shadow) note(orderID string, diffs []model.ShadowMismatch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := make(map[string]bool, len(diffs))
+	for _, d := range diffs {
+		if seen[d.Category] {
+			continue
+		}
+		seen[d.Category] = true
+		c := s.categories[d.Category]
+		if c == nil {
+			c = &model.ShadowCategory{Category: d.Category}
+			s.categories[d.Category] = c
+		}
+		c.Count++
+		c.LastOrderID, c.LastDetail = orderID, d.Detail
+	}
+}
+
+// Report returns the mirror counters and the mismatch categories seen,
+// most frequent first.
+func (s *Shadow) Report() model.ShadowReport {
+	r := model.ShadowReport{
+		Rate:       s.rate,
+		Mirrored:   s.mirrored.Load(),
+		Skipped:    s.skipped.Load(),
+		Failed:     s.failed.Load(),
+		Matched:    s.matched.Load(),
+		Diverged:   s.diverged.Load(),
+		Categories: []model.ShadowCategory{},
+	}
+	s.mu.Lock()
+	for _, c := range s.categories {
+		r.Categories = append(r.Categories, *c)
+	}
+	s.mu.Unlock()
+	slices.SortFunc(r.Categories, func(a, b model.ShadowCategory) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Category, b.Category))
+	})
+	return r
+}
+
+// HandleShadow reports the shadow comparison on GET.
+func (s *Shadow) HandleShadow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Report())
+}
@@ -0,0 +1,216 @@
+package httptransport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/vendor"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/traffic"
+)
+
+// stubShadow answers every mirrored order with resp and records the
+// orders it received.
+type stubShadow struct {
+	resp model.OrderResponse
+	err  error
+
+	mu   sync.Mutex
+	reqs []model.OrderRequest
+}
+
+func (s *stubShadow) Shadow(_ context.Context, req model.OrderRequest) (model.OrderResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reqs = append(s.reqs, req)
+	return s.resp, s.err
+}
+
+func (s *stubShadow) received() []model.OrderRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.reqs)
+}
+
+// syncBuffer is a bytes.Buffer safe for loggers written from mirror
+// goroutines.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+// waitMirrors waits until s has no mirrors in flight.
+func waitMirrors(t *testing.T, s *Shadow) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for len(s.slots) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for mirrors")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestShadowWrap(t *testing.T) {
+	t.Parallel()
+
+	target := &stubShadow{resp: model.OrderResponse{Status: model.StatusOK, Steps: []model.StepResult{{Name: "vendor", Status: model.StatusOK}}}}
+	logs := &syncBuffer{}
+	s := NewShadow(target, 0.5, time.Second, 4, slog.New(slog.NewTextHandler(logs, nil)))
+	draws := []float64{0.2, 0.7}
+	var mu sync.Mutex
+	s.rand = func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		d := draws[0]
+		draws = draws[1:]
+		return d
+	}
+	p := s.Wrap(&stubProcessor{steps: []model.StepResult{{Name: "vendor", Status: model.StatusError}}, err: vendor.ErrUnavailable})
+
+	for _, id := range []string{"o-mirrored", "o-skipped"} {
+		if _, err := p.Process(context.Background(), model.OrderRequest{OrderID: id}); !errors.Is(err, vendor.ErrUnavailable) {
+			t.Fatalf("expected the primary error, got %v", err)
+		}
+	}
+	waitMirrors(t, s)
+
+	if reqs := target.received(); len(reqs) != 1 || reqs[0].OrderID != "o-mirrored" {
+		t.Fatalf("expected only o-mirrored to be mirrored, got %+v", reqs)
+	}
+	if out := logs.String(); !strings.Contains(out, "shadow order diverged") || !strings.Contains(out, "step_status: step vendor: error != ok") {
+		t.Fatalf("expected a divergence log, got %q", out)
+	}
+	r := s.Report()
+	if r.Mirrored != 1 || r.Diverged != 1 || len(r.Categories) != 3 {
+		t.Fatalf("expected one divergence in three categories, got %+v", r)
+	}
+}
+
+func TestShadowWrap_SkipsWhenFull(t *testing.T) {
+	t.Parallel()
+
+	target := &stubShadow{resp: model.OrderResponse{Status: model.StatusOK}}
+	s := NewShadow(target, 1, time.Second, 1, slog.New(slog.NewTextHandler(&syncBuffer{}, nil)))
+	s.slots <- struct{}{} // one mirror already in flight
+	p := s.Wrap(&stubProcessor{})
+
+	if _, err := p.Process(context.Background(), model.OrderRequest{OrderID: "o-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-s.slots
+	waitMirrors(t, s)
+	if reqs := target.received(); len(reqs) != 0 {
+		t.Fatalf("expected no mirror, got %+v", reqs)
+	}
+	if r := s.Report(); r.Skipped != 1 || r.Mirrored != 0 {
+		t.Fatalf("expected one skipped mirror, got %+v", r)
+	}
+}
+
+func TestHTTPShadow(t *testing.T) {
+	t.Parallel()
+
+	trusted := func(*http.Request) bool { return true }
+	srv := httptest.NewServer(traffic.Middleware(trusted, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderNonce) == "" || r.Header.Get(HeaderTimestamp) == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if traffic.FromContext(r.Context()) != traffic.Synthetic {
+			w.WriteHeader(http.StatusPaymentRequired) // a live charge
+			return
+		}
+		var req model.OrderRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		writeJSON(w, http.StatusServiceUnavailable, model.OrderResponse{
+			Status: model.StatusError, OrderID: req.OrderID, Error: &model.ErrorPayload{Kind: "vendor_unavailable"},
+		})
+	})))
+	defer srv.Close()
+
+	got, err := HTTPShadow{URL: srv.URL}.Shadow(context.Background(), model.OrderRequest{OrderID: "o-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.OrderID != "o-1" || got.Status != model.StatusError || payloadKind(got.Error) != "vendor_unavailable" {
+		t.Fatalf("unexpected response %+v", got)
+	}
+}
+
+// A mirror signed with the target's replay secret passes its guard.
+func TestHTTPShadow_Secret(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("s3cret")
+	guard := NewReplayGuard(0, 0, 0, 0, secret)
+	srv := httptest.NewServer(guard.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req model.OrderRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		writeJSON(w, http.StatusOK, model.OrderResponse{Status: model.StatusOK, OrderID: req.OrderID})
+	})))
+	defer srv.Close()
+
+	got, err := HTTPShadow{URL: srv.URL, Secret: secret}.Shadow(context.Background(), model.OrderRequest{OrderID: "o-1"})
+	if err != nil || got.Status != model.StatusOK || got.OrderID != "o-1" {
+		t.Fatalf("expected the signed mirror accepted, got %+v, %v", got, err)
+	}
+	got, err = HTTPShadow{URL: srv.URL}.Shadow(context.Background(), model.OrderRequest{OrderID: "o-2"})
+	if err != nil || payloadKind(got.Error) != "replay_rejected" {
+		t.Fatalf("expected the unsigned mirror rejected, got %+v, %v", got, err)
+	}
+}
+
+func TestShadowReport(t *testing.T) {
+	t.Parallel()
+
+	s := NewShadow(&stubShadow{}, 1, time.Second, 1, slog.New(slog.NewTextHandler(&syncBuffer{}, nil)))
+	s.note("o-1", []model.ShadowMismatch{
+		{Category: model.MismatchStepStatus, Detail: "step vendor: ok != error"},
+		{Category: model.MismatchStepStatus, Detail: "step courier: ok != canceled"},
+		{Category: model.MismatchStatus, Detail: "ok != error"},
+	})
+	s.note("o-2", []model.ShadowMismatch{{Category: model.MismatchStepStatus, Detail: "step vendor: ok != error"}})
+
+	want := []model.ShadowCategory{
+		{Category: model.MismatchStepStatus, Count: 2, LastOrderID: "o-2", LastDetail: "step vendor: ok != error"},
+		{Category: model.MismatchStatus, Count: 1, LastOrderID: "o-1", LastDetail: "ok != error"},
+	}
+	if got := s.Report().Categories; !slices.Equal(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	w := httptest.NewRecorder()
+	s.HandleShadow(w, httptest.NewRequest(http.MethodGet, "/admin/shadow", nil))
+	var out model.ShadowReport
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil || w.Code != http.StatusOK || len(out.Categories) != 2 {
+		t.Fatalf("expected the report, got %d %+v (%v)", w.Code, out, err)
+	}
+	w = httptest.NewRecorder()
+	s.HandleShadow(w, httptest.NewRequest(http.MethodPost, "/admin/shadow", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,108 @@
+package httptransport
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// primaryOutcome builds the outcome the handler reports for a processed
+// order, without the parts that depend on the request, so it can be
+// compared with a shadow's. The steps are copied: the caller's slice
+// may be pooled.
+func primaryOutcome(req model.OrderRequest, steps []model.StepResult, err error) model.OrderResponse {
+	errs := splitErrors(err)
+	primary := mostSevere(errs)
+	out := model.OrderResponse{Status: orderStatus(steps, primary), OrderID: req.OrderID, Steps: slices.Clone(steps)}
+	if primary != nil {
+		out.Error = &model.ErrorPayload{Kind: errorKind(primary)}
+	}
+	if len(errs) > 1 {
+		for _, e := range errs {
+			out.Errors = append(out.Errors, model.ErrorPayload{Kind: errorKind(e), Step: stepName(e)})
+		}
+	}
+	return out
+}
+
+// normalizeOutcome strips what legitimately differs between two runs of
+// one order: the order ID, step durations and experiment variants, and
+// error messages. Steps and errors are sorted so order does not matter.
+func normalizeOutcome(resp model.OrderResponse) model.OrderResponse {
+	out := model.OrderResponse{Status: resp.Status}
+	if resp.Error != nil {
+		out.Error = &model.ErrorPayload{Kind: resp.Error.Kind}
+	}
+	for _, st := range resp.Steps {
+		out.Steps = append(out.Steps, model.StepResult{Name: st.Name, Status: st.Status, Detail: st.Detail})
+	}
+	slices.SortFunc(out.Steps, func(a, b model.StepResult) int { return cmp.Compare(a.Name, b.Name) })
+	for _, e := range resp.Errors {
+		out.Errors = append(out.Errors, model.ErrorPayload{Kind: e.Kind, Step: e.Step})
+	}
+	slices.SortFunc(out.Errors, func(a, b model.ErrorPayload) int {
+		return cmp.Or(cmp.Compare(a.Step, b.Step), cmp.Compare(a.Kind, b.Kind))
+	})
+	return out
+}
+
+// compareOutcomes normalizes primary and shadow and lists their
+// differences, each in one of the model.Mismatch* categories.
+func compareOutcomes(primary, shadow model.OrderResponse) []model.ShadowMismatch {
+	p, s := normalizeOutcome(primary), normalizeOutcome(shadow)
+	var diffs []model.ShadowMismatch
+	add := func(category, format string, args ...any) {
+		diffs = append(diffs, model.ShadowMismatch{Category: category, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	if p.Status != s.Status {
+		add(model.MismatchStatus, "%s != %s", p.Status, s.Status)
+	}
+	if pk, sk := payloadKind(p.Error), payloadKind(s.Error); pk != sk {
+		add(model.MismatchErrorKind, "%q != %q", pk, sk)
+	}
+	if pe, se := errorList(p.Errors), errorList(s.Errors); pe != se {
+		add(model.MismatchErrors, "%s != %s", pe, se)
+	}
+
+	// Both step lists are sorted by name; walk them together.
+	i, j := 0, 0
+	for i < len(p.Steps) || j < len(s.Steps) {
+		switch {
+		case j == len(s.Steps) || (i < len(p.Steps) && p.Steps[i].Name < s.Steps[j].Name):
+			add(model.MismatchStepMissing, "step %s", p.Steps[i].Name)
+			i++
+		case i == len(p.Steps) || s.Steps[j].Name < p.Steps[i].Name:
+			add(model.MismatchStepExtra, "step %s", s.Steps[j].Name)
+			j++
+		default:
+			ps, ss := p.Steps[i], s.Steps[j]
+			if ps.Status != ss.Status {
+				add(model.MismatchStepStatus, "step %s: %s != %s", ps.Name, ps.Status, ss.Status)
+			} else if ps.Detail != ss.Detail {
+				add(model.MismatchStepDetail, "step %s: %q != %q", ps.Name, ps.Detail, ss.Detail)
+			}
+			i++
+			j++
+		}
+	}
+	return diffs
+}
+
+func payloadKind(e *model.ErrorPayload) string {
+	if e == nil {
+		return ""
+	}
+	return e.Kind
+}
+
+// errorList formats normalized errors as [step:kind ...].
+func errorList(errs []model.ErrorPayload) string {
+	out := make([]string, len(errs))
+	for i, e := range errs {
+		out[i] = e.Step + ":" + e.Kind
+	}
+	return fmt.Sprint(out)
+}
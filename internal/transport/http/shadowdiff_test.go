@@ -0,0 +1,98 @@
+package httptransport
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/service/vendor"
+)
+
+func TestCompareOutcomes(t *testing.T) {
+	t.Parallel()
+
+	ok := model.OrderResponse{Status: model.StatusOK, OrderID: "o-1", Steps: []model.StepResult{
+		{Name: "payment", Status: model.StatusOK, DurationMS: 150},
+		{Name: "vendor", Status: model.StatusOK, DurationMS: 200, Variant: "a"},
+	}}
+
+	tests := []struct {
+		name   string
+		shadow model.OrderResponse
+		want   []model.ShadowMismatch
+	}{
+		{
+			name: "same_outcome_other_ids_timings_and_order",
+			shadow: model.OrderResponse{Status: model.StatusOK, OrderID: "o-1-shadow", Steps: []model.StepResult{
+				{Name: "vendor", Status: model.StatusOK, DurationMS: 1, Variant: "b"},
+				{Name: "payment", Status: model.StatusOK, DurationMS: 2},
+			}},
+		},
+		{
+			name: "failed_step",
+			shadow: model.OrderResponse{Status: model.StatusError, Error: &model.ErrorPayload{Kind: "vendor_unavailable", Message: "order failed"}, Steps: []model.StepResult{
+				{Name: "payment", Status: model.StatusCanceled},
+				{Name: "vendor", Status: model.StatusError, Detail: "vendor_unavailable"},
+			}},
+			want: []model.ShadowMismatch{
+				{Category: model.MismatchStatus, Detail: "ok != error"},
+				{Category: model.MismatchErrorKind, Detail: `"" != "vendor_unavailable"`},
+				{Category: model.MismatchStepStatus, Detail: "step payment: ok != canceled"},
+				{Category: model.MismatchStepStatus, Detail: "step vendor: ok != error"},
+			},
+		},
+		{
+			name: "other_steps",
+			shadow: model.OrderResponse{Status: model.StatusOK, Steps: []model.StepResult{
+				{Name: "fraud", Status: model.StatusOK},
+				{Name: "payment", Status: model.StatusOK},
+			}},
+			want: []model.ShadowMismatch{
+				{Category: model.MismatchStepExtra, Detail: "step fraud"},
+				{Category: model.MismatchStepMissing, Detail: "step vendor"},
+			},
+		},
+		{
+			name: "same_status_other_detail",
+			shadow: model.OrderResponse{Status: model.StatusOK, Steps: []model.StepResult{
+				{Name: "payment", Status: model.StatusOK},
+				{Name: "vendor", Status: model.StatusOK, Detail: "hedged"},
+			}},
+			want: []model.ShadowMismatch{{Category: model.MismatchStepDetail, Detail: `step vendor: "" != "hedged"`}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := compareOutcomes(ok, tt.shadow); !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPrimaryOutcome_MultipleErrors(t *testing.T) {
+	t.Parallel()
+
+	steps := []model.StepResult{{Name: "vendor", Status: model.StatusError}, {Name: "courier", Status: model.StatusError}}
+	err := errors.Join(testAppErr{kind: "no_courier"}, vendor.ErrUnavailable)
+	got := primaryOutcome(model.OrderRequest{OrderID: "o-1"}, steps, err)
+	steps[0].Name = "reused" // the outcome keeps its own copy
+
+	if got.Steps[0].Name != "vendor" || payloadKind(got.Error) != "no_courier" || len(got.Errors) != 2 {
+		t.Fatalf("unexpected outcome %+v", got)
+	}
+	shadow := got
+	shadow.Errors = []model.ErrorPayload{got.Errors[1], got.Errors[0]}
+	if diffs := compareOutcomes(got, shadow); len(diffs) != 0 {
+		t.Fatalf("expected error order to be ignored, got %+v", diffs)
+	}
+	shadow.Errors = shadow.Errors[:1]
+	if diffs := compareOutcomes(got, shadow); len(diffs) != 1 || diffs[0].Category != model.MismatchErrors {
+		t.Fatalf("expected an errors mismatch, got %+v", diffs)
+	}
+}
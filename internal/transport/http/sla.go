@@ -0,0 +1,206 @@
+package httptransport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// SLAClass sets the processing deadline and latency objective for one
+// service class, selected by OrderRequest.SLA.
+//
+// Classes differ only in deadline and objective: an express order waits
+// for pool slots in the same queue as any other, and is not served
+// first.
+type SLAClass struct {
+	Name    string
+	Timeout time.Duration // processing deadline; 0 leaves the handler's request timeout, which still applies
+	Target  time.Duration // latency objective; non-positive means Timeout
+}
+
+// SLA applies per-class deadlines to orders and tracks how many
+// complete successfully within their class's latency target.
+type SLA struct {
+	byName  map[string]*slaClass
+	classes []*slaClass // the first is the default
+	now     func() time.Time
+
+	// requestTimeout returns the handler's current request timeout,
+	// which a class with no timeout reports and targets; set by WithSLA
+	requestTimeout func() time.Duration
+}
+
+type slaClass struct {
+	SLAClass
+	deadline error // cancellation cause when Timeout fires
+	requests atomic.Int64
+	met      atomic.Int64
+}
+
+// NewSLA returns an SLA for the given classes. The first class is used
+// for orders with no class. A handler configured WithSLA rejects orders
+// naming an unknown class; other callers get the first class for them.
+//
+// It panics if no classes are given or a name is repeated.
+func NewSLA(classes ...SLAClass) *SLA {
+	if len(classes) == 0 {
+		panic("httptransport.NewSLA: no classes")
+	}
+	s := &SLA{byName: make(map[string]*slaClass, len(classes)), now: time.Now}
+	for _, c := range classes {
+		if _, dup := s.byName[c.Name]; dup {
+			panic("httptransport.NewSLA: duplicate class " + c.Name)
+		}
+		sc := &slaClass{SLAClass: c, deadline: fmt.Errorf("sla class %s deadline of %v exceeded", c.Name, c.Timeout)}
+		s.byName[c.Name] = sc
+		s.classes = append(s.classes, sc)
+	}
+	return s
+}
+
+// ParseSLAClasses parses a comma-separated list of classes given as
+// name=timeout or name=timeout/target, for example
+// "standard=0s/5s,express=3s/1s". The first class is the default. A zero
+// timeout sets no class deadline, leaving the request timeout; a missing
+// target is the timeout.
+func ParseSLAClasses(spec string) ([]SLAClass, error) {
+	var classes []SLAClass
+	seen := map[string]bool{}
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, durations, ok := strings.Cut(entry, "=")
+		timeout, target, hasTarget := strings.Cut(durations, "/")
+		c := SLAClass{Name: name}
+		var err error
+		if ok && validKind(name) {
+			c.Timeout, err = time.ParseDuration(timeout)
+			if err == nil && hasTarget {
+				c.Target, err = time.ParseDuration(target)
+			}
+		}
+		if !ok || !validKind(name) || err != nil || c.Timeout < 0 || c.Target < 0 {
+			return nil, fmt.Errorf("httptransport: sla class %q: want name=timeout[/target]", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("httptransport: sla class %q repeated", name)
+		}
+		seen[name] = true
+		classes = append(classes, c)
+	}
+	if len(classes) == 0 {
+		return nil, fmt.Errorf("httptransport: no sla classes")
+	}
+	return classes, nil
+}
+
+// Known reports whether orders may name class: "" selects the default
+// class, and any other name must be configured.
+func (s *SLA) Known(class string) bool {
+	_, ok := s.byName[class]
+	return ok || class == ""
+}
+
+// list returns the class names, default first, for error messages.
+func (s *SLA) list() string {
+	names := make([]string, len(s.classes))
+	for i, c := range s.classes {
+		names[i] = c.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Wrap returns an orderProcessor that runs p under the order's class
+// deadline and records whether the class target was met.
+func (s *SLA) Wrap(p orderProcessor) orderProcessor {
+	if p == nil {
+		panic("httptransport.SLA.Wrap: nil order processor")
+	}
+	return &slaProcessor{sla: s, wrapped: wrapped{p}}
+}
+
+// limits returns c's timeout and target. A class with no timeout is
+// resolved on every call to the request timeout then in effect, so
+// PUT /admin/timeouts applies to it; a non-positive target is the
+// timeout.
+func (s *SLA) limits(c *slaClass) (timeout, target time.Duration) {
+	timeout, target = c.Timeout, c.Target
+	if timeout == 0 && s.requestTimeout != nil {
+		timeout = s.requestTimeout()
+	}
+	if target <= 0 {
+		target = timeout
+	}
+	return timeout, target
+}
+
+// class returns the class named name, or the default class for orders
+// with no or an unknown class.
+func (s *SLA) class(name string) *slaClass {
+	if c, ok := s.byName[name]; ok {
+		return c
+	}
+	return s.classes[0]
+}
+
+// slaProcessor is the orderProcessor returned by SLA.Wrap.
+type slaProcessor struct {
+	wrapped
+	sla *SLA
+}
+
+func (sp *slaProcessor) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	c := sp.sla.class(req.SLA)
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, c.Timeout, c.deadline)
+		defer cancel()
+	}
+
+	_, target := sp.sla.limits(c)
+	start := sp.sla.now()
+	steps, err := sp.next.Process(ctx, req)
+	c.requests.Add(1)
+	if err == nil && (target <= 0 || sp.sla.now().Sub(start) <= target) {
+		c.met.Add(1)
+	}
+	return steps, err
+}
+
+// Stats reports SLA attainment per class, default class first.
+func (s *SLA) Stats() []model.SLAClassStats {
+	out := make([]model.SLAClassStats, len(s.classes))
+	for i, c := range s.classes {
+		timeout, target := s.limits(c)
+		st := model.SLAClassStats{
+			Class:     c.Name,
+			TimeoutMS: timeout.Milliseconds(),
+			TargetMS:  target.Milliseconds(),
+			Requests:  c.requests.Load(),
+			Met:       c.met.Load(),
+		}
+		if st.Requests > 0 {
+			st.Attainment = float64(st.Met) / float64(st.Requests)
+		}
+		out[i] = st
+	}
+	return out
+}
+
+// HandleSLA serves per-class SLA attainment.
+//
+// The request must be a GET.
+func (s *SLA) HandleSLA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Stats())
+}
@@ -0,0 +1,287 @@
+package httptransport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// deadlineProcessor records the deadline it was called with.
+type deadlineProcessor struct {
+	stubProcessor
+	remaining time.Duration
+}
+
+func (p *deadlineProcessor) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	if d, ok := ctx.Deadline(); ok {
+		p.remaining = time.Until(d)
+	}
+	return p.stubProcessor.Process(ctx, req)
+}
+
+func TestNewSLA_InvalidPanics(t *testing.T) {
+	t.Parallel()
+
+	for name, classes := range map[string][]SLAClass{
+		"none":      nil,
+		"duplicate": {{Name: "standard"}, {Name: "standard"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			NewSLA(classes...)
+		})
+	}
+}
+
+func TestParseSLAClasses(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    []SLAClass
+		wantErr bool
+	}{
+		{
+			name: "default",
+			spec: "standard=0s/5s,express=3s/1s",
+			want: []SLAClass{
+				{Name: "standard", Target: 5 * time.Second},
+				{Name: "express", Timeout: 3 * time.Second, Target: time.Second},
+			},
+		},
+		{name: "no_target", spec: " bulk=1m ", want: []SLAClass{{Name: "bulk", Timeout: time.Minute}}},
+		{name: "empty", spec: " , ", wantErr: true},
+		{name: "missing_timeout", spec: "standard", wantErr: true},
+		{name: "missing_name", spec: "=1s", wantErr: true},
+		{name: "bad_name", spec: "Express=1s", wantErr: true},
+		{name: "bad_timeout", spec: "express=fast", wantErr: true},
+		{name: "bad_target", spec: "express=1s/soon", wantErr: true},
+		{name: "negative", spec: "express=-1s", wantErr: true},
+		{name: "repeated", spec: "express=1s,express=2s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseSLAClasses(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHandleOrder_UnknownSLAClass(t *testing.T) {
+	t.Parallel()
+
+	s := NewSLA(
+		SLAClass{Name: "standard", Timeout: time.Second},
+		SLAClass{Name: "express", Timeout: time.Second},
+	)
+	h := New(&stubProcessor{}, time.Second, WithSLA(s))
+
+	tests := []struct {
+		name       string
+		sla        string
+		wantStatus int
+	}{
+		{name: "default", sla: "", wantStatus: http.StatusOK},
+		{name: "known", sla: "express", wantStatus: http.StatusOK},
+		{name: "unknown", sla: "platinum", wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			body, _ := json.Marshal(model.OrderRequest{OrderID: "o-" + tt.name, Amount: 10, SLA: tt.sla})
+			req := httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			h.HandleOrder(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body)
+			}
+			if tt.wantStatus == http.StatusOK {
+				return
+			}
+			var out model.OrderResponse
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			want := []model.FieldError{{Field: "sla", Message: "must be one of standard, express"}}
+			if out.Error == nil || out.Error.Kind != "invalid_order" || !reflect.DeepEqual(out.Error.Fields, want) {
+				t.Fatalf("unexpected error: %+v", out.Error)
+			}
+		})
+	}
+}
+
+func TestSLAWrap_ClassDeadline(t *testing.T) {
+	t.Parallel()
+
+	s := NewSLA(
+		SLAClass{Name: "standard", Timeout: 10 * time.Second},
+		SLAClass{Name: "express", Timeout: 100 * time.Millisecond},
+	)
+
+	tests := []struct {
+		class string
+		max   time.Duration
+		min   time.Duration
+	}{
+		{class: "express", min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{class: "standard", min: 9 * time.Second, max: 10 * time.Second},
+		{class: "", min: 9 * time.Second, max: 10 * time.Second},
+		{class: "unknown", min: 9 * time.Second, max: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.class, func(t *testing.T) {
+			t.Parallel()
+			p := &deadlineProcessor{}
+			if _, err := s.Wrap(p).Process(context.Background(), model.OrderRequest{OrderID: "o-1", SLA: tt.class}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.remaining < tt.min || p.remaining > tt.max {
+				t.Fatalf("expected deadline in [%v, %v], got %v", tt.min, tt.max, p.remaining)
+			}
+		})
+	}
+}
+
+// A class with no timeout follows the handler's request timeout as it
+// is changed at runtime, rather than the value it had at startup.
+func TestSLA_RequestTimeoutAtRuntime(t *testing.T) {
+	t.Parallel()
+
+	s := NewSLA(SLAClass{Name: "standard"}, SLAClass{Name: "express", Timeout: time.Second})
+	p := &deadlineProcessor{}
+	h := New(s.Wrap(p), 10*time.Second, WithSLA(s))
+	h.SetRequestTimeout(20 * time.Second)
+
+	body, _ := json.Marshal(model.OrderRequest{OrderID: "o-1", Amount: 10})
+	w := httptest.NewRecorder()
+	h.HandleOrder(w, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if p.remaining < 19*time.Second || p.remaining > 20*time.Second {
+		t.Fatalf("expected the 20s request deadline, got %v", p.remaining)
+	}
+
+	stats := s.Stats()
+	if stats[0].TimeoutMS != 20000 || stats[0].TargetMS != 20000 {
+		t.Fatalf("expected standard limits to follow the request timeout, got %+v", stats[0])
+	}
+	if stats[1].TimeoutMS != 1000 || stats[1].TargetMS != 1000 {
+		t.Fatalf("expected express limits unchanged, got %+v", stats[1])
+	}
+}
+
+// causeProcessor waits for its context and reports its cancellation cause.
+type causeProcessor struct{}
+
+func (causeProcessor) Process(ctx context.Context, _ model.OrderRequest) ([]model.StepResult, error) {
+	<-ctx.Done()
+	return nil, context.Cause(ctx)
+}
+
+func TestSLAWrap_DeadlineCause(t *testing.T) {
+	t.Parallel()
+
+	s := NewSLA(SLAClass{Name: "express", Timeout: time.Millisecond})
+	_, err := s.Wrap(causeProcessor{}).Process(context.Background(), model.OrderRequest{OrderID: "o-1"})
+	if want := "sla class express deadline of 1ms exceeded"; err == nil || err.Error() != want {
+		t.Fatalf("expected %q, got %v", want, err)
+	}
+}
+
+func TestSLA_Attainment(t *testing.T) {
+	t.Parallel()
+
+	s := NewSLA(
+		SLAClass{Name: "standard", Timeout: 10 * time.Second, Target: 5 * time.Second},
+		SLAClass{Name: "express", Timeout: 3 * time.Second, Target: time.Second},
+	)
+	ok := &stubProcessor{}
+	failing := &stubProcessor{err: testAppErr{kind: "no_courier"}}
+
+	run := func(p orderProcessor, class string, took time.Duration) {
+		s.now = fakeClock(took) // start and end are one step apart
+		_, _ = s.Wrap(p).Process(context.Background(), model.OrderRequest{OrderID: "o-1", SLA: class})
+	}
+	run(ok, "express", 500*time.Millisecond)     // met
+	run(ok, "express", 2*time.Second)            // too slow
+	run(failing, "express", 10*time.Millisecond) // failed
+	run(ok, "express", time.Second)              // met, at the target
+	run(ok, "standard", 4*time.Second)           // met
+
+	stats := s.Stats()
+	if len(stats) != 2 || stats[0].Class != "standard" || stats[1].Class != "express" {
+		t.Fatalf("unexpected classes: %+v", stats)
+	}
+	express := stats[1]
+	if express.Requests != 4 || express.Met != 2 || express.Attainment != 0.5 {
+		t.Fatalf("unexpected express stats: %+v", express)
+	}
+	if express.TimeoutMS != 3000 || express.TargetMS != 1000 {
+		t.Fatalf("unexpected express limits: %+v", express)
+	}
+	if stats[0].Requests != 1 || stats[0].Attainment != 1 {
+		t.Fatalf("unexpected standard stats: %+v", stats[0])
+	}
+}
+
+func TestHandleSLA(t *testing.T) {
+	t.Parallel()
+
+	s := NewSLA(SLAClass{Name: "standard", Timeout: 2 * time.Second})
+
+	w := httptest.NewRecorder()
+	s.HandleSLA(w, httptest.NewRequest(http.MethodGet, "/admin/sla", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var out []model.SLAClassStats
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out) != 1 || out[0].TargetMS != 2000 || out[0].Attainment != 0 {
+		t.Fatalf("unexpected stats: %+v", out)
+	}
+
+	w = httptest.NewRecorder()
+	s.HandleSLA(w, httptest.NewRequest(http.MethodPost, "/admin/sla", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,211 @@
+package httptransport
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/statuswriter"
+)
+
+// Rolling windows for error-budget burn. The long window is one hour of
+// one-minute buckets; the short window is its last five minutes. A burn
+// rate of 14.4 sustained over both spends 2% of a 30-day budget in an
+// hour. Below sloMinRequests in the short window a route cannot burn
+// fast: on an idle route one slow request is a 100% error rate.
+const (
+	sloBuckets     = 60
+	sloShortWindow = 5
+	fastBurnRate   = 14.4
+	sloMinRequests = 20
+)
+
+// SLOObjective sets the availability and latency objectives of one route.
+type SLOObjective struct {
+	Route        string        // request path, e.g. "/order"
+	Availability float64       // target fraction of good requests, in (0, 1)
+	Latency      time.Duration // requests slower than this are bad; zero disables
+}
+
+// SLO tracks error-budget burn per route and logs an alert when a route
+// burns its budget fast.
+//
+// A request is bad if it fails with a 5xx status or exceeds its route's
+// latency objective. Client errors do not spend the budget.
+type SLO struct {
+	logger *slog.Logger
+	byPath map[string]*sloRoute
+	routes []*sloRoute // configuration order
+	now    func() time.Time
+}
+
+type sloRoute struct {
+	SLOObjective
+
+	mu      sync.Mutex
+	buckets [sloBuckets]sloBucket
+	burning bool // fast-burn alert raised and not yet cleared
+}
+
+// sloBucket counts requests for one minute.
+type sloBucket struct {
+	minute int64 // unix minutes
+	total  int64
+	bad    int64
+}
+
+// NewSLO returns an SLO for the given objectives, alerting through logger.
+//
+// It panics if logger is nil, no objectives are given, a route is
+// repeated, or an availability is outside (0, 1).
+func NewSLO(logger *slog.Logger, objectives ...SLOObjective) *SLO {
+	if logger == nil {
+		panic("httptransport.NewSLO: nil logger")
+	}
+	if len(objectives) == 0 {
+		panic("httptransport.NewSLO: no objectives")
+	}
+	s := &SLO{logger: logger, byPath: make(map[string]*sloRoute, len(objectives)), now: time.Now}
+	for _, o := range objectives {
+		if _, dup := s.byPath[o.Route]; dup {
+			panic("httptransport.NewSLO: duplicate route " + o.Route)
+		}
+		if o.Availability <= 0 || o.Availability >= 1 {
+			panic("httptransport.NewSLO: availability out of range for " + o.Route)
+		}
+		r := &sloRoute{SLOObjective: o}
+		s.byPath[o.Route] = r
+		s.routes = append(s.routes, r)
+	}
+	return s
+}
+
+// Middleware wraps next and records the outcome of requests to routes
+// with an objective.
+func (s *SLO) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := s.byPath[r.URL.Path]
+		if route == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := s.now()
+		sw := statuswriter.New(w)
+		next.ServeHTTP(sw, r)
+		end := s.now()
+
+		bad := sw.Status() >= http.StatusInternalServerError ||
+			(route.Latency > 0 && end.Sub(start) > route.Latency)
+		s.record(r.Context(), route, end, bad)
+	})
+}
+
+// record counts one request and raises or clears the fast-burn alert.
+func (s *SLO) record(ctx context.Context, r *sloRoute, now time.Time, bad bool) {
+	minute := now.Unix() / 60
+
+	r.mu.Lock()
+	b := &r.buckets[minute%sloBuckets]
+	if b.minute != minute {
+		*b = sloBucket{minute: minute}
+	}
+	b.total++
+	if bad {
+		b.bad++
+	}
+	r.mu.Unlock()
+
+	s.evaluate(ctx, r, minute)
+}
+
+// evaluate computes r's burn rates as of minute and raises or clears
+// the fast-burn alert to match, so the alert state never disagrees
+// with what Stats reports.
+func (s *SLO) evaluate(ctx context.Context, r *sloRoute, minute int64) model.SLOStats {
+	r.mu.Lock()
+	st := r.statsLocked(minute)
+	raised := st.FastBurn && !r.burning
+	cleared := !st.FastBurn && r.burning
+	r.burning = st.FastBurn
+	r.mu.Unlock()
+
+	switch {
+	case raised:
+		s.logger.LogAttrs(ctx, slog.LevelError, "SLO fast burn",
+			slog.String("route", r.Route),
+			slog.Float64("short_burn_rate", st.ShortBurnRate),
+			slog.Float64("long_burn_rate", st.LongBurnRate),
+		)
+	case cleared:
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "SLO fast burn cleared",
+			slog.String("route", r.Route),
+			slog.Float64("short_burn_rate", st.ShortBurnRate),
+			slog.Float64("long_burn_rate", st.LongBurnRate),
+		)
+	}
+	return st
+}
+
+// statsLocked computes burn rates as of minute. r.mu must be held.
+func (r *sloRoute) statsLocked(minute int64) model.SLOStats {
+	var short, long sloBucket
+	for _, b := range r.buckets {
+		age := minute - b.minute
+		if age < 0 || age >= sloBuckets {
+			continue
+		}
+		long.total += b.total
+		long.bad += b.bad
+		if age < sloShortWindow {
+			short.total += b.total
+			short.bad += b.bad
+		}
+	}
+	st := model.SLOStats{
+		Route:         r.Route,
+		Availability:  r.Availability,
+		LatencyMS:     r.Latency.Milliseconds(),
+		Requests:      long.total,
+		Bad:           long.bad,
+		ShortBurnRate: r.burn(short),
+		LongBurnRate:  r.burn(long),
+	}
+	st.FastBurn = short.total >= sloMinRequests &&
+		st.ShortBurnRate >= fastBurnRate && st.LongBurnRate >= fastBurnRate
+	return st
+}
+
+// burn returns the error rate in b as a multiple of the error budget.
+func (r *sloRoute) burn(b sloBucket) float64 {
+	if b.total == 0 {
+		return 0
+	}
+	return float64(b.bad) / float64(b.total) / (1 - r.Availability)
+}
+
+// Stats reports burn rates per route, in configuration order. A
+// fast-burn alert that has cleared since the last request is logged as
+// cleared here.
+func (s *SLO) Stats() []model.SLOStats {
+	minute := s.now().Unix() / 60
+	out := make([]model.SLOStats, len(s.routes))
+	for i, r := range s.routes {
+		out[i] = s.evaluate(context.Background(), r, minute)
+	}
+	return out
+}
+
+// HandleSLO serves per-route error-budget burn.
+//
+// The request must be a GET.
+func (s *SLO) HandleSLO(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Stats())
+}
@@ -0,0 +1,225 @@
+package httptransport
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestNewSLO_InvalidPanics(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.DiscardHandler)
+	for name, tc := range map[string]struct {
+		logger     *slog.Logger
+		objectives []SLOObjective
+	}{
+		"nil_logger":   {objectives: []SLOObjective{{Route: "/order", Availability: 0.99}}},
+		"none":         {logger: logger},
+		"duplicate":    {logger: logger, objectives: []SLOObjective{{Route: "/order", Availability: 0.99}, {Route: "/order", Availability: 0.9}}},
+		"availability": {logger: logger, objectives: []SLOObjective{{Route: "/order", Availability: 1}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			NewSLO(tc.logger, tc.objectives...)
+		})
+	}
+}
+
+func TestSLOMiddleware_Classification(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		path     string
+		status   int
+		took     time.Duration
+		wantReqs int64
+		wantBad  int64
+	}{
+		{name: "good", path: "/order", status: http.StatusOK, took: 100 * time.Millisecond, wantReqs: 1},
+		{name: "client_error", path: "/order", status: http.StatusBadRequest, took: time.Millisecond, wantReqs: 1},
+		{name: "server_error", path: "/order", status: http.StatusServiceUnavailable, took: time.Millisecond, wantReqs: 1, wantBad: 1},
+		{name: "too_slow", path: "/order", status: http.StatusOK, took: 2 * time.Second, wantReqs: 1, wantBad: 1},
+		{name: "untracked_route", path: "/capacity", status: http.StatusInternalServerError, took: time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := NewSLO(slog.New(slog.DiscardHandler), SLOObjective{Route: "/order", Availability: 0.99, Latency: time.Second})
+			s.now = fakeClock(tt.took)
+
+			h := s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, tt.path, nil))
+
+			st := s.Stats()[0]
+			if st.Requests != tt.wantReqs || st.Bad != tt.wantBad {
+				t.Fatalf("expected requests=%d bad=%d, got %d/%d", tt.wantReqs, tt.wantBad, st.Requests, st.Bad)
+			}
+		})
+	}
+}
+
+func TestSLO_FastBurnAlert(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	s := NewSLO(slog.New(slog.NewTextHandler(&buf, nil)), SLOObjective{Route: "/order", Availability: 0.99})
+	route := s.routes[0]
+	now := time.Unix(1_700_000_000, 0).Truncate(time.Minute)
+	s.now = func() time.Time { return now }
+	ctx := t.Context()
+
+	// An hour of healthy traffic: 100 good requests per minute.
+	for m := 0; m < 60; m++ {
+		for i := 0; i < 100; i++ {
+			s.record(ctx, route, now, false)
+		}
+		now = now.Add(time.Minute)
+	}
+	// The clock is now one minute past the last bucket, so the first
+	// minute has left the window.
+	if st := s.Stats()[0]; st.Requests != 5900 || st.LongBurnRate != 0 {
+		t.Fatalf("expected 5900 good requests in the window, got %+v", st)
+	}
+
+	// A burst of failures: the short window burns at once, the long
+	// window only after enough of the hour has gone bad.
+	for m := 0; m < 20; m++ {
+		for i := 0; i < 100; i++ {
+			s.record(ctx, route, now, true)
+		}
+		now = now.Add(time.Minute)
+		if m == 0 && strings.Contains(buf.String(), "SLO fast burn") {
+			t.Fatalf("expected no alert after one bad minute, got %q", buf.String())
+		}
+	}
+	st := s.Stats()[0]
+	if !st.FastBurn || st.ShortBurnRate < fastBurnRate || st.LongBurnRate < fastBurnRate {
+		t.Fatalf("expected fast burn, got %+v", st)
+	}
+	if n := strings.Count(buf.String(), `msg="SLO fast burn"`); n != 1 {
+		t.Fatalf("expected one fast-burn alert, got %d in %q", n, buf.String())
+	}
+
+	// Recovery: good traffic drains the short window and clears the alert.
+	for m := 0; m < sloShortWindow+1; m++ {
+		for i := 0; i < 100; i++ {
+			s.record(ctx, route, now, false)
+		}
+		now = now.Add(time.Minute)
+	}
+	if !strings.Contains(buf.String(), `msg="SLO fast burn cleared"`) {
+		t.Fatalf("expected cleared alert, got %q", buf.String())
+	}
+	if s.Stats()[0].FastBurn {
+		t.Fatal("expected fast burn to be cleared")
+	}
+
+	// Burn again, then stop sending requests: the alert clears once the
+	// failures leave the short window, without a later bad request.
+	for i := 0; i < 200; i++ {
+		s.record(ctx, route, now, true)
+	}
+	if n := strings.Count(buf.String(), `msg="SLO fast burn"`); n != 2 {
+		t.Fatalf("expected a second fast-burn alert, got %d in %q", n, buf.String())
+	}
+	now = now.Add(sloShortWindow * time.Minute)
+	if s.Stats()[0].FastBurn || route.burning {
+		t.Fatalf("expected fast burn cleared in Stats and the alert state, burning=%v", route.burning)
+	}
+	if n := strings.Count(buf.String(), `msg="SLO fast burn cleared"`); n != 2 {
+		t.Fatalf("expected a second cleared alert, got %d in %q", n, buf.String())
+	}
+}
+
+// On a route with little traffic, a few bad requests burn at a high
+// rate but do not page.
+func TestSLO_LowTraffic(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	s := NewSLO(slog.New(slog.NewTextHandler(&buf, nil)), SLOObjective{Route: "/order", Availability: 0.999, Latency: time.Second})
+	route := s.routes[0]
+	now := time.Unix(1_700_000_000, 0)
+	s.now = func() time.Time { return now }
+
+	for i := 0; i < sloMinRequests-1; i++ {
+		s.record(t.Context(), route, now, true)
+	}
+	st := s.Stats()[0]
+	if st.FastBurn || st.ShortBurnRate < fastBurnRate || st.LongBurnRate < fastBurnRate {
+		t.Fatalf("expected high burn rates without fast burn, got %+v", st)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected no alert, got %q", buf.String())
+	}
+
+	s.record(t.Context(), route, now, true)
+	if !s.Stats()[0].FastBurn || !strings.Contains(buf.String(), `msg="SLO fast burn"`) {
+		t.Fatalf("expected fast burn at %d requests, got %q", sloMinRequests, buf.String())
+	}
+}
+
+func TestSLO_WindowExpiry(t *testing.T) {
+	t.Parallel()
+
+	s := NewSLO(slog.New(slog.DiscardHandler), SLOObjective{Route: "/order", Availability: 0.5})
+	route := s.routes[0]
+	now := time.Unix(1_700_000_000, 0)
+	s.now = func() time.Time { return now }
+
+	s.record(t.Context(), route, now, true)
+	now = now.Add(10 * time.Minute)
+	st := s.Stats()[0]
+	if st.Bad != 1 || st.ShortBurnRate != 0 || st.LongBurnRate != 2 {
+		t.Fatalf("expected the failure in the long window only, got %+v", st)
+	}
+
+	now = now.Add(time.Hour)
+	if st := s.Stats()[0]; st.Requests != 0 || st.LongBurnRate != 0 {
+		t.Fatalf("expected an empty window after an hour, got %+v", st)
+	}
+}
+
+func TestHandleSLO(t *testing.T) {
+	t.Parallel()
+
+	s := NewSLO(slog.New(slog.DiscardHandler), SLOObjective{Route: "/order", Availability: 0.999, Latency: time.Second})
+
+	w := httptest.NewRecorder()
+	s.HandleSLO(w, httptest.NewRequest(http.MethodGet, "/admin/slo", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var out []model.SLOStats
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out) != 1 || out[0].Route != "/order" || out[0].Availability != 0.999 || out[0].LatencyMS != 1000 {
+		t.Fatalf("unexpected stats: %+v", out)
+	}
+
+	w = httptest.NewRecorder()
+	s.HandleSLO(w, httptest.NewRequest(http.MethodPost, "/admin/slo", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
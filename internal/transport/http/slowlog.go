@@ -0,0 +1,151 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// SlowLog keeps a fixed-size ring of diagnostic records for orders whose
+// processing took at least a threshold.
+type SlowLog struct {
+	threshold time.Duration
+	src       capacitySource // optional
+	now       func() time.Time
+
+	mu   sync.Mutex
+	ring []slowEntry
+	seq  uint64 // sequence number of the latest record
+	next int
+	full bool
+}
+
+// slowEntry is a record with its sequence number, the stable key by
+// which HandleSlowLog pages through the ring.
+type slowEntry struct {
+	seq uint64
+	rec model.SlowRequest
+}
+
+// NewSlowLog returns a SlowLog holding up to size records.
+//
+// A non-positive threshold defaults to 1 second and a non-positive size
+// to 100. src, if non-nil, supplies pool occupancy for each record.
+func NewSlowLog(threshold time.Duration, size int, src capacitySource) *SlowLog {
+	if threshold <= 0 {
+		threshold = time.Second
+	}
+	if size <= 0 {
+		size = 100
+	}
+	return &SlowLog{
+		threshold: threshold,
+		src:       src,
+		now:       time.Now,
+		ring:      make([]slowEntry, size),
+	}
+}
+
+// Wrap returns an orderProcessor that delegates to p and captures a
+// record whenever Process takes at least the threshold.
+func (s *SlowLog) Wrap(p orderProcessor) orderProcessor {
+	if p == nil {
+		panic("httptransport.SlowLog.Wrap: nil order processor")
+	}
+	return &slowProcessor{log: s, wrapped: wrapped{p}}
+}
+
+// slowProcessor is the orderProcessor returned by SlowLog.Wrap.
+type slowProcessor struct {
+	wrapped
+	log *SlowLog
+}
+
+func (sp *slowProcessor) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	start := sp.log.now()
+	steps, err := sp.next.Process(ctx, req)
+	if d := sp.log.now().Sub(start); d >= sp.log.threshold {
+		sp.log.record(start, req, steps, err, d) // copies steps
+	}
+	return steps, err
+}
+
+// record captures a diagnostic record into the ring.
+func (s *SlowLog) record(start time.Time, req model.OrderRequest, steps []model.StepResult, err error, d time.Duration) {
+	rec := model.SlowRequest{
+		Time:       start.UTC().Format(time.RFC3339Nano),
+		OrderID:    req.OrderID,
+		DurationMS: d.Milliseconds(),
+		ErrorKind:  errorKind(err),
+		Steps:      slices.Clone(steps),
+		Goroutines: runtime.NumGoroutine(),
+	}
+	if s.src != nil {
+		rec.PoolCapacity = s.src.Cap()
+		rec.PoolInUse = s.src.InUse()
+		rec.PoolWaiting = s.src.Waiting()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	s.ring[s.next] = slowEntry{seq: s.seq, rec: rec}
+	s.next = (s.next + 1) % len(s.ring)
+	if s.next == 0 {
+		s.full = true
+	}
+}
+
+// Entries returns the captured records, newest first.
+func (s *SlowLog) Entries() []model.SlowRequest {
+	entries := s.entries()
+	out := make([]model.SlowRequest, len(entries))
+	for i, e := range entries {
+		out[i] = e.rec
+	}
+	return out
+}
+
+// entries returns the ring's occupied slots, newest first.
+func (s *SlowLog) entries() []slowEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.next
+	if s.full {
+		n = len(s.ring)
+	}
+	out := make([]slowEntry, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, s.ring[(s.next-i+len(s.ring))%len(s.ring)])
+	}
+	return out
+}
+
+// HandleSlowLog serves the captured records as a JSON array, newest
+// first, paginated by the limit and cursor query parameters.
+//
+// The request must be a GET.
+func (s *SlowLog) HandleSlowLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	p, err := parsePage(r.URL.Query())
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	window, next := paginate(s.entries(), func(e slowEntry) uint64 { return e.seq }, p)
+	out := make([]model.SlowRequest, len(window))
+	for i, e := range window {
+		out[i] = e.rec
+	}
+	writePage(w, r, out, next, p.limit)
+}
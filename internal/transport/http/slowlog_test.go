@@ -0,0 +1,112 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// fakeClock advances by step on every call.
+func fakeClock(step time.Duration) func() time.Time {
+	now := time.Unix(1_700_000_000, 0)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestSlowLogWrap(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubProcessor{
+		steps: []model.StepResult{{Name: "courier", Status: "error", Detail: "no_courier"}},
+		err:   testAppErr{kind: "no_courier"},
+	}
+
+	tests := []struct {
+		name    string
+		step    time.Duration
+		wantLen int
+	}{
+		{name: "fast_not_recorded", step: 10 * time.Millisecond, wantLen: 0},
+		{name: "slow_recorded", step: 2 * time.Second, wantLen: 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := NewSlowLog(time.Second, 4, stubCapacity{cap: 5, inUse: 5, waiting: 2})
+			s.now = fakeClock(tt.step)
+
+			steps, err := s.Wrap(stub).Process(context.Background(), model.OrderRequest{OrderID: "o-1"})
+			if err != stub.err || len(steps) != 1 {
+				t.Fatalf("expected passthrough of stub results, got %v, %v", steps, err)
+			}
+
+			entries := s.Entries()
+			if len(entries) != tt.wantLen {
+				t.Fatalf("expected %d entries, got %d", tt.wantLen, len(entries))
+			}
+			if tt.wantLen == 0 {
+				return
+			}
+			e := entries[0]
+			if e.OrderID != "o-1" || e.DurationMS != 2000 || e.ErrorKind != "no_courier" {
+				t.Fatalf("unexpected record: %+v", e)
+			}
+			if e.PoolCapacity != 5 || e.PoolInUse != 5 || e.PoolWaiting != 2 || e.Goroutines == 0 {
+				t.Fatalf("unexpected diagnostics: %+v", e)
+			}
+			if len(e.Steps) != 1 || e.Steps[0].Name != "courier" {
+				t.Fatalf("unexpected steps: %+v", e.Steps)
+			}
+		})
+	}
+}
+
+// The ring keeps the newest records and returns them newest first.
+func TestSlowLogRing(t *testing.T) {
+	t.Parallel()
+
+	s := NewSlowLog(time.Second, 3, nil)
+	s.now = fakeClock(time.Second)
+	p := s.Wrap(&stubProcessor{})
+
+	for i := 1; i <= 5; i++ {
+		_, _ = p.Process(context.Background(), model.OrderRequest{OrderID: fmt.Sprintf("o-%d", i)})
+	}
+
+	w := httptest.NewRecorder()
+	s.HandleSlowLog(w, httptest.NewRequest(http.MethodGet, "/admin/slowlog", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var out []model.SlowRequest
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := []string{"o-5", "o-4", "o-3"}
+	if len(out) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(out))
+	}
+	for i, id := range want {
+		if out[i].OrderID != id {
+			t.Fatalf("entry %d: expected %s, got %s", i, id, out[i].OrderID)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	s.HandleSlowLog(w, httptest.NewRequest(http.MethodDelete, "/admin/slowlog", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
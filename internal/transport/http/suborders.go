@@ -0,0 +1,109 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// A basket can hold items from several vendors, which accept, prepare
+// and hand over their share independently. With WithSubOrders, an order
+// whose items name more than one vendor is split into sub-orders, one
+// per vendor, and each runs the whole pipeline on its own, concurrently.
+// A failing sub-order does not cancel the others: the order completes
+// when every sub-order does, fails when every one fails, and otherwise
+// is partially completed, answered 200 with model.StatusPartiallyCompleted.
+//
+// Sub-orders are processed as orders of their own, with IDs derived
+// from the parent's, so the audit log, recordings and dashboard count
+// each of them. They share the parent's deadline and client, and are
+// not coalesced.
+
+// WithSubOrders makes the handler split orders whose items come from
+// more than one vendor into a sub-order per vendor.
+func WithSubOrders() Option {
+	return func(h *Handler) { h.splitOrders = true }
+}
+
+// splitOrder returns the sub-orders of req, one per vendor in the order
+// the vendors first appear in its items, or nil if its items name fewer
+// than two vendors. Sub-order n, counting from 1, has ID
+// "<order_id>.<n>", carries its vendor's items and their total amount,
+// and otherwise copies req.
+func splitOrder(req model.OrderRequest) []model.OrderRequest {
+	var subs []model.OrderRequest
+	index := map[string]int{}
+	for _, it := range req.Items {
+		i, ok := index[it.Vendor]
+		if !ok {
+			i = len(subs)
+			index[it.Vendor] = i
+			sub := req
+			sub.OrderID = req.OrderID + "." + strconv.Itoa(i+1)
+			sub.Amount, sub.Items = 0, nil
+			subs = append(subs, sub)
+		}
+		subs[i].Amount += it.Amount
+		subs[i].Items = append(subs[i].Items, it)
+	}
+	if len(subs) < 2 {
+		return nil
+	}
+	return subs
+}
+
+// processSplit runs subs through the handler's processor concurrently
+// under ctx and returns their outcomes. The error joins the sub-orders'
+// errors when every one of them failed, and is nil otherwise.
+func (h *Handler) processSplit(ctx context.Context, subs []model.OrderRequest) ([]model.SubOrder, error) {
+	out := make([]model.SubOrder, len(subs))
+	errs := make([]error, len(subs))
+	var wg sync.WaitGroup
+	for i, sub := range subs {
+		wg.Go(func() {
+			steps, err := h.orderProcessor.Process(ctx, sub)
+			primary := mostSevere(splitErrors(err))
+			out[i] = model.SubOrder{
+				OrderID: sub.OrderID,
+				Vendor:  sub.Items[0].Vendor,
+				Amount:  sub.Amount,
+				Status:  orderStatus(steps, primary),
+				Steps:   slices.Clone(steps),
+			}
+			if primary != nil {
+				out[i].Error = &model.ErrorPayload{Kind: errorKind(primary), Message: "order failed"}
+				errs[i] = primary
+			}
+			if r, ok := h.orderProcessor.(resultReleaser); ok {
+				r.Release(steps)
+			}
+		})
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err == nil {
+			return out, nil
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
+// splitStatus returns the status of an order split into subs, not all
+// of which failed: StatusPartiallyCompleted if any failed, else the
+// status of the first sub-order not StatusOK, or StatusOK.
+func splitStatus(subs []model.SubOrder) model.Status {
+	status := model.StatusOK
+	for _, s := range subs {
+		if s.Status.Failed() {
+			return model.StatusPartiallyCompleted
+		}
+		if status == model.StatusOK {
+			status = s.Status
+		}
+	}
+	return status
+}
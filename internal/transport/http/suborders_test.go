@@ -0,0 +1,170 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// vendorProcessor fails the orders of the vendors in fail, and pools
+// its results like order.Service.
+type vendorProcessor struct {
+	fail     map[string]string // vendor → error kind
+	released atomic.Int64
+}
+
+func (p *vendorProcessor) Process(_ context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	vendor := ""
+	if len(req.Items) > 0 {
+		vendor = req.Items[0].Vendor
+	}
+	if kind, ok := p.fail[vendor]; ok {
+		return []model.StepResult{{Name: "vendor", Status: model.StatusError, Detail: kind}}, testAppErr{kind: kind}
+	}
+	return []model.StepResult{{Name: "vendor", Status: model.StatusOK, Detail: req.OrderID}}, nil
+}
+
+func (p *vendorProcessor) Release([]model.StepResult) { p.released.Add(1) }
+
+func TestSplitOrder(t *testing.T) {
+	t.Parallel()
+
+	req := model.OrderRequest{OrderID: "o-1", Amount: 30, Zone: "north", Items: []model.OrderItem{
+		{Vendor: "pizza", Amount: 10}, {Vendor: "sushi", Amount: 15}, {Vendor: "pizza", Amount: 5},
+	}}
+	got := splitOrder(req)
+	want := []model.OrderRequest{
+		{OrderID: "o-1.1", Amount: 15, Zone: "north", Items: []model.OrderItem{{Vendor: "pizza", Amount: 10}, {Vendor: "pizza", Amount: 5}}},
+		{OrderID: "o-1.2", Amount: 15, Zone: "north", Items: []model.OrderItem{{Vendor: "sushi", Amount: 15}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if len(req.Items) != 3 || req.Amount != 30 {
+		t.Fatalf("expected the parent unchanged, got %+v", req)
+	}
+	for _, items := range [][]model.OrderItem{nil, {{Vendor: "pizza", Amount: 10}, {Vendor: "pizza", Amount: 5}}} {
+		if subs := splitOrder(model.OrderRequest{OrderID: "o-2", Amount: 15, Items: items}); subs != nil {
+			t.Fatalf("expected no split for a single vendor, got %+v", subs)
+		}
+	}
+}
+
+func TestHandleOrder_SubOrders(t *testing.T) {
+	t.Parallel()
+
+	basket := `{"order_id":"o-1","amount":30,"items":[{"vendor":"pizza","amount":10},{"vendor":"sushi","amount":15},{"vendor":"tacos","amount":5}]}`
+
+	tests := []struct {
+		name       string
+		fail       map[string]string
+		split      bool
+		body       string
+		wantCode   int
+		wantStatus model.Status
+		wantSubs   []model.Status // nil: not split
+		wantKind   string
+	}{
+		{
+			name:       "all_completed",
+			split:      true,
+			body:       basket,
+			wantCode:   http.StatusOK,
+			wantStatus: model.StatusOK,
+			wantSubs:   []model.Status{model.StatusOK, model.StatusOK, model.StatusOK},
+		},
+		{
+			name:       "partially_completed",
+			fail:       map[string]string{"sushi": "vendor_unavailable"},
+			split:      true,
+			body:       basket,
+			wantCode:   http.StatusOK,
+			wantStatus: model.StatusPartiallyCompleted,
+			wantSubs:   []model.Status{model.StatusOK, model.StatusError, model.StatusOK},
+		},
+		{
+			name:       "all_failed",
+			fail:       map[string]string{"pizza": "payment_declined", "sushi": "vendor_unavailable", "tacos": "payment_declined"},
+			split:      true,
+			body:       basket,
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: model.StatusError,
+			wantSubs:   []model.Status{model.StatusError, model.StatusError, model.StatusError},
+			wantKind:   "vendor_unavailable",
+		},
+		{
+			name:       "single_vendor",
+			split:      true,
+			body:       `{"order_id":"o-1","amount":10,"items":[{"vendor":"pizza","amount":10}]}`,
+			wantCode:   http.StatusOK,
+			wantStatus: model.StatusOK,
+		},
+		{
+			name:       "split_off",
+			body:       basket,
+			wantCode:   http.StatusOK,
+			wantStatus: model.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			proc := &vendorProcessor{fail: tt.fail}
+			var opts []Option
+			if tt.split {
+				opts = append(opts, WithSubOrders())
+			}
+			h := New(proc, time.Second, opts...)
+			w := httptest.NewRecorder()
+			h.HandleOrder(w, httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, w.Code, w.Body)
+			}
+			var resp model.OrderResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != tt.wantStatus || resp.OrderID != "o-1" {
+				t.Fatalf("expected status %s, got %+v", tt.wantStatus, resp)
+			}
+			if got, want := proc.released.Load(), int64(max(len(tt.wantSubs), 1)); got != want {
+				t.Fatalf("expected %d results released, got %d", want, got)
+			}
+			if tt.wantSubs == nil {
+				if len(resp.SubOrders) != 0 || len(resp.Steps) != 1 {
+					t.Fatalf("expected an unsplit order, got %+v", resp)
+				}
+				return
+			}
+			if len(resp.Steps) != 0 || len(resp.SubOrders) != len(tt.wantSubs) {
+				t.Fatalf("expected %d sub-orders and no steps, got %+v", len(tt.wantSubs), resp)
+			}
+			for i, sub := range resp.SubOrders {
+				if sub.Status != tt.wantSubs[i] || sub.OrderID != "o-1."+strconv.Itoa(i+1) || (sub.Error != nil) != sub.Status.Failed() {
+					t.Fatalf("sub-order %d: expected %s, got %+v", i, tt.wantSubs[i], sub)
+				}
+				if !sub.Status.Failed() && (len(sub.Steps) != 1 || sub.Steps[0].Detail != sub.OrderID) {
+					t.Fatalf("sub-order %d: expected its own steps, got %+v", i, sub.Steps)
+				}
+			}
+			if got := resp.SubOrders[1]; got.Vendor != "sushi" || got.Amount != 15 {
+				t.Fatalf("expected the sushi sub-order second, got %+v", got)
+			}
+			if tt.wantKind != "" && (resp.Error == nil || resp.Error.Kind != tt.wantKind || len(resp.Errors) != 3) {
+				t.Fatalf("expected error %s listing every sub-order, got %+v %+v", tt.wantKind, resp.Error, resp.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package httptransport
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// requestTimer is the handler whose processing deadline Timeouts tunes.
+type requestTimer interface {
+	RequestTimeout() time.Duration
+	SetRequestTimeout(d time.Duration)
+}
+
+// Timeouts holds the HTTP server's read, write and idle timeouts and
+// the handler's request timeout, adjustable at runtime for incident
+// mitigation.
+//
+// http.Server reads its timeout fields without synchronization, so the
+// server is configured without them and Timeouts enforces them itself:
+// Middleware sets each request's read and write deadlines, and
+// ConnState closes connections left idle for too long. Changes apply to
+// requests received and connections going idle afterwards. The server's
+// ReadHeaderTimeout stays fixed.
+type Timeouts struct {
+	read, write, idle atomic.Int64 // nanoseconds
+	handler           requestTimer
+
+	setMu sync.Mutex // serializes Set
+
+	connMu    sync.Mutex
+	idleConns map[net.Conn]*time.Timer
+}
+
+// NewTimeouts returns Timeouts starting from the given read, write and
+// idle timeouts and the request timeout of h. Non-positive values
+// disable that timeout. It panics if h is nil.
+func NewTimeouts(read, write, idle time.Duration, h requestTimer) *Timeouts {
+	if h == nil {
+		panic("httptransport.NewTimeouts: nil handler")
+	}
+	t := &Timeouts{handler: h, idleConns: make(map[net.Conn]*time.Timer)}
+	t.read.Store(int64(max(read, 0)))
+	t.write.Store(int64(max(write, 0)))
+	t.idle.Store(int64(max(idle, 0)))
+	return t
+}
+
+// Snapshot reports the timeouts in effect.
+func (t *Timeouts) Snapshot() model.ServerTimeouts {
+	return model.ServerTimeouts{
+		ReadMS:    time.Duration(t.read.Load()).Milliseconds(),
+		WriteMS:   time.Duration(t.write.Load()).Milliseconds(),
+		IdleMS:    time.Duration(t.idle.Load()).Milliseconds(),
+		RequestMS: t.handler.RequestTimeout().Milliseconds(),
+	}
+}
+
+// Set changes the timeouts given as positive values in u and leaves
+// those given as zero. It refuses negative values and a write timeout
+// not longer than the request timeout, which would cut responses off
+// before the order's deadline, and then changes nothing.
+func (t *Timeouts) Set(u model.ServerTimeouts) error {
+	if u.ReadMS < 0 || u.WriteMS < 0 || u.IdleMS < 0 || u.RequestMS < 0 {
+		return errors.New("timeouts must not be negative")
+	}
+	t.setMu.Lock()
+	defer t.setMu.Unlock()
+
+	cur := t.Snapshot()
+	pick := func(cur, v int64) int64 {
+		if v > 0 {
+			return v
+		}
+		return cur
+	}
+	next := model.ServerTimeouts{
+		ReadMS:    pick(cur.ReadMS, u.ReadMS),
+		WriteMS:   pick(cur.WriteMS, u.WriteMS),
+		IdleMS:    pick(cur.IdleMS, u.IdleMS),
+		RequestMS: pick(cur.RequestMS, u.RequestMS),
+	}
+	if next.WriteMS > 0 && next.WriteMS <= next.RequestMS {
+		return errors.New("write timeout must be longer than the request timeout")
+	}
+	t.read.Store(int64(time.Duration(next.ReadMS) * time.Millisecond))
+	t.write.Store(int64(time.Duration(next.WriteMS) * time.Millisecond))
+	t.idle.Store(int64(time.Duration(next.IdleMS) * time.Millisecond))
+	t.handler.SetRequestTimeout(time.Duration(next.RequestMS) * time.Millisecond)
+	return nil
+}
+
+// Middleware sets the read and write deadlines of each request's
+// connection from the timeouts in effect when it arrives. It must wrap
+// the server's handler directly, so the deadlines reach the connection.
+func (t *Timeouts) Middleware(next http.Handler) http.Handler {
+	if next == nil {
+		panic("httptransport.Timeouts.Middleware: nil handler")
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		now := time.Now()
+		if d := time.Duration(t.read.Load()); d > 0 {
+			_ = rc.SetReadDeadline(now.Add(d))
+		}
+		if d := time.Duration(t.write.Load()); d > 0 {
+			_ = rc.SetWriteDeadline(now.Add(d))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ConnState is an http.Server ConnState hook closing connections that
+// stay idle between requests for longer than the idle timeout.
+func (t *Timeouts) ConnState(c net.Conn, state http.ConnState) {
+	t.connMu.Lock()
+	defer t.connMu.Unlock()
+	if timer, ok := t.idleConns[c]; ok {
+		timer.Stop()
+		delete(t.idleConns, c)
+	}
+	d := time.Duration(t.idle.Load())
+	if state != http.StateIdle || d <= 0 {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		t.connMu.Lock()
+		defer t.connMu.Unlock()
+		if t.idleConns[c] != timer {
+			return // the connection became active again
+		}
+		delete(t.idleConns, c)
+		_ = c.Close()
+	})
+	t.idleConns[c] = timer
+}
+
+// HandleTimeouts reports the timeouts on GET and changes them on PUT,
+// taking a model.ServerTimeouts whose zero fields are left unchanged.
+// Either way, it responds with the timeouts in effect.
+func (t *Timeouts) HandleTimeouts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req model.ServerTimeouts
+		if err := decodeStrictJSON(w, r, &req); err != nil {
+			badRequest(w, "invalid JSON")
+			return
+		}
+		if err := t.Set(req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, t.Snapshot())
+}
@@ -0,0 +1,158 @@
+package httptransport
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestTimeoutsSet(t *testing.T) {
+	t.Parallel()
+
+	start := model.ServerTimeouts{ReadMS: 10000, WriteMS: 15000, IdleMS: 60000, RequestMS: 10000}
+
+	tests := []struct {
+		name    string
+		update  model.ServerTimeouts
+		want    model.ServerTimeouts
+		wantErr bool
+	}{
+		{name: "unchanged", want: start},
+		{name: "partial", update: model.ServerTimeouts{IdleMS: 5000, RequestMS: 3000}, want: model.ServerTimeouts{ReadMS: 10000, WriteMS: 15000, IdleMS: 5000, RequestMS: 3000}},
+		{name: "raise_both", update: model.ServerTimeouts{WriteMS: 35000, RequestMS: 30000}, want: model.ServerTimeouts{ReadMS: 10000, WriteMS: 35000, IdleMS: 60000, RequestMS: 30000}},
+		{name: "negative", update: model.ServerTimeouts{ReadMS: -1}, wantErr: true},
+		{name: "write_not_above_request", update: model.ServerTimeouts{RequestMS: 15000}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := New(&stubProcessor{}, 10*time.Second)
+			timeouts := NewTimeouts(10*time.Second, 15*time.Second, time.Minute, h)
+			err := timeouts.Set(tt.update)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				if got := timeouts.Snapshot(); got != start {
+					t.Fatalf("expected no change, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := timeouts.Snapshot(); got != tt.want {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+			if got := h.RequestTimeout(); got != time.Duration(tt.want.RequestMS)*time.Millisecond {
+				t.Fatalf("expected the handler's request timeout to follow, got %v", got)
+			}
+		})
+	}
+}
+
+func TestHandleTimeouts(t *testing.T) {
+	t.Parallel()
+
+	p := &deadlineProcessor{stubProcessor: stubProcessor{steps: []model.StepResult{{Name: "payment", Status: model.StatusOK}}}}
+	h := New(p, 10*time.Second)
+	timeouts := NewTimeouts(10*time.Second, 15*time.Second, time.Minute, h)
+
+	w := httptest.NewRecorder()
+	timeouts.HandleTimeouts(w, httptest.NewRequest(http.MethodPut, "/admin/timeouts", strings.NewReader(`{"request_ms":2000}`)))
+	var got model.ServerTimeouts
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil || w.Code != http.StatusOK || got.RequestMS != 2000 {
+		t.Fatalf("expected the new request timeout, got %d %+v (%v)", w.Code, got, err)
+	}
+	w = httptest.NewRecorder()
+	h.HandleOrder(w, httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"order_id":"o-1","amount":100}`)))
+	if p.remaining <= time.Second || p.remaining > 2*time.Second {
+		t.Fatalf("expected a 2s deadline for the next order, got %v", p.remaining)
+	}
+
+	for _, body := range []string{`{"write_ms":-5}`, `{"write_ms":1000}`, `{"idle":1}`} {
+		w = httptest.NewRecorder()
+		timeouts.HandleTimeouts(w, httptest.NewRequest(http.MethodPut, "/admin/timeouts", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+	w = httptest.NewRecorder()
+	timeouts.HandleTimeouts(w, httptest.NewRequest(http.MethodPost, "/admin/timeouts", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
+// newTimeoutServer serves handler behind timeouts on a real server.
+func newTimeoutServer(t *testing.T, timeouts *Timeouts, handler http.Handler) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(timeouts.Middleware(handler))
+	srv.Config.ConnState = timeouts.ConnState
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTimeouts_WriteDeadline(t *testing.T) {
+	t.Parallel()
+
+	timeouts := NewTimeouts(0, time.Second, 0, New(&stubProcessor{}, 10*time.Second))
+	if err := timeouts.Set(model.ServerTimeouts{RequestMS: 10, WriteMS: 50}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv := newTimeoutServer(t, timeouts, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+		_, _ = io.WriteString(w, "late")
+	}))
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("expected the response to be cut off, got %s", resp.Status)
+	}
+}
+
+func TestTimeouts_IdleConnectionClosed(t *testing.T) {
+	t.Parallel()
+
+	timeouts := NewTimeouts(0, 0, time.Minute, New(&stubProcessor{}, 10*time.Second))
+	if err := timeouts.Set(model.ServerTimeouts{IdleMS: 50}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv := newTimeoutServer(t, timeouts, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\n\r\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := br.ReadByte(); err != io.EOF {
+		t.Fatalf("expected the idle connection to be closed, got %v", err)
+	}
+}
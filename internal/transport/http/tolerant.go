@@ -0,0 +1,149 @@
+package httptransport
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// TolerantReader relaxes the strict decoding of request bodies on
+// selected routes: a body with fields the server does not know is
+// decoded as if they were absent instead of being rejected with 400.
+// During a rolling upgrade this keeps older servers accepting requests
+// from newer clients that send additive fields.
+//
+// Each ignored field is counted by route and name; the first time a
+// name is seen on a route it is also logged. Only the first unknown
+// field of a body is named.
+type TolerantReader struct {
+	routes map[string]bool
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu     sync.Mutex
+	fields map[tolerantKey]*model.UnknownField
+}
+
+type tolerantKey struct{ route, field string }
+
+// NewTolerantReader returns a TolerantReader for the given route paths.
+// It panics if logger is nil.
+func NewTolerantReader(routes []string, logger *slog.Logger) *TolerantReader {
+	if logger == nil {
+		panic("httptransport.NewTolerantReader: nil logger")
+	}
+	t := &TolerantReader{
+		routes: make(map[string]bool, len(routes)),
+		logger: logger,
+		now:    time.Now,
+		fields: make(map[tolerantKey]*model.UnknownField),
+	}
+	for _, r := range routes {
+		t.routes[r] = true
+	}
+	return t
+}
+
+// ParseTolerantRoutes parses a comma-separated list of route paths,
+// e.g. "/order,/admin/timeouts". Each must start with "/".
+func ParseTolerantRoutes(spec string) ([]string, error) {
+	var routes []string
+	for r := range strings.SplitSeq(spec, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		if !strings.HasPrefix(r, "/") {
+			return nil, fmt.Errorf("httptransport: tolerant route %q: want a path starting with /", r)
+		}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
+
+type tolerantReaderKey struct{}
+
+// Middleware marks requests to the reader's routes so that their bodies
+// are decoded tolerantly.
+func (t *TolerantReader) Middleware(next http.Handler) http.Handler {
+	if next == nil {
+		panic("httptransport.TolerantReader.Middleware: nil handler")
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t.routes[r.URL.Path] {
+			r = r.WithContext(context.WithValue(r.Context(), tolerantReaderKey{}, t))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tolerantReaderFrom returns the reader marking ctx, or nil.
+func tolerantReaderFrom(ctx context.Context) *TolerantReader {
+	t, _ := ctx.Value(tolerantReaderKey{}).(*TolerantReader)
+	return t
+}
+
+// unknownField returns the field named by an unknown-field error from
+// a json.Decoder, which has no error type of its own.
+func unknownField(err error) (string, bool) {
+	msg, ok := strings.CutPrefix(err.Error(), "json: unknown field ")
+	if !ok {
+		return "", false
+	}
+	name, uerr := strconv.Unquote(msg)
+	return name, uerr == nil
+}
+
+// ignored records that field was ignored on route.
+func (t *TolerantReader) ignored(ctx context.Context, route, field string) {
+	t.mu.Lock()
+	k := tolerantKey{route, field}
+	f := t.fields[k]
+	first := f == nil
+	if first {
+		f = &model.UnknownField{Route: route, Field: field}
+		t.fields[k] = f
+	}
+	f.Count++
+	f.LastSeen = t.now().UTC().Format(time.RFC3339)
+	t.mu.Unlock()
+
+	if first {
+		t.logger.LogAttrs(ctx, slog.LevelWarn, "ignoring unknown JSON field",
+			slog.String("route", route),
+			slog.String("field", field),
+		)
+	}
+}
+
+// Report returns the ignored fields, most frequent first.
+func (t *TolerantReader) Report() []model.UnknownField {
+	t.mu.Lock()
+	out := make([]model.UnknownField, 0, len(t.fields))
+	for _, f := range t.fields {
+		out = append(out, *f)
+	}
+	t.mu.Unlock()
+	slices.SortFunc(out, func(a, b model.UnknownField) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Route, b.Route), cmp.Compare(a.Field, b.Field))
+	})
+	return out
+}
+
+// HandleUnknownFields reports the ignored fields on GET.
+func (t *TolerantReader) HandleUnknownFields(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, t.Report())
+}
@@ -0,0 +1,85 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func TestParseTolerantRoutes(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseTolerantRoutes(" /order, ,/admin/timeouts")
+	if err != nil || !slices.Equal(got, []string{"/order", "/admin/timeouts"}) {
+		t.Fatalf("expected two routes, got %v (%v)", got, err)
+	}
+	if _, err := ParseTolerantRoutes("/order,admin"); err == nil {
+		t.Fatal("expected an error for a route without /")
+	}
+}
+
+func TestTolerantReader(t *testing.T) {
+	t.Parallel()
+
+	logs := &syncBuffer{}
+	tr := NewTolerantReader([]string{"/order"}, slog.New(slog.NewTextHandler(logs, nil)))
+	tr.now = func() time.Time { return time.Unix(1_700_000_000, 0) }
+	proc := &capturingProcessor{}
+	h := tr.Middleware(http.HandlerFunc(New(proc, time.Second).HandleOrder))
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "unknown_field_ignored", path: "/order", body: `{"order_id":"o-1","amount":10,"gift_wrap":true}`, wantStatus: http.StatusOK},
+		{name: "same_field_again", path: "/order", body: `{"order_id":"o-2","amount":10,"gift_wrap":false}`, wantStatus: http.StatusOK},
+		{name: "other_field", path: "/order", body: `{"order_id":"o-3","amount":10,"note":"x"}`, wantStatus: http.StatusOK},
+		{name: "strict_route", path: "/order/v0", body: `{"order_id":"o-4","amount":10,"gift_wrap":true}`, wantStatus: http.StatusBadRequest},
+		{name: "malformed", path: "/order", body: `{"order_id":`, wantStatus: http.StatusBadRequest},
+		{name: "wrong_type", path: "/order", body: `{"order_id":"o-5","amount":"ten","note":"x"}`, wantStatus: http.StatusBadRequest},
+		{name: "multiple_values", path: "/order", body: `{"order_id":"o-6","amount":10,"note":"x"}{}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+		if w.Code != tt.wantStatus {
+			t.Fatalf("%s: expected %d, got %d: %s", tt.name, tt.wantStatus, w.Code, w.Body)
+		}
+	}
+	if proc.got.OrderID != "o-3" || proc.got.Amount != 10 {
+		t.Fatalf("expected the known fields decoded, got %+v", proc.got)
+	}
+
+	want := []model.UnknownField{
+		{Route: "/order", Field: "gift_wrap", Count: 2, LastSeen: "2023-11-14T22:13:20Z"},
+		{Route: "/order", Field: "note", Count: 1, LastSeen: "2023-11-14T22:13:20Z"},
+	}
+	if got := tr.Report(); !slices.Equal(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if n := strings.Count(logs.String(), "ignoring unknown JSON field"); n != 2 {
+		t.Fatalf("expected one log line per new field, got %d:\n%s", n, logs)
+	}
+
+	w := httptest.NewRecorder()
+	tr.HandleUnknownFields(w, httptest.NewRequest(http.MethodGet, "/admin/unknown-fields", nil))
+	var out []model.UnknownField
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil || w.Code != http.StatusOK || len(out) != 2 {
+		t.Fatalf("expected the report, got %d %+v (%v)", w.Code, out, err)
+	}
+	w = httptest.NewRecorder()
+	tr.HandleUnknownFields(w, httptest.NewRequest(http.MethodPost, "/admin/unknown-fields", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,109 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// webhookDispatcher holds webhook subscriptions and delivers events to
+// them.
+type webhookDispatcher interface {
+	Subscribe(req model.WebhookSubscriptionRequest) (model.WebhookSubscription, error)
+	Unsubscribe(id string) bool
+	Subscriptions() []model.WebhookSubscription
+	Publish(e model.WebhookEvent)
+	Deliveries() []model.WebhookDelivery
+}
+
+// Webhooks publishes order outcomes to subscribed endpoints and serves
+// the subscription API.
+type Webhooks struct {
+	dispatcher webhookDispatcher
+}
+
+// NewWebhooks returns Webhooks publishing through d. It panics if d is
+// nil.
+func NewWebhooks(d webhookDispatcher) *Webhooks {
+	if d == nil {
+		panic("httptransport.NewWebhooks: nil dispatcher")
+	}
+	return &Webhooks{dispatcher: d}
+}
+
+// Wrap returns an orderProcessor that delegates to p and publishes one
+// order.completed or order.failed event per order, and a step.failed
+// event per failed step.
+func (wh *Webhooks) Wrap(p orderProcessor) orderProcessor {
+	if p == nil {
+		panic("httptransport.Webhooks.Wrap: nil order processor")
+	}
+	return &webhookProcessor{webhooks: wh, wrapped: wrapped{p}}
+}
+
+// webhookProcessor is the orderProcessor returned by Webhooks.Wrap.
+type webhookProcessor struct {
+	wrapped
+	webhooks *Webhooks
+}
+
+func (wp *webhookProcessor) Process(ctx context.Context, req model.OrderRequest) ([]model.StepResult, error) {
+	steps, err := wp.next.Process(ctx, req)
+
+	d := wp.webhooks.dispatcher
+	for _, s := range steps {
+		if s.Status == model.StatusError {
+			d.Publish(model.WebhookEvent{Type: model.EventStepFailed, OrderID: req.OrderID, Status: s.Status, Step: s.Name, ErrorKind: s.Detail})
+		}
+	}
+	primary := mostSevere(splitErrors(err))
+	e := model.WebhookEvent{Type: model.EventOrderCompleted, OrderID: req.OrderID, Status: orderStatus(steps, primary)}
+	if primary != nil {
+		e.Type, e.ErrorKind = model.EventOrderFailed, errorKind(primary)
+	}
+	d.Publish(e)
+	return steps, err
+}
+
+// HandleWebhooks serves the subscription API: GET lists subscriptions,
+// POST registers one from a model.WebhookSubscriptionRequest and
+// answers 201, and DELETE ?id= removes one, answering 204, or 404 if
+// there is none.
+func (wh *Webhooks) HandleWebhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, wh.dispatcher.Subscriptions())
+	case http.MethodPost:
+		var req model.WebhookSubscriptionRequest
+		if err := decodeStrictJSON(w, r, &req); err != nil {
+			badRequest(w, "invalid JSON")
+			return
+		}
+		sub, err := wh.dispatcher.Subscribe(req)
+		if err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, sub)
+	case http.MethodDelete:
+		if !wh.dispatcher.Unsubscribe(r.URL.Query().Get("id")) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleDeliveries serves the delivery log, newest first.
+//
+// The request must be a GET.
+func (wh *Webhooks) HandleDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, wh.dispatcher.Deliveries())
+}
@@ -0,0 +1,153 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// fakeDispatcher records published events and subscriptions.
+type fakeDispatcher struct {
+	mu     sync.Mutex
+	events []model.WebhookEvent
+	subs   []model.WebhookSubscription
+}
+
+func (f *fakeDispatcher) Subscribe(req model.WebhookSubscriptionRequest) (model.WebhookSubscription, error) {
+	if req.Secret == "" {
+		return model.WebhookSubscription{}, errors.New("empty secret")
+	}
+	sub := model.WebhookSubscription{ID: "wh-1", URL: req.URL, Events: req.Events}
+	f.subs = append(f.subs, sub)
+	return sub, nil
+}
+
+func (f *fakeDispatcher) Unsubscribe(id string) bool {
+	for i, s := range f.subs {
+		if s.ID == id {
+			f.subs = append(f.subs[:i], f.subs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeDispatcher) Subscriptions() []model.WebhookSubscription { return f.subs }
+
+func (f *fakeDispatcher) Publish(e model.WebhookEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, e)
+}
+
+func (f *fakeDispatcher) Deliveries() []model.WebhookDelivery {
+	return []model.WebhookDelivery{{Subscription: "wh-1", Outcome: model.DeliveryDelivered}}
+}
+
+func TestWebhooksWrap(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		proc  *stubProcessor
+		wantE []model.WebhookEvent
+	}{
+		{
+			name: "completed",
+			proc: &stubProcessor{steps: []model.StepResult{{Name: "payment", Status: model.StatusOK}}},
+			wantE: []model.WebhookEvent{
+				{Type: model.EventOrderCompleted, OrderID: "o-1", Status: model.StatusOK},
+			},
+		},
+		{
+			name: "failed",
+			proc: &stubProcessor{
+				steps: []model.StepResult{
+					{Name: "payment", Status: model.StatusOK},
+					{Name: "vendor", Status: model.StatusError, Detail: "vendor_unavailable"},
+					{Name: "courier", Status: model.StatusCanceled},
+				},
+				err: testAppErr{kind: "vendor_unavailable"},
+			},
+			wantE: []model.WebhookEvent{
+				{Type: model.EventStepFailed, OrderID: "o-1", Status: model.StatusError, Step: "vendor", ErrorKind: "vendor_unavailable"},
+				{Type: model.EventOrderFailed, OrderID: "o-1", Status: model.StatusError, ErrorKind: "vendor_unavailable"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d := &fakeDispatcher{}
+			p := NewWebhooks(d).Wrap(tt.proc)
+			if _, err := p.Process(context.Background(), model.OrderRequest{OrderID: "o-1"}); !errors.Is(err, tt.proc.err) {
+				t.Fatalf("expected the order's error, got %v", err)
+			}
+			if len(d.events) != len(tt.wantE) {
+				t.Fatalf("expected %d events, got %+v", len(tt.wantE), d.events)
+			}
+			for i, want := range tt.wantE {
+				if d.events[i] != want {
+					t.Fatalf("event %d: expected %+v, got %+v", i, want, d.events[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHandleWebhooks(t *testing.T) {
+	t.Parallel()
+
+	d := &fakeDispatcher{}
+	wh := NewWebhooks(d)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		wh.HandleWebhooks(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	steps := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantSubs   int
+	}{
+		{name: "create", method: http.MethodPost, target: "/admin/webhooks", body: `{"url":"https://example.com/hook","events":["order.failed"],"secret":"s"}`, wantStatus: http.StatusCreated, wantSubs: 1},
+		{name: "unknown_field", method: http.MethodPost, target: "/admin/webhooks", body: `{"url":"https://example.com","secret":"s","retries":9}`, wantStatus: http.StatusBadRequest, wantSubs: 1},
+		{name: "invalid", method: http.MethodPost, target: "/admin/webhooks", body: `{"url":"https://example.com","events":["order.failed"]}`, wantStatus: http.StatusBadRequest, wantSubs: 1},
+		{name: "list", method: http.MethodGet, target: "/admin/webhooks", wantStatus: http.StatusOK, wantSubs: 1},
+		{name: "delete_unknown", method: http.MethodDelete, target: "/admin/webhooks?id=wh-9", wantStatus: http.StatusNotFound, wantSubs: 1},
+		{name: "delete", method: http.MethodDelete, target: "/admin/webhooks?id=wh-1", wantStatus: http.StatusNoContent, wantSubs: 0},
+		{name: "put", method: http.MethodPut, target: "/admin/webhooks", wantStatus: http.StatusMethodNotAllowed, wantSubs: 0},
+	}
+	for _, st := range steps {
+		w := do(st.method, st.target, st.body)
+		if w.Code != st.wantStatus {
+			t.Fatalf("%s: expected %d, got %d %s", st.name, st.wantStatus, w.Code, w.Body)
+		}
+		if len(d.subs) != st.wantSubs {
+			t.Fatalf("%s: expected %d subscriptions, got %d", st.name, st.wantSubs, len(d.subs))
+		}
+		if st.name == "create" && strings.Contains(w.Body.String(), `"secret"`) {
+			t.Fatalf("expected the secret withheld, got %s", w.Body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	wh.HandleDeliveries(w, httptest.NewRequest(http.MethodGet, "/admin/webhooks/deliveries", nil))
+	var log []model.WebhookDelivery
+	if err := json.NewDecoder(w.Body).Decode(&log); err != nil || len(log) != 1 {
+		t.Fatalf("expected one delivery, got %v (%v)", log, err)
+	}
+}
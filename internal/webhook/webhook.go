@@ -0,0 +1,457 @@
+// Package webhook delivers order events to endpoints registered by
+// their consumers.
+//
+// A Dispatcher holds subscriptions, each an http(s) URL, the event types
+// it wants and a secret. Publish queues an event for every subscription
+// wanting its type; worker goroutines POST it as JSON, signed with the
+// subscription's secret, and retry failed attempts with a doubling
+// backoff. The outcome of each delivery is kept in a bounded log, newest
+// first. Subscriptions and the log are kept in memory and lost on
+// restart.
+//
+// Subscribers choose where the server sends requests, so unless
+// Config.AllowPrivate is set, URLs whose host is or resolves to a
+// loopback, private, link-local or unspecified address are refused, and
+// the default client refuses to connect to one, in case a name resolves
+// differently by the time an event is delivered.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+// Delivery request headers.
+const (
+	HeaderEvent     = "X-Webhook-Event"     // the event type
+	HeaderID        = "X-Webhook-Id"        // the event ID, the same on every attempt
+	HeaderSignature = "X-Webhook-Signature" // "sha256=" + hex HMAC-SHA256 of the body
+)
+
+// ErrInvalid is returned by Subscribe for unusable subscriptions.
+var ErrInvalid = errors.New("webhook: invalid subscription")
+
+// Events lists the event types a subscription may ask for.
+func Events() []string {
+	return []string{model.EventOrderCompleted, model.EventOrderFailed, model.EventStepFailed}
+}
+
+// Config sets how events are delivered.
+type Config struct {
+	Attempts  int           // attempts per delivery; default 3
+	Backoff   time.Duration // wait after the first failed attempt, doubled after each; default 1 second
+	Timeout   time.Duration // deadline of one attempt; default 5 seconds
+	Workers   int           // concurrent deliveries; default 4
+	QueueSize int           // deliveries waiting for a worker; default 1000
+	LogSize   int           // deliveries kept in the log; default 100
+	Client    *http.Client  // default a client that only connects to public addresses
+
+	// AllowPrivate accepts subscriptions to loopback, private and
+	// link-local addresses, for consumers on the same network.
+	AllowPrivate bool
+}
+
+// Dispatcher holds subscriptions and delivers events to them.
+type Dispatcher struct {
+	cfg    Config
+	logger *slog.Logger
+	now    func() time.Time
+	lookup func(ctx context.Context, host string) ([]netip.Addr, error)
+
+	mu     sync.Mutex
+	subs   map[string]*subscription
+	nextID int
+
+	events atomic.Int64 // event IDs
+	queue  chan delivery
+
+	logMu sync.Mutex
+	log   []model.WebhookDelivery // ring; the oldest is at next once full
+	next  int
+}
+
+type subscription struct {
+	model.WebhookSubscription
+	seq       int // registration order
+	secret    []byte
+	delivered atomic.Int64
+	failed    atomic.Int64
+}
+
+type delivery struct {
+	sub   *subscription
+	event model.WebhookEvent
+	body  []byte
+}
+
+// New returns a Dispatcher without subscriptions. Non-positive Config
+// fields take their defaults. It panics if logger is nil.
+func New(cfg Config, logger *slog.Logger) *Dispatcher {
+	if logger == nil {
+		panic("webhook.New: nil logger")
+	}
+	if cfg.Attempts <= 0 {
+		cfg.Attempts = 3
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.LogSize <= 0 {
+		cfg.LogSize = 100
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+		if !cfg.AllowPrivate {
+			cfg.Client = publicClient()
+		}
+	}
+	return &Dispatcher{
+		cfg:    cfg,
+		logger: logger,
+		now:    time.Now,
+		lookup: lookupIP,
+		subs:   map[string]*subscription{},
+		queue:  make(chan delivery, cfg.QueueSize),
+		log:    make([]model.WebhookDelivery, 0, cfg.LogSize),
+	}
+}
+
+// Subscribe registers an endpoint. The URL must be an absolute http(s)
+// URL whose host resolves to public addresses only (any address with
+// AllowPrivate), events must name at least one of Events, and the
+// secret must not be empty; otherwise the error wraps ErrInvalid.
+func (d *Dispatcher) Subscribe(req model.WebhookSubscriptionRequest) (model.WebhookSubscription, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return model.WebhookSubscription{}, fmt.Errorf("%w: url %q: want an http(s) URL", ErrInvalid, req.URL)
+	}
+	if !d.cfg.AllowPrivate {
+		if err := d.checkHost(u.Hostname()); err != nil {
+			return model.WebhookSubscription{}, fmt.Errorf("%w: url %q: %v", ErrInvalid, req.URL, err)
+		}
+	}
+	if len(req.Events) == 0 {
+		return model.WebhookSubscription{}, fmt.Errorf("%w: no events", ErrInvalid)
+	}
+	events := slices.Clone(req.Events)
+	slices.Sort(events)
+	events = slices.Compact(events)
+	for _, e := range events {
+		if !slices.Contains(Events(), e) {
+			return model.WebhookSubscription{}, fmt.Errorf("%w: unknown event %q", ErrInvalid, e)
+		}
+	}
+	if req.Secret == "" {
+		return model.WebhookSubscription{}, fmt.Errorf("%w: empty secret", ErrInvalid)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextID++
+	s := &subscription{
+		WebhookSubscription: model.WebhookSubscription{
+			ID:        "wh-" + strconv.Itoa(d.nextID),
+			URL:       u.String(),
+			Events:    events,
+			CreatedAt: d.now().UTC().Format(time.RFC3339),
+		},
+		seq:    d.nextID,
+		secret: []byte(req.Secret),
+	}
+	d.subs[s.ID] = s
+	d.logger.Info("webhook subscribed", slog.String("id", s.ID), slog.String("url", s.URL))
+	return s.snapshot(), nil
+}
+
+// checkHost returns an error unless host resolves, and only to public
+// addresses.
+func (d *Dispatcher) checkHost(host string) error {
+	var addrs []netip.Addr
+	if a, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{a}
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), d.cfg.Timeout)
+		defer cancel()
+		if addrs, err = d.lookup(ctx, host); err != nil || len(addrs) == 0 {
+			return fmt.Errorf("host %q does not resolve", host)
+		}
+	}
+	for _, a := range addrs {
+		if !public(a) {
+			return fmt.Errorf("host %q: %s is not a public address", host, a)
+		}
+	}
+	return nil
+}
+
+// lookupIP resolves host to its IPv4 and IPv6 addresses.
+func lookupIP(ctx context.Context, host string) ([]netip.Addr, error) {
+	return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+}
+
+// public reports whether a is an address subscribers may send the
+// server to: not loopback, private, link-local, multicast or
+// unspecified.
+func public(a netip.Addr) bool {
+	a = a.Unmap()
+	return a.IsValid() && a.IsGlobalUnicast() && !a.IsPrivate()
+}
+
+// publicClient returns a client that refuses to connect to non-public
+// addresses, whatever a host name resolved to. It ignores proxy
+// settings, so that the check applies to the subscriber's host.
+func publicClient() *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = (&net.Dialer{Timeout: 30 * time.Second, Control: dialPublic}).DialContext
+	return &http.Client{Transport: t}
+}
+
+// dialPublic is a net.Dialer Control func refusing non-public addresses.
+func dialPublic(_, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !public(ap.Addr()) {
+		return fmt.Errorf("webhook: refusing to connect to non-public address %s", ap.Addr())
+	}
+	return nil
+}
+
+// Unsubscribe removes a subscription and reports whether it existed.
+// Its queued deliveries are dropped.
+func (d *Dispatcher) Unsubscribe(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.subs[id]; !ok {
+		return false
+	}
+	delete(d.subs, id)
+	d.logger.Info("webhook unsubscribed", slog.String("id", id))
+	return true
+}
+
+// subscribed reports whether s is still registered.
+func (d *Dispatcher) subscribed(s *subscription) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.subs[s.ID] == s
+}
+
+// Subscriptions returns the registered endpoints in registration order,
+// with their delivery counts.
+func (d *Dispatcher) Subscriptions() []model.WebhookSubscription {
+	d.mu.Lock()
+	subs := make([]*subscription, 0, len(d.subs))
+	for _, s := range d.subs {
+		subs = append(subs, s)
+	}
+	d.mu.Unlock()
+	slices.SortFunc(subs, func(a, b *subscription) int { return a.seq - b.seq })
+	out := make([]model.WebhookSubscription, len(subs))
+	for i, s := range subs {
+		out[i] = s.snapshot()
+	}
+	return out
+}
+
+func (s *subscription) snapshot() model.WebhookSubscription {
+	out := s.WebhookSubscription
+	out.Events = slices.Clone(s.Events)
+	out.Delivered, out.Failed = s.delivered.Load(), s.failed.Load()
+	return out
+}
+
+// Publish queues e for every subscription wanting its type, setting its
+// ID and time if unset. It never blocks: when the queue is full the
+// delivery is logged as failed without an attempt.
+func (d *Dispatcher) Publish(e model.WebhookEvent) {
+	if e.ID == "" {
+		e.ID = "evt-" + strconv.FormatInt(d.events.Add(1), 10)
+	}
+	if e.Time == "" {
+		e.Time = d.now().UTC().Format(time.RFC3339)
+	}
+
+	d.mu.Lock()
+	var subs []*subscription
+	for _, s := range d.subs {
+		if slices.Contains(s.Events, e.Type) {
+			subs = append(subs, s)
+		}
+	}
+	d.mu.Unlock()
+	if len(subs) == 0 {
+		return
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		d.logger.Error("webhook event encoding failed", slog.String("event", e.Type), slog.Any("err", err))
+		return
+	}
+	for _, s := range subs {
+		select {
+		case d.queue <- delivery{sub: s, event: e, body: body}:
+		default:
+			d.logger.Warn("webhook queue full", slog.String("id", s.ID), slog.String("event", e.Type))
+			d.record(s, e, model.WebhookDelivery{Outcome: model.DeliveryFailed, LastError: "queue full"})
+		}
+	}
+}
+
+// Run delivers queued events with Config.Workers goroutines until ctx is
+// done. Deliveries still queued or retrying then are abandoned.
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range d.cfg.Workers {
+		wg.Go(func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case dl := <-d.queue:
+					d.deliver(ctx, dl)
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// deliver POSTs dl until an attempt answers 2xx, the attempts run out,
+// or its subscription is removed.
+func (d *Dispatcher) deliver(ctx context.Context, dl delivery) {
+	backoff := d.cfg.Backoff
+	var out model.WebhookDelivery
+	for out.Attempts < d.cfg.Attempts {
+		if !d.subscribed(dl.sub) {
+			return
+		}
+		if out.Attempts > 0 {
+			t := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return
+			case <-t.C:
+			}
+			backoff *= 2
+		}
+		out.Attempts++
+		status, err := d.attempt(ctx, dl)
+		out.ResponseStatus, out.LastError = status, ""
+		if err == nil {
+			out.Outcome = model.DeliveryDelivered
+			d.record(dl.sub, dl.event, out)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		out.LastError = err.Error()
+	}
+	out.Outcome = model.DeliveryFailed
+	d.logger.Warn("webhook delivery failed",
+		slog.String("id", dl.sub.ID),
+		slog.String("event", dl.event.Type),
+		slog.String("order_id", dl.event.OrderID),
+		slog.String("err", out.LastError),
+	)
+	d.record(dl.sub, dl.event, out)
+}
+
+// attempt POSTs dl once and returns the response status, with an error
+// unless it is 2xx.
+func (d *Dispatcher) attempt(ctx context.Context, dl delivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.sub.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, dl.event.Type)
+	req.Header.Set(HeaderID, dl.event.ID)
+	req.Header.Set(HeaderSignature, Sign(dl.sub.secret, dl.body))
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value of body under secret:
+// "sha256=" followed by the hex HMAC-SHA256. Consumers recompute it
+// over the raw body to authenticate a delivery.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// record settles one delivery: it counts the outcome on s and adds it to
+// the log, evicting the oldest entry once full.
+func (d *Dispatcher) record(s *subscription, e model.WebhookEvent, out model.WebhookDelivery) {
+	if out.Outcome == model.DeliveryDelivered {
+		s.delivered.Add(1)
+	} else {
+		s.failed.Add(1)
+	}
+	out.Subscription, out.EventID, out.Event, out.OrderID = s.ID, e.ID, e.Type, e.OrderID
+	out.Time = d.now().UTC().Format(time.RFC3339)
+
+	d.logMu.Lock()
+	defer d.logMu.Unlock()
+	if len(d.log) < d.cfg.LogSize {
+		d.log = append(d.log, out)
+		return
+	}
+	d.log[d.next] = out
+	d.next = (d.next + 1) % len(d.log)
+}
+
+// Deliveries returns the logged deliveries, newest first.
+func (d *Dispatcher) Deliveries() []model.WebhookDelivery {
+	d.logMu.Lock()
+	defer d.logMu.Unlock()
+	out := make([]model.WebhookDelivery, 0, len(d.log))
+	out = append(out, d.log[d.next:]...)
+	out = append(out, d.log[:d.next]...)
+	slices.Reverse(out)
+	return out
+}
@@ -0,0 +1,258 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iliamunaev/Order-Pipeline-Goroutine-Orchestration/internal/model"
+)
+
+func newDispatcher(cfg Config) *Dispatcher {
+	d := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	d.lookup = fakeLookup
+	return d
+}
+
+// fakeLookup resolves the test host names without DNS.
+func fakeLookup(_ context.Context, host string) ([]netip.Addr, error) {
+	switch host {
+	case "example.com":
+		return []netip.Addr{netip.MustParseAddr("93.184.215.14")}, nil
+	case "localhost":
+		return []netip.Addr{netip.MustParseAddr("::1"), netip.MustParseAddr("127.0.0.1")}, nil
+	case "mixed.example.com":
+		return []netip.Addr{netip.MustParseAddr("93.184.215.14"), netip.MustParseAddr("10.1.2.3")}, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+// waitForDeliveries waits until d has logged n deliveries.
+func waitForDeliveries(t *testing.T, d *Dispatcher, n int) []model.WebhookDelivery {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if got := d.Deliveries(); len(got) >= n {
+			return got
+		}
+	}
+	t.Fatalf("expected %d deliveries, got %+v", n, d.Deliveries())
+	return nil
+}
+
+func TestSubscribe(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		req     model.WebhookSubscriptionRequest
+		wantErr bool
+	}{
+		{name: "valid", req: model.WebhookSubscriptionRequest{URL: "https://example.com/hook", Events: []string{model.EventOrderFailed}, Secret: "s"}},
+		{name: "relative_url", req: model.WebhookSubscriptionRequest{URL: "/hook", Events: []string{model.EventOrderFailed}, Secret: "s"}, wantErr: true},
+		{name: "other_scheme", req: model.WebhookSubscriptionRequest{URL: "ftp://example.com", Events: []string{model.EventOrderFailed}, Secret: "s"}, wantErr: true},
+		{name: "no_events", req: model.WebhookSubscriptionRequest{URL: "https://example.com", Secret: "s"}, wantErr: true},
+		{name: "unknown_event", req: model.WebhookSubscriptionRequest{URL: "https://example.com", Events: []string{"order.created"}, Secret: "s"}, wantErr: true},
+		{name: "no_secret", req: model.WebhookSubscriptionRequest{URL: "https://example.com", Events: []string{model.EventOrderFailed}}, wantErr: true},
+		{name: "loopback", req: model.WebhookSubscriptionRequest{URL: "http://127.0.0.1:8080/admin/maintenance", Events: Events(), Secret: "s"}, wantErr: true},
+		{name: "loopback_v6", req: model.WebhookSubscriptionRequest{URL: "http://[::1]/hook", Events: Events(), Secret: "s"}, wantErr: true},
+		{name: "mapped_loopback", req: model.WebhookSubscriptionRequest{URL: "http://[::ffff:127.0.0.1]/hook", Events: Events(), Secret: "s"}, wantErr: true},
+		{name: "private", req: model.WebhookSubscriptionRequest{URL: "http://10.0.0.5/hook", Events: Events(), Secret: "s"}, wantErr: true},
+		{name: "metadata", req: model.WebhookSubscriptionRequest{URL: "http://169.254.169.254/latest/meta-data", Events: Events(), Secret: "s"}, wantErr: true},
+		{name: "unspecified", req: model.WebhookSubscriptionRequest{URL: "http://0.0.0.0/hook", Events: Events(), Secret: "s"}, wantErr: true},
+		{name: "resolves_to_loopback", req: model.WebhookSubscriptionRequest{URL: "http://localhost/hook", Events: Events(), Secret: "s"}, wantErr: true},
+		{name: "resolves_partly_private", req: model.WebhookSubscriptionRequest{URL: "https://mixed.example.com/hook", Events: Events(), Secret: "s"}, wantErr: true},
+		{name: "unresolvable", req: model.WebhookSubscriptionRequest{URL: "https://nowhere.invalid/hook", Events: Events(), Secret: "s"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d := newDispatcher(Config{})
+			sub, err := d.Subscribe(tt.req)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("expected ErrInvalid, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := d.Subscriptions(); len(got) != 1 || got[0].ID != sub.ID {
+				t.Fatalf("expected %s listed, got %+v", sub.ID, got)
+			}
+			if !d.Unsubscribe(sub.ID) || d.Unsubscribe(sub.ID) {
+				t.Fatal("expected the first unsubscribe only to succeed")
+			}
+		})
+	}
+}
+
+func TestSubscribe_AllowPrivate(t *testing.T) {
+	t.Parallel()
+
+	d := newDispatcher(Config{AllowPrivate: true})
+	for _, url := range []string{"http://127.0.0.1:9000/hook", "http://10.0.0.5/hook", "http://localhost/hook"} {
+		if _, err := d.Subscribe(model.WebhookSubscriptionRequest{URL: url, Events: Events(), Secret: "s"}); err != nil {
+			t.Fatalf("expected %s accepted, got %v", url, err)
+		}
+	}
+}
+
+// The default client refuses non-public addresses when it connects, so
+// a name resolving to a public address at Subscribe and to a private
+// one later is still not reached.
+func TestDispatcher_RefusesPrivateDial(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { calls.Add(1) }))
+	defer srv.Close()
+
+	d := newDispatcher(Config{Attempts: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	// Register a public address, then point the subscription at the
+	// test server, as a rebinding DNS server would.
+	sub, err := d.Subscribe(model.WebhookSubscriptionRequest{URL: "https://example.com/hook", Events: Events(), Secret: "s"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d.mu.Lock()
+	d.subs[sub.ID].URL = srv.URL
+	d.mu.Unlock()
+	d.Publish(model.WebhookEvent{Type: model.EventOrderFailed, OrderID: "o-1", Status: model.StatusError})
+
+	log := waitForDeliveries(t, d, 1)
+	if log[0].Outcome != model.DeliveryFailed || !strings.Contains(log[0].LastError, "non-public address") || calls.Load() != 0 {
+		t.Fatalf("expected the connection refused, got %+v after %d calls", log[0], calls.Load())
+	}
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	t.Parallel()
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan received, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{header: r.Header, body: body}
+	}))
+	defer srv.Close()
+
+	d := newDispatcher(Config{AllowPrivate: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	sub, err := d.Subscribe(model.WebhookSubscriptionRequest{URL: srv.URL, Events: []string{model.EventOrderFailed}, Secret: "s3cret"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d.Publish(model.WebhookEvent{Type: model.EventOrderCompleted, OrderID: "o-1", Status: model.StatusOK}) // not subscribed
+	d.Publish(model.WebhookEvent{Type: model.EventOrderFailed, OrderID: "o-2", Status: model.StatusError, ErrorKind: "vendor_unavailable"})
+
+	r := <-got
+	if sig := r.header.Get(HeaderSignature); sig != Sign([]byte("s3cret"), r.body) {
+		t.Fatalf("expected the body signed, got %q", sig)
+	}
+	var e model.WebhookEvent
+	if err := json.Unmarshal(r.body, &e); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if e.Type != model.EventOrderFailed || e.OrderID != "o-2" || e.ID == "" || e.ID != r.header.Get(HeaderID) {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	log := waitForDeliveries(t, d, 1)
+	if len(log) != 1 || log[0].Outcome != model.DeliveryDelivered || log[0].Attempts != 1 || log[0].ResponseStatus != http.StatusOK {
+		t.Fatalf("unexpected log %+v", log)
+	}
+	if subs := d.Subscriptions(); subs[0].ID != sub.ID || subs[0].Delivered != 1 {
+		t.Fatalf("expected one delivery counted, got %+v", subs)
+	}
+}
+
+func TestDispatcher_Retry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		failures     int64 // attempts answered 500 before one answers 200
+		wantOutcome  string
+		wantAttempts int
+	}{
+		{name: "recovers", failures: 2, wantOutcome: model.DeliveryDelivered, wantAttempts: 3},
+		{name: "gives_up", failures: 5, wantOutcome: model.DeliveryFailed, wantAttempts: 3},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var calls atomic.Int64
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) <= tt.failures {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			defer srv.Close()
+
+			d := newDispatcher(Config{Attempts: 3, Backoff: time.Millisecond, AllowPrivate: true})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go d.Run(ctx)
+			if _, err := d.Subscribe(model.WebhookSubscriptionRequest{URL: srv.URL, Events: Events(), Secret: "s"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			d.Publish(model.WebhookEvent{Type: model.EventStepFailed, OrderID: "o-1", Status: model.StatusError, Step: "vendor"})
+
+			log := waitForDeliveries(t, d, 1)
+			if log[0].Outcome != tt.wantOutcome || log[0].Attempts != tt.wantAttempts {
+				t.Fatalf("expected %s after %d attempts, got %+v", tt.wantOutcome, tt.wantAttempts, log[0])
+			}
+			if tt.wantOutcome == model.DeliveryFailed && log[0].LastError != "status 500" {
+				t.Fatalf("expected the last error kept, got %q", log[0].LastError)
+			}
+		})
+	}
+}
+
+func TestDispatcher_QueueFullAndLog(t *testing.T) {
+	t.Parallel()
+
+	// Without Run nothing drains the queue.
+	d := newDispatcher(Config{QueueSize: 1, LogSize: 2, AllowPrivate: true})
+	if _, err := d.Subscribe(model.WebhookSubscriptionRequest{URL: "http://127.0.0.1:1", Events: Events(), Secret: "s"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, id := range []string{"o-1", "o-2", "o-3", "o-4"} {
+		d.Publish(model.WebhookEvent{Type: model.EventOrderCompleted, OrderID: id, Status: model.StatusOK})
+	}
+
+	log := d.Deliveries()
+	if len(log) != 2 || log[0].OrderID != "o-4" || log[1].OrderID != "o-3" {
+		t.Fatalf("expected the two newest drops, got %+v", log)
+	}
+	if log[0].Outcome != model.DeliveryFailed || log[0].LastError != "queue full" || log[0].Attempts != 0 {
+		t.Fatalf("unexpected drop %+v", log[0])
+	}
+	if subs := d.Subscriptions(); subs[0].Failed != 3 {
+		t.Fatalf("expected 3 failures counted, got %+v", subs)
+	}
+}